@@ -0,0 +1,124 @@
+package bootstrap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/logging"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// clfTimeFormat is the timestamp format of the Common Log Format.
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// accessLogEntry is a single request written to the access log.
+type accessLogEntry struct {
+	Time      time.Time `json:"time"`
+	Remote    string    `json:"remote"`
+	User      string    `json:"user,omitempty"`
+	Method    string    `json:"method"`
+	URI       string    `json:"uri"`
+	Proto     string    `json:"proto"`
+	Status    int       `json:"status"`
+	Bytes     int64     `json:"bytes"`
+	Duration  float64   `json:"duration"`
+	RequestID string    `json:"request_id,omitempty"`
+	Function  string    `json:"function,omitempty"`
+}
+
+// accessLogger writes a line for every request served to out, as JSON or in the
+// Common Log Format, or a record to logger when it is set.
+type accessLogger struct {
+	format string
+	logger *slog.Logger
+
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func newAccessLogger(format string, out io.Writer) *accessLogger {
+	return &accessLogger{format: format, out: out}
+}
+
+func newSlogAccessLogger(logger *slog.Logger) *accessLogger {
+	return &accessLogger{logger: logger}
+}
+
+func (l *accessLogger) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := httputil.NewHttpWriteInterceptor(w)
+		next.ServeHTTP(ww, r)
+
+		remote, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			remote = r.RemoteAddr
+		}
+		user, _, _ := r.BasicAuth()
+
+		l.write(accessLogEntry{
+			Time:      start,
+			Remote:    remote,
+			User:      user,
+			Method:    r.Method,
+			URI:       r.RequestURI,
+			Proto:     r.Proto,
+			Status:    ww.Status(),
+			Bytes:     ww.BytesWritten(),
+			Duration:  time.Since(start).Seconds(),
+			RequestID: logging.RequestIDFromContext(r.Context()),
+			Function:  httputil.PathVar(r, "name"),
+		})
+	})
+}
+
+func (l *accessLogger) write(e accessLogEntry) {
+	if l.logger != nil {
+		attrs := []any{"method", e.Method, "path", e.URI, "status", e.Status,
+			"duration", time.Duration(e.Duration * float64(time.Second)), "bytes", e.Bytes, "remote", e.Remote}
+		if len(e.RequestID) > 0 {
+			attrs = append(attrs, "request_id", e.RequestID)
+		}
+		if len(e.Function) > 0 {
+			attrs = append(attrs, "function", e.Function)
+		}
+		l.logger.Info("request", attrs...)
+		return
+	}
+
+	var line []byte
+	if l.format == types.AccessLogFormatCLF {
+		line = []byte(formatCLF(e))
+	} else {
+		line, _ = json.Marshal(e)
+		line = append(line, '\n')
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out.Write(line)
+}
+
+// formatCLF formats e as `%h %l %u %t "%r" %>s %b`.
+func formatCLF(e accessLogEntry) string {
+	user := e.User
+	if len(user) == 0 {
+		user = "-"
+	}
+
+	size := "-"
+	if e.Bytes > 0 {
+		size = strconv.FormatInt(e.Bytes, 10)
+	}
+
+	return fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %s\n",
+		e.Remote, user, e.Time.Format(clfTimeFormat), e.Method, e.URI, e.Proto, e.Status, size)
+}
@@ -0,0 +1,132 @@
+package bootstrap
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/openfaas/faas-provider/logging"
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_accessLogger_CLF(t *testing.T) {
+	out := &bytes.Buffer{}
+	handler := newAccessLogger(types.AccessLogFormatCLF, out).middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("hello"))
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/function/figlet?text=hi", nil)
+	r.RemoteAddr = "10.0.0.1:51234"
+	r.SetBasicAuth("admin", "secret")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	want := regexp.MustCompile(`^10\.0\.0\.1 - admin \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [-+]\d{4}\] "POST /function/figlet\?text=hi HTTP/1\.1" 202 5\n$`)
+	if !want.MatchString(out.String()) {
+		t.Errorf("want CLF line, got: %q", out.String())
+	}
+}
+
+func Test_accessLogger_CLF_EmptyBody(t *testing.T) {
+	out := &bytes.Buffer{}
+	handler := newAccessLogger(types.AccessLogFormatCLF, out).middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	r.RemoteAddr = "10.0.0.1:51234"
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	want := regexp.MustCompile(`^10\.0\.0\.1 - - \[.+\] "GET /healthz HTTP/1\.1" 204 -\n$`)
+	if !want.MatchString(out.String()) {
+		t.Errorf("want CLF line, got: %q", out.String())
+	}
+}
+
+func Test_accessLogger_JSON(t *testing.T) {
+	out := &bytes.Buffer{}
+	handler := newAccessLogger("", out).middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/system/functions", nil)
+	r.RemoteAddr = "10.0.0.1:51234"
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	got := accessLogEntry{}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("want a JSON line, got: %q, %s", out.String(), err)
+	}
+
+	if got.Remote != "10.0.0.1" || got.Method != http.MethodGet || got.URI != "/system/functions" || got.Status != http.StatusOK || got.Bytes != 5 {
+		t.Errorf("unexpected entry: %+v", got)
+	}
+}
+
+func Test_accessLogger_Logger(t *testing.T) {
+	out := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(out, nil))
+	handler := newSlogAccessLogger(logger).middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/function/figlet", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	got := map[string]interface{}{}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("want a JSON record, got: %q, %s", out.String(), err)
+	}
+
+	if got["method"] != http.MethodPost || got["path"] != "/function/figlet" || got["status"] != float64(http.StatusAccepted) {
+		t.Errorf("want method, path and status attributes, got: %v", got)
+	}
+	if _, ok := got["duration"]; !ok {
+		t.Errorf("want a duration attribute, got: %v", got)
+	}
+}
+
+func Test_Server_AccessLogRequestID(t *testing.T) {
+	out := &bytes.Buffer{}
+	s := NewServer(&types.FaaSConfig{
+		Logger:    slog.New(slog.NewJSONHandler(out, nil)),
+		AccessLog: true,
+	})
+
+	var gotHeader string
+	handlers := validHandlers()
+	handlers.FunctionProxy = func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get(logging.RequestIDHeader)
+	}
+	s.Handlers(handlers)
+
+	r := httptest.NewRequest(http.MethodPost, "/function/figlet", nil)
+	r.Header.Set(logging.RequestIDHeader, "abc")
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, r)
+
+	if gotHeader != "abc" {
+		t.Errorf("request ID seen by the handler, want: %s, got: %s", "abc", gotHeader)
+	}
+	if got := w.Header().Get(logging.RequestIDHeader); got != "abc" {
+		t.Errorf("request ID of the response, want: %s, got: %s", "abc", got)
+	}
+
+	var record map[string]interface{}
+	for _, line := range bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n")) {
+		entry := map[string]interface{}{}
+		if err := json.Unmarshal(line, &entry); err == nil && entry["msg"] == "request" {
+			record = entry
+		}
+	}
+	if record == nil {
+		t.Fatalf("want an access log record, got: %q", out.String())
+	}
+	if record["request_id"] != "abc" || record["function"] != "figlet" {
+		t.Errorf("want request_id and function attributes, got: %v", record)
+	}
+}
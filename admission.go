@@ -0,0 +1,43 @@
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// decorateWithAdmission calls admit with the FunctionDeployment in the request before next,
+// and rejects the request with a 403 and the error message when admit returns an error.
+// A *types.QuotaError is written with types.WriteQuotaError. Requests which can not be
+// decoded are passed to next, which reports the error.
+func decorateWithAdmission(next http.HandlerFunc, admit func(context.Context, types.FunctionDeployment) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, ok := httputil.BufferBody(w, r)
+		if !ok {
+			return
+		}
+
+		req := types.FunctionDeployment{}
+		if err := json.Unmarshal(body, &req); err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if err := admit(r.Context(), req); err != nil {
+			var quotaErr *types.QuotaError
+			if errors.As(err, &quotaErr) {
+				types.WriteQuotaError(w, quotaErr)
+				return
+			}
+
+			httputil.WriteErrorCode(w, r, http.StatusForbidden, httputil.AdmissionDenied, err.Error())
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
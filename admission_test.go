@@ -0,0 +1,58 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_decorateWithAdmission(t *testing.T) {
+	admit := func(ctx context.Context, fd types.FunctionDeployment) error {
+		if fd.Limits != nil && fd.Limits.Memory == "64Gi" {
+			return errors.New("requested memory exceeds remaining capacity")
+		}
+		if fd.Namespace == "full" {
+			return &types.QuotaError{Namespace: "full", Resource: "functions", Limit: 1, Current: 1}
+		}
+		return nil
+	}
+
+	testCases := []struct {
+		name     string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{name: "admitted", body: `{"service":"figlet"}`, wantCode: http.StatusAccepted, wantBody: `{"service":"figlet"}`},
+		{name: "rejected", body: `{"service":"figlet","limits":{"memory":"64Gi"}}`, wantCode: http.StatusForbidden, wantBody: `"message":"requested memory exceeds remaining capacity","errorCode":"AdmissionDenied"`},
+		{name: "quota", body: `{"service":"figlet","namespace":"full"}`, wantCode: http.StatusForbidden, wantBody: `"resource":"functions"`},
+		{name: "invalid body", body: `{`, wantCode: http.StatusAccepted, wantBody: `{`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := decorateWithAdmission(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				w.WriteHeader(http.StatusAccepted)
+				w.Write(body)
+			}, admit)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/system/functions", strings.NewReader(tc.body)))
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+
+			if got := w.Body.String(); !strings.Contains(got, tc.wantBody) {
+				t.Errorf("body, want: %q, got: %q", tc.wantBody, got)
+			}
+		})
+	}
+}
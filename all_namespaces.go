@@ -0,0 +1,59 @@
+package bootstrap
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// decorateWithAllNamespaces removes the functions in namespaces which are not in allowed
+// from the list returned by next, when the caller asked for every namespace with
+// httputil.AllNamespacesRequested, so that each provider enforces the allowlist the same
+// way. Functions without a namespace are removed, as they can not be checked. Other
+// requests, and every request when allowed is empty, are passed to next unchanged.
+func decorateWithAllNamespaces(next http.HandlerFunc, allowed []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(allowed) == 0 || !httputil.AllNamespacesRequested(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		bw := httputil.NewBufferedResponseWriter()
+		next.ServeHTTP(bw, r)
+
+		if bw.Status() != http.StatusOK {
+			bw.Flush(w)
+			return
+		}
+
+		var functions []types.FunctionStatus
+		if err := json.Unmarshal(bw.Body(), &functions); err != nil {
+			httputil.WriteErrorCode(w, r, http.StatusInternalServerError, httputil.InternalError, fmt.Sprintf("unable to filter functions by namespace: %s", err))
+			return
+		}
+
+		visible := make([]types.FunctionStatus, 0, len(functions))
+		for _, fn := range functions {
+			if len(fn.Namespace) > 0 && httputil.NamespaceAllowed(fn.Namespace, allowed) {
+				visible = append(visible, fn)
+			}
+		}
+
+		body, err := json.Marshal(visible)
+		if err != nil {
+			httputil.WriteErrorCode(w, r, http.StatusInternalServerError, httputil.InternalError, fmt.Sprintf("unable to filter functions by namespace: %s", err))
+			return
+		}
+
+		for k, v := range bw.Header() {
+			w.Header()[k] = v
+		}
+		w.Header().Del("Content-Length")
+		w.Header().Del("ETag")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}
+}
@@ -0,0 +1,60 @@
+package bootstrap
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_decorateWithAllNamespaces(t *testing.T) {
+	lister := func(w http.ResponseWriter, r *http.Request) {
+		functions := []types.FunctionStatus{
+			{Name: "figlet", Namespace: "openfaas-fn"},
+			{Name: "env", Namespace: "dev"},
+			{Name: "coredns", Namespace: "kube-system"},
+			{Name: "unknown"},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(functions)
+	}
+
+	testCases := []struct {
+		name    string
+		target  string
+		allowed []string
+		want    []string
+	}{
+		{name: "wildcard filtered", target: "/system/functions?namespace=*", allowed: []string{"openfaas-fn", "dev"}, want: []string{"figlet", "env"}},
+		{name: "allNamespaces filtered", target: "/system/functions?allNamespaces=true", allowed: []string{"dev"}, want: []string{"env"}},
+		{name: "no allowlist", target: "/system/functions?namespace=*", want: []string{"figlet", "env", "coredns", "unknown"}},
+		{name: "single namespace unchanged", target: "/system/functions?namespace=openfaas-fn", allowed: []string{"dev"}, want: []string{"figlet", "env", "coredns", "unknown"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			decorateWithAllNamespaces(lister, tc.allowed).ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.target, nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status code, want: %d, got: %d", http.StatusOK, w.Code)
+			}
+
+			var got []types.FunctionStatus
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != len(tc.want) {
+				t.Fatalf("functions, want: %v, got: %v", tc.want, got)
+			}
+			for i, name := range tc.want {
+				if got[i].Name != name {
+					t.Errorf("function %d, want: %s, got: %s", i, name, got[i].Name)
+				}
+			}
+		})
+	}
+}
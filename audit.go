@@ -0,0 +1,181 @@
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-provider/audit"
+	"github.com/openfaas/faas-provider/auth"
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/logging"
+)
+
+// maxAuditBodyPeek is how much of the body of a request is kept to read the function it
+// addresses, such as the service of a deployment.
+const maxAuditBodyPeek = 64 * 1024
+
+// auditor logs a record to trail for every request to the system API which may change
+// something, and for every invocation when invocations is set.
+type auditor struct {
+	trail       *audit.Trail
+	invocations bool
+}
+
+func newAuditor(trail *audit.Trail, invocations bool) *auditor {
+	return &auditor{trail: trail, invocations: invocations}
+}
+
+// auditDetailsKey carries the *auditDetails of a request in its context.
+type auditDetailsKey struct{}
+
+// auditDetails are what the handlers of a request add to its record: the caller, once the
+// request is authenticated, and what a handler reports it affected, such as the instances
+// killed through "/danger/kill".
+type auditDetails struct {
+	user      string
+	function  string
+	namespace string
+	instances int
+}
+
+// auditDetailsFromContext returns the auditDetails of the request of ctx, or nil when the
+// request is not audited.
+func auditDetailsFromContext(ctx context.Context) *auditDetails {
+	details, _ := ctx.Value(auditDetailsKey{}).(*auditDetails)
+	return details
+}
+
+// authenticator wraps authenticator, so that the caller of each authenticated request is
+// recorded. Requests rejected by the authenticator are recorded without a user.
+func (a *auditor) authenticator(authenticator auth.Authenticator) auth.Authenticator {
+	return auth.AuthenticatorFunc(func(next http.HandlerFunc) http.HandlerFunc {
+		return authenticator.Decorate(func(w http.ResponseWriter, r *http.Request) {
+			if details := auditDetailsFromContext(r.Context()); details != nil {
+				details.user = requestUser(r)
+			}
+			next(w, r)
+		})
+	})
+}
+
+func (a *auditor) audited(r *http.Request) bool {
+	if strings.HasPrefix(r.URL.Path, "/system/") || r.URL.Path == "/danger/kill" {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return false
+		}
+		return true
+	}
+
+	if a.invocations {
+		return isInvocation(r.URL.Path)
+	}
+	return false
+}
+
+func (a *auditor) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.audited(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		details := &auditDetails{}
+		r = r.WithContext(context.WithValue(r.Context(), auditDetailsKey{}, details))
+
+		var body *auditBody
+		if r.Body != nil && r.Body != http.NoBody {
+			body = &auditBody{ReadCloser: r.Body, peek: r.URL.Path == "/system/functions" || r.URL.Path == "/system/register"}
+			r.Body = body
+		}
+
+		ww := httputil.NewHttpWriteInterceptor(w)
+		next.ServeHTTP(ww, r)
+
+		remote, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			remote = r.RemoteAddr
+		}
+
+		record := audit.Record{
+			Time:      start,
+			RequestID: logging.RequestIDFromContext(r.Context()),
+			User:      details.user,
+			Remote:    remote,
+			Method:    r.Method,
+			Route:     routeTemplate(r),
+			Path:      r.URL.Path,
+			Status:    ww.Status(),
+			Duration:  time.Since(start).Seconds(),
+			BytesOut:  ww.BytesWritten(),
+			Instances: details.instances,
+		}
+
+		record.Function, record.Namespace = httputil.SplitFunctionName(httputil.PathVar(r, "name"))
+		if len(record.Function) == 0 {
+			record.Function, record.Namespace = details.function, details.namespace
+		}
+		if body != nil {
+			record.BytesIn = body.read
+			if len(record.Function) == 0 {
+				record.Function, record.Namespace = body.function()
+			}
+		}
+		if len(record.Namespace) == 0 && strings.HasPrefix(r.URL.Path, "/system/") {
+			record.Namespace = httputil.NamespaceFromRequest(r)
+		}
+
+		a.trail.Log(record)
+	})
+}
+
+// auditBody counts the bytes of the body of a request read by the handler, keeping the
+// start of the body of deploy, update, delete and register requests to read the function
+// from.
+type auditBody struct {
+	io.ReadCloser
+	read int64
+	peek bool
+	buf  bytes.Buffer
+}
+
+func (b *auditBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if b.peek && b.buf.Len() < maxAuditBodyPeek {
+		b.buf.Write(p[:min(n, maxAuditBodyPeek-b.buf.Len())])
+	}
+	return n, err
+}
+
+// function returns the function and namespace named by a JSON body, as the service of a
+// deployment, the functionName of a delete request or the name of a registration.
+func (b *auditBody) function() (string, string) {
+	if b.buf.Len() == 0 {
+		return "", ""
+	}
+
+	req := struct {
+		Service      string `json:"service"`
+		FunctionName string `json:"functionName"`
+		Name         string `json:"name"`
+		Namespace    string `json:"namespace"`
+	}{}
+	if err := json.Unmarshal(b.buf.Bytes(), &req); err != nil {
+		return "", ""
+	}
+
+	for _, name := range []string{req.Service, req.FunctionName, req.Name} {
+		if len(name) > 0 {
+			return name, req.Namespace
+		}
+	}
+	return "", req.Namespace
+}
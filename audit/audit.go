@@ -0,0 +1,160 @@
+// Package audit records who changed or invoked what through the API of a provider, one
+// Record per request, and delivers the records to sinks such as a rotated file or an HTTP
+// collector. Serve records the requests to its routes when FaaSConfig.Audit is set.
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// defaultQueueSize is how many records are held for the sinks when Config.QueueSize is
+// not set.
+const defaultQueueSize = 1024
+
+// Record is a single request to the API.
+type Record struct {
+	// Time is when the request was received.
+	Time time.Time `json:"time"`
+	// RequestID is the X-Request-Id of the request, if any.
+	RequestID string `json:"requestId,omitempty"`
+	// User is the identity of the caller, the basic auth user or the subject of the
+	// bearer token, empty when the request was not authenticated.
+	User string `json:"user,omitempty"`
+	// Remote is the address of the client.
+	Remote string `json:"remote"`
+	// Method is the HTTP method of the request.
+	Method string `json:"method"`
+	// Route is the template of the route which served the request, i.e.
+	// "/system/scale-function/{name}".
+	Route string `json:"route"`
+	// Path is the path of the request.
+	Path string `json:"path"`
+	// Function is the name of the function the request addressed, if any.
+	Function string `json:"function,omitempty"`
+	// Namespace of the function, if given.
+	Namespace string `json:"namespace,omitempty"`
+	// Status is the status code of the response.
+	Status int `json:"status"`
+	// Duration is how long the request took to serve, in seconds.
+	Duration float64 `json:"duration"`
+	// BytesIn is the number of bytes read from the body of the request.
+	BytesIn int64 `json:"bytesIn"`
+	// BytesOut is the number of bytes written to the body of the response.
+	BytesOut int64 `json:"bytesOut"`
+	// Instances is the number of instances the request affected, such as those killed
+	// through "/danger/kill", when known.
+	Instances int `json:"instances,omitempty"`
+}
+
+// Sink delivers records outside of the process, see FileSink and HTTPSink.
+type Sink interface {
+	Write(ctx context.Context, record Record) error
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(ctx context.Context, record Record) error
+
+// Write calls f.
+func (f SinkFunc) Write(ctx context.Context, record Record) error {
+	return f(ctx, record)
+}
+
+// Config configures a Trail.
+type Config struct {
+	// Sinks are written every record, in order.
+	Sinks []Sink
+	// QueueSize is how many records are held while the sinks are slow, further records are
+	// dropped and counted. The default is 1024.
+	QueueSize int
+	// Logger receives failures to deliver records, the default is slog.Default().
+	Logger *slog.Logger
+}
+
+var droppedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Subsystem: "provider",
+	Name:      "audit_records_dropped_total",
+	Help:      "Total number of audit records which were not delivered, by reason.",
+}, []string{"reason"})
+
+// Trail delivers records to its sinks, it is safe for concurrent use. Log never blocks,
+// records are written to the sinks by Run.
+type Trail struct {
+	sinks  []Sink
+	logger *slog.Logger
+	queue  chan Record
+}
+
+// NewTrail creates a Trail, Run must be called for its sinks to be written records. Serve
+// calls Run for FaaSConfig.Audit.
+func NewTrail(config Config) *Trail {
+	size := config.QueueSize
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+
+	logger := config.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &Trail{
+		sinks:  config.Sinks,
+		logger: logger,
+		queue:  make(chan Record, size),
+	}
+}
+
+// Log queues record for the sinks, it is dropped when the queue is full.
+func (t *Trail) Log(record Record) {
+	select {
+	case t.queue <- record:
+	default:
+		droppedTotal.WithLabelValues("queue_full").Inc()
+		t.logger.Warn("Audit queue is full, dropping record", "method", record.Method, "path", record.Path)
+	}
+}
+
+// Run writes queued records to each sink until ctx is cancelled, then writes the records
+// which are still queued. A sink which fails is logged and the record is still written to
+// the other sinks.
+func (t *Trail) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			t.drain()
+			return
+		case record := <-t.queue:
+			if ctx.Err() != nil {
+				// ctx was cancelled while a record was queued, it is written as it is drained
+				t.write(context.Background(), record)
+				continue
+			}
+			t.write(ctx, record)
+		}
+	}
+}
+
+func (t *Trail) drain() {
+	for {
+		select {
+		case record := <-t.queue:
+			t.write(context.Background(), record)
+		default:
+			return
+		}
+	}
+}
+
+func (t *Trail) write(ctx context.Context, record Record) {
+	for _, sink := range t.sinks {
+		if err := sink.Write(ctx, record); err != nil {
+			droppedTotal.WithLabelValues("sink_error").Inc()
+			t.logger.Warn("Unable to deliver audit record", "method", record.Method, "path", record.Path, "error", err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func Test_Trail_Run(t *testing.T) {
+	var mu sync.Mutex
+	var got []Record
+	written := make(chan struct{}, 2)
+
+	trail := NewTrail(Config{Sinks: []Sink{
+		SinkFunc(func(ctx context.Context, record Record) error {
+			return errors.New("unavailable")
+		}),
+		SinkFunc(func(ctx context.Context, record Record) error {
+			mu.Lock()
+			got = append(got, record)
+			mu.Unlock()
+			written <- struct{}{}
+			return nil
+		}),
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go trail.Run(ctx)
+
+	trail.Log(Record{Function: "figlet"})
+	trail.Log(Record{Function: "env"})
+	<-written
+	<-written
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 2 || got[0].Function != "figlet" || got[1].Function != "env" {
+		t.Errorf("want both records after a failing sink, got: %+v", got)
+	}
+}
+
+func Test_Trail_RunDrainsOnCancel(t *testing.T) {
+	var got []Record
+	trail := NewTrail(Config{QueueSize: 2, Sinks: []Sink{
+		SinkFunc(func(ctx context.Context, record Record) error {
+			got = append(got, record)
+			return nil
+		}),
+	}})
+
+	trail.Log(Record{Function: "figlet"})
+	trail.Log(Record{Function: "env"})
+	// The queue is full, so the record is dropped
+	trail.Log(Record{Function: "nodeinfo"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	trail.Run(ctx)
+
+	if len(got) != 2 {
+		t.Errorf("records, want: %d, got: %d", 2, len(got))
+	}
+}
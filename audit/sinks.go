@@ -0,0 +1,175 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/openfaas/faas-provider/events"
+)
+
+// SignatureHeader carries the HMAC-SHA256 of the body sent by an HTTPSink, keyed with its
+// secret, in the same format as events.SignatureHeader, for the receiver to verify with
+// events.VerifySignature.
+const SignatureHeader = "X-Audit-Signature"
+
+// defaultHTTPTimeout bounds each request to an HTTPSink.
+const defaultHTTPTimeout = 10 * time.Second
+
+// FileSink appends each record as a line of JSON to a file, which is rotated once it
+// reaches a size. The rotated files are named after the file with a suffix of ".1" for
+// the most recent, up to the number of backups kept.
+type FileSink struct {
+	path       string
+	maxBytes   int64
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewFileSink opens the file at path for appending. It is rotated when a record would
+// take it over maxBytes, keeping maxBackups rotated files. A maxBytes of 0 never rotates
+// the file.
+func NewFileSink(path string, maxBytes int64, maxBackups int) (*FileSink, error) {
+	s := &FileSink{path: path, maxBytes: maxBytes, maxBackups: maxBackups}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to open audit file: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("unable to open audit file: %w", err)
+	}
+
+	s.file, s.size = f, info.Size()
+	return nil
+}
+
+// Write appends record to the file, rotating it first when it would exceed its size.
+func (s *FileSink) Write(ctx context.Context, record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return fmt.Errorf("audit file %s is closed", s.path)
+	}
+
+	if s.maxBytes > 0 && s.size > 0 && s.size+int64(len(line)) > s.maxBytes {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	return err
+}
+
+// rotate renames the file to the first backup, shifting the others along and removing
+// the oldest, then opens a new file.
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("unable to rotate audit file: %w", err)
+	}
+	s.file = nil
+
+	if s.maxBackups <= 0 {
+		os.Remove(s.path)
+	} else {
+		os.Remove(s.backup(s.maxBackups))
+		for i := s.maxBackups - 1; i > 0; i-- {
+			os.Rename(s.backup(i), s.backup(i+1))
+		}
+		if err := os.Rename(s.path, s.backup(1)); err != nil {
+			return fmt.Errorf("unable to rotate audit file: %w", err)
+		}
+	}
+
+	return s.open()
+}
+
+func (s *FileSink) backup(i int) string {
+	return fmt.Sprintf("%s.%d", s.path, i)
+}
+
+// Close closes the file, further writes fail.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// HTTPSink posts each record as JSON to a URL, such as a log collector.
+type HTTPSink struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+// NewHTTPSink creates a sink which posts records to url. When secret is not empty, the
+// body is signed in the SignatureHeader.
+func NewHTTPSink(url, secret string) *HTTPSink {
+	return &HTTPSink{
+		url:    url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: defaultHTTPTimeout},
+	}
+}
+
+// Write posts record to the URL, a response other than 2xx is an error.
+func (s *HTTPSink) Write(ctx context.Context, record Record) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(s.secret) > 0 {
+		req.Header.Set(SignatureHeader, events.Sign(s.secret, body))
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("audit collector %s responded with %d", s.url, res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-provider/events"
+)
+
+func Test_FileSink_Rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	record := Record{Method: http.MethodPost, Path: "/system/functions", Function: "figlet"}
+	line, _ := json.Marshal(record)
+
+	// Two records fit in each file, so five records leave one in the file itself
+	sink, err := NewFileSink(path, int64(2*(len(line)+1)), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	for i := 0; i < 5; i++ {
+		if err := sink.Write(context.Background(), record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, want := range map[string]int{"audit.log": 1, "audit.log.1": 2, "audit.log.2": 2} {
+		body, err := os.ReadFile(filepath.Join(filepath.Dir(path), name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Count(string(body), "\n"); got != want {
+			t.Errorf("records in %s, want: %d, got: %d", name, want, got)
+		}
+	}
+
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("want only 2 backups to be kept, got: %v", err)
+	}
+}
+
+func Test_HTTPSink_Write(t *testing.T) {
+	secret := []byte("s3cret")
+
+	var got Record
+	var verified bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verified = events.VerifySignature(secret, body, r.Header.Get(SignatureHeader))
+		json.Unmarshal(body, &got)
+	}))
+	defer server.Close()
+
+	sink := NewHTTPSink(server.URL, string(secret))
+	if err := sink.Write(context.Background(), Record{User: "admin", Function: "figlet"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !verified {
+		t.Errorf("want a valid signature")
+	}
+	if got.User != "admin" || got.Function != "figlet" {
+		t.Errorf("record, want the user and function, got: %+v", got)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	if err := NewHTTPSink(failing.URL, "").Write(context.Background(), Record{}); err == nil {
+		t.Errorf("want an error for a 503")
+	}
+}
@@ -0,0 +1,116 @@
+package bootstrap
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-provider/audit"
+	"github.com/openfaas/faas-provider/auth"
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_Audit(t *testing.T) {
+	testCases := []struct {
+		name        string
+		invocations bool
+		method      string
+		path        string
+		body        string
+		user        string
+		want        *audit.Record
+	}{
+		{
+			name: "deploy", method: http.MethodPost, path: "/system/functions",
+			body: `{"service":"figlet","namespace":"dev"}`, user: "admin",
+			want: &audit.Record{User: "admin", Method: http.MethodPost, Route: "/system/functions", Function: "figlet", Namespace: "dev", Status: http.StatusOK, BytesIn: 38},
+		},
+		{
+			name: "scale", method: http.MethodPost, path: "/system/scale-function/figlet.dev",
+			body: `{"replicas":2}`, user: "admin",
+			want: &audit.Record{User: "admin", Method: http.MethodPost, Route: "/system/scale-function/{name:[" + NameExpression + "]+}", Function: "figlet", Namespace: "dev", Status: http.StatusOK, BytesIn: 14},
+		},
+		{
+			name: "unauthenticated", method: http.MethodDelete, path: "/system/functions",
+			body: `{"functionName":"figlet"}`, user: "mallory",
+			// The ID of the request in the error is 32 hex characters.
+			want: &audit.Record{Method: http.MethodDelete, Route: "/system/functions", Status: http.StatusUnauthorized,
+				BytesOut: int64(len(`{"code":401,"message":"invalid credentials","requestId":"00000000000000000000000000000000","errorCode":"Unauthorized"}` + "\n"))},
+		},
+		{name: "read", method: http.MethodGet, path: "/system/functions", user: "admin"},
+		{name: "invocation", method: http.MethodPost, path: "/function/figlet", body: "hi"},
+		{
+			name: "invocation when enabled", invocations: true, method: http.MethodPost, path: "/function/figlet", body: "hi",
+			want: &audit.Record{Method: http.MethodPost, Route: "/function/{name:[" + NameExpression + "]+}", Function: "figlet", Status: http.StatusOK, BytesIn: 2, BytesOut: 5},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []audit.Record
+			trail := audit.NewTrail(audit.Config{Sinks: []audit.Sink{
+				audit.SinkFunc(func(ctx context.Context, record audit.Record) error {
+					got = append(got, record)
+					return nil
+				}),
+			}})
+
+			read := func(w http.ResponseWriter, r *http.Request) {
+				io.ReadAll(r.Body)
+			}
+			handlers := validHandlers()
+			handlers.DeployFunction = read
+			handlers.ScaleFunction = read
+			handlers.FunctionProxy = func(w http.ResponseWriter, r *http.Request) {
+				io.ReadAll(r.Body)
+				w.Write([]byte("hello"))
+			}
+
+			s := NewServer(&types.FaaSConfig{
+				Audit:            trail,
+				AuditInvocations: tc.invocations,
+				Authenticator:    &auth.BasicAuthCredentials{User: "admin", Password: "secret"},
+			})
+			s.Handlers(handlers)
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			if len(tc.user) > 0 {
+				password := "secret"
+				if tc.user != "admin" {
+					password = "guess"
+				}
+				req.SetBasicAuth(tc.user, password)
+			}
+			s.Router().ServeHTTP(httptest.NewRecorder(), req)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			trail.Run(ctx)
+
+			if tc.want == nil {
+				if len(got) != 0 {
+					t.Fatalf("want no records, got: %+v", got)
+				}
+				return
+			}
+			if len(got) != 1 {
+				t.Fatalf("records, want: %d, got: %d", 1, len(got))
+			}
+
+			record := got[0]
+			if record.Time.IsZero() || record.Duration <= 0 || len(record.Remote) == 0 || len(record.RequestID) == 0 {
+				t.Errorf("want the time, duration, remote and request ID to be set, got: %+v", record)
+			}
+			if record.Path != tc.path {
+				t.Errorf("path, want: %s, got: %s", tc.path, record.Path)
+			}
+			record.Time, record.Duration, record.Remote, record.RequestID, record.Path = tc.want.Time, 0, "", "", ""
+			if record != *tc.want {
+				t.Errorf("record, want: %+v, got: %+v", *tc.want, record)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright (c) OpenFaaS Author(s). All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package auth
+
+import "net/http"
+
+// Authenticator decorates the handlers of the system API so that only authenticated
+// requests reach them, i.e. by checking basic auth credentials or verifying a bearer token.
+type Authenticator interface {
+	Decorate(next http.HandlerFunc) http.HandlerFunc
+}
+
+// AuthenticatorFunc adapts an ordinary function to an Authenticator.
+type AuthenticatorFunc func(next http.HandlerFunc) http.HandlerFunc
+
+// Decorate calls f(next).
+func (f AuthenticatorFunc) Decorate(next http.HandlerFunc) http.HandlerFunc {
+	return f(next)
+}
+
+// MiddlewareAuthenticator adapts a middleware of the func(http.Handler) http.Handler shape,
+// as provided by most bearer token and OIDC libraries, to an Authenticator.
+type MiddlewareAuthenticator func(next http.Handler) http.Handler
+
+// Decorate wraps next with the middleware.
+func (m MiddlewareAuthenticator) Decorate(next http.HandlerFunc) http.HandlerFunc {
+	return m(next).ServeHTTP
+}
+
+// Decorate enforces basic auth with the credentials, see DecorateWithBasicAuth.
+func (c *BasicAuthCredentials) Decorate(next http.HandlerFunc) http.HandlerFunc {
+	return DecorateWithBasicAuth(next, c)
+}
@@ -0,0 +1,98 @@
+// Copyright (c) OpenFaaS Author(s). All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_BasicAuthCredentials_Authenticator(t *testing.T) {
+	var authenticator Authenticator = &BasicAuthCredentials{User: "admin", Password: "password"}
+
+	decorated := authenticator.Decorate(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", nil)
+	r.SetBasicAuth("admin", "wrong")
+	decorated.ServeHTTP(w, r)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status code, want: %d, got: %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func Test_AuthenticatorFunc(t *testing.T) {
+	bearer := AuthenticatorFunc(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ") != "token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next(w, r)
+		}
+	})
+
+	decorated := bearer.Decorate(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	testCases := []struct {
+		authorization string
+		wantCode      int
+	}{
+		{authorization: "Bearer token", wantCode: http.StatusOK},
+		{authorization: "Bearer other", wantCode: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", nil)
+		r.Header.Set("Authorization", tc.authorization)
+		decorated.ServeHTTP(w, r)
+
+		if w.Code != tc.wantCode {
+			t.Errorf("%s, status code, want: %d, got: %d", tc.authorization, tc.wantCode, w.Code)
+		}
+	}
+}
+
+func Test_MiddlewareAuthenticator(t *testing.T) {
+	var authenticator Authenticator = MiddlewareAuthenticator(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Api-Key") != "key" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	decorated := authenticator.Decorate(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	testCases := []struct {
+		key      string
+		wantCode int
+	}{
+		{key: "key", wantCode: http.StatusOK},
+		{key: "other", wantCode: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", nil)
+		r.Header.Set("X-Api-Key", tc.key)
+		decorated.ServeHTTP(w, r)
+
+		if w.Code != tc.wantCode {
+			t.Errorf("status code for %q, want: %d, got: %d", tc.key, tc.wantCode, w.Code)
+		}
+	}
+}
@@ -4,27 +4,62 @@
 package auth
 
 import (
+	"crypto/sha256"
 	"crypto/subtle"
 	"net/http"
+
+	"github.com/openfaas/faas-provider/httputil"
 )
 
+// CredentialSource returns the current basic auth credentials, which may change while the
+// server is running, see ReloadingCredentials.
+type CredentialSource interface {
+	Credentials() *BasicAuthCredentials
+}
+
 // DecorateWithBasicAuth enforces basic auth as a middleware with given credentials
 func DecorateWithBasicAuth(next http.HandlerFunc, credentials *BasicAuthCredentials) http.HandlerFunc {
+	return decorateWithBasicAuth(next, func() *BasicAuthCredentials {
+		return credentials
+	})
+}
+
+// DecorateWithCredentialSource enforces basic auth as a middleware with the credentials of
+// source, which are looked up for each request so that rotated credentials take effect
+// without a restart.
+func DecorateWithCredentialSource(next http.HandlerFunc, source CredentialSource) http.HandlerFunc {
+	return decorateWithBasicAuth(next, source.Credentials)
+}
+
+// decorateWithBasicAuth enforces basic auth with the credentials returned by get for each
+// request, so that they can be replaced while the server is running. The user is set as
+// the principal of the request, see PrincipalFromContext.
+func decorateWithBasicAuth(next http.HandlerFunc, get func() *BasicAuthCredentials) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		user, password, ok := r.BasicAuth()
 
-		const noMatch = 0
-		if !ok ||
-			user != credentials.User ||
-			subtle.ConstantTimeCompare([]byte(credentials.Password), []byte(password)) == noMatch {
-
+		if !ok || !credentialsMatch(user, password, get()) {
 			w.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
-			w.WriteHeader(http.StatusUnauthorized)
-			w.Write([]byte("invalid credentials"))
+			httputil.WriteErrorCode(w, r, http.StatusUnauthorized, httputil.Unauthorized, "invalid credentials")
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), user)))
 	}
 }
+
+// credentialsMatch compares user and password with the credentials in constant time. The
+// values are hashed first, so that the time taken does not depend on their lengths, and
+// both are always compared, so that a valid user can not be told apart from an invalid one.
+func credentialsMatch(user, password string, credentials *BasicAuthCredentials) bool {
+	gotUser := sha256.Sum256([]byte(user))
+	wantUser := sha256.Sum256([]byte(credentials.User))
+	gotPassword := sha256.Sum256([]byte(password))
+	wantPassword := sha256.Sum256([]byte(credentials.Password))
+
+	userMatch := subtle.ConstantTimeCompare(gotUser[:], wantUser[:])
+	passwordMatch := subtle.ConstantTimeCompare(gotPassword[:], wantPassword[:])
+
+	return userMatch&passwordMatch == 1
+}
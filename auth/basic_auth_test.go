@@ -79,3 +79,37 @@ func Test_AuthWithInvalidPassword_Gives403(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func Test_DecorateWithBasicAuth_ComparesCredentials(t *testing.T) {
+	credentials := &BasicAuthCredentials{User: "admin", Password: "password"}
+
+	testCases := []struct {
+		name     string
+		user     string
+		password string
+		wantCode int
+	}{
+		{name: "valid credentials", user: "admin", password: "password", wantCode: http.StatusOK},
+		{name: "wrong password of the same length", user: "admin", password: "passwore", wantCode: http.StatusUnauthorized},
+		{name: "wrong password of a different length", user: "admin", password: "pass", wantCode: http.StatusUnauthorized},
+		{name: "wrong user", user: "root", password: "password", wantCode: http.StatusUnauthorized},
+		{name: "wrong user of a different length", user: "administrator", password: "password", wantCode: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			decorated := DecorateWithBasicAuth(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}, credentials)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", nil)
+			r.SetBasicAuth(tc.user, tc.password)
+			decorated.ServeHTTP(w, r)
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ package auth
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path"
 	"strings"
 )
@@ -16,6 +17,8 @@ type BasicAuthCredentials struct {
 	Password string
 }
 
+// ReadBasicAuth reads BasicAuthCredentials from a source such as files in a mounted secret,
+// see ReadBasicAuthFromDisk, or environment variables, see ReadBasicAuthFromEnv.
 type ReadBasicAuth interface {
 	Read() (*BasicAuthCredentials, error)
 }
@@ -64,3 +67,39 @@ func (r *ReadBasicAuthFromDisk) Read() (*BasicAuthCredentials, error) {
 
 	return credentials, nil
 }
+
+// ReadBasicAuthFromEnv reads the credentials from the basic_auth_user and basic_auth_password
+// environment variables, or the variables named by UserEnv and PasswordEnv, for when no
+// secret is mounted, such as in local development or CI.
+type ReadBasicAuthFromEnv struct {
+	UserEnv string
+
+	PasswordEnv string
+}
+
+func (r *ReadBasicAuthFromEnv) Read() (*BasicAuthCredentials, error) {
+	userKey := "basic_auth_user"
+	if len(r.UserEnv) > 0 {
+		userKey = r.UserEnv
+	}
+
+	passwordKey := "basic_auth_password"
+	if len(r.PasswordEnv) > 0 {
+		passwordKey = r.PasswordEnv
+	}
+
+	user := strings.TrimSpace(os.Getenv(userKey))
+	if len(user) == 0 {
+		return nil, fmt.Errorf("unable to load %s from the environment", userKey)
+	}
+
+	password := strings.TrimSpace(os.Getenv(passwordKey))
+	if len(password) == 0 {
+		return nil, fmt.Errorf("unable to load %s from the environment", passwordKey)
+	}
+
+	return &BasicAuthCredentials{
+		User:     user,
+		Password: password,
+	}, nil
+}
@@ -62,3 +62,55 @@ func Test_ReadFromCustomLocation_DefaultNames(t *testing.T) {
 		t.Errorf("password, want: %s, got %s", passWant, creds.Password)
 	}
 }
+
+func Test_ReadFromEnv(t *testing.T) {
+	t.Setenv("basic_auth_user", "admin")
+	t.Setenv("basic_auth_password", "test1234\n")
+
+	reader := ReadBasicAuthFromEnv{}
+
+	creds, err := reader.Read()
+	if err != nil {
+		t.Fatalf("can't read credentials: %s", err.Error())
+	}
+
+	if creds.User != "admin" {
+		t.Errorf("user, want: %s, got %s", "admin", creds.User)
+	}
+	if creds.Password != "test1234" {
+		t.Errorf("password, want: %s, got %s", "test1234", creds.Password)
+	}
+}
+
+func Test_ReadFromEnv_CustomNames(t *testing.T) {
+	t.Setenv("gateway_user", "admin")
+	t.Setenv("gateway_password", "test1234")
+
+	reader := ReadBasicAuthFromEnv{
+		UserEnv:     "gateway_user",
+		PasswordEnv: "gateway_password",
+	}
+
+	creds, err := reader.Read()
+	if err != nil {
+		t.Fatalf("can't read credentials: %s", err.Error())
+	}
+
+	if creds.User != "admin" {
+		t.Errorf("user, want: %s, got %s", "admin", creds.User)
+	}
+	if creds.Password != "test1234" {
+		t.Errorf("password, want: %s, got %s", "test1234", creds.Password)
+	}
+}
+
+func Test_ReadFromEnv_Missing(t *testing.T) {
+	t.Setenv("basic_auth_user", "admin")
+	t.Setenv("basic_auth_password", "")
+
+	reader := ReadBasicAuthFromEnv{}
+
+	if _, err := reader.Read(); err == nil {
+		t.Errorf("want error for missing basic_auth_password")
+	}
+}
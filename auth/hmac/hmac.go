@@ -0,0 +1,305 @@
+// Copyright (c) OpenFaaS Author(s). All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+// Package hmac authenticates the requests sent to a provider by the gateway with a key
+// shared between them, so that a provider reachable by other nodes only serves the
+// gateway. Each request carries the time it was signed, a nonce, and an HMAC-SHA256 over
+// them, its method, path and body, which the provider checks with Verifier. A nonce is
+// required, and only accepted once, so that a captured request can not be replayed.
+package hmac
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openfaas/faas-provider/auth"
+	"github.com/openfaas/faas-provider/httputil"
+)
+
+const (
+	// SignatureHeader carries the signature of a request, as "sha256=" followed by hex.
+	SignatureHeader = "X-Provider-Signature"
+	// TimestampHeader carries the time a request was signed, in seconds since the Unix
+	// epoch.
+	TimestampHeader = "X-Provider-Timestamp"
+	// NonceHeader carries a random value which is unique to each request, so that the
+	// Verifier can reject a request which is sent again.
+	NonceHeader = "X-Provider-Nonce"
+
+	// GatewayPrincipal is the principal of a request signed with the key, see
+	// auth.PrincipalFromContext, as it can only have been sent by the gateway.
+	GatewayPrincipal = "gateway"
+
+	// DefaultKeyFilename is the file in the secret mount which holds the shared key.
+	DefaultKeyFilename = "provider-hmac-key"
+
+	// DefaultMaxSkew is how far the time a request was signed may be from the clock of
+	// the provider, when Verifier.MaxSkew is not set.
+	DefaultMaxSkew = 5 * time.Minute
+
+	// DefaultMaxBodyBytes is the largest body the Verifier reads to check a signature,
+	// when Verifier.MaxBodyBytes is not set.
+	DefaultMaxBodyBytes = 10 << 20
+)
+
+var (
+	// ErrMissingSignature is returned by Verify for a request without a signature or time.
+	ErrMissingSignature = errors.New("request is not signed")
+	// ErrMissingNonce is returned by Verify for a signed request without a nonce, unless
+	// Verifier.AllowMissingNonce is set.
+	ErrMissingNonce = errors.New("request signature has no nonce")
+	// ErrInvalidSignature is returned by Verify for a request whose signature does not
+	// match.
+	ErrInvalidSignature = errors.New("invalid request signature")
+	// ErrExpiredSignature is returned by Verify for a request signed too far from the
+	// current time.
+	ErrExpiredSignature = errors.New("request signature has expired")
+	// ErrReplayedSignature is returned by Verify for a request whose nonce was already
+	// accepted.
+	ErrReplayedSignature = errors.New("request has already been received")
+)
+
+// Sign returns the signature of a request for the SignatureHeader. The signed message is
+// the timestamp, the nonce when it is not empty, the method and the path with its query
+// string, each followed by a newline, then the body.
+func Sign(key []byte, timestamp int64, nonce, method, requestURI string, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%d\n", timestamp)
+	if len(nonce) > 0 {
+		fmt.Fprintf(mac, "%s\n", nonce)
+	}
+	fmt.Fprintf(mac, "%s\n%s\n", method, requestURI)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignRequest signs req with key at the current time and a random nonce, setting the
+// SignatureHeader, TimestampHeader and NonceHeader. The body is read and replaced, so that
+// it can still be sent.
+func SignRequest(req *http.Request, key []byte) error {
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return fmt.Errorf("unable to generate a nonce: %w", err)
+	}
+	nonce := hex.EncodeToString(random)
+
+	timestamp := time.Now().Unix()
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(NonceHeader, nonce)
+	req.Header.Set(SignatureHeader, Sign(key, timestamp, nonce, req.Method, req.URL.RequestURI(), body))
+	return nil
+}
+
+// ReadKey reads the shared key from filename in secretMountPath, or DefaultKeyFilename
+// when filename is empty. Whitespace around the key is trimmed.
+func ReadKey(secretMountPath, filename string) ([]byte, error) {
+	if len(filename) == 0 {
+		filename = DefaultKeyFilename
+	}
+
+	keyPath := path.Join(secretMountPath, filename)
+	key, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load %s: %w", keyPath, err)
+	}
+
+	key = bytes.TrimSpace(key)
+	if len(key) == 0 {
+		return nil, fmt.Errorf("unable to load %s: the key is empty", keyPath)
+	}
+	return key, nil
+}
+
+// Verifier checks the signature of requests signed with Key.
+type Verifier struct {
+	// Key is shared with the gateway.
+	Key []byte
+	// MaxSkew is how far the time a request was signed may be from the current time,
+	// either way, DefaultMaxSkew when 0.
+	MaxSkew time.Duration
+	// Exempt are paths which are served without a signature, such as health checks which
+	// are called by the orchestrator rather than the gateway.
+	Exempt []string
+	// ExemptPrefixes are served without a signature when their path starts with one of
+	// them, such as the invocation routes, whose bodies would otherwise be buffered in
+	// full, which breaks streaming.
+	ExemptPrefixes []string
+	// MaxBodyBytes is the largest body read to check a signature, a larger body is
+	// rejected with a 413. The default is DefaultMaxBodyBytes.
+	MaxBodyBytes int64
+	// AllowMissingNonce accepts requests signed without a nonce, by signers which predate
+	// it. Such a request can be replayed until its signature expires, after MaxSkew.
+	AllowMissingNonce bool
+
+	now    func() time.Time
+	nonces nonceCache
+}
+
+// NewVerifier creates a Verifier for key, exempting the paths given.
+func NewVerifier(key []byte, maxSkew time.Duration, exempt ...string) *Verifier {
+	return &Verifier{Key: key, MaxSkew: maxSkew, Exempt: exempt, now: time.Now}
+}
+
+// Verify checks that r was signed with the Key within MaxSkew of the current time, and
+// that its nonce was not accepted before. Requests without a nonce are rejected, unless
+// AllowMissingNonce is set. The body is read and replaced, so that it can still be read by
+// the handler.
+func (v *Verifier) Verify(r *http.Request) error {
+	signature, timestampValue := r.Header.Get(SignatureHeader), r.Header.Get(TimestampHeader)
+	if len(signature) == 0 || len(timestampValue) == 0 {
+		return ErrMissingSignature
+	}
+
+	timestamp, err := strconv.ParseInt(timestampValue, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	maxSkew := v.MaxSkew
+	if maxSkew <= 0 {
+		maxSkew = DefaultMaxSkew
+	}
+	now := time.Now
+	if v.now != nil {
+		now = v.now
+	}
+	if skew := now().Sub(time.Unix(timestamp, 0)); skew > maxSkew || skew < -maxSkew {
+		return ErrExpiredSignature
+	}
+
+	nonce := r.Header.Get(NonceHeader)
+	if len(nonce) == 0 && !v.AllowMissingNonce {
+		return ErrMissingNonce
+	}
+
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+
+	want := Sign(v.Key, timestamp, nonce, r.Method, r.URL.RequestURI(), body)
+	if !hmac.Equal([]byte(want), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+
+	// A nonce is remembered until its signature expires, after which a replay is rejected
+	// by its timestamp instead.
+	if len(nonce) > 0 && !v.nonces.add(nonce, time.Unix(timestamp, 0).Add(maxSkew), now()) {
+		return ErrReplayedSignature
+	}
+	return nil
+}
+
+// Middleware rejects requests with a 401 unless they are signed with the Key, apart from
+// those to the Exempt paths and ExemptPrefixes. A body larger than MaxBodyBytes is
+// rejected with a 413. GatewayPrincipal is set as the principal of a signed request.
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v.exempt(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		maxBodyBytes := v.MaxBodyBytes
+		if maxBodyBytes <= 0 {
+			maxBodyBytes = DefaultMaxBodyBytes
+		}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+			if _, ok := httputil.BufferBody(w, r); !ok {
+				return
+			}
+		}
+
+		if err := v.Verify(r); err != nil {
+			httputil.WriteErrorCode(w, r, http.StatusUnauthorized, httputil.Unauthorized, err.Error())
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), GatewayPrincipal)))
+	})
+}
+
+func (v *Verifier) exempt(requestPath string) bool {
+	for _, exempt := range v.Exempt {
+		if requestPath == exempt {
+			return true
+		}
+	}
+	for _, prefix := range v.ExemptPrefixes {
+		if strings.HasPrefix(requestPath, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// nonceCache holds the nonces accepted by a Verifier until they expire.
+type nonceCache struct {
+	mu        sync.Mutex
+	expiries  map[string]time.Time
+	nextPrune time.Time
+}
+
+// add records nonce until expiry, or returns false when it is already recorded.
+func (c *nonceCache) add(nonce string, expiry, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.expiries == nil {
+		c.expiries = map[string]time.Time{}
+	}
+	if now.After(c.nextPrune) {
+		for n, e := range c.expiries {
+			if now.After(e) {
+				delete(c.expiries, n)
+			}
+		}
+		c.nextPrune = now.Add(time.Minute)
+	}
+
+	if e, ok := c.expiries[nonce]; ok && !now.After(e) {
+		return false
+	}
+	c.expiries[nonce] = expiry
+	return true
+}
+
+// readBody reads the body of r and replaces it with a copy.
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if r.GetBody != nil {
+		r.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	return body, nil
+}
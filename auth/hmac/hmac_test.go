@@ -0,0 +1,180 @@
+// Copyright (c) OpenFaaS Author(s). All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package hmac
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Verify(t *testing.T) {
+	key := []byte("s3cret")
+	now := time.Unix(1700000000, 0)
+
+	nonces := 0
+	signed := func(method, target, body string, at time.Time, key []byte) *http.Request {
+		nonces++
+		nonce := "signed-" + strconv.Itoa(nonces)
+
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		r.Header.Set(TimestampHeader, strconv.FormatInt(at.Unix(), 10))
+		r.Header.Set(NonceHeader, nonce)
+		r.Header.Set(SignatureHeader, Sign(key, at.Unix(), nonce, method, r.URL.RequestURI(), []byte(body)))
+		return r
+	}
+
+	tamperedBody := signed(http.MethodPost, "/invoke/figlet", "hello", now, key)
+	tamperedBody.Body = io.NopCloser(strings.NewReader("goodbye"))
+
+	tamperedPath := signed(http.MethodPost, "/invoke/figlet", "hello", now, key)
+	tamperedPath.URL.Path = "/invoke/env"
+
+	withNonce := func(nonce string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/invoke/figlet", strings.NewReader("hello"))
+		r.Header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+		r.Header.Set(NonceHeader, nonce)
+		r.Header.Set(SignatureHeader, Sign(key, now.Unix(), nonce, http.MethodPost, r.URL.RequestURI(), []byte("hello")))
+		return r
+	}
+
+	tamperedNonce := withNonce("n2")
+	tamperedNonce.Header.Set(NonceHeader, "n3")
+
+	testCases := []struct {
+		name    string
+		r       *http.Request
+		wantErr error
+	}{
+		{name: "valid", r: signed(http.MethodPost, "/invoke/figlet?q=1", "hello", now, key)},
+		{name: "within the skew", r: signed(http.MethodGet, "/system/functions", "", now.Add(-4*time.Minute), key)},
+		{name: "unsigned", r: httptest.NewRequest(http.MethodGet, "/system/functions", nil), wantErr: ErrMissingSignature},
+		{name: "another key", r: signed(http.MethodPost, "/invoke/figlet", "hello", now, []byte("guess")), wantErr: ErrInvalidSignature},
+		{name: "tampered body", r: tamperedBody, wantErr: ErrInvalidSignature},
+		{name: "tampered path", r: tamperedPath, wantErr: ErrInvalidSignature},
+		{name: "nonce", r: withNonce("n1")},
+		{name: "replayed nonce", r: withNonce("n1"), wantErr: ErrReplayedSignature},
+		{name: "tampered nonce", r: tamperedNonce, wantErr: ErrInvalidSignature},
+		{name: "without a nonce", r: withNonce(""), wantErr: ErrMissingNonce},
+		{name: "expired", r: signed(http.MethodGet, "/system/functions", "", now.Add(-6*time.Minute), key), wantErr: ErrExpiredSignature},
+		{name: "from the future", r: signed(http.MethodGet, "/system/functions", "", now.Add(6*time.Minute), key), wantErr: ErrExpiredSignature},
+	}
+
+	v := NewVerifier(key, 0)
+	v.now = func() time.Time { return now }
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := v.Verify(tc.r)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("error, want: %v, got: %v", tc.wantErr, err)
+			}
+
+			if err == nil {
+				if body, _ := io.ReadAll(tc.r.Body); tc.r.Method == http.MethodPost && string(body) != "hello" {
+					t.Errorf("body, want: %q, got: %q", "hello", string(body))
+				}
+			}
+		})
+	}
+}
+
+func Test_Verify_AllowMissingNonce(t *testing.T) {
+	key := []byte("s3cret")
+	now := time.Unix(1700000000, 0)
+
+	v := NewVerifier(key, 0)
+	v.AllowMissingNonce = true
+	v.now = func() time.Time { return now }
+
+	// Without a nonce, the same request is accepted again until its signature expires.
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRequest(http.MethodGet, "/system/functions", nil)
+		r.Header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+		r.Header.Set(SignatureHeader, Sign(key, now.Unix(), "", http.MethodGet, r.URL.RequestURI(), nil))
+		if err := v.Verify(r); err != nil {
+			t.Fatalf("want no error, got: %s", err)
+		}
+	}
+}
+
+func Test_Middleware(t *testing.T) {
+	key := []byte("s3cret")
+	verifier := NewVerifier(key, time.Minute, "/healthz")
+	verifier.ExemptPrefixes = []string{"/function/"}
+	verifier.MaxBodyBytes = 8
+	handler := verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	for _, tc := range []struct {
+		path     string
+		body     string
+		sign     bool
+		wantCode int
+	}{
+		{path: "/invoke/figlet", sign: true, wantCode: http.StatusOK},
+		{path: "/invoke/figlet", wantCode: http.StatusUnauthorized},
+		{path: "/invoke/figlet", body: "hello, world", sign: true, wantCode: http.StatusRequestEntityTooLarge},
+		{path: "/healthz", wantCode: http.StatusOK},
+		{path: "/function/figlet", body: "hello, world", wantCode: http.StatusOK},
+	} {
+		body := tc.body
+		if len(body) == 0 {
+			body = "hello"
+		}
+		req, _ := http.NewRequest(http.MethodPost, server.URL+tc.path, strings.NewReader(body))
+		if tc.sign {
+			if err := SignRequest(req, key); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+
+		if res.StatusCode != tc.wantCode {
+			t.Errorf("%s: status code, want: %d, got: %d (%s)", tc.path, tc.wantCode, res.StatusCode, got)
+		}
+		if tc.wantCode == http.StatusOK && string(got) != body {
+			t.Errorf("%s: body, want: %q, got: %q", tc.path, body, string(got))
+		}
+	}
+}
+
+func Test_ReadKey(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, DefaultKeyFilename), []byte("s3cret\n"), 0600)
+	os.WriteFile(filepath.Join(dir, "empty"), []byte("\n"), 0600)
+
+	key, err := ReadKey(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(key) != "s3cret" {
+		t.Errorf("key, want: %q, got: %q", "s3cret", string(key))
+	}
+
+	if _, err := ReadKey(dir, "empty"); err == nil {
+		t.Errorf("want an error for an empty key")
+	}
+	if _, err := ReadKey(dir, "missing"); err == nil {
+		t.Errorf("want an error for a missing key")
+	}
+}
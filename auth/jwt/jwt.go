@@ -0,0 +1,495 @@
+// Copyright (c) OpenFaaS Author(s). All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+// Package jwt verifies the bearer tokens issued by an OIDC provider, or by the gateway, so
+// that they can authenticate requests to the system API in place of basic auth. Tokens
+// must be signed with RS256, RS384, RS512, ES256, ES384 or ES512 by a key published in the
+// issuer's JSON Web Key Set (JWKS).
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openfaas/faas-provider/auth"
+	"github.com/openfaas/faas-provider/httputil"
+)
+
+const (
+	// defaultRefreshInterval is how often the key set is fetched again.
+	defaultRefreshInterval = time.Hour
+
+	// minRefreshInterval limits how often the key set is fetched, whether the last fetch
+	// succeeded or not, so that tokens with made up key IDs can not be used to flood the
+	// issuer, and requests do not wait on an issuer which is down.
+	minRefreshInterval = time.Minute
+
+	// fetchTimeout bounds how long fetching the key set may take.
+	fetchTimeout = 10 * time.Second
+)
+
+// defaultClient fetches the key set when Verifier.Client is not set.
+var defaultClient = &http.Client{Timeout: fetchTimeout}
+
+// Claims are the registered claims of a verified token.
+type Claims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  Audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+	IssuedAt  int64    `json:"iat"`
+}
+
+// Audience is the "aud" claim, which may be a single string or a list of strings.
+type Audience []string
+
+// UnmarshalJSON accepts either a string or a list of strings.
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("aud must be a string or a list of strings")
+	}
+	*a = list
+	return nil
+}
+
+// Contains reports whether audience is one of the values of a.
+func (a Audience) Contains(audience string) bool {
+	for _, v := range a {
+		if v == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// Verifier verifies tokens against the key set at JWKSURL. It implements auth.Authenticator,
+// so it can be set as FaaSConfig.Authenticator.
+type Verifier struct {
+	// Issuer must equal the "iss" claim of every token, every token is rejected when it
+	// is empty.
+	Issuer string
+	// Audience must be one of the values of the "aud" claim of every token, every token is
+	// rejected when it is empty.
+	Audience string
+	// JWKSURL is the URL of the issuer's key set, i.e. the jwks_uri of its OIDC discovery document.
+	JWKSURL string
+	// RefreshInterval is how often the key set is fetched again, so that rotated keys are
+	// picked up, the default is one hour. A token signed by an unknown key also causes the
+	// key set to be fetched. The key set is fetched at most once a minute, and the last key
+	// set fetched is used until a fetch succeeds.
+	RefreshInterval time.Duration
+	// Leeway allows for clock skew when checking the "exp" and "nbf" claims.
+	Leeway time.Duration
+	// Client fetches the key set, the default is a client with a timeout of 10 seconds.
+	// A fetch is cancelled after 10 seconds regardless.
+	Client *http.Client
+
+	now func() time.Time
+
+	mu          sync.Mutex
+	keys        map[string]publicKey
+	attemptedAt time.Time
+	fetchErr    error
+	fetching    *fetch
+}
+
+// publicKey is a key of the key set, with the algorithm it is restricted to, if any.
+type publicKey struct {
+	key       crypto.PublicKey
+	algorithm string
+}
+
+// fetch is a fetch of the key set in flight, shared by every request which needs it.
+type fetch struct {
+	done chan struct{}
+	err  error
+}
+
+// Decorate rejects requests to next with a 401 unless they carry a valid bearer token in the
+// Authorization header. The claims of the token can be read in next with ClaimsFromContext,
+// and its subject is set as the principal of the request, see auth.PrincipalFromContext.
+func (v *Verifier) Decorate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || len(token) == 0 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="Restricted"`)
+			httputil.WriteErrorCode(w, r, http.StatusUnauthorized, httputil.Unauthorized, "a bearer token is required")
+			return
+		}
+
+		claims, err := v.Verify(r.Context(), token)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="Restricted", error="invalid_token"`)
+			httputil.WriteErrorCode(w, r, http.StatusUnauthorized, httputil.Unauthorized, "invalid token")
+			return
+		}
+
+		ctx := auth.WithPrincipal(r.Context(), claims.Subject)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, claimsKey{}, claims)))
+	}
+}
+
+type claimsKey struct{}
+
+// ClaimsFromContext returns the claims of the token verified by Decorate for a request.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// Verify checks the signature of token, then that it was issued by Issuer for Audience and
+// has not expired, and returns its claims.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	if len(v.Issuer) == 0 || len(v.Audience) == 0 {
+		return nil, errors.New("the verifier requires an Issuer and an Audience")
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("token must have three parts")
+	}
+
+	h := header{}
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+
+	hash, err := hashFor(h.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature: %w", err)
+	}
+
+	key, err := v.key(ctx, h.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(key.algorithm) > 0 && key.algorithm != h.Algorithm {
+		return nil, fmt.Errorf("algorithm %q does not match the algorithm %q of key %q", h.Algorithm, key.algorithm, h.KeyID)
+	}
+
+	hasher := hash.New()
+	hasher.Write([]byte(parts[0] + "." + parts[1]))
+	if err := verifySignature(h.Algorithm, key.key, hash, hasher.Sum(nil), signature); err != nil {
+		return nil, err
+	}
+
+	claims := &Claims{}
+	if err := decodeSegment(parts[1], claims); err != nil {
+		return nil, fmt.Errorf("invalid claims: %w", err)
+	}
+
+	if err := v.validate(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (v *Verifier) validate(claims *Claims) error {
+	if claims.Issuer != v.Issuer {
+		return fmt.Errorf("token was issued by %q, not %q", claims.Issuer, v.Issuer)
+	}
+
+	if !claims.Audience.Contains(v.Audience) {
+		return fmt.Errorf("token is not for the audience %q", v.Audience)
+	}
+
+	now := v.timeNow()
+	if claims.ExpiresAt == 0 {
+		return fmt.Errorf("token has no expiry")
+	}
+	if now.After(time.Unix(claims.ExpiresAt, 0).Add(v.Leeway)) {
+		return fmt.Errorf("token has expired")
+	}
+	if claims.NotBefore != 0 && now.Add(v.Leeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return fmt.Errorf("token is not valid yet")
+	}
+
+	return nil
+}
+
+func (v *Verifier) timeNow() time.Time {
+	if v.now != nil {
+		return v.now()
+	}
+	return time.Now()
+}
+
+// key returns the key for kid, fetching the key set when it is stale or does not have the
+// key. The key set is fetched outside of the lock, once for every request which needs it.
+func (v *Verifier) key(ctx context.Context, kid string) (publicKey, error) {
+	v.mu.Lock()
+	key, found := v.keys[kid]
+	if !v.stale(found) {
+		// Until a key set has been fetched, the error of the last fetch is returned.
+		err := v.fetchErr
+		if v.keys != nil {
+			err = nil
+		}
+		v.mu.Unlock()
+		if !found {
+			if err != nil {
+				return publicKey{}, err
+			}
+			return publicKey{}, fmt.Errorf("unknown key %q", kid)
+		}
+		return key, nil
+	}
+
+	f := v.fetching
+	if f == nil {
+		f = &fetch{done: make(chan struct{})}
+		v.fetching = f
+		go v.refresh(f)
+	}
+	v.mu.Unlock()
+
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		return publicKey{}, ctx.Err()
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	// The keys already fetched are used while the issuer is unavailable.
+	key, found = v.keys[kid]
+	if !found {
+		if f.err != nil {
+			return publicKey{}, f.err
+		}
+		return publicKey{}, fmt.Errorf("unknown key %q", kid)
+	}
+	return key, nil
+}
+
+// stale reports whether the key set must be fetched, found is whether it has the key of
+// the token. v.mu must be held.
+func (v *Verifier) stale(found bool) bool {
+	refresh := v.RefreshInterval
+	if refresh <= 0 {
+		refresh = defaultRefreshInterval
+	}
+
+	if v.attemptedAt.IsZero() {
+		return true
+	}
+
+	since := v.timeNow().Sub(v.attemptedAt)
+	if since <= minRefreshInterval {
+		return false
+	}
+	return v.fetchErr != nil || !found || since > refresh
+}
+
+// refresh fetches the key set for f, it is not bound to the context of the request which
+// started it, as other requests wait for it too.
+func (v *Verifier) refresh(f *fetch) {
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+
+	keys, err := v.fetchKeys(ctx)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if err == nil {
+		v.keys = keys
+	}
+	v.attemptedAt = v.timeNow()
+	v.fetchErr = err
+	f.err = err
+	v.fetching = nil
+	close(f.done)
+}
+
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	Alg     string `json:"alg"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+func (v *Verifier) fetchKeys(ctx context.Context) (map[string]publicKey, error) {
+	client := v.Client
+	if client == nil {
+		client = defaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.JWKSURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch JWKS: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch JWKS: unexpected status code %d", res.StatusCode)
+	}
+
+	set := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{}
+	if err := json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("unable to decode JWKS: %w", err)
+	}
+
+	keys := map[string]publicKey{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			// Keys of other types, such as symmetric keys, are skipped.
+			continue
+		}
+		keys[k.KeyID] = publicKey{key: key, algorithm: k.Alg}
+	}
+
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curve, ok := curves[k.Curve]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+	}
+}
+
+// curves are the named curves of EC keys in a JWKS.
+var curves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+// algorithmCurves are the curves each ECDSA algorithm signs with.
+var algorithmCurves = map[string]elliptic.Curve{
+	"ES256": elliptic.P256(),
+	"ES384": elliptic.P384(),
+	"ES512": elliptic.P521(),
+}
+
+func hashFor(algorithm string) (crypto.Hash, error) {
+	switch algorithm {
+	case "RS256", "ES256":
+		return crypto.SHA256, nil
+	case "RS384", "ES384":
+		return crypto.SHA384, nil
+	case "RS512", "ES512":
+		return crypto.SHA512, nil
+	default:
+		return 0, fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+}
+
+func verifySignature(algorithm string, key crypto.PublicKey, hash crypto.Hash, digest, signature []byte) error {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(algorithm, "RS") {
+			return fmt.Errorf("algorithm %q does not match an RSA key", algorithm)
+		}
+		if err := rsa.VerifyPKCS1v15(k, hash, digest, signature); err != nil {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		if curve, ok := algorithmCurves[algorithm]; !ok || curve != k.Curve {
+			return fmt.Errorf("algorithm %q does not match an EC key on %s", algorithm, k.Curve.Params().Name)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid signature")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported key")
+	}
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
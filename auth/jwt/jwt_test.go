@@ -0,0 +1,399 @@
+// Copyright (c) OpenFaaS Author(s). All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-provider/auth"
+)
+
+type testKeys struct {
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKeys{rsa: rsaKey, ec: ecKey}
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// jwks serves the public keys, counting the requests it receives.
+func (k testKeys) jwks(t *testing.T, fetches *int32) *httptest.Server {
+	t.Helper()
+
+	set := map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA", "kid": "rsa", "use": "sig",
+				"n": encode(k.rsa.N.Bytes()),
+				"e": encode(big.NewInt(int64(k.rsa.E)).Bytes()),
+			},
+			{
+				"kty": "RSA", "kid": "rsa-384", "use": "sig", "alg": "RS384",
+				"n": encode(k.rsa.N.Bytes()),
+				"e": encode(big.NewInt(int64(k.rsa.E)).Bytes()),
+			},
+			{
+				"kty": "EC", "kid": "ec", "crv": "P-256",
+				"x": encode(k.ec.X.FillBytes(make([]byte, 32))),
+				"y": encode(k.ec.Y.FillBytes(make([]byte, 32))),
+			},
+		},
+	}
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(fetches, 1)
+		json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (k testKeys) sign(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	t.Helper()
+
+	h, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	c, _ := json.Marshal(claims)
+	signed := encode(h) + "." + encode(c)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch alg {
+	case "RS256":
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k.rsa, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+	case "ES256", "ES384":
+		hashed := digest[:]
+		if alg == "ES384" {
+			sum := sha512.Sum384([]byte(signed))
+			hashed = sum[:]
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, k.ec, hashed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+
+	return signed + "." + encode(signature)
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss": "https://issuer.example.com",
+		"sub": "alice",
+		"aud": "openfaas",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func Test_Verifier_Verify(t *testing.T) {
+	keys := newTestKeys(t)
+	var fetches int32
+	s := keys.jwks(t, &fetches)
+
+	with := func(key string, value interface{}) map[string]interface{} {
+		c := validClaims()
+		if value == nil {
+			delete(c, key)
+		} else {
+			c[key] = value
+		}
+		return c
+	}
+
+	cases := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"RS256", keys.sign(t, "RS256", "rsa", validClaims()), false},
+		{"ES256", keys.sign(t, "ES256", "ec", validClaims()), false},
+		{"audience in a list", keys.sign(t, "RS256", "rsa", with("aud", []string{"other", "openfaas"})), false},
+		{"wrong issuer", keys.sign(t, "RS256", "rsa", with("iss", "https://evil.example.com")), true},
+		{"wrong audience", keys.sign(t, "RS256", "rsa", with("aud", "other")), true},
+		{"expired", keys.sign(t, "RS256", "rsa", with("exp", time.Now().Add(-time.Hour).Unix())), true},
+		{"no expiry", keys.sign(t, "RS256", "rsa", with("exp", nil)), true},
+		{"not valid yet", keys.sign(t, "RS256", "rsa", with("nbf", time.Now().Add(time.Hour).Unix())), true},
+		{"unknown key", keys.sign(t, "RS256", "missing", validClaims()), true},
+		{"algorithm does not match key", keys.sign(t, "ES256", "rsa", validClaims()), true},
+		{"algorithm does not match alg of key", keys.sign(t, "RS256", "rsa-384", validClaims()), true},
+		{"algorithm does not match curve of key", keys.sign(t, "ES384", "ec", validClaims()), true},
+		{"alg none", encode([]byte(`{"alg":"none","kid":"rsa"}`)) + "." + encode([]byte(`{}`)) + ".", true},
+		{"malformed", "not-a-token", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := &Verifier{Issuer: "https://issuer.example.com", Audience: "openfaas", JWKSURL: s.URL}
+
+			claims, err := v.Verify(context.Background(), tc.token)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("want error, got claims: %+v", claims)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want no error, got: %s", err)
+			}
+			if claims.Subject != "alice" {
+				t.Errorf("subject, want: %s, got: %s", "alice", claims.Subject)
+			}
+		})
+	}
+}
+
+func Test_Verifier_Verify_TamperedClaims(t *testing.T) {
+	keys := newTestKeys(t)
+	var fetches int32
+	s := keys.jwks(t, &fetches)
+
+	token := keys.sign(t, "RS256", "rsa", validClaims())
+	other := keys.sign(t, "RS256", "rsa", map[string]interface{}{
+		"iss": "https://issuer.example.com", "sub": "admin", "aud": "openfaas",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+
+	// Use the signature of one token with the claims of the other.
+	tampered := token[:len(token)-len(signatureOf(token))] + signatureOf(other)
+
+	v := &Verifier{Issuer: "https://issuer.example.com", Audience: "openfaas", JWKSURL: s.URL}
+	if _, err := v.Verify(context.Background(), tampered); err == nil {
+		t.Errorf("want error for a tampered token")
+	}
+}
+
+func Test_Verifier_RequiresIssuerAndAudience(t *testing.T) {
+	keys := newTestKeys(t)
+	var fetches int32
+	s := keys.jwks(t, &fetches)
+
+	token := keys.sign(t, "RS256", "rsa", validClaims())
+	for _, v := range []*Verifier{
+		{Audience: "openfaas", JWKSURL: s.URL},
+		{Issuer: "https://issuer.example.com", JWKSURL: s.URL},
+	} {
+		if _, err := v.Verify(context.Background(), token); err == nil {
+			t.Errorf("want error for a verifier without an Issuer or Audience, got none for %+v", v)
+		}
+	}
+	if got := atomic.LoadInt32(&fetches); got != 0 {
+		t.Errorf("fetches, want: %d, got: %d", 0, got)
+	}
+}
+
+func Test_Verifier_FetchesKeysOnce(t *testing.T) {
+	keys := newTestKeys(t)
+	var fetches int32
+	s := keys.jwks(t, &fetches)
+
+	v := &Verifier{Issuer: "https://issuer.example.com", Audience: "openfaas", JWKSURL: s.URL}
+	token := keys.sign(t, "RS256", "rsa", validClaims())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := v.Verify(context.Background(), token); err != nil {
+				t.Errorf("want no error, got: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&fetches); got != 1 {
+		t.Errorf("fetches, want: %d, got: %d", 1, got)
+	}
+}
+
+func signatureOf(token string) string {
+	for i := len(token) - 1; i >= 0; i-- {
+		if token[i] == '.' {
+			return token[i+1:]
+		}
+	}
+	return ""
+}
+
+func Test_Verifier_RefreshesKeys(t *testing.T) {
+	keys := newTestKeys(t)
+	var fetches int32
+	s := keys.jwks(t, &fetches)
+
+	now := time.Now()
+	v := &Verifier{
+		Issuer:          "https://issuer.example.com",
+		Audience:        "openfaas",
+		JWKSURL:         s.URL,
+		RefreshInterval: 10 * time.Minute,
+		now:             func() time.Time { return now },
+	}
+
+	token := keys.sign(t, "RS256", "rsa", validClaims())
+	for i := 0; i < 3; i++ {
+		if _, err := v.Verify(context.Background(), token); err != nil {
+			t.Fatalf("want no error, got: %s", err)
+		}
+	}
+	if got := atomic.LoadInt32(&fetches); got != 1 {
+		t.Errorf("fetches, want: %d, got: %d", 1, got)
+	}
+
+	// An unknown key ID does not refetch the key set until the minimum interval has passed.
+	unknown := keys.sign(t, "RS256", "rotated", validClaims())
+	v.Verify(context.Background(), unknown)
+	if got := atomic.LoadInt32(&fetches); got != 1 {
+		t.Errorf("fetches after unknown key, want: %d, got: %d", 1, got)
+	}
+
+	now = now.Add(2 * time.Minute)
+	v.Verify(context.Background(), unknown)
+	if got := atomic.LoadInt32(&fetches); got != 2 {
+		t.Errorf("fetches after unknown key and minimum interval, want: %d, got: %d", 2, got)
+	}
+
+	now = now.Add(11 * time.Minute)
+	v.Verify(context.Background(), token)
+	if got := atomic.LoadInt32(&fetches); got != 3 {
+		t.Errorf("fetches after refresh interval, want: %d, got: %d", 3, got)
+	}
+}
+
+func Test_Verifier_BacksOffAfterFailedFetch(t *testing.T) {
+	keys := newTestKeys(t)
+	var fetches int32
+	good := keys.jwks(t, &fetches)
+
+	var failing int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			atomic.AddInt32(&fetches, 1)
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		good.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer s.Close()
+
+	now := time.Now()
+	v := &Verifier{
+		Issuer:          "https://issuer.example.com",
+		Audience:        "openfaas",
+		JWKSURL:         s.URL,
+		RefreshInterval: 10 * time.Minute,
+		now:             func() time.Time { return now },
+	}
+
+	token := keys.sign(t, "RS256", "rsa", validClaims())
+	if _, err := v.Verify(context.Background(), token); err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+
+	// The last key set fetched is used when the issuer fails, and neither a valid token nor
+	// an unknown key ID fetches it again until the minimum interval has passed.
+	atomic.StoreInt32(&failing, 1)
+	now = now.Add(11 * time.Minute)
+	unknown := keys.sign(t, "RS256", "made-up", validClaims())
+	for i := 0; i < 3; i++ {
+		if _, err := v.Verify(context.Background(), token); err != nil {
+			t.Fatalf("want no error, got: %s", err)
+		}
+		v.Verify(context.Background(), unknown)
+	}
+	if got := atomic.LoadInt32(&fetches); got != 2 {
+		t.Errorf("fetches after failure, want: %d, got: %d", 2, got)
+	}
+
+	now = now.Add(2 * time.Minute)
+	v.Verify(context.Background(), unknown)
+	if got := atomic.LoadInt32(&fetches); got != 3 {
+		t.Errorf("fetches after failure and minimum interval, want: %d, got: %d", 3, got)
+	}
+}
+
+func Test_Verifier_Decorate(t *testing.T) {
+	keys := newTestKeys(t)
+	var fetches int32
+	s := keys.jwks(t, &fetches)
+
+	v := &Verifier{Issuer: "https://issuer.example.com", Audience: "openfaas", JWKSURL: s.URL}
+	var subject, principal string
+	handler := v.Decorate(func(w http.ResponseWriter, r *http.Request) {
+		if claims, ok := ClaimsFromContext(r.Context()); ok {
+			subject = claims.Subject
+		}
+		principal, _ = auth.PrincipalFromContext(r.Context())
+	})
+
+	cases := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"basic auth", "Basic YWRtaW46c2VjcmV0", http.StatusUnauthorized},
+		{"invalid token", "Bearer not-a-token", http.StatusUnauthorized},
+		{"valid token", "Bearer " + keys.sign(t, "RS256", "rsa", validClaims()), http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/system/functions", nil)
+			if tc.authorization != "" {
+				r.Header.Set("Authorization", tc.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tc.wantStatus {
+				t.Errorf("status code, want: %d, got: %d", tc.wantStatus, w.Code)
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("want WWW-Authenticate header on 401")
+			}
+		})
+	}
+
+	if subject != "alice" {
+		t.Errorf("subject from context, want: %s, got: %s", "alice", subject)
+	}
+	if principal != "alice" {
+		t.Errorf("principal from context, want: %s, got: %s", "alice", principal)
+	}
+}
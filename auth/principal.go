@@ -0,0 +1,21 @@
+// Copyright (c) OpenFaaS Author(s). All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package auth
+
+import "context"
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying principal, the name of the caller verified by
+// an Authenticator, such as the basic auth user or the subject of a bearer token.
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal set with WithPrincipal, ok is false when the
+// request was not authenticated by an Authenticator which sets one.
+func PrincipalFromContext(ctx context.Context) (principal string, ok bool) {
+	principal, ok = ctx.Value(principalKey{}).(string)
+	return principal, ok
+}
@@ -0,0 +1,101 @@
+// Copyright (c) OpenFaaS Author(s). All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/openfaas/faas-provider/logging"
+)
+
+// ReloadingCredentials holds BasicAuthCredentials which are read again from a ReadBasicAuth
+// by Reload, or periodically by Watch, so that a rotated secret is picked up without a
+// restart. The user and password are swapped together, so a request always sees a
+// consistent pair.
+type ReloadingCredentials struct {
+	reader ReadBasicAuth
+
+	mu          sync.RWMutex
+	credentials *BasicAuthCredentials
+}
+
+// NewReloadingCredentials reads the credentials from reader for the first time.
+func NewReloadingCredentials(reader ReadBasicAuth) (*ReloadingCredentials, error) {
+	c := &ReloadingCredentials{reader: reader}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload reads the credentials again, the current credentials are kept when they can not be read.
+func (c *ReloadingCredentials) Reload() error {
+	_, err := c.reload()
+	return err
+}
+
+// reload reads the credentials again, the bool is true when they changed.
+func (c *ReloadingCredentials) reload() (bool, error) {
+	credentials, err := c.reader.Read()
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	changed := c.credentials == nil || *c.credentials != *credentials
+	c.credentials = credentials
+	return changed, nil
+}
+
+// Credentials returns the current credentials, which must not be modified.
+func (c *ReloadingCredentials) Credentials() *BasicAuthCredentials {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.credentials
+}
+
+// Watch calls Reload every interval, and each time the process receives SIGHUP, until
+// ctx is done, so that a rotated secret can be picked up at once without waiting for the
+// interval. Errors are logged and the current credentials kept, so that a secret caught
+// part way through being rotated does not lock out every client.
+func (c *ReloadingCredentials) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	logger := logging.FromContext(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-hup:
+		}
+
+		changed, err := c.reload()
+		if err != nil {
+			logger.Warn("Unable to reload basic auth credentials", "error", err)
+			continue
+		}
+		if changed {
+			logger.Info("Reloaded basic auth credentials")
+		}
+	}
+}
+
+// Decorate enforces basic auth with the current credentials, see DecorateWithCredentialSource.
+func (c *ReloadingCredentials) Decorate(next http.HandlerFunc) http.HandlerFunc {
+	return DecorateWithCredentialSource(next, c)
+}
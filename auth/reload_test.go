@@ -0,0 +1,164 @@
+// Copyright (c) OpenFaaS Author(s). All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"path"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func writeCredentials(t *testing.T, dir, user, password string) {
+	t.Helper()
+
+	if err := os.WriteFile(path.Join(dir, "basic-auth-user"), []byte(user), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path.Join(dir, "basic-auth-password"), []byte(password), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func statusWithPassword(handler http.HandlerFunc, user, password string) int {
+	r := httptest.NewRequest(http.MethodGet, "/system/functions", nil)
+	r.SetBasicAuth(user, password)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w.Code
+}
+
+func Test_ReloadingCredentials_Reload(t *testing.T) {
+	dir := t.TempDir()
+	writeCredentials(t, dir, "admin", "old-password")
+
+	credentials, err := NewReloadingCredentials(&ReadBasicAuthFromDisk{SecretMountPath: dir})
+	if err != nil {
+		t.Fatalf("can't read secrets: %s", err)
+	}
+
+	handler := credentials.Decorate(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	if got := statusWithPassword(handler, "admin", "old-password"); got != http.StatusOK {
+		t.Errorf("old password before reload, want: %d, got: %d", http.StatusOK, got)
+	}
+
+	writeCredentials(t, dir, "admin", "new-password")
+	if err := credentials.Reload(); err != nil {
+		t.Fatalf("can't reload secrets: %s", err)
+	}
+
+	if got := statusWithPassword(handler, "admin", "new-password"); got != http.StatusOK {
+		t.Errorf("new password after reload, want: %d, got: %d", http.StatusOK, got)
+	}
+	if got := statusWithPassword(handler, "admin", "old-password"); got != http.StatusUnauthorized {
+		t.Errorf("old password after reload, want: %d, got: %d", http.StatusUnauthorized, got)
+	}
+}
+
+func Test_ReloadingCredentials_KeepsCredentialsOnError(t *testing.T) {
+	dir := t.TempDir()
+	writeCredentials(t, dir, "admin", "password")
+
+	credentials, err := NewReloadingCredentials(&ReadBasicAuthFromDisk{SecretMountPath: dir})
+	if err != nil {
+		t.Fatalf("can't read secrets: %s", err)
+	}
+
+	os.Remove(path.Join(dir, "basic-auth-password"))
+	if err := credentials.Reload(); err == nil {
+		t.Errorf("want error for missing password")
+	}
+
+	if got := credentials.Credentials().Password; got != "password" {
+		t.Errorf("password, want: %s, got: %s", "password", got)
+	}
+}
+
+func Test_ReloadingCredentials_Watch(t *testing.T) {
+	dir := t.TempDir()
+	writeCredentials(t, dir, "admin", "old-password")
+
+	credentials, err := NewReloadingCredentials(&ReadBasicAuthFromDisk{SecretMountPath: dir})
+	if err != nil {
+		t.Fatalf("can't read secrets: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go credentials.Watch(ctx, 10*time.Millisecond)
+
+	writeCredentials(t, dir, "admin", "new-password")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for credentials.Credentials().Password != "new-password" {
+		if time.Now().After(deadline) {
+			t.Fatalf("credentials were not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func Test_ReloadingCredentials_WatchReloadsOnSIGHUP(t *testing.T) {
+	dir := t.TempDir()
+	writeCredentials(t, dir, "admin", "old-password")
+
+	credentials, err := NewReloadingCredentials(&ReadBasicAuthFromDisk{SecretMountPath: dir})
+	if err != nil {
+		t.Fatalf("can't read secrets: %s", err)
+	}
+
+	// SIGHUP terminates the process without a handler, until Watch has installed its own.
+	guard := make(chan os.Signal, 1)
+	signal.Notify(guard, syscall.SIGHUP)
+	defer signal.Stop(guard)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go credentials.Watch(ctx, time.Hour)
+
+	writeCredentials(t, dir, "admin", "new-password")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for credentials.Credentials().Password != "new-password" {
+		if time.Now().After(deadline) {
+			t.Fatalf("credentials were not reloaded on SIGHUP")
+		}
+		// Watch may not have installed its handler yet, so the signal is sent until it has.
+		syscall.Kill(os.Getpid(), syscall.SIGHUP)
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func Test_DecorateWithCredentialSource_UsesCurrentCredentials(t *testing.T) {
+	dir := t.TempDir()
+	writeCredentials(t, dir, "admin", "old-password")
+
+	credentials, err := NewReloadingCredentials(&ReadBasicAuthFromDisk{SecretMountPath: dir})
+	if err != nil {
+		t.Fatalf("can't read secrets: %s", err)
+	}
+
+	handler := DecorateWithCredentialSource(func(w http.ResponseWriter, r *http.Request) {}, credentials)
+
+	writeCredentials(t, dir, "admin", "new-password")
+	if err := credentials.Reload(); err != nil {
+		t.Fatalf("can't reload secrets: %s", err)
+	}
+
+	if got := statusWithPassword(handler, "admin", "old-password"); got != http.StatusUnauthorized {
+		t.Errorf("old password, want: %d, got: %d", http.StatusUnauthorized, got)
+	}
+	if got := statusWithPassword(handler, "admin", "new-password"); got != http.StatusOK {
+		t.Errorf("new password, want: %d, got: %d", http.StatusOK, got)
+	}
+}
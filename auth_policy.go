@@ -0,0 +1,33 @@
+package bootstrap
+
+import (
+	"net/http"
+
+	"github.com/openfaas/faas-provider/auth"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// decorateWithAuthPolicy authenticates requests to next according to policy. With
+// types.AuthOptional only requests which carry an Authorization header are checked, so
+// that anonymous callers are served but wrong credentials are still rejected.
+func decorateWithAuthPolicy(next http.HandlerFunc, authenticator auth.Authenticator, policy types.AuthPolicy) http.HandlerFunc {
+	if authenticator == nil || next == nil {
+		return next
+	}
+
+	switch policy {
+	case types.AuthRequired:
+		return authenticator.Decorate(next)
+	case types.AuthOptional:
+		authenticated := authenticator.Decorate(next)
+		return func(w http.ResponseWriter, r *http.Request) {
+			if len(r.Header.Get("Authorization")) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			authenticated.ServeHTTP(w, r)
+		}
+	default:
+		return next
+	}
+}
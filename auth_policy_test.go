@@ -0,0 +1,87 @@
+package bootstrap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openfaas/faas-provider/auth"
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_decorateWithAuthPolicy(t *testing.T) {
+	credentials := &auth.BasicAuthCredentials{User: "admin", Password: "secret"}
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	cases := []struct {
+		name       string
+		policy     types.AuthPolicy
+		user       string
+		password   string
+		wantStatus int
+	}{
+		{name: "required without credentials", policy: types.AuthRequired, wantStatus: http.StatusUnauthorized},
+		{name: "required with wrong credentials", policy: types.AuthRequired, user: "admin", password: "wrong", wantStatus: http.StatusUnauthorized},
+		{name: "required with credentials", policy: types.AuthRequired, user: "admin", password: "secret", wantStatus: http.StatusOK},
+		{name: "optional without credentials", policy: types.AuthOptional, wantStatus: http.StatusOK},
+		{name: "optional with wrong credentials", policy: types.AuthOptional, user: "admin", password: "wrong", wantStatus: http.StatusUnauthorized},
+		{name: "optional with credentials", policy: types.AuthOptional, user: "admin", password: "secret", wantStatus: http.StatusOK},
+		{name: "none with wrong credentials", policy: types.AuthNone, user: "admin", password: "wrong", wantStatus: http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := decorateWithAuthPolicy(ok, credentials, tc.policy)
+
+			r := httptest.NewRequest(http.MethodPost, "/danger/kill", nil)
+			if len(tc.user) > 0 {
+				r.SetBasicAuth(tc.user, tc.password)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tc.wantStatus {
+				t.Errorf("status code, want: %d, got: %d", tc.wantStatus, w.Code)
+			}
+		})
+	}
+}
+
+func Test_Server_AuthPolicies(t *testing.T) {
+	handlers := validHandlers()
+	ok := handlers.Info
+	handlers.KillAllInstance = ok
+	handlers.ListCheckpoint = ok
+	handlers.AsyncFunction = ok
+
+	srv, err := NewHTTPServer(handlers, &types.FaaSConfig{
+		Authenticator: &auth.BasicAuthCredentials{User: "admin", Password: "secret"},
+		AuthPolicies:  map[string]types.AuthPolicy{types.KillRoute: types.AuthRequired, types.InvokeRoute: types.AuthRequired},
+
+		KillConfirmationToken: "s3cr3t",
+	})
+	if err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+
+	cases := []struct {
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{http.MethodPost, "/danger/kill", http.StatusUnauthorized},
+		{http.MethodGet, "/system/checkpoints", http.StatusOK},
+		{http.MethodPost, "/async-function/figlet", http.StatusUnauthorized},
+		{http.MethodGet, "/system/functions", http.StatusUnauthorized},
+	}
+
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+		if w.Code != tc.wantStatus {
+			t.Errorf("%s %s status code, want: %d, got: %d", tc.method, tc.path, tc.wantStatus, w.Code)
+		}
+	}
+}
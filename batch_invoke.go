@@ -0,0 +1,235 @@
+package bootstrap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// batchItem is one invocation of a batch, with the body and Content-Type it is sent with.
+type batchItem struct {
+	contentType string
+	body        []byte
+}
+
+// batchInvoker fans the items of a batch out to the handler which invokes a function,
+// at most parallelism at a time. Each item is sent as a POST to prefix and the name of
+// the function, i.e. "/invoke/figlet", with the headers and query string of the batch.
+// When limiter is set, each item is charged to the rate limit of the function, and an item
+// over the limit is answered with a 429 without being invoked.
+type batchInvoker struct {
+	invoke      http.HandlerFunc
+	prefix      string
+	parallelism int
+	logger      *slog.Logger
+	limiter     *invokeRateLimiter
+}
+
+func newBatchInvoker(invoke http.HandlerFunc, prefix string, parallelism int, logger *slog.Logger) *batchInvoker {
+	return &batchInvoker{invoke: invoke, prefix: prefix, parallelism: parallelism, logger: logger}
+}
+
+// handler reads the whole batch, then writes a BatchResult for each item as a line of
+// JSON as soon as it and the items before it have completed. A batch which can not be
+// read is rejected before any item is invoked.
+func (b *batchInvoker) handler(w http.ResponseWriter, r *http.Request) {
+	name := httputil.PathVar(r, "name")
+
+	items, status, err := readBatch(r)
+	if err != nil {
+		code := httputil.InvalidRequest
+		switch status {
+		case http.StatusRequestEntityTooLarge:
+			code = httputil.RequestTooLarge
+		case http.StatusUnsupportedMediaType:
+			code = httputil.UnsupportedMediaType
+		}
+		httputil.WriteErrorCode(w, r, status, code, err.Error())
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		httputil.WriteErrorCode(w, r, http.StatusInternalServerError, httputil.InternalError, "streaming is not supported by the response writer")
+		return
+	}
+
+	results := make([]chan types.BatchResult, len(items))
+	for i := range results {
+		results[i] = make(chan types.BatchResult, 1)
+	}
+
+	ctx := r.Context()
+	go func() {
+		sem := make(chan struct{}, b.parallelism)
+		for i, item := range items {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				for j := i; j < len(items); j++ {
+					results[j] <- types.BatchResult{Index: j, Error: ctx.Err().Error()}
+				}
+				return
+			}
+
+			go func(i int, item batchItem) {
+				defer func() { <-sem }()
+				results[i] <- b.run(r, name, i, item)
+			}(i, item)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	for _, result := range results {
+		if err := encoder.Encode(<-result); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// run invokes the function with item, recovering from a panic in the handler, as it is
+// not served on the goroutine of the request.
+func (b *batchInvoker) run(batch *http.Request, name string, index int, item batchItem) (result types.BatchResult) {
+	result.Index = index
+
+	if b.limiter != nil {
+		if _, _, _, allowed := b.limiter.take(name); !allowed {
+			result.Status = http.StatusTooManyRequests
+			result.Error = "rate limit exceeded for " + name
+			return result
+		}
+	}
+
+	req, err := http.NewRequestWithContext(batch.Context(), http.MethodPost, b.prefix+name, bytes.NewReader(item.body))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	req.URL.RawQuery = batch.URL.RawQuery
+	req.Host = batch.Host
+	req.RemoteAddr = batch.RemoteAddr
+	req.Header = batch.Header.Clone()
+	req.Header.Del("Content-Length")
+	req.Header.Del("Content-Type")
+	if len(item.contentType) > 0 {
+		req.Header.Set("Content-Type", item.contentType)
+	}
+	req = httputil.WithRoute(req, b.prefix+"{name:["+NameExpression+"]+}", map[string]string{"name": name})
+
+	bw := httputil.NewBufferedResponseWriter()
+	start := time.Now()
+	defer func() {
+		result.Latency = time.Since(start).Seconds()
+
+		// http.ErrAbortHandler is panicked again by recoverPanics, such as by a proxy
+		// whose upstream fails mid-response, which would otherwise stop the process.
+		if v := recover(); v != nil {
+			result.Status = http.StatusBadGateway
+			result.Error = fmt.Sprint(v)
+		}
+	}()
+
+	recoverPanics(b.logger)(b.invoke).ServeHTTP(bw, req)
+
+	result.Status = bw.Status()
+	result.ContentType = bw.Header().Get("Content-Type")
+	result.Body = bw.Body()
+	return result
+}
+
+// readBatch reads the items of a batch, along with the status to reject it with when it
+// can not be read.
+func readBatch(r *http.Request) ([]batchItem, int, error) {
+	contentType := r.Header.Get("Content-Type")
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+
+	var items []batchItem
+	switch mediaType {
+	case "application/x-ndjson":
+		items, err = readNDJSONBatch(r.Body)
+	case "multipart/form-data", "multipart/mixed":
+		if len(params["boundary"]) == 0 {
+			return nil, http.StatusBadRequest, fmt.Errorf("invalid Content-Type %q: must have a boundary", contentType)
+		}
+		items, err = readMultipartBatch(multipart.NewReader(r.Body, params["boundary"]))
+	default:
+		return nil, http.StatusUnsupportedMediaType, fmt.Errorf("unsupported Content-Type %q: must be application/x-ndjson, multipart/form-data or multipart/mixed", contentType)
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return nil, http.StatusRequestEntityTooLarge, fmt.Errorf("request body must not be larger than %d bytes", maxBytesErr.Limit)
+	}
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if len(items) == 0 {
+		return nil, http.StatusBadRequest, fmt.Errorf("batch must have at least one item")
+	}
+	return items, http.StatusOK, nil
+}
+
+// readNDJSONBatch reads each line which is not blank as the JSON body of an item.
+func readNDJSONBatch(body io.Reader) ([]batchItem, error) {
+	var items []batchItem
+
+	reader := bufio.NewReader(body)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		if data := bytes.TrimSpace(data); len(data) > 0 {
+			if !json.Valid(data) {
+				return nil, fmt.Errorf("invalid JSON on line %d", line)
+			}
+			items = append(items, batchItem{contentType: "application/json", body: data})
+		}
+
+		if err == io.EOF {
+			return items, nil
+		}
+	}
+}
+
+// readMultipartBatch reads each part as the body of an item, with the part's Content-Type.
+func readMultipartBatch(reader *multipart.Reader) ([]batchItem, error) {
+	var items []batchItem
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return items, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid multipart batch: %w", err)
+		}
+
+		body, err := io.ReadAll(part)
+		part.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid multipart batch: %w", err)
+		}
+
+		items = append(items, batchItem{contentType: part.Header.Get("Content-Type"), body: body})
+	}
+}
@@ -0,0 +1,237 @@
+package bootstrap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_BatchInvoke(t *testing.T) {
+	var inflight, maxInflight int32
+
+	handlers := validHandlers()
+	handlers.InvokeFunction = func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inflight, 1)
+		defer atomic.AddInt32(&inflight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInflight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInflight, max, n) {
+				break
+			}
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		switch string(body) {
+		case `"panic"`:
+			panic("malformed item")
+		case `"slow"`:
+			time.Sleep(20 * time.Millisecond)
+		case `"fail"`:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
+		w.Header().Set("Content-Type", "text/plain")
+		name, _, _ := httputil.FunctionFromContext(r.Context())
+		w.Write([]byte(name + ":" + r.URL.Path + ":" + r.Header.Get("Content-Type") + ":" + string(body)))
+	}
+
+	s := NewServer(&types.FaaSConfig{BatchParallelism: 2, MaxProxyBodyBytes: 1024})
+	s.Handlers(handlers)
+
+	server := httptest.NewServer(s.Router())
+	defer server.Close()
+
+	post := func(t *testing.T, contentType string, body []byte) []types.BatchResult {
+		t.Helper()
+
+		res, err := http.Post(server.URL+"/invoke-batch/figlet", contentType, bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("status code, want: %d, got: %d", http.StatusOK, res.StatusCode)
+		}
+		if got := res.Header.Get("Content-Type"); got != "application/x-ndjson" {
+			t.Errorf("Content-Type, want: %s, got: %s", "application/x-ndjson", got)
+		}
+
+		var results []types.BatchResult
+		scanner := bufio.NewScanner(res.Body)
+		for scanner.Scan() {
+			result := types.BatchResult{}
+			if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+				t.Fatal(err)
+			}
+			results = append(results, result)
+		}
+		return results
+	}
+
+	t.Run("ndjson", func(t *testing.T) {
+		results := post(t, "application/x-ndjson", []byte("\"slow\"\n{\"n\":1}\n\n\"fail\"\n\"panic\"\n\"slow\"\n"))
+
+		want := []struct {
+			status int
+			body   string
+		}{
+			{http.StatusOK, `figlet:/invoke/figlet:application/json:"slow"`},
+			{http.StatusOK, `figlet:/invoke/figlet:application/json:{"n":1}`},
+			{http.StatusInternalServerError, `figlet:/invoke/figlet:application/json:"fail"`},
+			{http.StatusInternalServerError, ""},
+			{http.StatusOK, `figlet:/invoke/figlet:application/json:"slow"`},
+		}
+		if len(results) != len(want) {
+			t.Fatalf("results, want: %d, got: %d", len(want), len(results))
+		}
+
+		for i, result := range results {
+			if result.Index != i {
+				t.Errorf("index, want: %d, got: %d", i, result.Index)
+			}
+			if result.Status != want[i].status {
+				t.Errorf("%d: status, want: %d, got: %d", i, want[i].status, result.Status)
+			}
+			if want[i].body != "" && string(result.Body) != want[i].body {
+				t.Errorf("%d: body, want: %q, got: %q", i, want[i].body, string(result.Body))
+			}
+		}
+
+		if results[0].Latency < (20 * time.Millisecond).Seconds() {
+			t.Errorf("latency, want at least 20ms, got: %fs", results[0].Latency)
+		}
+		if got := atomic.LoadInt32(&maxInflight); got > 2 {
+			t.Errorf("items invoked at once, want at most: %d, got: %d", 2, got)
+		}
+	})
+
+	t.Run("multipart", func(t *testing.T) {
+		body := &bytes.Buffer{}
+		mw := multipart.NewWriter(body)
+		for _, part := range []struct{ contentType, body string }{
+			{"text/plain", "hello"},
+			{"application/octet-stream", "world"},
+		} {
+			pw, _ := mw.CreatePart(textproto.MIMEHeader{"Content-Type": []string{part.contentType}})
+			pw.Write([]byte(part.body))
+		}
+		mw.Close()
+
+		results := post(t, mw.FormDataContentType(), body.Bytes())
+
+		want := []string{"figlet:/invoke/figlet:text/plain:hello", "figlet:/invoke/figlet:application/octet-stream:world"}
+		if len(results) != len(want) {
+			t.Fatalf("results, want: %d, got: %d", len(want), len(results))
+		}
+		for i, result := range results {
+			if string(result.Body) != want[i] || result.ContentType != "text/plain" {
+				t.Errorf("%d: body, want: %q, got: %q (%s)", i, want[i], string(result.Body), result.ContentType)
+			}
+		}
+	})
+
+	t.Run("invalid batches", func(t *testing.T) {
+		testCases := []struct {
+			name        string
+			contentType string
+			body        string
+			wantCode    int
+		}{
+			{name: "unsupported content type", contentType: "application/json", body: `["a"]`, wantCode: http.StatusUnsupportedMediaType},
+			{name: "invalid json", contentType: "application/x-ndjson", body: "\"a\"\nb\n", wantCode: http.StatusBadRequest},
+			{name: "empty", contentType: "application/x-ndjson", body: "\n", wantCode: http.StatusBadRequest},
+			{name: "multipart without a boundary", contentType: "multipart/mixed", body: "a", wantCode: http.StatusBadRequest},
+			{name: "too large", contentType: "application/x-ndjson", body: strings.Repeat("1\n", 1024), wantCode: http.StatusRequestEntityTooLarge},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				r := httptest.NewRequest(http.MethodPost, "/invoke-batch/figlet", strings.NewReader(tc.body))
+				r.Header.Set("Content-Type", tc.contentType)
+
+				w := httptest.NewRecorder()
+				s.Router().ServeHTTP(w, r)
+
+				if w.Code != tc.wantCode {
+					t.Errorf("status code, want: %d, got: %d (%s)", tc.wantCode, w.Code, w.Body.String())
+				}
+			})
+		}
+	})
+}
+
+func Test_BatchInvoke_FunctionProxy(t *testing.T) {
+	handlers := validHandlers()
+	handlers.FunctionProxy = func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path + "?" + r.URL.RawQuery))
+	}
+
+	s := NewServer(&types.FaaSConfig{})
+	s.Handlers(handlers)
+
+	r := httptest.NewRequest(http.MethodPost, "/invoke-batch/figlet.dev?q=1", strings.NewReader("1\n"))
+	r.Header.Set("Content-Type", "application/x-ndjson")
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, r)
+
+	result := types.BatchResult{}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/function/figlet.dev?q=1"; string(result.Body) != want {
+		t.Errorf("body, want: %q, got: %q", want, string(result.Body))
+	}
+}
+
+func Test_BatchInvoke_ChargesRateLimitPerItem(t *testing.T) {
+	handlers := validHandlers()
+	handlers.InvokeFunction = func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}
+
+	s := NewServer(&types.FaaSConfig{InvokeRateLimit: 2, InvokeRateLimitWindow: time.Minute, BatchParallelism: 1})
+	s.Handlers(handlers)
+
+	r := httptest.NewRequest(http.MethodPost, "/invoke-batch/figlet", strings.NewReader("1\n2\n3\n"))
+	r.Header.Set("Content-Type", "application/x-ndjson")
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code, want: %d, got: %d", http.StatusOK, w.Code)
+	}
+
+	var statuses []int
+	decoder := json.NewDecoder(w.Body)
+	for decoder.More() {
+		result := types.BatchResult{}
+		if err := decoder.Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+		statuses = append(statuses, result.Status)
+	}
+	want := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("statuses, want: %v, got: %v", want, statuses)
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/invoke/figlet", nil))
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("invocation after the batch, want: %d, got: %d", http.StatusTooManyRequests, w.Code)
+	}
+}
@@ -0,0 +1,31 @@
+package bootstrap
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/openfaas/faas-provider/httputil"
+)
+
+// decorateWithBodyLimit rejects requests with a body larger than limit bytes with a 413.
+// A request which declares a larger Content-Length is rejected before next is called,
+// otherwise the body is wrapped with http.MaxBytesReader, so that reading past the limit
+// fails with an *http.MaxBytesError. A limit of 0 means unlimited.
+func decorateWithBodyLimit(next http.HandlerFunc, limit int64) http.HandlerFunc {
+	if limit <= 0 || next == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			httputil.WriteErrorCode(w, r, http.StatusRequestEntityTooLarge, httputil.RequestTooLarge, fmt.Sprintf("request body must not be larger than %d bytes", limit))
+			return
+		}
+
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
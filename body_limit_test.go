@@ -0,0 +1,59 @@
+package bootstrap
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_decorateWithBodyLimit(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	body := `{"service":"figlet","image":"ghcr.io/openfaas/figlet:latest"}`
+
+	testCases := []struct {
+		name     string
+		config   types.FaaSConfig
+		method   string
+		path     string
+		chunked  bool
+		wantCode int
+	}{
+		{name: "unlimited deploy", method: http.MethodPost, path: "/system/functions", wantCode: http.StatusOK},
+		{name: "deploy within limit", config: types.FaaSConfig{MaxRequestBodyBytes: 1024}, method: http.MethodPost, path: "/system/functions", wantCode: http.StatusOK},
+		{name: "deploy over limit", config: types.FaaSConfig{MaxRequestBodyBytes: 16}, method: http.MethodPost, path: "/system/functions", wantCode: http.StatusRequestEntityTooLarge},
+		{name: "chunked deploy over limit", config: types.FaaSConfig{MaxRequestBodyBytes: 16}, method: http.MethodPost, path: "/system/functions", chunked: true, wantCode: http.StatusRequestEntityTooLarge},
+		{name: "scale over limit", config: types.FaaSConfig{MaxRequestBodyBytes: 16}, method: http.MethodPost, path: "/system/scale-function/figlet", wantCode: http.StatusRequestEntityTooLarge},
+		{name: "proxy excluded", config: types.FaaSConfig{MaxRequestBodyBytes: 16}, method: http.MethodPost, path: "/function/figlet", wantCode: http.StatusOK},
+		{name: "proxy over limit", config: types.FaaSConfig{MaxProxyBodyBytes: 16}, method: http.MethodPost, path: "/function/figlet", wantCode: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewServer(&tc.config)
+			s.Handlers(&types.FaaSHandlers{DeployFunction: ok, ScaleFunction: ok, FunctionProxy: ok})
+
+			r := httptest.NewRequest(tc.method, tc.path, strings.NewReader(body))
+			if tc.chunked {
+				r.ContentLength = -1
+			}
+
+			w := httptest.NewRecorder()
+			s.Router().ServeHTTP(w, r)
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+		})
+	}
+}
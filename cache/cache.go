@@ -0,0 +1,103 @@
+// Package cache stores the responses of reads of functions from the provider API, so that
+// repeated reads are not sent to the backing runtime, see FaaSConfig.FunctionCache.
+// MemoryStore keeps them in the process, a Store backed by Redis or memcached can be used
+// to share them between the replicas of a provider.
+package cache
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often MemoryStore removes the entries which have expired.
+const sweepInterval = time.Minute
+
+// Entry is a cached response, it can be encoded as JSON by a Store which is not in memory.
+type Entry struct {
+	// Header of the response
+	Header http.Header `json:"header,omitempty"`
+
+	// Body of the response
+	Body []byte `json:"body"`
+}
+
+// Store holds entries by key until their TTL has passed, implementations must be safe
+// for concurrent use.
+type Store interface {
+	// Get returns the entry for key, the bool is false when there is none or it expired.
+	Get(ctx context.Context, key string) (Entry, bool, error)
+
+	// Set stores entry for key for ttl.
+	Set(ctx context.Context, key string, entry Entry, ttl time.Duration) error
+
+	// Delete removes the entries of keys, keys which are not stored are ignored.
+	Delete(ctx context.Context, keys ...string) error
+}
+
+type memoryEntry struct {
+	entry   Entry
+	expires time.Time
+}
+
+// MemoryStore is a Store which keeps entries in the process.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	swept   time.Time
+
+	now func() time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: map[string]memoryEntry{},
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (Entry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return Entry{}, false, nil
+	}
+	if !s.now().Before(e.expires) {
+		delete(s.entries, key)
+		return Entry{}, false, nil
+	}
+
+	return e.entry, true, nil
+}
+
+func (s *MemoryStore) Set(ctx context.Context, key string, entry Entry, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.swept) >= sweepInterval {
+		for k, e := range s.entries {
+			if !now.Before(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+		s.swept = now
+	}
+
+	s.entries[key] = memoryEntry{entry: entry, expires: now.Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+	return nil
+}
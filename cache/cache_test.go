@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func Test_MemoryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+
+	s.Set(ctx, "function/openfaas-fn/figlet", Entry{Body: []byte("figlet")}, time.Second)
+	s.Set(ctx, "functions/openfaas-fn", Entry{Body: []byte("[]")}, time.Minute)
+
+	entry, ok, err := s.Get(ctx, "function/openfaas-fn/figlet")
+	if err != nil || !ok {
+		t.Fatalf("want an entry, got: %v %v", ok, err)
+	}
+	if string(entry.Body) != "figlet" {
+		t.Errorf("body, want: %q, got: %q", "figlet", entry.Body)
+	}
+
+	now = now.Add(time.Second)
+	if _, ok, _ := s.Get(ctx, "function/openfaas-fn/figlet"); ok {
+		t.Errorf("want the entry to expire after its ttl")
+	}
+
+	s.Delete(ctx, "functions/openfaas-fn", "functions/unknown")
+	if _, ok, _ := s.Get(ctx, "functions/openfaas-fn"); ok {
+		t.Errorf("want the entry to be deleted")
+	}
+}
+
+func Test_MemoryStore_SweepsExpiredEntries(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+
+	s.Set(ctx, "function/openfaas-fn/figlet", Entry{}, time.Second)
+	now = now.Add(sweepInterval)
+	s.Set(ctx, "function/openfaas-fn/env", Entry{}, time.Hour)
+
+	if len(s.entries) != 1 {
+		t.Errorf("entries, want: %d, got: %d", 1, len(s.entries))
+	}
+}
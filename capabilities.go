@@ -0,0 +1,14 @@
+package bootstrap
+
+import (
+	"net/http"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+// newCapabilitiesHandler serves the capabilities of the provider as JSON.
+func newCapabilitiesHandler(capabilities types.Capabilities) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		types.WriteJSON(w, http.StatusOK, capabilities)
+	}
+}
@@ -0,0 +1,57 @@
+package bootstrap
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openfaas/faas-provider/auth"
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_Server_Capabilities(t *testing.T) {
+	handlers := validHandlers()
+	handlers.InvokeFunction = handlers.FunctionProxy
+	handlers.MutateNamespace = handlers.ListNamespaces
+
+	s := NewServer(&types.FaaSConfig{})
+	s.Handlers(handlers)
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/system/capabilities", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code, want: %d, got: %d", http.StatusOK, w.Code)
+	}
+
+	got := map[string]bool{}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unable to decode capabilities: %s", err)
+	}
+
+	want := map[string]bool{"invoke": true, "mutate_namespace": true, "checkpoints": false, "kill_instances": false}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s, want: %t, got: %t", k, v, got[k])
+		}
+	}
+}
+
+func Test_Server_Capabilities_Authenticated(t *testing.T) {
+	deny := auth.AuthenticatorFunc(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+
+	s := NewServer(&types.FaaSConfig{Authenticator: deny})
+	s.Handlers(validHandlers())
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/system/capabilities", nil))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status code, want: %d, got: %d", http.StatusUnauthorized, w.Code)
+	}
+}
@@ -0,0 +1,59 @@
+package bootstrap
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/openfaas/faas-provider/auth"
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/logging"
+)
+
+// Decorator wraps a handler with a cross-cutting concern such as auth, metrics or logging.
+type Decorator func(next http.HandlerFunc) http.HandlerFunc
+
+// Chain applies decorators to h. The first decorator is the outermost, so it sees the
+// request first and the response last:
+//
+//	handlers.DeployFunction = bootstrap.Chain(deploy,
+//		bootstrap.WithLogging(),
+//		bootstrap.WithBasicAuth(credentials),
+//		bootstrap.WithMetrics("/system/functions"),
+//	)
+func Chain(h http.HandlerFunc, decorators ...func(http.HandlerFunc) http.HandlerFunc) http.HandlerFunc {
+	for i := len(decorators) - 1; i >= 0; i-- {
+		h = decorators[i](h)
+	}
+	return h
+}
+
+// WithBasicAuth returns a Decorator which enforces basic auth with credentials.
+func WithBasicAuth(credentials *auth.BasicAuthCredentials) Decorator {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return auth.DecorateWithBasicAuth(next, credentials)
+	}
+}
+
+// WithMetrics returns a Decorator which records the R.E.D. metrics used for the system
+// endpoints. path is used as the path label, when empty the template of the mux route
+// which matched the request is used.
+func WithMetrics(path string) Decorator {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return defaultHttpMetrics().InstrumentHandler(next, path)
+	}
+}
+
+// WithLogging returns a Decorator which logs the method, path, status and duration of
+// each request with the logger of its context, see logging.FromContext.
+func WithLogging() Decorator {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := httputil.NewHttpWriteInterceptor(w)
+			next.ServeHTTP(ww, r)
+
+			logging.FromContext(r.Context()).Info("request", "method", r.Method, "path", r.URL.Path,
+				"status", ww.Status(), "duration", time.Since(start))
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package bootstrap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func Test_Chain_Order(t *testing.T) {
+	calls := []string{}
+
+	record := func(name string) Decorator {
+		return func(next http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			}
+		}
+	}
+
+	h := Chain(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	}, record("first"), record("second"))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	want := []string{"first", "second", "handler"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("want: %v, got: %v", want, calls)
+	}
+}
+
+func Test_Chain_NoDecorators(t *testing.T) {
+	h := Chain(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusTeapot {
+		t.Errorf("status code, want: %d, got: %d", http.StatusTeapot, w.Code)
+	}
+}
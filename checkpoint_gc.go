@@ -0,0 +1,158 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/logging"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// checkpointGC deletes the checkpoints of a CheckpointStore which exceed a retention
+// policy, in the background and when asked to through the API.
+type checkpointGC struct {
+	store     types.CheckpointStore
+	retention types.CheckpointRetention
+
+	// mu serialises collections, so that a manual collection does not race the
+	// background one to delete the same checkpoints.
+	mu sync.Mutex
+
+	now func() time.Time
+}
+
+func newCheckpointGC(store types.CheckpointStore, retention types.CheckpointRetention) *checkpointGC {
+	return &checkpointGC{
+		store:     store,
+		retention: retention,
+		now:       time.Now,
+	}
+}
+
+// run collects checkpoints every interval of the policy until ctx is cancelled.
+func (g *checkpointGC) run(ctx context.Context, logger *slog.Logger) {
+	ticker := time.NewTicker(g.retention.GetInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := g.collect(ctx, logger); err != nil {
+				logger.Warn("Unable to collect checkpoints", "error", err)
+			}
+		}
+	}
+}
+
+// collect deletes the checkpoints which exceed the policy. A checkpoint which can not be
+// deleted is logged and counted as failed, the others are still deleted.
+func (g *checkpointGC) collect(ctx context.Context, logger *slog.Logger) (types.CheckpointGCResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	result := types.CheckpointGCResult{Deleted: []types.Checkpoint{}}
+
+	checkpoints, err := g.store.ListCheckpoints(ctx)
+	if err != nil {
+		return result, fmt.Errorf("unable to list checkpoints: %w", err)
+	}
+
+	for _, checkpoint := range expiredCheckpoints(checkpoints, g.retention, g.now()) {
+		if err := g.store.DeleteCheckpoint(ctx, checkpoint); err != nil {
+			logger.Warn("Unable to delete checkpoint", "checkpoint", checkpoint.ID, "function", checkpoint.Function,
+				"namespace", checkpoint.Namespace, "error", err)
+			result.Failed++
+			continue
+		}
+
+		result.Deleted = append(result.Deleted, checkpoint)
+		result.FreedBytes += checkpoint.SizeBytes
+	}
+
+	checkpointsCollectedTotal.Add(float64(len(result.Deleted)))
+	checkpointBytesFreedTotal.Add(float64(result.FreedBytes))
+	if len(result.Deleted) > 0 {
+		logger.Info("Collected checkpoints", "deleted", len(result.Deleted), "freed_bytes", result.FreedBytes)
+	}
+
+	return result, nil
+}
+
+// handler collects checkpoints for a POST to "/system/checkpoints/gc" and responds with a
+// CheckpointGCResult.
+func (g *checkpointGC) handler(w http.ResponseWriter, r *http.Request) {
+	result, err := g.collect(r.Context(), logging.FromContext(r.Context()))
+	if err != nil {
+		httputil.WriteErrorCode(w, r, http.StatusInternalServerError, httputil.InternalError, err.Error())
+		return
+	}
+
+	types.WriteJSON(w, http.StatusOK, result)
+}
+
+// expiredCheckpoints returns the checkpoints which exceed the policy at now, oldest first:
+// those older than its TTL, then those over MaxPerFunction for their function, then the
+// oldest of the rest until their total size is within MaxTotalBytes.
+func expiredCheckpoints(checkpoints []types.Checkpoint, retention types.CheckpointRetention, now time.Time) []types.Checkpoint {
+	sorted := make([]types.Checkpoint, len(checkpoints))
+	copy(sorted, checkpoints)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+
+	expired := map[int]bool{}
+
+	if retention.TTL > 0 {
+		for i, checkpoint := range sorted {
+			if now.Sub(checkpoint.CreatedAt) > retention.TTL {
+				expired[i] = true
+			}
+		}
+	}
+
+	if retention.MaxPerFunction > 0 {
+		kept := map[string]int{}
+		// newest first, so that the oldest are over the limit
+		for i := len(sorted) - 1; i >= 0; i-- {
+			if expired[i] {
+				continue
+			}
+			key := sorted[i].Function + "." + sorted[i].Namespace
+			kept[key]++
+			if kept[key] > retention.MaxPerFunction {
+				expired[i] = true
+			}
+		}
+	}
+
+	if retention.MaxTotalBytes > 0 {
+		var total int64
+		for i, checkpoint := range sorted {
+			if !expired[i] {
+				total += checkpoint.SizeBytes
+			}
+		}
+		for i := 0; i < len(sorted) && total > retention.MaxTotalBytes; i++ {
+			if !expired[i] {
+				expired[i] = true
+				total -= sorted[i].SizeBytes
+			}
+		}
+	}
+
+	result := []types.Checkpoint{}
+	for i, checkpoint := range sorted {
+		if expired[i] {
+			result = append(result, checkpoint)
+		}
+	}
+	return result
+}
@@ -0,0 +1,179 @@
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+type fakeCheckpointStore struct {
+	mu          sync.Mutex
+	checkpoints []types.Checkpoint
+	failDelete  string
+}
+
+func (f *fakeCheckpointStore) ListCheckpoints(ctx context.Context) ([]types.Checkpoint, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]types.Checkpoint{}, f.checkpoints...), nil
+}
+
+func (f *fakeCheckpointStore) DeleteCheckpoint(ctx context.Context, checkpoint types.Checkpoint) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if checkpoint.ID == f.failDelete {
+		return errors.New("checkpoint is in use")
+	}
+	for i, c := range f.checkpoints {
+		if c.ID == checkpoint.ID {
+			f.checkpoints = append(f.checkpoints[:i], f.checkpoints[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+func Test_expiredCheckpoints(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(hoursAgo int) time.Time { return now.Add(-time.Duration(hoursAgo) * time.Hour) }
+
+	checkpoints := []types.Checkpoint{
+		{ID: "figlet-1", Function: "figlet", CreatedAt: at(30), SizeBytes: 100},
+		{ID: "figlet-2", Function: "figlet", CreatedAt: at(3), SizeBytes: 100},
+		{ID: "figlet-3", Function: "figlet", CreatedAt: at(2), SizeBytes: 100},
+		{ID: "figlet-4", Function: "figlet", CreatedAt: at(1), SizeBytes: 100},
+		{ID: "figlet-dev", Function: "figlet", Namespace: "dev", CreatedAt: at(4), SizeBytes: 300},
+		{ID: "env-1", Function: "env", CreatedAt: at(5), SizeBytes: 50},
+	}
+
+	testCases := []struct {
+		name      string
+		retention types.CheckpointRetention
+		want      []string
+	}{
+		{name: "no limits", want: []string{}},
+		{name: "ttl", retention: types.CheckpointRetention{TTL: 24 * time.Hour}, want: []string{"figlet-1"}},
+		{name: "per function", retention: types.CheckpointRetention{MaxPerFunction: 2}, want: []string{"figlet-1", "figlet-2"}},
+		{name: "total size", retention: types.CheckpointRetention{MaxTotalBytes: 400}, want: []string{"figlet-1", "env-1", "figlet-dev"}},
+		{
+			name:      "combined",
+			retention: types.CheckpointRetention{TTL: 24 * time.Hour, MaxPerFunction: 2, MaxTotalBytes: 500},
+			want:      []string{"figlet-1", "env-1", "figlet-2"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := []string{}
+			for _, c := range expiredCheckpoints(checkpoints, tc.retention, now) {
+				got = append(got, c.ID)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expired, want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_checkpointGC_collect(t *testing.T) {
+	now := time.Now()
+	store := &fakeCheckpointStore{
+		checkpoints: []types.Checkpoint{
+			{ID: "old", Function: "figlet", CreatedAt: now.Add(-2 * time.Hour), SizeBytes: 100},
+			{ID: "in-use", Function: "figlet", CreatedAt: now.Add(-2 * time.Hour), SizeBytes: 200},
+			{ID: "new", Function: "figlet", CreatedAt: now, SizeBytes: 300},
+		},
+		failDelete: "in-use",
+	}
+
+	gc := newCheckpointGC(store, types.CheckpointRetention{TTL: time.Hour})
+	result, err := gc.collect(context.Background(), slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Deleted) != 1 || result.Deleted[0].ID != "old" {
+		t.Errorf("deleted, want: [old], got: %v", result.Deleted)
+	}
+	if result.FreedBytes != 100 {
+		t.Errorf("freed bytes, want: %d, got: %d", 100, result.FreedBytes)
+	}
+	if result.Failed != 1 {
+		t.Errorf("failed, want: %d, got: %d", 1, result.Failed)
+	}
+
+	remaining, _ := store.ListCheckpoints(context.Background())
+	if len(remaining) != 2 {
+		t.Errorf("remaining checkpoints, want: %d, got: %d", 2, len(remaining))
+	}
+}
+
+func Test_Server_CheckpointGC(t *testing.T) {
+	store := &fakeCheckpointStore{
+		checkpoints: []types.Checkpoint{
+			{ID: "a", Function: "figlet", CreatedAt: time.Now().Add(-time.Minute), SizeBytes: 100},
+			{ID: "b", Function: "figlet", CreatedAt: time.Now(), SizeBytes: 100},
+		},
+	}
+
+	s := NewServer(&types.FaaSConfig{
+		CheckpointStore:     store,
+		CheckpointRetention: &types.CheckpointRetention{MaxPerFunction: 1},
+	})
+	s.Handlers(&types.FaaSHandlers{})
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/system/checkpoints/gc", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code, want: %d, got: %d", http.StatusOK, w.Code)
+	}
+
+	result := types.CheckpointGCResult{}
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Deleted) != 1 || result.Deleted[0].ID != "a" || result.FreedBytes != 100 {
+		t.Errorf("result, want: a deleted with 100 bytes freed, got: %+v", result)
+	}
+}
+
+func Test_Server_CheckpointGC_ReadOnly(t *testing.T) {
+	store := &fakeCheckpointStore{
+		checkpoints: []types.Checkpoint{
+			{ID: "a", Function: "figlet", CreatedAt: time.Now().Add(-time.Minute), SizeBytes: 100},
+			{ID: "b", Function: "figlet", CreatedAt: time.Now(), SizeBytes: 100},
+		},
+	}
+
+	s := NewServer(&types.FaaSConfig{
+		ReadOnly:            true,
+		CheckpointStore:     store,
+		CheckpointRetention: &types.CheckpointRetention{MaxPerFunction: 1},
+	})
+	s.Handlers(&types.FaaSHandlers{})
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/system/checkpoints/gc", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status code, want: %d, got: %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	remaining, _ := store.ListCheckpoints(context.Background())
+	if len(remaining) != 2 {
+		t.Errorf("remaining checkpoints, want: %d, got: %d", 2, len(remaining))
+	}
+}
@@ -0,0 +1,352 @@
+// Package client is a Go client for the API served by the bootstrap package, including
+// the routes this provider adds to the OpenFaaS API, such as "/system/register",
+// "/invoke", "/system/metrics", "/system/checkpoints" and "/danger/kill". Requests and
+// responses are the structs of the types package, which the server decodes and encodes.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-provider/auth"
+	"github.com/openfaas/faas-provider/auth/hmac"
+)
+
+const (
+	// DefaultRetries is how many times an idempotent request is retried when Config.Retries
+	// is not set.
+	DefaultRetries = 3
+	// DefaultBackoff is the wait before the first retry when Config.Backoff is not set, it
+	// doubles with every retry.
+	DefaultBackoff = 200 * time.Millisecond
+	// maxBackoff caps the wait between retries, including one asked for with Retry-After.
+	maxBackoff = 30 * time.Second
+)
+
+// Error is returned for a response with a status code outside of 2xx.
+type Error struct {
+	// StatusCode of the response
+	StatusCode int
+	// Message is the message of the JSON error envelope or problem, or the body of the
+	// response
+	Message string
+}
+
+func (e *Error) Error() string {
+	if len(e.Message) == 0 {
+		return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+	}
+	return fmt.Sprintf("unexpected status code: %d, message: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound returns true when err is an Error with a 404 status code.
+func IsNotFound(err error) bool {
+	return StatusCode(err) == http.StatusNotFound
+}
+
+// StatusCode returns the status code of err when it is an Error, otherwise 0.
+func StatusCode(err error) int {
+	if e, ok := err.(*Error); ok {
+		return e.StatusCode
+	}
+	return 0
+}
+
+// Config configures a Client.
+type Config struct {
+	// HTTPClient sends the requests, http.DefaultClient when nil. Its Timeout would also end
+	// streams such as Logs and WatchFunctions, so it should be left unset in favour of
+	// Timeout.
+	HTTPClient *http.Client
+	// Timeout, when set, is the deadline of each call which reads a whole response, including
+	// its retries. It is set on the context of the call, so that Logs, WatchFunctions,
+	// WatchEvents and Invoke are not bound by it.
+	Timeout time.Duration
+	// Retries is how many times an idempotent request is retried after a network error, a
+	// 429 or a 502, 503 or 504. DefaultRetries is used when 0, a negative value disables
+	// retries.
+	Retries int
+	// Backoff is the wait before the first retry, DefaultBackoff when 0. A Retry-After
+	// header sent by the server takes precedence.
+	Backoff time.Duration
+	// HMACKey, when set, signs every request with the key shared with a provider which
+	// sets EnableHMACAuth, see the auth/hmac package.
+	HMACKey []byte
+}
+
+// GetRetries returns Retries, or DefaultRetries when it is not set.
+func (c Config) GetRetries() int {
+	if c.Retries == 0 {
+		return DefaultRetries
+	}
+	if c.Retries < 0 {
+		return 0
+	}
+	return c.Retries
+}
+
+// GetBackoff returns Backoff, or DefaultBackoff when it is not set.
+func (c Config) GetBackoff() time.Duration {
+	if c.Backoff <= 0 {
+		return DefaultBackoff
+	}
+	return c.Backoff
+}
+
+// Client calls the API of a provider, it is safe for concurrent use.
+type Client struct {
+	baseURL     *url.URL
+	credentials *auth.BasicAuthCredentials
+	httpClient  *http.Client
+	config      Config
+
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// New creates a Client for the provider at baseURL, such as "http://127.0.0.1:8081".
+// The requests are sent with credentials as basic auth, unless they are nil.
+func New(baseURL string, credentials *auth.BasicAuthCredentials, config Config) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL %q: %w", baseURL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return nil, fmt.Errorf("invalid base URL %q: must be an absolute http or https URL", baseURL)
+	}
+
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Client{
+		baseURL:     u,
+		credentials: credentials,
+		httpClient:  httpClient,
+		config:      config,
+		sleep:       sleep,
+	}, nil
+}
+
+// request describes a call to the API, its body is kept as bytes so that it can be sent
+// again when the request is retried.
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	body   []byte
+}
+
+// newRequest creates a request, v is encoded as the JSON body when it is not nil.
+func newRequest(method, path string, query url.Values, v interface{}) (request, error) {
+	req := request{method: method, path: path, query: query, header: http.Header{}}
+	if v != nil {
+		body, err := json.Marshal(v)
+		if err != nil {
+			return req, fmt.Errorf("unable to encode request: %w", err)
+		}
+		req.body = body
+		req.header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// do sends req, retrying idempotent requests, and returns the response when its status
+// code is 2xx. Otherwise the body is read into an Error and closed.
+func (c *Client) do(ctx context.Context, req request) (*http.Response, error) {
+	retries := 0
+	if idempotent(req.method) {
+		retries = c.config.GetRetries()
+	}
+
+	backoff := c.config.GetBackoff()
+	for attempt := 0; ; attempt++ {
+		res, err := c.send(ctx, req)
+		if err == nil && res.StatusCode >= 200 && res.StatusCode < 300 {
+			return res, nil
+		}
+		if err == nil {
+			err = readError(res)
+		}
+
+		if attempt >= retries || !retryable(ctx, err) {
+			return nil, err
+		}
+
+		wait := backoff << attempt
+		if res != nil {
+			if after, ok := retryAfter(res.Header.Get("Retry-After")); ok {
+				wait = after
+			}
+		}
+		if wait > maxBackoff {
+			wait = maxBackoff
+		}
+		if err := c.sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, req request) (*http.Response, error) {
+	u := *c.baseURL
+	u.Path = c.baseURL.Path + req.path
+	u.RawPath = c.baseURL.EscapedPath() + escapePath(req.path)
+	if len(req.query) > 0 {
+		u.RawQuery = req.query.Encode()
+	}
+
+	var body io.Reader
+	if req.body != nil {
+		body = bytes.NewReader(req.body)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, req.method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range req.header {
+		r.Header[key] = values
+	}
+	if c.credentials != nil {
+		r.SetBasicAuth(c.credentials.User, c.credentials.Password)
+	}
+	if len(c.config.HMACKey) > 0 {
+		if err := hmac.SignRequest(r, c.config.HMACKey); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.httpClient.Do(r)
+}
+
+// doJSON sends req and decodes the JSON body of the response into v, when v is not nil.
+func (c *Client) doJSON(ctx context.Context, req request, v interface{}) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	res, err := c.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if v == nil {
+		io.Copy(io.Discard, res.Body)
+		return nil
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return fmt.Errorf("unable to decode response: %w", err)
+	}
+	return nil
+}
+
+// withTimeout returns a copy of ctx with the deadline of Config.Timeout, when it is set.
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.config.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.config.Timeout)
+}
+
+// readError reads the body of res into an Error and closes it. The message is taken
+// from the JSON error envelope when the body is one.
+func readError(res *http.Response) error {
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 64*1024))
+
+	// The provider writes {"message"}, {"error"} or, to clients which accept it, an RFC 7807
+	// problem with the message in "detail".
+	envelope := struct {
+		Message string `json:"message"`
+		Error   string `json:"error"`
+		Detail  string `json:"detail"`
+	}{}
+	if err := json.Unmarshal(body, &envelope); err == nil {
+		for _, message := range []string{envelope.Message, envelope.Error, envelope.Detail} {
+			if len(message) > 0 {
+				return &Error{StatusCode: res.StatusCode, Message: message}
+			}
+		}
+	}
+
+	return &Error{StatusCode: res.StatusCode, Message: strings.TrimSpace(string(body))}
+}
+
+// retryable returns true for network errors and for status codes which are expected to
+// change, unless ctx is done.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	e, ok := err.(*Error)
+	if !ok {
+		return true
+	}
+
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses a Retry-After header given in seconds.
+func retryAfter(value string) (time.Duration, bool) {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// escapePath escapes each segment of path, so that names are not split or joined by
+// the separators they contain.
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// namespaceQuery returns the "namespace" query string parameter, or nil for the default
+// namespace.
+func namespaceQuery(namespace string) url.Values {
+	if len(namespace) == 0 {
+		return nil
+	}
+	return url.Values{"namespace": []string{namespace}}
+}
@@ -0,0 +1,301 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-provider/auth"
+	"github.com/openfaas/faas-provider/auth/hmac"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// newTestClient creates a Client for handler which records the waits between retries
+// instead of sleeping.
+func newTestClient(t *testing.T, handler http.HandlerFunc, config Config) (*Client, *[]time.Duration) {
+	t.Helper()
+
+	s := httptest.NewServer(handler)
+	t.Cleanup(s.Close)
+
+	c, err := New(s.URL, &auth.BasicAuthCredentials{User: "admin", Password: "secret"}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waits := []time.Duration{}
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return ctx.Err()
+	}
+	return c, &waits
+}
+
+func Test_New(t *testing.T) {
+	cases := []struct {
+		baseURL string
+		wantErr bool
+	}{
+		{"http://127.0.0.1:8081", false},
+		{"https://gateway.example.com/provider/", false},
+		{"127.0.0.1:8081", true},
+		{"ftp://127.0.0.1", true},
+		{"http://", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.baseURL, func(t *testing.T) {
+			_, err := New(tc.baseURL, nil, Config{})
+			if (err != nil) != tc.wantErr {
+				t.Errorf("error, want: %v, got: %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_Client_SendsCredentials(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"provider":"faasd","orchestration":"containerd"}`))
+	}, Config{})
+
+	info, err := c.Info(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "faasd" {
+		t.Errorf("provider, want: %s, got: %s", "faasd", info.Name)
+	}
+}
+
+func Test_Client_SignsRequests(t *testing.T) {
+	key := []byte("s3cret")
+	verifier := hmac.NewVerifier(key, 0)
+
+	c, _ := newTestClient(t, verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"provider":"faasd","orchestration":"containerd"}`))
+	})).ServeHTTP, Config{HMACKey: key})
+
+	if _, err := c.Info(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_Client_Retries(t *testing.T) {
+	cases := []struct {
+		name      string
+		method    string
+		status    int
+		header    map[string]string
+		config    Config
+		wantCalls int32
+		wantWaits []time.Duration
+	}{
+		{
+			name:      "idempotent request is retried with backoff",
+			method:    http.MethodGet,
+			status:    http.StatusServiceUnavailable,
+			wantCalls: 4,
+			wantWaits: []time.Duration{200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond},
+		},
+		{
+			name:      "Retry-After is honoured",
+			method:    http.MethodGet,
+			status:    http.StatusTooManyRequests,
+			header:    map[string]string{"Retry-After": "2"},
+			config:    Config{Retries: 1},
+			wantCalls: 2,
+			wantWaits: []time.Duration{2 * time.Second},
+		},
+		{
+			name:      "retries can be disabled",
+			method:    http.MethodGet,
+			status:    http.StatusBadGateway,
+			config:    Config{Retries: -1},
+			wantCalls: 1,
+			wantWaits: []time.Duration{},
+		},
+		{
+			name:      "client errors are not retried",
+			method:    http.MethodGet,
+			status:    http.StatusNotFound,
+			wantCalls: 1,
+			wantWaits: []time.Duration{},
+		},
+		{
+			name:      "POST is not retried",
+			method:    http.MethodPost,
+			status:    http.StatusServiceUnavailable,
+			wantCalls: 1,
+			wantWaits: []time.Duration{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int32
+			c, waits := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				for k, v := range tc.header {
+					w.Header().Set(k, v)
+				}
+				http.Error(w, "unavailable", tc.status)
+			}, tc.config)
+
+			req, _ := newRequest(tc.method, "/system/info", nil, nil)
+			_, err := c.do(context.Background(), req)
+			if StatusCode(err) != tc.status {
+				t.Errorf("status code, want: %d, got: %d (%v)", tc.status, StatusCode(err), err)
+			}
+			if calls != tc.wantCalls {
+				t.Errorf("calls, want: %d, got: %d", tc.wantCalls, calls)
+			}
+			if len(*waits) != len(tc.wantWaits) {
+				t.Fatalf("waits, want: %v, got: %v", tc.wantWaits, *waits)
+			}
+			for i, want := range tc.wantWaits {
+				if (*waits)[i] != want {
+					t.Errorf("wait %d, want: %s, got: %s", i, want, (*waits)[i])
+				}
+			}
+		})
+	}
+}
+
+func Test_Client_RetrySendsBodyAgain(t *testing.T) {
+	var calls int32
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"enabled":true}` {
+			t.Errorf("body, want: %s, got: %s", `{"enabled":true}`, body)
+		}
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}, Config{})
+
+	if err := c.SetReadOnly(context.Background(), types.ReadOnlyMode{Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("calls, want: %d, got: %d", 2, calls)
+	}
+}
+
+func Test_Client_Errors(t *testing.T) {
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		wantMessage string
+	}{
+		{"JSON envelope", "application/json", `{"code":404,"message":"function figlet not found"}`, "function figlet not found"},
+		{"JSON error", "application/json", `{"error":"function figlet not found"}`, "function figlet not found"},
+		{"problem", "application/problem+json", `{"type":"about:blank","title":"Not Found","status":404,"detail":"function figlet not found","code":"FunctionNotFound"}`, "function figlet not found"},
+		{"plain text", "text/plain", "function figlet not found\n", "function figlet not found"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tc.contentType)
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(tc.body))
+			}, Config{})
+
+			_, err := c.GetFunction(context.Background(), "figlet", "")
+			if !IsNotFound(err) {
+				t.Fatalf("want not found, got: %v", err)
+			}
+			if got := err.(*Error).Message; got != tc.wantMessage {
+				t.Errorf("message, want: %q, got: %q", tc.wantMessage, got)
+			}
+		})
+	}
+}
+
+func Test_Client_Patch(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		patch, err := types.DecodeFunctionPatch(r)
+		if r.Method != http.MethodPatch || r.Header.Get("Content-Type") != types.MergePatchContentType || err != nil {
+			t.Errorf("want a merge patch, got: %s %s (%v)", r.Method, r.Header.Get("Content-Type"), err)
+		}
+		if want := `{"labels":{"tier":null},"namespace":"dev","service":"figlet"}`; string(patch.Patch) != want {
+			t.Errorf("patch, want: %s, got: %s", want, patch.Patch)
+		}
+	}, Config{})
+
+	if err := c.Patch(context.Background(), "figlet", "dev", map[string]interface{}{"labels": map[string]interface{}{"tier": nil}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_Client_WatchEvents(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("resourceVersion"); got != "7" {
+			t.Errorf("resourceVersion, want: %s, got: %s", "7", got)
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Write([]byte(`{"type":"added","resourceVersion":"8","name":"figlet"}` + "\n"))
+		w.Write([]byte(`{"type":"deleted","resourceVersion":"9","name":"figlet"}` + "\n"))
+	}, Config{})
+
+	events, err := c.WatchEvents(context.Background(), "dev", "7")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []types.WatchEvent
+	for event := range events {
+		got = append(got, event)
+	}
+	if len(got) != 2 || got[0].Type != types.WatchAdded || got[1].ResourceVersion != "9" {
+		t.Errorf("events, want added at 8 and deleted at 9, got: %+v", got)
+	}
+}
+
+func Test_Client_Timeout(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/system/functions/watch" {
+			for _, version := range []string{"8", "9"} {
+				time.Sleep(30 * time.Millisecond)
+				w.Write([]byte(`{"type":"added","resourceVersion":"` + version + `","name":"figlet"}` + "\n"))
+				w.(http.Flusher).Flush()
+			}
+			return
+		}
+
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+		w.Write([]byte("[]"))
+	}, Config{Timeout: 20 * time.Millisecond, Retries: -1})
+
+	if _, err := c.ListFunctions(context.Background(), ""); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ListFunctions, want: %s, got: %v", context.DeadlineExceeded, err)
+	}
+
+	events, err := c.WatchEvents(context.Background(), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := 0
+	for range events {
+		got++
+	}
+	if got != 2 {
+		t.Errorf("events streamed past the timeout, want: %d, got: %d", 2, got)
+	}
+}
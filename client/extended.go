@@ -0,0 +1,272 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+// confirmKillHeader passes the confirmation token to "/danger/kill", see
+// FaaSConfig.KillConfirmationToken.
+const confirmKillHeader = "X-Confirm-Kill"
+
+// callIDHeader identifies an asynchronous invocation, see queue.CallIDHeader.
+const callIDHeader = "X-Call-Id"
+
+// callbackURLHeader is where the result of an asynchronous invocation is posted to, see
+// queue.CallbackURLHeader.
+const callbackURLHeader = "X-Callback-Url"
+
+// instanceHeader addresses an invocation to one instance of a function, see
+// httputil.InstanceHeader.
+const instanceHeader = "X-Instance-Id"
+
+// Invocation is a request to a function.
+type Invocation struct {
+	// Function is the name of the function, which may be given as "name.namespace"
+	Function string
+	// Method of the request, POST when empty
+	Method string
+	// Path is appended to the URL of the function, i.e. "/items/1"
+	Path string
+	// Query is the query string of the request
+	Query url.Values
+	// Header of the request
+	Header http.Header
+	// Body of the request
+	Body []byte
+	// Instance is the ID of the instance which must serve the invocation, as returned by
+	// GetFunctionInstances, any instance may serve it when empty
+	Instance string
+}
+
+// Register registers an instance of a function with the provider, through
+// "/system/register".
+func (c *Client) Register(ctx context.Context, register types.RegisterRequest) (types.RegisterResponse, error) {
+	res := types.RegisterResponse{}
+
+	req, err := newRequest(http.MethodPost, "/system/register", nil, register)
+	if err != nil {
+		return res, err
+	}
+	err = c.doJSON(ctx, req, &res)
+	return res, err
+}
+
+// Invoke calls a function through "/invoke/{name}" and returns its response, which the
+// caller must close. Unlike the other methods, a response with a status code outside of
+// 2xx is returned rather than an Error, since it may have been written by the function.
+// Invocations are not retried.
+func (c *Client) Invoke(ctx context.Context, invocation Invocation) (*http.Response, error) {
+	return c.send(ctx, invocationRequest("/invoke/", invocation))
+}
+
+// InvokeAsync queues an invocation of a function through "/async-function/{name}" and
+// returns the X-Call-Id the provider assigned to it. When callbackURL is set, the result
+// of the invocation is posted to it.
+func (c *Client) InvokeAsync(ctx context.Context, invocation Invocation, callbackURL string) (string, error) {
+	req := invocationRequest("/async-function/", invocation)
+	if len(callbackURL) > 0 {
+		req.header.Set(callbackURLHeader, callbackURL)
+	}
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	res, err := c.do(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	res.Body.Close()
+
+	return res.Header.Get(callIDHeader), nil
+}
+
+func invocationRequest(prefix string, invocation Invocation) request {
+	method := invocation.Method
+	if len(method) == 0 {
+		method = http.MethodPost
+	}
+
+	header := invocation.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	if len(invocation.Instance) > 0 {
+		header.Set(instanceHeader, invocation.Instance)
+	}
+
+	return request{
+		method: method,
+		path:   prefix + invocation.Function + invocation.Path,
+		query:  invocation.Query,
+		header: header,
+		body:   bytes.Clone(invocation.Body),
+	}
+}
+
+// Metrics returns the metrics of every function instance, from "/system/metrics".
+func (c *Client) Metrics(ctx context.Context) ([]types.InstanceMetrics, error) {
+	req, _ := newRequest(http.MethodGet, "/system/metrics", nil, nil)
+	req.header.Set("Accept", "application/json")
+
+	metrics := []types.InstanceMetrics{}
+	err := c.doJSON(ctx, req, &metrics)
+	return metrics, err
+}
+
+// InstanceMetrics returns the metrics of one function instance, from "/system/metrics".
+func (c *Client) InstanceMetrics(ctx context.Context, instance string) ([]types.InstanceMetrics, error) {
+	req, _ := newRequest(http.MethodGet, "/system/metrics", url.Values{"instance": []string{instance}}, nil)
+	req.header.Set("Accept", "application/json")
+
+	metrics := []types.InstanceMetrics{}
+	err := c.doJSON(ctx, req, &metrics)
+	return metrics, err
+}
+
+// ResetMetrics resets the invocation counters of a function, or of every function when
+// function is empty.
+func (c *Client) ResetMetrics(ctx context.Context, function, namespace string) (types.MetricResetResult, error) {
+	query := url.Values{}
+	if len(function) > 0 {
+		query.Set("function", function)
+	}
+	if len(namespace) > 0 {
+		query.Set("namespace", namespace)
+	}
+
+	req, _ := newRequest(http.MethodDelete, "/system/metrics", query, nil)
+
+	result := types.MetricResetResult{}
+	err := c.doJSON(ctx, req, &result)
+	return result, err
+}
+
+// ListCheckpoints returns the checkpoints of the functions in namespace.
+func (c *Client) ListCheckpoints(ctx context.Context, namespace string) ([]types.Checkpoint, error) {
+	req, _ := newRequest(http.MethodGet, "/system/checkpoints", namespaceQuery(namespace), nil)
+
+	checkpoints := []types.Checkpoint{}
+	err := c.doJSON(ctx, req, &checkpoints)
+	return checkpoints, err
+}
+
+// CreateCheckpoint takes a checkpoint of a running instance of a function.
+func (c *Client) CreateCheckpoint(ctx context.Context, function, namespace string) (types.Checkpoint, error) {
+	checkpoint := types.Checkpoint{}
+
+	req, err := newRequest(http.MethodPost, "/system/function/"+function+"/checkpoint", nil, types.CreateCheckpointRequest{
+		Namespace: namespace,
+	})
+	if err != nil {
+		return checkpoint, err
+	}
+	err = c.doJSON(ctx, req, &checkpoint)
+	return checkpoint, err
+}
+
+// RestoreCheckpoint starts an instance of a function from one of its checkpoints.
+func (c *Client) RestoreCheckpoint(ctx context.Context, function string, restore types.RestoreCheckpointRequest) (types.RestoreCheckpointResponse, error) {
+	res := types.RestoreCheckpointResponse{}
+
+	req, err := newRequest(http.MethodPost, "/system/function/"+function+"/restore", nil, restore)
+	if err != nil {
+		return res, err
+	}
+	err = c.doJSON(ctx, req, &res)
+	return res, err
+}
+
+// GetCheckpoint returns the checkpoint with the given ID.
+func (c *Client) GetCheckpoint(ctx context.Context, id string) (types.Checkpoint, error) {
+	req, _ := newRequest(http.MethodGet, "/system/checkpoint/"+id, nil, nil)
+
+	checkpoint := types.Checkpoint{}
+	err := c.doJSON(ctx, req, &checkpoint)
+	return checkpoint, err
+}
+
+// DeleteCheckpoint removes the checkpoint with the given ID.
+func (c *Client) DeleteCheckpoint(ctx context.Context, id string) error {
+	req, _ := newRequest(http.MethodDelete, "/system/checkpoint/"+id, nil, nil)
+	return c.doJSON(ctx, req, nil)
+}
+
+// CollectCheckpoints deletes the checkpoints outside of the provider's retention policy,
+// see FaaSConfig.CheckpointRetention.
+func (c *Client) CollectCheckpoints(ctx context.Context) (types.CheckpointGCResult, error) {
+	req, _ := newRequest(http.MethodPost, "/system/checkpoints/gc", nil, nil)
+
+	result := types.CheckpointGCResult{}
+	err := c.doJSON(ctx, req, &result)
+	return result, err
+}
+
+// GetWarmPool returns the warm pool of a function, see FaaSHandlers.WarmPool.
+func (c *Client) GetWarmPool(ctx context.Context, function, namespace string) (types.WarmPoolStatus, error) {
+	req, _ := newRequest(http.MethodGet, "/system/warm-pool/"+function, namespaceQuery(namespace), nil)
+
+	status := types.WarmPoolStatus{}
+	err := c.doJSON(ctx, req, &status)
+	return status, err
+}
+
+// SetWarmPool sets the policy of the warm pool of a function, a MinWarm of 0 removes it.
+func (c *Client) SetWarmPool(ctx context.Context, function string, policy types.WarmPoolPolicy) (types.WarmPoolStatus, error) {
+	status := types.WarmPoolStatus{}
+
+	req, err := newRequest(http.MethodPost, "/system/warm-pool/"+function, namespaceQuery(policy.Namespace), policy)
+	if err != nil {
+		return status, err
+	}
+	err = c.doJSON(ctx, req, &status)
+	return status, err
+}
+
+// Kill kills the function instances selected by kill through "/danger/kill", with confirm
+// as the confirmation token, see FaaSConfig.KillConfirmationToken.
+func (c *Client) Kill(ctx context.Context, kill types.KillRequest, confirm string) (types.KillResponse, error) {
+	res := types.KillResponse{}
+
+	// The namespace is checked against the allowed namespaces from the query string.
+	req, err := newRequest(http.MethodPost, "/danger/kill", namespaceQuery(kill.Namespace), kill)
+	if err != nil {
+		return res, err
+	}
+	if len(confirm) > 0 {
+		req.header.Set(confirmKillHeader, confirm)
+	}
+
+	err = c.doJSON(ctx, req, &res)
+	return res, err
+}
+
+// Cordon stops routing invocations to the instances of a function selected by cordon, or
+// routes them again when cordon.Uncordon is set.
+func (c *Client) Cordon(ctx context.Context, function, namespace string, cordon types.CordonRequest) (types.DrainStatus, error) {
+	status := types.DrainStatus{}
+
+	req, err := newRequest(http.MethodPost, "/system/cordon/"+function, namespaceQuery(namespace), cordon)
+	if err != nil {
+		return status, err
+	}
+	err = c.doJSON(ctx, req, &status)
+	return status, err
+}
+
+// Drain starts to evict the instances of a function selected by drain. It returns once the
+// drain has started, its progress is streamed by WatchEvents.
+func (c *Client) Drain(ctx context.Context, function, namespace string, drain types.DrainRequest) (types.DrainStatus, error) {
+	status := types.DrainStatus{}
+
+	req, err := newRequest(http.MethodPost, "/system/drain/"+function, namespaceQuery(namespace), drain)
+	if err != nil {
+		return status, err
+	}
+	err = c.doJSON(ctx, req, &status)
+	return status, err
+}
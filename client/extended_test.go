@@ -0,0 +1,253 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+// sentRequest is what the test server received.
+type sentRequest struct {
+	method string
+	uri    string
+	header http.Header
+	body   string
+}
+
+// newRecordingClient creates a Client for a server which records the request and responds
+// with status and body.
+func newRecordingClient(t *testing.T, status int, body string) (*Client, *sentRequest) {
+	t.Helper()
+
+	sent := &sentRequest{}
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		*sent = sentRequest{method: r.Method, uri: r.URL.RequestURI(), header: r.Header, body: string(b)}
+
+		w.Header().Set("X-Call-Id", "call-1")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}, Config{})
+	return c, sent
+}
+
+func Test_Client_ExtendedRoutes(t *testing.T) {
+	cases := []struct {
+		name       string
+		response   string
+		call       func(c *Client) (interface{}, error)
+		wantMethod string
+		wantURI    string
+		wantBody   string
+		want       string
+	}{
+		{
+			name:     "register",
+			response: `{"name":"figlet","address":"10.0.0.2:8080"}`,
+			call: func(c *Client) (interface{}, error) {
+				return c.Register(context.Background(), types.RegisterRequest{Name: "figlet", Address: "10.0.0.2:8080", Ready: true})
+			},
+			wantMethod: http.MethodPost,
+			wantURI:    "/system/register",
+			wantBody:   `{"name":"figlet","address":"10.0.0.2:8080","ready":true}`,
+			want:       `{"name":"figlet","address":"10.0.0.2:8080"}`,
+		},
+		{
+			name:     "metrics",
+			response: `[{"function":"figlet","instance":"a","invocations":3,"inFlight":0,"cpuSeconds":0,"memoryBytes":0,"startedAt":"0001-01-01T00:00:00Z"}]`,
+			call: func(c *Client) (interface{}, error) {
+				return c.Metrics(context.Background())
+			},
+			wantMethod: http.MethodGet,
+			wantURI:    "/system/metrics",
+			want:       `[{"function":"figlet","instance":"a","invocations":3,"inFlight":0,"cpuSeconds":0,"memoryBytes":0,"startedAt":"0001-01-01T00:00:00Z"}]`,
+		},
+		{
+			name:     "instance metrics",
+			response: `[]`,
+			call: func(c *Client) (interface{}, error) {
+				return c.InstanceMetrics(context.Background(), "figlet-1")
+			},
+			wantMethod: http.MethodGet,
+			wantURI:    "/system/metrics?instance=figlet-1",
+			want:       `[]`,
+		},
+		{
+			name:     "reset metrics",
+			response: `{"function":"figlet","namespace":"dev","reset":1,"resetAt":"0001-01-01T00:00:00Z"}`,
+			call: func(c *Client) (interface{}, error) {
+				return c.ResetMetrics(context.Background(), "figlet", "dev")
+			},
+			wantMethod: http.MethodDelete,
+			wantURI:    "/system/metrics?function=figlet&namespace=dev",
+			want:       `{"function":"figlet","namespace":"dev","reset":1,"resetAt":"0001-01-01T00:00:00Z"}`,
+		},
+		{
+			name:     "list checkpoints",
+			response: `[]`,
+			call: func(c *Client) (interface{}, error) {
+				return c.ListCheckpoints(context.Background(), "dev")
+			},
+			wantMethod: http.MethodGet,
+			wantURI:    "/system/checkpoints?namespace=dev",
+			want:       `[]`,
+		},
+		{
+			name:     "create checkpoint",
+			response: `{"function":"figlet","id":"cp-1","createdAt":"0001-01-01T00:00:00Z","sizeBytes":10}`,
+			call: func(c *Client) (interface{}, error) {
+				return c.CreateCheckpoint(context.Background(), "figlet", "dev")
+			},
+			wantMethod: http.MethodPost,
+			wantURI:    "/system/function/figlet/checkpoint",
+			wantBody:   `{"namespace":"dev"}`,
+			want:       `{"function":"figlet","id":"cp-1","createdAt":"0001-01-01T00:00:00Z","sizeBytes":10}`,
+		},
+		{
+			name:     "restore checkpoint",
+			response: `{"function":"figlet","checkpointId":"cp-1","restoredAt":"0001-01-01T00:00:00Z"}`,
+			call: func(c *Client) (interface{}, error) {
+				return c.RestoreCheckpoint(context.Background(), "figlet", types.RestoreCheckpointRequest{CheckpointID: "cp-1"})
+			},
+			wantMethod: http.MethodPost,
+			wantURI:    "/system/function/figlet/restore",
+			wantBody:   `{"checkpointId":"cp-1"}`,
+			want:       `{"function":"figlet","checkpointId":"cp-1","restoredAt":"0001-01-01T00:00:00Z"}`,
+		},
+		{
+			name:     "checkpoint gc",
+			response: `{"deleted":[],"freedBytes":20}`,
+			call: func(c *Client) (interface{}, error) {
+				return c.CollectCheckpoints(context.Background())
+			},
+			wantMethod: http.MethodPost,
+			wantURI:    "/system/checkpoints/gc",
+			want:       `{"deleted":[],"freedBytes":20}`,
+		},
+		{
+			name:     "kill",
+			response: `{"killed":3,"namespace":"dev","function":"figlet"}`,
+			call: func(c *Client) (interface{}, error) {
+				return c.Kill(context.Background(), types.KillRequest{Namespace: "dev", Function: "figlet"}, "yes")
+			},
+			wantMethod: http.MethodPost,
+			wantURI:    "/danger/kill?namespace=dev",
+			wantBody:   `{"namespace":"dev","function":"figlet"}`,
+			want:       `{"killed":3,"namespace":"dev","function":"figlet"}`,
+		},
+		{
+			name:     "set warm pool",
+			response: `{"function":"figlet","namespace":"dev","policy":{"namespace":"dev","minWarm":2,"checkpointId":"cp-1"},"warm":0,"starting":2}`,
+			call: func(c *Client) (interface{}, error) {
+				return c.SetWarmPool(context.Background(), "figlet", types.WarmPoolPolicy{Namespace: "dev", MinWarm: 2, CheckpointID: "cp-1"})
+			},
+			wantMethod: http.MethodPost,
+			wantURI:    "/system/warm-pool/figlet?namespace=dev",
+			wantBody:   `{"namespace":"dev","minWarm":2,"checkpointId":"cp-1"}`,
+			want:       `{"function":"figlet","namespace":"dev","policy":{"namespace":"dev","minWarm":2,"checkpointId":"cp-1"},"warm":0,"starting":2}`,
+		},
+		{
+			name:     "cordon",
+			response: `{"function":"figlet","namespace":"dev","instances":[{"id":"figlet-1","stage":"cordoned"}]}`,
+			call: func(c *Client) (interface{}, error) {
+				return c.Cordon(context.Background(), "figlet", "dev", types.CordonRequest{Instances: []string{"figlet-1"}})
+			},
+			wantMethod: http.MethodPost,
+			wantURI:    "/system/cordon/figlet?namespace=dev",
+			wantBody:   `{"instances":["figlet-1"]}`,
+			want:       `{"function":"figlet","namespace":"dev","instances":[{"id":"figlet-1","stage":"cordoned"}]}`,
+		},
+		{
+			name:     "drain",
+			response: `{"function":"figlet","instances":[{"id":"figlet-1","stage":"cordoned","inFlight":2}]}`,
+			call: func(c *Client) (interface{}, error) {
+				return c.Drain(context.Background(), "figlet", "", types.DrainRequest{Checkpoint: true, TimeoutSeconds: 30})
+			},
+			wantMethod: http.MethodPost,
+			wantURI:    "/system/drain/figlet",
+			wantBody:   `{"checkpoint":true,"timeoutSeconds":30}`,
+			want:       `{"function":"figlet","instances":[{"id":"figlet-1","stage":"cordoned","inFlight":2}]}`,
+		},
+		{
+			name: "invoke async",
+			call: func(c *Client) (interface{}, error) {
+				return c.InvokeAsync(context.Background(), Invocation{Function: "figlet.dev", Body: []byte("hi")}, "http://callback.local/")
+			},
+			wantMethod: http.MethodPost,
+			wantURI:    "/async-function/figlet.dev",
+			wantBody:   "hi",
+			want:       `"call-1"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, sent := newRecordingClient(t, http.StatusOK, tc.response)
+
+			got, err := tc.call(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if sent.method != tc.wantMethod {
+				t.Errorf("method, want: %s, got: %s", tc.wantMethod, sent.method)
+			}
+			if sent.uri != tc.wantURI {
+				t.Errorf("URI, want: %s, got: %s", tc.wantURI, sent.uri)
+			}
+			if sent.body != tc.wantBody {
+				t.Errorf("body, want: %s, got: %s", tc.wantBody, sent.body)
+			}
+
+			body, _ := json.Marshal(got)
+			if string(body) != tc.want {
+				t.Errorf("result, want: %s, got: %s", tc.want, body)
+			}
+		})
+	}
+}
+
+func Test_Client_KillConfirmation(t *testing.T) {
+	c, sent := newRecordingClient(t, http.StatusOK, `{"killed":0}`)
+
+	if _, err := c.Kill(context.Background(), types.KillRequest{}, "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	if got := sent.header.Get(confirmKillHeader); got != "s3cret" {
+		t.Errorf("%s, want: %s, got: %s", confirmKillHeader, "s3cret", got)
+	}
+}
+
+func Test_Client_InvokeReturnsFunctionErrors(t *testing.T) {
+	c, sent := newRecordingClient(t, http.StatusInternalServerError, "function failed")
+
+	res, err := c.Invoke(context.Background(), Invocation{
+		Function: "figlet",
+		Method:   http.MethodPut,
+		Path:     "/items/1",
+		Header:   http.Header{"X-Trace": []string{"1"}},
+		Body:     []byte("hi"),
+		Instance: "figlet-1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusInternalServerError {
+		t.Errorf("status, want: %d, got: %d", http.StatusInternalServerError, res.StatusCode)
+	}
+	if sent.method != http.MethodPut || sent.uri != "/invoke/figlet/items/1" || sent.body != "hi" {
+		t.Errorf("request, want: PUT /invoke/figlet/items/1 hi, got: %s %s %s", sent.method, sent.uri, sent.body)
+	}
+	if sent.header.Get("X-Trace") != "1" {
+		t.Errorf("X-Trace, want: %s, got: %s", "1", sent.header.Get("X-Trace"))
+	}
+	if sent.header.Get(instanceHeader) != "figlet-1" {
+		t.Errorf("%s, want: %s, got: %s", instanceHeader, "figlet-1", sent.header.Get(instanceHeader))
+	}
+}
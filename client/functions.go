@@ -0,0 +1,383 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-provider/logs"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// ListFunctions returns the functions deployed to namespace, or to the provider's default
+// namespace when it is empty.
+func (c *Client) ListFunctions(ctx context.Context, namespace string) ([]types.FunctionStatus, error) {
+	req, _ := newRequest(http.MethodGet, "/system/functions", namespaceQuery(namespace), nil)
+
+	functions := []types.FunctionStatus{}
+	err := c.doJSON(ctx, req, &functions)
+	return functions, err
+}
+
+// GetFunction returns the status of a function, IsNotFound is true for the error when it
+// is not deployed.
+func (c *Client) GetFunction(ctx context.Context, name, namespace string) (types.FunctionStatus, error) {
+	req, _ := newRequest(http.MethodGet, "/system/function/"+name, namespaceQuery(namespace), nil)
+
+	status := types.FunctionStatus{}
+	err := c.doJSON(ctx, req, &status)
+	return status, err
+}
+
+// GetFunctionSpec returns the FunctionDeployment a function was deployed with.
+func (c *Client) GetFunctionSpec(ctx context.Context, name, namespace string) (types.FunctionDeployment, error) {
+	req, _ := newRequest(http.MethodGet, "/system/function/"+name+"/spec", namespaceQuery(namespace), nil)
+
+	spec := types.FunctionDeployment{}
+	err := c.doJSON(ctx, req, &spec)
+	return spec, err
+}
+
+// GetFunctionInstances returns the running instances of a function.
+func (c *Client) GetFunctionInstances(ctx context.Context, name, namespace string) ([]types.FunctionInstance, error) {
+	req, _ := newRequest(http.MethodGet, "/system/function/"+name+"/instances", namespaceQuery(namespace), nil)
+
+	instances := []types.FunctionInstance{}
+	err := c.doJSON(ctx, req, &instances)
+	return instances, err
+}
+
+// Deploy deploys a new function.
+func (c *Client) Deploy(ctx context.Context, deployment types.FunctionDeployment) error {
+	req, err := newRequest(http.MethodPost, "/system/functions", nil, deployment)
+	if err != nil {
+		return err
+	}
+	return c.doJSON(ctx, req, nil)
+}
+
+// Update updates a deployed function.
+func (c *Client) Update(ctx context.Context, deployment types.FunctionDeployment) error {
+	req, err := newRequest(http.MethodPut, "/system/functions", nil, deployment)
+	if err != nil {
+		return err
+	}
+	return c.doJSON(ctx, req, nil)
+}
+
+// Patch updates the fields of a deployed function given in patch, a JSON merge patch of
+// its FunctionDeployment, leaving the others as they are. A field set to nil is removed.
+func (c *Client) Patch(ctx context.Context, name, namespace string, patch map[string]interface{}) error {
+	body := map[string]interface{}{}
+	for key, value := range patch {
+		body[key] = value
+	}
+	body["service"] = name
+	if len(namespace) > 0 {
+		body["namespace"] = namespace
+	}
+
+	req, err := newRequest(http.MethodPatch, "/system/functions", nil, body)
+	if err != nil {
+		return err
+	}
+	req.header.Set("Content-Type", types.MergePatchContentType)
+	return c.doJSON(ctx, req, nil)
+}
+
+// DeleteFunction removes a deployed function.
+func (c *Client) DeleteFunction(ctx context.Context, name, namespace string) error {
+	req, err := newRequest(http.MethodDelete, "/system/functions", nil, types.DeleteFunctionRequest{
+		FunctionName: name,
+		Namespace:    namespace,
+	})
+	if err != nil {
+		return err
+	}
+	return c.doJSON(ctx, req, nil)
+}
+
+// ScaleFunction sets the desired replicas of a function.
+func (c *Client) ScaleFunction(ctx context.Context, name, namespace string, replicas uint64) error {
+	req, err := newRequest(http.MethodPost, "/system/scale-function/"+name, nil, types.ScaleServiceRequest{
+		ServiceName: name,
+		Namespace:   namespace,
+		Replicas:    replicas,
+	})
+	if err != nil {
+		return err
+	}
+	return c.doJSON(ctx, req, nil)
+}
+
+// WatchFunctions streams a FunctionEvent whenever a function is added, changed or removed,
+// until ctx is cancelled or the server closes the stream. The channel is closed then.
+func (c *Client) WatchFunctions(ctx context.Context, namespace string) (<-chan types.FunctionEvent, error) {
+	req, _ := newRequest(http.MethodGet, "/system/functions/watch", namespaceQuery(namespace), nil)
+	req.header.Set("Accept", "text/event-stream")
+
+	res, err := c.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan types.FunctionEvent)
+	go func() {
+		defer close(events)
+		defer res.Body.Close()
+
+		scanner := bufio.NewScanner(res.Body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+
+			event := types.FunctionEvent{}
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				continue
+			}
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// WatchEvents streams a WatchEvent for each function event published by a provider which
+// relies on the built-in watch, starting after resourceVersion, or with the next event
+// when it is empty. It returns an error with a 410 status, see StatusCode, when the events
+// after resourceVersion are no longer kept. The channel is closed when ctx is cancelled or
+// the server closes the stream, the client then resumes from the ResourceVersion of the
+// last event it received.
+func (c *Client) WatchEvents(ctx context.Context, namespace, resourceVersion string) (<-chan types.WatchEvent, error) {
+	query := namespaceQuery(namespace)
+	if len(resourceVersion) > 0 {
+		if query == nil {
+			query = url.Values{}
+		}
+		query.Set("resourceVersion", resourceVersion)
+	}
+
+	req, _ := newRequest(http.MethodGet, "/system/functions/watch", query, nil)
+	res, err := c.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan types.WatchEvent)
+	go func() {
+		defer close(events)
+		defer res.Body.Close()
+
+		decoder := json.NewDecoder(res.Body)
+		for {
+			event := types.WatchEvent{}
+			if err := decoder.Decode(&event); err != nil {
+				return
+			}
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// Logs streams the log messages selected by logReq, until ctx is cancelled or the server
+// ends the stream, i.e. when logReq.Follow is false and the logs have been read. The
+// channel is closed then.
+func (c *Client) Logs(ctx context.Context, logReq logs.Request) (<-chan logs.Message, error) {
+	query := url.Values{"name": []string{logReq.Name}}
+	if len(logReq.Namespace) > 0 {
+		query.Set("namespace", logReq.Namespace)
+	}
+	if len(logReq.Instance) > 0 {
+		query.Set("instance", logReq.Instance)
+	}
+	if logReq.Since != nil {
+		query.Set("since", logReq.Since.Format(time.RFC3339))
+	}
+	if logReq.Tail > 0 {
+		query.Set("tail", strconv.Itoa(logReq.Tail))
+	}
+	if logReq.Follow {
+		query.Set("follow", "true")
+	}
+
+	req, _ := newRequest(http.MethodGet, "/system/logs", query, nil)
+	res, err := c.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make(chan logs.Message)
+	go func() {
+		defer close(messages)
+		defer res.Body.Close()
+
+		decoder := json.NewDecoder(res.Body)
+		for {
+			msg := logs.Message{}
+			if err := decoder.Decode(&msg); err != nil {
+				return
+			}
+
+			select {
+			case messages <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return messages, nil
+}
+
+// ListSecrets returns the secrets of namespace, without their values.
+func (c *Client) ListSecrets(ctx context.Context, namespace string) ([]types.Secret, error) {
+	req, _ := newRequest(http.MethodGet, "/system/secrets", namespaceQuery(namespace), nil)
+
+	secrets := []types.Secret{}
+	err := c.doJSON(ctx, req, &secrets)
+	return secrets, err
+}
+
+// CreateSecret creates a secret, the request fails with a 409 when it already exists.
+func (c *Client) CreateSecret(ctx context.Context, secret types.Secret) error {
+	return c.secret(ctx, http.MethodPost, secret)
+}
+
+// UpdateSecret changes the value of an existing secret.
+func (c *Client) UpdateSecret(ctx context.Context, secret types.Secret) error {
+	return c.secret(ctx, http.MethodPut, secret)
+}
+
+// DeleteSecret removes the secret with the name and namespace of secret.
+func (c *Client) DeleteSecret(ctx context.Context, secret types.Secret) error {
+	return c.secret(ctx, http.MethodDelete, secret)
+}
+
+func (c *Client) secret(ctx context.Context, method string, secret types.Secret) error {
+	req, err := newRequest(method, "/system/secrets", nil, secret)
+	if err != nil {
+		return err
+	}
+	return c.doJSON(ctx, req, nil)
+}
+
+// ListNamespaces returns the namespaces functions can be deployed to.
+func (c *Client) ListNamespaces(ctx context.Context) ([]string, error) {
+	req, _ := newRequest(http.MethodGet, "/system/namespaces", nil, nil)
+
+	namespaces := []string{}
+	err := c.doJSON(ctx, req, &namespaces)
+	return namespaces, err
+}
+
+// GetNamespace returns a namespace with its labels and annotations.
+func (c *Client) GetNamespace(ctx context.Context, name string) (types.FunctionNamespace, error) {
+	req, _ := newRequest(http.MethodGet, "/system/namespace/"+name, nil, nil)
+
+	namespace := types.FunctionNamespace{}
+	err := c.doJSON(ctx, req, &namespace)
+	return namespace, err
+}
+
+// CreateNamespace creates a namespace, when the provider supports it.
+func (c *Client) CreateNamespace(ctx context.Context, namespace types.FunctionNamespace) error {
+	return c.namespace(ctx, http.MethodPost, namespace)
+}
+
+// UpdateNamespace changes the labels and annotations of a namespace.
+func (c *Client) UpdateNamespace(ctx context.Context, namespace types.FunctionNamespace) error {
+	return c.namespace(ctx, http.MethodPut, namespace)
+}
+
+// DeleteNamespace removes a namespace.
+func (c *Client) DeleteNamespace(ctx context.Context, name string) error {
+	req, _ := newRequest(http.MethodDelete, "/system/namespace/"+name, nil, nil)
+	return c.doJSON(ctx, req, nil)
+}
+
+func (c *Client) namespace(ctx context.Context, method string, namespace types.FunctionNamespace) error {
+	req, err := newRequest(method, "/system/namespace/"+namespace.Name, nil, namespace)
+	if err != nil {
+		return err
+	}
+	return c.doJSON(ctx, req, nil)
+}
+
+// Info returns the name and version of the provider.
+func (c *Client) Info(ctx context.Context) (types.ProviderInfo, error) {
+	req, _ := newRequest(http.MethodGet, "/system/info", nil, nil)
+
+	info := types.ProviderInfo{}
+	err := c.doJSON(ctx, req, &info)
+	return info, err
+}
+
+// Capabilities returns which of the optional routes the provider serves.
+func (c *Client) Capabilities(ctx context.Context) (types.Capabilities, error) {
+	req, _ := newRequest(http.MethodGet, "/system/capabilities", nil, nil)
+
+	capabilities := types.Capabilities{}
+	err := c.doJSON(ctx, req, &capabilities)
+	return capabilities, err
+}
+
+// ReadOnly returns whether the provider rejects changes to functions.
+func (c *Client) ReadOnly(ctx context.Context) (types.ReadOnlyMode, error) {
+	req, _ := newRequest(http.MethodGet, "/system/read-only", nil, nil)
+
+	mode := types.ReadOnlyMode{}
+	err := c.doJSON(ctx, req, &mode)
+	return mode, err
+}
+
+// SetReadOnly turns the read-only mode of the provider on or off.
+func (c *Client) SetReadOnly(ctx context.Context, mode types.ReadOnlyMode) error {
+	req, err := newRequest(http.MethodPut, "/system/read-only", nil, mode)
+	if err != nil {
+		return err
+	}
+	return c.doJSON(ctx, req, nil)
+}
+
+// Maintenance returns the maintenance mode of the provider.
+func (c *Client) Maintenance(ctx context.Context) (types.MaintenanceMode, error) {
+	req, _ := newRequest(http.MethodGet, "/system/maintenance", nil, nil)
+
+	mode := types.MaintenanceMode{}
+	err := c.doJSON(ctx, req, &mode)
+	return mode, err
+}
+
+// SetMaintenance starts or ends a maintenance window.
+func (c *Client) SetMaintenance(ctx context.Context, mode types.MaintenanceMode) error {
+	req, err := newRequest(http.MethodPut, "/system/maintenance", nil, mode)
+	if err != nil {
+		return err
+	}
+	return c.doJSON(ctx, req, nil)
+}
+
+// Ready returns nil when the provider reports that it is ready to serve requests.
+func (c *Client) Ready(ctx context.Context) error {
+	req, _ := newRequest(http.MethodGet, "/readyz", nil, nil)
+	return c.doJSON(ctx, req, nil)
+}
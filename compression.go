@@ -0,0 +1,178 @@
+package bootstrap
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/openfaas/faas-provider/httputil"
+)
+
+// compression decompresses gzip request bodies and compresses responses with gzip on the
+// "/system/" API. Requests to functions are passed through as they are, as their encoding
+// is for the function to handle.
+type compression struct {
+	decompress      bool
+	compress        bool
+	maxDecompressed int64
+}
+
+// defaultMaxDecompressedBytes caps the decompressed size of a request body when
+// MaxRequestBodyBytes is not set, so that a small body can not expand without bound.
+const defaultMaxDecompressedBytes = 32 * 1024 * 1024
+
+// newCompression creates the middleware, decompressing requests up to maxDecompressed
+// bytes when decompress is set, or defaultMaxDecompressedBytes when it is 0, and
+// compressing responses when compress is set.
+func newCompression(decompress, compress bool, maxDecompressed int64) *compression {
+	if maxDecompressed <= 0 {
+		maxDecompressed = defaultMaxDecompressedBytes
+	}
+	return &compression{decompress: decompress, compress: compress, maxDecompressed: maxDecompressed}
+}
+
+// middleware replaces the body of a request sent with "Content-Encoding: gzip" by its
+// decompressed content, read through http.MaxBytesReader at maxDecompressed, so that every
+// handler sees an error wrapping *http.MaxBytesError for a body which expands past it. Other
+// encodings are rejected with a 415. Responses are compressed when the client accepts gzip.
+func (c *compression) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/system/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if c.decompress && r.Body != nil && r.Body != http.NoBody {
+			switch encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding {
+			case "", "identity":
+			case "gzip", "x-gzip":
+				gz, err := gzip.NewReader(r.Body)
+				if err != nil {
+					httputil.WriteErrorCode(w, r, http.StatusBadRequest, httputil.InvalidRequest, fmt.Sprintf("invalid gzip request body: %s", err))
+					return
+				}
+
+				r.Body = http.MaxBytesReader(w, &gzipReadCloser{Reader: gz, body: r.Body}, c.maxDecompressed)
+				r.ContentLength = -1
+				r.Header.Del("Content-Encoding")
+				r.Header.Del("Content-Length")
+			default:
+				httputil.WriteErrorCode(w, r, http.StatusUnsupportedMediaType, httputil.UnsupportedMediaType, fmt.Sprintf("unsupported Content-Encoding %q, only gzip is accepted", encoding))
+				return
+			}
+		}
+
+		if c.compress && r.Method != http.MethodHead && acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			gw := &gzipResponseWriter{ResponseWriter: w}
+			defer gw.close()
+			w = gw
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// acceptsGzip returns true when an Accept-Encoding header lists gzip with a quality above 0.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, err := strconv.ParseFloat(value, 64)
+			return err == nil && q > 0
+		}
+		return true
+	}
+	return false
+}
+
+// gzipReadCloser closes both the gzip reader and the body it reads.
+type gzipReadCloser struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (g *gzipReadCloser) Close() error {
+	g.Reader.Close()
+	return g.body.Close()
+}
+
+// gzipResponseWriter compresses the body of a response, unless the handler set its own
+// Content-Encoding or the status has no body. Flush flushes the compressed stream, so that
+// streamed responses such as logs are still sent as they are written.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+func (g *gzipResponseWriter) WriteHeader(statusCode int) {
+	// Informational responses are followed by the final one.
+	if statusCode < http.StatusOK {
+		g.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	if g.wroteHeader {
+		return
+	}
+	g.wroteHeader = true
+
+	header := g.Header()
+	header.Add("Vary", "Accept-Encoding")
+
+	bodyless := statusCode == http.StatusNoContent || statusCode == http.StatusNotModified
+	if !bodyless && len(header.Get("Content-Encoding")) == 0 {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		// The compressed body is a different representation, see RFC 9110 section 8.8.3.
+		if etag := header.Get("ETag"); len(etag) > 0 && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+		g.gz = gzip.NewWriter(g.ResponseWriter)
+	}
+
+	g.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !g.wroteHeader {
+		// Sniffed before compression, as net/http would sniff the compressed bytes.
+		if len(g.Header().Get("Content-Type")) == 0 {
+			g.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		g.WriteHeader(http.StatusOK)
+	}
+
+	if g.gz == nil {
+		return g.ResponseWriter.Write(b)
+	}
+	return g.gz.Write(b)
+}
+
+func (g *gzipResponseWriter) Flush() {
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	if flusher, ok := g.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter, so that http.ResponseController can set
+// deadlines on the connection.
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+// close writes the end of the compressed stream, when a body was compressed.
+func (g *gzipResponseWriter) close() {
+	if g.gz != nil {
+		g.gz.Close()
+	}
+}
@@ -0,0 +1,226 @@
+package bootstrap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+func gzipped(t *testing.T, s string) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	if _, err := gz.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	gz.Close()
+	return buf.Bytes()
+}
+
+func Test_compression_DecompressesRequests(t *testing.T) {
+	body := `{"service":"figlet","image":"ghcr.io/openfaas/figlet:latest"}`
+
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.Write(b)
+	}
+
+	testCases := []struct {
+		name     string
+		config   types.FaaSConfig
+		path     string
+		encoding string
+		body     []byte
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "gzip deploy",
+			config:   types.FaaSConfig{DecompressRequests: true},
+			path:     "/system/functions",
+			encoding: "gzip",
+			body:     gzipped(t, body),
+			wantCode: http.StatusOK,
+			wantBody: body,
+		},
+		{
+			name:     "decompressed size over limit",
+			config:   types.FaaSConfig{DecompressRequests: true, MaxRequestBodyBytes: 32},
+			path:     "/system/functions",
+			encoding: "gzip",
+			body:     gzipped(t, strings.Repeat("a", 1024)),
+			wantCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:     "invalid gzip",
+			config:   types.FaaSConfig{DecompressRequests: true},
+			path:     "/system/functions",
+			encoding: "gzip",
+			body:     []byte(body),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "unsupported encoding",
+			config:   types.FaaSConfig{DecompressRequests: true},
+			path:     "/system/functions",
+			encoding: "br",
+			body:     []byte(body),
+			wantCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:     "function request is passed through",
+			config:   types.FaaSConfig{DecompressRequests: true},
+			path:     "/function/figlet",
+			encoding: "br",
+			body:     []byte(body),
+			wantCode: http.StatusOK,
+			wantBody: body,
+		},
+		{
+			name:     "disabled",
+			path:     "/system/functions",
+			encoding: "gzip",
+			body:     gzipped(t, body),
+			wantCode: http.StatusOK,
+			wantBody: string(gzipped(t, body)),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewServer(&tc.config)
+			s.Handlers(&types.FaaSHandlers{DeployFunction: echo, FunctionProxy: echo})
+
+			r := httptest.NewRequest(http.MethodPost, tc.path, bytes.NewReader(tc.body))
+			r.Header.Set("Content-Encoding", tc.encoding)
+
+			w := httptest.NewRecorder()
+			s.Router().ServeHTTP(w, r)
+
+			if w.Code != tc.wantCode {
+				t.Fatalf("status code, want: %d, got: %d (%s)", tc.wantCode, w.Code, w.Body.String())
+			}
+			if len(tc.wantBody) > 0 && w.Body.String() != tc.wantBody {
+				t.Errorf("body, want: %q, got: %q", tc.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func Test_compression_CapsDecompressedSize(t *testing.T) {
+	c := newCompression(true, false, 16)
+
+	var readErr error
+	handler := c.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/system/secrets", bytes.NewReader(gzipped(t, strings.Repeat("a", 1024))))
+	r.Header.Set("Content-Encoding", "gzip")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	var maxBytesErr *http.MaxBytesError
+	if !errors.As(readErr, &maxBytesErr) {
+		t.Errorf("want a *http.MaxBytesError reading past the cap, got: %v", readErr)
+	}
+
+	if got := newCompression(true, false, 0).maxDecompressed; got != defaultMaxDecompressedBytes {
+		t.Errorf("default cap, want: %d, got: %d", defaultMaxDecompressedBytes, got)
+	}
+}
+
+func Test_compression_CompressesResponses(t *testing.T) {
+	info := `{"provider":"faasd","orchestration":"containerd"}`
+
+	s := NewServer(&types.FaaSConfig{CompressResponses: true})
+	s.Handlers(&types.FaaSHandlers{
+		Info: func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(info))
+		},
+		FunctionProxy: func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("hello"))
+		},
+	})
+
+	testCases := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		wantGzip       bool
+	}{
+		{name: "system API with gzip", path: "/system/info", acceptEncoding: "gzip, deflate", wantGzip: true},
+		{name: "system API without gzip", path: "/system/info", acceptEncoding: "identity"},
+		{name: "gzip refused", path: "/system/info", acceptEncoding: "gzip;q=0"},
+		{name: "function", path: "/function/figlet", acceptEncoding: "gzip"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			r.Header.Set("Accept-Encoding", tc.acceptEncoding)
+
+			w := httptest.NewRecorder()
+			s.Router().ServeHTTP(w, r)
+
+			if got := w.Header().Get("Content-Encoding") == "gzip"; got != tc.wantGzip {
+				t.Fatalf("gzip, want: %v, got: %v", tc.wantGzip, got)
+			}
+			if !tc.wantGzip {
+				return
+			}
+
+			gz, err := gzip.NewReader(w.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := io.ReadAll(gz)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The Info handler is decorated with the time the provider started.
+			if !strings.Contains(string(body), `"provider":"faasd"`) {
+				t.Errorf("body, want: %s, got: %s", info, body)
+			}
+			if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary, want: %s, got: %s", "Accept-Encoding", got)
+			}
+		})
+	}
+}
+
+func Test_acceptsGzip(t *testing.T) {
+	testCases := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, gzip;q=0.5", true},
+		{"GZIP", true},
+		{"gzip;q=0", false},
+		{"gzip; q=0.0", false},
+		{"br", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.header, func(t *testing.T) {
+			if got := acceptsGzip(tc.header); got != tc.want {
+				t.Errorf("acceptsGzip(%q), want: %v, got: %v", tc.header, tc.want, got)
+			}
+		})
+	}
+}
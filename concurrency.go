@@ -0,0 +1,94 @@
+package bootstrap
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/openfaas/faas-provider/httputil"
+)
+
+// concurrencyLimiter caps the number of requests served at once for the system API and for
+// function invocations separately, so that a burst of invocations can not use up the capacity
+// needed to manage functions.
+type concurrencyLimiter struct {
+	system    chan struct{}
+	dataPlane chan struct{}
+}
+
+// newConcurrencyLimiter creates a limiter, a limit of zero means unlimited.
+func newConcurrencyLimiter(maxSystem, maxDataPlane int) *concurrencyLimiter {
+	l := &concurrencyLimiter{}
+	if maxSystem > 0 {
+		l.system = make(chan struct{}, maxSystem)
+	}
+	if maxDataPlane > 0 {
+		l.dataPlane = make(chan struct{}, maxDataPlane)
+	}
+	return l
+}
+
+// semaphoreFor returns the semaphore for the class of route addressed by path, or nil when
+// the route is not limited, such as "/healthz" and "/metrics".
+func (l *concurrencyLimiter) semaphoreFor(path string) chan struct{} {
+	switch {
+	case strings.HasPrefix(path, "/system/"):
+		return l.system
+	case isInvocation(path):
+		return l.dataPlane
+	}
+	return nil
+}
+
+// middleware rejects requests with 429 when the budget for their class of route is in use.
+func (l *concurrencyLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sem := l.semaphoreFor(r.URL.Path)
+		if sem == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+			next.ServeHTTP(w, r)
+		default:
+			w.Header().Set("Retry-After", "1")
+			httputil.WriteErrorCode(w, r, http.StatusTooManyRequests, httputil.TooManyRequests, "too many concurrent requests")
+		}
+	})
+}
+
+// writeLimiter caps the number of requests which deploy, update, delete or scale functions
+// served at once, as each may call into the orchestrator, which has no backpressure of its
+// own. Requests over the limit are rejected rather than queued.
+type writeLimiter struct {
+	sem chan struct{}
+}
+
+// newWriteLimiter creates a limiter, a limit of zero means unlimited.
+func newWriteLimiter(max int) *writeLimiter {
+	l := &writeLimiter{}
+	if max > 0 {
+		l.sem = make(chan struct{}, max)
+	}
+	return l
+}
+
+// decorate rejects requests to next with a 429 when the limit is in use.
+func (l *writeLimiter) decorate(next http.HandlerFunc) http.HandlerFunc {
+	if l.sem == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case l.sem <- struct{}{}:
+			defer func() { <-l.sem }()
+			next.ServeHTTP(w, r)
+		default:
+			w.Header().Set("Retry-After", "1")
+			httputil.WriteErrorCode(w, r, http.StatusTooManyRequests, httputil.TooManyRequests, "too many concurrent changes to functions")
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package bootstrap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_concurrencyLimiter_SeparateBudgets(t *testing.T) {
+	l := newConcurrencyLimiter(1, 1)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	blocking := l.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+
+	go blocking.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/function/figlet", nil))
+	<-started
+	defer close(release)
+
+	ok := l.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	testCases := []struct {
+		path     string
+		wantCode int
+	}{
+		{path: "/function/figlet", wantCode: http.StatusTooManyRequests},
+		{path: "/invoke/figlet", wantCode: http.StatusTooManyRequests},
+		{path: "/system/functions", wantCode: http.StatusOK},
+		{path: "/healthz", wantCode: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ok.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+		})
+	}
+}
+
+func Test_Server_MaxSystemConcurrency(t *testing.T) {
+	const limit = 2
+
+	release := make(chan struct{})
+	started := make(chan struct{}, limit)
+	handlers := validHandlers()
+	handlers.DeployFunction = func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusAccepted)
+	}
+
+	s := NewServer(&types.FaaSConfig{MaxSystemConcurrency: limit})
+	s.Handlers(handlers)
+
+	deploy := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/system/functions", strings.NewReader(`{"service":"figlet"}`)))
+		return w
+	}
+
+	inFlight := make(chan int, limit)
+	for i := 0; i < limit; i++ {
+		go func() {
+			inFlight <- deploy().Code
+		}()
+	}
+	for i := 0; i < limit; i++ {
+		<-started
+	}
+
+	rejected := deploy()
+	if rejected.Code != http.StatusTooManyRequests {
+		t.Errorf("status code over the limit, want: %d, got: %d", http.StatusTooManyRequests, rejected.Code)
+	}
+	if got := rejected.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After, want: %s, got: %q", "1", got)
+	}
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/function/figlet", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("invocation status code, want: %d, got: %d", http.StatusOK, w.Code)
+	}
+
+	close(release)
+	for i := 0; i < limit; i++ {
+		if code := <-inFlight; code != http.StatusAccepted {
+			t.Errorf("in-flight deploy status code, want: %d, got: %d", http.StatusAccepted, code)
+		}
+	}
+}
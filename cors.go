@@ -0,0 +1,112 @@
+package bootstrap
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+// cors adds the Access-Control-Allow-* headers to responses for requests from an allowed
+// origin and answers their preflight requests. Requests without an Origin header, or from
+// an origin which is not allowed, are passed on unchanged.
+type cors struct {
+	origins          map[string]bool
+	anyOrigin        bool
+	methods          string
+	headers          string
+	allowCredentials bool
+}
+
+func newCORS(config *types.CORSConfig) *cors {
+	c := &cors{
+		origins:          map[string]bool{},
+		methods:          strings.Join(config.GetAllowedMethods(), ", "),
+		headers:          strings.Join(config.AllowedHeaders, ", "),
+		allowCredentials: config.AllowCredentials,
+	}
+
+	for _, origin := range config.AllowedOrigins {
+		if origin == "*" {
+			c.anyOrigin = true
+			continue
+		}
+		c.origins[origin] = true
+	}
+
+	return c
+}
+
+// serve answers preflight requests and adds the CORS headers to the responses of next.
+func (c *cors) serve(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	origin := r.Header.Get("Origin")
+	if len(origin) == 0 {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	w.Header().Add("Vary", "Origin")
+
+	if !c.anyOrigin && !c.origins[origin] {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	if c.anyOrigin && !c.allowCredentials {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+
+	if c.allowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if r.Method == http.MethodOptions && len(r.Header.Get("Access-Control-Request-Method")) > 0 {
+		w.Header().Set("Access-Control-Allow-Methods", c.methods)
+		if len(c.headers) > 0 {
+			w.Header().Set("Access-Control-Allow-Headers", c.headers)
+		} else if headers := r.Header.Get("Access-Control-Request-Headers"); len(headers) > 0 {
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	next.ServeHTTP(w, r)
+}
+
+// corsPolicies applies the CORS policy of functions to the invocationPrefixes, and that of
+// the API to every other route. Either may be nil, when
+// no CORS headers are sent for the routes.
+type corsPolicies struct {
+	system    *cors
+	functions *cors
+}
+
+func newCORSPolicies(system, functions *types.CORSConfig) *corsPolicies {
+	p := &corsPolicies{}
+	if system != nil {
+		p.system = newCORS(system)
+		p.functions = p.system
+	}
+	if functions != nil {
+		p.functions = newCORS(functions)
+	}
+	return p
+}
+
+func (p *corsPolicies) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := p.system
+		if _, ok := invokedFunction(r.URL.Path); ok {
+			c = p.functions
+		}
+
+		if c == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		c.serve(w, r, next)
+	})
+}
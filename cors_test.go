@@ -0,0 +1,112 @@
+package bootstrap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_cors(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	testCases := []struct {
+		name            string
+		cors            *types.CORSConfig
+		functionCORS    *types.CORSConfig
+		method          string
+		path            string
+		origin          string
+		preflight       bool
+		requestHeaders  string
+		wantCode        int
+		wantOrigin      string
+		wantMethods     string
+		wantHeaders     string
+		wantCredentials string
+	}{
+		{name: "disabled", method: http.MethodGet, path: "/system/functions", origin: "https://dashboard.example.com",
+			wantCode: http.StatusOK},
+		{name: "disabled preflight", method: http.MethodOptions, path: "/system/functions", origin: "https://dashboard.example.com", preflight: true,
+			wantCode: http.StatusMethodNotAllowed},
+		{name: "allowed origin", cors: &types.CORSConfig{AllowedOrigins: []string{"https://dashboard.example.com"}},
+			method: http.MethodGet, path: "/system/functions", origin: "https://dashboard.example.com",
+			wantCode: http.StatusOK, wantOrigin: "https://dashboard.example.com"},
+		{name: "other origin", cors: &types.CORSConfig{AllowedOrigins: []string{"https://dashboard.example.com"}},
+			method: http.MethodGet, path: "/system/functions", origin: "https://example.com",
+			wantCode: http.StatusOK},
+		{name: "preflight", cors: &types.CORSConfig{AllowedOrigins: []string{"https://dashboard.example.com"}},
+			method: http.MethodOptions, path: "/system/functions", origin: "https://dashboard.example.com", preflight: true,
+			wantCode: http.StatusNoContent, wantOrigin: "https://dashboard.example.com", wantMethods: "GET, HEAD, POST, PUT, DELETE"},
+		{name: "preflight to function", cors: &types.CORSConfig{AllowedOrigins: []string{"*"}, AllowedMethods: []string{http.MethodPost}},
+			method: http.MethodOptions, path: "/function/figlet", origin: "https://example.com", preflight: true,
+			wantCode: http.StatusNoContent, wantOrigin: "*", wantMethods: "POST"},
+		{name: "options without preflight", cors: &types.CORSConfig{AllowedOrigins: []string{"*"}},
+			method: http.MethodOptions, path: "/system/functions",
+			wantCode: http.StatusMethodNotAllowed},
+		{name: "credentials", cors: &types.CORSConfig{AllowedOrigins: []string{"https://dashboard.example.com"}, AllowCredentials: true},
+			method: http.MethodGet, path: "/system/functions", origin: "https://dashboard.example.com",
+			wantCode: http.StatusOK, wantOrigin: "https://dashboard.example.com", wantCredentials: "true"},
+		{name: "requested headers", cors: &types.CORSConfig{AllowedOrigins: []string{"*"}},
+			method: http.MethodOptions, path: "/system/functions", origin: "https://example.com", preflight: true, requestHeaders: "X-Custom",
+			wantCode: http.StatusNoContent, wantOrigin: "*", wantMethods: "GET, HEAD, POST, PUT, DELETE", wantHeaders: "X-Custom"},
+		{name: "allowed headers", cors: &types.CORSConfig{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"Authorization", "Content-Type"}},
+			method: http.MethodOptions, path: "/system/functions", origin: "https://example.com", preflight: true, requestHeaders: "X-Custom",
+			wantCode: http.StatusNoContent, wantOrigin: "*", wantMethods: "GET, HEAD, POST, PUT, DELETE", wantHeaders: "Authorization, Content-Type"},
+		{name: "function policy", cors: &types.CORSConfig{AllowedOrigins: []string{"https://dashboard.example.com"}},
+			functionCORS: &types.CORSConfig{AllowedOrigins: []string{"*"}},
+			method:       http.MethodGet, path: "/function/figlet", origin: "https://example.com",
+			wantCode: http.StatusOK, wantOrigin: "*"},
+		{name: "function policy for a batch", cors: &types.CORSConfig{AllowedOrigins: []string{"https://dashboard.example.com"}},
+			functionCORS: &types.CORSConfig{AllowedOrigins: []string{"*"}, AllowedMethods: []string{http.MethodPost}},
+			method:       http.MethodOptions, path: "/invoke-batch/figlet", origin: "https://example.com", preflight: true,
+			wantCode: http.StatusNoContent, wantOrigin: "*", wantMethods: "POST"},
+		{name: "function policy does not apply to the API", cors: &types.CORSConfig{AllowedOrigins: []string{"https://dashboard.example.com"}},
+			functionCORS: &types.CORSConfig{AllowedOrigins: []string{"*"}},
+			method:       http.MethodGet, path: "/system/functions", origin: "https://example.com",
+			wantCode: http.StatusOK},
+		{name: "function policy only", functionCORS: &types.CORSConfig{AllowedOrigins: []string{"*"}},
+			method: http.MethodOptions, path: "/system/functions", origin: "https://example.com", preflight: true,
+			wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewServer(&types.FaaSConfig{CORS: tc.cors, FunctionCORS: tc.functionCORS})
+			s.Handlers(&types.FaaSHandlers{FunctionLister: ok, FunctionProxy: ok})
+
+			r := httptest.NewRequest(tc.method, tc.path, nil)
+			if len(tc.origin) > 0 {
+				r.Header.Set("Origin", tc.origin)
+			}
+			if tc.preflight {
+				r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+			}
+			if len(tc.requestHeaders) > 0 {
+				r.Header.Set("Access-Control-Request-Headers", tc.requestHeaders)
+			}
+
+			w := httptest.NewRecorder()
+			s.Router().ServeHTTP(w, r)
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tc.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin, want: %q, got: %q", tc.wantOrigin, got)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Methods"); got != tc.wantMethods {
+				t.Errorf("Access-Control-Allow-Methods, want: %q, got: %q", tc.wantMethods, got)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Headers"); got != tc.wantHeaders {
+				t.Errorf("Access-Control-Allow-Headers, want: %q, got: %q", tc.wantHeaders, got)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials"); got != tc.wantCredentials {
+				t.Errorf("Access-Control-Allow-Credentials, want: %q, got: %q", tc.wantCredentials, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package bootstrap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/logging"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// decorateWithDeploymentInterceptors calls each interceptor in turn with the
+// FunctionDeployment in the request before next. When an interceptor changed the
+// deployment, next is given its JSON in place of the original body, so fields the client
+// sent which FunctionDeployment does not know are dropped. Otherwise the body is passed
+// through as it was sent. A rejection is written as a JSON error with its status
+// code and reason. Requests which can not be decoded are passed to next, which reports the
+// error.
+func decorateWithDeploymentInterceptors(next http.HandlerFunc, interceptors []types.DeploymentInterceptor) http.HandlerFunc {
+	if next == nil || len(interceptors) == 0 {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, ok := httputil.BufferBody(w, r)
+		if !ok {
+			return
+		}
+
+		original, deployment := types.FunctionDeployment{}, &types.FunctionDeployment{}
+		if err := json.Unmarshal(body, &original); err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		json.Unmarshal(body, deployment)
+
+		for _, intercept := range interceptors {
+			if err := intercept(r.Context(), deployment); err != nil {
+				logging.FromContext(r.Context()).Info("Deployment rejected by interceptor",
+					"function", original.Service, "namespace", original.Namespace, "error", err)
+
+				var rejection *types.DeploymentRejection
+				if errors.As(err, &rejection) {
+					httputil.WriteError(w, r, rejection.GetStatusCode(), rejection.Reason)
+					return
+				}
+				httputil.WriteError(w, r, http.StatusForbidden, err.Error())
+				return
+			}
+		}
+
+		if !reflect.DeepEqual(original, *deployment) {
+			body, err := json.Marshal(deployment)
+			if err != nil {
+				httputil.WriteErrorCode(w, r, http.StatusInternalServerError, httputil.InternalError, fmt.Sprintf("unable to encode deployment: %s", err))
+				return
+			}
+			httputil.ReplaceBody(r, body)
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,154 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_decorateWithDeploymentInterceptors(t *testing.T) {
+	registry := func(ctx context.Context, fd *types.FunctionDeployment) error {
+		if !strings.HasPrefix(fd.Image, "ghcr.io/acme/") {
+			return types.RejectDeployment("image %s must be pulled from ghcr.io/acme", fd.Image)
+		}
+		return nil
+	}
+	inject := func(ctx context.Context, fd *types.FunctionDeployment) error {
+		if fd.EnvVars == nil {
+			fd.EnvVars = map[string]string{}
+		}
+		fd.EnvVars["REGION"] = "eu-west-1"
+		return nil
+	}
+	readOnly := func(ctx context.Context, fd *types.FunctionDeployment) error {
+		if fd.Namespace == "frozen" {
+			return errors.New("namespace is frozen")
+		}
+		return nil
+	}
+	unprocessable := func(ctx context.Context, fd *types.FunctionDeployment) error {
+		if fd.Limits != nil && fd.Limits.Memory == "0" {
+			return &types.DeploymentRejection{StatusCode: http.StatusUnprocessableEntity, Reason: "memory limit must not be 0"}
+		}
+		return nil
+	}
+
+	testCases := []struct {
+		name     string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "mutated",
+			body:     `{"service":"figlet","image":"ghcr.io/acme/figlet:latest"}`,
+			wantCode: http.StatusAccepted,
+			wantBody: `"envVars":{"REGION":"eu-west-1"}`,
+		},
+		{
+			name:     "rejected with reason",
+			body:     `{"service":"figlet","image":"docker.io/figlet:latest"}`,
+			wantCode: http.StatusForbidden,
+			wantBody: `"message":"image docker.io/figlet:latest must be pulled from ghcr.io/acme"`,
+		},
+		{
+			name:     "rejected with error",
+			body:     `{"service":"figlet","image":"ghcr.io/acme/figlet:latest","namespace":"frozen"}`,
+			wantCode: http.StatusForbidden,
+			wantBody: `"message":"namespace is frozen"`,
+		},
+		{
+			name:     "rejected with status code",
+			body:     `{"service":"figlet","image":"ghcr.io/acme/figlet:latest","limits":{"memory":"0"}}`,
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: `"message":"memory limit must not be 0"`,
+		},
+		{
+			name:     "invalid body",
+			body:     `{`,
+			wantCode: http.StatusAccepted,
+			wantBody: `{`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := decorateWithDeploymentInterceptors(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				w.WriteHeader(http.StatusAccepted)
+				w.Write(body)
+			}, []types.DeploymentInterceptor{registry, inject, readOnly, unprocessable})
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/system/functions", strings.NewReader(tc.body)))
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+			if got := w.Body.String(); !strings.Contains(got, tc.wantBody) {
+				t.Errorf("body, want: %q, got: %q", tc.wantBody, got)
+			}
+		})
+	}
+}
+
+func Test_decorateWithDeploymentInterceptors_Unchanged(t *testing.T) {
+	// Fields FunctionDeployment does not know are kept when no interceptor changes the
+	// deployment.
+	body := `{"service":"figlet","image":"ghcr.io/acme/figlet:latest","custom":true}`
+
+	handler := decorateWithDeploymentInterceptors(func(w http.ResponseWriter, r *http.Request) {
+		got, _ := io.ReadAll(r.Body)
+		w.Write(got)
+	}, []types.DeploymentInterceptor{func(ctx context.Context, fd *types.FunctionDeployment) error { return nil }})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/system/functions", strings.NewReader(body)))
+
+	if got := w.Body.String(); got != body {
+		t.Errorf("body, want: %s, got: %s", body, got)
+	}
+}
+
+func Test_Server_DeploymentInterceptors(t *testing.T) {
+	handlers := validHandlers()
+	var got []string
+	record := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, r.Method+" "+string(body))
+		w.WriteHeader(http.StatusAccepted)
+	}
+	handlers.DeployFunction, handlers.UpdateFunction = record, record
+	handlers.DeploymentInterceptors = []types.DeploymentInterceptor{
+		func(ctx context.Context, fd *types.FunctionDeployment) error {
+			fd.Labels = &map[string]string{"team": "payments"}
+			return nil
+		},
+	}
+
+	s := NewServer(&types.FaaSConfig{})
+	s.Handlers(handlers)
+
+	for _, method := range []string{http.MethodPost, http.MethodPut} {
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, httptest.NewRequest(method, "/system/functions", strings.NewReader(`{"service":"figlet","image":"figlet:latest"}`)))
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("%s status code, want: %d, got: %d (%s)", method, http.StatusAccepted, w.Code, w.Body.String())
+		}
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("want 2 requests, got: %d", len(got))
+	}
+	for _, request := range got {
+		if !strings.Contains(request, `"labels":{"team":"payments"}`) {
+			t.Errorf("want the label to be injected, got: %s", request)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package bootstrap
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// decorateWithDeprecationWarnings sets the deprecation headers on the response when the
+// FunctionDeployment in the request uses deprecated fields. The request body is restored
+// before next is called, which still receives the request unchanged.
+func decorateWithDeprecationWarnings(next http.HandlerFunc, sunset time.Time) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, ok := httputil.BufferBody(w, r)
+		if !ok {
+			return
+		}
+
+		req := types.FunctionDeployment{}
+		if err := json.Unmarshal(body, &req); err == nil {
+			if messages := req.DeprecatedFields(); len(messages) > 0 {
+				httputil.SetDeprecationHeaders(w, sunset, messages...)
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,46 @@
+package bootstrap
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_decorateWithDeprecationWarnings(t *testing.T) {
+	sunset := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name            string
+		body            string
+		wantDeprecation string
+	}{
+		{name: "deprecated field", body: `{"service":"figlet","envProcess":"figlet"}`, wantDeprecation: "true"},
+		{name: "current fields", body: `{"service":"figlet","envVars":{"fprocess":"figlet"}}`},
+		{name: "invalid body", body: `{`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotBody string
+			handler := decorateWithDeprecationWarnings(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				gotBody = string(b)
+				w.WriteHeader(http.StatusAccepted)
+			}, sunset)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/system/functions", strings.NewReader(tc.body)))
+
+			if got := w.Header().Get("Deprecation"); got != tc.wantDeprecation {
+				t.Errorf("Deprecation, want: %q, got: %q", tc.wantDeprecation, got)
+			}
+
+			if gotBody != tc.body {
+				t.Errorf("body, want: %s, got: %s", tc.body, gotBody)
+			}
+		})
+	}
+}
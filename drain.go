@@ -0,0 +1,63 @@
+package bootstrap
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// proxyDrain tracks the requests in flight to functions, so that they can be given longer
+// to complete than the rest of the API when the server shuts down.
+type proxyDrain struct {
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newProxyDrain() *proxyDrain {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &proxyDrain{ctx: ctx, cancel: cancel}
+}
+
+// decorate tracks each request to next, and cancels its context when the drain times out.
+func (d *proxyDrain) decorate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		d.wg.Add(1)
+		defer d.wg.Done()
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		go func() {
+			select {
+			case <-d.ctx.Done():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
+// wait blocks until the tracked requests complete or timeout elapses, at which point the
+// remaining requests are cancelled. It reports whether every request completed in time.
+func (d *proxyDrain) wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		d.cancel()
+		return false
+	}
+}
@@ -0,0 +1,53 @@
+package bootstrap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_proxyDrain_CompletesInTime(t *testing.T) {
+	d := newProxyDrain()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := d.decorate(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+
+	go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/function/batch", nil))
+	<-started
+
+	time.AfterFunc(10*time.Millisecond, func() { close(release) })
+
+	if !d.wait(time.Second) {
+		t.Errorf("want requests to complete within the drain timeout")
+	}
+}
+
+func Test_proxyDrain_CancelsAfterTimeout(t *testing.T) {
+	d := newProxyDrain()
+
+	cancelled := make(chan struct{})
+	started := make(chan struct{})
+	handler := d.decorate(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+		close(cancelled)
+	})
+
+	go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/function/batch", nil))
+	<-started
+
+	if d.wait(10 * time.Millisecond) {
+		t.Fatalf("want the drain to time out")
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Errorf("want the request to be cancelled once the drain timed out")
+	}
+}
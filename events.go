@@ -0,0 +1,112 @@
+package bootstrap
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/openfaas/faas-provider/events"
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/scaling"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// lifecycleEventTypes maps the changes reported to FaaSConfig.LifecycleHook to the
+// events published for them.
+var lifecycleEventTypes = map[types.LifecycleEventType]events.Type{
+	types.FunctionCreated: events.FunctionDeployed,
+	types.FunctionUpdated: events.FunctionUpdated,
+	types.FunctionDeleted: events.FunctionDeleted,
+	types.FunctionScaled:  events.FunctionScaled,
+}
+
+// publishLifecycleEvents returns a lifecycle hook which publishes each change on bus, then
+// calls next when it is set.
+func publishLifecycleEvents(bus *events.Bus, next func(types.LifecycleEvent)) func(types.LifecycleEvent) {
+	return func(e types.LifecycleEvent) {
+		event := events.Event{
+			Type:      lifecycleEventTypes[e.Type],
+			Function:  e.Name,
+			Namespace: e.Namespace,
+			Timestamp: e.Timestamp,
+		}
+		if e.Type == types.FunctionScaled {
+			event.Data = map[string]string{"replicas": strconv.FormatUint(e.Replicas, 10)}
+		}
+		bus.Publish(event)
+
+		if next != nil {
+			next(e)
+		}
+	}
+}
+
+// publishColdStarts returns an OnColdStart callback for the scaling package which
+// publishes each wake up on bus, then calls next.
+func publishColdStarts(bus *events.Bus, next func(scaling.ColdStart)) func(scaling.ColdStart) {
+	return func(c scaling.ColdStart) {
+		result := "ready"
+		if c.Err != nil {
+			result = "error"
+		}
+		bus.Publish(events.Event{
+			Type:      events.ColdStart,
+			Function:  c.Function,
+			Namespace: c.Namespace,
+			Data: map[string]string{
+				"duration": c.Duration.String(),
+				"result":   result,
+			},
+		})
+
+		next(c)
+	}
+}
+
+// decorateWithCheckpointEvent publishes events.CheckpointCreated on bus once next has
+// taken a checkpoint of the function in the path.
+func decorateWithCheckpointEvent(next http.HandlerFunc, bus *events.Bus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ww := httputil.NewHttpWriteInterceptor(w)
+		next(ww, r)
+
+		if status := ww.Status(); status < http.StatusOK || status >= http.StatusMultipleChoices {
+			return
+		}
+
+		function, namespace := httputil.SplitFunctionName(httputil.PathVar(r, "name"))
+		if len(namespace) == 0 {
+			namespace = httputil.NamespaceFromRequest(r)
+		}
+		bus.Publish(events.Event{Type: events.CheckpointCreated, Function: function, Namespace: namespace})
+	}
+}
+
+// decorateWithKillEvent publishes events.InstanceKilled on bus with the KillResponse
+// written by next, when it killed any instance, with the user who asked for it.
+func decorateWithKillEvent(next http.HandlerFunc, bus *events.Bus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bw := httputil.NewBufferedResponseWriter()
+		next(bw, r)
+		bw.Flush(w)
+
+		if status := bw.Status(); status < http.StatusOK || status >= http.StatusMultipleChoices {
+			return
+		}
+
+		res := types.KillResponse{}
+		if err := json.Unmarshal(bw.Body(), &res); err != nil || res.Killed == 0 {
+			return
+		}
+		data := map[string]string{"killed": strconv.Itoa(res.Killed)}
+		if user := requestUser(r); len(user) > 0 {
+			data["user"] = user
+		}
+		bus.Publish(events.Event{
+			Type:      events.InstanceKilled,
+			Function:  res.Function,
+			Namespace: res.Namespace,
+			Data:      data,
+		})
+	}
+}
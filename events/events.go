@@ -0,0 +1,266 @@
+// Package events publishes structured events about the functions of a provider, such as
+// deploys, scaling and cold starts, to sinks such as a webhook or NATS, and to subscribers
+// in the same process. Serve publishes the events of the routes it owns when
+// FaaSConfig.Events is set, providers publish the rest from their handlers with Publish.
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// defaultQueueSize is how many events are held for the sinks when Config.QueueSize is
+// not set.
+const defaultQueueSize = 1024
+
+// subscriberBuffer is how many events are held for each subscriber.
+const subscriberBuffer = 64
+
+// Type is the kind of an event.
+type Type string
+
+const (
+	// FunctionDeployed is published after a function was deployed.
+	FunctionDeployed Type = "function.deployed"
+	// FunctionUpdated is published after a function was updated.
+	FunctionUpdated Type = "function.updated"
+	// FunctionDeleted is published after a function was deleted.
+	FunctionDeleted Type = "function.deleted"
+	// FunctionScaled is published after the replicas of a function were changed, with the
+	// "replicas" requested in Data.
+	FunctionScaled Type = "function.scaled"
+	// CheckpointCreated is published after a checkpoint of a function was taken.
+	CheckpointCreated Type = "checkpoint.created"
+	// InstanceKilled is published after instances were killed, with the number "killed"
+	// in Data.
+	InstanceKilled Type = "instance.killed"
+	// ColdStart is published after a function scaled to zero was woken, with its
+	// "duration" and "result" in Data.
+	ColdStart Type = "function.cold_start"
+	// InstanceDrain is published as an instance is cordoned, drained, checkpointed and
+	// terminated, with the "instance" and its "stage" in Data, and its "checkpoint" or
+	// "error" when set.
+	InstanceDrain Type = "instance.drain"
+)
+
+// Event is something which happened to a function.
+type Event struct {
+	// ID identifies the event, for sinks to discard duplicates. It is set by Publish when
+	// empty.
+	ID string `json:"id"`
+	// Type is the kind of event.
+	Type Type `json:"type"`
+	// Function is the name of the function, empty for events about many functions.
+	Function string `json:"function,omitempty"`
+	// Namespace of the function, if supported by the faas-provider.
+	Namespace string `json:"namespace,omitempty"`
+	// Timestamp is when the event happened, it is set by Publish when zero.
+	Timestamp time.Time `json:"timestamp"`
+	// Data are further details which depend on the Type.
+	Data map[string]string `json:"data,omitempty"`
+}
+
+// Sink delivers events outside of the process, i.e. to a webhook, see WebhookSink and
+// PublisherSink.
+type Sink interface {
+	Send(ctx context.Context, event Event) error
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(ctx context.Context, event Event) error
+
+// Send calls f.
+func (f SinkFunc) Send(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+// Config configures a Bus.
+type Config struct {
+	// Sinks are sent every event, in order.
+	Sinks []Sink
+	// QueueSize is how many events are held while the sinks are slow, further events are
+	// dropped and counted. The default is 1024.
+	QueueSize int
+	// Logger receives failures to deliver events, the default is slog.Default().
+	Logger *slog.Logger
+}
+
+var droppedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Subsystem: "provider",
+	Name:      "events_dropped_total",
+	Help:      "Total number of events which were not delivered, by reason.",
+}, []string{"reason"})
+
+// Bus delivers published events to its sinks and subscribers, it is safe for concurrent
+// use. Publish never blocks, events are sent to the sinks by Run.
+type Bus struct {
+	sinks  []Sink
+	logger *slog.Logger
+	queue  chan Event
+
+	mu          sync.Mutex
+	subscribers map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	types  map[Type]bool
+	events chan Event
+}
+
+// NewBus creates a Bus, Run must be called for its sinks to be sent events. Serve calls
+// Run for FaaSConfig.Events.
+func NewBus(config Config) *Bus {
+	size := config.QueueSize
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+
+	logger := config.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &Bus{
+		sinks:       config.Sinks,
+		logger:      logger,
+		queue:       make(chan Event, size),
+		subscribers: map[*subscriber]struct{}{},
+	}
+}
+
+// Publish sets the ID and Timestamp of event when they are empty, then queues it for the
+// sinks and sends it to the subscribers of its Type. It does not wait for the event to be
+// delivered.
+func (b *Bus) Publish(event Event) {
+	if len(event.ID) == 0 {
+		event.ID = newID()
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	if len(b.sinks) > 0 {
+		select {
+		case b.queue <- event:
+		default:
+			droppedTotal.WithLabelValues("queue_full").Inc()
+			b.logger.Warn("Event queue is full, dropping event", "type", event.Type, "function", event.Function)
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for s := range b.subscribers {
+		if len(s.types) > 0 && !s.types[event.Type] {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+			droppedTotal.WithLabelValues("slow_subscriber").Inc()
+		}
+	}
+}
+
+// Subscribe returns a channel of the events of the given types, or of every event when
+// none are given, and a function which unsubscribes and closes the channel. Events are
+// dropped for a subscriber which does not keep up.
+func (b *Bus) Subscribe(types ...Type) (<-chan Event, func()) {
+	s := &subscriber{
+		types:  map[Type]bool{},
+		events: make(chan Event, subscriberBuffer),
+	}
+	for _, t := range types {
+		s.types[t] = true
+	}
+
+	b.mu.Lock()
+	b.subscribers[s] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return s.events, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, s)
+			b.mu.Unlock()
+			close(s.events)
+		})
+	}
+}
+
+// Run sends queued events to each sink until ctx is cancelled, then sends the events still
+// queued before it returns. A sink which fails is logged and the event is still sent to the
+// other sinks.
+func (b *Bus) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			b.drain()
+			return
+		case event := <-b.queue:
+			if ctx.Err() != nil {
+				// ctx was cancelled while an event was queued, it is sent as it is drained
+				b.send(context.Background(), event)
+				continue
+			}
+			b.send(ctx, event)
+		}
+	}
+}
+
+func (b *Bus) drain() {
+	for {
+		select {
+		case event := <-b.queue:
+			b.send(context.Background(), event)
+		default:
+			return
+		}
+	}
+}
+
+func (b *Bus) send(ctx context.Context, event Event) {
+	for _, sink := range b.sinks {
+		if err := sink.Send(ctx, event); err != nil {
+			droppedTotal.WithLabelValues("sink_error").Inc()
+			b.logger.Warn("Unable to deliver event", "type", event.Type, "function", event.Function, "error", err)
+		}
+	}
+}
+
+type contextKey struct{}
+
+// WithBus returns a copy of ctx carrying bus, for Publish.
+func WithBus(ctx context.Context, bus *Bus) context.Context {
+	return context.WithValue(ctx, contextKey{}, bus)
+}
+
+// FromContext returns the Bus carried by ctx, or nil.
+func FromContext(ctx context.Context) *Bus {
+	bus, _ := ctx.Value(contextKey{}).(*Bus)
+	return bus
+}
+
+// Publish publishes event on the Bus carried by ctx, it does nothing when there is none.
+// Serve sets the bus of FaaSConfig.Events on the context of every request.
+func Publish(ctx context.Context, event Event) {
+	if bus := FromContext(ctx); bus != nil {
+		bus.Publish(event)
+	}
+}
+
+// newID returns a random ID of 32 hex characters.
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,120 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_Bus_Subscribe(t *testing.T) {
+	bus := NewBus(Config{})
+
+	deployed, unsubscribe := bus.Subscribe(FunctionDeployed)
+	all, unsubscribeAll := bus.Subscribe()
+	defer unsubscribeAll()
+
+	bus.Publish(Event{Type: FunctionDeployed, Function: "figlet"})
+	bus.Publish(Event{Type: FunctionDeleted, Function: "figlet"})
+
+	event := <-deployed
+	if event.Type != FunctionDeployed || event.Function != "figlet" {
+		t.Errorf("event, want: %s figlet, got: %s %s", FunctionDeployed, event.Type, event.Function)
+	}
+	if len(event.ID) != 32 {
+		t.Errorf("ID length, want: %d, got: %d", 32, len(event.ID))
+	}
+	if event.Timestamp.IsZero() {
+		t.Errorf("want the timestamp to be set")
+	}
+
+	select {
+	case event := <-deployed:
+		t.Errorf("want only %s events, got: %s", FunctionDeployed, event.Type)
+	default:
+	}
+
+	if got := len(all); got != 2 {
+		t.Errorf("events for a subscriber of every type, want: %d, got: %d", 2, got)
+	}
+
+	unsubscribe()
+	unsubscribe()
+	if _, ok := <-deployed; ok {
+		t.Errorf("want the channel to be closed after unsubscribing")
+	}
+	bus.Publish(Event{Type: FunctionDeployed, Function: "figlet"})
+}
+
+func Test_Bus_Run(t *testing.T) {
+	received := make(chan Event, 2)
+	bus := NewBus(Config{Sinks: []Sink{
+		SinkFunc(func(ctx context.Context, event Event) error {
+			return errors.New("unavailable")
+		}),
+		SinkFunc(func(ctx context.Context, event Event) error {
+			received <- event
+			return nil
+		}),
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go bus.Run(ctx)
+
+	bus.Publish(Event{Type: FunctionScaled, Function: "figlet", Data: map[string]string{"replicas": "3"}})
+
+	select {
+	case event := <-received:
+		if event.Data["replicas"] != "3" {
+			t.Errorf("replicas, want: %s, got: %s", "3", event.Data["replicas"])
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("event was not sent to the sink after a failing sink")
+	}
+}
+
+func Test_Bus_RunDrainsQueue(t *testing.T) {
+	var received []Event
+	bus := NewBus(Config{Sinks: []Sink{SinkFunc(func(ctx context.Context, event Event) error {
+		received = append(received, event)
+		return nil
+	})}})
+
+	bus.Publish(Event{Type: FunctionDeployed})
+	bus.Publish(Event{Type: FunctionDeleted})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	bus.Run(ctx)
+
+	if got := len(received); got != 2 {
+		t.Errorf("events sent after cancelling, want: %d, got: %d", 2, got)
+	}
+}
+
+func Test_Bus_DropsWhenQueueFull(t *testing.T) {
+	bus := NewBus(Config{QueueSize: 1, Sinks: []Sink{SinkFunc(func(ctx context.Context, event Event) error { return nil })}})
+
+	bus.Publish(Event{Type: FunctionDeployed})
+	bus.Publish(Event{Type: FunctionDeleted})
+
+	if got := len(bus.queue); got != 1 {
+		t.Errorf("queued events, want: %d, got: %d", 1, got)
+	}
+}
+
+func Test_Publish(t *testing.T) {
+	// without a bus, events are discarded
+	Publish(context.Background(), Event{Type: FunctionDeployed})
+
+	bus := NewBus(Config{})
+	events, unsubscribe := bus.Subscribe()
+	defer unsubscribe()
+
+	Publish(WithBus(context.Background(), bus), Event{Type: FunctionDeployed, Function: "figlet"})
+
+	if got := len(events); got != 1 {
+		t.Errorf("events, want: %d, got: %d", 1, got)
+	}
+}
@@ -0,0 +1,121 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// SignatureHeader carries the HMAC-SHA256 of the body of a webhook, keyed with the
+	// secret of the WebhookSink, as "sha256=" followed by hex, for the receiver to verify
+	// with VerifySignature.
+	SignatureHeader = "X-Event-Signature"
+	// TypeHeader carries the Type of the event sent to a webhook.
+	TypeHeader = "X-Event-Type"
+	// IDHeader carries the ID of the event sent to a webhook.
+	IDHeader = "X-Event-Id"
+)
+
+// defaultWebhookTimeout bounds each request to a webhook.
+const defaultWebhookTimeout = 10 * time.Second
+
+// WebhookSink posts each event as JSON to a URL.
+type WebhookSink struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+// NewWebhookSink creates a sink which posts events to url. When secret is not empty, the
+// body is signed in the SignatureHeader.
+func NewWebhookSink(url, secret string) *WebhookSink {
+	return &WebhookSink{
+		url:    url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: defaultWebhookTimeout},
+	}
+}
+
+// Send posts event to the webhook, a response other than 2xx is an error.
+func (s *WebhookSink) Send(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TypeHeader, string(event.Type))
+	req.Header.Set(IDHeader, event.ID)
+	if len(s.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(s.secret, body))
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook %s responded with %d", s.url, res.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the signature of body for the SignatureHeader.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature reports whether signature is the signature of body with secret, in
+// constant time.
+func VerifySignature(secret, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// Publisher publishes a message to a subject, it is implemented by *nats.Conn.
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// PublisherSink publishes each event as JSON to a subject named after its Type, i.e.
+// "faas.events.function.deployed" for a prefix of "faas.events".
+type PublisherSink struct {
+	publisher Publisher
+	prefix    string
+}
+
+// NewPublisherSink creates a sink which publishes events with publisher, such as a NATS
+// connection, to subjects starting with prefix.
+func NewPublisherSink(publisher Publisher, prefix string) *PublisherSink {
+	return &PublisherSink{publisher: publisher, prefix: prefix}
+}
+
+// Send publishes event.
+func (s *PublisherSink) Send(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	subject := string(event.Type)
+	if len(s.prefix) > 0 {
+		subject = s.prefix + "." + subject
+	}
+	return s.publisher.Publish(subject, body)
+}
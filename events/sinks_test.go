@@ -0,0 +1,83 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_WebhookSink_Send(t *testing.T) {
+	secret := []byte("s3cret")
+
+	var got Event
+	var verified bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verified = VerifySignature(secret, body, r.Header.Get(SignatureHeader))
+		json.Unmarshal(body, &got)
+
+		if r.Header.Get(TypeHeader) != string(FunctionDeployed) {
+			t.Errorf("%s, want: %s, got: %s", TypeHeader, FunctionDeployed, r.Header.Get(TypeHeader))
+		}
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL, string(secret))
+	if err := sink.Send(context.Background(), Event{ID: "1", Type: FunctionDeployed, Function: "figlet"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !verified {
+		t.Errorf("want a valid signature")
+	}
+	if got.Function != "figlet" {
+		t.Errorf("function, want: %s, got: %s", "figlet", got.Function)
+	}
+
+	if VerifySignature([]byte("other"), []byte("{}"), Sign(secret, []byte("{}"))) {
+		t.Errorf("want the signature of another secret to be rejected")
+	}
+}
+
+func Test_WebhookSink_SendError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if err := NewWebhookSink(server.URL, "").Send(context.Background(), Event{Type: FunctionDeployed}); err == nil {
+		t.Errorf("want an error for a 500")
+	}
+}
+
+type recordingPublisher struct {
+	subject string
+	data    []byte
+}
+
+func (p *recordingPublisher) Publish(subject string, data []byte) error {
+	p.subject = subject
+	p.data = data
+	return nil
+}
+
+func Test_PublisherSink_Send(t *testing.T) {
+	publisher := &recordingPublisher{}
+	sink := NewPublisherSink(publisher, "faas.events")
+
+	if err := sink.Send(context.Background(), Event{Type: InstanceKilled, Function: "figlet"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "faas.events.instance.killed"; publisher.subject != want {
+		t.Errorf("subject, want: %s, got: %s", want, publisher.subject)
+	}
+
+	event := Event{}
+	if err := json.Unmarshal(publisher.data, &event); err != nil || event.Function != "figlet" {
+		t.Errorf("want the event as JSON, got: %s", publisher.data)
+	}
+}
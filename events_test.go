@@ -0,0 +1,94 @@
+package bootstrap
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-provider/events"
+	"github.com/openfaas/faas-provider/scaling"
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_Server_PublishesEvents(t *testing.T) {
+	bus := events.NewBus(events.Config{})
+	published, unsubscribe := bus.Subscribe()
+	defer unsubscribe()
+
+	s := NewServer(&types.FaaSConfig{Events: bus, KillConfirmationToken: "s3cr3t"})
+	s.Handlers(&types.FaaSHandlers{
+		DeployFunction: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		},
+		ScaleFunction: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		},
+		CreateCheckpoint: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		},
+		KillAllInstance: func(w http.ResponseWriter, r *http.Request) {
+			types.WriteJSON(w, http.StatusOK, types.KillResponse{Killed: 2, Function: "figlet"})
+		},
+		Info: func(w http.ResponseWriter, r *http.Request) {
+			events.Publish(r.Context(), events.Event{Type: "provider.info"})
+		},
+	})
+
+	requests := []struct {
+		method, path, body string
+	}{
+		{http.MethodPost, "/system/functions", `{"service":"figlet","image":"figlet"}`},
+		{http.MethodPost, "/system/scale-function/figlet", `{"serviceName":"figlet","replicas":3}`},
+		{http.MethodPost, "/system/function/figlet.dev/checkpoint", `{}`},
+		{http.MethodPost, "/danger/kill?confirm=s3cr3t", `{"function":"figlet"}`},
+		{http.MethodGet, "/system/info", ``},
+	}
+	for _, req := range requests {
+		s.Router().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.path, strings.NewReader(req.body)))
+	}
+
+	want := []events.Event{
+		{Type: events.FunctionDeployed, Function: "figlet"},
+		{Type: events.FunctionScaled, Function: "figlet", Data: map[string]string{"replicas": "3"}},
+		{Type: events.CheckpointCreated, Function: "figlet", Namespace: "dev"},
+		{Type: events.InstanceKilled, Function: "figlet", Data: map[string]string{"killed": "2"}},
+		{Type: "provider.info"},
+	}
+	for _, w := range want {
+		select {
+		case got := <-published:
+			if got.Type != w.Type || got.Function != w.Function || got.Namespace != w.Namespace {
+				t.Errorf("event, want: %s %s %s, got: %s %s %s", w.Type, w.Function, w.Namespace, got.Type, got.Function, got.Namespace)
+			}
+			for k, v := range w.Data {
+				if got.Data[k] != v {
+					t.Errorf("%s %s, want: %s, got: %s", w.Type, k, v, got.Data[k])
+				}
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no %s event was published", w.Type)
+		}
+	}
+}
+
+func Test_publishColdStarts(t *testing.T) {
+	bus := events.NewBus(events.Config{})
+	published, unsubscribe := bus.Subscribe(events.ColdStart)
+	defer unsubscribe()
+
+	var recorded bool
+	onColdStart := publishColdStarts(bus, func(scaling.ColdStart) { recorded = true })
+	onColdStart(scaling.ColdStart{Function: "figlet", Duration: time.Second, Err: errors.New("timeout")})
+
+	if !recorded {
+		t.Errorf("want the next callback to be called")
+	}
+
+	got := <-published
+	if got.Function != "figlet" || got.Data["result"] != "error" || got.Data["duration"] != "1s" {
+		t.Errorf("event, want: figlet error 1s, got: %s %v", got.Function, got.Data)
+	}
+}
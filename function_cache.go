@@ -0,0 +1,130 @@
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/openfaas/faas-provider/cache"
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/logging"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// CacheHeader is set on the responses of "/system/functions" and "/system/function/{name}"
+// when FaaSConfig.FunctionCache is set, to HIT when the response was served from the cache,
+// MISS when it was not, or BYPASS when the request can not be cached.
+const CacheHeader = "X-Cache"
+
+// functionCache serves reads of functions from a cache.Store, and removes the entries of a
+// function when it is changed through the API.
+type functionCache struct {
+	store  cache.Store
+	ttl    time.Duration
+	logger *slog.Logger
+
+	// defaultNamespace is the namespace of the functions requested without one, so that
+	// they share their entries with the requests which name it.
+	defaultNamespace string
+}
+
+func newFunctionCache(store cache.Store, ttl time.Duration, defaultNamespace string, logger *slog.Logger) *functionCache {
+	return &functionCache{store: store, ttl: ttl, defaultNamespace: defaultNamespace, logger: logger}
+}
+
+// decorate serves requests to next from the cache, and caches its 200 responses for ttl.
+// The function and namespace are read from the context set by decorateWithFunction, list
+// is true for the FunctionLister. A failure of the store is logged and the request is
+// passed to next.
+func (c *functionCache) decorate(next http.HandlerFunc, list bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, ok := c.key(r, list)
+		if !ok {
+			w.Header().Set(CacheHeader, "BYPASS")
+			next(w, r)
+			return
+		}
+
+		logger := logging.FromContext(r.Context())
+
+		entry, hit, err := c.store.Get(r.Context(), key)
+		if err != nil {
+			logger.Warn("Unable to read from the function cache", "key", key, "error", err)
+		}
+		if hit {
+			for k, v := range entry.Header {
+				w.Header()[k] = v
+			}
+			w.Header().Set(CacheHeader, "HIT")
+			w.WriteHeader(http.StatusOK)
+			w.Write(entry.Body)
+			return
+		}
+
+		bw := httputil.NewBufferedResponseWriter()
+		next(bw, r)
+
+		if bw.Status() == http.StatusOK {
+			entry := cache.Entry{Header: bw.Header().Clone(), Body: bytes.Clone(bw.Body())}
+			if err := c.store.Set(r.Context(), key, entry, c.ttl); err != nil {
+				logger.Warn("Unable to write to the function cache", "key", key, "error", err)
+			}
+		}
+
+		bw.Header().Set(CacheHeader, "MISS")
+		bw.Flush(w)
+	}
+}
+
+// invalidate returns a LifecycleHook which removes the entries of the function of each
+// event from the cache, then calls next.
+func (c *functionCache) invalidate(next func(types.LifecycleEvent)) func(types.LifecycleEvent) {
+	return func(e types.LifecycleEvent) {
+		name, namespace := qualifiedFunction(e.Name, e.Namespace, c.defaultNamespace)
+		keys := []string{
+			functionStatusCacheKey(name, namespace),
+			functionListCacheKey(namespace),
+			functionListCacheKey(httputil.AllNamespaces),
+		}
+		if err := c.store.Delete(context.Background(), keys...); err != nil {
+			c.logger.Warn("Unable to remove a function from the function cache", "function", e.Name, "namespace", e.Namespace, "error", err)
+		}
+
+		if next != nil {
+			next(e)
+		}
+	}
+}
+
+// key returns the key of the cached response to r, the bool is false when the request has
+// query string parameters which change the response, such as "env".
+func (c *functionCache) key(r *http.Request, list bool) (string, bool) {
+	for param := range r.URL.Query() {
+		switch {
+		case param == "namespace", list && param == "allNamespaces", !list && param == "name":
+		default:
+			return "", false
+		}
+	}
+
+	name, namespace, _ := httputil.FunctionFromContext(r.Context())
+	name, namespace = qualifiedFunction(name, namespace, c.defaultNamespace)
+	if list {
+		if httputil.AllNamespacesRequested(r) {
+			namespace = httputil.AllNamespaces
+		}
+		return functionListCacheKey(namespace), true
+	}
+
+	return functionStatusCacheKey(name, namespace), true
+}
+
+func functionStatusCacheKey(name, namespace string) string {
+	return "function/" + namespace + "/" + name
+}
+
+func functionListCacheKey(namespace string) string {
+	return "functions/" + namespace
+}
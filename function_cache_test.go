@@ -0,0 +1,145 @@
+package bootstrap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-provider/cache"
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_Server_FunctionCache(t *testing.T) {
+	type request struct {
+		method string
+		path   string
+		body   string
+	}
+
+	read := request{method: http.MethodGet, path: "/system/function/figlet?namespace=openfaas-fn"}
+	list := request{method: http.MethodGet, path: "/system/functions?namespace=openfaas-fn"}
+
+	testCases := []struct {
+		name      string
+		requests  []request
+		wantCache string
+		wantCalls int
+	}{
+		{
+			name:      "first read is a miss",
+			requests:  []request{read},
+			wantCache: "MISS",
+			wantCalls: 1,
+		},
+		{
+			name:      "second read is a hit",
+			requests:  []request{read, read},
+			wantCache: "HIT",
+			wantCalls: 1,
+		},
+		{
+			name:      "list is a hit",
+			requests:  []request{list, list},
+			wantCache: "HIT",
+			wantCalls: 1,
+		},
+		{
+			name:      "env is bypassed",
+			requests:  []request{read, {method: http.MethodGet, path: "/system/function/figlet?namespace=openfaas-fn&env=true"}},
+			wantCache: "BYPASS",
+			wantCalls: 2,
+		},
+		{
+			name:      "other namespace is a miss",
+			requests:  []request{read, {method: http.MethodGet, path: "/system/function/figlet?namespace=staging"}},
+			wantCache: "MISS",
+			wantCalls: 2,
+		},
+		{
+			name: "update invalidates the function",
+			requests: []request{read,
+				{method: http.MethodPut, path: "/system/functions", body: `{"service":"figlet","image":"figlet:latest","namespace":"openfaas-fn"}`},
+				read},
+			wantCache: "MISS",
+			wantCalls: 2,
+		},
+		{
+			name: "scale invalidates the list",
+			requests: []request{list,
+				{method: http.MethodPost, path: "/system/scale-function/figlet", body: `{"serviceName":"figlet","namespace":"openfaas-fn","replicas":2}`},
+				list},
+			wantCache: "MISS",
+			wantCalls: 2,
+		},
+		{
+			name:      "default namespace shares the entry",
+			requests:  []request{read, {method: http.MethodGet, path: "/system/function/figlet"}},
+			wantCache: "HIT",
+			wantCalls: 1,
+		},
+		{
+			name:      "name.namespace shares the entry",
+			requests:  []request{read, {method: http.MethodGet, path: "/system/function/figlet.openfaas-fn"}},
+			wantCache: "HIT",
+			wantCalls: 1,
+		},
+		{
+			name: "update without a namespace invalidates the function",
+			requests: []request{read,
+				{method: http.MethodPut, path: "/system/functions", body: `{"service":"figlet","image":"figlet:latest"}`},
+				read},
+			wantCache: "MISS",
+			wantCalls: 2,
+		},
+		{
+			name: "scale of name.namespace invalidates the function",
+			requests: []request{read,
+				{method: http.MethodPost, path: "/system/scale-function/figlet.openfaas-fn", body: `{"serviceName":"figlet","replicas":2}`},
+				read},
+			wantCache: "MISS",
+			wantCalls: 2,
+		},
+		{
+			name: "delete of another function is a hit",
+			requests: []request{read,
+				{method: http.MethodDelete, path: "/system/functions", body: `{"functionName":"env","namespace":"openfaas-fn"}`},
+				read},
+			wantCache: "HIT",
+			wantCalls: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			read := func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"name":"figlet"}`))
+			}
+
+			handlers := validHandlers()
+			handlers.FunctionStatus, handlers.FunctionLister = read, read
+
+			s := NewServer(&types.FaaSConfig{FunctionCache: cache.NewMemoryStore(), DefaultNamespace: "openfaas-fn"})
+			s.Handlers(handlers)
+
+			var w *httptest.ResponseRecorder
+			for _, req := range tc.requests {
+				w = httptest.NewRecorder()
+				s.Router().ServeHTTP(w, httptest.NewRequest(req.method, req.path, strings.NewReader(req.body)))
+			}
+
+			if got := w.Header().Get(CacheHeader); got != tc.wantCache {
+				t.Errorf("%s, want: %q, got: %q", CacheHeader, tc.wantCache, got)
+			}
+			if calls != tc.wantCalls {
+				t.Errorf("calls, want: %d, got: %d", tc.wantCalls, calls)
+			}
+			if w.Body.String() != `{"name":"figlet"}` || w.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("want the response of the handler, got: %q %v", w.Body.String(), w.Header())
+			}
+		})
+	}
+}
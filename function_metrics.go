@@ -0,0 +1,45 @@
+package bootstrap
+
+import (
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// functionMetricsRegistry holds the collectors of per-function metrics, it is kept apart
+	// from the default registry so that they are not mixed with the runtime metrics.
+	functionMetricsRegistry = prometheus.NewRegistry()
+
+	functionMetricsRegistered atomic.Bool
+)
+
+// RegisterFunctionMetrics registers collectors of per-function metrics, such as invocation
+// counts or success rates, to be served from "/system/function-metrics" instead of "/metrics".
+// The route is only configured when a collector has been registered before Serve is called.
+// It is authenticated like the rest of the system API, and served from FaaSConfig.MetricsPort
+// when it is set.
+func RegisterFunctionMetrics(collectors ...prometheus.Collector) error {
+	for _, c := range collectors {
+		if err := functionMetricsRegistry.Register(c); err != nil {
+			return err
+		}
+		functionMetricsRegistered.Store(true)
+	}
+	return nil
+}
+
+// newFunctionMetricsHandler serves the collectors registered with RegisterFunctionMetrics in
+// the same formats as "/metrics".
+func newFunctionMetricsHandler(timeout time.Duration, logger *slog.Logger) http.Handler {
+	return promhttp.HandlerFor(functionMetricsRegistry, promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+		ErrorHandling:     promhttp.ContinueOnError,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelError),
+		Timeout:           timeout,
+	})
+}
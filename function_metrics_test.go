@@ -0,0 +1,105 @@
+package bootstrap
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-provider/auth"
+	"github.com/openfaas/faas-provider/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func Test_FunctionMetrics(t *testing.T) {
+	invocations := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "test_function_invocations_total",
+		Help: "Invocations of each function.",
+	}, []string{"function_name"})
+	invocations.WithLabelValues("figlet").Add(3)
+
+	registered := functionMetricsRegistered.Load()
+	t.Cleanup(func() {
+		functionMetricsRegistry.Unregister(invocations)
+		functionMetricsRegistered.Store(registered)
+	})
+
+	if err := RegisterFunctionMetrics(invocations); err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+
+	if !functionMetricsRegistered.Load() {
+		t.Fatalf("want the route to be enabled after registering a collector")
+	}
+
+	w := httptest.NewRecorder()
+	newFunctionMetricsHandler(0, slog.Default()).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/system/function-metrics", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code, want: %d, got: %d", http.StatusOK, w.Code)
+	}
+
+	body := w.Body.String()
+	want := `test_function_invocations_total{function_name="figlet"} 3`
+	if !strings.Contains(body, want) {
+		t.Errorf("want body containing %q, got: %s", want, body)
+	}
+
+	if strings.Contains(body, "go_goroutines") {
+		t.Errorf("want runtime metrics to be served from /metrics only, got: %s", body)
+	}
+}
+
+func Test_Server_FunctionMetricsRoute(t *testing.T) {
+	invocations := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "test_function_route_invocations_total",
+		Help: "Invocations of a function.",
+	})
+
+	registered := functionMetricsRegistered.Load()
+	t.Cleanup(func() {
+		functionMetricsRegistry.Unregister(invocations)
+		functionMetricsRegistered.Store(registered)
+	})
+
+	if err := RegisterFunctionMetrics(invocations); err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+
+	apiPort, metricsPort := 8080, 8081
+	credentials := &auth.BasicAuthCredentials{User: "admin", Password: "secret"}
+	api := NewServer(&types.FaaSConfig{Authenticator: credentials})
+	api.Handlers(validHandlers())
+	separate := NewServer(&types.FaaSConfig{Authenticator: credentials, TCPPort: &apiPort, MetricsPort: &metricsPort})
+	separate.Handlers(validHandlers())
+
+	testCases := []struct {
+		name     string
+		router   http.Handler
+		user     string
+		wantCode int
+	}{
+		{name: "without credentials", router: api.Router(), wantCode: http.StatusUnauthorized},
+		{name: "with credentials", router: api.Router(), user: "admin", wantCode: http.StatusOK},
+		{name: "removed from the API with a MetricsPort", router: separate.Router(), user: "admin", wantCode: http.StatusNotFound},
+		{name: "metrics port without credentials", router: separate.metricsRouter, wantCode: http.StatusUnauthorized},
+		{name: "metrics port with credentials", router: separate.metricsRouter, user: "admin", wantCode: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/system/function-metrics", nil)
+			if len(tc.user) > 0 {
+				req.SetBasicAuth(tc.user, "secret")
+			}
+
+			w := httptest.NewRecorder()
+			tc.router.ServeHTTP(w, req)
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package bootstrap
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/openfaas/faas-provider/httputil"
+)
+
+// decorateWithFunction carries the function a request addresses in its context, for
+// handlers to read with httputil.FunctionFromContext. System routes take the namespace from
+// a "name.namespace" name or the "namespace" query string parameter, and reject a request
+// giving two different namespaces with a 400. Invocations only take it from the name, as
+// their query string belongs to the function.
+func decorateWithFunction(next http.HandlerFunc, fromQuery bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var name, namespace string
+		if fromQuery {
+			var err error
+			name, namespace, err = httputil.FunctionFromRequest(r)
+			if err != nil {
+				httputil.WriteErrorCode(w, r, http.StatusBadRequest, httputil.InvalidRequest, err.Error())
+				return
+			}
+		} else {
+			name, namespace = httputil.SplitFunctionName(httputil.PathVar(r, "name"))
+		}
+
+		next.ServeHTTP(w, r.WithContext(httputil.WithFunction(r.Context(), name, namespace)))
+	}
+}
+
+// qualifiedFunction returns the name and namespace of a function for keying the state kept
+// per function. name may be given as "name.namespace", when it has no namespace namespace
+// is used, then defaultNamespace.
+func qualifiedFunction(name, namespace, defaultNamespace string) (string, string) {
+	if n, ns := httputil.SplitFunctionName(name); len(ns) > 0 {
+		name, namespace = n, ns
+	}
+	if len(namespace) == 0 {
+		namespace = defaultNamespace
+	}
+	return name, namespace
+}
+
+// invocationPrefixes are the routes which invoke a function, each followed by its name, as
+// opposed to the system API.
+var invocationPrefixes = []string{"/function/", "/invoke/", "/invoke-batch/", "/async-function/"}
+
+// isInvocation reports whether path is one of the invocationPrefixes.
+func isInvocation(path string) bool {
+	for _, prefix := range invocationPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// invokedFunction returns the name of the function addressed by a path under one of the
+// invocationPrefixes.
+func invokedFunction(path string) (string, bool) {
+	for _, prefix := range invocationPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			name := strings.TrimPrefix(path, prefix)
+			if i := strings.Index(name, "/"); i >= 0 {
+				name = name[:i]
+			}
+			return name, len(name) > 0
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,56 @@
+package bootstrap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_Server_FunctionInContext(t *testing.T) {
+	var gotName, gotNamespace string
+	record := func(w http.ResponseWriter, r *http.Request) {
+		gotName, gotNamespace, _ = httputil.FunctionFromContext(r.Context())
+	}
+
+	s := NewServer(&types.FaaSConfig{})
+	s.Handlers(&types.FaaSHandlers{
+		FunctionStatus: record,
+		FunctionLister: record,
+		Logs:           record,
+		FunctionProxy:  record,
+	})
+
+	testCases := []struct {
+		name          string
+		url           string
+		wantCode      int
+		wantName      string
+		wantNamespace string
+	}{
+		{name: "status", url: "/system/function/figlet.dev", wantCode: http.StatusOK, wantName: "figlet", wantNamespace: "dev"},
+		{name: "status with query", url: "/system/function/figlet?namespace=dev", wantCode: http.StatusOK, wantName: "figlet", wantNamespace: "dev"},
+		{name: "status with two namespaces", url: "/system/function/figlet.dev?namespace=prod", wantCode: http.StatusBadRequest},
+		{name: "list", url: "/system/functions?namespace=dev", wantCode: http.StatusOK, wantNamespace: "dev"},
+		{name: "logs", url: "/system/logs?name=figlet&namespace=dev", wantCode: http.StatusOK, wantName: "figlet", wantNamespace: "dev"},
+		{name: "invoke", url: "/function/figlet.dev/path?namespace=ignored", wantCode: http.StatusOK, wantName: "figlet", wantNamespace: "dev"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotName, gotNamespace = "", ""
+
+			w := httptest.NewRecorder()
+			s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.url, nil))
+
+			if w.Code != tc.wantCode {
+				t.Fatalf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+			if gotName != tc.wantName || gotNamespace != tc.wantNamespace {
+				t.Errorf("function, want: %s %s, got: %s %s", tc.wantName, tc.wantNamespace, gotName, gotNamespace)
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openfaas/faas-provider/events"
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/types"
+)
+
+const (
+	// watchHistorySize is how many events are kept for clients which resume a watch.
+	watchHistorySize = 1024
+
+	// watcherBuffer is how many events are held for each client, a client which does not
+	// keep up has its stream closed, and resumes from the last event it received.
+	watcherBuffer = 64
+)
+
+// watchEventTypes maps the event types published for functions to their WatchEventType,
+// other events are not streamed.
+var watchEventTypes = map[events.Type]types.WatchEventType{
+	events.FunctionDeployed: types.WatchAdded,
+	events.FunctionUpdated:  types.WatchModified,
+	events.FunctionScaled:   types.WatchModified,
+	events.FunctionDeleted:  types.WatchDeleted,
+	events.InstanceDrain:    types.WatchDrain,
+}
+
+// functionWatch keeps the recent function events published on the bus, each with a
+// resource version, and streams them to the clients of "/system/functions/watch".
+type functionWatch struct {
+	unsubscribe func()
+
+	// allowed limits the events streamed to the namespaces of FaaSConfig.AllowedNamespaces,
+	// when it is not empty.
+	allowed []string
+
+	mu       sync.Mutex
+	version  uint64
+	history  []types.WatchEvent
+	watchers map[chan types.WatchEvent]struct{}
+}
+
+// newFunctionWatch subscribes to the function events of bus until stop is called. When
+// allowed is not empty, only the events of functions in those namespaces are streamed.
+func newFunctionWatch(bus *events.Bus, allowed []string) *functionWatch {
+	published, unsubscribe := bus.Subscribe(events.FunctionDeployed, events.FunctionUpdated, events.FunctionScaled, events.FunctionDeleted, events.InstanceDrain)
+
+	w := &functionWatch{
+		unsubscribe: unsubscribe,
+		allowed:     allowed,
+		watchers:    map[chan types.WatchEvent]struct{}{},
+	}
+	go func() {
+		for event := range published {
+			w.add(event)
+		}
+	}()
+	return w
+}
+
+func (w *functionWatch) stop() {
+	w.unsubscribe()
+}
+
+// add records event with the next resource version and sends it to each client.
+func (w *functionWatch) add(event events.Event) {
+	watchEvent := types.WatchEvent{
+		Type:      watchEventTypes[event.Type],
+		Name:      event.Function,
+		Namespace: event.Namespace,
+		Timestamp: event.Timestamp,
+	}
+	if replicas, err := strconv.ParseUint(event.Data["replicas"], 10, 64); err == nil {
+		watchEvent.Replicas = &replicas
+	}
+	if event.Type == events.InstanceDrain {
+		watchEvent.Instance = event.Data["instance"]
+		watchEvent.Stage = types.DrainStage(event.Data["stage"])
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.version++
+	watchEvent.ResourceVersion = strconv.FormatUint(w.version, 10)
+
+	if len(w.history) == watchHistorySize {
+		w.history = w.history[1:]
+	}
+	w.history = append(w.history, watchEvent)
+
+	for watcher := range w.watchers {
+		select {
+		case watcher <- watchEvent:
+		default:
+			delete(w.watchers, watcher)
+			close(watcher)
+		}
+	}
+}
+
+// watch returns the events after version, and a channel of the events which follow them.
+// The bool is false when events after version are no longer kept, or when version is
+// ahead of the latest event, as it was given by a provider which has since restarted.
+func (w *functionWatch) watch(version uint64) ([]types.WatchEvent, chan types.WatchEvent, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if version > w.version {
+		return nil, nil, false
+	}
+
+	var missed []types.WatchEvent
+	if version < w.version {
+		oldest := w.version - uint64(len(w.history)) + 1
+		if version+1 < oldest {
+			return nil, nil, false
+		}
+		missed = append(missed, w.history[version+1-oldest:]...)
+	}
+
+	watcher := make(chan types.WatchEvent, watcherBuffer)
+	w.watchers[watcher] = struct{}{}
+	return missed, watcher, true
+}
+
+func (w *functionWatch) unwatch(watcher chan types.WatchEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.watchers[watcher]; ok {
+		delete(w.watchers, watcher)
+		close(watcher)
+	}
+}
+
+// currentVersion returns the resource version of the latest event.
+func (w *functionWatch) currentVersion() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.version
+}
+
+// handler streams a WatchEvent for each change to a function, as lines of JSON, or as
+// Server-Sent Events when the client accepts "text/event-stream". Without a
+// "resourceVersion" query string parameter, or a Last-Event-ID header, the stream starts
+// with the next change. A version which is no longer kept is rejected with a 410, for the
+// client to list the functions again. The "namespace" parameter limits the stream to the
+// functions of a namespace, and "timeoutSeconds" ends it after a time, for clients which
+// long-poll. Events for namespaces outside of the allowlist are never sent.
+func (w *functionWatch) handler(rw http.ResponseWriter, r *http.Request) {
+	cursor := r.URL.Query().Get("resourceVersion")
+	if len(cursor) == 0 {
+		cursor = r.Header.Get("Last-Event-ID")
+	}
+
+	version := w.currentVersion()
+	if len(cursor) > 0 {
+		var err error
+		version, err = strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			httputil.WriteError(rw, r, http.StatusBadRequest, fmt.Sprintf("invalid resourceVersion %q: must be a whole number", cursor))
+			return
+		}
+	}
+
+	ctx := r.Context()
+	if timeout := r.URL.Query().Get("timeoutSeconds"); len(timeout) > 0 {
+		seconds, err := strconv.Atoi(timeout)
+		if err != nil || seconds <= 0 {
+			httputil.WriteError(rw, r, http.StatusBadRequest, fmt.Sprintf("invalid timeoutSeconds %q: must be a whole number greater than zero", timeout))
+			return
+		}
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(seconds)*time.Second)
+		defer cancel()
+	}
+
+	missed, watcher, ok := w.watch(version)
+	if !ok {
+		httputil.WriteError(rw, r, http.StatusGone, fmt.Sprintf("resourceVersion %d is too old, list the functions to start again", version))
+		return
+	}
+	defer w.unwatch(watcher)
+
+	send, err := newWatchWriter(rw, r)
+	if err != nil {
+		httputil.WriteError(rw, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	namespace := r.URL.Query().Get("namespace")
+	write := func(event types.WatchEvent) bool {
+		if len(namespace) > 0 && event.Namespace != namespace {
+			return true
+		}
+		if len(w.allowed) > 0 && !httputil.NamespaceAllowed(event.Namespace, w.allowed) {
+			return true
+		}
+		return send(event) == nil
+	}
+
+	for _, event := range missed {
+		if !write(event) {
+			return
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher:
+			if !ok || !write(event) {
+				return
+			}
+		}
+	}
+}
+
+// decorateWithWatchNamespace rejects a watch for a "namespace" which is not in allowed
+// with a 403, before next starts the stream. A watch without a namespace is passed to next,
+// which limits the events it sends to allowed.
+func decorateWithWatchNamespace(next http.HandlerFunc, allowed []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		namespace := r.URL.Query().Get("namespace")
+		if len(allowed) > 0 && len(namespace) > 0 && !httputil.NamespaceAllowed(namespace, allowed) {
+			httputil.WriteErrorCode(w, r, http.StatusForbidden, httputil.NamespaceForbidden, fmt.Sprintf("namespace %s is not allowed", namespace))
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+// newWatchWriter writes the headers of the stream and returns a func which sends an event
+// on it.
+func newWatchWriter(w http.ResponseWriter, r *http.Request) (func(types.WatchEvent) error, error) {
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		stream, err := httputil.NewEventStream(w)
+		if err != nil {
+			return nil, err
+		}
+		return func(event types.WatchEvent) error {
+			if _, err := fmt.Fprintf(w, "id: %s\n", event.ResourceVersion); err != nil {
+				return err
+			}
+			return stream.Send(string(event.Type), event)
+		}, nil
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, fmt.Errorf("streaming is not supported by the response writer")
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	encoder := json.NewEncoder(w)
+	return func(event types.WatchEvent) error {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}, nil
+}
@@ -0,0 +1,198 @@
+package bootstrap
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-provider/events"
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_FunctionWatch(t *testing.T) {
+	bus := events.NewBus(events.Config{})
+	s := NewServer(&types.FaaSConfig{Events: bus})
+	s.Handlers(validHandlers())
+
+	server := httptest.NewServer(s.Router())
+	defer server.Close()
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/system/functions", strings.NewReader(`{"service":"figlet","namespace":"dev"}`)),
+		httptest.NewRequest(http.MethodPost, "/system/functions", strings.NewReader(`{"service":"env","namespace":"prod"}`)),
+		httptest.NewRequest(http.MethodPost, "/system/scale-function/figlet?namespace=dev", strings.NewReader(`{"serviceName":"figlet","namespace":"dev","replicas":3}`)),
+	} {
+		rr := httptest.NewRecorder()
+		s.Router().ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("status code, want: %d, got: %d", http.StatusOK, rr.Code)
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for s.watch.currentVersion() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	watch := func(t *testing.T, query string, header http.Header) (*http.Response, func() types.WatchEvent) {
+		t.Helper()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/system/functions/watch?"+query, nil)
+		for key := range header {
+			req.Header.Set(key, header.Get(key))
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { res.Body.Close() })
+
+		scanner := bufio.NewScanner(res.Body)
+		return res, func() types.WatchEvent {
+			t.Helper()
+			for scanner.Scan() {
+				line := strings.TrimPrefix(scanner.Text(), "data: ")
+				if !strings.HasPrefix(line, "{") {
+					continue
+				}
+				event := types.WatchEvent{}
+				if err := json.Unmarshal([]byte(line), &event); err != nil {
+					t.Fatal(err)
+				}
+				return event
+			}
+			t.Fatalf("stream ended: %v", scanner.Err())
+			return types.WatchEvent{}
+		}
+	}
+
+	t.Run("resumes after resourceVersion", func(t *testing.T) {
+		res, next := watch(t, "resourceVersion=1", nil)
+		if got := res.Header.Get("Content-Type"); got != "application/x-ndjson" {
+			t.Errorf("Content-Type, want: %s, got: %s", "application/x-ndjson", got)
+		}
+
+		if event := next(); event.Type != types.WatchAdded || event.Name != "env" || event.ResourceVersion != "2" {
+			t.Errorf("want env to be added at 2, got: %+v", event)
+		}
+		event := next()
+		if event.Type != types.WatchModified || event.Name != "figlet" || event.Replicas == nil || *event.Replicas != 3 {
+			t.Errorf("want figlet to be scaled to 3, got: %+v", event)
+		}
+	})
+
+	t.Run("namespace", func(t *testing.T) {
+		_, next := watch(t, "resourceVersion=0&namespace=dev", nil)
+
+		for _, want := range []string{"1", "3"} {
+			if event := next(); event.Namespace != "dev" || event.ResourceVersion != want {
+				t.Errorf("want the event at %s in dev, got: %+v", want, event)
+			}
+		}
+	})
+
+	t.Run("server-sent events stream the next change", func(t *testing.T) {
+		res, next := watch(t, "", http.Header{"Accept": []string{"text/event-stream"}})
+		if got := res.Header.Get("Content-Type"); got != "text/event-stream" {
+			t.Errorf("Content-Type, want: %s, got: %s", "text/event-stream", got)
+		}
+
+		bus.Publish(events.Event{Type: events.FunctionDeleted, Function: "env", Namespace: "prod"})
+		if event := next(); event.Type != types.WatchDeleted || event.Name != "env" || event.ResourceVersion != "4" {
+			t.Errorf("want env to be deleted at 4, got: %+v", event)
+		}
+	})
+
+	t.Run("invalid cursors", func(t *testing.T) {
+		for query, want := range map[string]int{
+			"resourceVersion=latest": http.StatusBadRequest,
+			"resourceVersion=99":     http.StatusGone,
+			"timeoutSeconds=0":       http.StatusBadRequest,
+			"timeoutSeconds=soon":    http.StatusBadRequest,
+		} {
+			rr := httptest.NewRecorder()
+			s.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/system/functions/watch?"+query, nil))
+			if rr.Code != want {
+				t.Errorf("%s: status code, want: %d, got: %d", query, want, rr.Code)
+			}
+		}
+	})
+}
+
+func Test_FunctionWatch_History(t *testing.T) {
+	w := &functionWatch{watchers: map[chan types.WatchEvent]struct{}{}}
+	for i := 0; i < watchHistorySize+2; i++ {
+		w.add(events.Event{Type: events.FunctionUpdated, Function: "figlet"})
+	}
+
+	if _, _, ok := w.watch(1); ok {
+		t.Errorf("want events after 1 to be no longer kept")
+	}
+
+	missed, _, ok := w.watch(2)
+	if !ok || len(missed) != watchHistorySize || missed[0].ResourceVersion != "3" {
+		t.Errorf("want every kept event from 3, got: %d events", len(missed))
+	}
+}
+
+func Test_FunctionWatch_NotImplementedWithoutEvents(t *testing.T) {
+	s := NewServer(&types.FaaSConfig{})
+	s.Handlers(validHandlers())
+
+	rr := httptest.NewRecorder()
+	s.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/system/functions/watch", nil))
+	if rr.Code != http.StatusNotImplemented {
+		t.Errorf("status code, want: %d, got: %d", http.StatusNotImplemented, rr.Code)
+	}
+}
+
+func Test_FunctionWatch_AllowedNamespaces(t *testing.T) {
+	bus := events.NewBus(events.Config{})
+	s := NewServer(&types.FaaSConfig{Events: bus, AllowedNamespaces: []string{"dev"}})
+	s.Handlers(validHandlers())
+
+	bus.Publish(events.Event{Type: events.FunctionDeployed, Function: "figlet", Namespace: "dev"})
+	bus.Publish(events.Event{Type: events.FunctionDeployed, Function: "env", Namespace: "prod"})
+	bus.Publish(events.Event{Type: events.FunctionDeleted, Function: "nodeinfo"})
+
+	deadline := time.Now().Add(time.Second)
+	for s.watch.currentVersion() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A stream sends the missed events, then ends as the context is cancelled.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	rr := httptest.NewRecorder()
+	s.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/system/functions/watch?namespace=prod", nil).WithContext(ctx))
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("status code, want: %d, got: %d", http.StatusForbidden, rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	s.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/system/functions/watch?resourceVersion=0", nil).WithContext(ctx))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code, want: %d, got: %d", http.StatusOK, rr.Code)
+	}
+
+	var names []string
+	for _, line := range strings.Split(strings.TrimSpace(rr.Body.String()), "\n") {
+		event := types.WatchEvent{}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, event.Name)
+	}
+	if len(names) != 1 || names[0] != "figlet" {
+		t.Errorf("events, want: [figlet], got: %v", names)
+	}
+}
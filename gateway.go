@@ -0,0 +1,46 @@
+package bootstrap
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/openfaas/faas-provider/httputil"
+)
+
+// DefaultGatewayHeaders are set by the OpenFaaS gateway on the requests it proxies to
+// the provider.
+var DefaultGatewayHeaders = []string{"X-Call-Id"}
+
+// IsGatewayRequest reports whether r was proxied by the OpenFaaS gateway, by checking
+// that each of headers is present. DefaultGatewayHeaders are checked when no headers
+// are given.
+//
+// The headers can be set by any client which can reach the provider, so this is a way
+// to detect accidental direct use rather than a substitute for authentication.
+func IsGatewayRequest(r *http.Request, headers ...string) bool {
+	if len(headers) == 0 {
+		headers = DefaultGatewayHeaders
+	}
+
+	for _, h := range headers {
+		if len(r.Header.Get(h)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// requireGateway returns a middleware which rejects requests to the system API with
+// a 403 unless they were proxied by the gateway.
+func requireGateway(headers []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(r.URL.Path, "/system/") && !IsGatewayRequest(r, headers...) {
+				httputil.WriteErrorCode(w, r, http.StatusForbidden, httputil.GatewayRequired, "system API must be called through the gateway")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package bootstrap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openfaas/faas-provider/auth/hmac"
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_IsGatewayRequest(t *testing.T) {
+	testCases := []struct {
+		name    string
+		set     map[string]string
+		headers []string
+		want    bool
+	}{
+		{name: "direct request", want: false},
+		{name: "default headers", set: map[string]string{"X-Call-Id": "abc"}, want: true},
+		{name: "custom headers", set: map[string]string{"X-Gateway": "openfaas", "X-Call-Id": "abc"}, headers: []string{"X-Gateway", "X-Call-Id"}, want: true},
+		{name: "custom headers missing one", set: map[string]string{"X-Call-Id": "abc"}, headers: []string{"X-Gateway", "X-Call-Id"}, want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/system/functions", nil)
+			for k, v := range tc.set {
+				r.Header.Set(k, v)
+			}
+
+			if got := IsGatewayRequest(r, tc.headers...); got != tc.want {
+				t.Errorf("want: %t, got: %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_requireGateway(t *testing.T) {
+	handler := requireGateway(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	testCases := []struct {
+		name     string
+		path     string
+		callID   string
+		wantCode int
+	}{
+		{name: "direct system request", path: "/system/functions", wantCode: http.StatusForbidden},
+		{name: "gateway system request", path: "/system/functions", callID: "abc", wantCode: http.StatusOK},
+		{name: "direct invocation", path: "/function/figlet", wantCode: http.StatusOK},
+		{name: "health check", path: "/healthz", wantCode: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if len(tc.callID) > 0 {
+				r.Header.Set("X-Call-Id", tc.callID)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+		})
+	}
+}
+
+func Test_HMACAuth(t *testing.T) {
+	secrets := t.TempDir()
+	key := []byte("s3cret")
+	if err := os.WriteFile(filepath.Join(secrets, hmac.DefaultKeyFilename), key, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name              string
+		path              string
+		key               []byte
+		verifyInvocations bool
+		wantCode          int
+	}{
+		{name: "signed system request", path: "/system/functions", key: key, wantCode: http.StatusOK},
+		{name: "unsigned system request", path: "/system/functions", wantCode: http.StatusUnauthorized},
+		{name: "signed with another key", path: "/system/functions", key: []byte("guess"), wantCode: http.StatusUnauthorized},
+		{name: "unsigned invocation", path: "/function/figlet", wantCode: http.StatusOK},
+		{name: "unsigned invocation when verified", path: "/function/figlet", verifyInvocations: true, wantCode: http.StatusUnauthorized},
+		{name: "metrics", path: "/metrics", wantCode: http.StatusOK},
+		{name: "readiness check", path: "/readyz", wantCode: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewServer(&types.FaaSConfig{EnableHMACAuth: true, HMACVerifyInvocations: tc.verifyInvocations, SecretMountPath: secrets})
+			s.Handlers(validHandlers())
+
+			r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.key != nil {
+				if err := hmac.SignRequest(r, tc.key); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			w := httptest.NewRecorder()
+			s.Router().ServeHTTP(w, r)
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/openfaas/faas-provider/rpc"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// ServeGRPC serves the provider API over gRPC on FaaSConfig.GRPCPort until ctx is cancelled,
+// see the rpc package for the service. It creates a Server for handlers, so handlers must
+// not also be given to Serve, which would decorate them a second time. To serve HTTP and
+// gRPC from the same handlers, call Server.ServeGRPC alongside Server.Serve.
+func ServeGRPC(ctx context.Context, handlers *types.FaaSHandlers, config *types.FaaSConfig) error {
+	s := NewServer(config)
+	s.Handlers(handlers)
+	return s.ServeGRPC(ctx)
+}
+
+// ServeGRPC serves the provider API over gRPC on FaaSConfig.GRPCPort until ctx is
+// cancelled. Each call is answered by the router of the Server, so it goes through the
+// same authentication and middleware as the HTTP API, with the call's metadata as the
+// headers of the request. The API is served over TLS when TLSConfig is set, with the
+// certificate of the HTTP API, otherwise over HTTP/2 cleartext (h2c), as gRPC requires
+// HTTP/2.
+func (s *Server) ServeGRPC(ctx context.Context) error {
+	config := s.config
+
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	if s.err != nil {
+		return s.err
+	}
+	if !s.registered {
+		return errors.New("handlers must be registered before ServeGRPC")
+	}
+
+	if config.GRPCPort == nil {
+		return errors.New("GRPCPort is not set")
+	}
+
+	logger := config.GetLogger()
+
+	server := &http.Server{
+		Addr:              net.JoinHostPort(config.BindAddress, strconv.Itoa(*config.GRPCPort)),
+		IdleTimeout:       config.GetIdleTimeout(),
+		ReadHeaderTimeout: config.GetReadHeaderTimeout(),
+		MaxHeaderBytes:    http.DefaultMaxHeaderBytes,
+		Handler:           rpc.NewHandler(s.router),
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelError),
+		BaseContext: func(net.Listener) context.Context {
+			return s.baseContext
+		},
+	}
+
+	useTLS := config.TLSConfig != nil
+	if useTLS {
+		tlsConfig, err := s.serverTLSConfig()
+		if err != nil {
+			return err
+		}
+		server.TLSConfig = tlsConfig
+		go s.certs.watch(ctx, logger)
+	}
+
+	// ConfigureServer also sets TLSConfig on the server, so useTLS decides how to serve.
+	if err := configureHTTP2(server, config); err != nil {
+		return err
+	}
+
+	l, err := net.Listen(config.GetNetwork(), server.Addr)
+	if err != nil {
+		return fmt.Errorf("unable to listen for gRPC: %w", err)
+	}
+	logger.Info("Serving gRPC", "addr", l.Addr().String())
+
+	serveErr := make(chan error, 1)
+	go func() {
+		var err error
+		if useTLS {
+			err = server.ServeTLS(l, "", "")
+		} else {
+			err = server.Serve(l)
+		}
+
+		if err != nil && err != http.ErrServerClosed {
+			serveErr <- err
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+	case err := <-serveErr:
+		return err
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.GetShutdownTimeout())
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("gRPC server shutdown failed: %w", err)
+	}
+	return nil
+}
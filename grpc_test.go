@@ -0,0 +1,121 @@
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-provider/rpc"
+	"github.com/openfaas/faas-provider/types"
+	"golang.org/x/net/http2"
+)
+
+func Test_Server_ServeGRPC(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	handlers := validHandlers()
+	handlers.FunctionLister = func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]types.FunctionStatus{{Name: "figlet"}})
+	}
+
+	s := NewServer(&types.FaaSConfig{GRPCPort: &port})
+	s.Handlers(handlers)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ServeGRPC(ctx)
+	}()
+
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			break
+		}
+		select {
+		case err := <-done:
+			t.Fatalf("want the gRPC server to start, got: %v", err)
+		default:
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("want the gRPC server to start, got: %s", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	client := &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, addr)
+			},
+		},
+	}
+
+	// An empty ListFunctionsRequest: an uncompressed message of zero bytes.
+	res, err := client.Post("http://"+addr+"/"+rpc.Service+"/ListFunctions", "application/grpc", bytes.NewReader(make([]byte, 5)))
+	if err != nil {
+		t.Fatalf("want a gRPC response, got: %s", err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+
+	if got := res.Trailer.Get("Grpc-Status"); got != "0" {
+		t.Fatalf("grpc-status, want: %s, got: %s (%s)", "0", got, res.Trailer.Get("Grpc-Message"))
+	}
+	if len(body) < 5 || int(binary.BigEndian.Uint32(body[1:5])) != len(body)-5 {
+		t.Fatalf("want a length-prefixed message, got: %q", body)
+	}
+	if !strings.Contains(string(body), "figlet") {
+		t.Errorf("want the response to list figlet, got: %q", body)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("want no error after cancelling the context, got: %s", err)
+	}
+}
+
+func Test_Server_ServeGRPC_Errors(t *testing.T) {
+	port := 8081
+
+	cases := []struct {
+		name    string
+		config  *types.FaaSConfig
+		handle  bool
+		wantErr string
+	}{
+		{"no port", &types.FaaSConfig{}, true, "GRPCPort is not set"},
+		{"no handlers", &types.FaaSConfig{GRPCPort: &port}, false, "handlers must be registered before ServeGRPC"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewServer(tc.config)
+			if tc.handle {
+				s.Handlers(validHandlers())
+			}
+
+			err := s.ServeGRPC(context.Background())
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("want: %q, got: %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+// Package health runs the named probes which subsystems of a provider register, such as
+// the connection to a queue, a checkpoint store or the container runtime, and serves
+// their results as JSON for the "/healthz" liveness and "/readyz" readiness endpoints.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultTimeout bounds each probe when neither the probe nor the Checker sets a timeout.
+const defaultTimeout = 5 * time.Second
+
+// Kind is the endpoint a probe is reported on, a probe may be reported on both.
+type Kind uint8
+
+const (
+	// Liveness probes fail when the provider can not recover without being restarted.
+	Liveness Kind = 1 << iota
+	// Readiness probes fail while the provider can not serve traffic, i.e. while a
+	// dependency is unavailable.
+	Readiness
+)
+
+// Probe is a named check of a subsystem.
+type Probe struct {
+	// Name identifies the probe in the results, i.e. "queue" or "containerd".
+	Name string
+	// Kind is the endpoint the probe is reported on.
+	Kind Kind
+	// Check returns an error when the subsystem is unhealthy, it must return once ctx is
+	// done.
+	Check func(ctx context.Context) error
+	// Timeout bounds Check, the Checker's timeout is used when it is not set.
+	Timeout time.Duration
+}
+
+// Status is the result of a probe, or of every probe of an endpoint.
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusFail Status = "fail"
+)
+
+// Result is the result of one probe.
+type Result struct {
+	Name     string        `json:"name"`
+	Status   Status        `json:"status"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Report is the result of every probe of an endpoint, in the order of their names. Its
+// status is StatusFail when any probe failed.
+type Report struct {
+	Status Status   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// Checker holds the probes of a provider, it is safe for concurrent use.
+type Checker struct {
+	timeout time.Duration
+
+	mu     sync.RWMutex
+	probes map[string]Probe
+}
+
+// NewChecker creates a Checker which gives each probe up to timeout, unless the probe sets
+// its own. A timeout of 0 uses the default of 5s.
+func NewChecker(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	return &Checker{
+		timeout: timeout,
+		probes:  map[string]Probe{},
+	}
+}
+
+// Register adds a probe, its name must be unique.
+func (c *Checker) Register(probe Probe) error {
+	if err := validate(probe); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.probes[probe.Name]; ok {
+		return fmt.Errorf("probe %q is already registered", probe.Name)
+	}
+	c.probes[probe.Name] = probe
+	return nil
+}
+
+// Set adds a probe, replacing the probe registered with the same name, if any.
+func (c *Checker) Set(probe Probe) error {
+	if err := validate(probe); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.probes[probe.Name] = probe
+	return nil
+}
+
+func validate(probe Probe) error {
+	if len(probe.Name) == 0 {
+		return errors.New("probe name is required")
+	}
+	if probe.Check == nil {
+		return fmt.Errorf("probe %q has no Check", probe.Name)
+	}
+	if probe.Kind&(Liveness|Readiness) == 0 {
+		return fmt.Errorf("probe %q must be a Liveness or Readiness probe", probe.Name)
+	}
+	return nil
+}
+
+// Check runs the probes of kind at the same time and reports their results.
+func (c *Checker) Check(ctx context.Context, kind Kind) Report {
+	c.mu.RLock()
+	var probes []Probe
+	for _, probe := range c.probes {
+		if probe.Kind&kind != 0 {
+			probes = append(probes, probe)
+		}
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(probes, func(i, j int) bool {
+		return probes[i].Name < probes[j].Name
+	})
+
+	report := Report{Status: StatusOK, Checks: make([]Result, len(probes))}
+
+	wg := sync.WaitGroup{}
+	for i, probe := range probes {
+		wg.Add(1)
+		go func(i int, probe Probe) {
+			defer wg.Done()
+			report.Checks[i] = c.run(ctx, probe)
+		}(i, probe)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// run calls the probe within its timeout. A probe which does not return in time is
+// reported as failed without waiting for it.
+func (c *Checker) run(ctx context.Context, probe Probe) Result {
+	timeout := probe.Timeout
+	if timeout <= 0 {
+		timeout = c.timeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- probe.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+
+	result := Result{Name: probe.Name, Status: StatusOK, Duration: time.Since(start)}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Handler serves the report of the probes of kind as JSON, with a 200 when every probe
+// passed, otherwise a 503.
+func (c *Checker) Handler(kind Kind) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := c.Check(r.Context(), kind)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if report.Status != StatusOK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	}
+}
@@ -0,0 +1,160 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_Checker_Check(t *testing.T) {
+	c := NewChecker(20 * time.Millisecond)
+
+	probes := []Probe{
+		{Name: "runtime", Kind: Liveness | Readiness, Check: func(ctx context.Context) error { return nil }},
+		{Name: "queue", Kind: Readiness, Check: func(ctx context.Context) error { return errors.New("connection refused") }},
+		{Name: "checkpoints", Kind: Readiness, Check: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+		{Name: "store", Kind: Readiness, Timeout: time.Second, Check: func(ctx context.Context) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(50 * time.Millisecond):
+				return nil
+			}
+		}},
+	}
+	for _, probe := range probes {
+		if err := c.Register(probe); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name       string
+		kind       Kind
+		wantStatus Status
+		want       map[string]string
+	}{
+		{"liveness", Liveness, StatusOK, map[string]string{"runtime": ""}},
+		{"readiness", Readiness, StatusFail, map[string]string{
+			"checkpoints": "timed out after 20ms",
+			"queue":       "connection refused",
+			"runtime":     "",
+			"store":       "",
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			report := c.Check(context.Background(), tc.kind)
+
+			if report.Status != tc.wantStatus {
+				t.Errorf("status, want: %s, got: %s", tc.wantStatus, report.Status)
+			}
+
+			got := map[string]string{}
+			var names []string
+			for _, result := range report.Checks {
+				got[result.Name] = result.Error
+				names = append(names, result.Name)
+				if (result.Error == "") != (result.Status == StatusOK) {
+					t.Errorf("%s: status %s with error %q", result.Name, result.Status, result.Error)
+				}
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want: %v, got: %v", tc.want, got)
+			}
+			for i := 1; i < len(names); i++ {
+				if names[i-1] > names[i] {
+					t.Errorf("want the checks in the order of their names, got: %v", names)
+				}
+			}
+		})
+	}
+}
+
+func Test_Checker_Register(t *testing.T) {
+	check := func(ctx context.Context) error { return nil }
+
+	cases := []struct {
+		name    string
+		probe   Probe
+		wantErr string
+	}{
+		{"no name", Probe{Kind: Liveness, Check: check}, "probe name is required"},
+		{"no check", Probe{Name: "queue", Kind: Liveness}, `probe "queue" has no Check`},
+		{"no kind", Probe{Name: "queue", Check: check}, `probe "queue" must be a Liveness or Readiness probe`},
+		{"duplicate", Probe{Name: "runtime", Kind: Readiness, Check: check}, `probe "runtime" is already registered`},
+	}
+
+	c := NewChecker(0)
+	if err := c.Register(Probe{Name: "runtime", Kind: Liveness, Check: check}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := c.Register(tc.probe)
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("want: %q, got: %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_Checker_Set(t *testing.T) {
+	c := NewChecker(0)
+	c.Register(Probe{Name: "queue", Kind: Readiness, Check: func(ctx context.Context) error { return errors.New("connection refused") }})
+
+	if err := c.Set(Probe{Name: "queue", Kind: Readiness, Check: func(ctx context.Context) error { return nil }}); err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+	if err := c.Set(Probe{Name: "queue", Kind: Readiness}); err == nil {
+		t.Errorf("want an error for a probe without a Check")
+	}
+
+	report := c.Check(context.Background(), Readiness)
+	if len(report.Checks) != 1 || len(report.Checks[0].Error) > 0 {
+		t.Errorf("checks, want: the replaced queue probe passing, got: %+v", report.Checks)
+	}
+}
+
+func Test_Checker_Handler(t *testing.T) {
+	c := NewChecker(0)
+	c.Register(Probe{Name: "queue", Kind: Readiness, Check: func(ctx context.Context) error { return errors.New("connection refused") }})
+
+	cases := []struct {
+		name     string
+		kind     Kind
+		wantCode int
+	}{
+		{"liveness without probes", Liveness, http.StatusOK},
+		{"failing readiness", Readiness, http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c.Handler(tc.kind).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+			if got := w.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type, want: %s, got: %s", "application/json", got)
+			}
+
+			report := Report{}
+			if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+				t.Fatalf("want a JSON report, got: %s", err)
+			}
+		})
+	}
+}
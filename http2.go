@@ -0,0 +1,39 @@
+package bootstrap
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/openfaas/faas-provider/types"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// configureHTTP2 enables HTTP/2 on server with the settings of FaaSConfig.HTTP2. It is
+// negotiated with ALPN when server.TLSConfig is set, otherwise it is served as h2c
+// alongside HTTP/1.1.
+func configureHTTP2(server *http.Server, config *types.FaaSConfig) error {
+	useTLS := server.TLSConfig != nil
+	settings := config.GetHTTP2()
+
+	h2s := &http2.Server{
+		IdleTimeout:                  config.GetIdleTimeout(),
+		MaxConcurrentStreams:         settings.GetMaxConcurrentStreams(),
+		MaxReadFrameSize:             settings.GetMaxReadFrameSize(),
+		MaxUploadBufferPerStream:     settings.GetStreamWindowSize(),
+		MaxUploadBufferPerConnection: settings.GetConnectionWindowSize(),
+	}
+
+	// Configuring the http2.Server on the http.Server closes HTTP/2 connections
+	// gracefully on Shutdown, which does not track the connections h2c hijacks.
+	if err := http2.ConfigureServer(server, h2s); err != nil {
+		return fmt.Errorf("unable to configure HTTP/2: %w", err)
+	}
+
+	// ConfigureServer also sets TLSConfig, so useTLS was decided before it was called.
+	if !useTLS {
+		server.Handler = h2c.NewHandler(server.Handler, h2s)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package bootstrap
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openfaas/faas-provider/types"
+	"golang.org/x/net/http2"
+)
+
+func Test_configureHTTP2_NegotiatesH2OverTLS(t *testing.T) {
+	server := &http.Server{TLSConfig: &tls.Config{}}
+
+	if err := configureHTTP2(server, &types.FaaSConfig{}); err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, proto := range server.TLSConfig.NextProtos {
+		if proto == http2.NextProtoTLS {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("want %q in NextProtos, got: %v", http2.NextProtoTLS, server.TLSConfig.NextProtos)
+	}
+}
+
+func Test_configureHTTP2_ServesH2C(t *testing.T) {
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Proto))
+		}),
+	}
+
+	config := &types.FaaSConfig{HTTP2: &types.HTTP2Config{StreamWindowSize: 8 << 20}}
+	if err := configureHTTP2(server, config); err != nil {
+		t.Fatal(err)
+	}
+
+	s := httptest.NewServer(server.Handler)
+	defer s.Close()
+
+	client := &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, addr)
+			},
+		},
+	}
+
+	res, err := client.Get(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+
+	if string(body) != "HTTP/2.0" {
+		t.Errorf("protocol, want: %s, got: %s", "HTTP/2.0", body)
+	}
+}
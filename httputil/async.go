@@ -0,0 +1,43 @@
+package httputil
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// RespondAsync is the preference sent by clients in the "Prefer" header to ask for a long
+// running request, such as a deploy, to be accepted and completed in the background.
+const RespondAsync = "respond-async"
+
+// PrefersAsync reports whether the client sent "Prefer: respond-async", see RFC 7240.
+func PrefersAsync(r *http.Request) bool {
+	for _, header := range r.Header.Values("Prefer") {
+		for _, preference := range strings.Split(header, ",") {
+			token, _, _ := strings.Cut(strings.TrimSpace(preference), ";")
+			if strings.EqualFold(strings.TrimSpace(token), RespondAsync) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FunctionStatusLocation returns the path of the status of a function, for the Location
+// header of an accepted deploy, i.e. "/system/function/figlet?namespace=openfaas-fn".
+func FunctionStatusLocation(name, namespace string) string {
+	location := "/system/function/" + url.PathEscape(name)
+	if len(namespace) > 0 {
+		location += "?namespace=" + url.QueryEscape(namespace)
+	}
+	return location
+}
+
+// WriteAccepted tells the client that its request was accepted to be completed in the
+// background, with a 202 and a Location header of the resource to poll for its progress,
+// such as FunctionStatusLocation for a deploy.
+func WriteAccepted(w http.ResponseWriter, location string) {
+	w.Header().Set("Location", location)
+	w.Header().Set("Preference-Applied", RespondAsync)
+	w.WriteHeader(http.StatusAccepted)
+}
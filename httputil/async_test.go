@@ -0,0 +1,51 @@
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_PrefersAsync(t *testing.T) {
+	testCases := []struct {
+		prefer []string
+		want   bool
+	}{
+		{prefer: nil, want: false},
+		{prefer: []string{"respond-async"}, want: true},
+		{prefer: []string{"Respond-Async"}, want: true},
+		{prefer: []string{"return=minimal, respond-async, wait=10"}, want: true},
+		{prefer: []string{"return=minimal", "respond-async; foo=bar"}, want: true},
+		{prefer: []string{"return=minimal"}, want: false},
+		{prefer: []string{"respond-asynchronously"}, want: false},
+	}
+
+	for _, tc := range testCases {
+		r := httptest.NewRequest(http.MethodPost, "/system/functions", nil)
+		for _, v := range tc.prefer {
+			r.Header.Add("Prefer", v)
+		}
+
+		if got := PrefersAsync(r); got != tc.want {
+			t.Errorf("%q, want: %t, got: %t", tc.prefer, tc.want, got)
+		}
+	}
+}
+
+func Test_WriteAccepted(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteAccepted(w, FunctionStatusLocation("figlet", "openfaas-fn"))
+
+	if w.Code != http.StatusAccepted {
+		t.Errorf("status code, want: %d, got: %d", http.StatusAccepted, w.Code)
+	}
+
+	want := "/system/function/figlet?namespace=openfaas-fn"
+	if got := w.Header().Get("Location"); got != want {
+		t.Errorf("Location, want: %q, got: %q", want, got)
+	}
+
+	if got := w.Header().Get("Preference-Applied"); got != RespondAsync {
+		t.Errorf("Preference-Applied, want: %q, got: %q", RespondAsync, got)
+	}
+}
@@ -0,0 +1,45 @@
+package httputil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// BufferBody reads the body of r and replaces it with a copy, so that a middleware can
+// decode it and the next handler can still read it. A body larger than the limit of an
+// http.MaxBytesReader is answered with a 413, and any other error reading it with a 400,
+// in which case ok is false and the request must not be served further. The body is nil
+// when the request has none.
+func BufferBody(w http.ResponseWriter, r *http.Request) (body []byte, ok bool) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, true
+	}
+
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		WriteErrorCode(w, r, http.StatusRequestEntityTooLarge, RequestTooLarge, fmt.Sprintf("request body must not be larger than %d bytes", maxBytesErr.Limit))
+		return nil, false
+	}
+	if err != nil {
+		WriteErrorCode(w, r, http.StatusBadRequest, InvalidRequest, fmt.Sprintf("unable to read request body: %s", err))
+		return nil, false
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, true
+}
+
+// ReplaceBody sets body as the body of r, with its Content-Length, i.e. once a middleware
+// has changed the request it decoded with BufferBody.
+func ReplaceBody(r *http.Request, body []byte) {
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+}
@@ -0,0 +1,70 @@
+package httputil
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func Test_BufferBody(t *testing.T) {
+	testCases := []struct {
+		name       string
+		body       io.Reader
+		limit      int64
+		wantOK     bool
+		wantStatus int
+	}{
+		{name: "body", body: strings.NewReader("hello"), wantOK: true},
+		{name: "no body", wantOK: true},
+		{name: "over the limit", body: strings.NewReader("hello"), limit: 2, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "read error", body: failingReader{}, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/system/functions", tc.body)
+			if tc.limit > 0 {
+				r.Body = http.MaxBytesReader(w, r.Body, tc.limit)
+			}
+
+			body, ok := BufferBody(w, r)
+			if ok != tc.wantOK {
+				t.Fatalf("ok, want: %t, got: %t", tc.wantOK, ok)
+			}
+			if !ok {
+				if w.Code != tc.wantStatus {
+					t.Errorf("status code, want: %d, got: %d", tc.wantStatus, w.Code)
+				}
+				return
+			}
+
+			again, _ := io.ReadAll(r.Body)
+			if string(again) != string(body) {
+				t.Errorf("restored body, want: %q, got: %q", body, again)
+			}
+		})
+	}
+}
+
+func Test_ReplaceBody(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/system/functions", strings.NewReader("hello"))
+	ReplaceBody(r, []byte("goodbye"))
+
+	body, _ := io.ReadAll(r.Body)
+	if string(body) != "goodbye" {
+		t.Errorf("body, want: %q, got: %q", "goodbye", body)
+	}
+	if r.ContentLength != 7 || r.Header.Get("Content-Length") != "7" {
+		t.Errorf("Content-Length, want: %d, got: %d (%s)", 7, r.ContentLength, r.Header.Get("Content-Length"))
+	}
+}
@@ -0,0 +1,24 @@
+package httputil
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// SetDeprecationHeaders tells the client that its request relied on deprecated behaviour.
+// It sets the "Deprecation" header, a "Sunset" header with the date after which the behaviour
+// may be removed when sunset is not zero, and a "Warning" header for each message.
+//
+// It must be called before the status code is written.
+func SetDeprecationHeaders(w http.ResponseWriter, sunset time.Time, messages ...string) {
+	w.Header().Set("Deprecation", "true")
+
+	if !sunset.IsZero() {
+		w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+	}
+
+	for _, message := range messages {
+		w.Header().Add("Warning", "299 - "+strconv.Quote(message))
+	}
+}
@@ -0,0 +1,38 @@
+package httputil
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_SetDeprecationHeaders(t *testing.T) {
+	w := httptest.NewRecorder()
+	sunset := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	SetDeprecationHeaders(w, sunset, `envProcess is deprecated, use envVars["fprocess"]`)
+
+	if got := w.Header().Get("Deprecation"); got != "true" {
+		t.Errorf("Deprecation, want: %s, got: %s", "true", got)
+	}
+
+	if got := w.Header().Get("Sunset"); got != "Wed, 31 Jan 2024 00:00:00 GMT" {
+		t.Errorf("Sunset, want: %s, got: %s", "Wed, 31 Jan 2024 00:00:00 GMT", got)
+	}
+
+	want := []string{`299 - "envProcess is deprecated, use envVars[\"fprocess\"]"`}
+	if got := w.Header().Values("Warning"); !reflect.DeepEqual(want, got) {
+		t.Errorf("Warning, want: %v, got: %v", want, got)
+	}
+}
+
+func Test_SetDeprecationHeaders_NoSunset(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	SetDeprecationHeaders(w, time.Time{})
+
+	if got := w.Header().Get("Sunset"); got != "" {
+		t.Errorf("want no Sunset header, got: %s", got)
+	}
+}
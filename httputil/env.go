@@ -0,0 +1,13 @@
+package httputil
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// EnvRequested reports whether the caller of "/system/function/{name}" asked for the
+// function's environment with the "env" query string parameter, i.e. "?env=true".
+func EnvRequested(r *http.Request) bool {
+	requested, _ := strconv.ParseBool(r.URL.Query().Get("env"))
+	return requested
+}
@@ -0,0 +1,27 @@
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_EnvRequested(t *testing.T) {
+	testCases := []struct {
+		target string
+		want   bool
+	}{
+		{target: "/system/function/figlet", want: false},
+		{target: "/system/function/figlet?env=true", want: true},
+		{target: "/system/function/figlet?env=1", want: true},
+		{target: "/system/function/figlet?env=false", want: false},
+		{target: "/system/function/figlet?env=yes", want: false},
+	}
+
+	for _, tc := range testCases {
+		r := httptest.NewRequest(http.MethodGet, tc.target, nil)
+		if got := EnvRequested(r); got != tc.want {
+			t.Errorf("%s, want: %t, got: %t", tc.target, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package httputil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// DecorateWithETag computes a strong ETag from the body written by next and answers
+// conditional GET requests carrying a matching If-None-Match header with 304 Not Modified.
+//
+// Only successful (200) responses are given an ETag, any other response is passed through
+// to the client unchanged.
+func DecorateWithETag(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		bw := NewBufferedResponseWriter()
+		next.ServeHTTP(bw, r)
+
+		copyHeader(w.Header(), bw.Header())
+
+		if bw.Status() != http.StatusOK {
+			w.WriteHeader(bw.Status())
+			w.Write(bw.Body())
+			return
+		}
+
+		etag := computeETag(bw.Body())
+		w.Header().Set("ETag", etag)
+
+		if matchesETag(r.Header.Get("If-None-Match"), etag) {
+			w.Header().Del("Content-Length")
+			w.Header().Del("Content-Type")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(bw.Body())
+	}
+}
+
+// computeETag returns a quoted, strong ETag for the given body.
+func computeETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// matchesETag reports whether the If-None-Match header value contains etag
+// or the "*" wildcard. Weak validators are compared by their opaque tag.
+func matchesETag(ifNoneMatch string, etag string) bool {
+	if len(ifNoneMatch) == 0 {
+		return false
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+
+		if strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// BufferedResponseWriter holds the status, headers and body written by a handler
+// so that they can be inspected or changed before being sent to the client.
+type BufferedResponseWriter struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+// NewBufferedResponseWriter creates an empty BufferedResponseWriter.
+func NewBufferedResponseWriter() *BufferedResponseWriter {
+	return &BufferedResponseWriter{header: http.Header{}}
+}
+
+func (b *BufferedResponseWriter) Header() http.Header {
+	return b.header
+}
+
+func (b *BufferedResponseWriter) Write(data []byte) (int, error) {
+	if b.statusCode == 0 {
+		b.WriteHeader(http.StatusOK)
+	}
+	return b.body.Write(data)
+}
+
+func (b *BufferedResponseWriter) WriteHeader(code int) {
+	if b.statusCode == 0 {
+		b.statusCode = code
+	}
+}
+
+// Status returns the status code written by the handler, or 200 when none was written.
+func (b *BufferedResponseWriter) Status() int {
+	if b.statusCode == 0 {
+		return http.StatusOK
+	}
+	return b.statusCode
+}
+
+// Body returns the body written by the handler.
+func (b *BufferedResponseWriter) Body() []byte {
+	return b.body.Bytes()
+}
+
+// Flush sends the buffered headers, status and body to w.
+func (b *BufferedResponseWriter) Flush(w http.ResponseWriter) {
+	copyHeader(w.Header(), b.header)
+	w.WriteHeader(b.Status())
+	w.Write(b.body.Bytes())
+}
+
+// copyHeader clones the header values from the source into the destination.
+func copyHeader(destination http.Header, source http.Header) {
+	for k, v := range source {
+		vClone := make([]string, len(v))
+		copy(vClone, v)
+		destination[k] = vClone
+	}
+}
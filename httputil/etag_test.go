@@ -0,0 +1,90 @@
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_DecorateWithETag_SetsETag(t *testing.T) {
+	handler := DecorateWithETag(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name":"figlet"}`))
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/system/function/figlet", nil)
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code, want: %d, got: %d", http.StatusOK, w.Code)
+	}
+
+	want := computeETag([]byte(`{"name":"figlet"}`))
+	if got := w.Header().Get("ETag"); got != want {
+		t.Errorf("ETag, want: %s, got: %s", want, got)
+	}
+
+	if got := w.Body.String(); got != `{"name":"figlet"}` {
+		t.Errorf("body, want: %s, got: %s", `{"name":"figlet"}`, got)
+	}
+}
+
+func Test_DecorateWithETag_NotModified(t *testing.T) {
+	body := []byte(`{"name":"figlet"}`)
+	handler := DecorateWithETag(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	})
+
+	testCases := []struct {
+		name        string
+		ifNoneMatch string
+		wantCode    int
+	}{
+		{name: "matching etag", ifNoneMatch: computeETag(body), wantCode: http.StatusNotModified},
+		{name: "weak matching etag", ifNoneMatch: "W/" + computeETag(body), wantCode: http.StatusNotModified},
+		{name: "wildcard", ifNoneMatch: "*", wantCode: http.StatusNotModified},
+		{name: "one of many", ifNoneMatch: `"abc", ` + computeETag(body), wantCode: http.StatusNotModified},
+		{name: "stale etag", ifNoneMatch: `"abc"`, wantCode: http.StatusOK},
+		{name: "no header", ifNoneMatch: "", wantCode: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/system/function/figlet", nil)
+			if len(tc.ifNoneMatch) > 0 {
+				r.Header.Set("If-None-Match", tc.ifNoneMatch)
+			}
+
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tc.wantCode {
+				t.Fatalf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+
+			if tc.wantCode == http.StatusNotModified && w.Body.Len() > 0 {
+				t.Errorf("want empty body for 304, got: %q", w.Body.String())
+			}
+		})
+	}
+}
+
+func Test_DecorateWithETag_SkipsErrors(t *testing.T) {
+	handler := DecorateWithETag(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/system/function/figlet", nil)
+	r.Header.Set("If-None-Match", "*")
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status code, want: %d, got: %d", http.StatusNotFound, w.Code)
+	}
+
+	if got := w.Header().Get("ETag"); got != "" {
+		t.Errorf("want no ETag on error response, got: %s", got)
+	}
+}
@@ -0,0 +1,62 @@
+package httputil
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// SplitFunctionName splits a function addressed as "name.namespace", i.e.
+// "figlet.openfaas-fn", into its name and namespace. The namespace is empty when the
+// function is addressed by its name alone.
+func SplitFunctionName(name string) (string, string) {
+	if i := strings.LastIndex(name, "."); i > 0 {
+		return name[:i], name[i+1:]
+	}
+	return name, ""
+}
+
+// FunctionFromRequest returns the function a request to the system API addresses, from
+// the "name" path variable, or the "name" query string parameter for routes without one,
+// such as "/system/logs". The namespace is taken from a "name.namespace" name, otherwise
+// from the "namespace" query string parameter. An error is returned when both are given
+// and differ.
+func FunctionFromRequest(r *http.Request) (string, string, error) {
+	name, ok := PathVars(r)["name"]
+	if !ok {
+		name = strings.TrimSpace(r.URL.Query().Get("name"))
+	}
+
+	name, namespace := SplitFunctionName(name)
+	query := NamespaceFromRequest(r)
+	if len(namespace) > 0 && len(query) > 0 && namespace != query {
+		return "", "", fmt.Errorf("namespace %q of the function does not match the namespace %q in the query", namespace, query)
+	}
+	if len(namespace) == 0 {
+		namespace = query
+	}
+
+	return name, namespace, nil
+}
+
+type functionContextKey struct{}
+
+type function struct {
+	name      string
+	namespace string
+}
+
+// WithFunction returns a copy of ctx carrying the name and namespace of the function a
+// request addresses.
+func WithFunction(ctx context.Context, name, namespace string) context.Context {
+	return context.WithValue(ctx, functionContextKey{}, function{name: name, namespace: namespace})
+}
+
+// FunctionFromContext returns the name and namespace carried by ctx, the bool is false
+// when there are none. The provider sets them for the function and system routes which
+// address a function, so that handlers do not parse the name themselves.
+func FunctionFromContext(ctx context.Context) (string, string, bool) {
+	fn, ok := ctx.Value(functionContextKey{}).(function)
+	return fn.name, fn.namespace, ok
+}
@@ -0,0 +1,75 @@
+package httputil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func Test_SplitFunctionName(t *testing.T) {
+	cases := []struct {
+		name, wantFunction, wantNamespace string
+	}{
+		{"figlet", "figlet", ""},
+		{"figlet.openfaas-fn", "figlet", "openfaas-fn"},
+		{"nodeinfo.v2.dev", "nodeinfo.v2", "dev"},
+		{".hidden", ".hidden", ""},
+	}
+
+	for _, tc := range cases {
+		function, namespace := SplitFunctionName(tc.name)
+		if function != tc.wantFunction || namespace != tc.wantNamespace {
+			t.Errorf("%s, want: %s %s, got: %s %s", tc.name, tc.wantFunction, tc.wantNamespace, function, namespace)
+		}
+	}
+}
+
+func Test_FunctionFromRequest(t *testing.T) {
+	cases := []struct {
+		name          string
+		url           string
+		vars          map[string]string
+		wantFunction  string
+		wantNamespace string
+		wantErr       bool
+	}{
+		{name: "path", url: "/system/function/figlet", vars: map[string]string{"name": "figlet"}, wantFunction: "figlet"},
+		{name: "path with namespace", url: "/system/function/figlet.dev", vars: map[string]string{"name": "figlet.dev"}, wantFunction: "figlet", wantNamespace: "dev"},
+		{name: "query namespace", url: "/system/function/figlet?namespace=dev", vars: map[string]string{"name": "figlet"}, wantFunction: "figlet", wantNamespace: "dev"},
+		{name: "same namespaces", url: "/system/function/figlet.dev?namespace=dev", vars: map[string]string{"name": "figlet.dev"}, wantFunction: "figlet", wantNamespace: "dev"},
+		{name: "different namespaces", url: "/system/function/figlet.dev?namespace=prod", vars: map[string]string{"name": "figlet.dev"}, wantErr: true},
+		{name: "query name", url: "/system/logs?name=figlet.dev", wantFunction: "figlet", wantNamespace: "dev"},
+		{name: "namespace only", url: "/system/functions?namespace=dev", wantNamespace: "dev"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			if tc.vars != nil {
+				r = mux.SetURLVars(r, tc.vars)
+			}
+
+			function, namespace, err := FunctionFromRequest(r)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error, want: %t, got: %v", tc.wantErr, err)
+			}
+			if function != tc.wantFunction || namespace != tc.wantNamespace {
+				t.Errorf("want: %s %s, got: %s %s", tc.wantFunction, tc.wantNamespace, function, namespace)
+			}
+		})
+	}
+}
+
+func Test_FunctionFromContext(t *testing.T) {
+	if _, _, ok := FunctionFromContext(context.Background()); ok {
+		t.Errorf("want no function in an empty context")
+	}
+
+	name, namespace, ok := FunctionFromContext(WithFunction(context.Background(), "figlet", "dev"))
+	if !ok || name != "figlet" || namespace != "dev" {
+		t.Errorf("want: figlet dev true, got: %s %s %t", name, namespace, ok)
+	}
+}
@@ -0,0 +1,24 @@
+package httputil
+
+import (
+	"net/http"
+	"strings"
+)
+
+// InstanceHeader is set on an invocation to have it served by one instance of the
+// function, by its ID as returned by the FunctionInstances handler, i.e. to debug an
+// instance. Invocations without it may be served by any instance.
+const InstanceHeader = "X-Instance-Id"
+
+// InstanceFromRequest returns the ID of the instance an invocation is addressed to with
+// the InstanceHeader, or an empty string when any instance may serve it.
+func InstanceFromRequest(r *http.Request) string {
+	return strings.TrimSpace(r.Header.Get(InstanceHeader))
+}
+
+// InstanceFromQuery returns the ID of the instance given in the "instance" query string
+// parameter of a request to the system API, such as "/system/metrics", or an empty string
+// when the request is not scoped to an instance.
+func InstanceFromQuery(r *http.Request) string {
+	return strings.TrimSpace(r.URL.Query().Get("instance"))
+}
@@ -0,0 +1,46 @@
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_InstanceFromRequest(t *testing.T) {
+	testCases := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: ""},
+		{header: "figlet-1", want: "figlet-1"},
+		{header: " figlet-1 ", want: "figlet-1"},
+	}
+
+	for _, tc := range testCases {
+		r := httptest.NewRequest(http.MethodPost, "/function/figlet?instance=ignored", nil)
+		if len(tc.header) > 0 {
+			r.Header.Set(InstanceHeader, tc.header)
+		}
+		if got := InstanceFromRequest(r); got != tc.want {
+			t.Errorf("%q: want instance %q, got %q", tc.header, tc.want, got)
+		}
+	}
+}
+
+func Test_InstanceFromQuery(t *testing.T) {
+	testCases := []struct {
+		url  string
+		want string
+	}{
+		{url: "/system/metrics", want: ""},
+		{url: "/system/metrics?instance=figlet-1", want: "figlet-1"},
+		{url: "/system/metrics?instance=%20figlet-1%20", want: "figlet-1"},
+	}
+
+	for _, tc := range testCases {
+		r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+		if got := InstanceFromQuery(r); got != tc.want {
+			t.Errorf("%s: want instance %q, got %q", tc.url, tc.want, got)
+		}
+	}
+}
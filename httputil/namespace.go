@@ -0,0 +1,94 @@
+package httputil
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// AllNamespaces is the value of the "namespace" query string parameter which asks for
+// functions in every namespace the caller is allowed to see.
+const AllNamespaces = "*"
+
+// NamespaceFromRequest returns the namespace given in the "namespace" query string
+// parameter, or an empty string when the request is not scoped to a namespace.
+func NamespaceFromRequest(r *http.Request) string {
+	return strings.TrimSpace(r.URL.Query().Get("namespace"))
+}
+
+// AllNamespacesRequested reports whether the caller asked for functions across every
+// namespace, with "namespace=*" or "allNamespaces=true". Providers should then return the
+// functions of every namespace, with the Namespace of each set, and the list is filtered
+// down to the allowed namespaces by the bootstrap package.
+func AllNamespacesRequested(r *http.Request) bool {
+	if NamespaceFromRequest(r) == AllNamespaces {
+		return true
+	}
+
+	all, _ := strconv.ParseBool(r.URL.Query().Get("allNamespaces"))
+	return all
+}
+
+// NamespaceAllowed reports whether namespace is present in allowed. An empty
+// allowed list permits every namespace.
+func NamespaceAllowed(namespace string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	for _, a := range allowed {
+		if a == namespace {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DecorateWithNamespaceAllowlist rejects requests scoped to a namespace which is not
+// in allowed with a 403. The namespace is that of the function carried by the context, see
+// FunctionFromContext, so that a "name.namespace" path is checked as well, otherwise the
+// "namespace" query string parameter.
+//
+// A request without a namespace would address every namespace, i.e. an empty KillRequest.
+// When allowed lists a single namespace the request is scoped to it, in the query string
+// and in the function on the context, otherwise it is rejected with a 403. When allowed is
+// empty every request is passed to next unchanged.
+func DecorateWithNamespaceAllowlist(next http.HandlerFunc, allowed []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(allowed) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		namespace := NamespaceFromRequest(r)
+		name, ns, hasFunction := FunctionFromContext(r.Context())
+		if hasFunction {
+			namespace = ns
+		}
+
+		if len(namespace) == 0 {
+			if len(allowed) > 1 {
+				WriteErrorCode(w, r, http.StatusForbidden, NamespaceForbidden, "a namespace is required, one of: "+strings.Join(allowed, ", "))
+				return
+			}
+
+			namespace = allowed[0]
+			query := r.URL.Query()
+			query.Set("namespace", namespace)
+
+			r = r.Clone(r.Context())
+			r.URL.RawQuery = query.Encode()
+			if hasFunction {
+				r = r.WithContext(WithFunction(r.Context(), name, namespace))
+			}
+		}
+
+		if !NamespaceAllowed(namespace, allowed) {
+			WriteErrorCode(w, r, http.StatusForbidden, NamespaceForbidden, "namespace "+namespace+" is not allowed")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,93 @@
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_NamespaceFromRequest(t *testing.T) {
+	testCases := []struct {
+		url  string
+		want string
+	}{
+		{url: "/danger/kill", want: ""},
+		{url: "/danger/kill?namespace=openfaas-fn", want: "openfaas-fn"},
+		{url: "/danger/kill?namespace=%20dev%20", want: "dev"},
+	}
+
+	for _, tc := range testCases {
+		r := httptest.NewRequest(http.MethodPost, tc.url, nil)
+		if got := NamespaceFromRequest(r); got != tc.want {
+			t.Errorf("%s: want namespace %q, got %q", tc.url, tc.want, got)
+		}
+	}
+}
+
+func Test_DecorateWithNamespaceAllowlist(t *testing.T) {
+	allowed := []string{"openfaas-fn", "dev"}
+
+	testCases := []struct {
+		name          string
+		url           string
+		allowed       []string
+		function      bool
+		wantCode      int
+		wantNamespace string
+	}{
+		{name: "unscoped request", url: "/danger/kill", allowed: allowed, wantCode: http.StatusForbidden},
+		{name: "unscoped request with one allowed namespace", url: "/danger/kill", allowed: []string{"dev"}, wantCode: http.StatusOK, wantNamespace: "dev"},
+		{name: "unscoped function with one allowed namespace", url: "/system/cordon/figlet", allowed: []string{"dev"}, function: true, wantCode: http.StatusOK, wantNamespace: "dev"},
+		{name: "allowed namespace", url: "/danger/kill?namespace=dev", allowed: allowed, wantCode: http.StatusOK, wantNamespace: "dev"},
+		{name: "forbidden namespace", url: "/danger/kill?namespace=kube-system", allowed: allowed, wantCode: http.StatusForbidden},
+		{name: "no allowlist", url: "/danger/kill?namespace=kube-system", allowed: nil, wantCode: http.StatusOK, wantNamespace: "kube-system"},
+		{name: "unscoped request without an allowlist", url: "/danger/kill", allowed: nil, wantCode: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := DecorateWithNamespaceAllowlist(func(w http.ResponseWriter, r *http.Request) {
+				if got := NamespaceFromRequest(r); got != tc.wantNamespace {
+					t.Errorf("namespace in the query, want: %q, got: %q", tc.wantNamespace, got)
+				}
+				if _, got, ok := FunctionFromContext(r.Context()); ok && got != tc.wantNamespace {
+					t.Errorf("namespace of the function, want: %q, got: %q", tc.wantNamespace, got)
+				}
+				w.WriteHeader(http.StatusOK)
+			}, tc.allowed)
+
+			r := httptest.NewRequest(http.MethodPost, tc.url, nil)
+			if tc.function {
+				r = r.WithContext(WithFunction(r.Context(), "figlet", ""))
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+		})
+	}
+}
+
+func Test_AllNamespacesRequested(t *testing.T) {
+	testCases := []struct {
+		target string
+		want   bool
+	}{
+		{target: "/system/functions", want: false},
+		{target: "/system/functions?namespace=openfaas-fn", want: false},
+		{target: "/system/functions?namespace=*", want: true},
+		{target: "/system/functions?namespace=%2A", want: true},
+		{target: "/system/functions?allNamespaces=true", want: true},
+		{target: "/system/functions?allNamespaces=false", want: false},
+	}
+
+	for _, tc := range testCases {
+		r := httptest.NewRequest(http.MethodGet, tc.target, nil)
+		if got := AllNamespacesRequested(r); got != tc.want {
+			t.Errorf("%s, want: %t, got: %t", tc.target, tc.want, got)
+		}
+	}
+}
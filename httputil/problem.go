@@ -0,0 +1,126 @@
+package httputil
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/openfaas/faas-provider/logging"
+)
+
+// ProblemContentType is the media type of a Problem, see RFC 7807.
+const ProblemContentType = "application/problem+json"
+
+// ErrorCode identifies the kind of an error independently of its status code and message,
+// so that clients can act on it without parsing the message.
+type ErrorCode string
+
+const (
+	// FunctionNotFound is returned when the function addressed by a request does not exist.
+	FunctionNotFound ErrorCode = "FunctionNotFound"
+	// NamespaceForbidden is returned when the namespace addressed by a request may not be
+	// used by the caller or the provider.
+	NamespaceForbidden ErrorCode = "NamespaceForbidden"
+	// CheckpointMissing is returned when the checkpoint addressed by a request does not
+	// exist.
+	CheckpointMissing ErrorCode = "CheckpointMissing"
+	// Unauthorized is returned when the credentials of a request are missing or invalid.
+	Unauthorized ErrorCode = "Unauthorized"
+	// NotImplemented is returned for an optional route which the provider does not
+	// implement.
+	NotImplemented ErrorCode = "NotImplemented"
+	// InternalError is returned when the provider fails to serve a request.
+	InternalError ErrorCode = "InternalError"
+	// InvalidRequest is returned when a request is not valid, such as a malformed header or
+	// body, with the invalid fields of the body in "errors".
+	InvalidRequest ErrorCode = "InvalidRequest"
+	// RequestTooLarge is returned when the body of a request is larger than the provider
+	// accepts.
+	RequestTooLarge ErrorCode = "RequestTooLarge"
+	// UnsupportedMediaType is returned when the Content-Type or Content-Encoding of a
+	// request is not accepted.
+	UnsupportedMediaType ErrorCode = "UnsupportedMediaType"
+	// TooManyRequests is returned when a limit on concurrent requests is reached, the
+	// request can be retried after the Retry-After header.
+	TooManyRequests ErrorCode = "TooManyRequests"
+	// ReadOnly is returned for a request which would change state while the provider is in
+	// read-only mode.
+	ReadOnly ErrorCode = "ReadOnly"
+	// NotReady is returned while the provider is starting up or shutting down.
+	NotReady ErrorCode = "NotReady"
+	// Timeout is returned when a request does not complete within its deadline.
+	Timeout ErrorCode = "Timeout"
+	// GatewayRequired is returned for a request to the system API which did not come
+	// through the gateway.
+	GatewayRequired ErrorCode = "GatewayRequired"
+	// AdmissionDenied is returned when a deployment is rejected by an admission check.
+	AdmissionDenied ErrorCode = "AdmissionDenied"
+	// ConfirmationRequired is returned when a destructive request is missing its
+	// confirmation token, or the token does not match.
+	ConfirmationRequired ErrorCode = "ConfirmationRequired"
+	// SecretNotFound is returned when the secret addressed by a request does not exist.
+	SecretNotFound ErrorCode = "SecretNotFound"
+	// SecretExists is returned when a secret is created with the name of one which exists.
+	SecretExists ErrorCode = "SecretExists"
+	// IdempotencyKeyReused is returned when an idempotency key is sent again with a
+	// different request.
+	IdempotencyKeyReused ErrorCode = "IdempotencyKeyReused"
+	// IdempotencyKeyInProgress is returned when a request with the same idempotency key has
+	// not completed yet.
+	IdempotencyKeyInProgress ErrorCode = "IdempotencyKeyInProgress"
+)
+
+// Problem is an error response as defined by RFC 7807, written by WriteErrorCode to
+// clients which accept ProblemContentType. Type is always "about:blank", so Title is the
+// text of the status code, and the kind of error is given by Code:
+//
+//	{"type": "about:blank", "title": "Not Found", "status": 404, "detail": "function figlet not found", "code": "FunctionNotFound"}
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Code is an extension member with the ErrorCode of the problem.
+	Code ErrorCode `json:"code,omitempty"`
+	// RequestID is an extension member with the ID of the request, see logging.Middleware.
+	RequestID string `json:"requestId,omitempty"`
+	// Errors is an extension member with the individual errors behind Detail, see
+	// WriteErrorDetails.
+	Errors interface{} `json:"errors,omitempty"`
+}
+
+// AcceptsProblem reports whether the client of r accepts ProblemContentType.
+func AcceptsProblem(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(accept); err == nil && mediaType == ProblemContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteProblem writes a Problem with statusCode, code and detail, carrying the path and
+// ID of the request r.
+func WriteProblem(w http.ResponseWriter, r *http.Request, statusCode int, code ErrorCode, detail string) {
+	writeProblem(w, r, statusCode, code, detail, nil)
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, statusCode int, code ErrorCode, detail string, errors interface{}) {
+	body, _ := json.Marshal(Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(statusCode),
+		Status:    statusCode,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: logging.RequestIDFromContext(r.Context()),
+		Errors:    errors,
+	})
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(statusCode)
+	w.Write(append(body, '\n'))
+}
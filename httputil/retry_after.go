@@ -0,0 +1,22 @@
+package httputil
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryAfterSeconds rounds d up to whole seconds for a Retry-After header, at least one so
+// that clients do not retry straight away.
+func RetryAfterSeconds(d time.Duration) int {
+	seconds := int((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+// SetRetryAfter sets the Retry-After header of w to d, rounded up to whole seconds.
+func SetRetryAfter(w http.ResponseWriter, d time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(RetryAfterSeconds(d)))
+}
@@ -0,0 +1,38 @@
+package httputil
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_RetryAfterSeconds(t *testing.T) {
+	testCases := []struct {
+		name string
+		d    time.Duration
+		want int
+	}{
+		{name: "zero", d: 0, want: 1},
+		{name: "negative", d: -time.Second, want: 1},
+		{name: "under a second", d: 100 * time.Millisecond, want: 1},
+		{name: "whole seconds", d: 3 * time.Second, want: 3},
+		{name: "rounded up", d: 3*time.Second + time.Millisecond, want: 4},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := RetryAfterSeconds(tc.d); got != tc.want {
+				t.Errorf("seconds, want: %d, got: %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_SetRetryAfter(t *testing.T) {
+	w := httptest.NewRecorder()
+	SetRetryAfter(w, 1500*time.Millisecond)
+
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After, want: %q, got: %q", "2", got)
+	}
+}
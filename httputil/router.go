@@ -0,0 +1,128 @@
+package httputil
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// Router binds the handlers of the provider API to their routes. Paths are templates in
+// which "{name}" and "{name:pattern}" are path variables, as used by gorilla/mux and chi,
+// i.e. "/system/function/{name:[-a-zA-Z_0-9.]+}". Handlers and middleware read the
+// variables with PathVar, so a router other than gorilla/mux, such as chi or
+// http.ServeMux, can be used by adapting it to this interface and calling WithRoute for
+// each request it matches, before the middleware runs.
+type Router interface {
+	http.Handler
+
+	// Handle binds handler to path for the given methods, or for every method when none
+	// are given.
+	Handle(path string, handler http.Handler, methods ...string)
+
+	// HandlePrefix binds handler to every path which starts with prefix for the given
+	// methods, or for every method when none are given.
+	HandlePrefix(prefix string, handler http.Handler, methods ...string)
+
+	// Use appends middleware which is run, in the order added, for every request which
+	// matches a route.
+	Use(middleware ...func(http.Handler) http.Handler)
+
+	// NotFound sets the handler for requests which match no route.
+	NotFound(handler http.Handler)
+
+	// MethodNotAllowed sets the handler for requests which match the path of a route, but
+	// not its methods.
+	MethodNotAllowed(handler http.Handler)
+}
+
+// MuxRouter is the default Router, backed by gorilla/mux.
+type MuxRouter struct {
+	router *mux.Router
+}
+
+// NewMuxRouter creates a Router backed by a new mux.Router.
+func NewMuxRouter() *MuxRouter {
+	return &MuxRouter{router: mux.NewRouter()}
+}
+
+// Mux returns the underlying mux.Router, for when a route needs features of gorilla/mux
+// which Router does not offer, such as host or header matchers.
+func (m *MuxRouter) Mux() *mux.Router {
+	return m.router
+}
+
+func (m *MuxRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.router.ServeHTTP(w, r)
+}
+
+func (m *MuxRouter) Handle(path string, handler http.Handler, methods ...string) {
+	route := m.router.Handle(path, handler)
+	if len(methods) > 0 {
+		route.Methods(methods...)
+	}
+}
+
+func (m *MuxRouter) HandlePrefix(prefix string, handler http.Handler, methods ...string) {
+	route := m.router.PathPrefix(prefix).Handler(handler)
+	if len(methods) > 0 {
+		route.Methods(methods...)
+	}
+}
+
+func (m *MuxRouter) Use(middleware ...func(http.Handler) http.Handler) {
+	for _, mw := range middleware {
+		m.router.Use(mw)
+	}
+}
+
+func (m *MuxRouter) NotFound(handler http.Handler) {
+	m.router.NotFoundHandler = handler
+}
+
+func (m *MuxRouter) MethodNotAllowed(handler http.Handler) {
+	m.router.MethodNotAllowedHandler = handler
+}
+
+type routeContextKey struct{}
+
+type route struct {
+	template string
+	vars     map[string]string
+}
+
+// WithRoute returns a shallow copy of r carrying the path template of the route which
+// matched it and its path variables, for Router implementations other than MuxRouter.
+func WithRoute(r *http.Request, template string, vars map[string]string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), routeContextKey{}, route{template: template, vars: vars}))
+}
+
+// PathVars returns the path variables of the route which matched r, or nil when there are
+// none.
+func PathVars(r *http.Request) map[string]string {
+	if rt, ok := r.Context().Value(routeContextKey{}).(route); ok {
+		return rt.vars
+	}
+	return mux.Vars(r)
+}
+
+// PathVar returns the path variable key of the route which matched r, i.e. "name" for
+// "/function/{name}", or an empty string when it is not set.
+func PathVar(r *http.Request, key string) string {
+	return PathVars(r)[key]
+}
+
+// RouteTemplate returns the path template of the route which matched r, i.e.
+// "/system/function/{name:[-a-zA-Z_0-9.]+}", or an empty string when no route matched.
+func RouteTemplate(r *http.Request) string {
+	if rt, ok := r.Context().Value(routeContextKey{}).(route); ok {
+		return rt.template
+	}
+
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return ""
+}
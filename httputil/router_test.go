@@ -0,0 +1,119 @@
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_MuxRouter(t *testing.T) {
+	var gotName, gotParams, gotTemplate string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotName, gotParams, gotTemplate = PathVar(r, "name"), PathVar(r, "params"), RouteTemplate(r)
+	})
+
+	router := NewMuxRouter()
+	router.Handle("/function/{name:[-a-zA-Z_0-9.]+}/{params:.*}", handler)
+	router.Handle("/system/function/{name:[-a-zA-Z_0-9.]+}", handler, http.MethodGet)
+	router.HandlePrefix("/ui/", handler, http.MethodGet)
+	router.NotFound(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	var middleware int
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			middleware++
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		wantCode       int
+		wantName       string
+		wantParams     string
+		wantTemplate   string
+		wantMiddleware int
+	}{
+		{
+			name:           "path variables",
+			method:         http.MethodPost,
+			path:           "/function/figlet.dev/a/b",
+			wantCode:       http.StatusOK,
+			wantName:       "figlet.dev",
+			wantParams:     "a/b",
+			wantTemplate:   "/function/{name:[-a-zA-Z_0-9.]+}/{params:.*}",
+			wantMiddleware: 1,
+		},
+		{
+			name:           "method",
+			method:         http.MethodGet,
+			path:           "/system/function/figlet",
+			wantCode:       http.StatusOK,
+			wantName:       "figlet",
+			wantTemplate:   "/system/function/{name:[-a-zA-Z_0-9.]+}",
+			wantMiddleware: 1,
+		},
+		{
+			name:     "method not allowed",
+			method:   http.MethodPost,
+			path:     "/system/function/figlet",
+			wantCode: http.StatusMethodNotAllowed,
+		},
+		{
+			name:           "prefix",
+			method:         http.MethodGet,
+			path:           "/ui/index.html",
+			wantCode:       http.StatusOK,
+			wantTemplate:   "/ui/",
+			wantMiddleware: 1,
+		},
+		{
+			name:     "not found",
+			method:   http.MethodGet,
+			path:     "/system/unknown",
+			wantCode: http.StatusTeapot,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotName, gotParams, gotTemplate, middleware = "", "", "", 0
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+			if gotName != tc.wantName || gotParams != tc.wantParams {
+				t.Errorf("path variables, want: %q %q, got: %q %q", tc.wantName, tc.wantParams, gotName, gotParams)
+			}
+			if gotTemplate != tc.wantTemplate {
+				t.Errorf("template, want: %q, got: %q", tc.wantTemplate, gotTemplate)
+			}
+			if middleware != tc.wantMiddleware {
+				t.Errorf("middleware calls, want: %d, got: %d", tc.wantMiddleware, middleware)
+			}
+		})
+	}
+}
+
+func Test_WithRoute(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/function/figlet", nil)
+	if vars := PathVars(r); len(vars) != 0 {
+		t.Errorf("want no path variables before a route matched, got: %v", vars)
+	}
+
+	r = WithRoute(r, "/function/{name}", map[string]string{"name": "figlet"})
+
+	if got := PathVar(r, "name"); got != "figlet" {
+		t.Errorf("name, want: %s, got: %s", "figlet", got)
+	}
+	if got := RouteTemplate(r); got != "/function/{name}" {
+		t.Errorf("template, want: %s, got: %s", "/function/{name}", got)
+	}
+}
@@ -0,0 +1,57 @@
+package httputil
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// EventStream writes Server-Sent Events to a client, flushing each event as it is sent.
+type EventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// NewEventStream writes the headers of a Server-Sent Events response. An error is returned
+// when w can not be flushed, in which case nothing has been written.
+func NewEventStream(w http.ResponseWriter) (*EventStream, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, fmt.Errorf("streaming is not supported by the response writer")
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &EventStream{w: w, flusher: flusher}, nil
+}
+
+// Send writes data encoded as JSON in an event named event, which may be empty.
+func (s *EventStream) Send(event string, data interface{}) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	if len(event) > 0 {
+		if _, err := fmt.Fprintf(s.w, "event: %s\n", event); err != nil {
+			return err
+		}
+	}
+
+	if _, err := fmt.Fprintf(s.w, "data: %s\n\n", body); err != nil {
+		return err
+	}
+
+	s.flusher.Flush()
+	return nil
+}
+
+// Unwrap returns the ResponseWriter the events are written to, i.e. for
+// http.NewResponseController to extend the write deadline of a long-lived stream.
+func (s *EventStream) Unwrap() http.ResponseWriter {
+	return s.w
+}
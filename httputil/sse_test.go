@@ -0,0 +1,31 @@
+package httputil
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_EventStream(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	stream, err := NewEventStream(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream.Send("created", map[string]string{"name": "figlet"})
+	stream.Send("", map[string]string{"name": "nodeinfo"})
+
+	if got := w.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type, want: %s, got: %s", "text/event-stream", got)
+	}
+
+	want := "event: created\ndata: {\"name\":\"figlet\"}\n\ndata: {\"name\":\"nodeinfo\"}\n\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("body, want: %q, got: %q", want, got)
+	}
+
+	if !w.Flushed {
+		t.Errorf("want each event to be flushed")
+	}
+}
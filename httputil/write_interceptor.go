@@ -7,12 +7,13 @@ import (
 )
 
 func NewHttpWriteInterceptor(w http.ResponseWriter) *HttpWriteInterceptor {
-	return &HttpWriteInterceptor{w, 0}
+	return &HttpWriteInterceptor{ResponseWriter: w}
 }
 
 type HttpWriteInterceptor struct {
 	http.ResponseWriter
-	statusCode int
+	statusCode   int
+	bytesWritten int64
 }
 
 func (c *HttpWriteInterceptor) Status() int {
@@ -22,6 +23,11 @@ func (c *HttpWriteInterceptor) Status() int {
 	return c.statusCode
 }
 
+// BytesWritten returns the number of bytes of the response body written so far.
+func (c *HttpWriteInterceptor) BytesWritten() int64 {
+	return c.bytesWritten
+}
+
 func (c *HttpWriteInterceptor) Header() http.Header {
 	return c.ResponseWriter.Header()
 }
@@ -30,7 +36,9 @@ func (c *HttpWriteInterceptor) Write(data []byte) (int, error) {
 	if c.statusCode == 0 {
 		c.WriteHeader(http.StatusOK)
 	}
-	return c.ResponseWriter.Write(data)
+	n, err := c.ResponseWriter.Write(data)
+	c.bytesWritten += int64(n)
+	return n, err
 }
 
 func (c *HttpWriteInterceptor) WriteHeader(code int) {
@@ -48,6 +56,12 @@ func (c *HttpWriteInterceptor) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	return hj.Hijack()
 }
 
+// Unwrap returns the underlying ResponseWriter, so that http.ResponseController can set
+// deadlines on it.
+func (c *HttpWriteInterceptor) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
 func (c *HttpWriteInterceptor) CloseNotify() <-chan bool {
 	notifier, ok := c.ResponseWriter.(http.CloseNotifier)
 	if ok == false {
@@ -35,3 +35,13 @@ func Test_StatusIsRecorded(t *testing.T) {
 		t.Errorf("got code %d, want %d", gotCode, wantCode)
 	}
 }
+
+func Test_BytesWrittenIsRecorded(t *testing.T) {
+	ww := NewHttpWriteInterceptor(httptest.NewRecorder())
+	ww.Write([]byte("hello "))
+	ww.Write([]byte("world"))
+
+	if got := ww.BytesWritten(); got != 11 {
+		t.Errorf("got bytes written %d, want %d", got, 11)
+	}
+}
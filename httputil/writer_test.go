@@ -58,3 +58,93 @@ func Test_wn(t *testing.T) {
 func writeAccepted(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusAccepted)
 }
+
+func Test_WriteError(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteError(w, httptest.NewRequest(http.MethodGet, "/system/functions", nil), http.StatusNotFound, "not found")
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status code, want: %d, got: %d", http.StatusNotFound, w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type, want: %s, got: %s", "application/json", got)
+	}
+	if got, want := w.Body.String(), "{\"code\":404,\"message\":\"not found\"}\n"; got != want {
+		t.Errorf("body, want: %q, got: %q", want, got)
+	}
+}
+
+func Test_WriteErrorCode(t *testing.T) {
+	testCases := []struct {
+		name            string
+		accept          string
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name: "json", wantContentType: "application/json",
+			wantBody: "{\"code\":404,\"message\":\"function figlet not found\",\"errorCode\":\"FunctionNotFound\"}\n",
+		},
+		{
+			name: "problem", accept: "application/json, application/problem+json; q=0.9", wantContentType: ProblemContentType,
+			wantBody: "{\"type\":\"about:blank\",\"title\":\"Not Found\",\"status\":404,\"detail\":\"function figlet not found\",\"instance\":\"/system/function/figlet\",\"code\":\"FunctionNotFound\"}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/system/function/figlet", nil)
+			if len(tc.accept) > 0 {
+				r.Header.Set("Accept", tc.accept)
+			}
+
+			w := httptest.NewRecorder()
+			WriteErrorCode(w, r, http.StatusNotFound, FunctionNotFound, "function figlet not found")
+
+			if w.Code != http.StatusNotFound {
+				t.Errorf("status code, want: %d, got: %d", http.StatusNotFound, w.Code)
+			}
+			if got := w.Header().Get("Content-Type"); got != tc.wantContentType {
+				t.Errorf("Content-Type, want: %s, got: %s", tc.wantContentType, got)
+			}
+			if got := w.Body.String(); got != tc.wantBody {
+				t.Errorf("body, want: %q, got: %q", tc.wantBody, got)
+			}
+		})
+	}
+}
+
+func Test_WriteErrorDetails(t *testing.T) {
+	errors := []map[string]string{{"field": "service"}}
+
+	testCases := []struct {
+		name     string
+		accept   string
+		wantBody string
+	}{
+		{
+			name:     "json",
+			wantBody: "{\"code\":400,\"message\":\"invalid\",\"errorCode\":\"InvalidRequest\",\"errors\":[{\"field\":\"service\"}]}\n",
+		},
+		{
+			name: "problem", accept: ProblemContentType,
+			wantBody: "{\"type\":\"about:blank\",\"title\":\"Bad Request\",\"status\":400,\"detail\":\"invalid\",\"instance\":\"/system/functions\",\"code\":\"InvalidRequest\",\"errors\":[{\"field\":\"service\"}]}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/system/functions", nil)
+			if len(tc.accept) > 0 {
+				r.Header.Set("Accept", tc.accept)
+			}
+
+			w := httptest.NewRecorder()
+			WriteErrorDetails(w, r, http.StatusBadRequest, InvalidRequest, "invalid", errors)
+
+			if got := w.Body.String(); got != tc.wantBody {
+				t.Errorf("body, want: %q, got: %q", tc.wantBody, got)
+			}
+		})
+	}
+}
@@ -1,12 +1,84 @@
 package httputil
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+
+	"github.com/openfaas/faas-provider/logging"
 )
 
 // Errorf sets the response status code and write formats the provided message as the
 // response body
+//
+// Deprecated: Errorf writes plain text, use WriteError or WriteErrorCode, which write an
+// ErrorResponse or a Problem like the rest of the provider.
 func Errorf(w http.ResponseWriter, statusCode int, msg string, args ...interface{}) {
 	http.Error(w, fmt.Sprintf(msg, args...), statusCode)
 }
+
+// WriteJSON writes v as a JSON body with statusCode.
+func WriteJSON(w http.ResponseWriter, statusCode int, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_, err = w.Write(body)
+	return err
+}
+
+// ErrorResponse is the JSON body of errors written by the provider itself, rather than by a
+// function or the provider's handlers:
+//
+//	{"code": 500, "message": "Internal Server Error", "requestId": "6f1c..."}
+type ErrorResponse struct {
+	// Code is the HTTP status code of the response.
+	Code int `json:"code"`
+	// Message describes the error, without internal detail.
+	Message string `json:"message"`
+	// RequestID is the ID of the request, see logging.Middleware, for the error to be
+	// found in the provider's logs.
+	RequestID string `json:"requestId,omitempty"`
+	// ErrorCode is the kind of error, when it is one of the well-known ErrorCodes.
+	ErrorCode ErrorCode `json:"errorCode,omitempty"`
+	// Errors lists the individual errors behind Message, such as the invalid fields of a
+	// request, see WriteErrorDetails.
+	Errors interface{} `json:"errors,omitempty"`
+}
+
+// WriteError writes an ErrorResponse with statusCode and message, carrying the ID of the
+// request r, or a Problem when the client accepts ProblemContentType.
+func WriteError(w http.ResponseWriter, r *http.Request, statusCode int, message string) {
+	WriteErrorCode(w, r, statusCode, "", message)
+}
+
+// WriteErrorCode is WriteError for an error with a well-known ErrorCode.
+func WriteErrorCode(w http.ResponseWriter, r *http.Request, statusCode int, code ErrorCode, message string) {
+	WriteErrorDetails(w, r, statusCode, code, message, nil)
+}
+
+// WriteErrorDetails is WriteErrorCode for an error made up of several, such as the invalid
+// fields of a request, which are written as JSON in the "errors" member of the
+// ErrorResponse or Problem.
+func WriteErrorDetails(w http.ResponseWriter, r *http.Request, statusCode int, code ErrorCode, message string, errors interface{}) {
+	if AcceptsProblem(r) {
+		writeProblem(w, r, statusCode, code, message, errors)
+		return
+	}
+
+	body, _ := json.Marshal(ErrorResponse{
+		Code:      statusCode,
+		Message:   message,
+		RequestID: logging.RequestIDFromContext(r.Context()),
+		ErrorCode: code,
+		Errors:    errors,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(statusCode)
+	w.Write(append(body, '\n'))
+}
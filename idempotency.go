@@ -0,0 +1,191 @@
+package bootstrap
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/openfaas/faas-provider/httputil"
+)
+
+const (
+	// IdempotencyKeyHeader is set by clients of deploy, register and kill requests to a
+	// unique value per operation, so that a retry of the request is answered with the
+	// response to the first one rather than being applied again.
+	IdempotencyKeyHeader = "X-Idempotency-Key"
+	// IdempotencyReplayedHeader is set on responses which were replayed for a retry.
+	IdempotencyReplayedHeader = "X-Idempotency-Replayed"
+
+	// maxIdempotencyKeyLength is the longest key accepted from a client.
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentResponses caps the number of responses kept, the one which expires
+	// first is forgotten to make room.
+	maxIdempotentResponses = 10000
+)
+
+// idempotentResponse is the response to a request with an idempotency key, done is set
+// once it has been recorded.
+type idempotentResponse struct {
+	fingerprint [sha256.Size]byte
+	done        bool
+	status      int
+	header      http.Header
+	body        []byte
+	expires     time.Time
+}
+
+// idempotencyCache records the responses to requests with an IdempotencyKeyHeader for ttl,
+// it is safe for concurrent use.
+type idempotencyCache struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	responses map[string]*idempotentResponse
+
+	now func() time.Time
+}
+
+func newIdempotencyCache(ttl time.Duration) *idempotencyCache {
+	return &idempotencyCache{
+		ttl:       ttl,
+		responses: map[string]*idempotentResponse{},
+		now:       time.Now,
+	}
+}
+
+// decorate replays the recorded response to a request with the same idempotency key,
+// route and caller, with the IdempotencyReplayedHeader. A key which is reused for a
+// different request is rejected with a 422, and a retry which arrives while the first
+// request is still in progress with a 409. Requests without the header are passed to next.
+//
+// Responses with a 5xx or 429 status are not recorded, so that the request can be retried.
+func (c *idempotencyCache) decorate(next http.HandlerFunc) http.HandlerFunc {
+	if next == nil {
+		return nil
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if len(key) == 0 {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			httputil.WriteErrorCode(w, r, http.StatusBadRequest, httputil.InvalidRequest, fmt.Sprintf("%s must not be longer than %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength))
+			return
+		}
+
+		body, ok := httputil.BufferBody(w, r)
+		if !ok {
+			return
+		}
+
+		// Keys are scoped to the caller and route, so that one caller can not replay the
+		// response to another.
+		id := requestUser(r) + "\x00" + r.URL.Path + "\x00" + key
+		fingerprint := requestFingerprint(r, body)
+
+		res, replay := c.begin(id, fingerprint)
+		switch {
+		case res == nil:
+			httputil.WriteErrorCode(w, r, http.StatusUnprocessableEntity, httputil.IdempotencyKeyReused, IdempotencyKeyHeader+" was already used for a different request")
+			return
+		case replay && !res.done:
+			httputil.WriteErrorCode(w, r, http.StatusConflict, httputil.IdempotencyKeyInProgress, "a request with the same "+IdempotencyKeyHeader+" is in progress")
+			return
+		case replay:
+			for k, v := range res.header {
+				w.Header()[k] = v
+			}
+			w.Header().Set(IdempotencyReplayedHeader, "true")
+			w.WriteHeader(res.status)
+			w.Write(res.body)
+			return
+		}
+
+		bw := httputil.NewBufferedResponseWriter()
+		next(bw, r)
+		c.complete(id, res, bw)
+		bw.Flush(w)
+	}
+}
+
+// begin returns a copy of the recorded response for id and true, or a new entry for the
+// request and false. nil is returned when id was recorded for a different request.
+func (c *idempotencyCache) begin(id string, fingerprint [sha256.Size]byte) (*idempotentResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if res, ok := c.responses[id]; ok && now.Before(res.expires) {
+		if res.fingerprint != fingerprint {
+			return nil, true
+		}
+		replay := *res
+		return &replay, true
+	}
+
+	if len(c.responses) >= maxIdempotentResponses {
+		c.evict(now)
+	}
+
+	res := &idempotentResponse{fingerprint: fingerprint, expires: now.Add(c.ttl)}
+	c.responses[id] = res
+	return res, false
+}
+
+// complete records the response written to bw, or forgets the request when it may be
+// retried.
+func (c *idempotencyCache) complete(id string, res *idempotentResponse, bw *httputil.BufferedResponseWriter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	status := bw.Status()
+	if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+		if c.responses[id] == res {
+			delete(c.responses, id)
+		}
+		return
+	}
+
+	res.status = status
+	res.header = bw.Header().Clone()
+	res.body = bytes.Clone(bw.Body())
+	res.expires = c.now().Add(c.ttl)
+	res.done = true
+}
+
+// evict forgets the expired responses, or the one which expires first when none has.
+func (c *idempotencyCache) evict(now time.Time) {
+	var oldestID string
+	var oldest time.Time
+	for id, res := range c.responses {
+		if !now.Before(res.expires) {
+			delete(c.responses, id)
+			continue
+		}
+		if len(oldestID) == 0 || res.expires.Before(oldest) {
+			oldestID, oldest = id, res.expires
+		}
+	}
+
+	if len(c.responses) >= maxIdempotentResponses {
+		delete(c.responses, oldestID)
+	}
+}
+
+// requestFingerprint identifies the method, query and body of a request, so that a key
+// which is reused for a different request is detected.
+func requestFingerprint(r *http.Request, body []byte) [sha256.Size]byte {
+	h := sha256.New()
+	io.WriteString(h, r.Method+"\x00"+r.URL.RawQuery+"\x00")
+	h.Write(body)
+
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
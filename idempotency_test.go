@@ -0,0 +1,193 @@
+package bootstrap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-provider/auth"
+)
+
+func Test_idempotencyCache_decorate(t *testing.T) {
+	type request struct {
+		key       string
+		user      string
+		principal string
+		body      string
+	}
+
+	testCases := []struct {
+		name         string
+		status       int
+		first        request
+		retry        request
+		advance      time.Duration
+		wantCode     int
+		wantCalls    int32
+		wantReplayed bool
+	}{
+		{
+			name:         "retry is replayed",
+			status:       http.StatusAccepted,
+			first:        request{key: "deploy-1", body: `{"service":"figlet"}`},
+			retry:        request{key: "deploy-1", body: `{"service":"figlet"}`},
+			wantCode:     http.StatusAccepted,
+			wantCalls:    1,
+			wantReplayed: true,
+		},
+		{
+			name:         "client errors are replayed",
+			status:       http.StatusBadRequest,
+			first:        request{key: "deploy-1", body: `{}`},
+			retry:        request{key: "deploy-1", body: `{}`},
+			wantCode:     http.StatusBadRequest,
+			wantCalls:    1,
+			wantReplayed: true,
+		},
+		{
+			name:      "key reused for a different request",
+			status:    http.StatusAccepted,
+			first:     request{key: "deploy-1", body: `{"service":"figlet"}`},
+			retry:     request{key: "deploy-1", body: `{"service":"env"}`},
+			wantCode:  http.StatusUnprocessableEntity,
+			wantCalls: 1,
+		},
+		{
+			name:      "keys are scoped to the caller",
+			status:    http.StatusAccepted,
+			first:     request{key: "deploy-1", user: "alice", body: `{}`},
+			retry:     request{key: "deploy-1", user: "bob", body: `{}`},
+			wantCode:  http.StatusAccepted,
+			wantCalls: 2,
+		},
+		{
+			name:      "keys are scoped to the principal",
+			status:    http.StatusAccepted,
+			first:     request{key: "deploy-1", principal: "alice", body: `{}`},
+			retry:     request{key: "deploy-1", principal: "bob", body: `{}`},
+			wantCode:  http.StatusAccepted,
+			wantCalls: 2,
+		},
+		{
+			name:      "server errors are not recorded",
+			status:    http.StatusInternalServerError,
+			first:     request{key: "deploy-1", body: `{}`},
+			retry:     request{key: "deploy-1", body: `{}`},
+			wantCode:  http.StatusInternalServerError,
+			wantCalls: 2,
+		},
+		{
+			name:      "expired responses are forgotten",
+			status:    http.StatusAccepted,
+			first:     request{key: "deploy-1", body: `{}`},
+			retry:     request{key: "deploy-1", body: `{}`},
+			advance:   2 * time.Minute,
+			wantCode:  http.StatusAccepted,
+			wantCalls: 2,
+		},
+		{
+			name:      "requests without a key are not recorded",
+			status:    http.StatusAccepted,
+			first:     request{body: `{}`},
+			retry:     request{body: `{}`},
+			wantCode:  http.StatusAccepted,
+			wantCalls: 2,
+		},
+		{
+			name:      "key too long",
+			status:    http.StatusAccepted,
+			first:     request{key: "deploy-1", body: `{}`},
+			retry:     request{key: strings.Repeat("k", maxIdempotencyKeyLength+1), body: `{}`},
+			wantCode:  http.StatusBadRequest,
+			wantCalls: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			now := time.Now()
+			cache := newIdempotencyCache(time.Minute)
+			cache.now = func() time.Time { return now }
+
+			var calls int32
+			handler := cache.decorate(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				w.Header().Set("X-Deployed", "figlet")
+				w.WriteHeader(tc.status)
+				w.Write([]byte("done"))
+			})
+
+			send := func(req request) *httptest.ResponseRecorder {
+				r := httptest.NewRequest(http.MethodPost, "/system/functions", strings.NewReader(req.body))
+				if len(req.key) > 0 {
+					r.Header.Set(IdempotencyKeyHeader, req.key)
+				}
+				if len(req.user) > 0 {
+					r.SetBasicAuth(req.user, "secret")
+				}
+				if len(req.principal) > 0 {
+					r = r.WithContext(auth.WithPrincipal(r.Context(), req.principal))
+				}
+				w := httptest.NewRecorder()
+				handler(w, r)
+				return w
+			}
+
+			send(tc.first)
+			now = now.Add(tc.advance)
+			w := send(tc.retry)
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d (%s)", tc.wantCode, w.Code, w.Body.String())
+			}
+			if calls != tc.wantCalls {
+				t.Errorf("calls, want: %d, got: %d", tc.wantCalls, calls)
+			}
+
+			replayed := w.Header().Get(IdempotencyReplayedHeader) == "true"
+			if replayed != tc.wantReplayed {
+				t.Errorf("replayed, want: %v, got: %v", tc.wantReplayed, replayed)
+			}
+			if replayed && (w.Body.String() != "done" || w.Header().Get("X-Deployed") != "figlet") {
+				t.Errorf("want the original body and headers to be replayed, got: %q %v", w.Body.String(), w.Header())
+			}
+		})
+	}
+}
+
+func Test_idempotencyCache_decorate_InProgress(t *testing.T) {
+	cache := newIdempotencyCache(time.Minute)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := cache.decorate(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/danger/kill", nil)
+		r.Header.Set(IdempotencyKeyHeader, "kill-1")
+		return r
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler(httptest.NewRecorder(), newRequest())
+	}()
+	<-started
+
+	w := httptest.NewRecorder()
+	handler(w, newRequest())
+	close(release)
+	<-done
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("status code, want: %d, got: %d", http.StatusConflict, w.Code)
+	}
+}
@@ -0,0 +1,40 @@
+package bootstrap
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// inFlightGauge records the number of requests currently being served by the API.
+var inFlightGauge = promauto.NewGauge(prometheus.GaugeOpts{
+	Subsystem: "provider",
+	Name:      "http_requests_in_flight",
+	Help:      "Number of requests to the API currently being served.",
+})
+
+// inFlight counts the requests being served, so that the number still draining can be
+// seen during shutdown.
+type inFlight struct {
+	count atomic.Int64
+	gauge prometheus.Gauge
+}
+
+func newInFlight(gauge prometheus.Gauge) *inFlight {
+	return &inFlight{gauge: gauge}
+}
+
+func (f *inFlight) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.count.Add(1)
+		f.gauge.Inc()
+		defer func() {
+			f.count.Add(-1)
+			f.gauge.Dec()
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
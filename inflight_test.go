@@ -0,0 +1,106 @@
+package bootstrap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-provider/types"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func Test_inFlight(t *testing.T) {
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_requests_in_flight"})
+	f := newInFlight(gauge)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := f.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/function/figlet", nil))
+		close(done)
+	}()
+	<-started
+
+	m := &dto.Metric{}
+	gauge.Write(m)
+	if got := m.GetGauge().GetValue(); got != 1 {
+		t.Errorf("gauge while serving, want: %d, got: %v", 1, got)
+	}
+	if got := f.count.Load(); got != 1 {
+		t.Errorf("count while serving, want: %d, got: %d", 1, got)
+	}
+
+	close(release)
+	<-done
+
+	gauge.Write(m)
+	if got := m.GetGauge().GetValue(); got != 0 {
+		t.Errorf("gauge after serving, want: %d, got: %v", 0, got)
+	}
+}
+
+func Test_Server_NotReadyWhileDraining(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	var s *Server
+	var readyCode int
+	var inFlight int64
+
+	config := &types.FaaSConfig{
+		ListenAddress: types.UnixSocketScheme + filepath.Join(t.TempDir(), "provider.sock"),
+		PreShutdownHooks: []func(context.Context) error{
+			func(ctx context.Context) error {
+				w := httptest.NewRecorder()
+				s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+				readyCode = w.Code
+				inFlight = s.inFlight.count.Load()
+				close(release)
+				return nil
+			},
+		},
+	}
+
+	s = NewServer(config)
+	handlers := validHandlers()
+	handlers.FunctionProxy = func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}
+	s.Handlers(handlers)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Serve(ctx)
+	}()
+
+	go s.Router().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/function/figlet", nil))
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("want the request to start")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("want no error after cancelling the context, got: %s", err)
+	}
+
+	if readyCode != http.StatusServiceUnavailable {
+		t.Errorf("/readyz while draining, want: %d, got: %d", http.StatusServiceUnavailable, readyCode)
+	}
+	if inFlight != 1 {
+		t.Errorf("requests in flight while draining, want: %d, got: %d", 1, inFlight)
+	}
+}
@@ -0,0 +1,63 @@
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/openfaas/faas-provider/events"
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// PublishDrainProgress publishes the stage an instance of a function has reached on the
+// events.Bus of ctx, so that it is streamed from "/system/functions/watch" as a
+// types.WatchDrain. Providers call it for each stage of a drain which follows the
+// DrainStatus they responded with, i.e. from a goroutine started by the DrainFunction
+// handler with context.WithoutCancel(r.Context()). It does nothing without
+// FaaSConfig.Events.
+func PublishDrainProgress(ctx context.Context, function, namespace string, status types.InstanceDrainStatus) {
+	events.Publish(ctx, drainEvent(function, namespace, status))
+}
+
+func drainEvent(function, namespace string, status types.InstanceDrainStatus) events.Event {
+	data := map[string]string{
+		"instance": status.ID,
+		"stage":    string(status.Stage),
+	}
+	if len(status.CheckpointID) > 0 {
+		data["checkpoint"] = status.CheckpointID
+	}
+	if len(status.Error) > 0 {
+		data["error"] = status.Error
+	}
+
+	return events.Event{
+		Type:      events.InstanceDrain,
+		Function:  function,
+		Namespace: namespace,
+		Data:      data,
+	}
+}
+
+// decorateWithDrainEvents publishes events.InstanceDrain on bus for each instance in the
+// DrainStatus written by next, the stages reached by the time it responded.
+func decorateWithDrainEvents(next http.HandlerFunc, bus *events.Bus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bw := httputil.NewBufferedResponseWriter()
+		next(bw, r)
+		bw.Flush(w)
+
+		if status := bw.Status(); status < http.StatusOK || status >= http.StatusMultipleChoices {
+			return
+		}
+
+		res := types.DrainStatus{}
+		if err := json.Unmarshal(bw.Body(), &res); err != nil {
+			return
+		}
+		for _, instance := range res.Instances {
+			bus.Publish(drainEvent(res.Function, res.Namespace, instance))
+		}
+	}
+}
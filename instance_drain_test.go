@@ -0,0 +1,115 @@
+package bootstrap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-provider/events"
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_Server_DrainAndCordon(t *testing.T) {
+	bus := events.NewBus(events.Config{})
+	responded := make(chan struct{})
+
+	handlers := validHandlers()
+	handlers.CordonFunction = func(w http.ResponseWriter, r *http.Request) {
+		req, err := types.DecodeCordonRequest(r.Body)
+		if err != nil {
+			httputil.WriteError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		name, namespace, _ := httputil.FunctionFromContext(r.Context())
+		stage := types.DrainCordoned
+		if req.Uncordon {
+			stage = types.DrainUncordoned
+		}
+		status := types.DrainStatus{Function: name, Namespace: namespace}
+		for _, id := range req.Instances {
+			status.Instances = append(status.Instances, types.InstanceDrainStatus{ID: id, Stage: stage})
+		}
+		types.WriteJSON(w, http.StatusOK, status)
+	}
+	handlers.DrainFunction = func(w http.ResponseWriter, r *http.Request) {
+		req, err := types.DecodeDrainRequest(r.Body)
+		if err != nil {
+			httputil.WriteError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		name, namespace, _ := httputil.FunctionFromContext(r.Context())
+		instance := types.InstanceDrainStatus{ID: req.Instances[0], Stage: types.DrainCordoned, InFlight: 2}
+		types.WriteJSON(w, http.StatusAccepted, types.DrainStatus{Function: name, Namespace: namespace, Instances: []types.InstanceDrainStatus{instance}})
+
+		ctx := context.WithoutCancel(r.Context())
+		go func() {
+			// The stages which follow are only published once the response was.
+			<-responded
+			PublishDrainProgress(ctx, name, namespace, types.InstanceDrainStatus{ID: instance.ID, Stage: types.DrainDrained})
+			PublishDrainProgress(ctx, name, namespace, types.InstanceDrainStatus{ID: instance.ID, Stage: types.DrainCheckpointed, CheckpointID: "cp-1"})
+			PublishDrainProgress(ctx, name, namespace, types.InstanceDrainStatus{ID: instance.ID, Stage: types.DrainTerminated})
+		}()
+	}
+
+	s := NewServer(&types.FaaSConfig{Events: bus, AllowedNamespaces: []string{"dev"}})
+	s.Handlers(handlers)
+
+	requests := []struct {
+		path     string
+		body     string
+		wantCode int
+	}{
+		{"/system/cordon/figlet?namespace=dev", `{"instances":["figlet-1"]}`, http.StatusOK},
+		{"/system/drain/figlet?namespace=dev", `{"instances":["figlet-1"],"checkpoint":true}`, http.StatusAccepted},
+		{"/system/drain/figlet?namespace=prod", `{"instances":["figlet-1"]}`, http.StatusForbidden},
+		{"/system/drain/figlet.prod", `{"instances":["figlet-1"]}`, http.StatusForbidden},
+		{"/system/cordon/figlet.prod", `{"instances":["figlet-1"]}`, http.StatusForbidden},
+		{"/system/cordon/figlet?namespace=dev", `{"instances":[""]}`, http.StatusBadRequest},
+	}
+	for _, req := range requests {
+		rr := httptest.NewRecorder()
+		s.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, req.path, strings.NewReader(req.body)))
+		if rr.Code != req.wantCode {
+			t.Errorf("%s: status code, want: %d, got: %d (%s)", req.path, req.wantCode, rr.Code, rr.Body.String())
+		}
+	}
+	close(responded)
+
+	deadline := time.Now().Add(time.Second)
+	for s.watch.currentVersion() < 5 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	missed, watcher, ok := s.watch.watch(0)
+	if !ok {
+		t.Fatal("want the watch history to be kept")
+	}
+	s.watch.unwatch(watcher)
+
+	want := []types.DrainStage{types.DrainCordoned, types.DrainCordoned, types.DrainDrained, types.DrainCheckpointed, types.DrainTerminated}
+	if len(missed) != len(want) {
+		t.Fatalf("watch events, want: %d, got: %d", len(want), len(missed))
+	}
+	for i, event := range missed {
+		if event.Type != types.WatchDrain || event.Name != "figlet" || event.Namespace != "dev" || event.Instance != "figlet-1" || event.Stage != want[i] {
+			t.Errorf("%d: want figlet-1 to be %s, got: %+v", i, want[i], event)
+		}
+	}
+}
+
+func Test_Server_DrainNotConfigured(t *testing.T) {
+	s := NewServer(&types.FaaSConfig{})
+	s.Handlers(validHandlers())
+
+	rr := httptest.NewRecorder()
+	s.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/system/drain/figlet", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("status code, want: %d, got: %d", http.StatusNotFound, rr.Code)
+	}
+}
@@ -0,0 +1,91 @@
+package bootstrap
+
+import (
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// newInstanceMetricsHandler serves gatherer in the same formats as "/metrics". Only the
+// series of one instance are served when the "instance" query string parameter is given.
+func newInstanceMetricsHandler(gatherer prometheus.Gatherer, timeout time.Duration, logger *slog.Logger) http.HandlerFunc {
+	opts := promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+		ErrorHandling:     promhttp.ContinueOnError,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelError),
+		Timeout:           timeout,
+	}
+	all := promhttp.HandlerFor(gatherer, opts)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if instance := httputil.InstanceFromQuery(r); len(instance) > 0 {
+			promhttp.HandlerFor(instanceGatherer{gatherer: gatherer, instance: instance}, opts).ServeHTTP(w, r)
+			return
+		}
+		all.ServeHTTP(w, r)
+	}
+}
+
+// instanceGatherer gathers the series of one instance, by their "instance" label, leaving
+// out the families which have none.
+type instanceGatherer struct {
+	gatherer prometheus.Gatherer
+	instance string
+}
+
+func (g instanceGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.gatherer.Gather()
+
+	var filtered []*dto.MetricFamily
+	for _, family := range families {
+		var metrics []*dto.Metric
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "instance" && label.GetValue() == g.instance {
+					metrics = append(metrics, metric)
+					break
+				}
+			}
+		}
+
+		if len(metrics) > 0 {
+			filtered = append(filtered, &dto.MetricFamily{Name: family.Name, Help: family.Help, Type: family.Type, Metric: metrics})
+		}
+	}
+
+	return filtered, err
+}
+
+// decorateWithInstanceMetrics sends GET requests which accept a Prometheus exposition format
+// to exposition, and all others to next.
+func decorateWithInstanceMetrics(next, exposition http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && acceptsExposition(r.Header.Get("Accept")) {
+			exposition(w, r)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// acceptsExposition reports whether accept lists the Prometheus text or OpenMetrics format.
+func acceptsExposition(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "text/plain", "application/openmetrics-text":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package bootstrap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-provider/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func Test_InstanceMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	up := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "test_instance_up",
+		Help: "Whether the instance is up.",
+	}, []string{"instance"})
+	up.WithLabelValues("figlet-1").Set(1)
+	up.WithLabelValues("figlet-2").Set(0)
+	registry.MustRegister(up)
+
+	cases := []struct {
+		name           string
+		metricFunction bool
+		method         string
+		accept         string
+		wantStatus     int
+		wantExposition bool
+		query          string
+		wantOthers     bool
+	}{
+		{"exposition without MetricFunction", false, http.MethodGet, "", http.StatusOK, true, "", true},
+		{"DELETE without MetricFunction", false, http.MethodDelete, "", http.StatusMethodNotAllowed, false, "", false},
+		{"JSON with MetricFunction", true, http.MethodGet, "application/json", http.StatusOK, false, "", false},
+		{"text with MetricFunction", true, http.MethodGet, "text/plain;version=0.0.4", http.StatusOK, true, "", true},
+		{"OpenMetrics with MetricFunction", true, http.MethodGet, "application/openmetrics-text; version=1.0.0,*/*;q=0.1", http.StatusOK, true, "", true},
+		{"DELETE with MetricFunction", true, http.MethodDelete, "text/plain", http.StatusOK, false, "", false},
+		{"exposition of one instance", false, http.MethodGet, "", http.StatusOK, true, "?instance=figlet-1", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			handlers := validHandlers()
+			handlers.MetricFunction = nil
+			if tc.metricFunction {
+				handlers.MetricFunction = func(w http.ResponseWriter, r *http.Request) {
+					types.WriteJSON(w, http.StatusOK, []types.InstanceMetrics{})
+				}
+			}
+
+			srv, err := NewHTTPServer(handlers, &types.FaaSConfig{InstanceMetrics: registry})
+			if err != nil {
+				t.Fatalf("want no error, got: %s", err)
+			}
+
+			req := httptest.NewRequest(tc.method, "/system/metrics"+tc.query, nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			w := httptest.NewRecorder()
+			srv.Handler.ServeHTTP(w, req)
+
+			if w.Code != tc.wantStatus {
+				t.Fatalf("status code, want: %d, got: %d", tc.wantStatus, w.Code)
+			}
+			if got := strings.Contains(w.Body.String(), `test_instance_up{instance="figlet-1"} 1`); got != tc.wantExposition {
+				t.Errorf("exposition, want: %v, got: %v (%s)", tc.wantExposition, got, w.Body.String())
+			}
+			if got := strings.Contains(w.Body.String(), `figlet-2`); got != tc.wantOthers {
+				t.Errorf("other instances, want: %v, got: %v (%s)", tc.wantOthers, got, w.Body.String())
+			}
+		})
+	}
+}
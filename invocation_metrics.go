@@ -0,0 +1,214 @@
+package bootstrap
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/scaling"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// otherFunction is the function label of the invocations of functions over the limit of
+// invocationMetrics.
+const otherFunction = "other"
+
+// invocationMetrics records the invocations of each function through "/function/",
+// "/invoke/" and "/async-function/", labelled by function and namespace. To bound the
+// number of series, a function is only given its own series once it has returned a
+// response other than a 404, as the proxy returns a 404 for names which are not deployed,
+// and only up to limit functions. The other invocations are recorded under otherFunction.
+type invocationMetrics struct {
+	invocations *prometheus.CounterVec
+	duration    *prometheus.HistogramVec
+	starts      *prometheus.CounterVec
+	coldStarts  *prometheus.HistogramVec
+	warmPool    *prometheus.CounterVec
+
+	mu    sync.Mutex
+	limit int
+	known map[functionKey]bool
+}
+
+type functionKey struct {
+	function  string
+	namespace string
+}
+
+var (
+	sharedInvocationMetrics     *invocationMetrics
+	sharedInvocationMetricsOnce sync.Once
+)
+
+// defaultInvocationMetrics returns the invocationMetrics shared by Serve and RecordStart,
+// the collectors are registered with the default Prometheus registry on first use.
+func defaultInvocationMetrics() *invocationMetrics {
+	sharedInvocationMetricsOnce.Do(func() {
+		sharedInvocationMetrics = newInvocationMetrics()
+	})
+	return sharedInvocationMetrics
+}
+
+func newInvocationMetrics() *invocationMetrics {
+	return &invocationMetrics{
+		known: map[functionKey]bool{},
+		invocations: promauto.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: "provider",
+			Name:      "function_invocations_total",
+			Help:      "Total number of function invocations.",
+		}, []string{"function", "namespace", "code"}),
+		duration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Subsystem: "provider",
+			Name:      "function_invocation_duration_seconds",
+			Help:      "Seconds spent serving function invocations.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"function", "namespace"}),
+		starts: promauto.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: "provider",
+			Name:      "function_starts_total",
+			Help:      "Total number of function instances started, by whether the start was cold or warm.",
+		}, []string{"function", "namespace", "start"}),
+		coldStarts: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Subsystem: "provider",
+			Name:      "function_cold_start_duration_seconds",
+			Help:      "Seconds spent waking functions scaled to zero before invoking them, by whether they were ready in time.",
+			Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		}, []string{"function", "namespace", "result"}),
+		warmPool: promauto.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: "provider",
+			Name:      "function_warm_pool_requests_total",
+			Help:      "Total number of invocations which looked for a warm instance of a function, by whether one was available.",
+		}, []string{"function", "namespace", "result"}),
+	}
+}
+
+// setLimit sets the number of functions given their own series.
+func (m *invocationMetrics) setLimit(limit int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limit = limit
+}
+
+// labelsFor returns the function and namespace labels for an invocation, admitting the
+// function when admit is true and the limit has not been reached.
+func (m *invocationMetrics) labelsFor(key functionKey, admit bool) (string, string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.known[key] {
+		return key.function, key.namespace
+	}
+	if admit && len(m.known) < m.limit {
+		m.known[key] = true
+		return key.function, key.namespace
+	}
+	return otherFunction, ""
+}
+
+// reset deletes the series of function in namespace, or of every function when function is
+// empty, so that they start from zero. The function no longer counts towards the limit.
+func (m *invocationMetrics) reset(function, namespace string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(function) == 0 {
+		m.invocations.Reset()
+		m.duration.Reset()
+		m.starts.Reset()
+		m.coldStarts.Reset()
+		m.warmPool.Reset()
+		m.known = map[functionKey]bool{}
+		return
+	}
+
+	// The status codes of invocations are not known, the other labels are.
+	m.invocations.DeletePartialMatch(prometheus.Labels{"function": function, "namespace": namespace})
+	m.duration.DeleteLabelValues(function, namespace)
+	for _, start := range []string{"cold", "warm"} {
+		m.starts.DeleteLabelValues(function, namespace, start)
+	}
+	for _, result := range []string{"ready", "error"} {
+		m.coldStarts.DeleteLabelValues(function, namespace, result)
+	}
+	for _, result := range []string{"hit", "miss"} {
+		m.warmPool.DeleteLabelValues(function, namespace, result)
+	}
+	delete(m.known, functionKey{function: function, namespace: namespace})
+}
+
+// decorate records the invocations of next, which must be routed with a "name" variable.
+func (m *invocationMetrics) decorate(next http.HandlerFunc) http.HandlerFunc {
+	if next == nil {
+		return nil
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := httputil.NewHttpWriteInterceptor(w)
+		next.ServeHTTP(ww, r)
+		duration := time.Since(start)
+
+		code := ww.Status()
+		function, namespace := m.labelsFor(functionKeyFor(httputil.PathVar(r, "name")), code != http.StatusNotFound)
+
+		m.invocations.WithLabelValues(function, namespace, strconv.Itoa(code)).Inc()
+		m.duration.WithLabelValues(function, namespace).Observe(duration.Seconds())
+	}
+}
+
+// functionKeyFor splits a function name addressed as "name.namespace" into its parts.
+func functionKeyFor(name string) functionKey {
+	function, namespace := httputil.SplitFunctionName(name)
+	return functionKey{function: function, namespace: namespace}
+}
+
+// RecordStart adds a start of an instance of the function to the
+// provider_function_starts_total counter, with the "start" label set to "cold" when the
+// instance was started from scratch, or "warm" when an existing container was switched
+// to the function or it was restored from a checkpoint. The function shares the series
+// limit of the invocation metrics, see FaaSConfig.MaxFunctionMetrics. When the start
+// serves a request, it can also be recorded on the request's span with tracing.AddEvent.
+func RecordStart(function, namespace string, cold bool) {
+	m := defaultInvocationMetrics()
+
+	start := "warm"
+	if cold {
+		start = "cold"
+	}
+
+	function, namespace = m.labelsFor(functionKey{function: function, namespace: namespace}, true)
+	m.starts.WithLabelValues(function, namespace, start).Inc()
+}
+
+// recordColdStart adds the wake up of a function by the scaling package to the
+// provider_function_cold_start_duration_seconds histogram, with the "result" label set to
+// "ready", or "error" when the function was not ready in time.
+func (m *invocationMetrics) recordColdStart(c scaling.ColdStart) {
+	result := "ready"
+	if c.Err != nil {
+		result = "error"
+	}
+
+	function, namespace := m.labelsFor(functionKey{function: c.Function, namespace: c.Namespace}, c.Err == nil)
+	m.coldStarts.WithLabelValues(function, namespace, result).Observe(c.Duration.Seconds())
+}
+
+// RecordWarmPoolHit adds an invocation which looked for an idle instance in the warm pool
+// of the function, see FaaSHandlers.WarmPool, to the provider_function_warm_pool_requests_total
+// counter, with the "result" label set to "hit" when one was available, or "miss" when the
+// invocation had to wait for a cold start. The function shares the series limit of the
+// invocation metrics.
+func RecordWarmPoolHit(function, namespace string, hit bool) {
+	m := defaultInvocationMetrics()
+
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+
+	function, namespace = m.labelsFor(functionKey{function: function, namespace: namespace}, true)
+	m.warmPool.WithLabelValues(function, namespace, result).Inc()
+}
@@ -0,0 +1,166 @@
+package bootstrap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/openfaas/faas-provider/scaling"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// newTestInvocationMetrics creates invocationMetrics with collectors which are not
+// registered, so that each test starts from zero.
+func newTestInvocationMetrics(limit int) *invocationMetrics {
+	return &invocationMetrics{
+		limit: limit,
+		known: map[functionKey]bool{},
+		invocations: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_invocations_total"},
+			[]string{"function", "namespace", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_invocation_duration_seconds"},
+			[]string{"function", "namespace"}),
+		starts: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_starts_total"},
+			[]string{"function", "namespace", "start"}),
+		coldStarts: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_cold_start_duration_seconds"},
+			[]string{"function", "namespace", "result"}),
+		warmPool: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_warm_pool_requests_total"},
+			[]string{"function", "namespace", "result"}),
+	}
+}
+
+func counterValue(t *testing.T, c prometheus.Counter) float64 {
+	t.Helper()
+
+	m := &dto.Metric{}
+	if err := c.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func Test_invocationMetrics_decorate(t *testing.T) {
+	m := newTestInvocationMetrics(2)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/function/{name}", m.decorate(func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["name"] == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, path := range []string{
+		"/function/figlet.openfaas-fn",
+		"/function/figlet.openfaas-fn",
+		"/function/missing",
+		"/function/env",
+		"/function/nodeinfo",
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, path, nil))
+	}
+
+	cases := []struct {
+		function  string
+		namespace string
+		code      string
+		want      float64
+	}{
+		{"figlet", "openfaas-fn", "200", 2},
+		{"env", "", "200", 1},
+		// A 404 does not admit a function, and nodeinfo is over the limit of 2.
+		{otherFunction, "", "404", 1},
+		{otherFunction, "", "200", 1},
+		{"missing", "", "404", 0},
+		{"nodeinfo", "", "200", 0},
+	}
+
+	for _, tc := range cases {
+		got := counterValue(t, m.invocations.WithLabelValues(tc.function, tc.namespace, tc.code))
+		if got != tc.want {
+			t.Errorf("invocations of %s.%s with %s, want: %v, got: %v", tc.function, tc.namespace, tc.code, tc.want, got)
+		}
+	}
+}
+
+func Test_functionKeyFor(t *testing.T) {
+	cases := []struct {
+		name string
+		want functionKey
+	}{
+		{"figlet", functionKey{function: "figlet"}},
+		{"figlet.openfaas-fn", functionKey{function: "figlet", namespace: "openfaas-fn"}},
+		{"v1.2.staging", functionKey{function: "v1.2", namespace: "staging"}},
+	}
+
+	for _, tc := range cases {
+		if got := functionKeyFor(tc.name); got != tc.want {
+			t.Errorf("functionKeyFor(%q), want: %+v, got: %+v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func Test_RecordStart(t *testing.T) {
+	m := defaultInvocationMetrics()
+	m.setLimit(1000)
+
+	// The counters are global, so they are compared with their values before the test, for
+	// the test to pass when it is run more than once.
+	beforeCold := counterValue(t, m.starts.WithLabelValues("record-start-test", "openfaas-fn", "cold"))
+	beforeWarm := counterValue(t, m.starts.WithLabelValues("record-start-test", "openfaas-fn", "warm"))
+	RecordStart("record-start-test", "openfaas-fn", true)
+	RecordStart("record-start-test", "openfaas-fn", false)
+
+	if got := counterValue(t, m.starts.WithLabelValues("record-start-test", "openfaas-fn", "cold")) - beforeCold; got != 1 {
+		t.Errorf("cold starts, want: %d, got: %v", 1, got)
+	}
+	if got := counterValue(t, m.starts.WithLabelValues("record-start-test", "openfaas-fn", "warm")) - beforeWarm; got != 1 {
+		t.Errorf("warm starts, want: %d, got: %v", 1, got)
+	}
+}
+
+func Test_RecordWarmPoolHit(t *testing.T) {
+	m := defaultInvocationMetrics()
+	m.setLimit(1000)
+
+	beforeHits := counterValue(t, m.warmPool.WithLabelValues("warm-pool-test", "openfaas-fn", "hit"))
+	beforeMisses := counterValue(t, m.warmPool.WithLabelValues("warm-pool-test", "openfaas-fn", "miss"))
+	RecordWarmPoolHit("warm-pool-test", "openfaas-fn", true)
+	RecordWarmPoolHit("warm-pool-test", "openfaas-fn", true)
+	RecordWarmPoolHit("warm-pool-test", "openfaas-fn", false)
+
+	if got := counterValue(t, m.warmPool.WithLabelValues("warm-pool-test", "openfaas-fn", "hit")) - beforeHits; got != 2 {
+		t.Errorf("hits, want: %d, got: %v", 2, got)
+	}
+	if got := counterValue(t, m.warmPool.WithLabelValues("warm-pool-test", "openfaas-fn", "miss")) - beforeMisses; got != 1 {
+		t.Errorf("misses, want: %d, got: %v", 1, got)
+	}
+}
+
+func Test_invocationMetrics_recordColdStart(t *testing.T) {
+	m := newTestInvocationMetrics(1)
+
+	m.recordColdStart(scaling.ColdStart{Function: "figlet", Namespace: "openfaas-fn", Duration: 2 * time.Second})
+	m.recordColdStart(scaling.ColdStart{Function: "env", Namespace: "openfaas-fn", Duration: time.Second, Err: scaling.ErrNotReady})
+
+	cases := []struct {
+		function, namespace, result string
+		wantSum                     float64
+	}{
+		{"figlet", "openfaas-fn", "ready", 2},
+		{otherFunction, "", "error", 1},
+	}
+
+	for _, tc := range cases {
+		h := &dto.Metric{}
+		if err := m.coldStarts.WithLabelValues(tc.function, tc.namespace, tc.result).(prometheus.Histogram).Write(h); err != nil {
+			t.Fatal(err)
+		}
+		if got := h.GetHistogram().GetSampleSum(); got != tc.wantSum {
+			t.Errorf("%s %s, want: %v, got: %v", tc.function, tc.result, tc.wantSum, got)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package bootstrap
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+
+	"github.com/openfaas/faas-provider/auth"
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// confirmKillHeader can be used instead of the "confirm" query string parameter to
+// pass the confirmation token to /danger/kill.
+const confirmKillHeader = "X-Confirm-Kill"
+
+// decorateWithKillConfirmation only passes requests to next when they carry the
+// confirmation token, so that /danger/kill can not be triggered by accident.
+func decorateWithKillConfirmation(next http.HandlerFunc, token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		confirm := r.Header.Get(confirmKillHeader)
+		if len(confirm) == 0 {
+			confirm = r.URL.Query().Get("confirm")
+		}
+
+		if len(confirm) == 0 {
+			httputil.WriteErrorCode(w, r, http.StatusBadRequest, httputil.ConfirmationRequired, "a confirmation token is required, set the "+confirmKillHeader+" header or the confirm query parameter")
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(confirm), []byte(token)) != 1 {
+			httputil.WriteErrorCode(w, r, http.StatusForbidden, httputil.ConfirmationRequired, "invalid confirmation token")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// decorateWithKillAudit adds the function and the number of instances killed, read from
+// the KillResponse written by next, to the audit record of the request when
+// FaaSConfig.Audit is set. A failed kill is audited as well.
+func decorateWithKillAudit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		details := auditDetailsFromContext(r.Context())
+		if details == nil {
+			next(w, r)
+			return
+		}
+
+		bw := httputil.NewBufferedResponseWriter()
+		next(bw, r)
+		bw.Flush(w)
+
+		res := types.KillResponse{}
+		if err := json.Unmarshal(bw.Body(), &res); err != nil {
+			return
+		}
+
+		details.function, details.namespace, details.instances = res.Function, res.Namespace, res.Killed
+		if len(details.namespace) == 0 {
+			details.namespace = httputil.NamespaceFromRequest(r)
+		}
+	}
+}
+
+// requestUser returns the principal of r set by the Authenticator, see
+// auth.PrincipalFromContext, the basic auth user of r for an Authenticator which does not
+// set one, or an empty string.
+func requestUser(r *http.Request) string {
+	if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+		return principal
+	}
+	if user, _, ok := r.BasicAuth(); ok {
+		return user
+	}
+	return ""
+}
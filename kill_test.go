@@ -0,0 +1,189 @@
+package bootstrap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-provider/audit"
+	"github.com/openfaas/faas-provider/auth"
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_decorateWithKillConfirmation(t *testing.T) {
+	testCases := []struct {
+		name     string
+		token    string
+		url      string
+		header   string
+		wantCode int
+	}{
+		{name: "missing confirmation", token: "s3cr3t", url: "/danger/kill", wantCode: http.StatusBadRequest},
+		{name: "query parameter", token: "s3cr3t", url: "/danger/kill?confirm=s3cr3t", wantCode: http.StatusOK},
+		{name: "header", token: "s3cr3t", url: "/danger/kill", header: "s3cr3t", wantCode: http.StatusOK},
+		{name: "wrong token", token: "s3cr3t", url: "/danger/kill?confirm=guess", wantCode: http.StatusForbidden},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			called := false
+			handler := decorateWithKillConfirmation(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			}, tc.token)
+
+			r := httptest.NewRequest(http.MethodPost, tc.url, nil)
+			if len(tc.header) > 0 {
+				r.Header.Set(confirmKillHeader, tc.header)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+
+			if wantCalled := tc.wantCode == http.StatusOK; called != wantCalled {
+				t.Errorf("handler called, want: %t, got: %t", wantCalled, called)
+			}
+		})
+	}
+}
+
+func Test_Server_KillAudit(t *testing.T) {
+	var got []audit.Record
+	trail := audit.NewTrail(audit.Config{Sinks: []audit.Sink{
+		audit.SinkFunc(func(ctx context.Context, record audit.Record) error {
+			got = append(got, record)
+			return nil
+		}),
+	}})
+
+	handlers := validHandlers()
+	handlers.KillAllInstance = func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"killed":3,"function":"figlet"}`))
+	}
+
+	s := NewServer(&types.FaaSConfig{
+		Audit:                 trail,
+		KillAuthenticator:     &auth.BasicAuthCredentials{User: "admin", Password: "secret"},
+		KillConfirmationToken: "s3cr3t",
+	})
+	s.Handlers(handlers)
+
+	r := httptest.NewRequest(http.MethodPost, "/danger/kill?namespace=dev&confirm=s3cr3t", nil)
+	r.SetBasicAuth("admin", "secret")
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, r)
+
+	if got := w.Body.String(); !strings.Contains(got, `"killed":3`) {
+		t.Errorf("want the response to be passed through, got: %s", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	trail.Run(ctx)
+
+	if len(got) != 1 {
+		t.Fatalf("records, want: %d, got: %d", 1, len(got))
+	}
+	record := got[0]
+	if record.User != "admin" || record.Function != "figlet" || record.Namespace != "dev" || record.Instances != 3 {
+		t.Errorf("record, want: admin figlet dev 3, got: %s %s %s %d", record.User, record.Function, record.Namespace, record.Instances)
+	}
+}
+
+func Test_Server_KillRequiresConfirmationToken(t *testing.T) {
+	handlers := validHandlers()
+	handlers.KillAllInstance = handlers.Info
+
+	_, err := NewHTTPServer(handlers, &types.FaaSConfig{})
+	if err == nil || !strings.Contains(err.Error(), "KillConfirmationToken is required") {
+		t.Errorf("want an error for the missing KillConfirmationToken, got: %v", err)
+	}
+}
+
+func Test_Server_KillAuthenticator(t *testing.T) {
+	credentials := &auth.BasicAuthCredentials{User: "operator", Password: "kill-secret"}
+
+	handlers := validHandlers()
+	handlers.KillAllInstance = func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"killed":1}`))
+	}
+
+	s := NewServer(&types.FaaSConfig{KillAuthenticator: credentials, KillConfirmationToken: "s3cr3t"})
+	s.Handlers(handlers)
+
+	testCases := []struct {
+		name     string
+		user     string
+		password string
+		wantCode int
+	}{
+		{name: "without credentials", wantCode: http.StatusUnauthorized},
+		{name: "wrong credentials", user: "operator", password: "guess", wantCode: http.StatusUnauthorized},
+		{name: "kill credentials", user: "operator", password: "kill-secret", wantCode: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/danger/kill?confirm=s3cr3t", nil)
+			if len(tc.user) > 0 {
+				r.SetBasicAuth(tc.user, tc.password)
+			}
+
+			w := httptest.NewRecorder()
+			s.Router().ServeHTTP(w, r)
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+		})
+	}
+
+	// The rest of the API is still open, as EnableBasicAuth is not set.
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/system/functions", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("status code of /system/functions, want: %d, got: %d", http.StatusOK, w.Code)
+	}
+}
+
+func Test_Server_KillAllowedNamespaces(t *testing.T) {
+	var killed types.KillRequest
+	handlers := validHandlers()
+	handlers.KillAllInstance = func(w http.ResponseWriter, r *http.Request) {
+		killed, _ = types.DecodeKillRequest(r)
+		types.WriteJSON(w, http.StatusOK, types.KillResponse{Killed: 1, Namespace: killed.Namespace})
+	}
+
+	testCases := []struct {
+		name          string
+		allowed       []string
+		wantCode      int
+		wantNamespace string
+	}{
+		{name: "unscoped with several allowed namespaces", allowed: []string{"dev", "staging"}, wantCode: http.StatusForbidden},
+		{name: "unscoped with one allowed namespace", allowed: []string{"dev"}, wantCode: http.StatusOK, wantNamespace: "dev"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			killed = types.KillRequest{}
+			s := NewServer(&types.FaaSConfig{KillConfirmationToken: "s3cr3t", AllowedNamespaces: tc.allowed})
+			s.Handlers(handlers)
+
+			w := httptest.NewRecorder()
+			s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/danger/kill?confirm=s3cr3t", strings.NewReader(`{}`)))
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d (%s)", tc.wantCode, w.Code, w.Body.String())
+			}
+			if killed.Namespace != tc.wantNamespace {
+				t.Errorf("namespace killed in, want: %q, got: %q", tc.wantNamespace, killed.Namespace)
+			}
+		})
+	}
+}
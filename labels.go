@@ -0,0 +1,49 @@
+package bootstrap
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// decorateWithLabelValidation rejects deploy and update requests with a 400 when the labels
+// or annotations of the FunctionDeployment fail types.NormalizeLabels. When normalizing
+// trimmed a key, next is given the JSON of the normalized deployment in place of the
+// original body, otherwise the body is passed through as it was sent. Requests which can
+// not be decoded are passed to next, which reports the error, apart from those larger than
+// the limit of decorateWithBodyLimit, which are rejected with a 413.
+func decorateWithLabelValidation(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, ok := httputil.BufferBody(w, r)
+		if !ok {
+			return
+		}
+
+		req := types.FunctionDeployment{}
+		if err := json.Unmarshal(body, &req); err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		labels, annotations := req.Labels, req.Annotations
+		if err := req.NormalizeLabels(); err != nil {
+			httputil.WriteError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if !reflect.DeepEqual(labels, req.Labels) || !reflect.DeepEqual(annotations, req.Annotations) {
+			normalized, err := json.Marshal(req)
+			if err != nil {
+				httputil.WriteErrorCode(w, r, http.StatusInternalServerError, httputil.InternalError, fmt.Sprintf("unable to encode deployment: %s", err))
+				return
+			}
+			httputil.ReplaceBody(r, normalized)
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
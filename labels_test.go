@@ -0,0 +1,48 @@
+package bootstrap
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_decorateWithLabelValidation(t *testing.T) {
+	testCases := []struct {
+		name     string
+		body     string
+		wantBody string
+		wantCode int
+	}{
+		{name: "valid labels", body: `{"service":"figlet","labels":{"com.openfaas.scale.min":"1"}}`, wantCode: http.StatusAccepted},
+		{name: "duplicate labels", body: `{"service":"figlet","labels":{"team":"a","Team":"b"}}`, wantCode: http.StatusBadRequest},
+		{name: "invalid annotation", body: `{"service":"figlet","annotations":{"not valid":"a"}}`, wantCode: http.StatusBadRequest},
+		{name: "invalid limit", body: `{"service":"figlet","labels":{"com.openfaas.limits.max_inflight":"none"}}`, wantCode: http.StatusBadRequest},
+		{name: "invalid json is passed on", body: `{`, wantCode: http.StatusAccepted},
+		{name: "normalized labels are passed on", body: `{"service":"figlet","image":"figlet","labels":{" team ":"a"}}`, wantBody: `{"service":"figlet","image":"figlet","labels":{"team":"a"}}`, wantCode: http.StatusAccepted},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := decorateWithLabelValidation(func(w http.ResponseWriter, r *http.Request) {
+				wantBody := tc.body
+				if len(tc.wantBody) > 0 {
+					wantBody = tc.wantBody
+				}
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != wantBody {
+					t.Errorf("body, want: %q, got: %q", wantBody, string(body))
+				}
+				w.WriteHeader(http.StatusAccepted)
+			})
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/system/functions", strings.NewReader(tc.body)))
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+		})
+	}
+}
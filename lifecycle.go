@@ -0,0 +1,75 @@
+package bootstrap
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// decorateWithLifecycleHook calls hook with an event of eventType once next has responded
+// with a 2xx status. The name and namespace of the function are read from the request body,
+// which is restored before next is called.
+func decorateWithLifecycleHook(next http.HandlerFunc, eventType types.LifecycleEventType, hook func(types.LifecycleEvent)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, ok := httputil.BufferBody(w, r)
+		if !ok {
+			return
+		}
+
+		ww := httputil.NewHttpWriteInterceptor(w)
+		next.ServeHTTP(ww, r)
+
+		if ww.Status() < http.StatusOK || ww.Status() >= http.StatusMultipleChoices {
+			return
+		}
+
+		event, ok := lifecycleEventFromRequest(eventType, r, body)
+		if !ok {
+			return
+		}
+
+		event.Timestamp = time.Now()
+		hook(event)
+	}
+}
+
+// lifecycleEventFromRequest reads the function name and namespace from the body of the
+// deploy, update, delete or scale request.
+func lifecycleEventFromRequest(eventType types.LifecycleEventType, r *http.Request, body []byte) (types.LifecycleEvent, bool) {
+	event := types.LifecycleEvent{Type: eventType}
+
+	switch eventType {
+	case types.FunctionCreated, types.FunctionUpdated:
+		req := types.FunctionDeployment{}
+		if err := json.Unmarshal(body, &req); err != nil {
+			return event, false
+		}
+		event.Name = req.Service
+		event.Namespace = req.Namespace
+
+	case types.FunctionDeleted:
+		req := types.DeleteFunctionRequest{}
+		if err := json.Unmarshal(body, &req); err != nil {
+			return event, false
+		}
+		event.Name = req.FunctionName
+		event.Namespace = req.Namespace
+
+	case types.FunctionScaled:
+		req := types.ScaleServiceRequest{}
+		if err := json.Unmarshal(body, &req); err != nil {
+			return event, false
+		}
+		event.Name = req.ServiceName
+		if name := httputil.PathVar(r, "name"); len(name) > 0 {
+			event.Name = name
+		}
+		event.Namespace = req.Namespace
+		event.Replicas = req.Replicas
+	}
+
+	return event, len(event.Name) > 0
+}
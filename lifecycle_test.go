@@ -0,0 +1,96 @@
+package bootstrap
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_decorateWithLifecycleHook(t *testing.T) {
+	testCases := []struct {
+		name       string
+		eventType  types.LifecycleEventType
+		body       string
+		vars       map[string]string
+		statusCode int
+		want       *types.LifecycleEvent
+	}{
+		{
+			name:       "deploy",
+			eventType:  types.FunctionCreated,
+			body:       `{"service":"figlet","image":"ghcr.io/openfaas/figlet","namespace":"openfaas-fn"}`,
+			statusCode: http.StatusAccepted,
+			want:       &types.LifecycleEvent{Type: types.FunctionCreated, Name: "figlet", Namespace: "openfaas-fn"},
+		},
+		{
+			name:       "delete",
+			eventType:  types.FunctionDeleted,
+			body:       `{"functionName":"figlet"}`,
+			statusCode: http.StatusOK,
+			want:       &types.LifecycleEvent{Type: types.FunctionDeleted, Name: "figlet"},
+		},
+		{
+			name:       "scale uses the name from the path",
+			eventType:  types.FunctionScaled,
+			body:       `{"serviceName":"","replicas":3}`,
+			vars:       map[string]string{"name": "figlet"},
+			statusCode: http.StatusAccepted,
+			want:       &types.LifecycleEvent{Type: types.FunctionScaled, Name: "figlet", Replicas: 3},
+		},
+		{
+			name:       "failed deploy",
+			eventType:  types.FunctionCreated,
+			body:       `{"service":"figlet"}`,
+			statusCode: http.StatusBadRequest,
+			want:       nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got *types.LifecycleEvent
+			hook := func(e types.LifecycleEvent) {
+				got = &e
+			}
+
+			handler := decorateWithLifecycleHook(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != tc.body {
+					t.Errorf("want the body to be restored for the handler, got: %q", string(body))
+				}
+				w.WriteHeader(tc.statusCode)
+			}, tc.eventType, hook)
+
+			r := httptest.NewRequest(http.MethodPost, "/system/functions", strings.NewReader(tc.body))
+			if tc.vars != nil {
+				r = mux.SetURLVars(r, tc.vars)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			if tc.want == nil {
+				if got != nil {
+					t.Fatalf("want no event, got: %+v", *got)
+				}
+				return
+			}
+
+			if got == nil {
+				t.Fatalf("want event %+v, got none", *tc.want)
+			}
+
+			if got.Timestamp.IsZero() {
+				t.Errorf("want the event timestamp to be set")
+			}
+
+			got.Timestamp = tc.want.Timestamp
+			if *got != *tc.want {
+				t.Errorf("want event: %+v, got: %+v", *tc.want, *got)
+			}
+		})
+	}
+}
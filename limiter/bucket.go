@@ -0,0 +1,53 @@
+package limiter
+
+import (
+	"math"
+	"time"
+)
+
+// Bucket is a token bucket which holds up to burst tokens and is refilled at rate tokens
+// per second. Each request takes a token, so that requests are allowed at rate on average
+// and up to burst at once. It is not safe for concurrent use, callers hold their own lock.
+type Bucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewBucket returns a full Bucket, refilled at rate tokens per second up to burst tokens.
+// burst is at least one, so that a rate under one request per second allows a request.
+func NewBucket(rate, burst float64, now time.Time) *Bucket {
+	burst = math.Max(1, burst)
+	return &Bucket{rate: rate, burst: burst, tokens: burst, last: now}
+}
+
+// Take refills the bucket for the time since it was last used, then takes a token. When the
+// bucket is empty no token is taken, and the duration is how long until one is available.
+func (b *Bucket) Take(now time.Time) (time.Duration, bool) {
+	b.refill(now)
+
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / b.rate * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+// Remaining is the number of whole tokens left as of the last Take.
+func (b *Bucket) Remaining() int {
+	return int(b.tokens)
+}
+
+// UntilFull is how long until the bucket is full again, if nothing more is taken.
+func (b *Bucket) UntilFull(now time.Time) time.Duration {
+	b.refill(now)
+	return time.Duration((b.burst - b.tokens) / b.rate * float64(time.Second))
+}
+
+func (b *Bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed.Seconds()*b.rate)
+	}
+	b.last = now
+}
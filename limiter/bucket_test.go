@@ -0,0 +1,45 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_Bucket(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	b := NewBucket(0.5, 2, now)
+
+	steps := []struct {
+		name          string
+		advance       time.Duration
+		wantAllowed   bool
+		wantWait      time.Duration
+		wantRemaining int
+	}{
+		{name: "first", wantAllowed: true, wantRemaining: 1},
+		{name: "burst", wantAllowed: true, wantRemaining: 0},
+		{name: "empty", wantAllowed: false, wantWait: 2 * time.Second, wantRemaining: 0},
+		{name: "half refilled", advance: time.Second, wantAllowed: false, wantWait: time.Second, wantRemaining: 0},
+		{name: "refilled", advance: time.Second, wantAllowed: true, wantRemaining: 0},
+		{name: "refill is capped at burst", advance: time.Hour, wantAllowed: true, wantRemaining: 1},
+	}
+
+	for _, step := range steps {
+		now = now.Add(step.advance)
+
+		wait, allowed := b.Take(now)
+		if allowed != step.wantAllowed {
+			t.Errorf("%s: allowed, want: %v, got: %v", step.name, step.wantAllowed, allowed)
+		}
+		if wait != step.wantWait {
+			t.Errorf("%s: wait, want: %s, got: %s", step.name, step.wantWait, wait)
+		}
+		if got := b.Remaining(); got != step.wantRemaining {
+			t.Errorf("%s: remaining, want: %d, got: %d", step.name, step.wantRemaining, got)
+		}
+	}
+
+	if got := b.UntilFull(now); got != 2*time.Second {
+		t.Errorf("until full, want: %s, got: %s", 2*time.Second, got)
+	}
+}
@@ -0,0 +1,170 @@
+// Package limiter enforces the limits each function declares on its invocations with the
+// types.MaxInflightLabel and types.RequestsPerSecondLabel labels. Invocations over a limit
+// are rejected with a 429 and a Retry-After header.
+package limiter
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/logging"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// limitsTTL is how long the limits of a function are cached for, so that its labels are
+// not looked up for every invocation. Functions which have not been invoked for as long,
+// and have no invocations in flight, are forgotten.
+const limitsTTL = 10 * time.Second
+
+// functionState is the limits of a function, the invocations it has in flight and its
+// bucket of requests per second, nil when it has no rate.
+type functionState struct {
+	limits  types.FunctionLimits
+	expires time.Time
+
+	inflight int
+	bucket   *Bucket
+	last     time.Time
+}
+
+// Limiter applies the limits of each function to its invocations, it is safe for
+// concurrent use.
+type Limiter struct {
+	resolver types.LabelResolver
+
+	mu        sync.Mutex
+	functions map[string]*functionState
+	swept     time.Time
+
+	now func() time.Time
+}
+
+// New creates a Limiter which looks up the limits of functions with resolver.
+func New(resolver types.LabelResolver) *Limiter {
+	return &Limiter{
+		resolver:  resolver,
+		functions: map[string]*functionState{},
+		now:       time.Now,
+	}
+}
+
+// Decorate applies the limits of the function named by the "name" path variable to
+// requests to next.
+func (l *Limiter) Decorate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := httputil.PathVar(r, "name")
+		if len(name) == 0 {
+			next(w, r)
+			return
+		}
+
+		release, retryAfter, ok := l.acquire(logging.FromContext(r.Context()), name)
+		if !ok {
+			httputil.SetRetryAfter(w, retryAfter)
+			httputil.WriteError(w, r, http.StatusTooManyRequests, "too many requests to "+name)
+			return
+		}
+		defer release()
+
+		next(w, r)
+	}
+}
+
+// acquire takes a request of the function's rate and a slot of its in-flight requests,
+// the returned func gives back the slot. When a limit is reached, the duration is how long
+// the client should wait before retrying.
+func (l *Limiter) acquire(logger *slog.Logger, name string) (func(), time.Duration, bool) {
+	limits, refresh := l.cachedLimits(name)
+	if refresh {
+		limits = l.resolveLimits(logger, name)
+	}
+
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	state, ok := l.functions[name]
+	if !ok {
+		state = &functionState{last: now}
+		l.functions[name] = state
+	}
+	if refresh || !ok {
+		if rps := limits.RequestsPerSecond; rps != state.limits.RequestsPerSecond {
+			state.bucket = nil
+			if rps > 0 {
+				state.bucket = NewBucket(rps, rps, now)
+			}
+		}
+		state.limits = limits
+		state.expires = now.Add(limitsTTL)
+	}
+	state.last = now
+
+	if max := state.limits.MaxInflight; max > 0 && state.inflight >= max {
+		return nil, time.Second, false
+	}
+
+	if state.bucket != nil {
+		if wait, ok := state.bucket.Take(now); !ok {
+			return nil, wait, false
+		}
+	}
+
+	state.inflight++
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		state.inflight--
+	}, 0, true
+}
+
+// cachedLimits returns the cached limits of the function, the bool is true when they have
+// to be looked up again.
+func (l *Limiter) cachedLimits(name string) (types.FunctionLimits, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state, ok := l.functions[name]
+	if !ok || !l.now().Before(state.expires) {
+		return types.FunctionLimits{}, true
+	}
+	return state.limits, false
+}
+
+// resolveLimits looks up the limits of the function, a function whose labels can not be
+// read or are invalid is not limited.
+func (l *Limiter) resolveLimits(logger *slog.Logger, name string) types.FunctionLimits {
+	labels, err := l.resolver.ResolveLabels(name)
+	if err != nil {
+		logger.Warn("Unable to resolve labels for limits", "function", name, "error", err)
+		return types.FunctionLimits{}
+	}
+
+	limits, err := types.FunctionLimitsFromLabels(labels)
+	if err != nil {
+		logger.Warn("Invalid limits", "function", name, "error", err)
+		return types.FunctionLimits{}
+	}
+	return limits
+}
+
+// sweep forgets functions which are idle, at most once every limitsTTL.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < limitsTTL {
+		return
+	}
+	l.swept = now
+
+	for name, state := range l.functions {
+		if state.inflight == 0 && now.Sub(state.last) >= limitsTTL {
+			delete(l.functions, name)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package limiter
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/openfaas/faas-provider/types"
+)
+
+type testLabelResolver map[string]map[string]string
+
+func (r testLabelResolver) ResolveLabels(functionName string) (map[string]string, error) {
+	labels, ok := r[functionName]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return labels, nil
+}
+
+func invoke(handler http.HandlerFunc, name string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := mux.SetURLVars(httptest.NewRequest(http.MethodPost, "/function/"+name, nil), map[string]string{"name": name})
+	handler(w, r)
+	return w
+}
+
+func Test_Limiter_MaxInflight(t *testing.T) {
+	l := New(testLabelResolver{"figlet": {types.MaxInflightLabel: "1"}})
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	once := sync.Once{}
+	handler := l.Decorate(func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["name"] == "figlet" {
+			once.Do(func() {
+				close(started)
+				<-release
+			})
+		}
+	})
+
+	done := make(chan struct{})
+	go func() {
+		invoke(handler, "figlet")
+		close(done)
+	}()
+	<-started
+
+	w := invoke(handler, "figlet")
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("status code, want: %d, got: %d", http.StatusTooManyRequests, w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After, want: %s, got: %s", "1", got)
+	}
+
+	// Functions without limits, or whose labels can not be read, are not limited
+	for _, name := range []string{"env", "missing"} {
+		if w := invoke(handler, name); w.Code != http.StatusOK {
+			t.Errorf("%s status code, want: %d, got: %d", name, http.StatusOK, w.Code)
+		}
+	}
+
+	close(release)
+	<-done
+
+	if w := invoke(handler, "figlet"); w.Code != http.StatusOK {
+		t.Errorf("status code after release, want: %d, got: %d", http.StatusOK, w.Code)
+	}
+}
+
+func Test_Limiter_RequestsPerSecond(t *testing.T) {
+	l := New(testLabelResolver{"figlet": {types.RequestsPerSecondLabel: "0.5"}})
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	handler := l.Decorate(func(w http.ResponseWriter, r *http.Request) {})
+
+	if w := invoke(handler, "figlet"); w.Code != http.StatusOK {
+		t.Fatalf("first status code, want: %d, got: %d", http.StatusOK, w.Code)
+	}
+
+	w := invoke(handler, "figlet")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second status code, want: %d, got: %d", http.StatusTooManyRequests, w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After, want: %s, got: %s", "2", got)
+	}
+
+	now = now.Add(2 * time.Second)
+	if w := invoke(handler, "figlet"); w.Code != http.StatusOK {
+		t.Errorf("status code after waiting, want: %d, got: %d", http.StatusOK, w.Code)
+	}
+}
+
+func Test_Limiter_ForgetsIdleFunctions(t *testing.T) {
+	l := New(testLabelResolver{})
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	handler := l.Decorate(func(w http.ResponseWriter, r *http.Request) {})
+	invoke(handler, "figlet")
+
+	now = now.Add(limitsTTL)
+	invoke(handler, "env")
+
+	if _, ok := l.functions["figlet"]; ok {
+		t.Errorf("want idle function to be forgotten")
+	}
+}
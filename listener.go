@@ -0,0 +1,102 @@
+package bootstrap
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/net/netutil"
+)
+
+// newListener binds the address for the API on network, one of "tcp", "tcp4", "tcp6" or "unix", and, when maxConnections is greater
+// than zero, caps the number of concurrently accepted connections. Connections
+// beyond the limit wait in the kernel's accept backlog until a slot is released.
+//
+// The socket file of a "unix" listener is removed when the listener is closed.
+func newListener(network, addr string, maxConnections int, connections prometheus.Gauge) (net.Listener, error) {
+	if network == "unix" {
+		if err := removeStaleSocket(addr); err != nil {
+			return nil, err
+		}
+	}
+
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxConnections > 0 {
+		l = netutil.LimitListener(l, maxConnections)
+	}
+
+	return &countingListener{Listener: l, connections: connections}, nil
+}
+
+// removeStaleSocket removes the socket file at path when it was left behind by a server
+// which is no longer running, so that it can be bound again.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("unable to listen on %s: the file exists and is not a socket", path)
+	}
+
+	if c, err := net.Dial("unix", path); err == nil {
+		c.Close()
+		return fmt.Errorf("unable to listen on %s: the socket is in use", path)
+	}
+
+	return os.Remove(path)
+}
+
+// connectionsGauge records the number of connections currently open on the API listener.
+var connectionsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+	Subsystem: "provider",
+	Name:      "http_connections",
+	Help:      "Number of open connections to the API.",
+})
+
+// countingListener tracks open connections in a gauge.
+type countingListener struct {
+	net.Listener
+	connections prometheus.Gauge
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	l.connections.Inc()
+	return &countingConn{Conn: c, connections: l.connections}, nil
+}
+
+type countingConn struct {
+	net.Conn
+	connections prometheus.Gauge
+	closeOnce   sync.Once
+}
+
+func (c *countingConn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(c.connections.Dec)
+	return err
+}
+
+// closeListeners closes each of listeners, for when Serve fails before serving on them.
+func closeListeners(listeners []net.Listener) {
+	for _, l := range listeners {
+		l.Close()
+	}
+}
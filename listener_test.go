@@ -0,0 +1,101 @@
+package bootstrap
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func Test_newListener_TCP6(t *testing.T) {
+	probe, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 is not available: %s", err)
+	}
+	probe.Close()
+
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_connections"})
+
+	l, err := newListener("tcp6", "[::1]:0", 0, gauge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	addr := l.Addr().(*net.TCPAddr)
+	if addr.IP.To4() != nil {
+		t.Errorf("want an IPv6 address, got: %s", addr)
+	}
+
+	go func() {
+		if c, err := l.Accept(); err == nil {
+			c.Close()
+		}
+	}()
+
+	c, err := net.Dial("tcp6", addr.String())
+	if err != nil {
+		t.Fatalf("want to connect over tcp6, got: %s", err)
+	}
+	c.Close()
+}
+
+func Test_newListener_TCP4RejectsIPv6(t *testing.T) {
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_connections"})
+
+	if l, err := newListener("tcp4", "[::1]:0", 0, gauge); err == nil {
+		l.Close()
+		t.Errorf("want an error binding an IPv6 address on tcp4")
+	}
+}
+
+func Test_newListener_Unix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "provider.sock")
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_connections"})
+
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	l, err := newListener("unix", path, 0, gauge)
+	if err != nil {
+		t.Fatalf("want the stale socket to be replaced, got: %s", err)
+	}
+
+	if _, err := newListener("unix", path, 0, gauge); err == nil {
+		t.Errorf("want an error binding a socket which is in use")
+	}
+
+	go func() {
+		if c, err := l.Accept(); err == nil {
+			c.Close()
+		}
+	}()
+
+	c, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("want to connect over the socket, got: %s", err)
+	}
+	c.Close()
+
+	l.Close()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("want the socket file to be removed on close, got: %v", err)
+	}
+}
+
+func Test_newListener_UnixNotASocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "provider.sock")
+	os.WriteFile(path, []byte("data"), 0600)
+
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_connections"})
+	if l, err := newListener("unix", path, 0, gauge); err == nil {
+		l.Close()
+		t.Errorf("want an error when the path is not a socket")
+	}
+}
@@ -0,0 +1,61 @@
+package bootstrap
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// logStreamsGauge records the number of requests to "/system/logs" which are following logs.
+var logStreamsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+	Subsystem: "provider",
+	Name:      "log_streams",
+	Help:      "Number of log streams currently being followed.",
+})
+
+// logStreamLimiter caps the number of requests which follow logs at once, as each holds
+// a connection to the log backend for as long as the client stays connected.
+type logStreamLimiter struct {
+	streams chan struct{}
+	active  prometheus.Gauge
+}
+
+// newLogStreamLimiter creates a limiter, a limit of zero means unlimited, the streams
+// are still counted in active.
+func newLogStreamLimiter(maxStreams int, active prometheus.Gauge) *logStreamLimiter {
+	l := &logStreamLimiter{active: active}
+	if maxStreams > 0 {
+		l.streams = make(chan struct{}, maxStreams)
+	}
+	return l
+}
+
+// decorate rejects requests with "follow=true" with 429 when the limit of streams is in use,
+// requests for a fixed number of lines are not limited.
+func (l *logStreamLimiter) decorate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if follow, _ := strconv.ParseBool(r.URL.Query().Get("follow")); !follow {
+			next(w, r)
+			return
+		}
+
+		if l.streams != nil {
+			select {
+			case l.streams <- struct{}{}:
+				defer func() { <-l.streams }()
+			default:
+				w.Header().Set("Retry-After", "1")
+				httputil.WriteErrorCode(w, r, http.StatusTooManyRequests, httputil.TooManyRequests, "too many concurrent log streams")
+				return
+			}
+		}
+
+		l.active.Inc()
+		defer l.active.Dec()
+
+		next(w, r)
+	}
+}
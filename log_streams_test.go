@@ -0,0 +1,72 @@
+package bootstrap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func Test_logStreamLimiter(t *testing.T) {
+	active := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_log_streams"})
+	l := newLogStreamLimiter(1, active)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := l.decorate(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("block") == "true" {
+			started <- struct{}{}
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/system/logs?name=figlet&follow=true&block=true", nil))
+		close(done)
+	}()
+	<-started
+
+	m := &dto.Metric{}
+	active.Write(m)
+	if got := m.GetGauge().GetValue(); got != 1 {
+		t.Errorf("active streams, want: %d, got: %v", 1, got)
+	}
+
+	testCases := []struct {
+		name     string
+		query    string
+		wantCode int
+	}{
+		{name: "follow over the limit", query: "name=figlet&follow=true", wantCode: http.StatusTooManyRequests},
+		{name: "without follow", query: "name=figlet&tail=10", wantCode: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/system/logs?"+tc.query, nil))
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+		})
+	}
+
+	close(release)
+	<-done
+
+	active.Write(m)
+	if got := m.GetGauge().GetValue(); got != 0 {
+		t.Errorf("active streams, want: %d, got: %v", 0, got)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/system/logs?name=figlet&follow=true", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("status code after the stream closed, want: %d, got: %d", http.StatusOK, w.Code)
+	}
+}
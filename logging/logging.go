@@ -0,0 +1,100 @@
+// Package logging carries a structured logger and the ID of each request in its context,
+// so that handlers and the packages they call log with the request's ID and function
+// rather than through the log package.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+)
+
+const (
+	// RequestIDHeader identifies a request, it is propagated to functions and returned to
+	// the caller.
+	RequestIDHeader = "X-Request-Id"
+	// CallIDHeader identifies an invocation of a function, it is set to the request ID when
+	// the caller does not send one, see queue.CallIDHeader.
+	CallIDHeader = "X-Call-Id"
+)
+
+// maxRequestIDLength is the longest request ID accepted from a caller, longer IDs are
+// replaced.
+const maxRequestIDLength = 128
+
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	requestIDKey
+)
+
+// WithLogger returns a copy of ctx carrying logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// FromContext returns the logger carried by ctx, or slog.Default() when there is none.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// RequestIDFromContext returns the ID of the request ctx belongs to, or an empty string.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// NewRequestID returns a random ID of 32 hex characters.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Middleware assigns each request an ID, taken from its X-Request-Id or X-Call-Id header
+// when the caller sent a valid one. The ID is set on both headers of the request, so that
+// it is propagated to functions, and on the X-Request-Id header of the response. The
+// context of the request carries the ID and logger, with the "request_id" attribute.
+func Middleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = r.Header.Get(CallIDHeader)
+			}
+			if !validRequestID(id) {
+				id = NewRequestID()
+			}
+
+			r.Header.Set(RequestIDHeader, id)
+			if !validRequestID(r.Header.Get(CallIDHeader)) {
+				r.Header.Set(CallIDHeader, id)
+			}
+			w.Header().Set(RequestIDHeader, id)
+
+			ctx := context.WithValue(r.Context(), requestIDKey, id)
+			ctx = WithLogger(ctx, logger.With("request_id", id))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// validRequestID returns true for IDs of printable ASCII without spaces, so that an ID
+// from a caller can not break up a log line.
+func validRequestID(id string) bool {
+	if len(id) == 0 || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
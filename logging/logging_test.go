@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_Middleware(t *testing.T) {
+	cases := []struct {
+		name       string
+		header     http.Header
+		wantID     string
+		wantCallID string
+	}{
+		{"request id", http.Header{RequestIDHeader: {"abc"}}, "abc", "abc"},
+		{"call id", http.Header{CallIDHeader: {"def"}}, "def", "def"},
+		{"both", http.Header{RequestIDHeader: {"abc"}, CallIDHeader: {"def"}}, "abc", "def"},
+		{"invalid id is replaced", http.Header{RequestIDHeader: {"a b\n"}}, "", ""},
+		{"long id is replaced", http.Header{RequestIDHeader: {strings.Repeat("a", maxRequestIDLength+1)}}, "", ""},
+		{"none", http.Header{}, "", ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			logger := slog.New(slog.NewJSONHandler(out, nil))
+
+			var gotID, gotHeader, gotCallID string
+			handler := Middleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotID = RequestIDFromContext(r.Context())
+				gotHeader = r.Header.Get(RequestIDHeader)
+				gotCallID = r.Header.Get(CallIDHeader)
+				FromContext(r.Context()).Info("handled")
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/function/figlet", nil)
+			for k, v := range tc.header {
+				r.Header[k] = v
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if tc.wantID == "" {
+				if len(gotID) != 32 {
+					t.Fatalf("want a new ID of 32 characters, got: %q", gotID)
+				}
+				tc.wantID, tc.wantCallID = gotID, gotID
+			}
+
+			if gotID != tc.wantID {
+				t.Errorf("ID, want: %q, got: %q", tc.wantID, gotID)
+			}
+			if gotHeader != tc.wantID {
+				t.Errorf("%s header of the request, want: %q, got: %q", RequestIDHeader, tc.wantID, gotHeader)
+			}
+			if gotCallID != tc.wantCallID {
+				t.Errorf("%s header of the request, want: %q, got: %q", CallIDHeader, tc.wantCallID, gotCallID)
+			}
+			if got := w.Header().Get(RequestIDHeader); got != tc.wantID {
+				t.Errorf("%s header of the response, want: %q, got: %q", RequestIDHeader, tc.wantID, got)
+			}
+
+			record := map[string]interface{}{}
+			if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+				t.Fatalf("want a JSON record, got: %q, %s", out.String(), err)
+			}
+			if record["request_id"] != tc.wantID {
+				t.Errorf("request_id attribute, want: %q, got: %v", tc.wantID, record["request_id"])
+			}
+		})
+	}
+}
+
+func Test_FromContext_Default(t *testing.T) {
+	if got := FromContext(context.Background()); got != slog.Default() {
+		t.Errorf("want slog.Default() for a context without a logger")
+	}
+}
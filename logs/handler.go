@@ -3,13 +3,13 @@ package logs
 import (
 	"context"
 	"encoding/json"
-	"log"
 	"net/http"
 	"net/url"
 	"strconv"
 	"time"
 
 	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/logging"
 )
 
 // Requester submits queries the logging system.
@@ -26,23 +26,19 @@ func NewLogHandlerFunc(requestor Requester, timeout time.Duration) http.HandlerF
 			defer r.Body.Close()
 		}
 
-		cn, ok := w.(http.CloseNotifier)
-		if !ok {
-			log.Println("LogHandler: response is not a CloseNotifier, required for streaming response")
-			http.NotFound(w, r)
-			return
-		}
+		logger := logging.FromContext(r.Context())
+
 		flusher, ok := w.(http.Flusher)
 		if !ok {
-			log.Println("LogHandler: response is not a Flusher, required for streaming response")
+			logger.Error("LogHandler: response is not a Flusher, required for streaming response")
 			http.NotFound(w, r)
 			return
 		}
 
 		logRequest, err := parseRequest(r)
 		if err != nil {
-			log.Printf("LogHandler: could not parse request %s", err)
-			httputil.Errorf(w, http.StatusUnprocessableEntity, "could not parse the log request")
+			logger.Warn("LogHandler: could not parse request", "error", err)
+			httputil.WriteErrorCode(w, r, http.StatusUnprocessableEntity, httputil.InvalidRequest, "could not parse the log request")
 			return
 		}
 
@@ -51,7 +47,7 @@ func NewLogHandlerFunc(requestor Requester, timeout time.Duration) http.HandlerF
 		messages, err := requestor.Query(ctx, logRequest)
 		if err != nil {
 			// add smarter error handling here
-			httputil.Errorf(w, http.StatusInternalServerError, "function log request failed")
+			httputil.WriteErrorCode(w, r, http.StatusInternalServerError, httputil.InternalError, "function log request failed")
 			return
 		}
 
@@ -72,12 +68,13 @@ func NewLogHandlerFunc(requestor Requester, timeout time.Duration) http.HandlerF
 		jsonEncoder := json.NewEncoder(w)
 		for messages != nil {
 			select {
-			case <-cn.CloseNotify():
-				log.Println("LogHandler: client stopped listening")
+			case <-ctx.Done():
+				// The client went away, or the timeout passed
+				logger.Debug("LogHandler: client stopped listening")
 				return
 			case msg, ok := <-messages:
 				if !ok {
-					log.Println("LogHandler: end of log stream")
+					logger.Debug("LogHandler: end of log stream")
 					messages = nil
 					return
 				}
@@ -87,8 +84,7 @@ func NewLogHandlerFunc(requestor Requester, timeout time.Duration) http.HandlerF
 				if err != nil {
 					// can't actually write the status header here so we should json serialize an error
 					// and return that because we have already sent the content type and status code
-					log.Printf("LogHandler: failed to serialize log message: '%s'\n", msg.String())
-					log.Println(err.Error())
+					logger.Error("LogHandler: failed to serialize log message", "message", msg.String(), "error", err)
 					// write json error message here ?
 					jsonEncoder.Encode(Message{Text: "failed to serialize log message"})
 					flusher.Flush()
@@ -108,6 +104,10 @@ func parseRequest(r *http.Request) (logRequest Request, err error) {
 	query := r.URL.Query()
 	logRequest.Name = getValue(query, "name")
 	logRequest.Namespace = getValue(query, "namespace")
+	if len(logRequest.Namespace) == 0 {
+		// the function may be addressed as "name.namespace"
+		logRequest.Name, logRequest.Namespace = httputil.SplitFunctionName(logRequest.Name)
+	}
 	logRequest.Instance = getValue(query, "instance")
 	tailStr := getValue(query, "tail")
 	if tailStr != "" {
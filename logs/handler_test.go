@@ -77,7 +77,7 @@ func Test_logsHandlerDoesNotLeakGoroutinesWhenClientClosesConnection(t *testing.
 		defer resp.Body.Close()
 		_, err := ioutil.ReadAll(resp.Body)
 		if err != context.Canceled {
-			t.Fatalf("unexpected error reading log response: %s", err)
+			t.Errorf("unexpected error reading log response: %s", err)
 		}
 	}()
 	cancel()
@@ -109,6 +109,12 @@ func Test_GETRequestParsing(t *testing.T) {
 			err:             "",
 			expectedRequest: Request{Name: "theactual name"},
 		},
+		{
+			name:            "name with namespace",
+			rawQueryStr:     "name=foobar.dev",
+			err:             "",
+			expectedRequest: Request{Name: "foobar", Namespace: "dev"},
+		},
 		{
 			name:        "valid request with every parameter",
 			rawQueryStr: "name=foobar&since=2019-02-16T09%3A10%3A06%2B00%3A00&tail=5&follow=true&namespace=default",
@@ -184,3 +190,48 @@ func newFakeQueryRequester(l []Message, err error) fakeQueryRequester {
 	}
 
 }
+
+// flushOnlyWriter hides every optional interface of the ResponseWriter apart from
+// http.Flusher, as a middleware which wraps the writer may do.
+type flushOnlyWriter struct {
+	w http.ResponseWriter
+}
+
+func (f *flushOnlyWriter) Header() http.Header         { return f.w.Header() }
+func (f *flushOnlyWriter) Write(b []byte) (int, error) { return f.w.Write(b) }
+func (f *flushOnlyWriter) WriteHeader(code int)        { f.w.WriteHeader(code) }
+func (f *flushOnlyWriter) Flush()                      { f.w.(http.Flusher).Flush() }
+
+func Test_logsHandlerStreamsThroughWrappedWriter(t *testing.T) {
+	msgs := []Message{{Name: "funcFoo", Text: "msg 0"}}
+
+	var expected bytes.Buffer
+	json.NewEncoder(&expected).Encode(msgs[0])
+
+	querier := newFakeQueryRequester(msgs, nil)
+	logHandler := NewLogHandlerFunc(querier, queryTimeout)
+	testSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logHandler(&flushOnlyWriter{w: w}, r)
+	}))
+	defer testSrv.Close()
+
+	resp, err := http.Get(testSrv.URL + "?name=funcFoo")
+	if err != nil {
+		t.Fatalf("unexpected error sending log request: %s", err)
+	}
+	defer resp.Body.Close()
+
+	querier.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status code, want: %d, got: %d", http.StatusOK, resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("unexpected error reading log response: %s", err)
+	}
+	if string(body) != expected.String() {
+		t.Fatalf("expected log message %s, got: %s", expected.String(), body)
+	}
+}
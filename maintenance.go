@@ -0,0 +1,145 @@
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// defaultMaintenanceMessage is returned when maintenance is enabled without a message.
+const defaultMaintenanceMessage = "provider is under maintenance"
+
+// maintenanceExemptPaths are served during maintenance, so that the provider can still
+// be monitored and maintenance can be ended.
+var maintenanceExemptPaths = map[string]bool{
+	"/healthz":            true,
+	"/readyz":             true,
+	"/metrics":            true,
+	"/system/maintenance": true,
+}
+
+// maintenanceMode rejects every request, apart from health checks and metrics, while it
+// is enabled.
+type maintenanceMode struct {
+	mu    sync.RWMutex
+	state types.MaintenanceMode
+}
+
+func newMaintenanceMode(state types.MaintenanceMode) *maintenanceMode {
+	return &maintenanceMode{state: state}
+}
+
+func (m *maintenanceMode) get() types.MaintenanceMode {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+// middleware returns 503 with the maintenance message and a Retry-After header while
+// maintenance is enabled.
+func (m *maintenanceMode) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := m.get()
+		if !state.Enabled || maintenanceExemptPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		message := state.Message
+		if len(message) == 0 {
+			message = defaultMaintenanceMessage
+		}
+
+		body, _ := json.Marshal(struct {
+			Message    string `json:"message"`
+			RetryAfter int    `json:"retryAfter"`
+		}{message, state.RetryAfter})
+
+		if state.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(state.RetryAfter))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write(body)
+	})
+}
+
+// readMaintenanceFile returns the state requested by file, which enables maintenance while
+// it exists, with its content as the message when it is not empty. The message and
+// RetryAfter of fallback are used otherwise.
+func readMaintenanceFile(file string, fallback types.MaintenanceMode) (types.MaintenanceMode, error) {
+	content, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return types.MaintenanceMode{Message: fallback.Message, RetryAfter: fallback.RetryAfter}, nil
+	}
+	if err != nil {
+		return types.MaintenanceMode{}, err
+	}
+
+	state := types.MaintenanceMode{Enabled: true, Message: fallback.Message, RetryAfter: fallback.RetryAfter}
+	if message := strings.TrimSpace(string(content)); len(message) > 0 {
+		state.Message = message
+	}
+	return state, nil
+}
+
+// watch sets the state from file with readMaintenanceFile each time the process receives
+// SIGHUP until ctx is done, so that maintenance can be started and ended on the node
+// without the API. The state is kept when the file can not be read.
+func (m *maintenanceMode) watch(ctx context.Context, file string, fallback types.MaintenanceMode, logger *slog.Logger) {
+	onSIGHUP(ctx, func() {
+		state, err := readMaintenanceFile(file, fallback)
+		if err != nil {
+			logger.Error("Unable to read the maintenance file", "file", file, "error", err)
+			return
+		}
+
+		m.mu.Lock()
+		changed := m.state.Enabled != state.Enabled
+		m.state = state
+		m.mu.Unlock()
+
+		if changed {
+			logger.Info("Changed maintenance mode", "enabled", state.Enabled, "file", file)
+		}
+	})
+}
+
+// handler reports the current maintenance state on GET and replaces it on PUT.
+func (m *maintenanceMode) handler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPut {
+		if r.Body != nil {
+			defer r.Body.Close()
+		}
+
+		req := types.MaintenanceMode{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			httputil.WriteErrorCode(w, r, http.StatusBadRequest, httputil.InvalidRequest, "unable to decode maintenance mode request")
+			return
+		}
+
+		if req.RetryAfter < 0 {
+			httputil.WriteErrorCode(w, r, http.StatusBadRequest, httputil.InvalidRequest, "retryAfter must not be negative")
+			return
+		}
+
+		m.mu.Lock()
+		m.state = req
+		m.mu.Unlock()
+	}
+
+	body, _ := json.Marshal(m.get())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
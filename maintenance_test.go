@@ -0,0 +1,133 @@
+package bootstrap
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_maintenanceMode(t *testing.T) {
+	mode := newMaintenanceMode(types.MaintenanceMode{})
+	handler := mode.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/function/figlet", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code before maintenance, want: %d, got: %d", http.StatusOK, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	mode.handler(w, httptest.NewRequest(http.MethodPut, "/system/maintenance",
+		strings.NewReader(`{"enabled":true,"message":"upgrading until 14:00 UTC","retryAfter":600}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code enabling maintenance, want: %d, got: %d", http.StatusOK, w.Code)
+	}
+
+	testCases := []struct {
+		path     string
+		wantCode int
+	}{
+		{path: "/function/figlet", wantCode: http.StatusServiceUnavailable},
+		{path: "/system/functions", wantCode: http.StatusServiceUnavailable},
+		{path: "/healthz", wantCode: http.StatusOK},
+		{path: "/metrics", wantCode: http.StatusOK},
+		{path: "/system/maintenance", wantCode: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			if w.Code != tc.wantCode {
+				t.Fatalf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+
+			if tc.wantCode != http.StatusServiceUnavailable {
+				return
+			}
+
+			if got := w.Header().Get("Retry-After"); got != "600" {
+				t.Errorf("Retry-After, want: %s, got: %s", "600", got)
+			}
+
+			want := `{"message":"upgrading until 14:00 UTC","retryAfter":600}`
+			if got := w.Body.String(); got != want {
+				t.Errorf("body, want: %s, got: %s", want, got)
+			}
+		})
+	}
+}
+
+func Test_maintenanceMode_DefaultMessage(t *testing.T) {
+	mode := newMaintenanceMode(types.MaintenanceMode{Enabled: true})
+	handler := mode.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/system/functions", nil))
+
+	want := `{"message":"provider is under maintenance","retryAfter":0}`
+	if got := w.Body.String(); got != want {
+		t.Errorf("body, want: %s, got: %s", want, got)
+	}
+
+	if got := w.Header().Get("Retry-After"); got != "" {
+		t.Errorf("want no Retry-After header, got: %s", got)
+	}
+}
+
+func Test_maintenanceMode_WatchesFileOnSIGHUP(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "maintenance")
+	fallback := types.MaintenanceMode{Message: "back soon", RetryAfter: 60}
+	mode := newMaintenanceMode(fallback)
+
+	// SIGHUP terminates the process without a handler, until watch has installed its own.
+	guard := make(chan os.Signal, 1)
+	signal.Notify(guard, syscall.SIGHUP)
+	defer signal.Stop(guard)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go mode.watch(ctx, file, fallback, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	for _, tc := range []struct {
+		exists  bool
+		content string
+		want    types.MaintenanceMode
+	}{
+		{exists: true, want: types.MaintenanceMode{Enabled: true, Message: "back soon", RetryAfter: 60}},
+		{exists: true, content: "upgrading the cluster\n", want: types.MaintenanceMode{Enabled: true, Message: "upgrading the cluster", RetryAfter: 60}},
+		{want: types.MaintenanceMode{Message: "back soon", RetryAfter: 60}},
+	} {
+		if tc.exists {
+			os.WriteFile(file, []byte(tc.content), 0600)
+		} else {
+			os.Remove(file)
+		}
+
+		deadline := time.Now().Add(2 * time.Second)
+		for mode.get() != tc.want {
+			if time.Now().After(deadline) {
+				t.Fatalf("maintenance state, want: %+v, got: %+v", tc.want, mode.get())
+			}
+			// watch may not have installed its handler yet, so the signal is sent until it has.
+			syscall.Kill(os.Getpid(), syscall.SIGHUP)
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package bootstrap
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/openfaas/faas-provider/httputil"
+)
+
+// decorateWithMetricReset resets the invocation metrics of the function given in the
+// "function" and "namespace" query string parameters, or of every function when they are
+// omitted, once next has served a DELETE on /system/metrics successfully, and writes an
+// audit line for every DELETE. The metrics have no instance label, so they are left as
+// they are for a reset of one instance. Other requests are passed through.
+func decorateWithMetricReset(next http.HandlerFunc, metrics *invocationMetrics, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ww := httputil.NewHttpWriteInterceptor(w)
+		next.ServeHTTP(ww, r)
+
+		query := r.URL.Query()
+		function, namespace, instance := query.Get("function"), query.Get("namespace"), httputil.InstanceFromQuery(r)
+		if status := ww.Status(); status >= 200 && status <= 299 && len(instance) == 0 {
+			metrics.reset(function, namespace)
+		}
+
+		user, _, _ := r.BasicAuth()
+		logger.Info("audit: metrics reset", "function", function, "namespace", namespace,
+			"instance", instance, "user", user, "remote", r.RemoteAddr, "status", ww.Status())
+	}
+}
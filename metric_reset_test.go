@@ -0,0 +1,94 @@
+package bootstrap
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// seriesCount returns the number of series collected from c.
+func seriesCount(c prometheus.Collector) int {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		c.Collect(ch)
+		close(ch)
+	}()
+
+	count := 0
+	for range ch {
+		count++
+	}
+	return count
+}
+
+func Test_decorateWithMetricReset(t *testing.T) {
+	logs := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(logs, nil))
+
+	m := newTestInvocationMetrics(10)
+	status := http.StatusOK
+	handler := decorateWithMetricReset(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}, m, logger)
+
+	record := func() {
+		for _, name := range []string{"figlet.openfaas-fn", "env.openfaas-fn"} {
+			function, namespace := m.labelsFor(functionKeyFor(name), true)
+			m.invocations.WithLabelValues(function, namespace, "200").Inc()
+			m.invocations.WithLabelValues(function, namespace, "500").Inc()
+			m.duration.WithLabelValues(function, namespace).Observe(1)
+			m.starts.WithLabelValues(function, namespace, "cold").Inc()
+		}
+	}
+	record()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/system/metrics", nil))
+	if logs.Len() > 0 {
+		t.Errorf("want no audit line for GET, got: %s", logs.String())
+	}
+
+	testCases := []struct {
+		name        string
+		path        string
+		status      int
+		invocations int
+		durations   int
+	}{
+		{name: "failed reset", path: "/system/metrics?function=figlet&namespace=openfaas-fn", status: http.StatusInternalServerError, invocations: 4, durations: 2},
+		{name: "instance", path: "/system/metrics?function=figlet&namespace=openfaas-fn&instance=figlet-1", status: http.StatusOK, invocations: 4, durations: 2},
+		{name: "function", path: "/system/metrics?function=figlet&namespace=openfaas-fn", status: http.StatusOK, invocations: 2, durations: 1},
+		{name: "every function", path: "/system/metrics", status: http.StatusOK, invocations: 0, durations: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m.reset("", "")
+			record()
+			status = tc.status
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, tc.path, nil))
+
+			if got := seriesCount(m.invocations); got != tc.invocations {
+				t.Errorf("invocation series, want: %d, got: %d", tc.invocations, got)
+			}
+			if got := seriesCount(m.duration); got != tc.durations {
+				t.Errorf("duration series, want: %d, got: %d", tc.durations, got)
+			}
+			if got := seriesCount(m.starts); got != tc.durations {
+				t.Errorf("start series, want: %d, got: %d", tc.durations, got)
+			}
+		})
+	}
+
+	want := `msg="audit: metrics reset" function=figlet namespace=openfaas-fn`
+	if !strings.Contains(logs.String(), want) {
+		t.Errorf("want audit line containing %q, got: %s", want, logs.String())
+	}
+}
@@ -3,8 +3,10 @@ package bootstrap
 import (
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/openfaas/faas-provider/httputil"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -18,12 +20,71 @@ type httpMetrics struct {
 
 	// RequestDurationHistogram is a Prometheus summary vector partitioned by method and status.
 	RequestDurationHistogram *prometheus.HistogramVec
+
+	// routeStats holds the request count and last request time for each path label,
+	// for reading in-process through RouteStats.
+	routeStats   map[string]RouteStat
+	routeStatsMu sync.Mutex
+
+	// observers caches the collectors for each label set seen by InstrumentHandler.
+	observers   map[routeKey]*routeObservers
+	observersMu sync.RWMutex
+}
+
+// RouteStat is a summary of the requests served by a route.
+type RouteStat struct {
+	// Count is the number of requests served
+	Count uint64 `json:"count"`
+
+	// LastRequest is the time the most recent request completed
+	LastRequest time.Time `json:"lastRequest"`
+}
+
+// RouteStats returns the number of requests and the time of the last request for each
+// instrumented route, keyed by the same path label used in the Prometheus metrics.
+func RouteStats() map[string]RouteStat {
+	hm := defaultHttpMetrics()
+
+	hm.routeStatsMu.Lock()
+	defer hm.routeStatsMu.Unlock()
+
+	stats := make(map[string]RouteStat, len(hm.routeStats))
+	for path, stat := range hm.routeStats {
+		stats[path] = stat
+	}
+	return stats
+}
+
+func (hm *httpMetrics) recordRouteStat(path string, at time.Time) {
+	hm.routeStatsMu.Lock()
+	defer hm.routeStatsMu.Unlock()
+
+	stat := hm.routeStats[path]
+	stat.Count++
+	stat.LastRequest = at
+	hm.routeStats[path] = stat
+}
+
+var (
+	sharedHttpMetrics     *httpMetrics
+	sharedHttpMetricsOnce sync.Once
+)
+
+// defaultHttpMetrics returns the httpMetrics shared by Serve and WithMetrics, the
+// collectors are registered with the default Prometheus registry on first use.
+func defaultHttpMetrics() *httpMetrics {
+	sharedHttpMetricsOnce.Do(func() {
+		sharedHttpMetrics = newHttpMetrics()
+	})
+	return sharedHttpMetrics
 }
 
 // newHttpMetrics initialises a new httpMetrics struct for
 // recording R.E.D. metrics for system endpoint calls
 func newHttpMetrics() *httpMetrics {
 	return &httpMetrics{
+		routeStats: map[string]RouteStat{},
+		observers:  map[routeKey]*routeObservers{},
 		RequestsTotal: promauto.NewCounterVec(prometheus.CounterOpts{
 			Subsystem: "provider",
 			Name:      "http_requests_total",
@@ -46,27 +107,116 @@ func (hm *httpMetrics) InstrumentHandler(next http.Handler, pathOverride string)
 		next.ServeHTTP(ww, r)
 		duration := time.Since(start)
 
-		path := r.URL.Path
-		if len(pathOverride) > 0 {
-			path = pathOverride
+		path := routePath(r, pathOverride)
+		hm.recordRouteStat(path, start.Add(duration))
+
+		o := hm.observersFor(routeKey{code: ww.Status(), method: r.Method, path: path})
+		o.requests.Inc()
+		o.duration.Observe(duration.Seconds())
+	}
+}
+
+// unmatchedRoute is the path label of a request which was not routed by a mux.Router, so
+// that requests for arbitrary paths cannot grow the labels without bound.
+const unmatchedRoute = "unmatched"
+
+// routePath returns the path label of r: pathOverride when set, otherwise the template of
+// the route which matched r, i.e. "/system/namespace/{name}" rather than the path of the
+// namespace.
+func routePath(r *http.Request, pathOverride string) string {
+	if len(pathOverride) > 0 {
+		return pathOverride
+	}
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
 		}
+	}
+	return unmatchedRoute
+}
+
+// routeKey identifies the label set of a request in the R.E.D. metrics.
+type routeKey struct {
+	code   int
+	method string
+	path   string
+}
+
+// routeObservers are the collectors for a single label set, they are cached so that
+// recording a request does not build a label map or look up the vectors each time.
+type routeObservers struct {
+	requests prometheus.Counter
+	duration prometheus.Observer
+}
+
+// observersFor returns the cached collectors for key, creating them on first use.
+func (hm *httpMetrics) observersFor(key routeKey) *routeObservers {
+	hm.observersMu.RLock()
+	o, ok := hm.observers[key]
+	hm.observersMu.RUnlock()
+	if ok {
+		return o
+	}
+
+	hm.observersMu.Lock()
+	defer hm.observersMu.Unlock()
+
+	if o, ok := hm.observers[key]; ok {
+		return o
+	}
+
+	code := strconv.Itoa(key.code)
+	o = &routeObservers{
+		requests: hm.RequestsTotal.WithLabelValues(code, key.method, key.path),
+		duration: hm.RequestDurationHistogram.WithLabelValues(code, key.method, key.path),
+	}
+	hm.observers[key] = o
+	return o
+}
+
+var (
+	// restoreDurationHistogram records how long it takes a provider to restore a
+	// function instance from a checkpoint.
+	restoreDurationHistogram = promauto.NewHistogram(prometheus.HistogramOpts{
+		Subsystem: "provider",
+		Name:      "checkpoint_restore_duration_seconds",
+		Help:      "Seconds spent restoring function instances from a checkpoint.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	// checkpointsCollectedTotal counts the checkpoints deleted by the retention policy.
+	checkpointsCollectedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Subsystem: "provider",
+		Name:      "checkpoints_collected_total",
+		Help:      "Total number of checkpoints deleted by the retention policy.",
+	})
+
+	// checkpointBytesFreedTotal counts the size of the checkpoints deleted by the
+	// retention policy.
+	checkpointBytesFreedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Subsystem: "provider",
+		Name:      "checkpoint_gc_freed_bytes_total",
+		Help:      "Total size in bytes of the checkpoints deleted by the retention policy.",
+	})
+
+	// instanceKillsTotal counts the function instances killed by the provider.
+	instanceKillsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Subsystem: "provider",
+		Name:      "instance_kills_total",
+		Help:      "Total number of function instances killed.",
+	})
+)
+
+// RecordRestore records the time taken to restore a function instance from a checkpoint
+// in the provider_checkpoint_restore_duration_seconds histogram.
+func RecordRestore(duration time.Duration) {
+	restoreDurationHistogram.Observe(duration.Seconds())
+}
 
-		defer func() {
-			hm.RequestsTotal.With(
-				prometheus.Labels{"code": strconv.Itoa(ww.Status()),
-					"method": r.Method,
-					"path":   path,
-				}).
-				Inc()
-		}()
-
-		defer func() {
-			hm.RequestDurationHistogram.With(
-				prometheus.Labels{"code": strconv.Itoa(ww.Status()),
-					"method": r.Method,
-					"path":   path,
-				}).
-				Observe(duration.Seconds())
-		}()
+// RecordKill adds n killed function instances to the provider_instance_kills_total counter.
+func RecordKill(n int) {
+	if n <= 0 {
+		return
 	}
+	instanceKillsTotal.Add(float64(n))
 }
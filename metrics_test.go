@@ -0,0 +1,158 @@
+package bootstrap
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_RouteStats(t *testing.T) {
+	h := WithMetrics("/system/route-stats-test")(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	before := RouteStats()["/system/route-stats-test"]
+
+	for i := 0; i < 3; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/system/route-stats-test", nil))
+	}
+
+	got := RouteStats()["/system/route-stats-test"]
+	if got.Count-before.Count != 3 {
+		t.Errorf("count, want: %d, got: %d", 3, got.Count-before.Count)
+	}
+
+	if got.LastRequest.IsZero() {
+		t.Errorf("want the last request time to be recorded")
+	}
+}
+
+func Test_RouteStats_KeyedByRouteTemplate(t *testing.T) {
+	r := mux.NewRouter()
+	r.Handle("/system/route-template-test/{name}", WithMetrics("")(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	before := RouteStats()
+
+	for i := 0; i < 3; i++ {
+		path := fmt.Sprintf("/system/route-template-test/ns-%d", i)
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	after := RouteStats()
+
+	if got := after["/system/route-template-test/{name}"].Count - before["/system/route-template-test/{name}"].Count; got != 3 {
+		t.Errorf("count, want: %d, got: %d", 3, got)
+	}
+	if _, ok := after["/system/route-template-test/ns-0"]; ok {
+		t.Errorf("want requests to be keyed by the route template, not by the path")
+	}
+
+	hm := defaultHttpMetrics()
+	hm.observersMu.RLock()
+	defer hm.observersMu.RUnlock()
+
+	for key := range hm.observers {
+		if key.path == "/system/route-template-test/ns-0" {
+			t.Errorf("want the request metrics to be labelled by the route template, not by the path")
+		}
+	}
+	if _, ok := hm.observers[routeKey{code: http.StatusOK, method: http.MethodGet, path: "/system/route-template-test/{name}"}]; !ok {
+		t.Errorf("want the request metrics to be labelled by the route template")
+	}
+}
+
+func Test_routePath(t *testing.T) {
+	testCases := []struct {
+		name     string
+		override string
+		want     string
+	}{
+		{name: "override", override: "/system/function", want: "/system/function"},
+		{name: "no route", want: unmatchedRoute},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/system/function/figlet", nil)
+			if got := routePath(r, tc.override); got != tc.want {
+				t.Errorf("path, want: %s, got: %s", tc.want, got)
+			}
+		})
+	}
+}
+
+// discardResponseWriter is a ResponseWriter which does not allocate, so that only the
+// allocations of the handler under test are counted.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (d *discardResponseWriter) Header() http.Header         { return d.header }
+func (d *discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (d *discardResponseWriter) WriteHeader(int)             {}
+
+func Test_InstrumentHandler_Allocations(t *testing.T) {
+	h := defaultHttpMetrics().InstrumentHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), "/system/allocs-test")
+
+	w := &discardResponseWriter{header: http.Header{}}
+	r := httptest.NewRequest(http.MethodGet, "/system/allocs-test", nil)
+
+	// The only allocation is the response writer interceptor
+	allocs := testing.AllocsPerRun(100, func() {
+		h.ServeHTTP(w, r)
+	})
+	if allocs > 1 {
+		t.Errorf("allocs per request, want: <= %d, got: %.0f", 1, allocs)
+	}
+}
+
+func BenchmarkInstrumentHandler(b *testing.B) {
+	h := defaultHttpMetrics().InstrumentHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), "/system/benchmark")
+
+	w := &discardResponseWriter{header: http.Header{}}
+	r := httptest.NewRequest(http.MethodGet, "/system/benchmark", nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		h.ServeHTTP(w, r)
+	}
+}
+
+func Test_Server_InstrumentsInvocations(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	s := NewServer(&types.FaaSConfig{})
+	s.Handlers(&types.FaaSHandlers{FunctionProxy: ok, InvokeFunction: ok})
+
+	before := RouteStats()
+
+	for _, path := range []string{"/function/figlet", "/function/env/path", "/invoke/figlet"} {
+		s.Router().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, path, nil))
+	}
+
+	after := RouteStats()
+
+	if got := after["/function"].Count - before["/function"].Count; got != 2 {
+		t.Errorf("/function requests, want: %d, got: %d", 2, got)
+	}
+	if got := after["/invoke"].Count - before["/invoke"].Count; got != 1 {
+		t.Errorf("/invoke requests, want: %d, got: %d", 1, got)
+	}
+	if _, ok := after["/function/figlet"]; ok {
+		t.Errorf("want invocations to be labelled by route, not by function")
+	}
+}
@@ -0,0 +1,73 @@
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/logging"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// newMergePatchHandler serves PATCH "/system/functions". Requests are rejected with a 415
+// unless their body is a types.MergePatchContentType, and with a 400 unless it is a JSON
+// object which names the function, see types.ParseFunctionPatch. The patch is applied to
+// the deployment returned by read, then the merged deployment is passed to update as the
+// body of a PUT, so that it is checked in the same way as an update.
+func newMergePatchHandler(update http.HandlerFunc, read func(ctx context.Context, name, namespace string) (types.FunctionDeployment, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType != types.MergePatchContentType {
+			httputil.WriteError(w, r, http.StatusUnsupportedMediaType, fmt.Sprintf("Content-Type must be %s", types.MergePatchContentType))
+			return
+		}
+
+		body, ok := httputil.BufferBody(w, r)
+		if !ok {
+			return
+		}
+
+		patch, err := types.ParseFunctionPatch(body)
+		if err != nil {
+			httputil.WriteError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		deployment, err := read(r.Context(), patch.Service, patch.Namespace)
+		if errors.Is(err, types.ErrFunctionNotFound) {
+			httputil.WriteErrorCode(w, r, http.StatusNotFound, httputil.FunctionNotFound, fmt.Sprintf("function %s not found", patch.Service))
+			return
+		}
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Unable to read the deployment to patch", "function", patch.Service, "namespace", patch.Namespace, "error", err)
+			httputil.WriteError(w, r, http.StatusInternalServerError, "unable to read the deployment to patch")
+			return
+		}
+
+		patched, err := deployment.ApplyPatch(patch.Patch)
+		if err != nil {
+			httputil.WriteError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		if patched.Service != patch.Service {
+			httputil.WriteError(w, r, http.StatusBadRequest, "invalid merge patch: service must not be changed")
+			return
+		}
+
+		merged, err := json.Marshal(patched)
+		if err != nil {
+			httputil.WriteError(w, r, http.StatusInternalServerError, "unable to encode the patched deployment")
+			return
+		}
+
+		put := r.Clone(r.Context())
+		put.Method = http.MethodPut
+		put.Header.Set("Content-Type", "application/json")
+		httputil.ReplaceBody(put, merged)
+		update.ServeHTTP(w, put)
+	}
+}
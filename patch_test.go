@@ -0,0 +1,78 @@
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_PatchFunction(t *testing.T) {
+	labels := map[string]string{"team": "a"}
+	deployment := types.FunctionDeployment{Service: "figlet", Namespace: "dev", Image: "ghcr.io/openfaas/figlet:latest", Labels: &labels}
+
+	handlers := validHandlers()
+	handlers.ReadDeployment = func(ctx context.Context, name, namespace string) (types.FunctionDeployment, error) {
+		if name != deployment.Service || namespace != deployment.Namespace {
+			return types.FunctionDeployment{}, fmt.Errorf("%s.%s: %w", name, namespace, types.ErrFunctionNotFound)
+		}
+		return deployment, nil
+	}
+	handlers.UpdateFunction = func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("method, want: %s, got: %s", http.MethodPut, r.Method)
+		}
+		updated := types.FunctionDeployment{}
+		if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		deployment = updated
+	}
+
+	var events []types.LifecycleEvent
+	s := NewServer(&types.FaaSConfig{LifecycleHook: func(event types.LifecycleEvent) {
+		events = append(events, event)
+	}})
+	s.Handlers(handlers)
+
+	testCases := []struct {
+		name        string
+		contentType string
+		body        string
+		wantCode    int
+	}{
+		{name: "not a merge patch", contentType: "application/json", body: `{"service":"figlet","namespace":"dev"}`, wantCode: http.StatusUnsupportedMediaType},
+		{name: "without the service", contentType: types.MergePatchContentType, body: `{"labels":{"tier":"gold"}}`, wantCode: http.StatusBadRequest},
+		{name: "unknown function", contentType: types.MergePatchContentType, body: `{"service":"figlet","namespace":"prod","labels":{"tier":"gold"}}`, wantCode: http.StatusNotFound},
+		{name: "unknown field", contentType: types.MergePatchContentType, body: `{"service":"figlet","namespace":"dev","replicas":2}`, wantCode: http.StatusBadRequest},
+		{name: "invalid deployment", contentType: types.MergePatchContentType, body: `{"service":"figlet","namespace":"dev","limits":{"memory":"lots"}}`, wantCode: http.StatusBadRequest},
+		{name: "labels", contentType: types.MergePatchContentType + "; charset=utf-8", body: `{"service":"figlet","namespace":"dev","labels":{"team":null,"tier":"gold"}}`, wantCode: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPatch, "/system/functions", strings.NewReader(tc.body))
+			r.Header.Set("Content-Type", tc.contentType)
+
+			w := httptest.NewRecorder()
+			s.Router().ServeHTTP(w, r)
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d (%s)", tc.wantCode, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if deployment.Image != "ghcr.io/openfaas/figlet:latest" || deployment.Labels == nil || len(*deployment.Labels) != 1 || (*deployment.Labels)["tier"] != "gold" {
+		t.Errorf("want only the labels to be patched, got: %+v", deployment)
+	}
+	if len(events) != 1 || events[0].Type != types.FunctionUpdated || events[0].Name != "figlet" || events[0].Namespace != "dev" {
+		t.Errorf("want one update event for figlet.dev, got: %+v", events)
+	}
+}
@@ -0,0 +1,21 @@
+package bootstrap
+
+import (
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/openfaas/faas-provider/httputil"
+)
+
+// registerProfiling adds the net/http/pprof handlers under "/debug/pprof/" to r, each
+// wrapped with decorate, i.e. so that they require the same auth as the system API.
+// The CPU profile and trace run for the "seconds" query parameter, which must be shorter
+// than WriteTimeout for the response to be written.
+func registerProfiling(r httputil.Router, decorate func(http.HandlerFunc) http.HandlerFunc) {
+	r.Handle("/debug/pprof/cmdline", decorate(pprof.Cmdline))
+	r.Handle("/debug/pprof/profile", decorate(pprof.Profile))
+	r.Handle("/debug/pprof/symbol", decorate(pprof.Symbol))
+	r.Handle("/debug/pprof/trace", decorate(pprof.Trace))
+	// Index also serves the named profiles, such as "/debug/pprof/heap".
+	r.HandlePrefix("/debug/pprof/", decorate(pprof.Index))
+}
@@ -0,0 +1,58 @@
+package bootstrap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openfaas/faas-provider/auth"
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_Server_Profiling(t *testing.T) {
+	deny := auth.AuthenticatorFunc(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+
+	metricsPort := 8081
+
+	testCases := []struct {
+		name        string
+		config      types.FaaSConfig
+		metrics     bool
+		wantCode    int
+		wantAPICode int
+	}{
+		{name: "disabled", config: types.FaaSConfig{}, wantCode: http.StatusNotFound},
+		{name: "enabled", config: types.FaaSConfig{EnableProfiling: true}, wantCode: http.StatusOK},
+		{name: "with auth", config: types.FaaSConfig{EnableProfiling: true, Authenticator: deny}, wantCode: http.StatusUnauthorized},
+		{name: "metrics port", config: types.FaaSConfig{EnableProfiling: true, MetricsPort: &metricsPort}, metrics: true, wantCode: http.StatusOK, wantAPICode: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewServer(&tc.config)
+			s.Handlers(&types.FaaSHandlers{})
+
+			router := s.Router()
+			if tc.metrics {
+				router = s.metricsRouter
+
+				w := httptest.NewRecorder()
+				s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/pprof/heap", nil))
+				if w.Code != tc.wantAPICode {
+					t.Errorf("API status code, want: %d, got: %d", tc.wantAPICode, w.Code)
+				}
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/pprof/heap", nil))
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/resolver"
+)
+
+// MultiURLResolver may be implemented by a BaseURLResolver which knows the address of every
+// instance of a function. The proxy will then balance requests across the instances itself,
+// with the resolver.Balancer named by FaaSConfig.ProxyLoadBalancing, rather than relying on
+// DNS or a Service to do so.
+type MultiURLResolver interface {
+	ResolveAll(functionName string) ([]url.URL, error)
+}
+
+// InstanceResolver may be implemented by a BaseURLResolver which can look up an instance of a
+// function by its ID, as returned by the FunctionInstances handler. Invocations which carry
+// the httputil.InstanceHeader are then sent to that instance, otherwise they are answered
+// with a 501. It should return an error wrapping ErrInstanceNotFound when the function has
+// no such instance.
+type InstanceResolver interface {
+	ResolveInstance(functionName, instance string) (url.URL, error)
+}
+
+// ErrInstanceNotFound should be returned, or wrapped, by an InstanceResolver when the function
+// has no instance with the requested ID, a 404 is then returned.
+var ErrInstanceNotFound = errors.New("function instance not found")
+
+// newBalancer returns the resolver.Balancer named by strategy, or round-robin when it is
+// unknown, which FaaSConfig.Validate reports.
+func newBalancer(strategy string) *resolver.Balancer {
+	b, err := resolver.NewBalancer(strategy)
+	if err != nil {
+		return resolver.RoundRobin()
+	}
+	return b
+}
+
+// FromResolver returns a BaseURLResolver for r, so that the proxy resolves each invocation
+// with the context of the request, and leaves picking the instance to the Strategy of r
+// rather than to config.ProxyLoadBalancing:
+//
+//	strategy, err := resolver.NewStrategy(config.ProxyLoadBalancing)
+//	...
+//	proxy.NewHandlerFunc(config, proxy.FromResolver(resolver.New(source, strategy)))
+//
+// The function name is split into its name and namespace as in "figlet.openfaas-fn".
+func FromResolver(r resolver.Resolver) BaseURLResolver {
+	return &contextResolver{r: r}
+}
+
+type contextResolver struct {
+	r resolver.Resolver
+}
+
+// Resolve resolves functionName without the context of a request, the instance is
+// released straight away.
+func (c *contextResolver) Resolve(functionName string) (url.URL, error) {
+	addr, release, err := c.resolve(context.Background(), functionName)
+	if err != nil {
+		return url.URL{}, err
+	}
+	release(false)
+	return addr, nil
+}
+
+func (c *contextResolver) resolve(ctx context.Context, functionName string) (url.URL, func(failed bool), error) {
+	name, namespace := httputil.SplitFunctionName(functionName)
+	addr, release, err := c.r.Resolve(ctx, name, namespace)
+	if err != nil {
+		return url.URL{}, nil, err
+	}
+	if release == nil {
+		release = func(bool) {}
+	}
+	return addr, release, nil
+}
@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/openfaas/faas-provider/resolver"
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_FromResolver(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}))
+	defer upstream.Close()
+
+	u, _ := url.Parse(upstream.URL)
+
+	var gotName, gotNamespace, gotKey string
+	released := make(chan bool, 1)
+	r := resolver.ResolverFunc(func(ctx context.Context, name, namespace string) (url.URL, resolver.ReleaseFunc, error) {
+		gotName, gotNamespace, gotKey = name, namespace, resolver.KeyFromContext(ctx)
+		return *u, func(failed bool) { released <- failed }, nil
+	})
+
+	proxyHandler := NewHandlerFunc(types.FaaSConfig{ReadTimeout: time.Second}, FromResolver(r))
+
+	req := httptest.NewRequest(http.MethodGet, "/function/figlet.dev", nil)
+	req = mux.SetURLVars(req, map[string]string{"name": "figlet.dev"})
+	req = req.WithContext(resolver.WithKey(req.Context(), "session-1"))
+	rr := httptest.NewRecorder()
+	proxyHandler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code, want: %d, got: %d", http.StatusOK, rr.Code)
+	}
+	if gotName != "figlet" || gotNamespace != "dev" || gotKey != "session-1" {
+		t.Errorf("resolved, want: figlet dev session-1, got: %s %s %s", gotName, gotNamespace, gotKey)
+	}
+	if failed := <-released; failed {
+		t.Errorf("want the instance to be released as reachable")
+	}
+}
+
+func Test_FromResolver_ClientDisconnectIsNotAFailure(t *testing.T) {
+	reached := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(reached)
+		<-r.Context().Done()
+	}))
+	defer upstream.Close()
+
+	u, _ := url.Parse(upstream.URL)
+
+	released := make(chan bool, 1)
+	r := resolver.ResolverFunc(func(ctx context.Context, name, namespace string) (url.URL, resolver.ReleaseFunc, error) {
+		return *u, func(failed bool) { released <- failed }, nil
+	})
+
+	proxyHandler := NewHandlerFunc(types.FaaSConfig{ReadTimeout: 5 * time.Second}, FromResolver(r))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-reached
+		cancel()
+	}()
+
+	req := httptest.NewRequest(http.MethodGet, "/function/figlet", nil).WithContext(ctx)
+	req = mux.SetURLVars(req, map[string]string{"name": "figlet"})
+	proxyHandler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if failed := <-released; failed {
+		t.Errorf("want the instance to be released as reachable when the client went away")
+	}
+}
@@ -0,0 +1,245 @@
+package proxy
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/openfaas/faas-provider/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ErrCircuitOpen is wrapped by the Error passed to the error handler when an invocation was
+// rejected because the circuit breaker of the function is open, see
+// FaaSConfig.ProxyCircuitBreaker.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// circuitIdleTimeout is how long a circuit is kept for without invocations of its function,
+// so that the circuits of functions which were removed are not kept forever. An open
+// circuit is kept until it may let an invocation through.
+const circuitIdleTimeout = 10 * time.Minute
+
+// The values of the provider_proxy_circuit_state metric.
+const (
+	circuitClosed   = 0
+	circuitHalfOpen = 1
+	circuitOpen     = 2
+)
+
+var (
+	circuitState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "provider",
+		Name:      "proxy_circuit_state",
+		Help:      "State of the circuit breaker of each function which has failed recently: 0 closed, 1 half-open, 2 open.",
+	}, []string{"function"})
+
+	circuitRejectionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "provider",
+		Name:      "proxy_circuit_rejections_total",
+		Help:      "Total number of invocations rejected because the circuit breaker of the function was open.",
+	}, []string{"function"})
+)
+
+// breakerResult is the outcome of an invocation allowed by the circuitBreaker.
+type breakerResult int
+
+const (
+	// breakerSkipped is an invocation which was not sent, or whose client went away, it
+	// does not change the circuit.
+	breakerSkipped breakerResult = iota
+	breakerSucceeded
+	breakerFailed
+)
+
+// circuit is the state of the breaker of a function which has failed, functions without
+// failures have no circuit.
+type circuit struct {
+	failures int
+	openedAt time.Time
+	probing  bool
+	used     time.Time
+}
+
+// circuitBreaker keeps a circuit for each function, a nil circuitBreaker allows every
+// invocation.
+type circuitBreaker struct {
+	threshold    int
+	openDuration time.Duration
+	labels       *functionLabels
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+	swept    time.Time
+
+	now func() time.Time
+}
+
+// newCircuitBreaker creates a circuitBreaker with the policy, whose metrics are labelled
+// with labels, or returns nil when the policy is nil.
+func newCircuitBreaker(policy *types.ProxyCircuitBreaker, labels *functionLabels) *circuitBreaker {
+	if policy == nil {
+		return nil
+	}
+
+	return &circuitBreaker{
+		threshold:    policy.GetFailureThreshold(),
+		openDuration: policy.GetOpenDuration(),
+		labels:       labels,
+		circuits:     map[string]*circuit{},
+		now:          time.Now,
+	}
+}
+
+// allow reports whether an invocation of the function may be sent. When it may, the
+// returned func must be called with its result. When it may not, the duration is how long
+// until the circuit lets an invocation through again.
+func (b *circuitBreaker) allow(functionName string) (func(breakerResult), time.Duration, bool) {
+	if b == nil {
+		return func(breakerResult) {}, 0, true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.sweep(now)
+
+	c, ok := b.circuits[functionName]
+	if ok {
+		c.used = now
+	}
+	if !ok || c.openedAt.IsZero() {
+		return b.done(functionName, false), 0, true
+	}
+
+	// A single invocation is let through once the circuit has been open for
+	// openDuration, the rest are rejected until its result is known.
+	remaining := c.openedAt.Add(b.openDuration).Sub(now)
+	if remaining > 0 || c.probing {
+		label, _ := b.labels.label(functionName)
+		circuitRejectionsTotal.WithLabelValues(label).Inc()
+		return nil, remaining, false
+	}
+
+	c.probing = true
+	b.setState(functionName, circuitHalfOpen)
+	return b.done(functionName, true), 0, true
+}
+
+// done records the result of an invocation, probe is set for the invocation let through
+// by an open circuit.
+func (b *circuitBreaker) done(functionName string, probe bool) func(breakerResult) {
+	return func(result breakerResult) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		c, ok := b.circuits[functionName]
+		switch {
+		case result == breakerSkipped:
+			if ok && probe {
+				c.probing = false
+				b.setState(functionName, circuitOpen)
+			}
+
+		case result == breakerSucceeded:
+			// An invocation which started before the circuit opened does not close it.
+			if ok && (probe || c.openedAt.IsZero()) {
+				delete(b.circuits, functionName)
+				b.setState(functionName, circuitClosed)
+			}
+
+		case probe:
+			if ok {
+				c.probing = false
+				c.openedAt = b.now()
+				b.setState(functionName, circuitOpen)
+			}
+
+		default:
+			if !ok {
+				c = &circuit{used: b.now()}
+				b.circuits[functionName] = c
+			}
+			if !c.openedAt.IsZero() {
+				return
+			}
+
+			c.failures++
+			if c.failures >= b.threshold {
+				c.openedAt = b.now()
+				b.setState(functionName, circuitOpen)
+			}
+		}
+	}
+}
+
+// setState sets the provider_proxy_circuit_state of the function, the state of the
+// functions over the limit of b.labels is not exported. b.mu must be held.
+func (b *circuitBreaker) setState(functionName string, state float64) {
+	if label, ok := b.labels.label(functionName); ok {
+		circuitState.WithLabelValues(label).Set(state)
+	}
+}
+
+// sweep removes the circuits which have not been used within circuitIdleTimeout, unless
+// they are open and may not let an invocation through yet, along with their
+// provider_proxy_circuit_state series. b.mu must be held.
+func (b *circuitBreaker) sweep(now time.Time) {
+	if now.Sub(b.swept) < circuitIdleTimeout {
+		return
+	}
+	b.swept = now
+
+	for name, c := range b.circuits {
+		if now.Sub(c.used) < circuitIdleTimeout || c.probing {
+			continue
+		}
+		if !c.openedAt.IsZero() && now.Sub(c.openedAt) < b.openDuration {
+			continue
+		}
+		delete(b.circuits, name)
+		circuitState.DeleteLabelValues(name)
+	}
+}
+
+func (b *circuitBreaker) state() []types.ProxyCircuitState {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	circuits := make([]types.ProxyCircuitState, 0, len(b.circuits))
+	for name, c := range b.circuits {
+		s := types.ProxyCircuitState{Function: name, State: "closed", Failures: c.failures}
+		if !c.openedAt.IsZero() {
+			openedAt := c.openedAt
+			s.OpenedAt = &openedAt
+			s.State = "open"
+			if c.probing {
+				s.State = "half-open"
+			}
+		}
+		circuits = append(circuits, s)
+	}
+
+	sort.Slice(circuits, func(i, j int) bool { return circuits[i].Function < circuits[j].Function })
+	return circuits
+}
+
+func (b *circuitBreaker) reset() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for name := range b.circuits {
+		b.setState(name, circuitClosed)
+	}
+	b.circuits = map[string]*circuit{}
+}
@@ -0,0 +1,195 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/openfaas/faas-provider/types"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// hasSeries reports whether c has a series with the function label set to function.
+func hasSeries(t *testing.T, c prometheus.Collector, function string) bool {
+	t.Helper()
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		c.Collect(ch)
+		close(ch)
+	}()
+
+	found := false
+	for metric := range ch {
+		m := &dto.Metric{}
+		if err := metric.Write(m); err != nil {
+			t.Fatalf("want no error, got: %s", err)
+		}
+		for _, label := range m.GetLabel() {
+			if label.GetName() == "function" && label.GetValue() == function {
+				found = true
+			}
+		}
+	}
+	return found
+}
+
+func Test_circuitBreaker(t *testing.T) {
+	now := time.Now()
+	b := newCircuitBreaker(&types.ProxyCircuitBreaker{FailureThreshold: 2, OpenDuration: 10 * time.Second}, newFunctionLabels(10))
+	b.now = func() time.Time { return now }
+
+	allow := func(want bool) func(breakerResult) {
+		t.Helper()
+		done, _, ok := b.allow("figlet")
+		if ok != want {
+			t.Fatalf("allowed, want: %v, got: %v", want, ok)
+		}
+		return done
+	}
+
+	allow(true)(breakerFailed)
+	allow(true)(breakerFailed)
+
+	// The circuit is open, so requests are rejected until OpenDuration has passed
+	_, retryAfter, _ := b.allow("figlet")
+	if retryAfter != 10*time.Second {
+		t.Errorf("retry after, want: %s, got: %s", 10*time.Second, retryAfter)
+	}
+	allow(false)
+
+	// A single request is let through, which opens the circuit again when it fails
+	now = now.Add(10 * time.Second)
+	probe := allow(true)
+	allow(false)
+	probe(breakerFailed)
+	allow(false)
+
+	// and closes it when it succeeds
+	now = now.Add(10 * time.Second)
+	allow(true)(breakerSucceeded)
+	allow(true)
+
+	if state := b.state(); len(state) != 0 {
+		t.Errorf("want no circuits once closed, got: %v", state)
+	}
+}
+
+func Test_circuitBreaker_SuccessResetsFailures(t *testing.T) {
+	b := newCircuitBreaker(&types.ProxyCircuitBreaker{FailureThreshold: 2}, newFunctionLabels(10))
+
+	for i := 0; i < 4; i++ {
+		done, _, ok := b.allow("figlet")
+		if !ok {
+			t.Fatalf("request %d, want it to be allowed", i)
+		}
+
+		result := breakerFailed
+		if i%2 == 1 {
+			result = breakerSucceeded
+		}
+		done(result)
+	}
+}
+
+func Test_circuitBreaker_CapsFunctionLabels(t *testing.T) {
+	b := newCircuitBreaker(&types.ProxyCircuitBreaker{FailureThreshold: 1}, newFunctionLabels(1))
+
+	for _, name := range []string{"capped-a", "capped-b"} {
+		done, _, _ := b.allow(name)
+		done(breakerFailed)
+	}
+
+	if !hasSeries(t, circuitState, "capped-a") {
+		t.Errorf("want a provider_proxy_circuit_state series for capped-a")
+	}
+	if hasSeries(t, circuitState, "capped-b") {
+		t.Errorf("want no provider_proxy_circuit_state series for capped-b, over the limit")
+	}
+
+	// Rejections of functions over the limit are counted under otherFunction.
+	b.allow("capped-b")
+	if hasSeries(t, circuitRejectionsTotal, "capped-b") || !hasSeries(t, circuitRejectionsTotal, otherFunction) {
+		t.Errorf("want the rejection of capped-b to be counted under %q", otherFunction)
+	}
+}
+
+func Test_circuitBreaker_EvictsIdleCircuits(t *testing.T) {
+	now := time.Now()
+	b := newCircuitBreaker(&types.ProxyCircuitBreaker{FailureThreshold: 2, OpenDuration: time.Hour}, newFunctionLabels(10))
+	b.now = func() time.Time { return now }
+
+	// idle-failed has failed once, idle-open has opened its circuit
+	done, _, _ := b.allow("idle-failed")
+	done(breakerFailed)
+	for i := 0; i < 2; i++ {
+		done, _, _ := b.allow("idle-open")
+		done(breakerFailed)
+	}
+
+	now = now.Add(circuitIdleTimeout)
+	b.allow("figlet")
+
+	state := b.state()
+	if len(state) != 1 || state[0].Function != "idle-open" {
+		t.Fatalf("want only the open circuit of idle-open to be kept, got: %v", state)
+	}
+	if hasSeries(t, circuitState, "idle-failed") {
+		t.Errorf("want the provider_proxy_circuit_state series of idle-failed to be removed")
+	}
+
+	// Once it may let an invocation through, the open circuit is removed as well
+	now = now.Add(time.Hour)
+	b.allow("figlet")
+
+	if state := b.state(); len(state) != 0 {
+		t.Errorf("want no circuits, got: %v", state)
+	}
+	if hasSeries(t, circuitState, "idle-open") {
+		t.Errorf("want the provider_proxy_circuit_state series of idle-open to be removed")
+	}
+}
+
+func Test_ProxyHandler_CircuitBreaker(t *testing.T) {
+	var calls int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer upstream.Close()
+
+	u, _ := url.Parse(upstream.URL)
+	config := types.FaaSConfig{
+		ReadTimeout:         time.Second,
+		ProxyCircuitBreaker: &types.ProxyCircuitBreaker{FailureThreshold: 2, OpenDuration: time.Minute},
+	}
+	handler := NewHandler(config, mockResolver{u, nil})
+
+	var rr *httptest.ResponseRecorder
+	for i := 0; i < 3; i++ {
+		rr = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/function/foo", nil)
+		req = mux.SetURLVars(req, map[string]string{"name": "foo"})
+		handler.ServeHTTP(rr, req)
+	}
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("status code, want: %d, got: %d", http.StatusServiceUnavailable, rr.Code)
+	}
+	if got := rr.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After, want: %q, got: %q", "60", got)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("calls, want: %d, got: %d", 2, got)
+	}
+
+	state := handler.State()
+	if len(state.Circuits) != 1 || state.Circuits[0].State != "open" {
+		t.Errorf("want the circuit of foo to be open, got: %v", state.Circuits)
+	}
+}
@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-provider/types"
+	"golang.org/x/net/http2"
+)
+
+// NewGRPCHandler creates an http.HandlerFunc which proxies gRPC requests to functions. The
+// request is sent to the function over HTTP/2 cleartext (h2c), response messages are flushed
+// to the client as they arrive and trailers such as grpc-status are preserved.
+//
+// gRPC clients connect with HTTP/2, so the provider's server must accept HTTP/2 for this
+// handler to be reached, either over TLS or with h2c. Requests which are not gRPC are
+// rejected with 415. NewHandlerFunc detects gRPC requests itself, this handler is for
+// providers which serve gRPC functions on a dedicated route.
+func NewGRPCHandler(config types.FaaSConfig, resolver BaseURLResolver) http.HandlerFunc {
+	if resolver == nil {
+		panic("NewGRPCHandler: empty proxy handler resolver, cannot be nil")
+	}
+
+	p := newFunctionProxy(config, resolver)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil {
+			defer r.Body.Close()
+		}
+
+		if !isGRPCRequest(r) {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+
+		p.proxyRequest(w, r)
+	}
+}
+
+// NewGRPCClient creates a http.Client which speaks HTTP/2 cleartext (h2c) to functions, as
+// required to forward gRPC requests. The timeout bounds dialing a function. The client has
+// no overall timeout, which would end long-lived streams, so the deadline of each request
+// is set on its context.
+func NewGRPCClient(timeout time.Duration) *http.Client {
+	return newH2CClient(timeout, "tcp", &types.HTTP2Config{})
+}
+
+// newH2CClient creates the client for NewGRPCClient and FaaSConfig.ProxyH2C, dialing
+// functions over network with the frame size and health checks of settings. The proxy sets
+// the timeout of each request on its context.
+func newH2CClient(timeout time.Duration, network string, settings *types.HTTP2Config) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 1 * time.Second,
+	}
+
+	transport := &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, _, addr string, _ *tls.Config) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		},
+		MaxReadFrameSize: settings.GetMaxReadFrameSize(),
+	}
+	if settings.ReadIdleTimeout > 0 {
+		transport.ReadIdleTimeout = settings.ReadIdleTimeout
+		transport.PingTimeout = settings.GetPingTimeout()
+	}
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// isGRPCRequest reports whether r is a gRPC request, including the +proto and +json variants.
+func isGRPCRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
+
+// copyWithFlush copies src to w, flushing after every read so that streamed messages are
+// sent to the client as soon as they are received from the function.
+func copyWithFlush(w http.ResponseWriter, src io.Reader) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		_, err := io.Copy(w, src)
+		return err
+	}
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if _, writeErr := w.Write(buf[:n]); writeErr != nil {
+				return writeErr
+			}
+			flusher.Flush()
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
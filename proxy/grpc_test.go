@@ -0,0 +1,133 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/openfaas/faas-provider/types"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func Test_NewHandlerFunc_ProxiesGRPCOverH2C(t *testing.T) {
+	upstream := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			t.Errorf("want the function to be called with HTTP/2, got: %s", r.Proto)
+		}
+
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("message"))
+		w.Header().Set("Grpc-Status", "0")
+	}), &http2.Server{}))
+	defer upstream.Close()
+
+	u, err := url.Parse(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := types.FaaSConfig{ReadTimeout: time.Second}
+	proxyHandler := NewHandlerFunc(config, mockResolver{u, nil})
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/function/greeter/helloworld.Greeter/SayHello", strings.NewReader("request"))
+	req.Header.Set("Content-Type", "application/grpc")
+	req = mux.SetURLVars(req, map[string]string{"name": "greeter", "params": "/helloworld.Greeter/SayHello"})
+
+	proxyHandler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code, want: %d, got: %d", http.StatusOK, rr.Code)
+	}
+
+	if got := rr.Body.String(); got != "message" {
+		t.Errorf("body, want: %q, got: %q", "message", got)
+	}
+
+	if got := rr.Result().Trailer.Get("Grpc-Status"); got != "0" {
+		t.Errorf("want Grpc-Status trailer to be preserved, got: %q", got)
+	}
+}
+
+func Test_NewGRPCClient_StreamsPastTimeout(t *testing.T) {
+	upstream := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/grpc")
+		for i := 0; i < 3; i++ {
+			w.Write([]byte("message"))
+			w.(http.Flusher).Flush()
+			time.Sleep(20 * time.Millisecond)
+		}
+	}), &http2.Server{}))
+	defer upstream.Close()
+
+	res, err := NewGRPCClient(10*time.Millisecond).Post(upstream.URL, "application/grpc", strings.NewReader("request"))
+	if err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("want the stream to outlive the dial timeout, got: %s", err)
+	}
+	if got := string(body); got != "messagemessagemessage" {
+		t.Errorf("body, want: %q, got: %q", "messagemessagemessage", got)
+	}
+}
+
+func Test_NewGRPCHandler_RejectsOtherRequests(t *testing.T) {
+	config := types.FaaSConfig{ReadTimeout: time.Second}
+	handler := NewGRPCHandler(config, mockResolver{&url.URL{Scheme: "http", Host: "127.0.0.1:1"}, nil})
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/function/greeter", strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	req = mux.SetURLVars(req, map[string]string{"name": "greeter"})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("status code, want: %d, got: %d", http.StatusUnsupportedMediaType, rr.Code)
+	}
+}
+
+func Test_NewHandlerFunc_ProxiesOverH2C(t *testing.T) {
+	upstream := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			t.Errorf("want the function to be called with HTTP/2, got: %s", r.Proto)
+		}
+		w.Write([]byte("hello"))
+	}), &http2.Server{}))
+	defer upstream.Close()
+
+	u, err := url.Parse(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := types.FaaSConfig{ReadTimeout: time.Second, ProxyH2C: true}
+	proxyHandler := NewHandlerFunc(config, mockResolver{u, nil})
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/function/figlet", strings.NewReader("request"))
+	// Rejected by HTTP/2 unless the proxy drops it.
+	req.Header.Set("Connection", "keep-alive, X-Internal")
+	req = mux.SetURLVars(req, map[string]string{"name": "figlet"})
+
+	proxyHandler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code, want: %d, got: %d (%s)", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	if got := rr.Body.String(); got != "hello" {
+		t.Errorf("body, want: %q, got: %q", "hello", got)
+	}
+}
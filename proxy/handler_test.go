@@ -2,7 +2,10 @@ package proxy
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"net/http/httptest"
@@ -12,6 +15,9 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/scaling"
+	"github.com/openfaas/faas-provider/tracing"
 	"github.com/openfaas/faas-provider/types"
 )
 
@@ -90,7 +96,7 @@ func Test_ProxyHandler_MissingFunctionNameError(t *testing.T) {
 		t.Errorf("expected status code `%d`, got `%d`", http.StatusBadRequest, w.Code)
 	}
 	want := "Provide function name in the request path"
-	respBody := strings.TrimSpace(w.Body.String())
+	respBody := errorMessage(t, w.Body.Bytes())
 	if respBody != want {
 		t.Errorf("want error message %q, but got %q", want, respBody)
 	}
@@ -118,7 +124,7 @@ func Test_ProxyHandler_ResolveError(t *testing.T) {
 	}
 
 	want := `No endpoints available for: foo.`
-	respBody := strings.TrimSpace(w.Body.String())
+	respBody := errorMessage(t, w.Body.Bytes())
 	if respBody != want {
 		t.Errorf("want error `%s`, but got `%s`", want, respBody)
 	}
@@ -165,3 +171,306 @@ func Test_ProxyHandler_Proxy_Success(t *testing.T) {
 		})
 	}
 }
+
+func Test_ProxyHandler_PropagatesTraceParent(t *testing.T) {
+	var got, gotState string
+	testFuncService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(tracing.TraceParentHeader)
+		gotState = r.Header.Get(tracing.TraceStateHeader)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer testFuncService.Close()
+
+	serverURL := strings.TrimPrefix(testFuncService.URL, "http://")
+	proxyFunc := NewHandlerFunc(types.FaaSConfig{ReadTimeout: 5 * time.Second}, &testBaseURLResolver{serverURL, nil})
+
+	tracer := tracing.NewTracer("http://127.0.0.1:1", "faas-provider", 0)
+	parent, _ := tracing.ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	parent.TraceState = "vendor=value"
+	ctx, span := tracer.Start(context.Background(), "POST /function/{name}", tracing.SpanKindServer, &parent)
+
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/foo", nil)
+	req.Header.Set(tracing.TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set(tracing.TraceStateHeader, "vendor=value")
+	req = mux.SetURLVars(req.WithContext(ctx), map[string]string{"name": "foo"})
+
+	proxyFunc(httptest.NewRecorder(), req)
+
+	if want := span.Context.TraceParent(); got != want {
+		t.Errorf("traceparent, want: %s, got: %s", want, got)
+	}
+	if gotState != "vendor=value" {
+		t.Errorf("tracestate, want: %s, got: %s", "vendor=value", gotState)
+	}
+}
+
+func Test_ProxyHandler_CustomErrorHandler(t *testing.T) {
+	resolveErr := errors.New("can not find test service `foo`")
+
+	var gotErr error
+	config := types.FaaSConfig{
+		ReadTimeout: 100 * time.Millisecond,
+		ProxyErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			gotErr = err
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(`{"error":"function unavailable"}`))
+		},
+	}
+	proxyFunc := NewHandlerFunc(config, &testBaseURLResolver{"", resolveErr})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+	req = mux.SetURLVars(req, map[string]string{"name": "foo"})
+
+	proxyFunc(w, req)
+
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("status code want `%d`, but got `%d`", http.StatusBadGateway, w.Code)
+	}
+
+	if got := w.Body.String(); got != `{"error":"function unavailable"}` {
+		t.Errorf("want custom error body, but got `%s`", got)
+	}
+
+	var proxyErr *Error
+	if !errors.As(gotErr, &proxyErr) {
+		t.Fatalf("want a *proxy.Error, got: %T", gotErr)
+	}
+
+	if proxyErr.FunctionName != "foo" {
+		t.Errorf("want function name `foo`, got `%s`", proxyErr.FunctionName)
+	}
+
+	if !errors.Is(gotErr, resolveErr) {
+		t.Errorf("want error to wrap the resolver error")
+	}
+}
+
+type slowResolver struct {
+	delay time.Duration
+}
+
+func (s slowResolver) Resolve(name string) (url.URL, error) {
+	time.Sleep(s.delay)
+	return url.URL{Scheme: "http", Host: "127.0.0.1:1"}, nil
+}
+
+func Test_ProxyHandler_ColdStartMaxWait(t *testing.T) {
+	config := types.FaaSConfig{
+		ReadTimeout:      100 * time.Millisecond,
+		ColdStartMaxWait: 10 * time.Millisecond,
+	}
+	proxyFunc := NewHandlerFunc(config, slowResolver{delay: 200 * time.Millisecond})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+	req = mux.SetURLVars(req, map[string]string{"name": "foo"})
+
+	proxyFunc(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status code want `%d`, but got `%d`", http.StatusServiceUnavailable, w.Code)
+	}
+
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After want `1`, but got `%s`", got)
+	}
+}
+
+// scaledToZero is a function which never becomes available once scaled up.
+type scaledToZero struct{}
+
+func (scaledToZero) Replicas(ctx context.Context, function, namespace string) (scaling.Replicas, error) {
+	return scaling.Replicas{}, nil
+}
+
+func (scaledToZero) Scale(ctx context.Context, function, namespace string, replicas uint64) error {
+	return nil
+}
+
+func Test_ProxyHandler_ScaleFromZeroTimeout(t *testing.T) {
+	var gotErr error
+	config := types.FaaSConfig{
+		ReadTimeout: 100 * time.Millisecond,
+		ProxyErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			gotErr = err
+			w.WriteHeader(http.StatusServiceUnavailable)
+		},
+	}
+	scaler := scaling.New(scaledToZero{}, scaling.Config{
+		Backoff: scaling.Backoff{Timeout: 20 * time.Millisecond, InitialInterval: time.Millisecond},
+	})
+	proxyFunc := scaler.Decorate(NewHandlerFunc(config, slowResolver{}))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+	req = mux.SetURLVars(req, map[string]string{"name": "foo"})
+
+	proxyFunc(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status code want `%d`, but got `%d`", http.StatusServiceUnavailable, w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After want `1`, but got `%s`", got)
+	}
+	if !errors.Is(gotErr, ErrColdStartTimeout) || !errors.Is(gotErr, scaling.ErrNotReady) {
+		t.Errorf("want error to wrap %s and %s, got: %v", ErrColdStartTimeout, scaling.ErrNotReady, gotErr)
+	}
+}
+
+type defaultFunctionResolver struct {
+	defaultFunction string
+	host            string
+}
+
+func (d defaultFunctionResolver) Resolve(name string) (url.URL, error) {
+	if name != d.defaultFunction {
+		return url.URL{}, fmt.Errorf("%s: %w", name, ErrFunctionNotFound)
+	}
+	return url.URL{Scheme: "http", Host: d.host}, nil
+}
+
+func Test_ProxyHandler_DefaultFunction(t *testing.T) {
+	var gotOriginal string
+	testFuncService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotOriginal = r.Header.Get(OriginalFunctionHeader)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer testFuncService.Close()
+
+	resolver := defaultFunctionResolver{
+		defaultFunction: "catch-all",
+		host:            strings.TrimPrefix(testFuncService.URL, "http://"),
+	}
+
+	testCases := []struct {
+		name            string
+		defaultFunction string
+		function        string
+		wantCode        int
+		wantOriginal    string
+	}{
+		{name: "not found without default", function: "foo", wantCode: http.StatusNotFound},
+		{name: "not found with default", defaultFunction: "catch-all", function: "foo", wantCode: http.StatusAccepted, wantOriginal: "foo"},
+		{name: "default called directly", defaultFunction: "catch-all", function: "catch-all", wantCode: http.StatusAccepted},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotOriginal = ""
+			config := types.FaaSConfig{
+				ReadTimeout:     time.Second,
+				DefaultFunction: tc.defaultFunction,
+			}
+			proxyFunc := NewHandlerFunc(config, resolver)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/"+tc.function, nil)
+			req = mux.SetURLVars(req, map[string]string{"name": tc.function})
+
+			proxyFunc(w, req)
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code want `%d`, but got `%d`", tc.wantCode, w.Code)
+			}
+
+			if gotOriginal != tc.wantOriginal {
+				t.Errorf("%s want `%s`, but got `%s`", OriginalFunctionHeader, tc.wantOriginal, gotOriginal)
+			}
+		})
+	}
+}
+
+type annotationResolver struct {
+	testBaseURLResolver
+	annotations map[string]string
+}
+
+func (a *annotationResolver) ResolveAnnotations(name string) (map[string]string, error) {
+	return a.annotations, nil
+}
+
+func Test_ProxyHandler_AnnotationResponseHeaders(t *testing.T) {
+	testFuncService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("X-Function", "static")
+		w.Write([]byte("<html></html>"))
+	}))
+	defer testFuncService.Close()
+
+	resolver := &annotationResolver{
+		testBaseURLResolver: testBaseURLResolver{testServerBase: strings.TrimPrefix(testFuncService.URL, "http://")},
+		annotations: map[string]string{
+			"com.openfaas.response.header.cache-control":               "max-age=60",
+			"com.openfaas.response.header.access-control-allow-origin": "*",
+		},
+	}
+
+	proxyFunc := NewHandlerFunc(types.FaaSConfig{ReadTimeout: time.Second}, resolver)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/static", nil)
+	req = mux.SetURLVars(req, map[string]string{"name": "static"})
+
+	proxyFunc(w, req)
+
+	wantHeaders := map[string]string{
+		"Cache-Control":               "max-age=60",
+		"Access-Control-Allow-Origin": "*",
+		"X-Function":                  "static",
+	}
+
+	for k, want := range wantHeaders {
+		if got := w.Header().Get(k); got != want {
+			t.Errorf("header %s want `%s`, but got `%s`", k, want, got)
+		}
+	}
+}
+
+func Test_defaultErrorHandler_WritesMessageVerbatim(t *testing.T) {
+	testCases := []struct {
+		name          string
+		err           error
+		wantCode      int
+		wantBody      string
+		wantErrorCode httputil.ErrorCode
+	}{
+		{name: "proxy error", err: &Error{StatusCode: http.StatusBadGateway, Message: "function 100%s unavailable"}, wantCode: http.StatusBadGateway, wantBody: "function 100%s unavailable"},
+		{name: "not found", err: &Error{StatusCode: http.StatusNotFound, Message: "Function not found: foo.", Err: ErrFunctionNotFound}, wantCode: http.StatusNotFound, wantBody: "Function not found: foo.", wantErrorCode: httputil.FunctionNotFound},
+		{name: "other error", err: errors.New("at 100%d capacity"), wantCode: http.StatusInternalServerError, wantBody: "at 100%d capacity", wantErrorCode: httputil.InternalError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			defaultErrorHandler(w, httptest.NewRequest(http.MethodGet, "/function/foo", nil), tc.err)
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+			res := httputil.ErrorResponse{}
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatalf("want an ErrorResponse, got: %q", w.Body.String())
+			}
+			if res.Message != tc.wantBody {
+				t.Errorf("message, want: %q, got: %q", tc.wantBody, res.Message)
+			}
+			if res.ErrorCode != tc.wantErrorCode {
+				t.Errorf("error code, want: %q, got: %q", tc.wantErrorCode, res.ErrorCode)
+			}
+		})
+	}
+}
+
+// errorMessage returns the message of the httputil.ErrorResponse in body.
+func errorMessage(t *testing.T, body []byte) string {
+	t.Helper()
+
+	res := httputil.ErrorResponse{}
+	if err := json.Unmarshal(body, &res); err != nil {
+		t.Fatalf("want an ErrorResponse, got: %q", string(body))
+	}
+	return res.Message
+}
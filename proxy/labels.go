@@ -0,0 +1,36 @@
+package proxy
+
+import "sync"
+
+// otherFunction is the function label of the proxy metrics of the functions over the limit
+// of functionLabels, as for the invocation metrics served by bootstrap.Serve.
+const otherFunction = "other"
+
+// functionLabels bounds the number of functions given their own series in the proxy
+// metrics to FaaSConfig.MaxFunctionMetrics, as the proxy is also sent names which are not
+// deployed. Functions are admitted in the order they are first labelled.
+type functionLabels struct {
+	mu    sync.Mutex
+	limit int
+	known map[string]bool
+}
+
+func newFunctionLabels(limit int) *functionLabels {
+	return &functionLabels{limit: limit, known: map[string]bool{}}
+}
+
+// label returns the function label for functionName, or otherFunction and false once the
+// limit has been reached.
+func (l *functionLabels) label(functionName string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.known[functionName] {
+		return functionName, true
+	}
+	if len(l.known) < l.limit {
+		l.known[functionName] = true
+		return functionName, true
+	}
+	return otherFunction, false
+}
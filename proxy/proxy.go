@@ -22,15 +22,25 @@
 package proxy
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
+	"strings"
+	"syscall"
 	"time"
 
-	"github.com/gorilla/mux"
 	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/logging"
+	"github.com/openfaas/faas-provider/resolver"
+	"github.com/openfaas/faas-provider/scaling"
+	"github.com/openfaas/faas-provider/timing"
+	"github.com/openfaas/faas-provider/tracing"
 	"github.com/openfaas/faas-provider/types"
 )
 
@@ -48,14 +58,88 @@ type BaseURLResolver interface {
 	Resolve(functionName string) (url.URL, error)
 }
 
+// AnnotationResolver may be implemented by a BaseURLResolver which can look up the annotations
+// of a function. The headers declared with types.ResponseHeaderAnnotationPrefix are then set on
+// every response proxied from the function, and the timeout declared with
+// types.FunctionTimeoutAnnotation is applied to its invocations.
+type AnnotationResolver interface {
+	ResolveAnnotations(functionName string) (map[string]string, error)
+}
+
+// Error describes why a request could not be proxied to a function. It is passed to
+// the ProxyErrorHandler set in types.FaaSConfig and can be inspected with errors.As.
+type Error struct {
+	// FunctionName is the name of the function the request was addressed to.
+	FunctionName string
+
+	// StatusCode is the status code written by the default error handler.
+	StatusCode int
+
+	// Message is the response body written by the default error handler.
+	Message string
+
+	// Err is the underlying resolver or transport error.
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Message, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// defaultErrorHandler writes the status code and message of a proxy Error with
+// httputil.WriteErrorCode, with FunctionNotFound for a function which does not exist and
+// Timeout for one which did not respond in time.
+func defaultErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	proxyErr, ok := err.(*Error)
+	if !ok {
+		httputil.WriteErrorCode(w, r, http.StatusInternalServerError, httputil.InternalError, err.Error())
+		return
+	}
+
+	var code httputil.ErrorCode
+	switch {
+	case errors.Is(proxyErr.Err, ErrFunctionNotFound):
+		code = httputil.FunctionNotFound
+	case proxyErr.StatusCode == http.StatusGatewayTimeout:
+		code = httputil.Timeout
+	}
+	httputil.WriteErrorCode(w, r, proxyErr.StatusCode, code, proxyErr.Message)
+}
+
 // NewHandlerFunc creates a standard http.HandlerFunc to proxy function requests.
 // The returned http.HandlerFunc will ensure:
 //
 //   - proper proxy request timeouts
 //   - proxy requests for GET, POST, PATCH, PUT, and DELETE
+//   - streaming request and response bodies without buffering them in memory
 //   - path parsing including support for extracing the function name, sub-paths, and query paremeters
 //   - passing and setting the `X-Forwarded-Host` and `X-Forwarded-For` headers
 //   - logging errors and proxy request timing to stdout
+//   - writing upstream failures with config.ProxyErrorHandler, when set
+//   - balancing requests across instances when the resolver implements MultiURLResolver,
+//     using the strategy in config.ProxyLoadBalancing, or with the Strategy of a
+//     resolver.Resolver passed through FromResolver
+//   - returning 503 with a Retry-After header when resolving a function takes longer than
+//     config.ColdStartMaxWait
+//   - sending requests for functions which are not found to config.DefaultFunction
+//   - applying the timeout declared in the function's annotations or labels, up to
+//     config.ProxyMaxTimeout, when the resolver implements AnnotationResolver or LabelResolver,
+//     and returning 504 when the function does not respond within it
+//   - setting response headers declared in the function's annotations, when the resolver
+//     implements AnnotationResolver
+//   - sending gRPC requests to the function over HTTP/2 cleartext, see NewGRPCHandler, and
+//     every other request which is not an upgrade when config.ProxyH2C is set
+//   - passing WebSocket and other upgraded connections through to the function, and, with
+//     config.EnableStreaming, lifting the timeouts for them and for Server-Sent Events
+//   - setting the `traceparent` header to the span of the request, when config.TracingEndpoint
+//     is set
+//   - retrying idempotent requests which could not reach the function, with config.ProxyRetry
+//   - rejecting requests with 503 while the circuit breaker of a function which keeps failing
+//     is open, with config.ProxyCircuitBreaker
 //
 // Note that this will panic if `resolver` is nil.
 func NewHandlerFunc(config types.FaaSConfig, resolver BaseURLResolver) http.HandlerFunc {
@@ -63,38 +147,139 @@ func NewHandlerFunc(config types.FaaSConfig, resolver BaseURLResolver) http.Hand
 		panic("NewHandlerFunc: empty proxy handler resolver, cannot be nil")
 	}
 
-	proxyClient := NewProxyClientFromConfig(config)
+	return NewHandler(config, resolver).ServeHTTP
+}
 
-	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Body != nil {
-			defer r.Body.Close()
-		}
+// Handler is the standard OpenFaaS proxy returned by NewHandlerFunc, it also gives access
+// to the proxy's internal state for debugging, see StateHandler.
+type Handler struct {
+	p *functionProxy
+}
 
-		switch r.Method {
-		case http.MethodPost,
-			http.MethodPut,
-			http.MethodPatch,
-			http.MethodDelete,
-			http.MethodGet,
-			http.MethodOptions,
-			http.MethodHead:
-			proxyRequest(w, r, proxyClient, resolver)
+// NewHandler creates the proxy returned by NewHandlerFunc, use it when the proxy's state
+// should be exposed with StateHandler.
+//
+// Note that this will panic if `resolver` is nil.
+func NewHandler(config types.FaaSConfig, resolver BaseURLResolver) *Handler {
+	if resolver == nil {
+		panic("NewHandler: empty proxy handler resolver, cannot be nil")
+	}
 
-		default:
-			w.WriteHeader(http.StatusMethodNotAllowed)
-		}
+	return &Handler{p: newFunctionProxy(config, resolver)}
+}
+
+// ServeHTTP proxies the request to the function named in the path.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Body != nil {
+		defer r.Body.Close()
+	}
+
+	switch r.Method {
+	case http.MethodPost,
+		http.MethodPut,
+		http.MethodPatch,
+		http.MethodDelete,
+		http.MethodGet,
+		http.MethodOptions,
+		http.MethodHead:
+		h.p.proxyRequest(w, r)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// functionProxy holds the clients and settings used to proxy requests to functions.
+type functionProxy struct {
+	client           *http.Client
+	grpcClient       *http.Client
+	streamClient     *http.Client
+	upgradeClient    *http.Client
+	h2c              bool
+	streaming        bool
+	resolver         BaseURLResolver
+	lb               *resolver.Balancer
+	coldStartMaxWait time.Duration
+	defaultFunction  string
+	timeouts         *timeoutCache
+	retry            *types.ProxyRetryPolicy
+	breaker          *circuitBreaker
+	labels           *functionLabels
+	errorHandler     func(http.ResponseWriter, *http.Request, error)
+}
+
+func newFunctionProxy(config types.FaaSConfig, resolver BaseURLResolver) *functionProxy {
+	errorHandler := defaultErrorHandler
+	if config.ProxyErrorHandler != nil {
+		errorHandler = config.ProxyErrorHandler
+	}
+
+	// The client must allow the longest timeout a function may declare, the timeout of each
+	// invocation is then set on the context of the request to the function.
+	client := NewProxyClientFromConfig(config)
+	grpcClient := newH2CClient(config.GetReadTimeout(), config.GetNetwork(), config.GetHTTP2())
+	if config.ProxyMaxTimeout > config.GetReadTimeout() {
+		client = newProxyClient(config.ProxyMaxTimeout, config.GetMaxIdleConns(), config.GetMaxIdleConnsPerHost(), config.GetNetwork())
+		grpcClient = newH2CClient(config.ProxyMaxTimeout, config.GetNetwork(), config.GetHTTP2())
+	}
+
+	// Upgrades only exist in HTTP/1.1, so they keep its transport when ProxyH2C is set.
+	upgradeClient := &http.Client{
+		Transport:     client.Transport,
+		CheckRedirect: client.CheckRedirect,
+	}
+	if config.ProxyH2C {
+		client = grpcClient
+	}
+
+	// Shares the transport of client, and so its pool of connections.
+	streamClient := &http.Client{
+		Transport:     client.Transport,
+		CheckRedirect: client.CheckRedirect,
+	}
+
+	labels := newFunctionLabels(config.GetMaxFunctionMetrics())
+
+	return &functionProxy{
+		client:           client,
+		grpcClient:       grpcClient,
+		streamClient:     streamClient,
+		upgradeClient:    upgradeClient,
+		h2c:              config.ProxyH2C,
+		streaming:        config.EnableStreaming,
+		resolver:         resolver,
+		lb:               newBalancer(config.ProxyLoadBalancing),
+		coldStartMaxWait: config.ColdStartMaxWait,
+		defaultFunction:  config.DefaultFunction,
+		timeouts:         newTimeoutCache(config.GetReadTimeout(), config.ProxyMaxTimeout),
+		retry:            config.ProxyRetry,
+		breaker:          newCircuitBreaker(config.ProxyCircuitBreaker, labels),
+		labels:           labels,
+		errorHandler:     errorHandler,
 	}
 }
 
 // NewProxyClientFromConfig creates a new http.Client designed for proxying requests and enforcing
 // certain minimum configuration values.
 func NewProxyClientFromConfig(config types.FaaSConfig) *http.Client {
-	return NewProxyClient(config.GetReadTimeout(), config.GetMaxIdleConns(), config.GetMaxIdleConnsPerHost())
+	return newProxyClient(config.GetReadTimeout(), config.GetMaxIdleConns(), config.GetMaxIdleConnsPerHost(), config.GetNetwork())
 }
 
 // NewProxyClient creates a new http.Client designed for proxying requests, this is exposed as a
 // convenience method for internal or advanced uses. Most people should use NewProxyClientFromConfig.
 func NewProxyClient(timeout time.Duration, maxIdleConns int, maxIdleConnsPerHost int) *http.Client {
+	return newProxyClient(timeout, maxIdleConns, maxIdleConnsPerHost, "tcp")
+}
+
+// newProxyClient creates the client for NewProxyClient, dialing functions over network,
+// which is one of "tcp", "tcp4" or "tcp6".
+func newProxyClient(timeout time.Duration, maxIdleConns int, maxIdleConnsPerHost int, network string) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 1 * time.Second,
+		DualStack: true,
+	}
+
 	return &http.Client{
 		// these Transport values ensure that the http Client will eventually timeout and prevents
 		// infinite retries. The default http.Client configure these timeouts.  The specific
@@ -112,11 +297,9 @@ func NewProxyClient(timeout time.Duration, maxIdleConns int, maxIdleConnsPerHost
 		// https://github.com/minio/minio/pull/5860
 		Transport: &http.Transport{
 			Proxy: http.ProxyFromEnvironment,
-			DialContext: (&net.Dialer{
-				Timeout:   timeout,
-				KeepAlive: 1 * time.Second,
-				DualStack: true,
-			}).DialContext,
+			DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
 			MaxIdleConns:          maxIdleConns,
 			MaxIdleConnsPerHost:   maxIdleConnsPerHost,
 			IdleConnTimeout:       120 * time.Millisecond,
@@ -131,27 +314,129 @@ func NewProxyClient(timeout time.Duration, maxIdleConns int, maxIdleConnsPerHost
 }
 
 // proxyRequest handles the actual resolution of and then request to the function service.
-func proxyRequest(w http.ResponseWriter, originalReq *http.Request, proxyClient *http.Client, resolver BaseURLResolver) {
+func (p *functionProxy) proxyRequest(w http.ResponseWriter, originalReq *http.Request) {
 	ctx := originalReq.Context()
+	logger := logging.FromContext(ctx)
+	resolver, lb, coldStartMaxWait, errorHandler := p.resolver, p.lb, p.coldStartMaxWait, p.errorHandler
 
-	pathVars := mux.Vars(originalReq)
+	proxyClient := p.client
+	grpc := isGRPCRequest(originalReq)
+	if grpc {
+		proxyClient = p.grpcClient
+	}
+
+	pathVars := httputil.PathVars(originalReq)
 	functionName := pathVars["name"]
 	if functionName == "" {
-		httputil.Errorf(w, http.StatusBadRequest, "Provide function name in the request path")
+		httputil.WriteErrorCode(w, originalReq, http.StatusBadRequest, httputil.InvalidRequest, "Provide function name in the request path")
+		return
+	}
+
+	// Invocations of a function whose circuit is open are rejected before it is resolved,
+	// so that a function which keeps failing is given time to recover.
+	breakerDone, retryAfter, allowed := p.breaker.allow(functionName)
+	if !allowed {
+		logger.Warn("Circuit breaker is open", "function", functionName)
+		httputil.SetRetryAfter(w, retryAfter)
+		errorHandler(w, originalReq, &Error{
+			FunctionName: functionName,
+			StatusCode:   http.StatusServiceUnavailable,
+			Message:      fmt.Sprintf("%s is unavailable, retry later.", functionName),
+			Err:          ErrCircuitOpen,
+		})
+		return
+	}
+	result := breakerSkipped
+	defer func() { breakerDone(result) }()
+
+	// Invocations addressed to an instance are sent to it alone, without balancing or
+	// falling back to the default function.
+	var functionAddr url.URL
+	var release func(failed bool)
+	var resolveErr error
+	resolved := timing.Start(ctx, timing.Resolve)
+	instance := httputil.InstanceFromRequest(originalReq)
+	if len(instance) > 0 {
+		instanceResolver, ok := resolver.(InstanceResolver)
+		if !ok {
+			httputil.WriteErrorCode(w, originalReq, http.StatusNotImplemented, httputil.NotImplemented, httputil.InstanceHeader+" is not supported by this provider")
+			return
+		}
+
+		functionAddr, resolveErr = instanceResolver.ResolveInstance(functionName, instance)
+		release = func(bool) {}
+		if errors.Is(resolveErr, ErrInstanceNotFound) || errors.Is(resolveErr, ErrFunctionNotFound) {
+			logger.Warn("Function instance not found", "function", functionName, "instance", instance, "error", resolveErr)
+			errorHandler(w, originalReq, &Error{
+				FunctionName: functionName,
+				StatusCode:   http.StatusNotFound,
+				Message:      fmt.Sprintf("Instance %s of function %s not found.", instance, functionName),
+				Err:          resolveErr,
+			})
+			return
+		}
+	} else {
+		functionAddr, release, resolveErr = resolveWithinColdStart(ctx, resolver, lb, functionName, coldStartMaxWait)
+	}
+
+	// Requests for a function which does not exist are sent to the default function, when
+	// configured, so that it can act as a catch-all.
+	originalName := ""
+	if errors.Is(resolveErr, ErrFunctionNotFound) && len(p.defaultFunction) > 0 && functionName != p.defaultFunction {
+		originalName = functionName
+		functionName = p.defaultFunction
+		functionAddr, release, resolveErr = resolveWithinColdStart(ctx, resolver, lb, functionName, coldStartMaxWait)
+	}
+	resolved()
+
+	if errors.Is(resolveErr, ErrColdStartTimeout) {
+		logger.Warn("Function was not ready within the cold start wait", "function", functionName, "wait", coldStartMaxWait)
+		tracing.AddEvent(ctx, tracing.EventColdStart, map[string]string{
+			"faas.function":          functionName,
+			"faas.cold_start.result": "timeout",
+		})
+		httputil.SetRetryAfter(w, coldStartMaxWait)
+		errorHandler(w, originalReq, &Error{
+			FunctionName: functionName,
+			StatusCode:   http.StatusServiceUnavailable,
+			Message:      fmt.Sprintf("%s is starting, retry later.", functionName),
+			Err:          resolveErr,
+		})
+		return
+	}
+
+	if errors.Is(resolveErr, ErrFunctionNotFound) {
+		logger.Warn("Function not found", "function", functionName, "error", resolveErr)
+		errorHandler(w, originalReq, &Error{
+			FunctionName: functionName,
+			StatusCode:   http.StatusNotFound,
+			Message:      fmt.Sprintf("Function not found: %s.", functionName),
+			Err:          resolveErr,
+		})
 		return
 	}
 
-	functionAddr, resolveErr := resolver.Resolve(functionName)
 	if resolveErr != nil {
 		// TODO: Should record the 404/not found error in Prometheus.
-		log.Printf("resolver error: no endpoints for %s: %s\n", functionName, resolveErr.Error())
-		httputil.Errorf(w, http.StatusServiceUnavailable, "No endpoints available for: %s.", functionName)
+		logger.Warn("No endpoints available for function", "function", functionName, "error", resolveErr)
+		errorHandler(w, originalReq, &Error{
+			FunctionName: functionName,
+			StatusCode:   http.StatusServiceUnavailable,
+			Message:      fmt.Sprintf("No endpoints available for: %s.", functionName),
+			Err:          resolveErr,
+		})
 		return
 	}
 
 	proxyReq, err := buildProxyRequest(originalReq, functionAddr, pathVars["params"])
 	if err != nil {
-		httputil.Errorf(w, http.StatusInternalServerError, "Failed to resolve service: %s.", functionName)
+		release(false)
+		errorHandler(w, originalReq, &Error{
+			FunctionName: functionName,
+			StatusCode:   http.StatusInternalServerError,
+			Message:      fmt.Sprintf("Failed to resolve service: %s.", functionName),
+			Err:          err,
+		})
 		return
 	}
 
@@ -159,14 +444,136 @@ func proxyRequest(w http.ResponseWriter, originalReq *http.Request, proxyClient
 		defer proxyReq.Body.Close()
 	}
 
+	if len(originalName) > 0 {
+		proxyReq.Header.Set(OriginalFunctionHeader, originalName)
+	}
+
+	// The function's spans join the trace as children of the provider's span
+	if span := tracing.SpanFromContext(ctx); span != nil {
+		proxyReq.Header.Set(tracing.TraceParentHeader, span.Context.TraceParent())
+		if len(span.Context.TraceState) > 0 {
+			proxyReq.Header.Set(tracing.TraceStateHeader, span.Context.TraceState)
+		} else {
+			proxyReq.Header.Del(tracing.TraceStateHeader)
+		}
+	}
+
+	timeout := p.timeouts.timeoutFor(ctx, resolver, functionName)
+	upgrade := isUpgradeRequest(originalReq)
+
+	// With streaming enabled the timeout only applies until the function responds, so that
+	// it can be lifted for upgraded connections and event streams. The client of the proxy
+	// must then not have a timeout of its own, nor may it for upgrades, as it would hide
+	// the connection behind the response body.
+	var cancel context.CancelFunc
+	var deadline *responseDeadline
+	if p.streaming && !grpc {
+		ctx, cancel = context.WithCancel(ctx)
+		deadline = newResponseDeadline(timeout, cancel)
+	} else {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+
+	if (p.streaming || upgrade) && !grpc {
+		proxyClient = p.streamClient
+		if upgrade {
+			proxyClient = p.upgradeClient
+		}
+	}
+
+	// HTTP/2 rejects the connection headers of HTTP/1.1, which only upgrades need.
+	if p.h2c && !upgrade && !grpc {
+		removeHopByHopHeaders(proxyReq.Header)
+	}
+
+	retry := p.retry != nil && isIdempotent(originalReq.Method) && !upgrade && !grpc
+	var body *unreadBody
+	if retry && proxyReq.Body != nil {
+		body = &unreadBody{body: proxyReq.Body}
+		proxyReq.Body = body
+	}
+
+	ctx = timing.WithClientTrace(ctx)
 	start := time.Now()
-	response, err := proxyClient.Do(proxyReq.WithContext(ctx))
+	response, err := proxyClient.Do(proxyReq.WithContext(httptrace.WithClientTrace(ctx, withInformationalResponses(w))))
+	// An instance is only marked as failed when it could not be reached, not when the
+	// client went away. An instance which responded is released once its body has been
+	// copied, so that least-connections counts streaming responses as in flight.
+	if err != nil {
+		release(!isClientDisconnect(originalReq, err))
+	}
+
+	// Invocations which could not reach the function are sent again as long as none of
+	// the body was sent, to another instance when the resolver returns every instance.
+	for attempt := 1; err != nil && retry && attempt < p.retry.GetMaxAttempts(); attempt++ {
+		if isTimeout(err) || isClientDisconnect(originalReq, err) || (body != nil && body.read.Load()) {
+			break
+		}
+		if !sleepContext(ctx, p.retry.GetBackoff(attempt)) {
+			break
+		}
+
+		if len(instance) == 0 {
+			resolved := timing.Start(ctx, timing.Resolve)
+			addr, nextRelease, resolveErr := resolveFunction(ctx, resolver, lb, functionName)
+			resolved()
+			if resolveErr != nil {
+				break
+			}
+			functionAddr, release = addr, nextRelease
+		}
+
+		next, buildErr := buildProxyRequest(originalReq, functionAddr, pathVars["params"])
+		if buildErr != nil {
+			release(false)
+			break
+		}
+		proxyReq.URL, proxyReq.Host = next.URL, next.Host
+
+		logger.Warn("Retrying request", "function", functionName, "attempt", attempt+1, "error", err)
+		label, _ := p.labels.label(functionName)
+		retriesTotal.WithLabelValues(label).Inc()
+
+		response, err = proxyClient.Do(proxyReq.WithContext(httptrace.WithClientTrace(ctx, withInformationalResponses(w))))
+		if err != nil {
+			release(!isClientDisconnect(originalReq, err))
+		}
+	}
 	seconds := time.Since(start)
+	if err == nil {
+		defer release(false)
+	}
+
+	if deadline != nil {
+		deadline.stop()
+	}
 
 	if err != nil {
-		log.Printf("error with proxy request to: %s, %s\n", proxyReq.URL.String(), err.Error())
+		timedOut := deadline != nil && deadline.expired()
+
+		// The client went away before the function responded, so there is nobody to
+		// report the error to and the function is not at fault.
+		if !timedOut && isClientDisconnect(originalReq, err) {
+			return
+		}
+
+		logger.Error("Unable to proxy request", "function", functionName, "url", proxyReq.URL.String(), "error", err)
+		result = breakerFailed
 
-		httputil.Errorf(w, http.StatusInternalServerError, "Can't reach service for: %s.", functionName)
+		statusCode := http.StatusBadGateway
+		message := fmt.Sprintf("Can't reach service for: %s.", functionName)
+		if timedOut || isTimeout(err) {
+			statusCode = http.StatusGatewayTimeout
+			message = fmt.Sprintf("Function %s did not respond within its timeout of %s.", functionName, timeout)
+		}
+
+		errorHandler(w, originalReq, &Error{
+			FunctionName: functionName,
+			StatusCode:   statusCode,
+			Message:      message,
+			Err:          err,
+		})
 		return
 	}
 
@@ -174,18 +581,172 @@ func proxyRequest(w http.ResponseWriter, originalReq *http.Request, proxyClient
 		defer response.Body.Close()
 	}
 
-	log.Printf("%s took %f seconds\n", functionName, seconds.Seconds())
+	logger.Info("Function invoked", "function", functionName, "status", response.StatusCode, "duration", seconds)
 
+	switch response.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		result = breakerFailed
+	default:
+		result = breakerSucceeded
+	}
+
+	if response.StatusCode == http.StatusSwitchingProtocols {
+		if err := switchProtocols(w, originalReq, response, p.streaming); err != nil {
+			logger.Error("Unable to switch protocols", "function", functionName, "error", err)
+		}
+		return
+	}
+
+	if deadline != nil {
+		if isEventStream(response) {
+			liftDeadlines(w)
+		} else {
+			deadline.restart(timeout - time.Since(start))
+			defer deadline.stop()
+		}
+	}
+
+	// The status, headers and body of the function are returned verbatim, only the
+	// hop-by-hop headers which apply to the connection with the function are dropped.
 	clientHeader := w.Header()
 	copyHeaders(clientHeader, &response.Header)
+	removeHopByHopHeaders(clientHeader)
 	w.Header().Set("Content-Type", getContentType(originalReq.Header, response.Header))
+	setAnnotationHeaders(logger, clientHeader, resolver, functionName)
 
 	w.WriteHeader(response.StatusCode)
 	if response.Body != nil {
-		io.Copy(w, response.Body)
+		var copyErr error
+		if grpc || isStreamingResponse(response) {
+			copyErr = copyWithFlush(w, response.Body)
+		} else {
+			_, copyErr = io.Copy(w, response.Body)
+		}
+
+		if copyErr != nil && !isClientDisconnect(originalReq, copyErr) {
+			logger.Error("Unable to copy response", "function", functionName, "error", copyErr)
+		}
+	}
+
+	// Trailers, such as grpc-status, are only known once the body has been read
+	for k, v := range response.Trailer {
+		w.Header()[http.TrailerPrefix+k] = v
 	}
 }
 
+// setAnnotationHeaders sets the response headers declared in the annotations of the function,
+// when the resolver implements AnnotationResolver. They take precedence over the headers
+// written by the function.
+func setAnnotationHeaders(logger *slog.Logger, header http.Header, resolver BaseURLResolver, functionName string) {
+	annotationResolver, ok := resolver.(AnnotationResolver)
+	if !ok {
+		return
+	}
+
+	annotations, err := annotationResolver.ResolveAnnotations(functionName)
+	if err != nil {
+		logger.Warn("Unable to resolve annotations", "function", functionName, "error", err)
+		return
+	}
+
+	for k, v := range types.ResponseHeadersFromAnnotations(annotations) {
+		header[k] = v
+	}
+}
+
+// ErrFunctionNotFound should be returned, or wrapped, by a BaseURLResolver when the function
+// is not deployed. The request is then sent to FaaSConfig.DefaultFunction when set, otherwise
+// a 404 is returned.
+var ErrFunctionNotFound = errors.New("function not found")
+
+// OriginalFunctionHeader carries the name of the function a request was addressed to when
+// it was sent to FaaSConfig.DefaultFunction instead.
+const OriginalFunctionHeader = "X-Original-Function"
+
+// ErrColdStartTimeout is wrapped by the Error passed to the error handler when the resolver
+// did not return within FaaSConfig.ColdStartMaxWait, i.e. because the function is still
+// being scaled up from zero, or when scaling.Wake returned scaling.ErrNotReady.
+var ErrColdStartTimeout = errors.New("function was not ready within the cold start wait")
+
+// resolveWithinColdStart wakes and resolves the function, giving up with ErrColdStartTimeout
+// when the wake up or the resolver, which may be waiting for the function to scale from
+// zero, takes longer than maxWait. A maxWait of zero waits for as long as they take.
+func resolveWithinColdStart(ctx context.Context, base BaseURLResolver, lb *resolver.Balancer, functionName string, maxWait time.Duration) (url.URL, func(failed bool), error) {
+	if maxWait <= 0 {
+		return wakeAndResolve(ctx, base, lb, functionName)
+	}
+
+	type result struct {
+		addr    url.URL
+		release func(failed bool)
+		err     error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		addr, release, err := wakeAndResolve(ctx, base, lb, functionName)
+		done <- result{addr, release, err}
+	}()
+
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+
+	select {
+	case res := <-done:
+		return res.addr, res.release, res.err
+	case <-timer.C:
+		// release the instance once the abandoned resolution completes
+		go func() {
+			if res := <-done; res.err == nil {
+				res.release(false)
+			}
+		}()
+		return url.URL{}, nil, ErrColdStartTimeout
+	}
+}
+
+// wakeAndResolve wakes the function with the scaling.Scaler on ctx, when there is one,
+// before resolving it. A function which was not ready within the Scaler's timeout returns
+// ErrColdStartTimeout, as when maxWait is exceeded.
+func wakeAndResolve(ctx context.Context, base BaseURLResolver, lb *resolver.Balancer, functionName string) (url.URL, func(failed bool), error) {
+	if err := scaling.Wake(ctx, functionName); err != nil {
+		if errors.Is(err, scaling.ErrNotReady) {
+			return url.URL{}, nil, fmt.Errorf("%w: %w", ErrColdStartTimeout, err)
+		}
+		return url.URL{}, nil, err
+	}
+
+	return resolveFunction(ctx, base, lb, functionName)
+}
+
+// resolveFunction resolves the address of the function with the resolver.Resolver when
+// base was created by FromResolver, or balancing requests between its instances when base
+// implements MultiURLResolver. The returned release func must be called once the request
+// to the function has completed.
+func resolveFunction(ctx context.Context, base BaseURLResolver, lb *resolver.Balancer, functionName string) (url.URL, func(failed bool), error) {
+	if r, ok := base.(*contextResolver); ok {
+		return r.resolve(ctx, functionName)
+	}
+
+	multiResolver, ok := base.(MultiURLResolver)
+	if !ok {
+		functionAddr, err := base.Resolve(functionName)
+		return functionAddr, func(bool) {}, err
+	}
+
+	instances, err := multiResolver.ResolveAll(functionName)
+	if err != nil {
+		return url.URL{}, nil, err
+	}
+
+	if len(instances) == 0 {
+		return url.URL{}, nil, fmt.Errorf("no instances found for %s", functionName)
+	}
+
+	functionAddr, release := lb.Pick(ctx, functionName, instances)
+	return functionAddr, release, nil
+}
+
 // buildProxyRequest creates a request object for the proxy request, it will ensure that
 // the original request headers are preserved as well as setting openfaas system headers
 func buildProxyRequest(originalReq *http.Request, baseURL url.URL, extraPath string) (*http.Request, error) {
@@ -215,8 +776,12 @@ func buildProxyRequest(originalReq *http.Request, baseURL url.URL, extraPath str
 		upstreamReq.Header["X-Forwarded-For"] = []string{originalReq.RemoteAddr}
 	}
 
+	// The body is streamed to the function as it is read from the client rather than
+	// being buffered in memory, the length is kept so that the function receives the
+	// same Content-Length, or a chunked body when the length is unknown.
 	if originalReq.Body != nil {
 		upstreamReq.Body = originalReq.Body
+		upstreamReq.ContentLength = originalReq.ContentLength
 	}
 
 	return upstreamReq, nil
@@ -231,6 +796,58 @@ func copyHeaders(destination http.Header, source *http.Header) {
 	}
 }
 
+// hopByHopHeaders apply to a single connection and must not be forwarded by a proxy,
+// see RFC 7230 section 6.1.
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// removeHopByHopHeaders deletes the hop-by-hop headers, including any listed in the
+// Connection header, from header.
+func removeHopByHopHeaders(header http.Header) {
+	for _, v := range header.Values("Connection") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); len(name) > 0 {
+				header.Del(name)
+			}
+		}
+	}
+
+	for _, name := range hopByHopHeaders {
+		header.Del(name)
+	}
+}
+
+// isClientDisconnect reports whether err was caused by the client of r going away, such
+// as closing the connection while the response was being streamed to it.
+func isClientDisconnect(r *http.Request, err error) bool {
+	if errors.Is(r.Context().Err(), context.Canceled) {
+		return true
+	}
+
+	return errors.Is(err, context.Canceled) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, http.ErrAbortHandler)
+}
+
+// isTimeout reports whether err was caused by the proxy request timing out.
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
 // getContentType resolves the correct Content-Type for a proxied function.
 func getContentType(request http.Header, proxyResponse http.Header) (headerContentType string) {
 	responseHeader := proxyResponse.Get("Content-Type")
@@ -2,16 +2,23 @@ package proxy
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/resolver"
+	"github.com/openfaas/faas-provider/timing"
 	"github.com/openfaas/faas-provider/types"
 )
 
@@ -528,3 +535,314 @@ func Test_NewProxyClientConfig(t *testing.T) {
 		})
 	}
 }
+
+func Test_proxyRequest_UpstreamStatusIsVerbatim(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Function-Header", "teapot")
+		w.Header().Set("Keep-Alive", "timeout=5")
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("I'm a teapot"))
+	}))
+	defer upstream.Close()
+
+	u, err := url.Parse(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := types.FaaSConfig{ReadTimeout: 1 * time.Second}
+	proxyHandler := NewHandlerFunc(config, mockResolver{u, nil})
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/function/teapot", nil)
+	req = mux.SetURLVars(req, map[string]string{"name": "teapot"})
+	proxyHandler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusTeapot {
+		t.Errorf("status code, want: %d, got: %d", http.StatusTeapot, rr.Code)
+	}
+
+	if got := rr.Body.String(); got != "I'm a teapot" {
+		t.Errorf("body, want: %q, got: %q", "I'm a teapot", got)
+	}
+
+	if got := rr.Header().Get("X-Function-Header"); got != "teapot" {
+		t.Errorf("X-Function-Header, want: %q, got: %q", "teapot", got)
+	}
+
+	if got := rr.Header().Get("Keep-Alive"); got != "" {
+		t.Errorf("want hop-by-hop Keep-Alive header to be removed, got: %q", got)
+	}
+}
+
+// instanceResolver resolves the instances of a function by their ID.
+type instanceResolver struct {
+	mockResolver
+	instances map[string]*url.URL
+}
+
+func (m instanceResolver) ResolveInstance(name, instance string) (url.URL, error) {
+	u, ok := m.instances[instance]
+	if !ok {
+		return url.URL{}, fmt.Errorf("%s: %w", instance, ErrInstanceNotFound)
+	}
+	return *u, nil
+}
+
+func Test_proxyRequest_InstanceHeader(t *testing.T) {
+	newUpstream := func(id string) *url.URL {
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(id))
+		}))
+		t.Cleanup(upstream.Close)
+
+		u, _ := url.Parse(upstream.URL)
+		return u
+	}
+
+	shared, instanceA := newUpstream("shared"), newUpstream("figlet-a")
+	targeted := instanceResolver{mockResolver: mockResolver{u: shared}, instances: map[string]*url.URL{"figlet-a": instanceA}}
+
+	testCases := []struct {
+		name     string
+		resolver BaseURLResolver
+		instance string
+		wantCode int
+		wantBody string
+	}{
+		{name: "any instance", resolver: targeted, wantCode: http.StatusOK, wantBody: "shared"},
+		{name: "targeted instance", resolver: targeted, instance: "figlet-a", wantCode: http.StatusOK, wantBody: "figlet-a"},
+		{name: "unknown instance", resolver: targeted, instance: "figlet-b", wantCode: http.StatusNotFound},
+		{name: "unsupported", resolver: mockResolver{u: shared}, instance: "figlet-a", wantCode: http.StatusNotImplemented},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			proxyHandler := NewHandlerFunc(types.FaaSConfig{ReadTimeout: time.Second}, tc.resolver)
+
+			req := httptest.NewRequest(http.MethodGet, "/function/figlet", nil)
+			req = mux.SetURLVars(req, map[string]string{"name": "figlet"})
+			if len(tc.instance) > 0 {
+				req.Header.Set(httputil.InstanceHeader, tc.instance)
+			}
+
+			rr := httptest.NewRecorder()
+			proxyHandler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantCode {
+				t.Fatalf("status code, want: %d, got: %d (%s)", tc.wantCode, rr.Code, rr.Body.String())
+			}
+			if len(tc.wantBody) > 0 && rr.Body.String() != tc.wantBody {
+				t.Errorf("body, want: %q, got: %q", tc.wantBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func Test_proxyRequest_TransportErrors(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closedURL, _ := url.Parse(closed.URL)
+	closed.Close()
+
+	slowURL, _ := url.Parse(slow.URL)
+
+	testCases := []struct {
+		name     string
+		upstream *url.URL
+		wantCode int
+		wantBody string
+	}{
+		{name: "unreachable upstream", upstream: closedURL, wantCode: http.StatusBadGateway},
+		{name: "upstream timeout", upstream: slowURL, wantCode: http.StatusGatewayTimeout, wantBody: "Function foo did not respond within its timeout of 100ms."},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := types.FaaSConfig{ReadTimeout: 100 * time.Millisecond}
+			proxyHandler := NewHandlerFunc(config, mockResolver{tc.upstream, nil})
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/function/foo", nil)
+			req = mux.SetURLVars(req, map[string]string{"name": "foo"})
+			proxyHandler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d", tc.wantCode, rr.Code)
+			}
+			if got := errorMessage(t, rr.Body.Bytes()); len(tc.wantBody) > 0 && got != tc.wantBody {
+				t.Errorf("body, want: %q, got: %q", tc.wantBody, got)
+			}
+		})
+	}
+}
+
+// zeroReader produces n zero bytes without allocating them up front.
+type zeroReader struct {
+	remaining int64
+}
+
+func (z *zeroReader) Read(p []byte) (int, error) {
+	if z.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > z.remaining {
+		p = p[:z.remaining]
+	}
+	for i := range p {
+		p[i] = 0
+	}
+	z.remaining -= int64(len(p))
+	return len(p), nil
+}
+
+func Test_proxyRequest_StreamsLargeBody(t *testing.T) {
+	const bodySize = 64 * 1024 * 1024
+
+	var received int64
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := io.Copy(io.Discard, r.Body)
+		received = n
+		if r.ContentLength != bodySize {
+			t.Errorf("Content-Length, want: %d, got: %d", bodySize, r.ContentLength)
+		}
+	}))
+	defer upstream.Close()
+
+	u, err := url.Parse(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := types.FaaSConfig{ReadTimeout: 10 * time.Second}
+	proxyHandler := NewHandlerFunc(config, mockResolver{u, nil})
+
+	req := httptest.NewRequest(http.MethodPost, "/function/upload", io.NopCloser(&zeroReader{remaining: bodySize}))
+	req.ContentLength = bodySize
+	req = mux.SetURLVars(req, map[string]string{"name": "upload"})
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	rr := httptest.NewRecorder()
+	proxyHandler.ServeHTTP(rr, req)
+
+	runtime.ReadMemStats(&after)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code, want: %d, got: %d", http.StatusOK, rr.Code)
+	}
+
+	if received != bodySize {
+		t.Errorf("upstream received %d bytes, want: %d", received, bodySize)
+	}
+
+	// allocations from the client, server and test are far below the size of the body
+	// when it is streamed
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > bodySize/4 {
+		t.Errorf("proxy allocated %d bytes for a %d byte body, want it to be streamed", allocated, bodySize)
+	}
+}
+
+func Test_proxyRequest_ClientDisconnect(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	slowURL, _ := url.Parse(slow.URL)
+
+	errorHandlerCalled := false
+	config := types.FaaSConfig{
+		ReadTimeout: time.Second,
+		ProxyErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			errorHandlerCalled = true
+		},
+	}
+	proxyHandler := NewHandlerFunc(config, mockResolver{slowURL, nil})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	logs := &bytes.Buffer{}
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(logs)
+
+	req := httptest.NewRequest(http.MethodGet, "/function/foo", nil).WithContext(ctx)
+	req = mux.SetURLVars(req, map[string]string{"name": "foo"})
+	proxyHandler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if errorHandlerCalled {
+		t.Errorf("want the error handler not to be called when the client disconnects")
+	}
+
+	if strings.Contains(logs.String(), "error") {
+		t.Errorf("want no error to be logged when the client disconnects, got: %s", logs.String())
+	}
+}
+
+func Test_proxyRequest_TimingHeaders(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}))
+	defer upstream.Close()
+
+	u, err := url.Parse(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := types.FaaSConfig{ReadTimeout: 1 * time.Second}
+	proxyHandler := timing.Decorate(NewHandlerFunc(config, mockResolver{u, nil}), true)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/function/figlet", nil)
+	req = mux.SetURLVars(req, map[string]string{"name": "figlet"})
+	proxyHandler.ServeHTTP(rr, req)
+
+	for _, phase := range []timing.Phase{timing.Resolve, timing.Connect, timing.FirstByte} {
+		if got := rr.Header().Get(timing.Header(phase)); len(got) == 0 {
+			t.Errorf("want %s header to be set", timing.Header(phase))
+		}
+	}
+	if got := rr.Header().Get(timing.Header(timing.Restore)); len(got) > 0 {
+		t.Errorf("%s, want: empty, got: %s", timing.Header(timing.Restore), got)
+	}
+}
+
+func Test_proxyRequest_ResolverMarksRestore(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}))
+	defer upstream.Close()
+
+	u, err := url.Parse(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := resolver.ResolverFunc(func(ctx context.Context, name, namespace string) (url.URL, resolver.ReleaseFunc, error) {
+		timing.Observe(ctx, timing.Restore, 250*time.Millisecond)
+		return *u, nil, nil
+	})
+
+	config := types.FaaSConfig{ReadTimeout: 1 * time.Second}
+	proxyHandler := timing.Decorate(NewHandlerFunc(config, FromResolver(r)), true)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/function/figlet", nil)
+	req = mux.SetURLVars(req, map[string]string{"name": "figlet"})
+	proxyHandler.ServeHTTP(rr, req)
+
+	if got := rr.Header().Get(timing.Header(timing.Restore)); got != "0.250000" {
+		t.Errorf("%s, want: %s, got: %q", timing.Header(timing.Restore), "0.250000", got)
+	}
+}
@@ -0,0 +1,58 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var retriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Subsystem: "provider",
+	Name:      "proxy_retries_total",
+	Help:      "Total number of invocations sent again after they could not reach the function.",
+}, []string{"function"})
+
+// isIdempotent reports whether a request with method may be sent more than once, see
+// RFC 9110 section 9.2.2.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// unreadBody passes the body of a request to the function, recording whether any of it
+// was read, so that the request can be sent again when an attempt failed before sending
+// it. Close is left to the handler, as the transport closes the body of a failed request.
+type unreadBody struct {
+	body io.ReadCloser
+	read atomic.Bool
+}
+
+func (b *unreadBody) Read(p []byte) (int, error) {
+	b.read.Store(true)
+	return b.body.Read(p)
+}
+
+func (b *unreadBody) Close() error {
+	return nil
+}
+
+// sleepContext waits for d, it returns false when ctx is done first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,139 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// multiResolver returns every instance of a function, in order.
+type multiResolver struct {
+	instances []url.URL
+}
+
+func (m multiResolver) Resolve(name string) (url.URL, error) {
+	return m.instances[0], nil
+}
+
+func (m multiResolver) ResolveAll(name string) ([]url.URL, error) {
+	return m.instances, nil
+}
+
+func Test_ProxyHandler_Retry(t *testing.T) {
+	var calls int32
+	var gotBody atomic.Value
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		body, _ := io.ReadAll(r.Body)
+		gotBody.Store(string(body))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closedURL, _ := url.Parse(closed.URL)
+	closed.Close()
+
+	upstreamURL, _ := url.Parse(upstream.URL)
+
+	policy := &types.ProxyRetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}
+
+	testCases := []struct {
+		name      string
+		policy    *types.ProxyRetryPolicy
+		method    string
+		body      string
+		wantCode  int
+		wantCalls int32
+	}{
+		{name: "get is retried on another instance", policy: policy, method: http.MethodGet, wantCode: http.StatusOK, wantCalls: 1},
+		{name: "put is retried with its body", policy: policy, method: http.MethodPut, body: `{"name":"figlet"}`, wantCode: http.StatusOK, wantCalls: 1},
+		{name: "post is not retried", policy: policy, method: http.MethodPost, body: `{"name":"figlet"}`, wantCode: http.StatusBadGateway},
+		{name: "without a policy", method: http.MethodGet, wantCode: http.StatusBadGateway},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			atomic.StoreInt32(&calls, 0)
+			gotBody.Store("")
+
+			config := types.FaaSConfig{ReadTimeout: time.Second, ProxyRetry: tc.policy}
+			proxyHandler := NewHandlerFunc(config, multiResolver{instances: []url.URL{*closedURL, *upstreamURL}})
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, "/function/foo", strings.NewReader(tc.body))
+			req = mux.SetURLVars(req, map[string]string{"name": "foo"})
+			proxyHandler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d (%s)", tc.wantCode, rr.Code, rr.Body.String())
+			}
+			if got := atomic.LoadInt32(&calls); got != tc.wantCalls {
+				t.Errorf("calls, want: %d, got: %d", tc.wantCalls, got)
+			}
+			if tc.wantCalls > 0 && gotBody.Load() != tc.body {
+				t.Errorf("body, want: %q, got: %q", tc.body, gotBody.Load())
+			}
+		})
+	}
+}
+
+// signalWriter closes wrote on the first write of the body.
+type signalWriter struct {
+	*httptest.ResponseRecorder
+	once  sync.Once
+	wrote chan struct{}
+}
+
+func (s *signalWriter) Write(p []byte) (int, error) {
+	defer s.once.Do(func() { close(s.wrote) })
+	return s.ResponseRecorder.Write(p)
+}
+
+func Test_ProxyHandler_ReleasesAfterBody(t *testing.T) {
+	finish := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		<-finish
+		w.Write([]byte("last"))
+	}))
+	defer upstream.Close()
+
+	upstreamURL, _ := url.Parse(upstream.URL)
+	h := NewHandler(types.FaaSConfig{ReadTimeout: time.Second}, multiResolver{instances: []url.URL{*upstreamURL}})
+
+	w := &signalWriter{ResponseRecorder: httptest.NewRecorder(), wrote: make(chan struct{})}
+	req := httptest.NewRequest(http.MethodGet, "/function/foo", nil)
+	req = mux.SetURLVars(req, map[string]string{"name": "foo"})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.ServeHTTP(w, req)
+	}()
+
+	<-w.wrote
+	if state := h.p.lb.State(); len(state) != 1 || state[0].Active != 1 {
+		t.Errorf("instances while the body is copied, want one active, got: %+v", state)
+	}
+
+	close(finish)
+	<-done
+
+	if state := h.p.lb.State(); len(state) != 0 {
+		t.Errorf("instances after the body was copied, want none active, got: %+v", state)
+	}
+	if got := w.Body.String(); got != "firstlast" {
+		t.Errorf("body, want: %s, got: %s", "firstlast", got)
+	}
+}
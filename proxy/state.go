@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// State returns the timeouts cached for each function, the load and failures recorded
+// for each instance which requests have been balanced across and the circuit breakers of
+// functions which have failed.
+func (h *Handler) State() types.ProxyState {
+	return types.ProxyState{
+		Timeouts:  h.p.timeouts.state(),
+		Instances: h.p.lb.State(),
+		Circuits:  h.p.breaker.state(),
+	}
+}
+
+// Reset clears the cached timeouts, the recorded failures of instances and the circuit
+// breakers, so that they are looked up again and every instance is tried on the next
+// requests.
+func (h *Handler) Reset() {
+	h.p.timeouts.reset()
+	h.p.lb.Reset()
+	h.p.breaker.reset()
+}
+
+// StateHandler returns the proxy's State as JSON on GET, and clears it with Reset on
+// DELETE. Bind it to FaaSHandlers.ProxyState.
+func (h *Handler) StateHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodDelete:
+		h.Reset()
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := json.Marshal(h.State())
+	if err != nil {
+		httputil.WriteErrorCode(w, r, http.StatusInternalServerError, httputil.InternalError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+func (c *timeoutCache) state() []types.ProxyTimeoutState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	timeouts := make([]types.ProxyTimeoutState, 0, len(c.entries))
+	for name, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			continue
+		}
+
+		timeouts = append(timeouts, types.ProxyTimeoutState{
+			Function:  name,
+			Timeout:   entry.timeout.String(),
+			ExpiresAt: entry.expires,
+		})
+	}
+
+	sort.Slice(timeouts, func(i, j int) bool { return timeouts[i].Function < timeouts[j].Function })
+	return timeouts
+}
+
+func (c *timeoutCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = map[string]cachedTimeout{}
+}
@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_Handler_StateHandler(t *testing.T) {
+	resolver := &labelResolver{labels: map[string]map[string]string{
+		"figlet": {types.FunctionTimeoutLabel: "30s"},
+	}}
+
+	h := NewHandler(types.FaaSConfig{ProxyMaxTimeout: time.Minute}, resolver)
+	h.p.timeouts.timeoutFor(context.Background(), resolver, "figlet")
+
+	instance, _ := url.Parse("http://10.0.0.1:8080")
+	_, release := h.p.lb.Pick(context.Background(), "figlet", []url.URL{*instance})
+	release(true)
+
+	w := httptest.NewRecorder()
+	h.StateHandler(w, httptest.NewRequest(http.MethodGet, "/system/proxy/state", nil))
+
+	state := types.ProxyState{}
+	if err := json.Unmarshal(w.Body.Bytes(), &state); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(state.Timeouts) != 1 || state.Timeouts[0].Function != "figlet" || state.Timeouts[0].Timeout != "30s" {
+		t.Errorf("timeouts, want figlet with 30s, got: %+v", state.Timeouts)
+	}
+
+	if len(state.Instances) != 1 || state.Instances[0].Address != "http://10.0.0.1:8080" || !state.Instances[0].CoolingDown {
+		t.Errorf("instances, want a failed instance cooling down, got: %+v", state.Instances)
+	}
+
+	w = httptest.NewRecorder()
+	h.StateHandler(w, httptest.NewRequest(http.MethodDelete, "/system/proxy/state", nil))
+
+	state = types.ProxyState{}
+	if err := json.Unmarshal(w.Body.Bytes(), &state); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(state.Timeouts) != 0 || len(state.Instances) != 0 {
+		t.Errorf("want the state to be cleared, got: %+v", state)
+	}
+}
+
+func Test_Handler_StateHandler_MethodNotAllowed(t *testing.T) {
+	h := NewHandler(types.FaaSConfig{}, &testBaseURLResolver{})
+
+	w := httptest.NewRecorder()
+	h.StateHandler(w, httptest.NewRequest(http.MethodPost, "/system/proxy/state", nil))
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status code, want: %d, got: %d", http.StatusMethodNotAllowed, w.Code)
+	}
+}
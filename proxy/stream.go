@@ -0,0 +1,96 @@
+package proxy
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"sync/atomic"
+	"time"
+)
+
+// withInformationalResponses returns a trace which forwards the 1xx responses of the
+// function, such as 103 Early Hints, to w before the final response. 100 Continue is not
+// forwarded, as the server has already answered the client's Expect header by the time the
+// body is sent to the function.
+func withInformationalResponses(w http.ResponseWriter) *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			if code == http.StatusContinue {
+				return nil
+			}
+
+			// WriteHeader sends the current headers with a 1xx response and keeps them for
+			// the final response, so the headers set before are restored afterwards.
+			h := w.Header()
+			saved := h.Clone()
+			for k, v := range header {
+				h[k] = v
+			}
+			w.WriteHeader(code)
+
+			for k := range h {
+				delete(h, k)
+			}
+			for k, v := range saved {
+				h[k] = v
+			}
+			return nil
+		},
+	}
+}
+
+// isStreamingResponse reports whether the body of res should be flushed to the client as
+// it is read, rather than when the response writer's buffer fills, which is the case for
+// Server-Sent Events and bodies of unknown length.
+func isStreamingResponse(res *http.Response) bool {
+	return res.ContentLength == -1 || isEventStream(res)
+}
+
+// isEventStream reports whether res is a stream of Server-Sent Events.
+func isEventStream(res *http.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	return mediaType == "text/event-stream"
+}
+
+// liftDeadlines clears the read and write deadlines the server set from its ReadTimeout
+// and WriteTimeout, so that a stream is not cut off by them. It has no effect when w does
+// not support it.
+func liftDeadlines(w http.ResponseWriter) {
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+}
+
+// responseDeadline cancels a request to a function when the timeout passes, it is stopped
+// once the response headers are received and can then be restarted for the rest of the
+// response.
+type responseDeadline struct {
+	cancel context.CancelFunc
+	timer  *time.Timer
+	fired  atomic.Bool
+}
+
+func newResponseDeadline(timeout time.Duration, cancel context.CancelFunc) *responseDeadline {
+	d := &responseDeadline{cancel: cancel}
+	d.restart(timeout)
+	return d
+}
+
+func (d *responseDeadline) restart(timeout time.Duration) {
+	d.timer = time.AfterFunc(timeout, func() {
+		d.fired.Store(true)
+		d.cancel()
+	})
+}
+
+func (d *responseDeadline) stop() {
+	d.timer.Stop()
+}
+
+// expired reports whether the request was cancelled by the deadline, rather than by the
+// client going away.
+func (d *responseDeadline) expired() bool {
+	return d.fired.Load()
+}
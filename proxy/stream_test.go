@@ -0,0 +1,182 @@
+package proxy
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// newFrontend serves the proxy for the function at upstream from a real server, as 1xx
+// responses and flushing can not be observed with a ResponseRecorder.
+func newFrontend(t *testing.T, upstream *httptest.Server) *httptest.Server {
+	t.Helper()
+	return newFrontendWithConfig(t, upstream, types.FaaSConfig{ReadTimeout: 5 * time.Second})
+}
+
+// newFrontendWithConfig serves the proxy with config, the server is given the ReadTimeout
+// and WriteTimeout of config.
+func newFrontendWithConfig(t *testing.T, upstream *httptest.Server, config types.FaaSConfig) *httptest.Server {
+	t.Helper()
+
+	u, _ := url.Parse(upstream.URL)
+	proxyHandler := NewHandlerFunc(config, mockResolver{u: u})
+
+	frontend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxyHandler.ServeHTTP(w, mux.SetURLVars(r, map[string]string{"name": "figlet"}))
+	}))
+	frontend.Config.ReadTimeout = config.ReadTimeout
+	frontend.Config.WriteTimeout = config.WriteTimeout
+	frontend.Start()
+	t.Cleanup(frontend.Close)
+	return frontend
+}
+
+func Test_proxyRequest_ForwardsEarlyHints(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</style.css>; rel=preload; as=style")
+		w.WriteHeader(http.StatusEarlyHints)
+
+		w.Header().Del("Link")
+		w.Header().Set("X-Final", "true")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	frontend := newFrontend(t, upstream)
+
+	var hints []textproto.MIMEHeader
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			if code == http.StatusEarlyHints {
+				hints = append(hints, header)
+			}
+			return nil
+		},
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, frontend.URL, nil)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if len(hints) != 1 {
+		t.Fatalf("early hints, want: %d, got: %d", 1, len(hints))
+	}
+	if got := hints[0].Get("Link"); got != "</style.css>; rel=preload; as=style" {
+		t.Errorf("Link of early hints, want: %s, got: %s", "</style.css>; rel=preload; as=style", got)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("status code, want: %d, got: %d", http.StatusOK, res.StatusCode)
+	}
+	if res.Header.Get("Link") != "" {
+		t.Errorf("want the Link header of the early hints left out of the final response")
+	}
+	if res.Header.Get("X-Final") != "true" {
+		t.Errorf("want the headers of the final response")
+	}
+}
+
+func Test_proxyRequest_FlushesEventStream(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("data: first\n\n"))
+		w.(http.Flusher).Flush()
+
+		// The second event is only sent once the first has reached the client.
+		<-release
+		w.Write([]byte("data: second\n\n"))
+	}))
+	defer upstream.Close()
+	defer close(release)
+
+	frontend := newFrontend(t, upstream)
+
+	res, err := http.Get(frontend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	line := make(chan string, 1)
+	go func() {
+		l, _ := bufio.NewReader(res.Body).ReadString('\n')
+		line <- l
+	}()
+
+	select {
+	case got := <-line:
+		if got != "data: first\n" {
+			t.Errorf("first event, want: %q, got: %q", "data: first\n", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the first event to be flushed")
+	}
+}
+
+func Test_proxyRequest_EnableStreaming_LiftsTimeouts(t *testing.T) {
+	timeout := 200 * time.Millisecond
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		for i := 0; i < 3; i++ {
+			w.Write([]byte("data: tick\n\n"))
+			w.(http.Flusher).Flush()
+			time.Sleep(timeout)
+		}
+	}))
+	defer upstream.Close()
+
+	cases := []struct {
+		name      string
+		streaming bool
+		wantTicks int
+	}{
+		{name: "streaming", streaming: true, wantTicks: 3},
+		{name: "not streaming", streaming: false, wantTicks: 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			frontend := newFrontendWithConfig(t, upstream, types.FaaSConfig{
+				ReadTimeout:     timeout,
+				WriteTimeout:    timeout,
+				EnableStreaming: tc.streaming,
+			})
+
+			res, err := http.Get(frontend.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+
+			ticks := 0
+			scanner := bufio.NewScanner(res.Body)
+			for scanner.Scan() {
+				if scanner.Text() == "data: tick" {
+					ticks++
+				}
+			}
+
+			if tc.streaming && ticks != tc.wantTicks {
+				t.Errorf("events, want: %d, got: %d", tc.wantTicks, ticks)
+			}
+			if !tc.streaming && ticks >= 3 {
+				t.Errorf("events, want the stream cut off by the timeout, got: %d", ticks)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package proxy
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/openfaas/faas-provider/logging"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// functionTimeoutTTL is how long the timeout of a function is cached for, so that its
+// labels are not looked up for every invocation.
+const functionTimeoutTTL = 10 * time.Second
+
+// LabelResolver may be implemented by a BaseURLResolver which can look up the labels of a
+// function. The proxy then applies the timeout declared by types.FunctionTimeoutLabel to
+// invocations of the function, unless types.FunctionTimeoutAnnotation is set.
+type LabelResolver interface {
+	ResolveLabels(functionName string) (map[string]string, error)
+}
+
+type cachedTimeout struct {
+	timeout time.Duration
+	expires time.Time
+}
+
+// timeoutCache holds the timeout of each function, as read from its annotations or labels,
+// for a short time.
+type timeoutCache struct {
+	defaultTimeout time.Duration
+	maxTimeout     time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cachedTimeout
+
+	now func() time.Time
+}
+
+// newTimeoutCache creates a cache which returns defaultTimeout for functions without a
+// timeout annotation or label, and clamps the timeout of each function to maxTimeout.
+func newTimeoutCache(defaultTimeout, maxTimeout time.Duration) *timeoutCache {
+	if maxTimeout < defaultTimeout {
+		maxTimeout = defaultTimeout
+	}
+
+	return &timeoutCache{
+		defaultTimeout: defaultTimeout,
+		maxTimeout:     maxTimeout,
+		entries:        map[string]cachedTimeout{},
+		now:            time.Now,
+	}
+}
+
+// timeoutFor returns the timeout for an invocation of the function, as declared by its
+// annotations when the resolver implements AnnotationResolver, otherwise by its labels
+// when it implements LabelResolver.
+func (c *timeoutCache) timeoutFor(ctx context.Context, resolver BaseURLResolver, functionName string) time.Duration {
+	annotationResolver, hasAnnotations := resolver.(AnnotationResolver)
+	labelResolver, hasLabels := resolver.(LabelResolver)
+	if !hasAnnotations && !hasLabels {
+		return c.defaultTimeout
+	}
+
+	now := c.now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[functionName]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.timeout
+	}
+
+	timeout, ok := c.defaultTimeout, false
+	if hasAnnotations {
+		timeout, ok = lookupTimeout(ctx, functionName, "annotations", annotationResolver.ResolveAnnotations, types.FunctionTimeoutFromAnnotations)
+	}
+	if !ok && hasLabels {
+		timeout, ok = lookupTimeout(ctx, functionName, "labels", labelResolver.ResolveLabels, types.FunctionTimeoutFromLabels)
+	}
+	if !ok {
+		timeout = c.defaultTimeout
+	}
+
+	if timeout > c.maxTimeout {
+		timeout = c.maxTimeout
+	}
+
+	c.mu.Lock()
+	c.entries[functionName] = cachedTimeout{timeout: timeout, expires: now.Add(functionTimeoutTTL)}
+	c.mu.Unlock()
+
+	return timeout
+}
+
+// lookupTimeout resolves the labels or annotations of the function and parses its timeout,
+// the bool is false when none is declared or it can not be read.
+func lookupTimeout(ctx context.Context, functionName, kind string, resolve func(string) (map[string]string, error), parse func(map[string]string) (time.Duration, bool, error)) (time.Duration, bool) {
+	values, err := resolve(functionName)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Unable to resolve "+kind, "function", functionName, "error", err)
+		return 0, false
+	}
+
+	timeout, ok, err := parse(values)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Invalid timeout", "function", functionName, "error", err)
+		return 0, false
+	}
+	return timeout, ok
+}
@@ -0,0 +1,147 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-provider/logging"
+	"github.com/openfaas/faas-provider/types"
+)
+
+type labelResolver struct {
+	labels map[string]map[string]string
+	calls  int
+}
+
+func (l *labelResolver) Resolve(name string) (url.URL, error) {
+	return url.URL{Scheme: "http", Host: "127.0.0.1:8080"}, nil
+}
+
+func (l *labelResolver) ResolveLabels(name string) (map[string]string, error) {
+	l.calls++
+	return l.labels[name], nil
+}
+
+func Test_timeoutCache_timeoutFor(t *testing.T) {
+	resolver := &labelResolver{labels: map[string]map[string]string{
+		"short":   {types.FunctionTimeoutLabel: "2s"},
+		"long":    {types.FunctionTimeoutLabel: "10m"},
+		"invalid": {types.FunctionTimeoutLabel: "soon"},
+	}}
+
+	c := newTimeoutCache(10*time.Second, time.Minute)
+
+	testCases := []struct {
+		function string
+		want     time.Duration
+	}{
+		{function: "short", want: 2 * time.Second},
+		{function: "long", want: time.Minute},
+		{function: "invalid", want: 10 * time.Second},
+		{function: "unlabelled", want: 10 * time.Second},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.function, func(t *testing.T) {
+			if got := c.timeoutFor(context.Background(), resolver, tc.function); got != tc.want {
+				t.Errorf("timeout, want: %s, got: %s", tc.want, got)
+			}
+		})
+	}
+
+	if got := c.timeoutFor(context.Background(), &testBaseURLResolver{}, "short"); got != 10*time.Second {
+		t.Errorf("timeout without a LabelResolver, want: %s, got: %s", 10*time.Second, got)
+	}
+}
+
+func Test_timeoutCache_LogsWithContextLogger(t *testing.T) {
+	resolver := &labelResolver{labels: map[string]map[string]string{
+		"invalid": {types.FunctionTimeoutLabel: "soon"},
+	}}
+
+	var buf bytes.Buffer
+	ctx := logging.WithLogger(context.Background(), slog.New(slog.NewTextHandler(&buf, nil)))
+
+	newTimeoutCache(10*time.Second, time.Minute).timeoutFor(ctx, resolver, "invalid")
+
+	if got := buf.String(); !strings.Contains(got, "Invalid timeout") || !strings.Contains(got, "function=invalid") {
+		t.Errorf("want the warning written to the context logger, got: %q", got)
+	}
+}
+
+func Test_timeoutCache_CachesLabels(t *testing.T) {
+	resolver := &labelResolver{labels: map[string]map[string]string{
+		"figlet": {types.FunctionTimeoutLabel: "30s"},
+	}}
+
+	now := time.Now()
+	c := newTimeoutCache(10*time.Second, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.timeoutFor(context.Background(), resolver, "figlet")
+	c.timeoutFor(context.Background(), resolver, "figlet")
+	if resolver.calls != 1 {
+		t.Errorf("label lookups within the TTL, want: %d, got: %d", 1, resolver.calls)
+	}
+
+	now = now.Add(functionTimeoutTTL)
+	c.timeoutFor(context.Background(), resolver, "figlet")
+	if resolver.calls != 2 {
+		t.Errorf("label lookups after the TTL, want: %d, got: %d", 2, resolver.calls)
+	}
+}
+
+type annotatedLabelResolver struct {
+	labelResolver
+	annotations map[string]map[string]string
+}
+
+func (a *annotatedLabelResolver) ResolveAnnotations(name string) (map[string]string, error) {
+	return a.annotations[name], nil
+}
+
+func Test_timeoutCache_timeoutForAnnotations(t *testing.T) {
+	resolver := &annotatedLabelResolver{
+		labelResolver: labelResolver{labels: map[string]map[string]string{
+			"both":      {types.FunctionTimeoutLabel: "5s"},
+			"label":     {types.FunctionTimeoutLabel: "5s"},
+			"invalid":   {types.FunctionTimeoutLabel: "5s"},
+			"clamped":   {},
+			"untimed":   {},
+			"annotated": {},
+		}},
+		annotations: map[string]map[string]string{
+			"both":      {types.FunctionTimeoutAnnotation: "20s"},
+			"invalid":   {types.FunctionTimeoutAnnotation: "soon"},
+			"clamped":   {types.FunctionTimeoutAnnotation: "1h"},
+			"annotated": {types.FunctionTimeoutAnnotation: "30"},
+		},
+	}
+
+	c := newTimeoutCache(10*time.Second, time.Minute)
+
+	testCases := []struct {
+		function string
+		want     time.Duration
+	}{
+		{function: "both", want: 20 * time.Second},
+		{function: "label", want: 5 * time.Second},
+		{function: "invalid", want: 5 * time.Second},
+		{function: "clamped", want: time.Minute},
+		{function: "untimed", want: 10 * time.Second},
+		{function: "annotated", want: 30 * time.Second},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.function, func(t *testing.T) {
+			if got := c.timeoutFor(context.Background(), resolver, tc.function); got != tc.want {
+				t.Errorf("timeout, want: %s, got: %s", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-provider/httputil"
+)
+
+// isUpgradeRequest reports whether r asks to switch protocols, such as to a WebSocket,
+// with the Connection and Upgrade headers.
+func isUpgradeRequest(r *http.Request) bool {
+	if len(r.Header.Get("Upgrade")) == 0 {
+		return false
+	}
+
+	for _, v := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// switchProtocols completes a 101 Switching Protocols response from a function by taking
+// over the connection of the client and copying between it and the connection to the
+// function until either side closes. When streaming is false the deadlines the server set
+// from its ReadTimeout and WriteTimeout are kept, so the connection is closed by them.
+func switchProtocols(w http.ResponseWriter, r *http.Request, res *http.Response, streaming bool) error {
+	backend, ok := res.Body.(io.ReadWriteCloser)
+	if !ok {
+		httputil.WriteError(w, r, http.StatusBadGateway, "the function switched protocols without a writable connection")
+		return fmt.Errorf("response body is not writable")
+	}
+	defer backend.Close()
+
+	if want, got := r.Header.Get("Upgrade"), res.Header.Get("Upgrade"); !strings.EqualFold(want, got) {
+		httputil.WriteError(w, r, http.StatusBadGateway, "the function switched to a protocol which was not requested")
+		return fmt.Errorf("requested upgrade to %q, function switched to %q", want, got)
+	}
+
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		httputil.WriteErrorCode(w, r, http.StatusInternalServerError, httputil.InternalError, "the connection can not be upgraded")
+		return fmt.Errorf("unable to hijack the connection: %w", err)
+	}
+	defer conn.Close()
+
+	if streaming {
+		conn.SetDeadline(time.Time{})
+	}
+
+	// The 101 and its headers, including Connection and Upgrade, are written as they were
+	// received, as the client needs them to complete the switch.
+	res.Body = nil
+	if err := res.Write(brw); err != nil {
+		return fmt.Errorf("unable to write the response: %w", err)
+	}
+	if err := brw.Flush(); err != nil {
+		return fmt.Errorf("unable to write the response: %w", err)
+	}
+
+	errc := make(chan error, 2)
+	go func() {
+		_, err := io.Copy(conn, backend)
+		errc <- err
+	}()
+	go func() {
+		// Read through brw, which may hold data the client sent after its request.
+		_, err := io.Copy(backend, brw)
+		errc <- err
+	}()
+
+	<-errc
+	return nil
+}
@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+// newEchoUpstream switches to the "echo" protocol and echoes what it receives.
+func newEchoUpstream(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "echo" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		conn, brw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+		brw.Flush()
+		io.Copy(conn, brw)
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+func Test_isUpgradeRequest(t *testing.T) {
+	cases := []struct {
+		connection string
+		upgrade    string
+		want       bool
+	}{
+		{connection: "Upgrade", upgrade: "websocket", want: true},
+		{connection: "keep-alive, upgrade", upgrade: "websocket", want: true},
+		{connection: "keep-alive", upgrade: "websocket", want: false},
+		{connection: "Upgrade", upgrade: "", want: false},
+	}
+
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Connection", tc.connection)
+		if len(tc.upgrade) > 0 {
+			r.Header.Set("Upgrade", tc.upgrade)
+		}
+
+		if got := isUpgradeRequest(r); got != tc.want {
+			t.Errorf("Connection: %q, Upgrade: %q, want: %v, got: %v", tc.connection, tc.upgrade, tc.want, got)
+		}
+	}
+}
+
+func Test_proxyRequest_Upgrade(t *testing.T) {
+	for _, streaming := range []bool{true, false} {
+		t.Run(map[bool]string{true: "streaming", false: "not streaming"}[streaming], func(t *testing.T) {
+			testUpgrade(t, streaming)
+		})
+	}
+}
+
+func testUpgrade(t *testing.T, streaming bool) {
+	upstream := newEchoUpstream(t)
+	frontend := newFrontendWithConfig(t, upstream, types.FaaSConfig{ReadTimeout: 5 * time.Second, EnableStreaming: streaming})
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(frontend.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	io.WriteString(conn, "GET /function/figlet HTTP/1.1\r\nHost: example.com\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status code, want: %d, got: %d", http.StatusSwitchingProtocols, res.StatusCode)
+	}
+	if got := res.Header.Get("Upgrade"); got != "echo" {
+		t.Errorf("Upgrade, want: %s, got: %s", "echo", got)
+	}
+
+	io.WriteString(conn, "ping\n")
+	line, err := br.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "ping\n" {
+		t.Errorf("echo, want: %q, got: %q", "ping\n", line)
+	}
+}
@@ -0,0 +1,133 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/logging"
+	"github.com/openfaas/faas-provider/types"
+)
+
+const (
+	// CallbackURLHeader is the header a caller sets to the URL the result of an asynchronous
+	// invocation is posted to.
+	CallbackURLHeader = "X-Callback-Url"
+	// CallIDHeader identifies an asynchronous invocation, it is returned to the caller and
+	// sent with the invocation and its result.
+	CallIDHeader = "X-Call-Id"
+)
+
+// CallbackValidator checks the X-Callback-Url of a request before it is queued, an error
+// rejects the request with a 400. The Worker posts the result of the invocation to the URL
+// from inside the provider's network, so it must not be left to the caller.
+type CallbackValidator func(u *url.URL) error
+
+// AllowCallbackHosts returns a CallbackValidator which only accepts callbacks to the given
+// hosts, each a host name or a host:port, i.e. "gateway.openfaas:8080".
+func AllowCallbackHosts(hosts ...string) CallbackValidator {
+	allowed := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		allowed[strings.ToLower(host)] = true
+	}
+
+	return func(u *url.URL) error {
+		if allowed[strings.ToLower(u.Host)] || allowed[strings.ToLower(u.Hostname())] {
+			return nil
+		}
+		return fmt.Errorf("host %q is not allowed", u.Host)
+	}
+}
+
+// NewHandlerFunc creates the handler for "/async-function/{name}", which publishes each
+// request to queuer and responds with a 202 and the X-Call-Id of the invocation, rather
+// than waiting for the function. When the request carries an X-Callback-Url accepted by
+// validateCallback, the result of the invocation is posted to it by the Worker. Callbacks
+// are rejected when validateCallback is nil. A full queue is answered with a 429.
+func NewHandlerFunc(queuer types.RequestQueuer, validateCallback CallbackValidator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := httputil.PathVars(r)
+		name := vars["name"]
+		if name == "" {
+			httputil.WriteErrorCode(w, r, http.StatusBadRequest, httputil.InvalidRequest, "Provide function name in the request path")
+			return
+		}
+
+		body, ok := httputil.BufferBody(w, r)
+		if !ok {
+			return
+		}
+
+		callbackURL, err := parseCallbackURL(r.Header.Get(CallbackURLHeader), validateCallback)
+		if err != nil {
+			httputil.WriteErrorCode(w, r, http.StatusBadRequest, httputil.InvalidRequest, fmt.Sprintf("invalid %s: %s", CallbackURLHeader, err))
+			return
+		}
+
+		callID := r.Header.Get(CallIDHeader)
+		if len(callID) == 0 {
+			callID = logging.NewRequestID()
+		}
+
+		header := r.Header.Clone()
+		header.Del(CallbackURLHeader)
+		header.Set(CallIDHeader, callID)
+
+		path := ""
+		if params, ok := vars["params"]; ok {
+			path = "/" + params
+		}
+
+		req := &types.QueueRequest{
+			Header:      header,
+			Host:        r.Host,
+			Body:        body,
+			Method:      r.Method,
+			Path:        path,
+			QueryString: r.URL.RawQuery,
+			Function:    name,
+			CallbackURL: callbackURL,
+		}
+
+		if err := queuer.Queue(req); err != nil {
+			if errors.Is(err, ErrQueueFull) {
+				w.Header().Set("Retry-After", "1")
+				httputil.WriteErrorCode(w, r, http.StatusTooManyRequests, httputil.TooManyRequests, "the queue is full, retry later")
+				return
+			}
+
+			logging.FromContext(r.Context()).Error("Unable to queue request", "function", name, "call_id", callID, "error", err)
+			httputil.WriteErrorCode(w, r, http.StatusInternalServerError, httputil.InternalError, "unable to queue request")
+			return
+		}
+
+		w.Header().Set(CallIDHeader, callID)
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// parseCallbackURL returns nil when value is empty, otherwise value must be an absolute
+// http or https URL accepted by validate.
+func parseCallbackURL(value string, validate CallbackValidator) (*url.URL, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	u, err := url.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return nil, fmt.Errorf("%q must be an absolute http or https URL", value)
+	}
+	if validate == nil {
+		return nil, fmt.Errorf("callbacks are not allowed")
+	}
+	if err := validate(u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
@@ -0,0 +1,171 @@
+package queue
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/openfaas/faas-provider/types"
+)
+
+type recordingQueuer struct {
+	requests []*types.QueueRequest
+	err      error
+}
+
+func (q *recordingQueuer) Queue(req *types.QueueRequest) error {
+	if q.err != nil {
+		return q.err
+	}
+	q.requests = append(q.requests, req)
+	return nil
+}
+
+func newRouter(queuer types.RequestQueuer) *mux.Router {
+	return newRouterWithValidator(queuer, AllowCallbackHosts("example.com"))
+}
+
+func newRouterWithValidator(queuer types.RequestQueuer, validateCallback CallbackValidator) *mux.Router {
+	r := mux.NewRouter()
+	handler := NewHandlerFunc(queuer, validateCallback)
+	r.HandleFunc("/async-function/{name}", handler)
+	r.HandleFunc("/async-function/{name}/{params:.*}", handler)
+	return r
+}
+
+func Test_NewHandlerFunc_QueuesRequest(t *testing.T) {
+	queuer := &recordingQueuer{}
+
+	r := httptest.NewRequest(http.MethodPost, "/async-function/figlet/api/render?font=big", strings.NewReader("hello"))
+	r.Header.Set(CallbackURLHeader, "https://example.com/callback")
+	r.Header.Set("Content-Type", "text/plain")
+	w := httptest.NewRecorder()
+	newRouter(queuer).ServeHTTP(w, r)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("status code, want: %d, got: %d", http.StatusAccepted, w.Code)
+	}
+	callID := w.Header().Get(CallIDHeader)
+	if len(callID) == 0 {
+		t.Errorf("want %s in the response", CallIDHeader)
+	}
+
+	if len(queuer.requests) != 1 {
+		t.Fatalf("queued requests, want: %d, got: %d", 1, len(queuer.requests))
+	}
+	got := queuer.requests[0]
+
+	if got.Function != "figlet" {
+		t.Errorf("Function, want: %s, got: %s", "figlet", got.Function)
+	}
+	if got.Path != "/api/render" {
+		t.Errorf("Path, want: %s, got: %s", "/api/render", got.Path)
+	}
+	if got.QueryString != "font=big" {
+		t.Errorf("QueryString, want: %s, got: %s", "font=big", got.QueryString)
+	}
+	if string(got.Body) != "hello" {
+		t.Errorf("Body, want: %s, got: %s", "hello", got.Body)
+	}
+	if got.CallbackURL == nil || got.CallbackURL.String() != "https://example.com/callback" {
+		t.Errorf("CallbackURL, want: %s, got: %v", "https://example.com/callback", got.CallbackURL)
+	}
+	if got.Header.Get(CallIDHeader) != callID {
+		t.Errorf("%s, want: %s, got: %s", CallIDHeader, callID, got.Header.Get(CallIDHeader))
+	}
+	if got.Header.Get(CallbackURLHeader) != "" {
+		t.Errorf("want %s removed from the queued headers", CallbackURLHeader)
+	}
+}
+
+func Test_NewHandlerFunc_KeepsCallID(t *testing.T) {
+	queuer := &recordingQueuer{}
+
+	r := httptest.NewRequest(http.MethodPost, "/async-function/figlet", nil)
+	r.Header.Set(CallIDHeader, "abc123")
+	w := httptest.NewRecorder()
+	newRouter(queuer).ServeHTTP(w, r)
+
+	if got := w.Header().Get(CallIDHeader); got != "abc123" {
+		t.Errorf("%s, want: %s, got: %s", CallIDHeader, "abc123", got)
+	}
+	if queuer.requests[0].Path != "" {
+		t.Errorf("Path, want empty, got: %s", queuer.requests[0].Path)
+	}
+}
+
+func Test_NewHandlerFunc_Errors(t *testing.T) {
+	cases := []struct {
+		name        string
+		callbackURL string
+		queueErr    error
+		wantStatus  int
+	}{
+		{name: "relative callback", callbackURL: "/callback", wantStatus: http.StatusBadRequest},
+		{name: "callback with other scheme", callbackURL: "ftp://example.com/callback", wantStatus: http.StatusBadRequest},
+		{name: "callback to a host which is not allowed", callbackURL: "http://169.254.169.254/latest/meta-data", wantStatus: http.StatusBadRequest},
+		{name: "queue full", queueErr: ErrQueueFull, wantStatus: http.StatusTooManyRequests},
+		{name: "queue error", queueErr: errors.New("nats: connection closed"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/async-function/figlet", nil)
+			if len(tc.callbackURL) > 0 {
+				r.Header.Set(CallbackURLHeader, tc.callbackURL)
+			}
+			w := httptest.NewRecorder()
+			newRouter(&recordingQueuer{err: tc.queueErr}).ServeHTTP(w, r)
+
+			if w.Code != tc.wantStatus {
+				body, _ := io.ReadAll(w.Body)
+				t.Errorf("status code, want: %d, got: %d (%s)", tc.wantStatus, w.Code, body)
+			}
+		})
+	}
+}
+
+func Test_NewHandlerFunc_RejectsCallbacksWithoutValidator(t *testing.T) {
+	queuer := &recordingQueuer{}
+
+	r := httptest.NewRequest(http.MethodPost, "/async-function/figlet", nil)
+	r.Header.Set(CallbackURLHeader, "https://example.com/callback")
+	w := httptest.NewRecorder()
+	newRouterWithValidator(queuer, nil).ServeHTTP(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status code, want: %d, got: %d", http.StatusBadRequest, w.Code)
+	}
+	if len(queuer.requests) != 0 {
+		t.Errorf("queued requests, want: %d, got: %d", 0, len(queuer.requests))
+	}
+}
+
+func Test_AllowCallbackHosts(t *testing.T) {
+	validate := AllowCallbackHosts("example.com", "gateway.openfaas:8080")
+
+	cases := []struct {
+		url     string
+		allowed bool
+	}{
+		{url: "https://example.com/callback", allowed: true},
+		{url: "https://EXAMPLE.com:8443/callback", allowed: true},
+		{url: "http://gateway.openfaas:8080/callback", allowed: true},
+		{url: "http://gateway.openfaas:9090/callback", allowed: false},
+		{url: "http://127.0.0.1/callback", allowed: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.url, func(t *testing.T) {
+			u, _ := url.Parse(tc.url)
+			if err := validate(u); (err == nil) != tc.allowed {
+				t.Errorf("allowed, want: %t, got error: %v", tc.allowed, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/openfaas/faas-provider/logging"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// ErrQueueFull is returned by Queue when the queue has no room for another request.
+var ErrQueueFull = errors.New("queue is full")
+
+// MemoryQueue is a types.RequestQueuer which holds requests in memory until they are
+// processed by a Worker, for providers which run a single replica. Requests which are
+// still queued when the provider stops are lost, a durable queue such as NATS should be
+// used behind the same interface when that matters.
+type MemoryQueue struct {
+	worker   *Worker
+	requests chan *types.QueueRequest
+}
+
+// NewMemoryQueue creates a queue which holds up to size requests.
+func NewMemoryQueue(worker *Worker, size int) *MemoryQueue {
+	return &MemoryQueue{
+		worker:   worker,
+		requests: make(chan *types.QueueRequest, size),
+	}
+}
+
+// Queue adds req to the queue, or returns ErrQueueFull.
+func (q *MemoryQueue) Queue(req *types.QueueRequest) error {
+	select {
+	case q.requests <- req:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Run processes queued requests with the given number of concurrent workers until ctx is
+// cancelled, then waits for the workers to return. The requests being processed are
+// cancelled with ctx, and the requests still queued are not processed.
+func (q *MemoryQueue) Run(ctx context.Context, workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case req := <-q.requests:
+					if err := q.worker.Process(ctx, req); err != nil {
+						logging.FromContext(ctx).Error("Unable to process queued request", "error", err)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,143 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-provider/logging"
+	"github.com/openfaas/faas-provider/types"
+)
+
+const (
+	// FunctionNameHeader is the name of the function whose result is posted to a callback.
+	FunctionNameHeader = "X-Function-Name"
+	// FunctionStatusHeader is the status code the function returned, or 503 when it could
+	// not be invoked.
+	FunctionStatusHeader = "X-Function-Status"
+	// DurationHeader is how long the invocation took, in seconds.
+	DurationHeader = "X-Duration-Seconds"
+)
+
+// The clients used when HTTPInvoker.Client or Worker.Client is not set, so that a function
+// or callback which does not respond cannot hold a worker forever.
+var (
+	defaultInvokeClient   = &http.Client{Timeout: 5 * time.Minute}
+	defaultCallbackClient = &http.Client{Timeout: 30 * time.Second}
+)
+
+// Invoker calls the function of a queued request.
+type Invoker interface {
+	Invoke(ctx context.Context, req *types.QueueRequest) (*http.Response, error)
+}
+
+// HTTPInvoker invokes functions through the "/function/" route of the gateway or
+// provider at URL, i.e. "http://127.0.0.1:8080".
+type HTTPInvoker struct {
+	URL string
+	// Client calls the functions, the default times out after 5 minutes.
+	Client *http.Client
+}
+
+// Invoke sends req to its function and returns the response.
+func (i *HTTPInvoker) Invoke(ctx context.Context, req *types.QueueRequest) (*http.Response, error) {
+	target := strings.TrimSuffix(i.URL, "/") + "/function/" + url.PathEscape(req.Function) + req.Path
+	if len(req.QueryString) > 0 {
+		target += "?" + req.QueryString
+	}
+
+	method := req.Method
+	if len(method) == 0 {
+		method = http.MethodPost
+	}
+
+	r, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(req.Body))
+	if err != nil {
+		return nil, err
+	}
+	if req.Header != nil {
+		r.Header = req.Header.Clone()
+	}
+
+	client := i.Client
+	if client == nil {
+		client = defaultInvokeClient
+	}
+	return client.Do(r)
+}
+
+// Worker invokes queued requests and posts their results to the CallbackURL of each, it
+// is shared by the queue implementations, such as MemoryQueue, or a subscriber to an
+// external queue which decodes each message into a types.QueueRequest.
+type Worker struct {
+	Invoker Invoker
+	// Client posts results to callback URLs, the default times out after 30 seconds.
+	Client *http.Client
+}
+
+// Process invokes the function of req, then posts the response to req.CallbackURL, when
+// set, with the FunctionNameHeader, FunctionStatusHeader, DurationHeader and CallIDHeader.
+// An error is returned when the result could not be delivered.
+func (w *Worker) Process(ctx context.Context, req *types.QueueRequest) error {
+	callID := req.Header.Get(CallIDHeader)
+
+	start := time.Now()
+	res, invokeErr := w.Invoker.Invoke(ctx, req)
+	duration := time.Since(start)
+
+	status := http.StatusServiceUnavailable
+	var body []byte
+	header := http.Header{}
+	if invokeErr != nil {
+		logging.FromContext(ctx).Warn("Unable to invoke function for queued request", "function", req.Function, "call_id", callID, "error", invokeErr)
+		body = []byte(fmt.Sprintf("unable to invoke %s", req.Function))
+	} else {
+		defer res.Body.Close()
+
+		status = res.StatusCode
+		var err error
+		if body, err = io.ReadAll(res.Body); err != nil {
+			return fmt.Errorf("unable to read the response of %s: %w", req.Function, err)
+		}
+		if contentType := res.Header.Get("Content-Type"); len(contentType) > 0 {
+			header.Set("Content-Type", contentType)
+		}
+	}
+
+	if req.CallbackURL == nil {
+		return nil
+	}
+
+	callback, err := http.NewRequestWithContext(ctx, http.MethodPost, req.CallbackURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	callback.Header = header
+	callback.Header.Set(CallIDHeader, callID)
+	callback.Header.Set(FunctionNameHeader, req.Function)
+	callback.Header.Set(FunctionStatusHeader, strconv.Itoa(status))
+	callback.Header.Set(DurationHeader, strconv.FormatFloat(duration.Seconds(), 'f', 6, 64))
+
+	client := w.Client
+	if client == nil {
+		client = defaultCallbackClient
+	}
+
+	callbackRes, err := client.Do(callback)
+	if err != nil {
+		return fmt.Errorf("unable to post the result of %s to %s: %w", callID, req.CallbackURL.Redacted(), err)
+	}
+	io.Copy(io.Discard, callbackRes.Body)
+	callbackRes.Body.Close()
+
+	if callbackRes.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unable to post the result of %s to %s: unexpected status code %d", callID, req.CallbackURL.Redacted(), callbackRes.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package queue
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+type callback struct {
+	header http.Header
+	body   string
+}
+
+func newCallbackServer(t *testing.T) (*httptest.Server, chan callback) {
+	t.Helper()
+
+	results := make(chan callback, 1)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		results <- callback{header: r.Header, body: string(body)}
+	}))
+	t.Cleanup(s.Close)
+	return s, results
+}
+
+func Test_MemoryQueue_DeliversResult(t *testing.T) {
+	function := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/function/figlet/render" || r.URL.RawQuery != "font=big" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("rendered " + string(body)))
+	}))
+	defer function.Close()
+
+	callbackServer, results := newCallbackServer(t)
+	callbackURL, _ := url.Parse(callbackServer.URL)
+
+	q := NewMemoryQueue(&Worker{Invoker: &HTTPInvoker{URL: function.URL}}, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Run(ctx, 1)
+
+	err := q.Queue(&types.QueueRequest{
+		Header:      http.Header{CallIDHeader: []string{"abc123"}},
+		Body:        []byte("hello"),
+		Method:      http.MethodPost,
+		Path:        "/render",
+		QueryString: "font=big",
+		Function:    "figlet",
+		CallbackURL: callbackURL,
+	})
+	if err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+
+	select {
+	case got := <-results:
+		if got.body != "rendered hello" {
+			t.Errorf("body, want: %s, got: %s", "rendered hello", got.body)
+		}
+		want := map[string]string{
+			CallIDHeader:         "abc123",
+			FunctionNameHeader:   "figlet",
+			FunctionStatusHeader: "201",
+			"Content-Type":       "text/plain",
+		}
+		for k, v := range want {
+			if got.header.Get(k) != v {
+				t.Errorf("%s, want: %s, got: %s", k, v, got.header.Get(k))
+			}
+		}
+		if got.header.Get(DurationHeader) == "" {
+			t.Errorf("want %s", DurationHeader)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the result")
+	}
+}
+
+func Test_Worker_InvokeError(t *testing.T) {
+	callbackServer, results := newCallbackServer(t)
+	callbackURL, _ := url.Parse(callbackServer.URL)
+
+	// Nothing listens on the port of a closed server.
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	w := &Worker{Invoker: &HTTPInvoker{URL: closed.URL}}
+	err := w.Process(context.Background(), &types.QueueRequest{Function: "figlet", CallbackURL: callbackURL})
+	if err != nil {
+		t.Fatalf("want no error delivering the failure, got: %s", err)
+	}
+
+	got := <-results
+	if status := got.header.Get(FunctionStatusHeader); status != "503" {
+		t.Errorf("%s, want: %s, got: %s", FunctionStatusHeader, "503", status)
+	}
+}
+
+func Test_MemoryQueue_Full(t *testing.T) {
+	q := NewMemoryQueue(&Worker{}, 1)
+
+	if err := q.Queue(&types.QueueRequest{Function: "figlet"}); err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+	if err := q.Queue(&types.QueueRequest{Function: "figlet"}); err != ErrQueueFull {
+		t.Errorf("want ErrQueueFull, got: %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// namespaceQuotas enforces FaaSConfig.Quotas with the usage returned by
+// FaaSConfig.NamespaceUsage.
+//
+// The requests of a namespace which are checked against its quota are served one at a
+// time, from the check until the handler returns, so that two deploys can not both pass
+// a check which only one of them fits in. This only holds within one provider, replicas
+// of a provider must enforce the quota in the handler, or its backing store, as well.
+type namespaceQuotas struct {
+	provider         types.QuotaProvider
+	usage            func(ctx context.Context, namespace string) (types.NamespaceUsage, error)
+	defaultNamespace string
+
+	mu    sync.Mutex
+	locks map[string]*namespaceLock
+}
+
+// namespaceLock serializes the requests of one namespace, refs counts the requests
+// holding or waiting for it so that it is deleted once there are none.
+type namespaceLock struct {
+	sync.Mutex
+	refs int
+}
+
+func newNamespaceQuotas(provider types.QuotaProvider, usage func(ctx context.Context, namespace string) (types.NamespaceUsage, error), defaultNamespace string) *namespaceQuotas {
+	return &namespaceQuotas{
+		provider:         provider,
+		usage:            usage,
+		defaultNamespace: defaultNamespace,
+		locks:            map[string]*namespaceLock{},
+	}
+}
+
+// lock waits for the other requests of namespace to be served, the returned func must be
+// called once the request has been served.
+func (q *namespaceQuotas) lock(namespace string) func() {
+	if len(namespace) == 0 {
+		namespace = q.defaultNamespace
+	}
+
+	q.mu.Lock()
+	l, ok := q.locks[namespace]
+	if !ok {
+		l = &namespaceLock{}
+		q.locks[namespace] = l
+	}
+	l.refs++
+	q.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+
+		q.mu.Lock()
+		defer q.mu.Unlock()
+
+		l.refs--
+		if l.refs == 0 {
+			delete(q.locks, namespace)
+		}
+	}
+}
+
+// serve checks the request against the quota of namespace with fn, then serves it with
+// next, while holding the lock of namespace.
+func (q *namespaceQuotas) serve(next http.HandlerFunc, w http.ResponseWriter, r *http.Request, namespace string, fn func(*types.NamespaceQuota, types.NamespaceUsage) error) {
+	unlock := q.lock(namespace)
+	defer unlock()
+
+	if q.check(w, r, namespace, fn) {
+		next.ServeHTTP(w, r)
+	}
+}
+
+// check calls fn with the quota and usage of namespace, and writes the rejection when it
+// returns an error. It returns false when the request was rejected.
+func (q *namespaceQuotas) check(w http.ResponseWriter, r *http.Request, namespace string, fn func(*types.NamespaceQuota, types.NamespaceUsage) error) bool {
+	quota, err := q.provider.Quota(r.Context(), namespace)
+	if err != nil {
+		httputil.WriteError(w, r, http.StatusInternalServerError, fmt.Sprintf("unable to read the quota of namespace %q: %s", namespace, err))
+		return false
+	}
+	if quota == nil {
+		return true
+	}
+
+	usage, err := q.usage(r.Context(), namespace)
+	if err != nil {
+		httputil.WriteError(w, r, http.StatusInternalServerError, fmt.Sprintf("unable to read the usage of namespace %q: %s", namespace, err))
+		return false
+	}
+
+	if err := fn(quota, usage); err != nil {
+		var quotaErr *types.QuotaError
+		if errors.As(err, &quotaErr) {
+			types.WriteQuotaError(w, quotaErr)
+			return false
+		}
+
+		httputil.WriteError(w, r, http.StatusBadRequest, err.Error())
+		return false
+	}
+	return true
+}
+
+// decorateDeployment checks the FunctionDeployment of a deploy or update request against
+// the quota of its namespace. Requests which can not be decoded are passed to next, which
+// reports the error.
+func (q *namespaceQuotas) decorateDeployment(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, ok := httputil.BufferBody(w, r)
+		if !ok {
+			return
+		}
+
+		deployment := types.FunctionDeployment{}
+		if err := json.Unmarshal(body, &deployment); err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		q.serve(next, w, r, deployment.Namespace, func(quota *types.NamespaceQuota, usage types.NamespaceUsage) error {
+			return quota.CheckDeployment(deployment.Namespace, usage, deployment)
+		})
+	}
+}
+
+// decorateRegister checks that an instance registering itself does not add a function to
+// a namespace over its quota.
+func (q *namespaceQuotas) decorateRegister(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, ok := httputil.BufferBody(w, r)
+		if !ok {
+			return
+		}
+
+		req := types.RegisterRequest{}
+		if err := json.Unmarshal(body, &req); err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		q.serve(next, w, r, req.Namespace, func(quota *types.NamespaceQuota, usage types.NamespaceUsage) error {
+			return quota.CheckRegister(req.Namespace, usage, req.Name)
+		})
+	}
+}
+
+// decorateCheckpoint checks that the checkpoints of the namespace of the function have
+// not reached the quota before another is created.
+func (q *namespaceQuotas) decorateCheckpoint(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, namespace := httputil.SplitFunctionName(httputil.PathVar(r, "name"))
+		if len(namespace) == 0 {
+			namespace = httputil.NamespaceFromRequest(r)
+		}
+
+		q.serve(next, w, r, namespace, func(quota *types.NamespaceQuota, usage types.NamespaceUsage) error {
+			return quota.CheckCheckpoint(namespace, usage)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package bootstrap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_Quotas(t *testing.T) {
+	quotas := &types.StaticQuotas{
+		Namespaces: map[string]types.NamespaceQuota{
+			"tenant-a": {MaxFunctions: 2, MaxMemory: "256Mi", MaxCheckpointBytes: 1024},
+		},
+	}
+	usage := func(ctx context.Context, namespace string) (types.NamespaceUsage, error) {
+		return types.NamespaceUsage{
+			Functions:       map[string]types.FunctionResources{"figlet": {Memory: "128Mi"}, "env": {}},
+			CheckpointBytes: 1024,
+		}, nil
+	}
+
+	testCases := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{name: "another namespace", method: http.MethodPost, path: "/system/functions", body: `{"service":"nodeinfo","namespace":"tenant-b"}`, wantCode: http.StatusOK},
+		{name: "too many functions", method: http.MethodPost, path: "/system/functions", body: `{"service":"nodeinfo","namespace":"tenant-a"}`, wantCode: http.StatusForbidden, wantBody: `"resource":"functions"`},
+		{name: "update within memory", method: http.MethodPut, path: "/system/functions", body: `{"service":"figlet","namespace":"tenant-a","limits":{"memory":"256Mi"}}`, wantCode: http.StatusOK},
+		{name: "update over memory", method: http.MethodPut, path: "/system/functions", body: `{"service":"env","namespace":"tenant-a","limits":{"memory":"256Mi"}}`, wantCode: http.StatusForbidden, wantBody: `"requested":268435456`},
+		{name: "register existing function", method: http.MethodPost, path: "/system/register", body: `{"name":"figlet","namespace":"tenant-a","address":"10.0.0.2:8080"}`, wantCode: http.StatusOK},
+		{name: "register new function", method: http.MethodPost, path: "/system/register", body: `{"name":"nodeinfo","namespace":"tenant-a","address":"10.0.0.2:8080"}`, wantCode: http.StatusForbidden, wantBody: `"resource":"functions"`},
+		{name: "checkpoint", method: http.MethodPost, path: "/system/function/figlet.tenant-a/checkpoint", wantCode: http.StatusForbidden, wantBody: `"resource":"checkpoints"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handlers := validHandlers()
+			handlers.RegisterFunction = func(w http.ResponseWriter, r *http.Request) {}
+			handlers.CreateCheckpoint = func(w http.ResponseWriter, r *http.Request) {}
+
+			s := NewServer(&types.FaaSConfig{Quotas: quotas, NamespaceUsage: usage})
+			s.Handlers(handlers)
+
+			rr := httptest.NewRecorder()
+			s.Router().ServeHTTP(rr, httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body)))
+
+			if rr.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d (%s)", tc.wantCode, rr.Code, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), tc.wantBody) {
+				t.Errorf("body, want: %q, got: %q", tc.wantBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func Test_Quotas_ConcurrentDeploys(t *testing.T) {
+	quotas := &types.StaticQuotas{
+		Namespaces: map[string]types.NamespaceQuota{
+			"tenant-a": {MaxFunctions: 1},
+		},
+	}
+
+	var mu sync.Mutex
+	functions := map[string]types.FunctionResources{}
+	usage := func(ctx context.Context, namespace string) (types.NamespaceUsage, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		used := make(map[string]types.FunctionResources, len(functions))
+		for name, resources := range functions {
+			used[name] = resources
+		}
+		return types.NamespaceUsage{Functions: used}, nil
+	}
+
+	handlers := validHandlers()
+	handlers.DeployFunction = func(w http.ResponseWriter, r *http.Request) {
+		// Leave time for another deploy to pass the check before this one is stored
+		time.Sleep(10 * time.Millisecond)
+
+		deployment := types.FunctionDeployment{}
+		if err := types.ReadJSON(r, &deployment); err != nil {
+			t.Errorf("unable to decode deployment: %s", err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		functions[deployment.Service] = types.FunctionResources{}
+	}
+
+	s := NewServer(&types.FaaSConfig{Quotas: quotas, NamespaceUsage: usage})
+	s.Handlers(handlers)
+
+	codes := make(chan int, 2)
+	var wg sync.WaitGroup
+	for _, name := range []string{"figlet", "env"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+
+			rr := httptest.NewRecorder()
+			body := `{"service":"` + name + `","namespace":"tenant-a"}`
+			s.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/system/functions", strings.NewReader(body)))
+			codes <- rr.Code
+		}(name)
+	}
+	wg.Wait()
+	close(codes)
+
+	accepted := 0
+	for code := range codes {
+		if code == http.StatusOK {
+			accepted++
+		}
+	}
+	if accepted != 1 {
+		t.Errorf("accepted deploys, want: %d, got: %d", 1, accepted)
+	}
+	if got := len(functions); got != 1 {
+		t.Errorf("functions, want: %d, got: %d", 1, got)
+	}
+}
@@ -0,0 +1,121 @@
+package bootstrap
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/limiter"
+)
+
+// defaultRateLimitWindow is used when InvokeRateLimitWindow is not set.
+const defaultRateLimitWindow = time.Second
+
+// invokeRateLimiter allows up to limit requests to each function per window, and tells
+// clients how close they are to the limit with the X-RateLimit-* headers. Each function
+// has a limiter.Bucket of limit requests refilled over the window, so that requests are
+// not let through in bursts of twice the limit either side of the start of a window.
+type invokeRateLimiter struct {
+	limit  int
+	window time.Duration
+
+	// defaultNamespace is the namespace of functions invoked without one, so that they
+	// share their bucket with the invocations which name it.
+	defaultNamespace string
+
+	// chargeBatchItems leaves "/invoke-batch/" to the batchInvoker, which charges each item.
+	chargeBatchItems bool
+
+	mu      sync.Mutex
+	buckets map[string]*limiter.Bucket
+	swept   time.Time
+
+	now func() time.Time
+}
+
+func newInvokeRateLimiter(limit int, window time.Duration, defaultNamespace string) *invokeRateLimiter {
+	if window <= 0 {
+		window = defaultRateLimitWindow
+	}
+
+	return &invokeRateLimiter{
+		limit:            limit,
+		window:           window,
+		defaultNamespace: defaultNamespace,
+		buckets:          map[string]*limiter.Bucket{},
+		now:              time.Now,
+	}
+}
+
+// take records a request to function, which may be given as "name.namespace". It returns
+// the requests remaining, how long until the limit is reset in full, and whether the
+// request is allowed, with how long until it would be when it is not.
+func (l *invokeRateLimiter) take(function string) (int, time.Duration, time.Duration, bool) {
+	now := l.now()
+
+	name, namespace := qualifiedFunction(function, "", l.defaultNamespace)
+	key := name
+	if len(namespace) > 0 {
+		key = name + "." + namespace
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = limiter.NewBucket(float64(l.limit)/l.window.Seconds(), float64(l.limit), now)
+		l.buckets[key] = b
+	}
+
+	wait, allowed := b.Take(now)
+	return b.Remaining(), b.UntilFull(now), wait, allowed
+}
+
+// sweep forgets the buckets which have refilled, at most once every window, as they are
+// the same as a new bucket. l.mu must be held.
+func (l *invokeRateLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < l.window {
+		return
+	}
+	l.swept = now
+
+	for key, b := range l.buckets {
+		if b.UntilFull(now) <= 0 {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// middleware limits requests to the invocationPrefixes, every response to them carries the
+// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers. With
+// chargeBatchItems, a batch is not charged itself, as each of its items is charged by the
+// batchInvoker.
+func (l *invokeRateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		function, ok := invokedFunction(r.URL.Path)
+		if !ok || (l.chargeBatchItems && strings.HasPrefix(r.URL.Path, "/invoke-batch/")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		remaining, reset, wait, allowed := l.take(function)
+
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(l.limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.Itoa(httputil.RetryAfterSeconds(reset)))
+
+		if !allowed {
+			httputil.SetRetryAfter(w, wait)
+			httputil.WriteError(w, r, http.StatusTooManyRequests, "rate limit exceeded for "+function)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,80 @@
+package bootstrap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_invokeRateLimiter(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := newInvokeRateLimiter(2, 8*time.Second, "openfaas-fn")
+	l.now = func() time.Time { return now }
+
+	handler := l.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// 2 requests per 8 seconds refill a request every 4 seconds.
+	steps := []struct {
+		name           string
+		path           string
+		advance        time.Duration
+		wantCode       int
+		wantRemaining  string
+		wantReset      string
+		wantRetryAfter string
+	}{
+		{name: "first", path: "/function/figlet", wantCode: http.StatusOK, wantRemaining: "1", wantReset: "4"},
+		{name: "second", path: "/function/figlet/sub/path", advance: 2 * time.Second, wantCode: http.StatusOK, wantRemaining: "0", wantReset: "6"},
+		{name: "limited", path: "/invoke/figlet", wantCode: http.StatusTooManyRequests, wantRemaining: "0", wantReset: "6", wantRetryAfter: "2"},
+		{name: "default namespace shares the limit", path: "/function/figlet.openfaas-fn", wantCode: http.StatusTooManyRequests, wantRemaining: "0", wantReset: "6", wantRetryAfter: "2"},
+		{name: "other namespace", path: "/function/figlet.staging", wantCode: http.StatusOK, wantRemaining: "1", wantReset: "4"},
+		{name: "other function", path: "/function/nodeinfo", wantCode: http.StatusOK, wantRemaining: "1", wantReset: "4"},
+		{name: "refilled", path: "/function/figlet", advance: 2 * time.Second, wantCode: http.StatusOK, wantRemaining: "0", wantReset: "8"},
+		{name: "system route", path: "/system/functions", wantCode: http.StatusOK},
+	}
+
+	for _, step := range steps {
+		now = now.Add(step.advance)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, step.path, nil))
+
+		if w.Code != step.wantCode {
+			t.Errorf("%s: status code, want: %d, got: %d", step.name, step.wantCode, w.Code)
+		}
+
+		if got := w.Header().Get("X-RateLimit-Remaining"); got != step.wantRemaining {
+			t.Errorf("%s: X-RateLimit-Remaining, want: %q, got: %q", step.name, step.wantRemaining, got)
+		}
+
+		if got := w.Header().Get("X-RateLimit-Reset"); got != step.wantReset {
+			t.Errorf("%s: X-RateLimit-Reset, want: %q, got: %q", step.name, step.wantReset, got)
+		}
+
+		if got := w.Header().Get("Retry-After"); got != step.wantRetryAfter {
+			t.Errorf("%s: Retry-After, want: %q, got: %q", step.name, step.wantRetryAfter, got)
+		}
+
+		if len(step.wantRemaining) > 0 && w.Header().Get("X-RateLimit-Limit") != "2" {
+			t.Errorf("%s: X-RateLimit-Limit, want: %q, got: %q", step.name, "2", w.Header().Get("X-RateLimit-Limit"))
+		}
+	}
+}
+
+func Test_invokeRateLimiter_ForgetsRefilledBuckets(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := newInvokeRateLimiter(2, time.Second, "")
+	l.now = func() time.Time { return now }
+
+	l.take("figlet")
+
+	now = now.Add(time.Second)
+	l.take("nodeinfo")
+
+	if _, ok := l.buckets["figlet"]; ok {
+		t.Errorf("want the refilled bucket to be forgotten")
+	}
+}
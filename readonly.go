@@ -0,0 +1,68 @@
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// readOnlyMode rejects mutating requests to the system API while it is enabled.
+type readOnlyMode struct {
+	enabled atomic.Bool
+}
+
+// decorate returns 503 for requests which would change state while read-only mode is
+// enabled. GET and HEAD requests are always passed through to next.
+func (m *readOnlyMode) decorate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if m.enabled.Load() && r.Method != http.MethodGet && r.Method != http.MethodHead {
+			httputil.WriteErrorCode(w, r, http.StatusServiceUnavailable, httputil.ReadOnly, "provider in read-only mode")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// watch enables read-only mode while file exists, checking each time the process receives
+// SIGHUP until ctx is done, so that the mode can be changed on the node without the API.
+// The mode is kept when the file can not be checked.
+func (m *readOnlyMode) watch(ctx context.Context, file string, logger *slog.Logger) {
+	onSIGHUP(ctx, func() {
+		enabled, err := fileExists(file)
+		if err != nil {
+			logger.Error("Unable to check the read-only mode file", "file", file, "error", err)
+			return
+		}
+		if m.enabled.Swap(enabled) != enabled {
+			logger.Info("Changed read-only mode", "enabled", enabled, "file", file)
+		}
+	})
+}
+
+// handler reports the current mode on GET and changes it on PUT.
+func (m *readOnlyMode) handler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPut {
+		if r.Body != nil {
+			defer r.Body.Close()
+		}
+
+		req := types.ReadOnlyMode{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			httputil.WriteErrorCode(w, r, http.StatusBadRequest, httputil.InvalidRequest, "unable to decode read-only mode request")
+			return
+		}
+
+		m.enabled.Store(req.Enabled)
+	}
+
+	body, _ := json.Marshal(types.ReadOnlyMode{Enabled: m.enabled.Load()})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
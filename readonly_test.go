@@ -0,0 +1,91 @@
+package bootstrap
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func Test_readOnlyMode(t *testing.T) {
+	mode := &readOnlyMode{}
+	deploy := mode.decorate(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	w := httptest.NewRecorder()
+	deploy.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/system/functions", nil))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("status code before read-only, want: %d, got: %d", http.StatusAccepted, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	mode.handler(w, httptest.NewRequest(http.MethodPut, "/system/read-only", strings.NewReader(`{"enabled":true}`)))
+	if got := strings.TrimSpace(w.Body.String()); got != `{"enabled":true}` {
+		t.Fatalf("want read-only mode to be enabled, got: %s", got)
+	}
+
+	w = httptest.NewRecorder()
+	deploy.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/system/functions", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status code in read-only, want: %d, got: %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if got := strings.TrimSpace(w.Body.String()); got != `{"code":503,"message":"provider in read-only mode","errorCode":"ReadOnly"}` {
+		t.Errorf("unexpected body: %s", got)
+	}
+
+	w = httptest.NewRecorder()
+	deploy.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/system/secrets", nil))
+	if w.Code != http.StatusAccepted {
+		t.Errorf("want reads to be allowed in read-only mode, got: %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	mode.handler(w, httptest.NewRequest(http.MethodPut, "/system/read-only", strings.NewReader(`{"enabled":false}`)))
+
+	w = httptest.NewRecorder()
+	deploy.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/system/functions", nil))
+	if w.Code != http.StatusAccepted {
+		t.Errorf("status code after read-only, want: %d, got: %d", http.StatusAccepted, w.Code)
+	}
+}
+
+func Test_readOnlyMode_WatchesFileOnSIGHUP(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "read-only")
+	mode := &readOnlyMode{}
+
+	// SIGHUP terminates the process without a handler, until watch has installed its own.
+	guard := make(chan os.Signal, 1)
+	signal.Notify(guard, syscall.SIGHUP)
+	defer signal.Stop(guard)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go mode.watch(ctx, file, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	for _, want := range []bool{true, false} {
+		if want {
+			os.WriteFile(file, nil, 0600)
+		} else {
+			os.Remove(file)
+		}
+
+		deadline := time.Now().Add(2 * time.Second)
+		for mode.enabled.Load() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("read-only mode was not set to %v on SIGHUP", want)
+			}
+			// watch may not have installed its handler yet, so the signal is sent until it has.
+			syscall.Kill(os.Getpid(), syscall.SIGHUP)
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package bootstrap
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// panicsTotal counts the panics recovered from handlers, by the template of the route, so
+// that the label is bounded by the number of routes.
+var panicsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Subsystem: "provider",
+	Name:      "handler_panics_total",
+	Help:      "Total number of panics recovered from handlers, by route.",
+}, []string{"path"})
+
+// recoverPanics returns a middleware which recovers from a panic in a handler, logs the value
+// with a stack trace, counts it in provider_handler_panics_total and returns a 500 written by
+// httputil.WriteErrorCode, so that one malformed request is reported instead of the connection
+// being dropped without a trace. A panic in a goroutine started by a handler can not be
+// recovered here and still stops the process.
+//
+// http.ErrAbortHandler is panicked again, as it is used to abort a response on purpose.
+func recoverPanics(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+
+				path := routeTemplate(r)
+				panicsTotal.WithLabelValues(path).Inc()
+
+				logger.Error("Panic serving request", "method", r.Method, "path", r.URL.Path, "route", path,
+					"request_id", logging.RequestIDFromContext(r.Context()), "panic", fmt.Sprint(v), "stack", string(debug.Stack()))
+				httputil.WriteErrorCode(w, r, http.StatusInternalServerError, httputil.InternalError, http.StatusText(http.StatusInternalServerError))
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// routeTemplate returns the path template of the route matched for r, i.e.
+// "/system/function/{name}", or "unknown".
+func routeTemplate(r *http.Request) string {
+	if template := httputil.RouteTemplate(r); len(template) > 0 {
+		return template
+	}
+	return "unknown"
+}
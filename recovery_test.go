@@ -0,0 +1,152 @@
+package bootstrap
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/logging"
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_Server_RecoversPanics(t *testing.T) {
+	s := NewServer(&types.FaaSConfig{})
+	s.Handlers(&types.FaaSHandlers{
+		DeployFunction: func(w http.ResponseWriter, r *http.Request) {
+			var deployment *types.FunctionDeployment
+			w.Write([]byte(deployment.Service))
+		},
+		FunctionLister: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		},
+	})
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/system/functions", strings.NewReader("{}")))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status code, want: %d, got: %d", http.StatusInternalServerError, w.Code)
+	}
+	if got := w.Body.String(); strings.Contains(got, "nil pointer") {
+		t.Errorf("want a generic body, got: %q", got)
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/system/functions", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("status code after panic, want: %d, got: %d", http.StatusOK, w.Code)
+	}
+}
+
+func Test_Server_DisableRecovery(t *testing.T) {
+	s := NewServer(&types.FaaSConfig{DisableRecovery: true})
+	s.Handlers(&types.FaaSHandlers{
+		FunctionLister: func(w http.ResponseWriter, r *http.Request) {
+			panic("lister failed")
+		},
+	})
+
+	defer func() {
+		if v := recover(); v != "lister failed" {
+			t.Errorf("want the panic to reach the caller, got: %v", v)
+		}
+	}()
+
+	s.Router().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/system/functions", nil))
+}
+
+func Test_Server_PanicErrorResponse(t *testing.T) {
+	s := NewServer(&types.FaaSConfig{})
+	s.Handlers(&types.FaaSHandlers{
+		FunctionStatus: func(w http.ResponseWriter, r *http.Request) {
+			panic("status failed")
+		},
+	})
+
+	before := counterValue(t, panicsTotal.WithLabelValues("/system/function/{name:["+NameExpression+"]+}"))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/system/function/figlet", nil)
+	r.Header.Set(logging.RequestIDHeader, "req-1")
+	s.Router().ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status code, want: %d, got: %d", http.StatusInternalServerError, w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type, want: %s, got: %s", "application/json", got)
+	}
+
+	body := httputil.ErrorResponse{}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	want := httputil.ErrorResponse{Code: http.StatusInternalServerError, Message: "Internal Server Error", RequestID: "req-1", ErrorCode: httputil.InternalError}
+	if body != want {
+		t.Errorf("body, want: %+v, got: %+v", want, body)
+	}
+
+	if got := counterValue(t, panicsTotal.WithLabelValues("/system/function/{name:["+NameExpression+"]+}")) - before; got != 1 {
+		t.Errorf("panics counted, want: %d, got: %v", 1, got)
+	}
+}
+
+func Test_Server_UnmatchedRouteErrorResponse(t *testing.T) {
+	s := NewServer(&types.FaaSConfig{})
+	s.Handlers(&types.FaaSHandlers{})
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		want   int
+	}{
+		{name: "no route", method: http.MethodGet, path: "/system/unknown", want: http.StatusNotFound},
+		{name: "wrong method", method: http.MethodPatch, path: "/system/functions", want: http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.Router().ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+
+			if w.Code != tc.want {
+				t.Errorf("status code, want: %d, got: %d", tc.want, w.Code)
+			}
+
+			body := httputil.ErrorResponse{}
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Code != tc.want {
+				t.Errorf("code, want: %d, got: %d", tc.want, body.Code)
+			}
+		})
+	}
+}
+
+func Test_Server_NotImplementedProblem(t *testing.T) {
+	s := NewServer(&types.FaaSConfig{})
+	s.Handlers(validHandlers())
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/system/namespace/dev", nil)
+	r.Header.Set("Accept", httputil.ProblemContentType)
+	s.Router().ServeHTTP(w, r)
+
+	if got := w.Header().Get("Content-Type"); got != httputil.ProblemContentType {
+		t.Errorf("Content-Type, want: %s, got: %s", httputil.ProblemContentType, got)
+	}
+
+	body := httputil.Problem{}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Status != http.StatusNotImplemented || body.Code != httputil.NotImplemented {
+		t.Errorf("want a %d with the code %s, got: %+v", http.StatusNotImplemented, httputil.NotImplemented, body)
+	}
+}
@@ -0,0 +1,122 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-provider/httputil"
+)
+
+// RequestTimeoutHeader is sent by clients of the "/system/" API to bound how long they will
+// wait for a response, as a number of seconds, i.e. "X-Request-Timeout: 2.5".
+const RequestTimeoutHeader = "X-Request-Timeout"
+
+// requestTimeout sets a deadline on the context of requests to the "/system/" API which
+// carry the RequestTimeoutHeader, so that handlers which honour r.Context() give up once
+// the client has stopped waiting.
+type requestTimeout struct {
+	// max clamps the timeout asked for by clients, a value of 0 means no limit.
+	max time.Duration
+}
+
+// newRequestTimeout creates the middleware, clamping timeouts to max when it is greater than zero.
+func newRequestTimeout(max time.Duration) *requestTimeout {
+	return &requestTimeout{max: max}
+}
+
+// middleware derives the deadline from the RequestTimeoutHeader and responds with a 504
+// when it passes before the handler has written its response. A header which is not a
+// positive number of seconds is rejected with a 400.
+func (t *requestTimeout) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value := r.Header.Get(RequestTimeoutHeader)
+		if len(value) == 0 || !strings.HasPrefix(r.URL.Path, "/system/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		seconds, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || seconds <= 0 {
+			httputil.WriteErrorCode(w, r, http.StatusBadRequest, httputil.InvalidRequest, "invalid "+RequestTimeoutHeader+": must be a positive number of seconds")
+			return
+		}
+
+		timeout := time.Duration(seconds * float64(time.Second))
+		if t.max > 0 && timeout > t.max {
+			timeout = t.max
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		r = r.WithContext(ctx)
+		tw := &timeoutWriter{ResponseWriter: w, r: r}
+		next.ServeHTTP(tw, r)
+
+		if !tw.wroteHeader && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			tw.timeout()
+		}
+	})
+}
+
+// timeoutWriter replaces the response of a handler with a 504 when the deadline of ctx has
+// passed before the handler wrote its status. A response which was started before the
+// deadline, such as a stream, is left as it is.
+type timeoutWriter struct {
+	http.ResponseWriter
+	r *http.Request
+
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	if tw.wroteHeader {
+		return
+	}
+
+	if errors.Is(tw.r.Context().Err(), context.DeadlineExceeded) {
+		tw.timeout()
+		return
+	}
+
+	tw.wroteHeader = true
+	tw.ResponseWriter.WriteHeader(code)
+}
+
+func (tw *timeoutWriter) Write(data []byte) (int, error) {
+	if !tw.wroteHeader {
+		tw.WriteHeader(http.StatusOK)
+	}
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	return tw.ResponseWriter.Write(data)
+}
+
+func (tw *timeoutWriter) Flush() {
+	if tw.timedOut {
+		return
+	}
+
+	if f, ok := tw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter, so that http.ResponseController can reach
+// the connection, i.e. to extend the write deadline of a stream.
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+func (tw *timeoutWriter) timeout() {
+	tw.wroteHeader = true
+	tw.timedOut = true
+	httputil.WriteErrorCode(tw.ResponseWriter, tw.r, http.StatusGatewayTimeout, httputil.Timeout, "request timed out after "+RequestTimeoutHeader)
+}
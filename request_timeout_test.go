@@ -0,0 +1,84 @@
+package bootstrap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_requestTimeout(t *testing.T) {
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		http.Error(w, r.Context().Err().Error(), http.StatusInternalServerError)
+	})
+	fast := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); !ok && r.Header.Get(RequestTimeoutHeader) != "" && r.URL.Path != "/function/figlet" {
+			t.Errorf("want a deadline on the request context")
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	testCases := []struct {
+		name     string
+		path     string
+		timeout  string
+		max      time.Duration
+		handler  http.Handler
+		wantCode int
+	}{
+		{name: "no header", path: "/system/functions", handler: fast, wantCode: http.StatusOK},
+		{name: "completes in time", path: "/system/functions", timeout: "5", handler: fast, wantCode: http.StatusOK},
+		{name: "expires", path: "/system/functions", timeout: "0.01", handler: slow, wantCode: http.StatusGatewayTimeout},
+		{name: "clamped to max", path: "/system/functions", timeout: "60", max: 10 * time.Millisecond, handler: slow, wantCode: http.StatusGatewayTimeout},
+		{name: "invalid", path: "/system/functions", timeout: "soon", handler: fast, wantCode: http.StatusBadRequest},
+		{name: "negative", path: "/system/functions", timeout: "-1", handler: fast, wantCode: http.StatusBadRequest},
+		{name: "not a system route", path: "/function/figlet", timeout: "soon", handler: fast, wantCode: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if len(tc.timeout) > 0 {
+				req.Header.Set(RequestTimeoutHeader, tc.timeout)
+			}
+
+			w := httptest.NewRecorder()
+			newRequestTimeout(tc.max).middleware(tc.handler).ServeHTTP(w, req)
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+		})
+	}
+}
+
+// deadlineRecorder records the write deadline set through http.ResponseController.
+type deadlineRecorder struct {
+	*httptest.ResponseRecorder
+	deadline time.Time
+}
+
+func (d *deadlineRecorder) SetWriteDeadline(deadline time.Time) error {
+	d.deadline = deadline
+	return nil
+}
+
+func Test_requestTimeout_Unwrap(t *testing.T) {
+	want := time.Now().Add(time.Minute)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := http.NewResponseController(w).SetWriteDeadline(want); err != nil {
+			t.Errorf("want no error, got: %s", err)
+		}
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/system/logs", nil)
+	req.Header.Set(RequestTimeoutHeader, "5")
+
+	w := &deadlineRecorder{ResponseRecorder: httptest.NewRecorder()}
+	newRequestTimeout(0).middleware(handler).ServeHTTP(w, req)
+
+	if !w.deadline.Equal(want) {
+		t.Errorf("write deadline, want: %s, got: %s", want, w.deadline)
+	}
+}
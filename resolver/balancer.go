@@ -0,0 +1,224 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+// FailureCooldown is how long a Balancer skips an instance for after a request failed to
+// reach it.
+const FailureCooldown = 5 * time.Second
+
+// sweepInterval is how often a Balancer forgets the functions it has not balanced and the
+// failures which have cooled down, so that functions and instances which are gone are not
+// kept forever.
+const sweepInterval = time.Minute
+
+// Balancer is the Strategy returned by RoundRobin, LeastConnections and ConsistentHash, and
+// the one the proxy uses for FaaSConfig.ProxyLoadBalancing. It counts the requests in
+// flight to each instance, and skips an instance for FailureCooldown once a request was
+// released as failed, unless every instance of the function failed.
+type Balancer struct {
+	strategy string
+
+	mu     sync.Mutex
+	next   map[string]*cursor
+	active map[string]int
+	failed map[string]time.Time
+	swept  time.Time
+
+	now func() time.Time
+}
+
+// cursor is the position of a function in the round-robin, used is when it last moved.
+type cursor struct {
+	next int
+	used time.Time
+}
+
+// NewBalancer returns the Balancer for the strategy named by FaaSConfig.ProxyLoadBalancing,
+// round-robin when it is empty.
+func NewBalancer(strategy string) (*Balancer, error) {
+	switch strategy {
+	case "", types.LoadBalancingRoundRobin:
+		return newBalancer(types.LoadBalancingRoundRobin), nil
+	case types.LoadBalancingLeastConnections, types.LoadBalancingConsistentHash:
+		return newBalancer(strategy), nil
+	}
+	return nil, fmt.Errorf("unknown load balancing strategy %q", strategy)
+}
+
+func newBalancer(strategy string) *Balancer {
+	return &Balancer{
+		strategy: strategy,
+		next:     map[string]*cursor{},
+		active:   map[string]int{},
+		failed:   map[string]time.Time{},
+		now:      time.Now,
+	}
+}
+
+// RoundRobin sends requests to each instance of a function in turn.
+func RoundRobin() *Balancer {
+	return newBalancer(types.LoadBalancingRoundRobin)
+}
+
+// LeastConnections sends requests to the instance of a function with the fewest requests
+// in flight.
+func LeastConnections() *Balancer {
+	return newBalancer(types.LoadBalancingLeastConnections)
+}
+
+// ConsistentHash sends the requests with the same key, see WithKey, to the same instance
+// of a function for as long as it is listed and reachable. When an instance is added or
+// removed only the keys of that instance move. Requests without a key are sent
+// round-robin.
+func ConsistentHash() *Balancer {
+	return newBalancer(types.LoadBalancingConsistentHash)
+}
+
+// Pick selects one of instances for a request to function.
+func (b *Balancer) Pick(ctx context.Context, function string, instances []url.URL) (url.URL, ReleaseFunc) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.sweep(now)
+
+	candidates := b.healthy(instances, now)
+
+	var picked url.URL
+	key := KeyFromContext(ctx)
+	switch {
+	case b.strategy == types.LoadBalancingConsistentHash && len(key) > 0:
+		picked = HashInstance(key, candidates)
+	case b.strategy == types.LoadBalancingLeastConnections:
+		picked = candidates[0]
+		for _, c := range candidates[1:] {
+			if b.active[c.String()] < b.active[picked.String()] {
+				picked = c
+			}
+		}
+	default:
+		c, ok := b.next[function]
+		if !ok {
+			c = &cursor{}
+			b.next[function] = c
+		}
+		i := c.next % len(candidates)
+		c.next, c.used = i+1, now
+		picked = candidates[i]
+	}
+
+	address := picked.String()
+	b.active[address]++
+
+	release := func(failed bool) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		b.active[address]--
+		if b.active[address] <= 0 {
+			delete(b.active, address)
+		}
+
+		if failed {
+			b.failed[address] = b.now()
+		} else {
+			delete(b.failed, address)
+		}
+	}
+
+	return picked, release
+}
+
+// healthy returns the instances which have not failed within the cooldown, or every
+// instance when all of them failed recently. b.mu must be held.
+func (b *Balancer) healthy(instances []url.URL, now time.Time) []url.URL {
+	healthy := make([]url.URL, 0, len(instances))
+	for _, instance := range instances {
+		failedAt, ok := b.failed[instance.String()]
+		if ok && now.Sub(failedAt) < FailureCooldown {
+			continue
+		}
+		healthy = append(healthy, instance)
+	}
+
+	if len(healthy) == 0 {
+		return instances
+	}
+
+	return healthy
+}
+
+// sweep forgets the round-robin position of the functions which have not been balanced
+// within the sweepInterval, which then start again from their first instance, and the
+// failures which have cooled down. b.mu must be held.
+func (b *Balancer) sweep(now time.Time) {
+	if now.Sub(b.swept) < sweepInterval {
+		return
+	}
+	b.swept = now
+
+	for function, c := range b.next {
+		if now.Sub(c.used) >= sweepInterval {
+			delete(b.next, function)
+		}
+	}
+	for address, failedAt := range b.failed {
+		if now.Sub(failedAt) >= FailureCooldown {
+			delete(b.failed, address)
+		}
+	}
+}
+
+// State returns the requests in flight and the last failure of each instance, for
+// "/system/proxy/state".
+func (b *Balancer) State() []types.ProxyInstanceState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	byAddress := map[string]*types.ProxyInstanceState{}
+	get := func(address string) *types.ProxyInstanceState {
+		if s, ok := byAddress[address]; ok {
+			return s
+		}
+		s := &types.ProxyInstanceState{Address: address}
+		byAddress[address] = s
+		return s
+	}
+
+	for address, active := range b.active {
+		get(address).Active = active
+	}
+
+	for address, failedAt := range b.failed {
+		failedAt := failedAt
+		s := get(address)
+		s.FailedAt = &failedAt
+		s.CoolingDown = now.Sub(failedAt) < FailureCooldown
+	}
+
+	instances := make([]types.ProxyInstanceState, 0, len(byAddress))
+	for _, s := range byAddress {
+		instances = append(instances, *s)
+	}
+
+	sort.Slice(instances, func(i, j int) bool { return instances[i].Address < instances[j].Address })
+	return instances
+}
+
+// Reset forgets the failures of every instance, so that they are all tried again.
+func (b *Balancer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failed = map[string]time.Time{}
+}
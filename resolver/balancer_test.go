@@ -0,0 +1,121 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func Test_Balancer_SkipsFailedInstances(t *testing.T) {
+	testCases := []struct {
+		name     string
+		balancer *Balancer
+		ctx      context.Context
+	}{
+		{name: "round-robin", balancer: RoundRobin(), ctx: context.Background()},
+		{name: "least-connections", balancer: LeastConnections(), ctx: context.Background()},
+		{name: "consistent-hash", balancer: ConsistentHash(), ctx: WithKey(context.Background(), "session-1")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			now := time.Now()
+			b := tc.balancer
+			b.now = func() time.Time { return now }
+
+			instances := testInstances("a:8080", "b:8080", "c:8080")
+
+			failed, release := b.Pick(tc.ctx, "figlet", instances)
+			release(true)
+
+			for i := 0; i < 3; i++ {
+				got, release := b.Pick(tc.ctx, "figlet", instances)
+				release(false)
+				if got.Host == failed.Host {
+					t.Errorf("want the failed instance %s to be skipped", failed.Host)
+				}
+			}
+
+			now = now.Add(FailureCooldown)
+			seen := map[string]bool{}
+			for i := 0; i < len(instances); i++ {
+				got, release := b.Pick(tc.ctx, "figlet", instances)
+				release(false)
+				seen[got.Host] = true
+			}
+			if tc.name != "least-connections" && !seen[failed.Host] {
+				t.Errorf("want the instance %s to be used again after the cooldown, got: %v", failed.Host, seen)
+			}
+		})
+	}
+}
+
+func Test_Balancer_AllFailed(t *testing.T) {
+	b := RoundRobin()
+	instances := testInstances("a:8080")
+
+	_, release := b.Pick(context.Background(), "figlet", instances)
+	release(true)
+
+	got, release := b.Pick(context.Background(), "figlet", instances)
+	release(false)
+	if got.Host != "a:8080" {
+		t.Errorf("want the only instance to be tried when all have failed, got: %s", got.Host)
+	}
+}
+
+func Test_Balancer_Sweep(t *testing.T) {
+	now := time.Now()
+	b := RoundRobin()
+	b.now = func() time.Time { return now }
+
+	instances := testInstances("a:8080", "b:8080")
+
+	_, release := b.Pick(context.Background(), "figlet", instances)
+	release(true)
+	_, release = b.Pick(context.Background(), "env", instances)
+	release(false)
+
+	now = now.Add(sweepInterval)
+	_, release = b.Pick(context.Background(), "nodeinfo", instances)
+	release(false)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.next["figlet"]; ok {
+		t.Errorf("want the position of an idle function to be forgotten")
+	}
+	if _, ok := b.next["nodeinfo"]; !ok {
+		t.Errorf("want the position of the function just balanced to be kept")
+	}
+	if len(b.failed) != 0 {
+		t.Errorf("want failures which cooled down to be forgotten, got: %v", b.failed)
+	}
+}
+
+func Test_Balancer_StateAndReset(t *testing.T) {
+	b := LeastConnections()
+	instances := testInstances("a:8080", "b:8080")
+
+	_, releaseA := b.Pick(context.Background(), "figlet", instances)
+	releaseA(true)
+	_, releaseB := b.Pick(context.Background(), "figlet", instances)
+
+	state := b.State()
+	if len(state) != 2 {
+		t.Fatalf("instances, want: %d, got: %+v", 2, state)
+	}
+	if state[0].Address != "http://a:8080" || !state[0].CoolingDown {
+		t.Errorf("want a:8080 to be cooling down, got: %+v", state[0])
+	}
+	if state[1].Address != "http://b:8080" || state[1].Active != 1 {
+		t.Errorf("want one request in flight to b:8080, got: %+v", state[1])
+	}
+
+	releaseB(false)
+	b.Reset()
+	if state := b.State(); len(state) != 0 {
+		t.Errorf("want the state to be cleared, got: %+v", state)
+	}
+}
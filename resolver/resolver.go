@@ -0,0 +1,143 @@
+// Package resolver maps the name of a function to the address of one of its instances, with
+// a Strategy to balance the requests across them. The proxy balances the instances of a
+// proxy.MultiURLResolver with the Balancer named by FaaSConfig.ProxyLoadBalancing, or uses
+// a Resolver passed through proxy.FromResolver, so that a provider controls how instances
+// are picked, and providers which serve InvokeFunction themselves can use the same
+// Resolver:
+//
+//	r := resolver.New(resolver.SourceFunc(listInstances), resolver.ConsistentHash())
+//	addr, release, err := r.Resolve(ctx, "figlet", "openfaas-fn")
+//	if err != nil {
+//		return err
+//	}
+//	res, err := invoke(addr)
+//	release(err != nil)
+//
+// ConsistentHash keeps requests with the same key, see WithKey, on the same instance, which
+// stateful functions rely on. Serve sets the key of every invocation from the header named
+// by FaaSConfig.ProxyHashHeader.
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+)
+
+// ErrNoInstances is returned by a Resolver created with New when the function has no
+// instance to send the request to.
+var ErrNoInstances = errors.New("function has no instances")
+
+// ReleaseFunc is returned with each resolved address, it must be called once the request
+// to it has completed, with failed set when the instance could not be reached. The
+// built-in strategies then skip the instance for FailureCooldown.
+type ReleaseFunc func(failed bool)
+
+// Resolver resolves the address of an instance of the function name in namespace, for
+// one request. Errors for a function which does not exist should wrap
+// proxy.ErrFunctionNotFound.
+type Resolver interface {
+	Resolve(ctx context.Context, name, namespace string) (url.URL, ReleaseFunc, error)
+}
+
+// ResolverFunc is a Resolver implemented by a func.
+type ResolverFunc func(ctx context.Context, name, namespace string) (url.URL, ReleaseFunc, error)
+
+// Resolve calls f.
+func (f ResolverFunc) Resolve(ctx context.Context, name, namespace string) (url.URL, ReleaseFunc, error) {
+	return f(ctx, name, namespace)
+}
+
+// Source lists the addresses of every available instance of a function.
+type Source interface {
+	Instances(ctx context.Context, name, namespace string) ([]url.URL, error)
+}
+
+// SourceFunc is a Source implemented by a func.
+type SourceFunc func(ctx context.Context, name, namespace string) ([]url.URL, error)
+
+// Instances calls f.
+func (f SourceFunc) Instances(ctx context.Context, name, namespace string) ([]url.URL, error) {
+	return f(ctx, name, namespace)
+}
+
+// Strategy picks one of the instances of a function for a request. instances is never
+// empty. It is safe for concurrent use.
+type Strategy interface {
+	Pick(ctx context.Context, function string, instances []url.URL) (url.URL, ReleaseFunc)
+}
+
+// New returns a Resolver which picks one of the instances listed by source with strategy.
+func New(source Source, strategy Strategy) Resolver {
+	return ResolverFunc(func(ctx context.Context, name, namespace string) (url.URL, ReleaseFunc, error) {
+		instances, err := source.Instances(ctx, name, namespace)
+		if err != nil {
+			return url.URL{}, nil, err
+		}
+		if len(instances) == 0 {
+			return url.URL{}, nil, fmt.Errorf("%s: %w", name, ErrNoInstances)
+		}
+
+		function := name
+		if len(namespace) > 0 {
+			function = name + "." + namespace
+		}
+		addr, release := strategy.Pick(ctx, function, instances)
+		return addr, release, nil
+	})
+}
+
+// NewStrategy returns the Strategy named by FaaSConfig.ProxyLoadBalancing, RoundRobin when
+// it is empty, see NewBalancer.
+func NewStrategy(name string) (Strategy, error) {
+	b, err := NewBalancer(name)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// HashInstance returns the instance key is mapped to by rendezvous hashing: the instance
+// with the highest hash of key and its address, so that the instance only changes for a
+// key when its instance is removed, or when the instance added wins.
+func HashInstance(key string, instances []url.URL) url.URL {
+	var picked url.URL
+	var highest uint64
+	for i, instance := range instances {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		h.Write([]byte(instance.String()))
+		if sum := h.Sum64(); i == 0 || sum > highest {
+			picked, highest = instance, sum
+		}
+	}
+	return picked
+}
+
+type keyContextKey struct{}
+
+// WithKey returns a copy of ctx carrying the key ConsistentHash picks an instance by.
+func WithKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, keyContextKey{}, key)
+}
+
+// KeyFromContext returns the key set with WithKey, or an empty string.
+func KeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(keyContextKey{}).(string)
+	return key
+}
+
+// DecorateWithKey sets the value of header as the key of each request, see WithKey, when
+// the request carries it.
+func DecorateWithKey(next http.HandlerFunc, header string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if key := r.Header.Get(header); len(key) > 0 {
+			r = r.WithContext(WithKey(r.Context(), key))
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,170 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func testInstances(hosts ...string) []url.URL {
+	instances := []url.URL{}
+	for _, h := range hosts {
+		instances = append(instances, url.URL{Scheme: "http", Host: h})
+	}
+	return instances
+}
+
+func Test_RoundRobin(t *testing.T) {
+	s := RoundRobin()
+	instances := testInstances("a:8080", "b:8080", "c:8080")
+
+	want := []string{"a:8080", "b:8080", "c:8080", "a:8080"}
+	for i, w := range want {
+		got, release := s.Pick(context.Background(), "figlet", instances)
+		release(false)
+
+		if got.Host != w {
+			t.Errorf("pick %d, want: %s, got: %s", i, w, got.Host)
+		}
+	}
+
+	if got, _ := s.Pick(context.Background(), "env", instances); got.Host != "a:8080" {
+		t.Errorf("want each function to be balanced on its own, got: %s", got.Host)
+	}
+}
+
+func Test_LeastConnections(t *testing.T) {
+	s := LeastConnections()
+	instances := testInstances("a:8080", "b:8080")
+
+	first, releaseFirst := s.Pick(context.Background(), "figlet", instances)
+	second, releaseSecond := s.Pick(context.Background(), "figlet", instances)
+	if first.Host == second.Host {
+		t.Fatalf("want the second request on the idle instance, both went to %s", first.Host)
+	}
+
+	releaseSecond(false)
+	third, releaseThird := s.Pick(context.Background(), "figlet", instances)
+	if third.Host != second.Host {
+		t.Errorf("want the instance without active requests %s, got: %s", second.Host, third.Host)
+	}
+
+	releaseFirst(false)
+	releaseThird(false)
+}
+
+func Test_ConsistentHash(t *testing.T) {
+	s := ConsistentHash()
+	instances := testInstances("a:8080", "b:8080", "c:8080", "d:8080")
+
+	picked := map[string]string{}
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("session-%d", i)
+		got, _ := s.Pick(WithKey(context.Background(), key), "figlet", instances)
+		picked[key] = got.Host
+
+		if again, _ := s.Pick(WithKey(context.Background(), key), "figlet", instances); again.Host != got.Host {
+			t.Fatalf("%s: want the same instance %s, got: %s", key, got.Host, again.Host)
+		}
+	}
+
+	// Only the keys of the removed instance move.
+	remaining := testInstances("a:8080", "b:8080", "c:8080")
+	moved := 0
+	for key, host := range picked {
+		got, _ := s.Pick(WithKey(context.Background(), key), "figlet", remaining)
+		if host != "d:8080" && got.Host != host {
+			t.Errorf("%s: want to stay on %s, got: %s", key, host, got.Host)
+		}
+		if host == "d:8080" {
+			moved++
+		}
+	}
+	if moved == 0 || moved == len(picked) {
+		t.Errorf("want the keys to be spread across the instances, %d of %d on d:8080", moved, len(picked))
+	}
+
+	// Requests without a key are sent round-robin.
+	first, _ := s.Pick(context.Background(), "figlet", instances)
+	second, _ := s.Pick(context.Background(), "figlet", instances)
+	if first.Host == second.Host {
+		t.Errorf("want requests without a key on different instances, both went to %s", first.Host)
+	}
+}
+
+func Test_New(t *testing.T) {
+	errUnavailable := errors.New("unavailable")
+
+	testCases := []struct {
+		name      string
+		instances []url.URL
+		err       error
+		wantHost  string
+		wantErr   error
+	}{
+		{name: "picks an instance", instances: testInstances("a:8080"), wantHost: "a:8080"},
+		{name: "no instances", wantErr: ErrNoInstances},
+		{name: "source error", err: errUnavailable, wantErr: errUnavailable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotName, gotNamespace string
+			source := SourceFunc(func(ctx context.Context, name, namespace string) ([]url.URL, error) {
+				gotName, gotNamespace = name, namespace
+				return tc.instances, tc.err
+			})
+
+			addr, release, err := New(source, RoundRobin()).Resolve(context.Background(), "figlet", "dev")
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("error, want: %v, got: %v", tc.wantErr, err)
+			}
+			if gotName != "figlet" || gotNamespace != "dev" {
+				t.Errorf("source, want: figlet dev, got: %s %s", gotName, gotNamespace)
+			}
+			if err != nil {
+				return
+			}
+
+			release(false)
+			if addr.Host != tc.wantHost {
+				t.Errorf("host, want: %s, got: %s", tc.wantHost, addr.Host)
+			}
+		})
+	}
+}
+
+func Test_NewStrategy(t *testing.T) {
+	for _, name := range []string{"", "round-robin", "least-connections", "consistent-hash"} {
+		if _, err := NewStrategy(name); err != nil {
+			t.Errorf("%q: unexpected error: %s", name, err)
+		}
+	}
+
+	if _, err := NewStrategy("random"); err == nil {
+		t.Errorf("want an error for an unknown strategy")
+	}
+}
+
+func Test_DecorateWithKey(t *testing.T) {
+	var got string
+	handler := DecorateWithKey(func(w http.ResponseWriter, r *http.Request) {
+		got = KeyFromContext(r.Context())
+	}, "X-Session-Id")
+
+	req := httptest.NewRequest(http.MethodPost, "/function/figlet", nil)
+	req.Header.Set("X-Session-Id", "session-1")
+	handler(httptest.NewRecorder(), req)
+	if got != "session-1" {
+		t.Errorf("key, want: %s, got: %s", "session-1", got)
+	}
+
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/function/figlet", nil))
+	if got != "" {
+		t.Errorf("key, want: empty, got: %s", got)
+	}
+}
@@ -0,0 +1,379 @@
+// Package rpc serves the provider API over gRPC, as defined in provider.proto, by adapting
+// each call to a request to the HTTP API. Calls are answered by the same handlers as the
+// HTTP routes, with the same authentication and middleware, see bootstrap.ServeGRPC.
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+// Service is the full name of the service in provider.proto, methods are called on
+// "/openfaas.provider.v1.Provider/{method}".
+const Service = "openfaas.provider.v1.Provider"
+
+// maxMessageBytes is the largest request message accepted, the default of gRPC servers.
+const maxMessageBytes = 4 << 20
+
+// The status codes of gRPC, see https://grpc.github.io/grpc/core/md_doc_statuscodes.html
+const (
+	codeOK                 = 0
+	codeInvalidArgument    = 3
+	codeNotFound           = 5
+	codeAlreadyExists      = 6
+	codePermissionDenied   = 7
+	codeResourceExhausted  = 8
+	codeFailedPrecondition = 9
+	codeUnimplemented      = 12
+	codeInternal           = 13
+	codeUnavailable        = 14
+	codeUnauthenticated    = 16
+)
+
+// method adapts a gRPC method to a route of the HTTP API.
+type method struct {
+	// request builds the request to the API from the request message
+	request func(ctx context.Context, message []byte) (*http.Request, error)
+	// response builds the response message from the body of a successful response
+	response func(body []byte) ([]byte, error)
+}
+
+var methods = map[string]method{
+	"Deploy": {
+		request:  deploymentRequest(http.MethodPost),
+		response: empty,
+	},
+	"Update": {
+		request:  deploymentRequest(http.MethodPut),
+		response: empty,
+	},
+	"ListFunctions": {
+		request: func(ctx context.Context, message []byte) (*http.Request, error) {
+			namespace, err := unmarshalNamespace(message)
+			if err != nil {
+				return nil, err
+			}
+			return newRequest(ctx, http.MethodGet, "/system/functions", namespace, nil)
+		},
+		response: func(body []byte) ([]byte, error) {
+			statuses := []types.FunctionStatus{}
+			if err := json.Unmarshal(body, &statuses); err != nil {
+				return nil, err
+			}
+			return marshalFunctionStatuses(statuses), nil
+		},
+	},
+	"GetFunction": {
+		request: func(ctx context.Context, message []byte) (*http.Request, error) {
+			name, namespace, err := unmarshalGetFunction(message)
+			if err != nil {
+				return nil, err
+			}
+			if len(name) == 0 {
+				return nil, errors.New("name is required")
+			}
+			return newRequest(ctx, http.MethodGet, "/system/function/"+url.PathEscape(name), namespace, nil)
+		},
+		response: func(body []byte) ([]byte, error) {
+			status := types.FunctionStatus{}
+			if err := json.Unmarshal(body, &status); err != nil {
+				return nil, err
+			}
+			return marshalFunctionStatus(status), nil
+		},
+	},
+	"ListInstances": {
+		request: func(ctx context.Context, message []byte) (*http.Request, error) {
+			name, namespace, err := unmarshalGetFunction(message)
+			if err != nil {
+				return nil, err
+			}
+			if len(name) == 0 {
+				return nil, errors.New("name is required")
+			}
+			return newRequest(ctx, http.MethodGet, "/system/function/"+url.PathEscape(name)+"/instances", namespace, nil)
+		},
+		response: func(body []byte) ([]byte, error) {
+			instances := []types.FunctionInstance{}
+			if err := json.Unmarshal(body, &instances); err != nil {
+				return nil, err
+			}
+			return marshalFunctionInstances(instances), nil
+		},
+	},
+	"Scale": {
+		request: func(ctx context.Context, message []byte) (*http.Request, error) {
+			req, err := unmarshalScaleRequest(message)
+			if err != nil {
+				return nil, err
+			}
+			if len(req.ServiceName) == 0 {
+				return nil, errors.New("service_name is required")
+			}
+			return newJSONRequest(ctx, http.MethodPost, "/system/scale-function/"+url.PathEscape(req.ServiceName), req)
+		},
+		response: empty,
+	},
+	"Register": {
+		request: func(ctx context.Context, message []byte) (*http.Request, error) {
+			req, err := unmarshalRegisterRequest(message)
+			if err != nil {
+				return nil, err
+			}
+			return newJSONRequest(ctx, http.MethodPost, "/system/register", req)
+		},
+		response: func(body []byte) ([]byte, error) {
+			res := types.RegisterResponse{}
+			if len(bytes.TrimSpace(body)) > 0 {
+				if err := json.Unmarshal(body, &res); err != nil {
+					return nil, err
+				}
+			}
+			return marshalRegisterResponse(res), nil
+		},
+	},
+	"ListCheckpoints": {
+		request: func(ctx context.Context, message []byte) (*http.Request, error) {
+			namespace, err := unmarshalNamespace(message)
+			if err != nil {
+				return nil, err
+			}
+			return newRequest(ctx, http.MethodGet, "/system/checkpoints", namespace, nil)
+		},
+		response: func(body []byte) ([]byte, error) {
+			checkpoints := []types.Checkpoint{}
+			if err := json.Unmarshal(body, &checkpoints); err != nil {
+				return nil, err
+			}
+			return marshalCheckpoints(checkpoints), nil
+		},
+	},
+}
+
+func deploymentRequest(httpMethod string) func(ctx context.Context, message []byte) (*http.Request, error) {
+	return func(ctx context.Context, message []byte) (*http.Request, error) {
+		d, err := unmarshalFunctionDeployment(message)
+		if err != nil {
+			return nil, err
+		}
+		return newJSONRequest(ctx, httpMethod, "/system/functions", d)
+	}
+}
+
+func empty(body []byte) ([]byte, error) {
+	return nil, nil
+}
+
+func newRequest(ctx context.Context, httpMethod, path, namespace string, body io.Reader) (*http.Request, error) {
+	if len(namespace) > 0 {
+		path += "?namespace=" + url.QueryEscape(namespace)
+	}
+	return http.NewRequestWithContext(ctx, httpMethod, path, body)
+}
+
+func newJSONRequest(ctx context.Context, httpMethod, path string, v interface{}) (*http.Request, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, httpMethod, path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// NewHandler creates an http.Handler which answers unary calls to the Provider service
+// by sending a request to api, usually the router of a bootstrap.Server. The metadata of
+// a call, such as "authorization", is passed on as the headers of the request, and a
+// response which is not a 2xx is returned as the closest gRPC status.
+//
+// gRPC clients connect with HTTP/2, so the handler must be served over TLS or with h2c.
+// Compressed messages are not supported.
+func NewHandler(api http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+
+		m, ok := methods[strings.TrimPrefix(r.URL.Path, "/"+Service+"/")]
+		if !ok || !strings.HasPrefix(r.URL.Path, "/"+Service+"/") {
+			writeStatus(w, codeUnimplemented, "unknown method "+r.URL.Path)
+			return
+		}
+
+		message, code, err := readMessage(r.Body)
+		if err != nil {
+			writeStatus(w, code, err.Error())
+			return
+		}
+
+		req, err := m.request(r.Context(), message)
+		if err != nil {
+			writeStatus(w, codeInvalidArgument, err.Error())
+			return
+		}
+		copyMetadata(req.Header, r.Header)
+		req.RemoteAddr = r.RemoteAddr
+		req.Host = r.Host
+		req.TLS = r.TLS
+
+		rec := &recorder{header: http.Header{}}
+		api.ServeHTTP(rec, req)
+
+		status := rec.status()
+		if status < 200 || status > 299 {
+			writeStatus(w, codeForStatus(status), strings.TrimSpace(rec.body.String()))
+			return
+		}
+
+		res, err := m.response(rec.body.Bytes())
+		if err != nil {
+			writeStatus(w, codeInternal, fmt.Sprintf("unable to read the response of the API: %s", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		w.WriteHeader(http.StatusOK)
+
+		frame := make([]byte, 5, 5+len(res))
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(res)))
+		w.Write(append(frame, res...))
+
+		w.Header().Set("Grpc-Status", strconv.Itoa(codeOK))
+	})
+}
+
+// readMessage reads the single, length-prefixed, message of a unary call.
+func readMessage(body io.Reader) ([]byte, int, error) {
+	prefix := make([]byte, 5)
+	if _, err := io.ReadFull(body, prefix); err != nil {
+		return nil, codeInvalidArgument, fmt.Errorf("unable to read the request message: %w", err)
+	}
+
+	if prefix[0] != 0 {
+		return nil, codeUnimplemented, errors.New("compressed messages are not supported")
+	}
+
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if size > maxMessageBytes {
+		return nil, codeResourceExhausted, fmt.Errorf("request message must not be larger than %d bytes", maxMessageBytes)
+	}
+
+	message := make([]byte, size)
+	if _, err := io.ReadFull(body, message); err != nil {
+		return nil, codeInvalidArgument, fmt.Errorf("unable to read the request message: %w", err)
+	}
+	return message, codeOK, nil
+}
+
+// copyMetadata passes the metadata of a call on to the request to the API, apart from
+// the headers which describe the gRPC request itself.
+func copyMetadata(dst, src http.Header) {
+	for k, vv := range src {
+		switch k = http.CanonicalHeaderKey(k); {
+		case k == "Content-Type", k == "Content-Length", k == "Te", strings.HasPrefix(k, "Grpc-"):
+			continue
+		}
+		for _, v := range vv {
+			dst.Add(k, v)
+		}
+	}
+}
+
+// codeForStatus maps the status code of a response from the API to a gRPC status.
+func codeForStatus(status int) int {
+	switch status {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return codeInvalidArgument
+	case http.StatusUnauthorized:
+		return codeUnauthenticated
+	case http.StatusForbidden:
+		return codePermissionDenied
+	case http.StatusNotFound:
+		return codeNotFound
+	case http.StatusConflict:
+		return codeAlreadyExists
+	case http.StatusTooManyRequests:
+		return codeResourceExhausted
+	case http.StatusNotImplemented, http.StatusMethodNotAllowed:
+		return codeUnimplemented
+	case http.StatusServiceUnavailable:
+		return codeUnavailable
+	}
+
+	if status >= 400 && status < 500 {
+		return codeFailedPrecondition
+	}
+	return codeInternal
+}
+
+// writeStatus ends a call without a response message, with the status in the trailers.
+func writeStatus(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	w.Header().Set("Grpc-Message", encodeMessage(message))
+}
+
+// encodeMessage percent-encodes a status message for the grpc-message trailer.
+func encodeMessage(message string) string {
+	var b strings.Builder
+	for i := 0; i < len(message); i++ {
+		c := message[i]
+		if c < 0x20 || c > 0x7e || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// recorder buffers the response of the API to a call.
+type recorder struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.code == 0 {
+		r.code = http.StatusOK
+	}
+	return r.body.Write(b)
+}
+
+func (r *recorder) WriteHeader(code int) {
+	if r.code == 0 {
+		r.code = code
+	}
+}
+
+func (r *recorder) status() int {
+	if r.code == 0 {
+		return http.StatusOK
+	}
+	return r.code
+}
@@ -0,0 +1,161 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/openfaas/faas-provider/types"
+)
+
+func frame(message []byte) []byte {
+	b := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(b[1:], uint32(len(message)))
+	return append(b, message...)
+}
+
+func call(t *testing.T, h http.Handler, method string, message []byte, header http.Header) (*http.Response, []byte) {
+	t.Helper()
+
+	r := httptest.NewRequest(http.MethodPost, "/"+Service+"/"+method, bytes.NewReader(frame(message)))
+	r.Header.Set("Content-Type", "application/grpc")
+	for k, v := range header {
+		r.Header[k] = v
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	res := w.Result()
+	body, _ := io.ReadAll(res.Body)
+	if len(body) == 0 {
+		return res, nil
+	}
+	if len(body) < 5 || int(binary.BigEndian.Uint32(body[1:5])) != len(body)-5 {
+		t.Fatalf("want a length-prefixed message, got: %q", body)
+	}
+	return res, body[5:]
+}
+
+func newAPI() *mux.Router {
+	r := mux.NewRouter()
+	r.HandleFunc("/system/functions", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode([]types.FunctionStatus{{Name: "figlet", Namespace: r.URL.Query().Get("namespace"), Replicas: 2}})
+	}).Methods(http.MethodGet)
+	r.HandleFunc("/system/functions", func(w http.ResponseWriter, r *http.Request) {
+		d := types.FunctionDeployment{}
+		json.NewDecoder(r.Body).Decode(&d)
+		if d.Service != "figlet" {
+			http.Error(w, "service is required", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}).Methods(http.MethodPost)
+	r.HandleFunc("/system/scale-function/{name}", func(w http.ResponseWriter, r *http.Request) {
+		req := types.ScaleServiceRequest{}
+		json.NewDecoder(r.Body).Decode(&req)
+		if mux.Vars(r)["name"] != "figlet" || req.Replicas != 3 {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods(http.MethodPost)
+	r.HandleFunc("/system/function/{name}/instances", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]types.FunctionInstance{{ID: mux.Vars(r)["name"] + "-1", Address: "10.0.0.2:8080"}})
+	}).Methods(http.MethodGet)
+	return r
+}
+
+func Test_NewHandler_ListFunctions(t *testing.T) {
+	h := NewHandler(newAPI())
+
+	e := encoder{}
+	e.string(1, "openfaas-fn")
+
+	res, message := call(t, h, "ListFunctions", e, http.Header{"Authorization": {"Bearer token"}})
+	if got := res.Trailer.Get("Grpc-Status"); got != "0" {
+		t.Fatalf("grpc-status, want: %s, got: %s (%s)", "0", got, res.Trailer.Get("Grpc-Message"))
+	}
+
+	var got []types.FunctionStatus
+	err := decode(message, func(f field) error {
+		status, err := unmarshalFunctionStatus(f.bytes)
+		got = append(got, status)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+
+	if len(got) != 1 || got[0].Name != "figlet" || got[0].Namespace != "openfaas-fn" || got[0].Replicas != 2 {
+		t.Errorf("functions, want: figlet in openfaas-fn with 2 replicas, got: %+v", got)
+	}
+}
+
+func Test_NewHandler_Status(t *testing.T) {
+	h := NewHandler(newAPI())
+
+	deploy := marshalFunctionDeployment(types.FunctionDeployment{Service: "figlet"})
+	scale := encoder{}
+	scale.string(1, "figlet")
+	scale.uint64(3, 3)
+	missing := encoder{}
+	missing.string(1, "env")
+	missing.uint64(3, 3)
+
+	cases := []struct {
+		name        string
+		method      string
+		message     []byte
+		wantStatus  string
+		wantMessage string
+	}{
+		{"deploy", "Deploy", deploy, "0", ""},
+		{"invalid deployment", "Deploy", nil, "3", "service is required"},
+		{"scale", "Scale", scale, "0", ""},
+		{"scale missing function", "Scale", missing, "5", "not found"},
+		{"scale without name", "Scale", nil, "3", "service_name is required"},
+		{"list instances", "ListInstances", scale, "0", ""},
+		{"list instances without name", "ListInstances", nil, "3", "name is required"},
+		{"unauthenticated", "ListFunctions", nil, "16", "unauthorized"},
+		{"unknown method", "Delete", nil, "12", "unknown method /" + Service + "/Delete"},
+		{"no route", "ListCheckpoints", nil, "5", "404 page not found"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, _ := call(t, h, tc.method, tc.message, nil)
+
+			if got := res.Trailer.Get("Grpc-Status"); got != tc.wantStatus {
+				t.Errorf("grpc-status, want: %s, got: %s", tc.wantStatus, got)
+			}
+			if got := res.Trailer.Get("Grpc-Message"); got != tc.wantMessage {
+				t.Errorf("grpc-message, want: %q, got: %q", tc.wantMessage, got)
+			}
+		})
+	}
+}
+
+func Test_NewHandler_RejectsOtherRequests(t *testing.T) {
+	w := httptest.NewRecorder()
+	NewHandler(newAPI()).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/system/functions", nil))
+
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("status code, want: %d, got: %d", http.StatusUnsupportedMediaType, w.Code)
+	}
+}
+
+func Test_encodeMessage(t *testing.T) {
+	if got, want := encodeMessage("100% done\n"), "100%25 done%0A"; got != want {
+		t.Errorf("want: %q, got: %q", want, got)
+	}
+}
@@ -0,0 +1,222 @@
+package rpc
+
+import (
+	"github.com/openfaas/faas-provider/types"
+)
+
+// The functions below convert between the messages of provider.proto and the types of the
+// HTTP API, so that requests can be answered by the same handlers.
+
+func marshalResources(r *types.FunctionResources) []byte {
+	e := encoder{}
+	e.string(1, r.Memory)
+	e.string(2, r.CPU)
+	return e
+}
+
+func unmarshalResources(b []byte) (*types.FunctionResources, error) {
+	r := &types.FunctionResources{}
+	err := decode(b, func(f field) error {
+		switch f.num {
+		case 1:
+			r.Memory = f.str()
+		case 2:
+			r.CPU = f.str()
+		}
+		return nil
+	})
+	return r, err
+}
+
+func unmarshalFunctionDeployment(b []byte) (types.FunctionDeployment, error) {
+	d := types.FunctionDeployment{}
+	err := decode(b, func(f field) error {
+		var err error
+		switch f.num {
+		case 1:
+			d.Service = f.str()
+		case 2:
+			d.Image = f.str()
+		case 3:
+			d.Namespace = f.str()
+		case 4:
+			d.EnvProcess = f.str()
+		case 5:
+			if d.EnvVars == nil {
+				d.EnvVars = map[string]string{}
+			}
+			err = decodeMapEntry(f, d.EnvVars)
+		case 6:
+			d.Constraints = append(d.Constraints, f.str())
+		case 7:
+			d.Secrets = append(d.Secrets, f.str())
+		case 8:
+			if d.Labels == nil {
+				d.Labels = &map[string]string{}
+			}
+			err = decodeMapEntry(f, *d.Labels)
+		case 9:
+			if d.Annotations == nil {
+				d.Annotations = &map[string]string{}
+			}
+			err = decodeMapEntry(f, *d.Annotations)
+		case 10:
+			d.Limits, err = unmarshalResources(f.bytes)
+		case 11:
+			d.Requests, err = unmarshalResources(f.bytes)
+		case 12:
+			d.ReadOnlyRootFilesystem = f.uint64() != 0
+		case 13:
+			d.Language = f.str()
+		case 14:
+			d.SnapshotIds = append(d.SnapshotIds, f.str())
+		}
+		return err
+	})
+	return d, err
+}
+
+func marshalFunctionStatus(s types.FunctionStatus) []byte {
+	e := encoder{}
+	e.string(1, s.Name)
+	e.string(2, s.Image)
+	e.string(3, s.Namespace)
+	e.string(4, s.EnvProcess)
+	e.stringMap(5, s.EnvVars)
+	e.strings(6, s.Constraints)
+	e.strings(7, s.Secrets)
+	if s.Labels != nil {
+		e.stringMap(8, *s.Labels)
+	}
+	if s.Annotations != nil {
+		e.stringMap(9, *s.Annotations)
+	}
+	if s.Limits != nil {
+		e.message(10, marshalResources(s.Limits))
+	}
+	if s.Requests != nil {
+		e.message(11, marshalResources(s.Requests))
+	}
+	e.bool(12, s.ReadOnlyRootFilesystem)
+	e.double(13, s.InvocationCount)
+	e.uint64(14, s.Replicas)
+	e.uint64(15, s.AvailableReplicas)
+	e.string(16, string(s.Phase))
+	e.string(17, s.FailureReason)
+	if !s.CreatedAt.IsZero() {
+		e.int64(18, s.CreatedAt.UnixNano())
+	}
+	return e
+}
+
+func marshalFunctionStatuses(statuses []types.FunctionStatus) []byte {
+	e := encoder{}
+	for _, s := range statuses {
+		e.message(1, marshalFunctionStatus(s))
+	}
+	return e
+}
+
+// unmarshalNamespace reads a request whose only field is the namespace, such as
+// ListFunctionsRequest and ListCheckpointsRequest.
+func unmarshalNamespace(b []byte) (string, error) {
+	namespace := ""
+	err := decode(b, func(f field) error {
+		if f.num == 1 {
+			namespace = f.str()
+		}
+		return nil
+	})
+	return namespace, err
+}
+
+// unmarshalGetFunction reads a GetFunctionRequest, or a ListInstancesRequest which has the
+// same fields, returning the name and namespace.
+func unmarshalGetFunction(b []byte) (string, string, error) {
+	name, namespace := "", ""
+	err := decode(b, func(f field) error {
+		switch f.num {
+		case 1:
+			name = f.str()
+		case 2:
+			namespace = f.str()
+		}
+		return nil
+	})
+	return name, namespace, err
+}
+
+func marshalFunctionInstances(instances []types.FunctionInstance) []byte {
+	e := encoder{}
+	for _, i := range instances {
+		m := encoder{}
+		m.string(1, i.ID)
+		m.string(2, i.Address)
+		m.string(3, i.Node)
+		m.string(4, i.State)
+		if !i.StartedAt.IsZero() {
+			m.int64(5, i.StartedAt.UnixNano())
+		}
+		e.message(1, m)
+	}
+	return e
+}
+
+func unmarshalScaleRequest(b []byte) (types.ScaleServiceRequest, error) {
+	r := types.ScaleServiceRequest{}
+	err := decode(b, func(f field) error {
+		switch f.num {
+		case 1:
+			r.ServiceName = f.str()
+		case 2:
+			r.Namespace = f.str()
+		case 3:
+			r.Replicas = f.uint64()
+		}
+		return nil
+	})
+	return r, err
+}
+
+func unmarshalRegisterRequest(b []byte) (types.RegisterRequest, error) {
+	r := types.RegisterRequest{}
+	err := decode(b, func(f field) error {
+		switch f.num {
+		case 1:
+			r.Name = f.str()
+		case 2:
+			r.Namespace = f.str()
+		case 3:
+			r.Address = f.str()
+		case 4:
+			r.Ready = f.uint64() != 0
+		}
+		return nil
+	})
+	return r, err
+}
+
+func marshalRegisterResponse(r types.RegisterResponse) []byte {
+	e := encoder{}
+	e.string(1, r.Name)
+	e.string(2, r.Namespace)
+	e.string(3, r.Address)
+	return e
+}
+
+func marshalCheckpoints(checkpoints []types.Checkpoint) []byte {
+	e := encoder{}
+	for _, c := range checkpoints {
+		m := encoder{}
+		m.string(1, c.Function)
+		m.string(2, c.Namespace)
+		m.string(3, c.ID)
+		if !c.CreatedAt.IsZero() {
+			m.int64(4, c.CreatedAt.UnixNano())
+		}
+		m.int64(5, c.SizeBytes)
+		m.string(6, c.Image)
+		e.message(1, m)
+	}
+	return e
+}
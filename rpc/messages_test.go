@@ -0,0 +1,148 @@
+package rpc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+// marshalFunctionDeployment and unmarshalFunctionStatus are the client side of the
+// conversions, used to check that each message round trips.
+func marshalFunctionDeployment(d types.FunctionDeployment) []byte {
+	e := encoder{}
+	e.string(1, d.Service)
+	e.string(2, d.Image)
+	e.string(3, d.Namespace)
+	e.string(4, d.EnvProcess)
+	e.stringMap(5, d.EnvVars)
+	e.strings(6, d.Constraints)
+	e.strings(7, d.Secrets)
+	if d.Labels != nil {
+		e.stringMap(8, *d.Labels)
+	}
+	if d.Annotations != nil {
+		e.stringMap(9, *d.Annotations)
+	}
+	if d.Limits != nil {
+		e.message(10, marshalResources(d.Limits))
+	}
+	if d.Requests != nil {
+		e.message(11, marshalResources(d.Requests))
+	}
+	e.bool(12, d.ReadOnlyRootFilesystem)
+	e.string(13, d.Language)
+	e.strings(14, d.SnapshotIds)
+	return e
+}
+
+func unmarshalFunctionStatus(b []byte) (types.FunctionStatus, error) {
+	s := types.FunctionStatus{}
+	err := decode(b, func(f field) error {
+		var err error
+		switch f.num {
+		case 1:
+			s.Name = f.str()
+		case 2:
+			s.Image = f.str()
+		case 3:
+			s.Namespace = f.str()
+		case 4:
+			s.EnvProcess = f.str()
+		case 5:
+			if s.EnvVars == nil {
+				s.EnvVars = map[string]string{}
+			}
+			err = decodeMapEntry(f, s.EnvVars)
+		case 6:
+			s.Constraints = append(s.Constraints, f.str())
+		case 7:
+			s.Secrets = append(s.Secrets, f.str())
+		case 8:
+			if s.Labels == nil {
+				s.Labels = &map[string]string{}
+			}
+			err = decodeMapEntry(f, *s.Labels)
+		case 9:
+			if s.Annotations == nil {
+				s.Annotations = &map[string]string{}
+			}
+			err = decodeMapEntry(f, *s.Annotations)
+		case 10:
+			s.Limits, err = unmarshalResources(f.bytes)
+		case 11:
+			s.Requests, err = unmarshalResources(f.bytes)
+		case 12:
+			s.ReadOnlyRootFilesystem = f.uint64() != 0
+		case 13:
+			s.InvocationCount = f.double()
+		case 14:
+			s.Replicas = f.uint64()
+		case 15:
+			s.AvailableReplicas = f.uint64()
+		case 16:
+			s.Phase = types.FunctionPhase(f.str())
+		case 17:
+			s.FailureReason = f.str()
+		case 18:
+			s.CreatedAt = time.Unix(0, int64(f.uint64()))
+		}
+		return err
+	})
+	return s, err
+}
+
+func Test_FunctionDeployment_RoundTrip(t *testing.T) {
+	labels := map[string]string{"com.openfaas.scale.min": "1"}
+	want := types.FunctionDeployment{
+		Service:                "figlet",
+		Image:                  "ghcr.io/openfaas/figlet:latest",
+		Namespace:              "openfaas-fn",
+		EnvVars:                map[string]string{"write_debug": "true", "fprocess": "figlet"},
+		Constraints:            []string{"node.platform.os == linux"},
+		Secrets:                []string{"api-key"},
+		Labels:                 &labels,
+		Limits:                 &types.FunctionResources{Memory: "128Mi"},
+		ReadOnlyRootFilesystem: true,
+		SnapshotIds:            []string{"snap-1", "snap-2"},
+	}
+
+	got, err := unmarshalFunctionDeployment(marshalFunctionDeployment(want))
+	if err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("deployment, want: %+v, got: %+v", want, got)
+	}
+}
+
+func Test_FunctionStatus_RoundTrip(t *testing.T) {
+	want := types.FunctionStatus{
+		Name:              "figlet",
+		Image:             "ghcr.io/openfaas/figlet:latest",
+		InvocationCount:   1.5,
+		Replicas:          3,
+		AvailableReplicas: 2,
+		Phase:             types.FunctionPhaseRunning,
+		CreatedAt:         time.Unix(1700000000, 5),
+		Requests:          &types.FunctionResources{CPU: "100m"},
+	}
+
+	got, err := unmarshalFunctionStatus(marshalFunctionStatus(want))
+	if err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("status, want: %+v, got: %+v", want, got)
+	}
+}
+
+func Test_decode_Truncated(t *testing.T) {
+	e := encoder{}
+	e.string(1, "figlet")
+
+	if _, err := unmarshalFunctionDeployment(e[:len(e)-1]); err == nil {
+		t.Errorf("want an error for a truncated message")
+	}
+}
@@ -0,0 +1,433 @@
+package rpc
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-provider/types"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// The messages are encoded by hand in messages.go, so the tests below check them against
+// provider.proto: each message is decoded with the descriptor of the .proto and compared to
+// its golden text format in testdata, and each request in testdata is encoded from the
+// descriptor and read back.
+
+// loadProto parses provider.proto into a descriptor. It reads the subset of proto3 the file
+// uses: messages of scalar, message, repeated and map<string, string> fields, and a service
+// of unary methods.
+func loadProto(t *testing.T) protoreflect.FileDescriptor {
+	t.Helper()
+
+	src, err := os.ReadFile("provider.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &protoParser{t: t, tokens: tokenizeProto(string(src))}
+	file := &descriptorpb.FileDescriptorProto{
+		Name:   proto.String("provider.proto"),
+		Syntax: proto.String("proto3"),
+	}
+
+	for !p.done() {
+		switch keyword := p.next(); keyword {
+		case "syntax", "option":
+			p.skipTo(";")
+		case "package":
+			file.Package = proto.String(p.next())
+			p.expect(";")
+		case "message":
+			file.MessageType = append(file.MessageType, p.message(file))
+		case "service":
+			file.Service = append(file.Service, p.service(file))
+		default:
+			t.Fatalf("provider.proto: unexpected %q", keyword)
+		}
+	}
+
+	fd, err := protodesc.NewFile(file, nil)
+	if err != nil {
+		t.Fatalf("provider.proto: %s", err)
+	}
+	return fd
+}
+
+// tokenizeProto splits src into identifiers, numbers, strings and punctuation, without
+// comments.
+func tokenizeProto(src string) []string {
+	var tokens []string
+	for _, line := range strings.Split(src, "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		for _, punct := range []string{"{", "}", "(", ")", "<", ">", ",", ";", "="} {
+			line = strings.ReplaceAll(line, punct, " "+punct+" ")
+		}
+		tokens = append(tokens, strings.Fields(line)...)
+	}
+	return tokens
+}
+
+type protoParser struct {
+	t      *testing.T
+	tokens []string
+}
+
+func (p *protoParser) done() bool {
+	return len(p.tokens) == 0
+}
+
+func (p *protoParser) next() string {
+	if p.done() {
+		return ""
+	}
+	token := p.tokens[0]
+	p.tokens = p.tokens[1:]
+	return token
+}
+
+func (p *protoParser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[0]
+}
+
+func (p *protoParser) expect(want string) {
+	if got := p.next(); got != want {
+		p.t.Fatalf("provider.proto: want %q, got: %q", want, got)
+	}
+}
+
+func (p *protoParser) skipTo(token string) {
+	for !p.done() && p.next() != token {
+	}
+}
+
+var scalarTypes = map[string]descriptorpb.FieldDescriptorProto_Type{
+	"string": descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	"bool":   descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+	"double": descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+	"int64":  descriptorpb.FieldDescriptorProto_TYPE_INT64,
+	"uint64": descriptorpb.FieldDescriptorProto_TYPE_UINT64,
+}
+
+// fieldDescriptor returns a field of type typeName, a scalar or a message of the package.
+func fieldDescriptor(file *descriptorpb.FileDescriptorProto, name, typeName string, number int32, label descriptorpb.FieldDescriptorProto_Label) *descriptorpb.FieldDescriptorProto {
+	f := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		JsonName: proto.String(jsonName(name)),
+		Number:   proto.Int32(number),
+		Label:    label.Enum(),
+	}
+	if typ, ok := scalarTypes[typeName]; ok {
+		f.Type = typ.Enum()
+	} else {
+		f.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+		f.TypeName = proto.String("." + file.GetPackage() + "." + typeName)
+	}
+	return f
+}
+
+func jsonName(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if len(parts[i]) > 0 {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+func (p *protoParser) message(file *descriptorpb.FileDescriptorProto) *descriptorpb.DescriptorProto {
+	m := &descriptorpb.DescriptorProto{Name: proto.String(p.next())}
+	p.expect("{")
+
+	for p.peek() != "}" {
+		if p.done() {
+			p.t.Fatalf("provider.proto: message %s is not closed", m.GetName())
+		}
+
+		label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+		typeName := p.next()
+		switch typeName {
+		case "repeated":
+			label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+			typeName = p.next()
+		case "map":
+			p.expect("<")
+			key := p.next()
+			p.expect(",")
+			value := p.next()
+			p.expect(">")
+			if key != "string" || value != "string" {
+				p.t.Fatalf("provider.proto: only map<string, string> is supported, got: map<%s, %s>", key, value)
+			}
+
+			// A map is a repeated entry message with the key and value in fields 1 and 2.
+			name, number := p.fieldName(m)
+			entry := jsonName(name)
+			entry = strings.ToUpper(entry[:1]) + entry[1:] + "Entry"
+			m.NestedType = append(m.NestedType, &descriptorpb.DescriptorProto{
+				Name: proto.String(entry),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					fieldDescriptor(file, "key", "string", 1, label),
+					fieldDescriptor(file, "value", "string", 2, label),
+				},
+				Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+			})
+			m.Field = append(m.Field, fieldDescriptor(file, name, m.GetName()+"."+entry, number, descriptorpb.FieldDescriptorProto_LABEL_REPEATED))
+			continue
+		}
+
+		name, number := p.fieldName(m)
+		m.Field = append(m.Field, fieldDescriptor(file, name, typeName, number, label))
+	}
+	p.expect("}")
+
+	return m
+}
+
+// fieldName reads the "name = number;" which ends the declaration of a field of m.
+func (p *protoParser) fieldName(m *descriptorpb.DescriptorProto) (string, int32) {
+	name := p.next()
+	p.expect("=")
+	number, err := strconv.Atoi(p.next())
+	if err != nil {
+		p.t.Fatalf("provider.proto: field %s.%s: %s", m.GetName(), name, err)
+	}
+	p.expect(";")
+	return name, int32(number)
+}
+
+func (p *protoParser) service(file *descriptorpb.FileDescriptorProto) *descriptorpb.ServiceDescriptorProto {
+	s := &descriptorpb.ServiceDescriptorProto{Name: proto.String(p.next())}
+	p.expect("{")
+
+	for p.peek() != "}" {
+		if p.done() {
+			p.t.Fatalf("provider.proto: service %s is not closed", s.GetName())
+		}
+
+		p.expect("rpc")
+		m := &descriptorpb.MethodDescriptorProto{Name: proto.String(p.next())}
+		p.expect("(")
+		m.InputType = proto.String("." + file.GetPackage() + "." + p.next())
+		p.expect(")")
+		p.expect("returns")
+		p.expect("(")
+		m.OutputType = proto.String("." + file.GetPackage() + "." + p.next())
+		p.expect(")")
+		p.expect(";")
+
+		s.Method = append(s.Method, m)
+	}
+	p.expect("}")
+
+	return s
+}
+
+// readGolden reads testdata/name.textproto into a message of the type name.
+func readGolden(t *testing.T, fd protoreflect.FileDescriptor, name string) *dynamicpb.Message {
+	t.Helper()
+
+	desc := fd.Messages().ByName(protoreflect.Name(name))
+	if desc == nil {
+		t.Fatalf("provider.proto has no message %s", name)
+	}
+
+	golden, err := os.ReadFile(filepath.Join("testdata", name+".textproto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := dynamicpb.NewMessage(desc)
+	if err := prototext.Unmarshal(golden, m); err != nil {
+		t.Fatalf("testdata/%s.textproto: %s", name, err)
+	}
+	return m
+}
+
+func Test_Service_MatchesProto(t *testing.T) {
+	fd := loadProto(t)
+
+	service := fd.Services().ByName("Provider")
+	if service == nil {
+		t.Fatalf("provider.proto has no service Provider")
+	}
+	if got := string(service.FullName()); got != Service {
+		t.Errorf("service, want: %s, got: %s", Service, got)
+	}
+
+	var declared, served []string
+	for i := 0; i < service.Methods().Len(); i++ {
+		declared = append(declared, string(service.Methods().Get(i).Name()))
+	}
+	for name := range methods {
+		served = append(served, name)
+	}
+	sort.Strings(declared)
+	sort.Strings(served)
+
+	if !reflect.DeepEqual(declared, served) {
+		t.Errorf("methods, want: %v, got: %v", declared, served)
+	}
+}
+
+func Test_Responses_Golden(t *testing.T) {
+	fd := loadProto(t)
+
+	labels := map[string]string{"com.openfaas.scale.min": "1"}
+	status := types.FunctionStatus{
+		Name:                   "figlet",
+		Image:                  "ghcr.io/openfaas/figlet:latest",
+		Namespace:              "openfaas-fn",
+		EnvProcess:             "figlet",
+		EnvVars:                map[string]string{"write_debug": "true"},
+		Constraints:            []string{"node.platform.os == linux"},
+		Secrets:                []string{"api-key"},
+		Labels:                 &labels,
+		Annotations:            &map[string]string{"topic": "cron"},
+		Limits:                 &types.FunctionResources{Memory: "128Mi"},
+		Requests:               &types.FunctionResources{CPU: "100m"},
+		ReadOnlyRootFilesystem: true,
+		InvocationCount:        1.5,
+		Replicas:               3,
+		AvailableReplicas:      2,
+		Phase:                  types.FunctionPhaseRunning,
+		FailureReason:          "none",
+		CreatedAt:              time.Unix(1700000000, 5),
+	}
+
+	cases := []struct {
+		message string
+		got     []byte
+	}{
+		{"FunctionStatus", marshalFunctionStatus(status)},
+		{"ListFunctionsResponse", marshalFunctionStatuses([]types.FunctionStatus{{Name: "figlet"}, {Name: "env", Replicas: 1}})},
+		{"RegisterResponse", marshalRegisterResponse(types.RegisterResponse{Name: "figlet", Namespace: "openfaas-fn", Address: "10.0.0.2:8080"})},
+		{"ListCheckpointsResponse", marshalCheckpoints([]types.Checkpoint{
+			{Function: "figlet", Namespace: "openfaas-fn", ID: "figlet-1", CreatedAt: time.Unix(1700000000, 0), SizeBytes: 1024, Image: "ghcr.io/openfaas/figlet:latest"},
+		})},
+		{"ListInstancesResponse", marshalFunctionInstances([]types.FunctionInstance{
+			{ID: "figlet-1", Address: "10.0.0.2:8080", Node: "node-1", State: "running", StartedAt: time.Unix(1700000000, 0)},
+			{ID: "figlet-2", State: "checkpointed"},
+		})},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.message, func(t *testing.T) {
+			want := readGolden(t, fd, tc.message)
+
+			got := dynamicpb.NewMessage(want.Descriptor())
+			if err := proto.Unmarshal(tc.got, got); err != nil {
+				t.Fatalf("want no error, got: %s", err)
+			}
+			if len(got.GetUnknown()) > 0 {
+				t.Errorf("want every field to be declared in provider.proto, got unknown fields: %x", got.GetUnknown())
+			}
+			if !proto.Equal(want, got) {
+				t.Errorf("message, want: %s, got: %s", prototext.Format(want), prototext.Format(got))
+			}
+		})
+	}
+}
+
+func Test_Requests_Golden(t *testing.T) {
+	fd := loadProto(t)
+
+	labels := map[string]string{"com.openfaas.scale.min": "1"}
+	annotations := map[string]string{"topic": "cron"}
+
+	cases := []struct {
+		message   string
+		unmarshal func([]byte) (interface{}, error)
+		want      interface{}
+	}{
+		{
+			message:   "FunctionDeployment",
+			unmarshal: func(b []byte) (interface{}, error) { return unmarshalFunctionDeployment(b) },
+			want: types.FunctionDeployment{
+				Service:                "figlet",
+				Image:                  "ghcr.io/openfaas/figlet:latest",
+				Namespace:              "openfaas-fn",
+				EnvProcess:             "figlet",
+				EnvVars:                map[string]string{"write_debug": "true"},
+				Constraints:            []string{"node.platform.os == linux"},
+				Secrets:                []string{"api-key"},
+				Labels:                 &labels,
+				Annotations:            &annotations,
+				Limits:                 &types.FunctionResources{Memory: "128Mi"},
+				Requests:               &types.FunctionResources{CPU: "100m"},
+				ReadOnlyRootFilesystem: true,
+				Language:               "go",
+				SnapshotIds:            []string{"snap-1", "snap-2"},
+			},
+		},
+		{
+			message:   "ListFunctionsRequest",
+			unmarshal: func(b []byte) (interface{}, error) { return unmarshalNamespace(b) },
+			want:      "openfaas-fn",
+		},
+		{
+			message: "GetFunctionRequest",
+			unmarshal: func(b []byte) (interface{}, error) {
+				name, namespace, err := unmarshalGetFunction(b)
+				return name + "." + namespace, err
+			},
+			want: "figlet.openfaas-fn",
+		},
+		{
+			message: "ListInstancesRequest",
+			unmarshal: func(b []byte) (interface{}, error) {
+				name, namespace, err := unmarshalGetFunction(b)
+				return name + "." + namespace, err
+			},
+			want: "figlet.openfaas-fn",
+		},
+		{
+			message:   "ScaleRequest",
+			unmarshal: func(b []byte) (interface{}, error) { return unmarshalScaleRequest(b) },
+			want:      types.ScaleServiceRequest{ServiceName: "figlet", Namespace: "openfaas-fn", Replicas: 3},
+		},
+		{
+			message:   "RegisterRequest",
+			unmarshal: func(b []byte) (interface{}, error) { return unmarshalRegisterRequest(b) },
+			want:      types.RegisterRequest{Name: "figlet", Namespace: "openfaas-fn", Address: "10.0.0.2:8080", Ready: true},
+		},
+		{
+			message:   "ListCheckpointsRequest",
+			unmarshal: func(b []byte) (interface{}, error) { return unmarshalNamespace(b) },
+			want:      "openfaas-fn",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.message, func(t *testing.T) {
+			b, err := proto.MarshalOptions{Deterministic: true}.Marshal(readGolden(t, fd, tc.message))
+			if err != nil {
+				t.Fatalf("want no error, got: %s", err)
+			}
+
+			got, err := tc.unmarshal(b)
+			if err != nil {
+				t.Fatalf("want no error, got: %s", err)
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("request, want: %+v, got: %+v", tc.want, got)
+			}
+		})
+	}
+}
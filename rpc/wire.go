@@ -0,0 +1,163 @@
+package rpc
+
+import (
+	"math"
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// encoder appends the fields of a message in the protobuf wire format. Fields with the
+// zero value are omitted, as in proto3.
+type encoder []byte
+
+func (e *encoder) string(num protowire.Number, v string) {
+	if len(v) == 0 {
+		return
+	}
+	*e = protowire.AppendTag(*e, num, protowire.BytesType)
+	*e = protowire.AppendString(*e, v)
+}
+
+func (e *encoder) strings(num protowire.Number, vs []string) {
+	for _, v := range vs {
+		*e = protowire.AppendTag(*e, num, protowire.BytesType)
+		*e = protowire.AppendString(*e, v)
+	}
+}
+
+func (e *encoder) uint64(num protowire.Number, v uint64) {
+	if v == 0 {
+		return
+	}
+	*e = protowire.AppendTag(*e, num, protowire.VarintType)
+	*e = protowire.AppendVarint(*e, v)
+}
+
+func (e *encoder) int64(num protowire.Number, v int64) {
+	e.uint64(num, uint64(v))
+}
+
+func (e *encoder) bool(num protowire.Number, v bool) {
+	if v {
+		e.uint64(num, 1)
+	}
+}
+
+func (e *encoder) double(num protowire.Number, v float64) {
+	if v == 0 {
+		return
+	}
+	*e = protowire.AppendTag(*e, num, protowire.Fixed64Type)
+	*e = protowire.AppendFixed64(*e, math.Float64bits(v))
+}
+
+// message appends an embedded message, even when it is empty, as it is set.
+func (e *encoder) message(num protowire.Number, v []byte) {
+	*e = protowire.AppendTag(*e, num, protowire.BytesType)
+	*e = protowire.AppendBytes(*e, v)
+}
+
+// stringMap appends a map<string, string> as entries with the key and value in fields 1
+// and 2, ordered by key so that the encoding is stable.
+func (e *encoder) stringMap(num protowire.Number, m map[string]string) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		entry := encoder{}
+		entry.string(1, k)
+		entry.string(2, m[k])
+		e.message(num, entry)
+	}
+}
+
+// field is a field read from a message by decode.
+type field struct {
+	num    protowire.Number
+	typ    protowire.Type
+	bytes  []byte
+	scalar uint64
+}
+
+// str returns the value of a string field, or "" when the field has another type.
+func (f field) str() string {
+	if f.typ != protowire.BytesType {
+		return ""
+	}
+	return string(f.bytes)
+}
+
+func (f field) uint64() uint64 {
+	if f.typ != protowire.VarintType {
+		return 0
+	}
+	return f.scalar
+}
+
+func (f field) double() float64 {
+	if f.typ != protowire.Fixed64Type {
+		return 0
+	}
+	return math.Float64frombits(f.scalar)
+}
+
+// decode calls fn for each field of the message in b, fields of a type which is not
+// known, such as groups, are skipped.
+func decode(b []byte, fn func(f field) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		f := field{num: num, typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.scalar, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			f.scalar, n = protowire.ConsumeFixed64(b)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(b)
+			f.scalar = uint64(v)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeMapEntry adds an entry of a map<string, string> field to m.
+func decodeMapEntry(f field, m map[string]string) error {
+	var key, value string
+	err := decode(f.bytes, func(entry field) error {
+		switch entry.num {
+		case 1:
+			key = entry.str()
+		case 2:
+			value = entry.str()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	m[key] = value
+	return nil
+}
@@ -0,0 +1,83 @@
+package scaling
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultTimeout         = 30 * time.Second
+	defaultInitialInterval = 50 * time.Millisecond
+	defaultMaxInterval     = time.Second
+)
+
+// Backoff is how often WaitForReady polls the replicas of a function, and for how long.
+type Backoff struct {
+	// Timeout is how long to wait for an available replica, defaults to 30s.
+	Timeout time.Duration
+	// InitialInterval is the wait before the first poll, doubled after each poll which
+	// finds no available replica. Defaults to 50ms.
+	InitialInterval time.Duration
+	// MaxInterval bounds the wait between polls, defaults to 1s.
+	MaxInterval time.Duration
+}
+
+// GetTimeout returns Timeout, or its default when unset.
+func (b Backoff) GetTimeout() time.Duration {
+	if b.Timeout <= 0 {
+		return defaultTimeout
+	}
+	return b.Timeout
+}
+
+func (b Backoff) getInitialInterval() time.Duration {
+	if b.InitialInterval <= 0 {
+		return defaultInitialInterval
+	}
+	return b.InitialInterval
+}
+
+func (b Backoff) getMaxInterval() time.Duration {
+	if b.MaxInterval <= 0 {
+		return defaultMaxInterval
+	}
+	return b.MaxInterval
+}
+
+// WaitForReady polls scaler until the function has an available replica, backing off
+// exponentially between polls. It returns ErrNotReady when the timeout of backoff is
+// reached first, or the error of ctx when it is done.
+func WaitForReady(ctx context.Context, scaler FunctionScaler, function, namespace string, backoff Backoff) error {
+	ctx, cancel := context.WithTimeout(ctx, backoff.GetTimeout())
+	defer cancel()
+
+	interval := backoff.getInitialInterval()
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return fmt.Errorf("%w within %s", ErrNotReady, backoff.GetTimeout())
+			}
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		replicas, err := scaler.Replicas(ctx, function, namespace)
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+		if err == nil && replicas.Available > 0 {
+			return nil
+		}
+
+		interval *= 2
+		if max := backoff.getMaxInterval(); interval > max {
+			interval = max
+		}
+		timer.Reset(interval)
+	}
+}
@@ -0,0 +1,246 @@
+// Package scaling wakes functions which are scaled to zero before they are invoked. The
+// Scaler decorates the proxy and invoke handlers, and the proxy calls Wake while resolving
+// a function, so that a request to a function without an available replica scales it up
+// and waits for it to be ready, within FaaSConfig.ColdStartMaxWait, rather than failing.
+package scaling
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/logging"
+	"github.com/openfaas/faas-provider/tracing"
+)
+
+// readyTTL is how long a function is known to be ready after its replicas were looked up,
+// so that they are not looked up for every invocation.
+const readyTTL = 5 * time.Second
+
+// ErrNotFound should be wrapped by the errors of a FunctionScaler for a function which is
+// not deployed. Wake then returns nil, for the proxy to return a 404.
+var ErrNotFound = errors.New("function not found")
+
+// ErrNotReady is returned by WaitForReady and Wake when the function has no available replica
+// within the timeout.
+var ErrNotReady = errors.New("function has no available replica")
+
+// Replicas are the replicas of a function.
+type Replicas struct {
+	// Desired is the number of replicas the function is scaled to.
+	Desired uint64
+	// Available is the number of replicas ready to serve requests.
+	Available uint64
+}
+
+// FunctionScaler looks up and sets the replicas of functions, it is implemented by the
+// provider. The namespace is empty when a function is invoked without one, for the
+// provider to use its default namespace.
+type FunctionScaler interface {
+	// Replicas returns the replicas of the function.
+	Replicas(ctx context.Context, function, namespace string) (Replicas, error)
+	// Scale sets the desired replicas of the function.
+	Scale(ctx context.Context, function, namespace string, replicas uint64) error
+}
+
+// ColdStart describes the wake up of a function which had no available replica.
+type ColdStart struct {
+	Function  string
+	Namespace string
+	// Duration is how long the function took to have an available replica, or how long
+	// was waited for when Err is set.
+	Duration time.Duration
+	// Err is set when the function could not be scaled up, or was not ready in time.
+	Err error
+}
+
+// Config configures a Scaler.
+type Config struct {
+	// Backoff is how often the replicas of a function are polled while it is scaled up,
+	// and for how long.
+	Backoff Backoff
+	// OnColdStart, when set, is called after each wake up of a function, i.e. to record
+	// the durations of cold starts.
+	OnColdStart func(ColdStart)
+}
+
+// wakeup is a scale up of a function in progress, shared by the requests waiting for it.
+type wakeup struct {
+	done chan struct{}
+	err  error
+}
+
+// Scaler wakes functions before they are invoked, it is safe for concurrent use.
+type Scaler struct {
+	scaler FunctionScaler
+	config Config
+
+	mu      sync.Mutex
+	ready   map[string]time.Time
+	waking  map[string]*wakeup
+	swept   time.Time
+	timeout time.Duration
+
+	now func() time.Time
+}
+
+// New creates a Scaler which looks up and sets the replicas of functions with scaler.
+func New(scaler FunctionScaler, config Config) *Scaler {
+	return &Scaler{
+		scaler:  scaler,
+		config:  config,
+		ready:   map[string]time.Time{},
+		waking:  map[string]*wakeup{},
+		timeout: config.Backoff.GetTimeout(),
+		now:     time.Now,
+	}
+}
+
+type scalerContextKey struct{}
+
+// Decorate adds the Scaler to the context of requests passed to next, for the proxy to wake
+// the function before resolving it, see Wake.
+func (s *Scaler) Decorate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		next(w, r.WithContext(context.WithValue(r.Context(), scalerContextKey{}, s)))
+	}
+}
+
+// Wake wakes the function name, i.e. "figlet.openfaas-fn", with the Scaler added to ctx by
+// Decorate, and waits for it to be ready. It returns nil when ctx carries no Scaler, or when
+// the function is not deployed, for the resolver to report it. A function which is not ready
+// within the timeout returns ErrNotReady. Providers whose FunctionProxy is not created with
+// the proxy package call Wake before resolving the function.
+func Wake(ctx context.Context, name string) error {
+	s, _ := ctx.Value(scalerContextKey{}).(*Scaler)
+	if s == nil || len(name) == 0 || s.isReady(name) {
+		return nil
+	}
+
+	err := s.wake(ctx, name)
+	switch {
+	case err == nil, errors.Is(err, ErrNotFound):
+		return nil
+
+	case errors.Is(err, ErrNotReady), ctx.Err() != nil:
+		// when the client has gone away, the wake up carries on for the next request
+		return err
+
+	default:
+		// The proxy may still reach the function, i.e. when the scaler's API is down.
+		logging.FromContext(ctx).Error("Unable to scale function from zero", "function", name, "error", err)
+		return nil
+	}
+}
+
+// isReady returns true when the function was found to have an available replica within
+// the last readyTTL.
+func (s *Scaler) isReady(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	expires, ok := s.ready[name]
+	return ok && now.Before(expires)
+}
+
+// wake scales up the function when it has no available replica and waits for it to be
+// ready. Concurrent requests for the same function share one wake up, which carries on
+// when the request which started it is cancelled.
+func (s *Scaler) wake(ctx context.Context, name string) error {
+	function, namespace := httputil.SplitFunctionName(name)
+
+	replicas, err := s.scaler.Replicas(ctx, function, namespace)
+	if err != nil {
+		return err
+	}
+	if replicas.Available > 0 {
+		s.markReady(name)
+		return nil
+	}
+
+	s.mu.Lock()
+	wu, ok := s.waking[name]
+	if !ok {
+		wu = &wakeup{done: make(chan struct{})}
+		s.waking[name] = wu
+		go s.scaleUp(name, function, namespace, replicas, wu)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-wu.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if wu.err == nil {
+		tracing.AddEvent(ctx, tracing.EventColdStart, map[string]string{
+			"faas.function":          name,
+			"faas.cold_start.result": "ready",
+		})
+	}
+	return wu.err
+}
+
+// scaleUp sets the function to one replica, when it is scaled to zero, and waits for it
+// to be ready.
+func (s *Scaler) scaleUp(name, function, namespace string, replicas Replicas, wu *wakeup) {
+	start := s.now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	var err error
+	if replicas.Desired == 0 {
+		err = s.scaler.Scale(ctx, function, namespace, 1)
+	}
+	if err == nil {
+		err = WaitForReady(ctx, s.scaler, function, namespace, s.config.Backoff)
+	}
+
+	if err == nil {
+		s.markReady(name)
+	}
+	if s.config.OnColdStart != nil {
+		s.config.OnColdStart(ColdStart{
+			Function:  function,
+			Namespace: namespace,
+			Duration:  s.now().Sub(start),
+			Err:       err,
+		})
+	}
+
+	s.mu.Lock()
+	delete(s.waking, name)
+	s.mu.Unlock()
+
+	wu.err = err
+	close(wu.done)
+}
+
+func (s *Scaler) markReady(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ready[name] = s.now().Add(readyTTL)
+}
+
+// sweep forgets the functions whose readiness has expired, at most once every readyTTL.
+func (s *Scaler) sweep(now time.Time) {
+	if now.Sub(s.swept) < readyTTL {
+		return
+	}
+	s.swept = now
+
+	for name, expires := range s.ready {
+		if !now.Before(expires) {
+			delete(s.ready, name)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package scaling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testScaler is a function which becomes available after readyAfter polls once scaled up.
+type testScaler struct {
+	mu         sync.Mutex
+	replicas   map[string]*Replicas
+	readyAfter int
+	polls      int
+	scales     int
+	err        error
+}
+
+func (s *testScaler) Replicas(ctx context.Context, function, namespace string) (Replicas, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return Replicas{}, s.err
+	}
+	r, ok := s.replicas[function+"."+namespace]
+	if !ok {
+		return Replicas{}, fmt.Errorf("%s: %w", function, ErrNotFound)
+	}
+
+	if r.Desired > 0 && r.Available == 0 {
+		s.polls++
+		if s.polls > s.readyAfter {
+			r.Available = r.Desired
+		}
+	}
+	return *r, nil
+}
+
+func (s *testScaler) Scale(ctx context.Context, function, namespace string, replicas uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scales++
+	s.replicas[function+"."+namespace].Desired = replicas
+	return nil
+}
+
+// invoke passes a request through the Scaler's decorator to a handler which wakes the
+// function, as the proxy does, and returns the error of Wake.
+func invoke(s *Scaler, name string) error {
+	var err error
+	handler := s.Decorate(func(w http.ResponseWriter, r *http.Request) {
+		err = Wake(r.Context(), name)
+	})
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/function/"+name, nil))
+	return err
+}
+
+var fastBackoff = Backoff{Timeout: time.Second, InitialInterval: time.Millisecond, MaxInterval: 5 * time.Millisecond}
+
+func Test_Scaler_WakesFunction(t *testing.T) {
+	fs := &testScaler{replicas: map[string]*Replicas{"figlet.openfaas-fn": {}}, readyAfter: 2}
+
+	var coldStarts []ColdStart
+	s := New(fs, Config{
+		Backoff: fastBackoff,
+		OnColdStart: func(c ColdStart) {
+			coldStarts = append(coldStarts, c)
+		},
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := invoke(s, "figlet.openfaas-fn"); err != nil {
+			t.Fatalf("want no error, got: %s", err)
+		}
+	}
+
+	if fs.scales != 1 {
+		t.Errorf("scales, want: %d, got: %d", 1, fs.scales)
+	}
+	if len(coldStarts) != 1 {
+		t.Fatalf("cold starts, want: %d, got: %d", 1, len(coldStarts))
+	}
+	if c := coldStarts[0]; c.Function != "figlet" || c.Namespace != "openfaas-fn" || c.Err != nil || c.Duration <= 0 {
+		t.Errorf("cold start, want: figlet in openfaas-fn without an error, got: %+v", c)
+	}
+}
+
+func Test_Scaler_SharesWakeUp(t *testing.T) {
+	fs := &testScaler{replicas: map[string]*Replicas{"figlet.": {}}, readyAfter: 5}
+	s := New(fs, Config{Backoff: fastBackoff})
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := invoke(s, "figlet"); err != nil {
+				t.Errorf("want no error, got: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if fs.scales != 1 {
+		t.Errorf("scales, want: %d, got: %d", 1, fs.scales)
+	}
+}
+
+func Test_Scaler_NotReady(t *testing.T) {
+	fs := &testScaler{replicas: map[string]*Replicas{"figlet.": {}}, readyAfter: 1000}
+
+	var coldStart ColdStart
+	s := New(fs, Config{
+		Backoff:     Backoff{Timeout: 20 * time.Millisecond, InitialInterval: time.Millisecond},
+		OnColdStart: func(c ColdStart) { coldStart = c },
+	})
+
+	if err := invoke(s, "figlet"); !errors.Is(err, ErrNotReady) {
+		t.Errorf("want: %s, got: %v", ErrNotReady, err)
+	}
+	if !errors.Is(coldStart.Err, ErrNotReady) {
+		t.Errorf("cold start error, want: %s, got: %v", ErrNotReady, coldStart.Err)
+	}
+}
+
+func Test_Wake_PassesOn(t *testing.T) {
+	cases := []struct {
+		name   string
+		scaler *testScaler
+	}{
+		{"available", &testScaler{replicas: map[string]*Replicas{"figlet.": {Desired: 1, Available: 1}}}},
+		{"not found", &testScaler{replicas: map[string]*Replicas{}}},
+		{"scaler error", &testScaler{err: errors.New("connection refused")}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := invoke(New(tc.scaler, Config{Backoff: fastBackoff}), "figlet"); err != nil {
+				t.Errorf("want no error, got: %s", err)
+			}
+			if tc.scaler.scales != 0 {
+				t.Errorf("scales, want: %d, got: %d", 0, tc.scaler.scales)
+			}
+		})
+	}
+}
+
+func Test_Wake_WithoutScaler(t *testing.T) {
+	if err := Wake(context.Background(), "figlet"); err != nil {
+		t.Errorf("want no error, got: %s", err)
+	}
+}
+
+func Test_WaitForReady_Cancelled(t *testing.T) {
+	fs := &testScaler{replicas: map[string]*Replicas{"figlet.": {Desired: 1}}, readyAfter: 1000}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := WaitForReady(ctx, fs, "figlet", "", fastBackoff)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("want: %s, got: %v", context.Canceled, err)
+	}
+}
@@ -0,0 +1,66 @@
+// Package scrape collects the Prometheus metrics of individual function instances, so that a
+// provider can serve them merged from a single endpoint, see FaaSConfig.InstanceMetrics.
+//
+// The provider adds a Target to the Registry for each instance as it starts, and removes it
+// when the instance stops. A Scraper then fetches the metrics of every target on an
+// interval and implements prometheus.Gatherer over the merged result.
+package scrape
+
+import (
+	"sort"
+	"sync"
+)
+
+// Target is an instance of a function whose metrics are scraped.
+type Target struct {
+	// Function is the name of the function the instance belongs to
+	Function string
+	// Namespace of the function, if supported by the faas-provider
+	Namespace string
+	// Instance uniquely identifies the instance within the provider
+	Instance string
+	// URL is the metrics endpoint of the instance, i.e. "http://10.62.0.5:8081/metrics"
+	URL string
+}
+
+// Registry holds the targets to scrape, it is safe for concurrent use.
+type Registry struct {
+	mu      sync.Mutex
+	targets map[string]Target
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{targets: map[string]Target{}}
+}
+
+// Add adds target, replacing any target with the same Instance.
+func (r *Registry) Add(target Target) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.targets[target.Instance] = target
+}
+
+// Remove removes the target of instance, if there is one.
+func (r *Registry) Remove(instance string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.targets, instance)
+}
+
+// Targets returns the registered targets ordered by Instance.
+func (r *Registry) Targets() []Target {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	targets := make([]Target, 0, len(r.targets))
+	for _, t := range r.targets {
+		targets = append(targets, t)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].Instance < targets[j].Instance
+	})
+	return targets
+}
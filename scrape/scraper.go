@@ -0,0 +1,205 @@
+package scrape
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/openfaas/faas-provider/logging"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/proto"
+)
+
+// upMetric reports whether the last scrape of each target succeeded, as the "up" metric
+// does for a Prometheus server.
+const upMetric = "provider_instance_up"
+
+// maxScrapeBytes bounds the size of the metrics read from an instance, so that a function
+// can not exhaust the provider's memory.
+const maxScrapeBytes = 16 << 20
+
+// Scraper fetches the metrics of every target in a Registry and merges them, adding the
+// "function", "namespace" and "instance" labels of the target to each series. It implements
+// prometheus.Gatherer, so it can be served with promhttp.HandlerFor.
+type Scraper struct {
+	registry *Registry
+	client   *http.Client
+
+	mu       sync.RWMutex
+	families []*dto.MetricFamily
+}
+
+// NewScraper creates a scraper for the targets of registry, each scrape is given up to
+// timeout.
+func NewScraper(registry *Registry, timeout time.Duration) *Scraper {
+	return &Scraper{
+		registry: registry,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// Run scrapes every target straight away, then on every interval until ctx is cancelled.
+func (s *Scraper) Run(ctx context.Context, interval time.Duration) {
+	s.Scrape(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Scrape(ctx)
+		}
+	}
+}
+
+// Scrape fetches the metrics of every target concurrently and replaces the merged result.
+// A target which can not be scraped only has its provider_instance_up series, set to 0.
+func (s *Scraper) Scrape(ctx context.Context) {
+	targets := s.registry.Targets()
+
+	results := make([]map[string]*dto.MetricFamily, len(targets))
+	wg := sync.WaitGroup{}
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target Target) {
+			defer wg.Done()
+
+			families, err := s.fetch(ctx, target)
+			if err != nil {
+				logging.FromContext(ctx).Warn("Unable to scrape instance", "function", target.Function, "instance", target.Instance, "error", err)
+			}
+			results[i] = families
+		}(i, target)
+	}
+	wg.Wait()
+
+	merged := map[string]*dto.MetricFamily{}
+	up := &dto.MetricFamily{
+		Name: proto.String(upMetric),
+		Help: proto.String("Whether the last scrape of the function instance succeeded."),
+		Type: dto.MetricType_GAUGE.Enum(),
+	}
+	merged[upMetric] = up
+
+	for i, target := range targets {
+		value := 0.0
+		if results[i] != nil {
+			value = 1
+		}
+		up.Metric = append(up.Metric, &dto.Metric{
+			Label: targetLabels(target),
+			Gauge: &dto.Gauge{Value: proto.Float64(value)},
+		})
+
+		for name, family := range results[i] {
+			existing, ok := merged[name]
+			if !ok {
+				merged[name] = family
+				continue
+			}
+			if existing.GetType() != family.GetType() {
+				logging.FromContext(ctx).Warn("Skipping metric reported with a different type", "function", target.Function,
+					"instance", target.Instance, "metric", name, "type", family.GetType(), "want", existing.GetType())
+				continue
+			}
+			existing.Metric = append(existing.Metric, family.Metric...)
+		}
+	}
+
+	families := make([]*dto.MetricFamily, 0, len(merged))
+	for _, family := range merged {
+		families = append(families, family)
+	}
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].GetName() < families[j].GetName()
+	})
+
+	s.mu.Lock()
+	s.families = families
+	s.mu.Unlock()
+}
+
+// Gather returns the metrics merged by the last Scrape.
+func (s *Scraper) Gather() ([]*dto.MetricFamily, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.families, nil
+}
+
+// fetch scrapes target and labels its metrics with the target.
+func (s *Scraper) fetch(ctx context.Context, target Target) (map[string]*dto.MetricFamily, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", string(expfmt.FmtText))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxScrapeBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxScrapeBytes {
+		return nil, fmt.Errorf("metrics exceed %d bytes", maxScrapeBytes)
+	}
+
+	parser := expfmt.TextParser{}
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	labels := targetLabels(target)
+	for _, family := range families {
+		for _, m := range family.Metric {
+			m.Label = withLabels(m.Label, labels)
+		}
+	}
+	return families, nil
+}
+
+func targetLabels(target Target) []*dto.LabelPair {
+	return []*dto.LabelPair{
+		{Name: proto.String("function"), Value: proto.String(target.Function)},
+		{Name: proto.String("instance"), Value: proto.String(target.Instance)},
+		{Name: proto.String("namespace"), Value: proto.String(target.Namespace)},
+	}
+}
+
+// withLabels sets the target labels on a series, replacing any labels of the same name the
+// instance reported, and keeps the labels sorted by name as the exposition format expects.
+func withLabels(existing, target []*dto.LabelPair) []*dto.LabelPair {
+	labels := make([]*dto.LabelPair, 0, len(existing)+len(target))
+	labels = append(labels, target...)
+	for _, l := range existing {
+		switch l.GetName() {
+		case "function", "instance", "namespace":
+			continue
+		}
+		labels = append(labels, l)
+	}
+
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].GetName() < labels[j].GetName()
+	})
+	return labels
+}
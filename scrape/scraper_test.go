@@ -0,0 +1,116 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func newInstance(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func labelsOf(m *dto.Metric) string {
+	var pairs []string
+	for _, l := range m.Label {
+		pairs = append(pairs, l.GetName()+"="+l.GetValue())
+	}
+	return strings.Join(pairs, ",")
+}
+
+func Test_Scraper_MergesTargets(t *testing.T) {
+	a := newInstance(t, "# TYPE requests_total counter\nrequests_total{code=\"200\",instance=\"spoofed\"} 3\n")
+	b := newInstance(t, "# TYPE requests_total counter\nrequests_total{code=\"200\"} 5\n# TYPE queue_depth gauge\nqueue_depth 2\n")
+
+	registry := NewRegistry()
+	registry.Add(Target{Function: "figlet", Namespace: "openfaas-fn", Instance: "figlet-1", URL: a.URL})
+	registry.Add(Target{Function: "figlet", Namespace: "openfaas-fn", Instance: "figlet-2", URL: b.URL})
+	registry.Add(Target{Function: "env", Namespace: "openfaas-fn", Instance: "env-1", URL: "http://127.0.0.1:1/metrics"})
+
+	s := NewScraper(registry, time.Second)
+	s.Scrape(context.Background())
+
+	families, err := s.Gather()
+	if err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+
+	got := map[string][]string{}
+	for _, f := range families {
+		for _, m := range f.Metric {
+			value := m.GetCounter().GetValue() + m.GetGauge().GetValue()
+			got[f.GetName()] = append(got[f.GetName()], fmt.Sprintf("%s %g", labelsOf(m), value))
+		}
+	}
+
+	want := map[string][]string{
+		"provider_instance_up": {
+			"function=env,instance=env-1,namespace=openfaas-fn 0",
+			"function=figlet,instance=figlet-1,namespace=openfaas-fn 1",
+			"function=figlet,instance=figlet-2,namespace=openfaas-fn 1",
+		},
+		"requests_total": {
+			"code=200,function=figlet,instance=figlet-1,namespace=openfaas-fn 3",
+			"code=200,function=figlet,instance=figlet-2,namespace=openfaas-fn 5",
+		},
+		"queue_depth": {
+			"function=figlet,instance=figlet-2,namespace=openfaas-fn 2",
+		},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("families, want: %d, got: %d (%v)", len(want), len(got), got)
+	}
+	for name, series := range want {
+		if strings.Join(got[name], "\n") != strings.Join(series, "\n") {
+			t.Errorf("%s, want: %v, got: %v", name, series, got[name])
+		}
+	}
+}
+
+func Test_Scraper_LimitsBody(t *testing.T) {
+	large := newInstance(t, "# TYPE padding gauge\n"+strings.Repeat("padding 1\n", maxScrapeBytes/10+1))
+
+	registry := NewRegistry()
+	registry.Add(Target{Function: "figlet", Instance: "figlet-1", URL: large.URL})
+
+	s := NewScraper(registry, time.Second)
+	s.Scrape(context.Background())
+
+	families, _ := s.Gather()
+	if len(families) != 1 || families[0].GetName() != upMetric {
+		t.Fatalf("families, want: only %s, got: %d", upMetric, len(families))
+	}
+	if up := families[0].Metric[0].GetGauge().GetValue(); up != 0 {
+		t.Errorf("%s, want: %g, got: %g", upMetric, 0.0, up)
+	}
+}
+
+func Test_Registry_Remove(t *testing.T) {
+	registry := NewRegistry()
+	registry.Add(Target{Function: "figlet", Instance: "figlet-2", URL: "http://10.62.0.6:8081/metrics"})
+	registry.Add(Target{Function: "figlet", Instance: "figlet-1", URL: "http://10.62.0.5:8081/metrics"})
+	registry.Add(Target{Function: "figlet", Instance: "figlet-1", URL: "http://10.62.0.7:8081/metrics"})
+	registry.Remove("figlet-2")
+
+	targets := registry.Targets()
+	if len(targets) != 1 {
+		t.Fatalf("targets, want: %d, got: %d", 1, len(targets))
+	}
+	if targets[0].URL != "http://10.62.0.7:8081/metrics" {
+		t.Errorf("URL, want: %s, got: %s", "http://10.62.0.7:8081/metrics", targets[0].URL)
+	}
+}
@@ -0,0 +1,185 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+// FileStore keeps each secret as a file named after it, in a directory named after its
+// namespace under Path, so that they can be mounted into functions as they are. Secrets
+// are only readable by the provider's user. Path is usually FaaSConfig.SecretMountPath,
+// the basic auth secrets at its top level are not in any namespace, so they are never
+// listed or changed.
+type FileStore struct {
+	// Path is the directory holding a directory for each namespace.
+	Path string
+
+	mu sync.RWMutex
+}
+
+// NewFileStore creates a FileStore which keeps secrets under path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{Path: path}
+}
+
+// List returns the names of the secrets of namespace, sorted by name.
+func (s *FileStore) List(ctx context.Context, namespace string) ([]types.Secret, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	dir, err := s.dir(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []types.Secret{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to list secrets: %w", err)
+	}
+
+	secrets := []types.Secret{}
+	for _, entry := range entries {
+		// skips the temporary files of writes in progress
+		if !entry.Type().IsRegular() || ValidateName(entry.Name()) != nil {
+			continue
+		}
+		secrets = append(secrets, types.Secret{Name: entry.Name(), Namespace: namespace})
+	}
+	sort.Slice(secrets, func(i, j int) bool { return secrets[i].Name < secrets[j].Name })
+
+	return secrets, nil
+}
+
+// Create writes a new secret, or returns an error wrapping ErrExists.
+func (s *FileStore) Create(ctx context.Context, secret types.Secret) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := s.file(secret.Namespace, secret.Name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(file); err == nil {
+		return fmt.Errorf("%w: %s.%s", ErrExists, secret.Name, secret.Namespace)
+	}
+
+	return write(file, value(secret))
+}
+
+// Update replaces the value of a secret, or returns an error wrapping ErrNotFound.
+func (s *FileStore) Update(ctx context.Context, secret types.Secret) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := s.file(secret.Namespace, secret.Name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(file); errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s.%s", ErrNotFound, secret.Name, secret.Namespace)
+	}
+
+	return write(file, value(secret))
+}
+
+// Delete removes a secret, or returns an error wrapping ErrNotFound.
+func (s *FileStore) Delete(ctx context.Context, namespace, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := s.file(namespace, name)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s.%s", ErrNotFound, name, namespace)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to delete secret: %w", err)
+	}
+	return nil
+}
+
+// GetForFunction reads the secrets with the given names, in the same order.
+func (s *FileStore) GetForFunction(ctx context.Context, namespace string, names []string) ([]types.Secret, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	secrets := make([]types.Secret, 0, len(names))
+	for _, name := range names {
+		file, err := s.file(namespace, name)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := os.ReadFile(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s.%s", ErrNotFound, name, namespace)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read secret: %w", err)
+		}
+
+		secrets = append(secrets, types.Secret{Name: name, Namespace: namespace, RawValue: data})
+	}
+
+	return secrets, nil
+}
+
+func (s *FileStore) dir(namespace string) (string, error) {
+	if err := ValidateName(namespace); err != nil {
+		return "", fmt.Errorf("invalid namespace: %w", err)
+	}
+	return filepath.Join(s.Path, namespace), nil
+}
+
+func (s *FileStore) file(namespace, name string) (string, error) {
+	dir, err := s.dir(namespace)
+	if err != nil {
+		return "", err
+	}
+	if err := ValidateName(name); err != nil {
+		return "", fmt.Errorf("invalid secret: %w", err)
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// write replaces file with data through a temporary file, so that a function never reads
+// a secret part way through being written.
+func write(file string, data []byte) error {
+	dir := filepath.Dir(file)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("unable to write secret: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".secret-*")
+	if err != nil {
+		return fmt.Errorf("unable to write secret: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to write secret: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("unable to write secret: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return fmt.Errorf("unable to write secret: %w", err)
+	}
+	return nil
+}
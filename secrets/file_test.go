@@ -0,0 +1,111 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_FileStore_CreateListAndGet(t *testing.T) {
+	dir := t.TempDir()
+	// basic auth secrets are at the top level, outside of any namespace
+	os.WriteFile(filepath.Join(dir, "basic-auth-user"), []byte("admin"), 0600)
+
+	store := NewFileStore(dir)
+	ctx := context.Background()
+
+	for _, secret := range []types.Secret{
+		{Name: "db-password", Namespace: "openfaas-fn", Value: "s3cret"},
+		{Name: "api-key", Namespace: "openfaas-fn", RawValue: []byte{0, 1, 2}},
+	} {
+		if err := store.Create(ctx, secret); err != nil {
+			t.Fatalf("create %s: %s", secret.Name, err)
+		}
+	}
+
+	secrets, err := store.List(ctx, "openfaas-fn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secrets) != 2 || secrets[0].Name != "api-key" || secrets[1].Name != "db-password" {
+		t.Fatalf("secrets, want: [api-key db-password], got: %v", secrets)
+	}
+	for _, secret := range secrets {
+		if len(secret.Value) > 0 || len(secret.RawValue) > 0 {
+			t.Errorf("secret %s, want: no value listed, got: %v", secret.Name, secret)
+		}
+	}
+
+	got, err := store.GetForFunction(ctx, "openfaas-fn", []string{"db-password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got[0].RawValue) != "s3cret" {
+		t.Errorf("value, want: %s, got: %s", "s3cret", got[0].RawValue)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "openfaas-fn", "db-password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode, want: %v, got: %v", os.FileMode(0600), info.Mode().Perm())
+	}
+
+	secrets, err = store.List(ctx, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secrets) != 0 {
+		t.Errorf("secrets of an empty namespace, want: 0, got: %d", len(secrets))
+	}
+}
+
+func Test_FileStore_Errors(t *testing.T) {
+	store := NewFileStore(t.TempDir())
+	ctx := context.Background()
+
+	secret := types.Secret{Name: "db-password", Namespace: "openfaas-fn", Value: "s3cret"}
+	if err := store.Update(ctx, secret); !errors.Is(err, ErrNotFound) {
+		t.Errorf("update missing, want: %s, got: %v", ErrNotFound, err)
+	}
+	if err := store.Delete(ctx, secret.Namespace, secret.Name); !errors.Is(err, ErrNotFound) {
+		t.Errorf("delete missing, want: %s, got: %v", ErrNotFound, err)
+	}
+	if _, err := store.GetForFunction(ctx, secret.Namespace, []string{secret.Name}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get missing, want: %s, got: %v", ErrNotFound, err)
+	}
+
+	if err := store.Create(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Create(ctx, secret); !errors.Is(err, ErrExists) {
+		t.Errorf("create existing, want: %s, got: %v", ErrExists, err)
+	}
+
+	secret.Value = "changed"
+	if err := store.Update(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := store.GetForFunction(ctx, secret.Namespace, []string{secret.Name})
+	if string(got[0].RawValue) != "changed" {
+		t.Errorf("updated value, want: %s, got: %s", "changed", got[0].RawValue)
+	}
+
+	if err := store.Delete(ctx, secret.Namespace, secret.Name); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"../escape", "..", ".hidden", ""} {
+		if err := store.Create(ctx, types.Secret{Name: name, Namespace: "openfaas-fn", Value: "x"}); err == nil {
+			t.Errorf("create %q, want: error, got: nil", name)
+		}
+	}
+	if _, err := store.List(ctx, "../"); err == nil {
+		t.Errorf("list namespace %q, want: error, got: nil", "../")
+	}
+}
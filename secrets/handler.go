@@ -0,0 +1,116 @@
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/logging"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// NewHandlerFunc creates the handler for "/system/secrets", to be set as
+// FaaSHandlers.Secrets. GET lists the secrets of the namespace in the query, POST creates,
+// PUT updates and DELETE removes the types.Secret in the body. Requests without a
+// namespace, in the body or query, use defaultNamespace. Writes are answered with a 202,
+// as by faas-netes, a missing secret with a 404 and a secret which already exists with a
+// 409.
+func NewHandlerFunc(store Store, defaultNamespace string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			namespace := httputil.NamespaceFromRequest(r)
+			if len(namespace) == 0 {
+				namespace = defaultNamespace
+			}
+			if err := ValidateName(namespace); err != nil {
+				httputil.WriteErrorCode(w, r, http.StatusBadRequest, httputil.InvalidRequest, fmt.Sprintf("invalid namespace: %s", err))
+				return
+			}
+
+			secrets, err := store.List(r.Context(), namespace)
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(secrets)
+			return
+		}
+
+		secret := types.Secret{}
+		if r.Body == nil {
+			httputil.WriteErrorCode(w, r, http.StatusBadRequest, httputil.InvalidRequest, "unable to decode secret: empty body")
+			return
+		}
+		defer r.Body.Close()
+		if err := json.NewDecoder(r.Body).Decode(&secret); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				httputil.WriteErrorCode(w, r, http.StatusRequestEntityTooLarge, httputil.RequestTooLarge, fmt.Sprintf("request body must not be larger than %d bytes", maxBytesErr.Limit))
+				return
+			}
+			httputil.WriteErrorCode(w, r, http.StatusBadRequest, httputil.InvalidRequest, fmt.Sprintf("unable to decode secret: %s", err))
+			return
+		}
+
+		if len(secret.Namespace) == 0 {
+			secret.Namespace = httputil.NamespaceFromRequest(r)
+		}
+		if len(secret.Namespace) == 0 {
+			secret.Namespace = defaultNamespace
+		}
+		if err := ValidateName(secret.Namespace); err != nil {
+			httputil.WriteErrorCode(w, r, http.StatusBadRequest, httputil.InvalidRequest, fmt.Sprintf("invalid namespace: %s", err))
+			return
+		}
+		if err := ValidateName(secret.Name); err != nil {
+			httputil.WriteErrorCode(w, r, http.StatusBadRequest, httputil.InvalidRequest, fmt.Sprintf("invalid secret: %s", err))
+			return
+		}
+
+		var err error
+		switch r.Method {
+		case http.MethodPost, http.MethodPut:
+			if len(value(secret)) == 0 {
+				httputil.WriteErrorCode(w, r, http.StatusBadRequest, httputil.InvalidRequest, "invalid secret: value or rawValue is required")
+				return
+			}
+			if r.Method == http.MethodPost {
+				err = store.Create(r.Context(), secret)
+			} else {
+				err = store.Update(r.Context(), secret)
+			}
+
+		case http.MethodDelete:
+			err = store.Delete(r.Context(), secret.Namespace, secret.Name)
+
+		default:
+			w.Header().Set("Allow", "GET, POST, PUT, DELETE")
+			httputil.WriteError(w, r, http.StatusMethodNotAllowed, "method "+r.Method+" is not allowed")
+			return
+		}
+
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// writeError writes the status for an error of a Store, errors other than ErrNotFound and
+// ErrExists are logged and answered with a 500, without their detail.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		httputil.WriteErrorCode(w, r, http.StatusNotFound, httputil.SecretNotFound, err.Error())
+	case errors.Is(err, ErrExists):
+		httputil.WriteErrorCode(w, r, http.StatusConflict, httputil.SecretExists, err.Error())
+	default:
+		logging.FromContext(r.Context()).Error("Unable to access secrets", "method", r.Method, "error", err)
+		httputil.WriteErrorCode(w, r, http.StatusInternalServerError, httputil.InternalError, "unable to access secrets")
+	}
+}
@@ -0,0 +1,63 @@
+package secrets
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_NewHandlerFunc(t *testing.T) {
+	handler := NewHandlerFunc(NewFileStore(t.TempDir()), "openfaas-fn")
+
+	cases := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+		want   int
+	}{
+		{"create", http.MethodPost, "/system/secrets", `{"name":"db-password","value":"s3cret"}`, http.StatusAccepted},
+		{"create existing", http.MethodPost, "/system/secrets", `{"name":"db-password","value":"s3cret"}`, http.StatusConflict},
+		{"create in namespace from query", http.MethodPost, "/system/secrets?namespace=dev", `{"name":"db-password","value":"s3cret"}`, http.StatusAccepted},
+		{"create without value", http.MethodPost, "/system/secrets", `{"name":"empty"}`, http.StatusBadRequest},
+		{"create with invalid name", http.MethodPost, "/system/secrets", `{"name":"../etc","value":"x"}`, http.StatusBadRequest},
+		{"create with invalid namespace", http.MethodPost, "/system/secrets", `{"name":"a","namespace":"a/b","value":"x"}`, http.StatusBadRequest},
+		{"create with invalid body", http.MethodPost, "/system/secrets", `{`, http.StatusBadRequest},
+		{"update", http.MethodPut, "/system/secrets", `{"name":"db-password","value":"changed"}`, http.StatusAccepted},
+		{"update missing", http.MethodPut, "/system/secrets", `{"name":"missing","value":"x"}`, http.StatusNotFound},
+		{"delete", http.MethodDelete, "/system/secrets?namespace=dev", `{"name":"db-password"}`, http.StatusAccepted},
+		{"delete missing", http.MethodDelete, "/system/secrets", `{"name":"missing"}`, http.StatusNotFound},
+		{"list with invalid namespace", http.MethodGet, "/system/secrets?namespace=..", "", http.StatusBadRequest},
+		{"patch", http.MethodPatch, "/system/secrets", `{"name":"db-password"}`, http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+			w := httptest.NewRecorder()
+			handler(w, r)
+
+			if w.Code != tc.want {
+				t.Errorf("status, want: %d, got: %d (%s)", tc.want, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/system/secrets", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("list status, want: %d, got: %d", http.StatusOK, w.Code)
+	}
+
+	secrets := []types.Secret{}
+	if err := json.NewDecoder(w.Body).Decode(&secrets); err != nil {
+		t.Fatal(err)
+	}
+	if len(secrets) != 1 || secrets[0].Name != "db-password" || secrets[0].Namespace != "openfaas-fn" {
+		t.Errorf("secrets, want: [db-password.openfaas-fn], got: %v", secrets)
+	}
+}
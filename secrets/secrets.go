@@ -0,0 +1,70 @@
+// Package secrets stores the secrets made available to functions behind the Store
+// interface, and serves "/system/secrets" from any Store with NewHandlerFunc, so that providers
+// share the same name rules, validation and status codes. FileStore keeps secrets on disk
+// for small providers, larger ones implement Store against their orchestrator.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+// MaxNameLength is the longest name of a secret or namespace.
+const MaxNameLength = 253
+
+var (
+	// ErrNotFound should be wrapped by the errors of a Store for a secret which does not
+	// exist, it is returned to the client as a 404.
+	ErrNotFound = errors.New("secret not found")
+	// ErrExists should be wrapped by the errors of a Store when creating a secret which
+	// already exists, it is returned to the client as a 409.
+	ErrExists = errors.New("secret already exists")
+)
+
+// nameExpression matches the names of secrets, which are also the names of the files they
+// are mounted as, so they must not contain a "/".
+var nameExpression = regexp.MustCompile(`^[a-zA-Z0-9][-a-zA-Z0-9_.]*$`)
+
+// Store keeps the secrets of each namespace. The namespace passed to a Store is never
+// empty, NewHandlerFunc replaces an empty namespace with its default.
+type Store interface {
+	// List returns the secrets of namespace, without their values.
+	List(ctx context.Context, namespace string) ([]types.Secret, error)
+	// Create adds a secret, or returns an error wrapping ErrExists.
+	Create(ctx context.Context, secret types.Secret) error
+	// Update replaces the value of a secret, or returns an error wrapping ErrNotFound.
+	Update(ctx context.Context, secret types.Secret) error
+	// Delete removes a secret, or returns an error wrapping ErrNotFound.
+	Delete(ctx context.Context, namespace, name string) error
+	// GetForFunction returns the secrets with the given names and their values, for a
+	// function to be deployed with. An error wrapping ErrNotFound is returned when any of
+	// them does not exist.
+	GetForFunction(ctx context.Context, namespace string, names []string) ([]types.Secret, error)
+}
+
+// ValidateName returns an error when name can not be used as the name of a secret or
+// namespace.
+func ValidateName(name string) error {
+	if len(name) == 0 {
+		return fmt.Errorf("name is required")
+	}
+	if len(name) > MaxNameLength {
+		return fmt.Errorf("name %q must not be longer than %d characters", name, MaxNameLength)
+	}
+	if !nameExpression.MatchString(name) {
+		return fmt.Errorf("name %q must start with a letter or digit followed by letters, digits, \"-\", \"_\" or \".\"", name)
+	}
+	return nil
+}
+
+// value returns the value of secret, its RawValue is used when Value is empty.
+func value(secret types.Secret) []byte {
+	if len(secret.Value) > 0 {
+		return []byte(secret.Value)
+	}
+	return secret.RawValue
+}
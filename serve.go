@@ -5,158 +5,1442 @@ package bootstrap
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"os"
 	"os/signal"
 
-	"github.com/gorilla/mux"
 	"github.com/openfaas/faas-provider/auth"
+	"github.com/openfaas/faas-provider/auth/hmac"
+	"github.com/openfaas/faas-provider/events"
+	"github.com/openfaas/faas-provider/health"
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/limiter"
+	"github.com/openfaas/faas-provider/logging"
+	"github.com/openfaas/faas-provider/resolver"
+	"github.com/openfaas/faas-provider/scaling"
+	"github.com/openfaas/faas-provider/timing"
+	"github.com/openfaas/faas-provider/tracing"
 	"github.com/openfaas/faas-provider/types"
+	"github.com/openfaas/faas-provider/validation"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // NameExpression for a function / service
 const NameExpression = "-a-zA-Z_0-9."
 
-var r *mux.Router
+var (
+	defaultServer   *Server
+	defaultServerMu sync.Mutex
+)
+
+// Router gives access to the underlying router for when new routes need to be added.
+// It must be called before Serve, as each call to Serve takes the router for its own
+// and the next call to Router returns a new one. It is a httputil.MuxRouter, as the config
+// is not known yet, use NewServer with FaaSConfig.NewRouter for another implementation.
+func Router() httputil.Router {
+	defaultServerMu.Lock()
+	defer defaultServerMu.Unlock()
 
-// Mark this as a Golang "package"
-func init() {
-	r = mux.NewRouter()
+	if defaultServer == nil {
+		defaultServer = NewServer(nil)
+	}
+	return defaultServer.Router()
 }
 
-// Router gives access to the underlying router for when new routes need to be added.
-func Router() *mux.Router {
-	return r
+// takeDefaultServer returns the Server used by Serve with config, the routes added with
+// Router are kept. The next call to Router or Serve creates a new Server, so that calling
+// Serve again does not register every route twice.
+func takeDefaultServer(config *types.FaaSConfig) *Server {
+	defaultServerMu.Lock()
+	defer defaultServerMu.Unlock()
+
+	s := defaultServer
+	defaultServer = nil
+
+	if s == nil {
+		return NewServer(config)
+	}
+	s.config = config
+	return s
 }
 
 // Serve load your handlers into the correct OpenFaaS route spec. This function is blocking.
 func Serve(handlers *types.FaaSHandlers, config *types.FaaSConfig) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := ServeContext(ctx, handlers, config); err != nil {
+		exit(config, err)
+	}
+}
+
+// ServeContext behaves like Serve, but returns when ctx is cancelled as well as on SIGINT
+// or SIGTERM, after the same graceful shutdown, so that a process which embeds the provider
+// and owns signal handling can stop the server on its own terms. ctx is not used as the
+// context of requests, so cancelling it lets in-flight requests drain rather than cancelling
+// them, see ServeWithContext for that.
+//
+// An error is returned rather than exiting the process, see ServeWithError.
+func ServeContext(ctx context.Context, handlers *types.FaaSHandlers, config *types.FaaSConfig) error {
+	return serve(context.Background(), ctx, handlers, config)
+}
+
+// ServeWithError behaves like Serve, but returns an error rather than exiting the process
+// when the config is invalid, the basic auth credentials can not be read, the port can not
+// be bound or the server does not shut down cleanly, so that the caller can run its own
+// cleanup. It returns nil once the server has shut down gracefully on SIGINT or SIGTERM.
+func ServeWithError(handlers *types.FaaSHandlers, config *types.FaaSConfig) error {
+	return ServeContext(context.Background(), handlers, config)
+}
+
+// ServeWithContext behaves like Serve, but uses ctx as the base context of every request,
+// so that values set on it, such as a backend client or logger, can be read from r.Context()
+// in the handlers.
+//
+// ctx should live for as long as the server. Cancelling it cancels the context of every
+// in-flight and future request, but does not stop the server, which still shuts down on
+// SIGINT or SIGTERM. This function is blocking, and exits the process when config fails
+// FaaSConfig.Validate.
+func ServeWithContext(ctx context.Context, handlers *types.FaaSHandlers, config *types.FaaSConfig) {
+	if err := serve(ctx, context.Background(), handlers, config); err != nil {
+		exit(config, err)
+	}
+}
+
+// NewHTTPServer registers the handlers like Serve, but returns the http.Server for the API
+// rather than serving it, so that a process which embeds the provider can call ListenAndServe
+// or Serve and Shutdown itself, and own signal handling and shutdown deadlines. When TLSConfig
+// is set the certificate is loaded into the TLSConfig of the server, so ListenAndServeTLS
+// should be called with empty file names, it is not reloaded on SIGHUP. The routes added
+// with Router are kept.
+//
+// The features which rely on the lifecycle managed by Serve are not available, and an error
+// is returned when they are configured: ListenAddress, a separate MetricsPort, DebugPort,
+// StartupChecks, OnReady, BasicAuthReloadInterval, ProxyDrainTimeout, HealthcheckInterval,
+// Events, Audit, CheckpointRetention, TracingEndpoint and the shutdown hooks.
+func NewHTTPServer(handlers *types.FaaSHandlers, config *types.FaaSConfig) (*http.Server, error) {
+	if config == nil {
+		config = &types.FaaSConfig{}
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	var unsupported []string
+	if len(config.ListenAddress) > 0 {
+		unsupported = append(unsupported, "ListenAddress")
+	}
+	if len(config.UnixSocketPath) > 0 {
+		unsupported = append(unsupported, "UnixSocketPath")
+	}
+	if config.MetricsPort != nil && *config.MetricsPort != config.GetTCPPort() {
+		unsupported = append(unsupported, "MetricsPort")
+	}
+	if config.DebugPort != nil {
+		unsupported = append(unsupported, "DebugPort")
+	}
+	if len(config.StartupChecks) > 0 {
+		unsupported = append(unsupported, "StartupChecks")
+	}
+	if config.OnReady != nil {
+		unsupported = append(unsupported, "OnReady")
+	}
+	if config.BasicAuthReloadInterval > 0 {
+		unsupported = append(unsupported, "BasicAuthReloadInterval")
+	}
+	if config.ProxyDrainTimeout > 0 {
+		unsupported = append(unsupported, "ProxyDrainTimeout")
+	}
+	if config.HealthcheckInterval > 0 {
+		unsupported = append(unsupported, "HealthcheckInterval")
+	}
+	if len(config.PreShutdownHooks) > 0 || len(config.PostShutdownHooks) > 0 {
+		unsupported = append(unsupported, "PreShutdownHooks", "PostShutdownHooks")
+	}
+	if config.Events != nil {
+		unsupported = append(unsupported, "Events")
+	}
+	if config.Audit != nil {
+		unsupported = append(unsupported, "Audit")
+	}
+	if config.CheckpointRetention != nil {
+		unsupported = append(unsupported, "CheckpointRetention")
+	}
+	if len(config.TracingEndpoint) > 0 {
+		unsupported = append(unsupported, "TracingEndpoint")
+	}
+	if len(unsupported) > 0 {
+		return nil, fmt.Errorf("invalid config: %s require Serve", strings.Join(unsupported, ", "))
+	}
+
+	s := takeDefaultServer(config)
+	s.Handlers(handlers)
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	markStarted(time.Now())
+
+	return s.newHTTPServer(net.JoinHostPort(config.BindAddress, strconv.Itoa(config.GetTCPPort())))
+}
+
+// exit logs err to the Logger of config and exits the process.
+func exit(config *types.FaaSConfig, err error) {
+	logger := slog.Default()
+	if config != nil {
+		logger = config.GetLogger()
+	}
+
+	logger.Error("Unable to serve the provider API", "error", err)
+	os.Exit(1)
+}
+
+// serve registers the handlers with the default Server and serves the API, with ctx as the
+// base context of requests, until stop is cancelled or SIGINT or SIGTERM is received.
+func serve(ctx, stop context.Context, handlers *types.FaaSHandlers, config *types.FaaSConfig) error {
+	stop, cancel := signal.NotifyContext(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
 
-	if config.EnableBasicAuth {
-		reader := auth.ReadBasicAuthFromDisk{
+	s := takeDefaultServer(config)
+	s.baseContext = ctx
+	s.Handlers(handlers)
+
+	return s.Serve(stop)
+}
+
+// Server serves the OpenFaaS provider API. Each Server has its own router, so that more
+// than one provider can be run in a process, or a clean router used in each test.
+type Server struct {
+	config *types.FaaSConfig
+	router httputil.Router
+	server *http.Server
+
+	// metricsRouter serves "/metrics" on FaaSConfig.MetricsPort, when it is set.
+	metricsRouter httputil.Router
+
+	// debugRouter serves the debug endpoints on FaaSConfig.DebugPort, when it is set.
+	debugRouter httputil.Router
+
+	// baseContext is the base context of every request, see ServeWithContext.
+	baseContext context.Context
+
+	gate  *startupGate
+	drain *proxyDrain
+
+	// inFlight counts the requests being served, it is logged when shutdown starts.
+	inFlight *inFlight
+
+	// certs serves the TLS certificate, it is reloaded on SIGHUP while serving.
+	certs     *certReloader
+	certsOnce sync.Once
+
+	// credentials are reloaded every BasicAuthReloadInterval while serving, when set.
+	credentials *auth.ReloadingCredentials
+
+	// checkpoints collects checkpoints by CheckpointRetention while serving, when set.
+	checkpoints *checkpointGC
+
+	// readOnly is set from ReadOnlyFile on SIGHUP while serving, when it is set.
+	readOnly *readOnlyMode
+
+	// maintenance is set from MaintenanceFile on SIGHUP while serving, when it is set.
+	maintenance *maintenanceMode
+
+	// watch streams the function events of Events from "/system/functions/watch", when
+	// the provider does not set WatchFunctions.
+	watch *functionWatch
+
+	// tracer exports the spans of requests to TracingEndpoint, when set.
+	tracer *tracing.Tracer
+
+	registered bool
+
+	// err is the first error from Handlers, returned by Serve.
+	err error
+}
+
+// NewServer creates a Server for config, a nil config uses the defaults.
+func NewServer(config *types.FaaSConfig) *Server {
+	if config == nil {
+		config = &types.FaaSConfig{}
+	}
+
+	// Unmatched requests are answered with the same JSON errors as the provider's own.
+	router := config.GetRouter()
+	router.NotFound(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		httputil.WriteError(w, r, http.StatusNotFound, "no route matches "+r.URL.Path)
+	}))
+	router.MethodNotAllowed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		httputil.WriteError(w, r, http.StatusMethodNotAllowed, "method "+r.Method+" is not allowed for "+r.URL.Path)
+	}))
+
+	return &Server{
+		config:      config,
+		router:      router,
+		baseContext: context.Background(),
+	}
+}
+
+// Router gives access to the router of the Server for when new routes need to be added.
+func (s *Server) Router() httputil.Router {
+	return s.router
+}
+
+// Handlers registers the handlers in the OpenFaaS route spec. It should be called once,
+// before Serve, which returns any error from registering them, such as when a required
+// handler is nil, see FaaSHandlers.Validate, or the basic auth credentials can not be read.
+func (s *Server) Handlers(handlers *types.FaaSHandlers) {
+	if s.err != nil {
+		return
+	}
+
+	if s.registered {
+		s.err = fmt.Errorf("handlers are already registered")
+		return
+	}
+
+	s.registered = true
+
+	// The handlers are checked before they are decorated, as a decorated nil handler is
+	// not nil. The routes are still registered when a handler is missing, so that the
+	// router can be used on its own, but Serve returns the error.
+	err := handlers.Validate()
+	if handlers == nil {
+		s.err = err
+		return
+	}
+
+	if registerErr := s.register(handlers); registerErr != nil {
+		s.err = registerErr
+		return
+	}
+	s.err = err
+}
+
+// register decorates the handlers and binds them to the router of the Server.
+func (s *Server) register(handlers *types.FaaSHandlers) error {
+	r := s.router
+	config := s.config
+
+	// "/danger/kill" terminates every instance of a function, so it is only served with a
+	// confirmation token.
+	if handlers.KillAllInstance != nil && len(config.KillConfirmationToken) == 0 {
+		return fmt.Errorf("invalid config: KillConfirmationToken is required to serve KillAllInstance")
+	}
+
+	if handlers.WatchFunctions == nil && config.Events != nil {
+		s.watch = newFunctionWatch(config.Events, config.AllowedNamespaces)
+		handlers.WatchFunctions = s.watch.handler
+	}
+	if handlers.WatchFunctions != nil {
+		handlers.WatchFunctions = decorateWithWatchNamespace(handlers.WatchFunctions, config.AllowedNamespaces)
+	}
+
+	// Capabilities are read before the handlers are decorated, as a decorated nil handler
+	// is not nil.
+	capabilitiesHandler := newCapabilitiesHandler(types.CapabilitiesFromHandlers(handlers))
+
+	// Responses are cached inside the ETag, so that conditional requests are answered from
+	// the cache as well.
+	var functionCache *functionCache
+	if config.FunctionCache != nil {
+		functionCache = newFunctionCache(config.FunctionCache, config.GetFunctionCacheTTL(), config.DefaultNamespace, config.GetLogger())
+		handlers.FunctionStatus = functionCache.decorate(handlers.FunctionStatus, false)
+		handlers.FunctionLister = functionCache.decorate(handlers.FunctionLister, true)
+	}
+
+	// The ETag is computed from the status written by the provider, so that pollers can
+	// use If-None-Match to skip unchanged responses.
+	handlers.FunctionStatus = httputil.DecorateWithETag(handlers.FunctionStatus)
+
+	handlers.FunctionLister = decorateWithAllNamespaces(handlers.FunctionLister, config.AllowedNamespaces)
+
+	// The function and namespace a request addresses, as "name.namespace" or with the
+	// "namespace" query string parameter, are parsed once for the handlers.
+	handlers.FunctionStatus = decorateWithFunction(handlers.FunctionStatus, true)
+	handlers.FunctionLister = decorateWithFunction(handlers.FunctionLister, true)
+	handlers.Logs = decorateWithFunction(handlers.Logs, true)
+	if handlers.WarmPool != nil {
+		handlers.WarmPool = decorateWithFunction(handlers.WarmPool, true)
+	}
+	// Cordons and drains evict instances like "/danger/kill", so they are limited to the
+	// allowed namespaces as well. The allowlist runs within decorateWithFunction, so that
+	// it checks the namespace of a "name.namespace" path too.
+	if handlers.CordonFunction != nil {
+		handlers.CordonFunction = httputil.DecorateWithNamespaceAllowlist(handlers.CordonFunction, config.AllowedNamespaces)
+		handlers.CordonFunction = decorateWithFunction(handlers.CordonFunction, true)
+	}
+	if handlers.DrainFunction != nil {
+		handlers.DrainFunction = httputil.DecorateWithNamespaceAllowlist(handlers.DrainFunction, config.AllowedNamespaces)
+		handlers.DrainFunction = decorateWithFunction(handlers.DrainFunction, true)
+	}
+	// Checkpoints are addressed by ID, with the "namespace" query string parameter.
+	if handlers.CheckpointStatus != nil {
+		handlers.CheckpointStatus = httputil.DecorateWithNamespaceAllowlist(handlers.CheckpointStatus, config.AllowedNamespaces)
+	}
+	if handlers.DeleteCheckpoint != nil {
+		handlers.DeleteCheckpoint = httputil.DecorateWithNamespaceAllowlist(handlers.DeleteCheckpoint, config.AllowedNamespaces)
+	}
+
+	handlers.Logs = newLogStreamLimiter(config.MaxLogStreams, logStreamsGauge).decorate(handlers.Logs)
+
+	handlers.DeployFunction = decorateWithDeprecationWarnings(handlers.DeployFunction, config.DeprecationSunset)
+	handlers.UpdateFunction = decorateWithDeprecationWarnings(handlers.UpdateFunction, config.DeprecationSunset)
+
+	if config.AdmitDeploy != nil {
+		handlers.DeployFunction = decorateWithAdmission(handlers.DeployFunction, config.AdmitDeploy)
+		handlers.UpdateFunction = decorateWithAdmission(handlers.UpdateFunction, config.AdmitDeploy)
+	}
+
+	// Quotas are checked after validation, so that the quantities of a deployment can be
+	// parsed, and before the checkpoint event is published.
+	if config.Quotas != nil {
+		quotas := newNamespaceQuotas(config.Quotas, config.NamespaceUsage, config.DefaultNamespace)
+		handlers.DeployFunction = quotas.decorateDeployment(handlers.DeployFunction)
+		handlers.UpdateFunction = quotas.decorateDeployment(handlers.UpdateFunction)
+		if handlers.RegisterFunction != nil {
+			handlers.RegisterFunction = quotas.decorateRegister(handlers.RegisterFunction)
+		}
+		if handlers.CreateCheckpoint != nil {
+			handlers.CreateCheckpoint = quotas.decorateCheckpoint(handlers.CreateCheckpoint)
+		}
+	}
+
+	handlers.DeployFunction = decorateWithLabelValidation(handlers.DeployFunction)
+	handlers.UpdateFunction = decorateWithLabelValidation(handlers.UpdateFunction)
+	limits := validation.Limits{MaxLabelValueLength: config.MaxLabelValueLength}
+	handlers.DeployFunction = validation.Decorate(handlers.DeployFunction, limits)
+	handlers.UpdateFunction = validation.Decorate(handlers.UpdateFunction, limits)
+
+	// Interceptors run before validation and admission, so that what they check is the
+	// deployment as it was changed.
+	handlers.DeployFunction = decorateWithDeploymentInterceptors(handlers.DeployFunction, handlers.DeploymentInterceptors)
+	handlers.UpdateFunction = decorateWithDeploymentInterceptors(handlers.UpdateFunction, handlers.DeploymentInterceptors)
+
+	lifecycleHook := config.LifecycleHook
+	if config.Events != nil {
+		lifecycleHook = publishLifecycleEvents(config.Events, lifecycleHook)
+	}
+	// The cache is invalidated before the event is published, so that a subscriber which
+	// reads the function is not given a stale status.
+	if functionCache != nil {
+		lifecycleHook = functionCache.invalidate(lifecycleHook)
+	}
+	if lifecycleHook != nil {
+		handlers.DeployFunction = decorateWithLifecycleHook(handlers.DeployFunction, types.FunctionCreated, lifecycleHook)
+		handlers.UpdateFunction = decorateWithLifecycleHook(handlers.UpdateFunction, types.FunctionUpdated, lifecycleHook)
+		handlers.DeleteFunction = decorateWithLifecycleHook(handlers.DeleteFunction, types.FunctionDeleted, lifecycleHook)
+		handlers.ScaleFunction = decorateWithLifecycleHook(handlers.ScaleFunction, types.FunctionScaled, lifecycleHook)
+	}
+	// A merge patch is applied to the deployment it names, which is then updated through the
+	// same checks and lifecycle hook as a PUT.
+	var patchHandler http.HandlerFunc
+	if handlers.ReadDeployment != nil {
+		patchHandler = newMergePatchHandler(handlers.UpdateFunction, handlers.ReadDeployment)
+	}
+
+	if config.Events != nil && handlers.CreateCheckpoint != nil {
+		handlers.CreateCheckpoint = decorateWithCheckpointEvent(handlers.CreateCheckpoint, config.Events)
+	}
+	if config.Events != nil && handlers.CordonFunction != nil {
+		handlers.CordonFunction = decorateWithDrainEvents(handlers.CordonFunction, config.Events)
+	}
+	if config.Events != nil && handlers.DrainFunction != nil {
+		handlers.DrainFunction = decorateWithDrainEvents(handlers.DrainFunction, config.Events)
+	}
+
+	// Retries with the same X-Idempotency-Key are answered before validation, hooks or
+	// events, but within the body limit, read-only mode and auth.
+	idempotency := newIdempotencyCache(config.GetIdempotencyTTL())
+	handlers.DeployFunction = idempotency.decorate(handlers.DeployFunction)
+	handlers.RegisterFunction = idempotency.decorate(handlers.RegisterFunction)
+	handlers.KillAllInstance = idempotency.decorate(handlers.KillAllInstance)
+
+	handlers.DeployFunction = decorateWithBodyLimit(handlers.DeployFunction, config.MaxRequestBodyBytes)
+	handlers.UpdateFunction = decorateWithBodyLimit(handlers.UpdateFunction, config.MaxRequestBodyBytes)
+	patchHandler = decorateWithBodyLimit(patchHandler, config.MaxRequestBodyBytes)
+	handlers.RegisterFunction = decorateWithBodyLimit(handlers.RegisterFunction, config.MaxRequestBodyBytes)
+	handlers.Secrets = decorateWithBodyLimit(handlers.Secrets, config.MaxRequestBodyBytes)
+	handlers.ScaleFunction = decorateWithBodyLimit(handlers.ScaleFunction, config.MaxRequestBodyBytes)
+	handlers.WarmPool = decorateWithBodyLimit(handlers.WarmPool, config.MaxRequestBodyBytes)
+	handlers.CordonFunction = decorateWithBodyLimit(handlers.CordonFunction, config.MaxRequestBodyBytes)
+	handlers.DrainFunction = decorateWithBodyLimit(handlers.DrainFunction, config.MaxRequestBodyBytes)
+
+	readOnly := &readOnlyMode{}
+	readOnly.enabled.Store(config.ReadOnly)
+	if len(config.ReadOnlyFile) > 0 {
+		exists, err := fileExists(config.ReadOnlyFile)
+		if err != nil {
+			return fmt.Errorf("unable to check ReadOnlyFile: %w", err)
+		}
+		readOnly.enabled.Store(config.ReadOnly || exists)
+	}
+	s.readOnly = readOnly
+	handlers.DeployFunction = readOnly.decorate(handlers.DeployFunction)
+	handlers.UpdateFunction = readOnly.decorate(handlers.UpdateFunction)
+	handlers.DeleteFunction = readOnly.decorate(handlers.DeleteFunction)
+	handlers.ScaleFunction = readOnly.decorate(handlers.ScaleFunction)
+	handlers.Secrets = readOnly.decorate(handlers.Secrets)
+	if handlers.MutateNamespace != nil {
+		handlers.MutateNamespace = readOnly.decorate(handlers.MutateNamespace)
+	}
+	if handlers.WarmPool != nil {
+		handlers.WarmPool = readOnly.decorate(handlers.WarmPool)
+	}
+	if patchHandler != nil {
+		patchHandler = readOnly.decorate(patchHandler)
+	}
+	if handlers.CordonFunction != nil {
+		handlers.CordonFunction = readOnly.decorate(handlers.CordonFunction)
+	}
+	if handlers.DrainFunction != nil {
+		handlers.DrainFunction = readOnly.decorate(handlers.DrainFunction)
+	}
+	if handlers.DeleteCheckpoint != nil {
+		handlers.DeleteCheckpoint = readOnly.decorate(handlers.DeleteCheckpoint)
+	}
+
+	readOnlyHandler := http.HandlerFunc(readOnly.handler)
+
+	var checkpointGCHandler http.HandlerFunc
+	if config.CheckpointRetention != nil {
+		s.checkpoints = newCheckpointGC(config.CheckpointStore, *config.CheckpointRetention)
+		checkpointGCHandler = readOnly.decorate(s.checkpoints.handler)
+	}
+
+	writes := newWriteLimiter(config.MaxSystemConcurrency)
+	handlers.DeployFunction = writes.decorate(handlers.DeployFunction)
+	handlers.UpdateFunction = writes.decorate(handlers.UpdateFunction)
+	handlers.DeleteFunction = writes.decorate(handlers.DeleteFunction)
+	handlers.ScaleFunction = writes.decorate(handlers.ScaleFunction)
+	if patchHandler != nil {
+		patchHandler = writes.decorate(patchHandler)
+	}
+
+	maintenanceState := types.MaintenanceMode{
+		Enabled:    config.Maintenance,
+		Message:    config.MaintenanceMessage,
+		RetryAfter: int(config.MaintenanceRetryAfter / time.Second),
+	}
+	if len(config.MaintenanceFile) > 0 {
+		state, err := readMaintenanceFile(config.MaintenanceFile, maintenanceState)
+		if err != nil {
+			return fmt.Errorf("unable to read MaintenanceFile: %w", err)
+		}
+		if state.Enabled || !config.Maintenance {
+			maintenanceState = state
+		}
+	}
+	maintenance := newMaintenanceMode(maintenanceState)
+	s.maintenance = maintenance
+	maintenanceHandler := http.HandlerFunc(maintenance.handler)
+
+	handlers.Info = decorateWithStartedAt(handlers.Info)
+
+	authenticator := config.Authenticator
+	if authenticator == nil && config.EnableBasicAuth {
+		var reader auth.ReadBasicAuth = &auth.ReadBasicAuthFromDisk{
 			SecretMountPath: config.SecretMountPath,
 		}
+		if len(config.SecretMountPath) == 0 {
+			reader = &auth.ReadBasicAuthFromEnv{}
+		}
+
+		if config.BasicAuthReloadInterval > 0 {
+			credentials, err := auth.NewReloadingCredentials(reader)
+			if err != nil {
+				return fmt.Errorf("unable to read basic auth credentials: %w", err)
+			}
+
+			s.credentials = credentials
+			authenticator = credentials
+		} else {
+			credentials, err := reader.Read()
+			if err != nil {
+				return fmt.Errorf("unable to read basic auth credentials: %w", err)
+			}
+
+			authenticator = credentials
+		}
+	}
 
-		credentials, err := reader.Read()
+	var hmacVerifier *hmac.Verifier
+	if config.EnableHMACAuth {
+		key, err := hmac.ReadKey(config.SecretMountPath, "")
 		if err != nil {
-			log.Fatal(err)
+			return fmt.Errorf("unable to read the HMAC key: %w", err)
+		}
+		hmacVerifier = hmac.NewVerifier(key, config.HMACMaxSkew, "/healthz", "/readyz", "/metrics")
+		hmacVerifier.MaxBodyBytes = config.MaxRequestBodyBytes
+		hmacVerifier.AllowMissingNonce = config.HMACAllowMissingNonce
+		if config.HMACVerifyInvocations {
+			if config.MaxProxyBodyBytes > hmacVerifier.MaxBodyBytes {
+				hmacVerifier.MaxBodyBytes = config.MaxProxyBodyBytes
+			}
+		} else {
+			hmacVerifier.ExemptPrefixes = invocationPrefixes
+		}
+	}
+
+	var auditor *auditor
+	if config.Audit != nil {
+		auditor = newAuditor(config.Audit, config.AuditInvocations)
+		if authenticator != nil {
+			authenticator = auditor.authenticator(authenticator)
+		}
+	}
+
+	if authenticator != nil {
+		handlers.FunctionLister = authenticator.Decorate(handlers.FunctionLister)
+		handlers.DeployFunction = authenticator.Decorate(handlers.DeployFunction)
+		handlers.DeleteFunction = authenticator.Decorate(handlers.DeleteFunction)
+		handlers.UpdateFunction = authenticator.Decorate(handlers.UpdateFunction)
+		handlers.FunctionStatus = authenticator.Decorate(handlers.FunctionStatus)
+		handlers.ScaleFunction = authenticator.Decorate(handlers.ScaleFunction)
+		handlers.Info = authenticator.Decorate(handlers.Info)
+		handlers.Secrets = authenticator.Decorate(handlers.Secrets)
+		handlers.Logs = authenticator.Decorate(handlers.Logs)
+		handlers.RegisterFunction = authenticator.Decorate(handlers.RegisterFunction)
+		if handlers.FunctionInstances != nil {
+			handlers.FunctionInstances = authenticator.Decorate(handlers.FunctionInstances)
+		}
+		if handlers.WatchFunctions != nil {
+			handlers.WatchFunctions = authenticator.Decorate(handlers.WatchFunctions)
+		}
+		if handlers.ProxyState != nil {
+			handlers.ProxyState = authenticator.Decorate(handlers.ProxyState)
+		}
+		if handlers.FunctionSpec != nil {
+			handlers.FunctionSpec = authenticator.Decorate(handlers.FunctionSpec)
+		}
+		if patchHandler != nil {
+			patchHandler = authenticator.Decorate(patchHandler)
+		}
+		if handlers.CreateCheckpoint != nil {
+			handlers.CreateCheckpoint = authenticator.Decorate(handlers.CreateCheckpoint)
+		}
+		if handlers.RestoreCheckpoint != nil {
+			handlers.RestoreCheckpoint = authenticator.Decorate(handlers.RestoreCheckpoint)
+		}
+		if handlers.CheckpointStatus != nil {
+			handlers.CheckpointStatus = authenticator.Decorate(handlers.CheckpointStatus)
+		}
+		if handlers.DeleteCheckpoint != nil {
+			handlers.DeleteCheckpoint = authenticator.Decorate(handlers.DeleteCheckpoint)
+		}
+		if handlers.WarmPool != nil {
+			handlers.WarmPool = authenticator.Decorate(handlers.WarmPool)
+		}
+		if handlers.CordonFunction != nil {
+			handlers.CordonFunction = authenticator.Decorate(handlers.CordonFunction)
+		}
+		if handlers.DrainFunction != nil {
+			handlers.DrainFunction = authenticator.Decorate(handlers.DrainFunction)
 		}
+		if checkpointGCHandler != nil {
+			checkpointGCHandler = authenticator.Decorate(checkpointGCHandler)
+		}
+		readOnlyHandler = authenticator.Decorate(readOnlyHandler)
+		capabilitiesHandler = authenticator.Decorate(capabilitiesHandler)
+		maintenanceHandler = authenticator.Decorate(maintenanceHandler)
+	}
+
+	// Invoke, BatchInvoke, AsyncFunction, KillAllInstance, MetricFunction and ListCheckpoint
+	// are only authenticated when their route is set in AuthPolicies, BatchInvoke and
+	// AsyncFunction by the policy of InvokeRoute. The metrics and kill handlers are decorated
+	// where they are registered, so that credentials are checked before the audit log and
+	// the confirmation token.
+	handlers.InvokeFunction = decorateWithAuthPolicy(handlers.InvokeFunction, authenticator, config.GetAuthPolicy(types.InvokeRoute))
+	handlers.BatchInvoke = decorateWithAuthPolicy(handlers.BatchInvoke, authenticator, config.GetAuthPolicy(types.InvokeRoute))
+	handlers.AsyncFunction = decorateWithAuthPolicy(handlers.AsyncFunction, authenticator, config.GetAuthPolicy(types.InvokeRoute))
+	handlers.ListCheckpoint = decorateWithAuthPolicy(handlers.ListCheckpoint, authenticator, config.GetAuthPolicy(types.CheckpointsRoute))
+
+	hm := defaultHttpMetrics()
+
+	// Every route is given a request ID and a logger carrying it, before any middleware
+	// which logs.
+	r.Use(logging.Middleware(config.GetLogger()))
+
+	if config.Events != nil {
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				next.ServeHTTP(w, r.WithContext(events.WithBus(r.Context(), config.Events)))
+			})
+		})
+	}
+
+	s.inFlight = newInFlight(inFlightGauge)
+	r.Use(s.inFlight.middleware)
+
+	if len(config.TracingEndpoint) > 0 {
+		s.tracer = tracing.NewTracer(config.TracingEndpoint, config.GetTracingServiceName(), config.GetTracingSampleRatio())
+		r.Use(newTracingMiddleware(s.tracer))
+	}
+
+	if !config.DisableRecovery {
+		r.Use(recoverPanics(config.GetLogger()))
+	}
+
+	for _, mw := range config.Middleware {
+		r.Use(mw)
+	}
+
+	if config.AccessLog {
+		accessLog := newAccessLogger(config.AccessLogFormat, os.Stdout)
+		if config.Logger != nil {
+			accessLog = newSlogAccessLogger(config.Logger)
+		}
+		r.Use(accessLog.middleware)
+	}
+
+	if auditor != nil {
+		r.Use(auditor.middleware)
+	}
+
+	// Preflight requests are answered before maintenance mode or requireGateway, as
+	// browsers send them without credentials or the gateway's headers.
+	if config.CORS != nil || config.FunctionCORS != nil {
+		r.Use(newCORSPolicies(config.CORS, config.FunctionCORS).middleware)
+	}
+
+	r.Use(maintenance.middleware)
+
+	if config.RequireGateway {
+		r.Use(requireGateway(config.GatewayHeaders))
+	}
 
-		handlers.FunctionLister = auth.DecorateWithBasicAuth(handlers.FunctionLister, credentials)
-		handlers.DeployFunction = auth.DecorateWithBasicAuth(handlers.DeployFunction, credentials)
-		handlers.DeleteFunction = auth.DecorateWithBasicAuth(handlers.DeleteFunction, credentials)
-		handlers.UpdateFunction = auth.DecorateWithBasicAuth(handlers.UpdateFunction, credentials)
-		handlers.FunctionStatus = auth.DecorateWithBasicAuth(handlers.FunctionStatus, credentials)
-		handlers.ScaleFunction = auth.DecorateWithBasicAuth(handlers.ScaleFunction, credentials)
-		handlers.Info = auth.DecorateWithBasicAuth(handlers.Info, credentials)
-		handlers.Secrets = auth.DecorateWithBasicAuth(handlers.Secrets, credentials)
-		handlers.Logs = auth.DecorateWithBasicAuth(handlers.Logs, credentials)
-		handlers.RegisterFunction = auth.DecorateWithBasicAuth(handlers.RegisterFunction, credentials)
-		// NOTE by huang-jl Invoke, KillAllInstance, Metric, ListCheckpoint function do not need auth for simplicity
+	if hmacVerifier != nil {
+		r.Use(hmacVerifier.Middleware)
 	}
 
-	hm := newHttpMetrics()
+	var rateLimiter *invokeRateLimiter
+	if config.InvokeRateLimit > 0 {
+		rateLimiter = newInvokeRateLimiter(config.InvokeRateLimit, config.InvokeRateLimitWindow, config.DefaultNamespace)
+		// Batches served by the batchInvoker are charged once per item instead.
+		rateLimiter.chargeBatchItems = handlers.BatchInvoke == nil
+		r.Use(rateLimiter.middleware)
+	}
+
+	if config.MaxSystemRequests > 0 || config.MaxDataPlaneRequests > 0 {
+		r.Use(newConcurrencyLimiter(config.MaxSystemRequests, config.MaxDataPlaneRequests).middleware)
+	}
+
+	r.Use(newRequestTimeout(config.MaxRequestTimeout).middleware)
+
+	if config.DecompressRequests || config.CompressResponses {
+		r.Use(newCompression(config.DecompressRequests, config.CompressResponses, config.MaxRequestBodyBytes).middleware)
+	}
 
 	// System (auth) endpoints
-	r.HandleFunc("/system/functions", hm.InstrumentHandler(handlers.FunctionLister, "")).Methods(http.MethodGet)
-	r.HandleFunc("/system/functions", hm.InstrumentHandler(handlers.DeployFunction, "")).Methods(http.MethodPost)
-	r.HandleFunc("/system/functions", hm.InstrumentHandler(handlers.DeleteFunction, "")).Methods(http.MethodDelete)
-	r.HandleFunc("/system/functions", hm.InstrumentHandler(handlers.UpdateFunction, "")).Methods(http.MethodPut)
+	r.Handle("/system/functions", hm.InstrumentHandler(handlers.FunctionLister, ""), http.MethodGet)
+	r.Handle("/system/functions", hm.InstrumentHandler(handlers.DeployFunction, ""), http.MethodPost)
+	r.Handle("/system/functions", hm.InstrumentHandler(handlers.DeleteFunction, ""), http.MethodDelete)
+	r.Handle("/system/functions", hm.InstrumentHandler(handlers.UpdateFunction, ""), http.MethodPut)
+	if patchHandler != nil {
+		r.Handle("/system/functions", hm.InstrumentHandler(patchHandler, ""), http.MethodPatch)
+	}
 
-	r.HandleFunc("/system/function/{name:["+NameExpression+"]+}",
-		hm.InstrumentHandler(handlers.FunctionStatus, "/system/function")).Methods(http.MethodGet)
-	r.HandleFunc("/system/scale-function/{name:["+NameExpression+"]+}",
-		hm.InstrumentHandler(handlers.ScaleFunction, "/system/scale-function")).Methods(http.MethodPost)
+	if handlers.WatchFunctions != nil {
+		r.Handle("/system/functions/watch", hm.InstrumentHandler(handlers.WatchFunctions, ""), http.MethodGet)
+	} else {
+		r.Handle("/system/functions/watch",
+			hm.InstrumentHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				httputil.WriteErrorCode(w, r, http.StatusNotImplemented, httputil.NotImplemented, "Feature not implemented in this version of OpenFaaS")
+			}), ""), http.MethodGet)
+	}
 
-	r.HandleFunc("/system/info",
-		hm.InstrumentHandler(handlers.Info, "")).Methods(http.MethodGet)
+	r.Handle("/system/function/{name:["+NameExpression+"]+}",
+		hm.InstrumentHandler(handlers.FunctionStatus, "/system/function"), http.MethodGet)
+	r.Handle("/system/scale-function/{name:["+NameExpression+"]+}",
+		hm.InstrumentHandler(handlers.ScaleFunction, "/system/scale-function"), http.MethodPost)
 
-	r.HandleFunc("/system/secrets",
-		hm.InstrumentHandler(handlers.Secrets, "")).Methods(http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete)
+	if handlers.FunctionInstances != nil {
+		r.Handle("/system/function/{name:["+NameExpression+"]+}/instances",
+			hm.InstrumentHandler(handlers.FunctionInstances, "/system/function/instances"), http.MethodGet)
+	}
+
+	if handlers.FunctionSpec != nil {
+		r.Handle("/system/function/{name:["+NameExpression+"]+}/spec",
+			hm.InstrumentHandler(handlers.FunctionSpec, "/system/function/spec"), http.MethodGet)
+	}
 
-	r.HandleFunc("/system/logs",
-		hm.InstrumentHandler(handlers.Logs, "")).Methods(http.MethodGet)
+	r.Handle("/system/info",
+		hm.InstrumentHandler(handlers.Info, ""), http.MethodGet)
 
-	r.HandleFunc("/system/namespaces", hm.InstrumentHandler(handlers.ListNamespaces, "")).Methods(http.MethodGet)
+	r.Handle("/system/secrets",
+		hm.InstrumentHandler(handlers.Secrets, ""), http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete)
+
+	r.Handle("/system/logs",
+		hm.InstrumentHandler(handlers.Logs, ""), http.MethodGet)
+
+	r.Handle("/system/capabilities",
+		hm.InstrumentHandler(capabilitiesHandler, ""), http.MethodGet)
+
+	r.Handle("/system/read-only",
+		hm.InstrumentHandler(readOnlyHandler, ""), http.MethodGet, http.MethodPut)
+
+	r.Handle("/system/maintenance",
+		hm.InstrumentHandler(maintenanceHandler, ""), http.MethodGet, http.MethodPut)
+
+	if handlers.ProxyState != nil {
+		r.Handle("/system/proxy/state",
+			hm.InstrumentHandler(handlers.ProxyState, ""), http.MethodGet, http.MethodDelete)
+	}
+
+	r.Handle("/system/namespaces", hm.InstrumentHandler(handlers.ListNamespaces, ""), http.MethodGet)
 
 	// Only register the mutate namespace handler if it is defined
 	if handlers.MutateNamespace != nil {
-		r.HandleFunc("/system/namespace/{name:["+NameExpression+"]*}",
-			hm.InstrumentHandler(handlers.MutateNamespace, "")).Methods(http.MethodPost, http.MethodDelete, http.MethodPut, http.MethodGet)
+		r.Handle("/system/namespace/{name:["+NameExpression+"]*}",
+			hm.InstrumentHandler(handlers.MutateNamespace, ""), http.MethodPost, http.MethodDelete, http.MethodPut, http.MethodGet)
 	} else {
-		r.HandleFunc("/system/namespace/{name:["+NameExpression+"]*}",
+		r.Handle("/system/namespace/{name:["+NameExpression+"]*}",
 			hm.InstrumentHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				http.Error(w, "Feature not implemented in this version of OpenFaaS", http.StatusNotImplemented)
-			}), "")).Methods(http.MethodGet)
+				httputil.WriteErrorCode(w, r, http.StatusNotImplemented, httputil.NotImplemented, "Feature not implemented in this version of OpenFaaS")
+			}), ""), http.MethodGet)
 	}
 
-	proxyHandler := handlers.FunctionProxy
+	proxyHandler := decorateWithBodyLimit(handlers.FunctionProxy, config.MaxProxyBodyBytes)
+	invokeHandler := decorateWithBodyLimit(handlers.InvokeFunction, config.MaxProxyBodyBytes)
+	asyncHandler := decorateWithBodyLimit(handlers.AsyncFunction, config.MaxProxyBodyBytes)
+
+	// The Scaler is added within the limits, so that requests over a limit do not wake
+	// functions. The proxy wakes them while resolving, within ColdStartMaxWait.
+	if config.FunctionScaler != nil {
+		onColdStart := defaultInvocationMetrics().recordColdStart
+		if config.Events != nil {
+			onColdStart = publishColdStarts(config.Events, onColdStart)
+		}
+		scaler := scaling.New(config.FunctionScaler, scaling.Config{
+			Backoff:     scaling.Backoff{Timeout: config.ScaleFromZeroTimeout},
+			OnColdStart: onColdStart,
+		})
+		proxyHandler = scaler.Decorate(proxyHandler)
+		if invokeHandler != nil {
+			invokeHandler = scaler.Decorate(invokeHandler)
+		}
+	}
+
+	if config.FunctionLabels != nil {
+		limits := limiter.New(config.FunctionLabels)
+		proxyHandler = limits.Decorate(proxyHandler)
+		if invokeHandler != nil {
+			invokeHandler = limits.Decorate(invokeHandler)
+		}
+	}
+
+	if config.ProxyDrainTimeout > 0 {
+		s.drain = newProxyDrain()
+		proxyHandler = s.drain.decorate(proxyHandler)
+		if invokeHandler != nil {
+			invokeHandler = s.drain.decorate(invokeHandler)
+		}
+	}
+
+	// Invocations are labelled by route rather than by function, so that the number of
+	// series does not grow with the number of functions. The per-function metrics bound
+	// their series with MaxFunctionMetrics instead.
+	im := defaultInvocationMetrics()
+	im.setLimit(config.GetMaxFunctionMetrics())
+	proxyHandler = im.decorate(proxyHandler)
+	invokeHandler = im.decorate(invokeHandler)
+	asyncHandler = im.decorate(asyncHandler)
+
+	proxyHandler = decorateWithFunction(proxyHandler, false)
+	if invokeHandler != nil {
+		invokeHandler = decorateWithFunction(invokeHandler, false)
+	}
+	if asyncHandler != nil {
+		asyncHandler = decorateWithFunction(asyncHandler, false)
+	}
+
+	// The key is set for the resolver before the function is woken or resolved.
+	if len(config.ProxyHashHeader) > 0 {
+		proxyHandler = resolver.DecorateWithKey(proxyHandler, config.ProxyHashHeader)
+		if invokeHandler != nil {
+			invokeHandler = resolver.DecorateWithKey(invokeHandler, config.ProxyHashHeader)
+		}
+	}
+
+	// The breakdown of the latency of every invocation is observed, and returned in its
+	// X-Duration headers with ProxyTimingHeaders.
+	proxyHandler = timing.Decorate(proxyHandler, config.ProxyTimingHeaders)
+	if invokeHandler != nil {
+		invokeHandler = timing.Decorate(invokeHandler, config.ProxyTimingHeaders)
+	}
+
+	proxyHandler = hm.InstrumentHandler(proxyHandler, "/function")
+	if invokeHandler != nil {
+		invokeHandler = hm.InstrumentHandler(invokeHandler, "/invoke")
+	}
+	if asyncHandler != nil {
+		asyncHandler = hm.InstrumentHandler(asyncHandler, "/async-function")
+	}
 
 	// Open endpoints
-	r.HandleFunc("/function/{name:["+NameExpression+"]+}", proxyHandler)
-	r.HandleFunc("/function/{name:["+NameExpression+"]+}/", proxyHandler)
-	r.HandleFunc("/function/{name:["+NameExpression+"]+}/{params:.*}", proxyHandler)
+	r.Handle("/function/{name:["+NameExpression+"]+}", proxyHandler)
+	r.Handle("/function/{name:["+NameExpression+"]+}/", proxyHandler)
+	r.Handle("/function/{name:["+NameExpression+"]+}/{params:.*}", proxyHandler)
+
+	s.gate = &startupGate{}
+	if len(config.StartupChecks) == 0 {
+		s.gate.ready.Store(true)
+	}
 
+	var healthHandler, readyHandler http.HandlerFunc
 	if handlers.Health != nil {
-		r.HandleFunc("/healthz", handlers.Health).Methods(http.MethodGet)
+		healthHandler = s.gate.decorate(handlers.Health)
+	}
+	if handlers.Ready != nil {
+		readyHandler = s.gate.decorate(handlers.Ready)
+	}
+
+	// The gate is registered as a probe rather than decorating the checker's handlers, so
+	// that starting and shutting down are reported in the JSON like any other probe. It is
+	// set rather than registered, so that the checker can be shared with a later server.
+	if checker := config.HealthChecker; checker != nil {
+		if err := checker.Set(health.Probe{Name: "provider", Kind: health.Liveness | health.Readiness, Check: s.gate.check}); err != nil {
+			return fmt.Errorf("unable to register the provider probe: %w", err)
+		}
+		if healthHandler == nil {
+			healthHandler = checker.Handler(health.Liveness)
+		}
+		if readyHandler == nil {
+			readyHandler = checker.Handler(health.Readiness)
+		}
+	}
+
+	if readyHandler == nil {
+		readyHandler = s.gate.decorate(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
 	}
 
+	if healthHandler != nil {
+		r.Handle("/healthz", healthHandler, http.MethodGet)
+	}
+	r.Handle("/readyz", readyHandler, http.MethodGet)
+
 	if handlers.RegisterFunction != nil {
-		r.HandleFunc("/system/register", handlers.RegisterFunction).Methods(http.MethodPost)
+		r.Handle("/system/register", handlers.RegisterFunction, http.MethodPost)
+	}
+	if invokeHandler != nil {
+		r.Handle("/invoke/{name:["+NameExpression+"]+}", invokeHandler)
+		r.Handle("/invoke/{name:["+NameExpression+"]+}/", invokeHandler)
+		r.Handle("/invoke/{name:["+NameExpression+"]+}/{params:.*}", invokeHandler)
 	}
-	if handlers.InvokeFunction != nil {
-		r.HandleFunc("/invoke/{name:["+NameExpression+"]+}", handlers.InvokeFunction)
-		r.HandleFunc("/invoke/{name:["+NameExpression+"]+}/", handlers.InvokeFunction)
-		r.HandleFunc("/invoke/{name:["+NameExpression+"]+}/{params:.*}", handlers.InvokeFunction)
+	batchHandler := handlers.BatchInvoke
+	if batchHandler == nil {
+		batch := newBatchInvoker(proxyHandler, "/function/", config.GetBatchParallelism(), config.GetLogger())
+		if invokeHandler != nil {
+			batch = newBatchInvoker(invokeHandler, "/invoke/", config.GetBatchParallelism(), config.GetLogger())
+		}
+		batch.limiter = rateLimiter
+		batchHandler = batch.handler
+	}
+	batchHandler = decorateWithBodyLimit(batchHandler, config.MaxProxyBodyBytes)
+	r.Handle("/invoke-batch/{name:["+NameExpression+"]+}",
+		hm.InstrumentHandler(decorateWithFunction(batchHandler, false), "/invoke-batch"), http.MethodPost)
+	if asyncHandler != nil {
+		r.Handle("/async-function/{name:["+NameExpression+"]+}", asyncHandler)
+		r.Handle("/async-function/{name:["+NameExpression+"]+}/", asyncHandler)
+		r.Handle("/async-function/{name:["+NameExpression+"]+}/{params:.*}", asyncHandler)
+	}
+	// Metrics scraped from function instances are served from the same route as
+	// MetricFunction, to clients which ask for the Prometheus exposition formats.
+	if config.InstanceMetrics != nil {
+		exposition := newInstanceMetricsHandler(config.InstanceMetrics, config.MetricsTimeout, config.GetLogger())
+		if handlers.MetricFunction == nil {
+			exposition = decorateWithAuthPolicy(exposition, authenticator, config.GetAuthPolicy(types.MetricsRoute))
+			r.Handle("/system/metrics", exposition, http.MethodGet)
+		} else {
+			handlers.MetricFunction = decorateWithInstanceMetrics(handlers.MetricFunction, exposition)
+		}
 	}
 	if handlers.MetricFunction != nil {
-		r.HandleFunc("/system/metrics", handlers.MetricFunction).Methods(http.MethodGet, http.MethodDelete)
+		metricHandler := decorateWithMetricReset(handlers.MetricFunction, defaultInvocationMetrics(), config.GetLogger())
+		metricHandler = decorateWithAuthPolicy(metricHandler, authenticator, config.GetAuthPolicy(types.MetricsRoute))
+		r.Handle("/system/metrics", metricHandler, http.MethodGet, http.MethodDelete)
 	}
 	if handlers.ListCheckpoint != nil {
-		r.HandleFunc("/system/checkpoints", handlers.ListCheckpoint).Methods(http.MethodGet)
+		r.Handle("/system/checkpoints", handlers.ListCheckpoint, http.MethodGet)
+	}
+	if checkpointGCHandler != nil {
+		r.Handle("/system/checkpoints/gc",
+			hm.InstrumentHandler(checkpointGCHandler, "/system/checkpoints/gc"), http.MethodPost)
+	}
+	if handlers.CreateCheckpoint != nil {
+		r.Handle("/system/function/{name:["+NameExpression+"]+}/checkpoint",
+			hm.InstrumentHandler(handlers.CreateCheckpoint, "/system/function/checkpoint"), http.MethodPost)
+	}
+	if handlers.RestoreCheckpoint != nil {
+		handlers.RestoreCheckpoint = decorateWithRestoreEvent(handlers.RestoreCheckpoint)
+		r.Handle("/system/function/{name:["+NameExpression+"]+}/restore",
+			hm.InstrumentHandler(handlers.RestoreCheckpoint, "/system/function/restore"), http.MethodPost)
 	}
-  if handlers.KillAllInstance != nil {
-	  r.HandleFunc("/danger/kill", handlers.KillAllInstance).Methods(http.MethodGet, http.MethodPost, http.MethodPut)
-  }
+	if handlers.CheckpointStatus != nil {
+		r.Handle("/system/checkpoint/{id:["+NameExpression+"]+}",
+			hm.InstrumentHandler(handlers.CheckpointStatus, "/system/checkpoint"), http.MethodGet)
+	}
+	if handlers.DeleteCheckpoint != nil {
+		r.Handle("/system/checkpoint/{id:["+NameExpression+"]+}",
+			hm.InstrumentHandler(handlers.DeleteCheckpoint, "/system/checkpoint"), http.MethodDelete)
+	}
+	if handlers.WarmPool != nil {
+		r.Handle("/system/warm-pool/{name:["+NameExpression+"]+}",
+			hm.InstrumentHandler(handlers.WarmPool, "/system/warm-pool"), http.MethodGet, http.MethodPost)
+	}
+	if handlers.CordonFunction != nil {
+		r.Handle("/system/cordon/{name:["+NameExpression+"]+}",
+			hm.InstrumentHandler(handlers.CordonFunction, "/system/cordon"), http.MethodPost)
+	}
+	if handlers.DrainFunction != nil {
+		r.Handle("/system/drain/{name:["+NameExpression+"]+}",
+			hm.InstrumentHandler(handlers.DrainFunction, "/system/drain"), http.MethodPost)
+	}
+	if handlers.KillAllInstance != nil {
+		killHandler := handlers.KillAllInstance
+		if config.Events != nil {
+			killHandler = decorateWithKillEvent(killHandler, config.Events)
+		}
+		killHandler = decorateWithKillAudit(killHandler)
+		killHandler = httputil.DecorateWithNamespaceAllowlist(killHandler, config.AllowedNamespaces)
+		killHandler = decorateWithKillConfirmation(killHandler, config.KillConfirmationToken)
 
-	r.HandleFunc("/metrics", promhttp.Handler().ServeHTTP)
+		killAuthenticator, killPolicy := authenticator, config.GetAuthPolicy(types.KillRoute)
+		if config.KillAuthenticator != nil {
+			killAuthenticator, killPolicy = config.KillAuthenticator, types.AuthRequired
+			if auditor != nil {
+				killAuthenticator = auditor.authenticator(killAuthenticator)
+			}
+		}
+		killHandler = decorateWithAuthPolicy(killHandler, killAuthenticator, killPolicy)
+		r.Handle("/danger/kill", killHandler, http.MethodPost)
+	}
+
+	if len(config.StaticDir) > 0 {
+		staticPath := config.StaticPath
+		if len(staticPath) == 0 {
+			staticPath = defaultStaticPath
+		}
 
-	readTimeout := config.ReadTimeout
-	writeTimeout := config.WriteTimeout
+		if !strings.HasPrefix(staticPath, "/") || !strings.HasSuffix(staticPath, "/") || staticPathConflicts(staticPath) {
+			return fmt.Errorf("invalid StaticPath %q: must start and end with / and not overlap the API", staticPath)
+		}
 
-	port := 8080
-	if config.TCPPort != nil {
-		port = *config.TCPPort
+		r.HandlePrefix(staticPath, newStaticHandler(config.StaticDir, staticPath), http.MethodGet, http.MethodHead)
 	}
 
-	s := &http.Server{
-		Addr:           fmt.Sprintf(":%d", port),
-		ReadTimeout:    readTimeout,
-		WriteTimeout:   writeTimeout,
-		MaxHeaderBytes: http.DefaultMaxHeaderBytes, // 1MB - can be overridden by setting Server.MaxHeaderBytes.
-		Handler:        r,
+	// Clients which send "Accept: application/openmetrics-text" are given the OpenMetrics
+	// format, with exemplars, all others the Prometheus text format. The metrics which were
+	// gathered are still served when a collector fails, and a gather which takes longer
+	// than MetricsTimeout is answered with a 503.
+	metricsHandler := promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
+			EnableOpenMetrics: true,
+			ErrorHandling:     promhttp.ContinueOnError,
+			ErrorLog:          slog.NewLogLogger(config.GetLogger().Handler(), slog.LevelError),
+			Timeout:           config.MetricsTimeout,
+		}))
+	// With a separate MetricsPort, metrics and health checks are only served from that
+	// port, so that scrapers do not need access to the API.
+	if config.MetricsPort != nil && *config.MetricsPort != config.GetTCPPort() {
+		s.metricsRouter = config.GetRouter()
+		s.metricsRouter.Handle("/metrics", metricsHandler)
+		if healthHandler != nil {
+			s.metricsRouter.Handle("/healthz", healthHandler, http.MethodGet)
+		}
+	} else {
+		r.Handle("/metrics", metricsHandler)
+	}
+
+	if config.EnableProfiling || config.EnableDebugEndpoints {
+		decorate := func(next http.HandlerFunc) http.HandlerFunc { return next }
+		if authenticator != nil {
+			decorate = authenticator.Decorate
+		}
+
+		profilingRouter := r
+		if s.metricsRouter != nil {
+			profilingRouter = s.metricsRouter
+		}
+
+		debugRouter := profilingRouter
+		if config.EnableDebugEndpoints && config.DebugPort != nil {
+			s.debugRouter = config.GetRouter()
+			debugRouter = s.debugRouter
+		}
+
+		if config.EnableDebugEndpoints {
+			registerDebug(debugRouter, decorate, config)
+		}
+		// The debug endpoints include the profiles, so they are only registered again when
+		// the debug endpoints are served from DebugPort.
+		if config.EnableProfiling && (!config.EnableDebugEndpoints || debugRouter != profilingRouter) {
+			registerProfiling(profilingRouter, decorate)
+		}
+	}
+
+	// The function metrics are authenticated like the rest of the system API, and served
+	// from MetricsPort with "/metrics" when it is set.
+	if functionMetricsRegistered.Load() {
+		functionMetricsHandler := http.HandlerFunc(newFunctionMetricsHandler(config.MetricsTimeout, config.GetLogger()).ServeHTTP)
+		if authenticator != nil {
+			functionMetricsHandler = authenticator.Decorate(functionMetricsHandler)
+		}
+
+		functionMetricsRouter := r
+		if s.metricsRouter != nil {
+			functionMetricsRouter = s.metricsRouter
+		}
+		functionMetricsRouter.Handle("/system/function-metrics", hm.InstrumentHandler(functionMetricsHandler, ""), http.MethodGet)
+	}
+
+	// Routes are restricted to their methods, so a route is needed for preflight requests
+	// to reach the CORS middleware, any other OPTIONS request is not allowed.
+	if config.CORS != nil || config.FunctionCORS != nil {
+		r.HandlePrefix("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}), http.MethodOptions)
+	}
+
+	return nil
+}
+
+// Serve serves the API until ctx is cancelled, then shuts the server down gracefully.
+// Unlike the package level Serve, SIGINT and SIGTERM are left for the caller to handle.
+// It returns an error when the config is invalid, Handlers failed, the port can not be
+// bound or the server does not shut down cleanly.
+func (s *Server) Serve(ctx context.Context) error {
+	config := s.config
+
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	if s.err != nil {
+		return s.err
+	}
+
+	logger := config.GetLogger()
+
+	// Responses proxied from functions are streamed for up to the ReadTimeout of the proxy
+	// client, or ProxyMaxTimeout when longer, so a shorter WriteTimeout cuts them off part
+	// way through.
+	proxyTimeout := config.GetReadTimeout()
+	if config.ProxyMaxTimeout > proxyTimeout {
+		proxyTimeout = config.ProxyMaxTimeout
+	}
+	if config.WriteTimeout > 0 && config.WriteTimeout < proxyTimeout {
+		logger.Warn("WriteTimeout is shorter than the proxy timeout, long running or streamed function responses will be cut off",
+			"writeTimeout", config.WriteTimeout, "proxyTimeout", proxyTimeout)
+	}
+
+	// The key is only used by the proxy with consistent hashing, or by a resolver.Resolver
+	// with its own strategy.
+	if len(config.ProxyHashHeader) > 0 && config.ProxyLoadBalancing != types.LoadBalancingConsistentHash {
+		logger.Warn("ProxyHashHeader is only used with the consistent-hash load balancing strategy",
+			"proxyHashHeader", config.ProxyHashHeader, "proxyLoadBalancing", config.ProxyLoadBalancing)
+	}
+
+	markStarted(time.Now())
+
+	gate := s.gate
+	if gate == nil {
+		gate = &startupGate{}
+		gate.ready.Store(true)
+	}
+
+	network := config.GetNetwork()
+	addr := net.JoinHostPort(config.BindAddress, strconv.Itoa(config.GetTCPPort()))
+	if path, ok := config.GetUnixSocket(); ok {
+		network, addr = "unix", path
+	}
+
+	server, err := s.newHTTPServer(addr)
+	if err != nil {
+		return err
+	}
+	s.server = server
+
+	l, err := newListener(network, server.Addr, config.MaxConnections, connectionsGauge)
+	if err != nil {
+		return err
+	}
+	listeners := []net.Listener{l}
+
+	if network == "unix" {
+		if err := os.Chmod(addr, config.GetUnixSocketMode()); err != nil {
+			l.Close()
+			return fmt.Errorf("unable to set the mode of %s: %w", addr, err)
+		}
+	}
+
+	logger.Info("Listening", "network", network, "addr", l.Addr().String())
+
+	if len(config.UnixSocketPath) > 0 {
+		ul, err := newListener("unix", config.UnixSocketPath, config.MaxConnections, connectionsGauge)
+		if err != nil {
+			l.Close()
+			return err
+		}
+		listeners = append(listeners, ul)
+
+		if err := os.Chmod(config.UnixSocketPath, config.GetUnixSocketMode()); err != nil {
+			closeListeners(listeners)
+			return fmt.Errorf("unable to set the mode of %s: %w", config.UnixSocketPath, err)
+		}
+
+		logger.Info("Listening", "network", "unix", "addr", ul.Addr().String())
+	}
+
+	// Every listener is created before anything is started, so that a port which can not be
+	// bound leaves nothing running.
+	servers := []*http.Server{server}
+	bound := make([]boundListener, 0, len(listeners)+2)
+	for _, l := range listeners {
+		bound = append(bound, boundListener{server: server, listener: l, tls: config.TLSConfig != nil})
+	}
+
+	if s.metricsRouter != nil {
+		metricsServer, ml, err := s.listenAuxiliary(config.GetNetwork(), net.JoinHostPort(config.BindAddress, strconv.Itoa(*config.MetricsPort)), s.metricsRouter, server.ErrorLog)
+		if err != nil {
+			closeListeners(listeners)
+			return fmt.Errorf("unable to listen for metrics: %w", err)
+		}
+		servers = append(servers, metricsServer)
+		bound = append(bound, boundListener{server: metricsServer, listener: ml})
+		logger.Info("Serving metrics", "addr", ml.Addr().String())
+	}
+
+	if s.debugRouter != nil {
+		debugServer, dl, err := s.listenAuxiliary("tcp", net.JoinHostPort(debugBindAddress, strconv.Itoa(*config.DebugPort)), s.debugRouter, server.ErrorLog)
+		if err != nil {
+			for _, b := range bound {
+				b.listener.Close()
+			}
+			return fmt.Errorf("unable to listen for debug endpoints: %w", err)
+		}
+		servers = append(servers, debugServer)
+		bound = append(bound, boundListener{server: debugServer, listener: dl})
+		logger.Info("Serving debug endpoints", "addr", dl.Addr().String())
+	}
+
+	// A server which fails cancels ctx, which stops the background tasks and OnReady.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The spans which have ended are exported once the servers have stopped, however Serve
+	// returns.
+	if s.tracer != nil {
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownPhaseTimeout)
+			defer cancel()
+			if err := s.tracer.Shutdown(ctx); err != nil {
+				logger.Error("Unable to export spans", "error", err)
+			}
+		}()
+	}
+
+	if s.credentials != nil {
+		go s.credentials.Watch(logging.WithLogger(ctx, logger), config.BasicAuthReloadInterval)
+	}
+
+	if s.certs != nil {
+		go s.certs.watch(ctx, logger)
+	}
+
+	if len(config.ReadOnlyFile) > 0 && s.readOnly != nil {
+		go s.readOnly.watch(ctx, config.ReadOnlyFile, logger)
+	}
+
+	if len(config.MaintenanceFile) > 0 && s.maintenance != nil {
+		go s.maintenance.watch(ctx, config.MaintenanceFile, types.MaintenanceMode{
+			Message:    config.MaintenanceMessage,
+			RetryAfter: int(config.MaintenanceRetryAfter / time.Second),
+		}, logger)
+	}
+
+	if s.checkpoints != nil {
+		go s.checkpoints.run(ctx, logger)
+	}
+
+	// Events and Audit are run until the servers have shut down, so that the events and
+	// records of the requests still in flight are delivered before Serve returns.
+	sinksCtx, stopSinks := context.WithCancel(context.WithoutCancel(ctx))
+	var sinks sync.WaitGroup
+	defer func() {
+		stopSinks()
+		sinks.Wait()
+	}()
+
+	if config.Events != nil {
+		sinks.Add(1)
+		go func() {
+			defer sinks.Done()
+			config.Events.Run(sinksCtx)
+		}()
+	}
+
+	if config.Audit != nil {
+		sinks.Add(1)
+		go func() {
+			defer sinks.Done()
+			config.Audit.Run(sinksCtx)
+		}()
+	}
+
+	if s.watch != nil {
+		go func() {
+			<-ctx.Done()
+			s.watch.stop()
+		}()
 	}
 
 	go func() {
-		if err := s.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatal(err)
+		if len(config.StartupChecks) > 0 && s.gate != nil {
+			gate.run(ctx, logger, config.StartupChecks, config.StartupTimeout, startupCheckInterval)
+		}
+
+		if config.OnReady != nil && ctx.Err() == nil {
+			config.OnReady(l.Addr())
 		}
 	}()
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
-	<-sig
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	// Shutdown the server gracefully
-	if err := s.Shutdown(ctx); err != nil {
-		log.Fatalf("Server shutdown failed: %v\n", err)
+	serveErr := make(chan error, len(bound))
+	for _, b := range bound {
+		go func(b boundListener) {
+			var err error
+			if b.tls {
+				// The certificate was loaded into server.TLSConfig by ServerConfig
+				err = b.server.ServeTLS(b.listener, "", "")
+			} else {
+				err = b.server.Serve(b.listener)
+			}
+
+			if err != nil && err != http.ErrServerClosed {
+				serveErr <- err
+			}
+		}(b)
+	}
+
+	select {
+	case <-ctx.Done():
+	case err := <-serveErr:
+		cancel()
+		for _, srv := range servers {
+			srv.Close()
+		}
+		return err
+	}
+
+	if s.inFlight != nil {
+		logger.Info("Shutting down", "inFlight", s.inFlight.count.Load())
+	}
+
+	if err := shutdown(logger, servers, gate, s.drain, config.GetShutdownTimeout(), config.ProxyDrainTimeout, config.HealthcheckInterval, config.PreShutdownHooks, config.PostShutdownHooks); err != nil {
+		return fmt.Errorf("server shutdown failed: %w", err)
+	}
+
+	return nil
+}
+
+// boundListener is a listener and the server which serves it.
+type boundListener struct {
+	server   *http.Server
+	listener net.Listener
+	tls      bool
+}
+
+// listenAuxiliary creates the server and listener for handler on addr with the timeouts of
+// the config, for the routers of MetricsPort and DebugPort.
+func (s *Server) listenAuxiliary(network, addr string, handler http.Handler, errorLog *log.Logger) (*http.Server, net.Listener, error) {
+	config := s.config
+
+	server := &http.Server{
+		Addr:              addr,
+		ReadTimeout:       config.ReadTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.GetIdleTimeout(),
+		ReadHeaderTimeout: config.GetReadHeaderTimeout(),
+		MaxHeaderBytes:    http.DefaultMaxHeaderBytes,
+		Handler:           handler,
+		ErrorLog:          errorLog,
+	}
+
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return server, l, nil
+}
+
+// newHTTPServer creates the http.Server for the API on addr, with the timeouts of the config
+// and its TLS certificate loaded, or h2c enabled, and HTTP/2 tuned by FaaSConfig.HTTP2.
+func (s *Server) newHTTPServer(addr string) (*http.Server, error) {
+	config := s.config
+
+	server := &http.Server{
+		Addr:              addr,
+		ReadTimeout:       config.ReadTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.GetIdleTimeout(),
+		ReadHeaderTimeout: config.GetReadHeaderTimeout(),
+		MaxHeaderBytes:    http.DefaultMaxHeaderBytes, // 1MB - can be overridden by setting Server.MaxHeaderBytes.
+		Handler:           s.router,
+		ErrorLog:          slog.NewLogLogger(config.GetLogger().Handler(), slog.LevelError),
+		BaseContext: func(net.Listener) context.Context {
+			return s.baseContext
+		},
+	}
+
+	if config.TLSConfig != nil {
+		tlsConfig, err := s.serverTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		server.TLSConfig = tlsConfig
+	}
+
+	if config.TLSConfig != nil || config.EnableH2C {
+		if err := configureHTTP2(server, config); err != nil {
+			return nil, err
+		}
 	}
+
+	return server, nil
+}
+
+// serverTLSConfig returns a TLS config for a server of the API, from FaaSConfig.TLSConfig.
+// The certificate is served by s.certs, which is shared by the HTTP and gRPC servers, so
+// that both serve the certificate it reloads.
+func (s *Server) serverTLSConfig() (*tls.Config, error) {
+	config := s.config
+
+	tlsConfig, err := config.TLSConfig.ServerConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	s.certsOnce.Do(func() {
+		s.certs = newCertReloader(config.TLSConfig.CertFile, config.TLSConfig.KeyFile, &tlsConfig.Certificates[0])
+	})
+	tlsConfig.Certificates = nil
+	tlsConfig.GetCertificate = s.certs.getCertificate
+	return tlsConfig, nil
 }
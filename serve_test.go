@@ -0,0 +1,888 @@
+package bootstrap
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/openfaas/faas-provider/audit"
+	"github.com/openfaas/faas-provider/events"
+	"github.com/openfaas/faas-provider/health"
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/types"
+	"golang.org/x/net/http2"
+)
+
+// validHandlers returns handlers which pass FaaSHandlers.Validate, each responding with a 200.
+func validHandlers() *types.FaaSHandlers {
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return &types.FaaSHandlers{
+		FunctionProxy:  ok,
+		FunctionLister: ok,
+		DeployFunction: ok,
+		UpdateFunction: ok,
+		DeleteFunction: ok,
+		FunctionStatus: ok,
+		ScaleFunction:  ok,
+		Secrets:        ok,
+		Logs:           ok,
+		Info:           ok,
+		ListNamespaces: ok,
+	}
+}
+
+func Test_ServeWithError_InvalidConfig(t *testing.T) {
+	port := 0
+	config := &types.FaaSConfig{TCPPort: &port}
+
+	err := ServeWithError(&types.FaaSHandlers{}, config)
+	if err == nil {
+		t.Fatalf("want an error for an invalid config, got nil")
+	}
+
+	if !strings.Contains(err.Error(), "invalid config") {
+		t.Errorf("error, want: %q, got: %q", "invalid config", err.Error())
+	}
+}
+
+func Test_ServeContext_Cancel(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	handlers := validHandlers()
+	handlers.Health = func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	config := &types.FaaSConfig{TCPPort: &port, ShutdownTimeout: time.Second}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- ServeContext(ctx, handlers, config)
+	}()
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/healthz", port)
+	var res *http.Response
+	for i := 0; i < 50; i++ {
+		if res, err = http.Get(url); err == nil {
+			res.Body.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("want server to accept requests, got: %s", err)
+	}
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("want no error after cancelling the context, got: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("want ServeContext to return after cancelling the context")
+	}
+}
+
+func Test_ServeContext_ListenErrorStartsNothing(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	// The debug port is in use, so Serve fails once the API listener was created.
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+	debugPort := taken.Addr().(*net.TCPAddr).Port
+
+	ready := make(chan struct{}, 1)
+	config := &types.FaaSConfig{
+		TCPPort:              &port,
+		BindAddress:          "127.0.0.1",
+		EnableDebugEndpoints: true,
+		DebugPort:            &debugPort,
+		OnReady: func(net.Addr) {
+			ready <- struct{}{}
+		},
+	}
+
+	err = ServeContext(context.Background(), validHandlers(), config)
+	if err == nil {
+		t.Fatalf("want an error when the debug port is in use, got nil")
+	}
+
+	select {
+	case <-ready:
+		t.Errorf("want OnReady not to be called when Serve fails")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// The API listener was closed, so the port can be bound again.
+	l, err = net.Listen("tcp", net.JoinHostPort("127.0.0.1", fmt.Sprint(port)))
+	if err != nil {
+		t.Fatalf("want the API port to be released, got: %s", err)
+	}
+	l.Close()
+}
+
+func Test_Server_IsolatedRouters(t *testing.T) {
+	health := func(code int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(code)
+		}
+	}
+
+	a := NewServer(&types.FaaSConfig{})
+	a.Handlers(&types.FaaSHandlers{Health: health(http.StatusOK)})
+
+	b := NewServer(&types.FaaSConfig{})
+	b.Handlers(&types.FaaSHandlers{Health: health(http.StatusAccepted)})
+
+	testCases := []struct {
+		name     string
+		server   *Server
+		wantCode int
+	}{
+		{name: "first server", server: a, wantCode: http.StatusOK},
+		{name: "second server", server: b, wantCode: http.StatusAccepted},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tc.server.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+		})
+	}
+}
+
+// recordingRouter is a Router other than the default, which records the paths bound.
+type recordingRouter struct {
+	*httputil.MuxRouter
+	paths []string
+}
+
+func (r *recordingRouter) Handle(path string, handler http.Handler, methods ...string) {
+	r.paths = append(r.paths, path)
+	r.MuxRouter.Handle(path, handler, methods...)
+}
+
+func Test_Server_NewRouter(t *testing.T) {
+	var routers []*recordingRouter
+	config := &types.FaaSConfig{
+		NewRouter: func() httputil.Router {
+			router := &recordingRouter{MuxRouter: httputil.NewMuxRouter()}
+			routers = append(routers, router)
+			return router
+		},
+	}
+
+	handlers := validHandlers()
+	var gotName string
+	handlers.FunctionStatus = func(w http.ResponseWriter, r *http.Request) {
+		gotName = httputil.PathVar(r, "name")
+		w.WriteHeader(http.StatusOK)
+	}
+
+	s := NewServer(config)
+	s.Handlers(handlers)
+
+	if len(routers) != 1 || s.Router() != routers[0] {
+		t.Fatalf("want the router from NewRouter to be used, got: %d routers", len(routers))
+	}
+	if len(routers[0].paths) == 0 {
+		t.Fatalf("want the routes bound to the router from NewRouter")
+	}
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/system/function/figlet", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("status code, want: %d, got: %d", http.StatusOK, w.Code)
+	}
+	if gotName != "figlet" {
+		t.Errorf("name, want: %s, got: %s", "figlet", gotName)
+	}
+}
+
+func Test_Server_HandlersTwice(t *testing.T) {
+	s := NewServer(&types.FaaSConfig{})
+	s.Handlers(validHandlers())
+	s.Handlers(validHandlers())
+
+	err := s.Serve(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "already registered") {
+		t.Errorf("error, want: %q, got: %v", "already registered", err)
+	}
+}
+
+func Test_Server_OnReady(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "provider.sock")
+
+	checked := make(chan struct{})
+	ready := make(chan net.Addr, 1)
+	config := &types.FaaSConfig{
+		ListenAddress: types.UnixSocketScheme + path,
+		StartupChecks: []func(context.Context) error{
+			func(ctx context.Context) error {
+				close(checked)
+				return nil
+			},
+		},
+		OnReady: func(addr net.Addr) {
+			ready <- addr
+		},
+	}
+
+	s := NewServer(config)
+	s.Handlers(validHandlers())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Serve(ctx)
+	}()
+
+	select {
+	case addr := <-ready:
+		select {
+		case <-checked:
+		default:
+			t.Errorf("want OnReady to be called after the startup checks")
+		}
+
+		if addr.String() != path {
+			t.Errorf("address, want: %s, got: %s", path, addr)
+		}
+	case err := <-done:
+		t.Fatalf("want the server to start, got: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("want OnReady to be called")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("want no error after cancelling the context, got: %s", err)
+	}
+}
+
+func Test_Server_MetricsPort(t *testing.T) {
+	health := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	apiPort, metricsPort := 8080, 8081
+	s := NewServer(&types.FaaSConfig{TCPPort: &apiPort, MetricsPort: &metricsPort})
+	s.Handlers(&types.FaaSHandlers{Health: health})
+
+	if s.metricsRouter == nil {
+		t.Fatalf("want a metrics router when MetricsPort differs from TCPPort")
+	}
+
+	testCases := []struct {
+		name     string
+		router   http.Handler
+		path     string
+		wantCode int
+	}{
+		{name: "metrics removed from the API", router: s.Router(), path: "/metrics", wantCode: http.StatusNotFound},
+		{name: "health still served by the API", router: s.Router(), path: "/healthz", wantCode: http.StatusOK},
+		{name: "metrics on the metrics port", router: s.metricsRouter, path: "/metrics", wantCode: http.StatusOK},
+		{name: "health on the metrics port", router: s.metricsRouter, path: "/healthz", wantCode: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tc.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+		})
+	}
+
+	same := NewServer(&types.FaaSConfig{TCPPort: &apiPort, MetricsPort: &apiPort})
+	same.Handlers(&types.FaaSHandlers{})
+	if same.metricsRouter != nil {
+		t.Errorf("want metrics on the API when MetricsPort is the same as TCPPort")
+	}
+}
+
+func Test_Server_Readyz(t *testing.T) {
+	testCases := []struct {
+		name     string
+		ready    http.HandlerFunc
+		wantCode int
+	}{
+		{name: "default", wantCode: http.StatusOK},
+		{name: "not ready", ready: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}, wantCode: http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewServer(&types.FaaSConfig{})
+			s.Handlers(&types.FaaSHandlers{Ready: tc.ready})
+
+			w := httptest.NewRecorder()
+			s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if w.Code != tc.wantCode {
+				t.Errorf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+		})
+	}
+}
+
+func Test_Server_HealthChecker(t *testing.T) {
+	checker := health.NewChecker(time.Second)
+	checker.Register(health.Probe{Name: "queue", Kind: health.Readiness, Check: func(ctx context.Context) error {
+		return errors.New("connection refused")
+	}})
+
+	s := NewServer(&types.FaaSConfig{HealthChecker: checker})
+	s.Handlers(validHandlers())
+
+	report := func(path string) (int, health.Report) {
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		report := health.Report{}
+		if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+			t.Fatalf("%s: want a JSON report, got: %s", path, err)
+		}
+		return w.Code, report
+	}
+
+	if code, _ := report("/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz status code, want: %d, got: %d", http.StatusOK, code)
+	}
+	code, ready := report("/readyz")
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz status code, want: %d, got: %d", http.StatusServiceUnavailable, code)
+	}
+	if len(ready.Checks) != 2 || ready.Checks[0].Name != "provider" || ready.Checks[1].Error != "connection refused" {
+		t.Errorf("/readyz checks, want: provider and a failing queue, got: %+v", ready.Checks)
+	}
+
+	s.gate.stopping.Store(true)
+
+	code, live := report("/healthz")
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("/healthz status code during shutdown, want: %d, got: %d", http.StatusServiceUnavailable, code)
+	}
+	if len(live.Checks) != 1 || live.Checks[0].Error != "provider is shutting down" {
+		t.Errorf("/healthz checks during shutdown, want: provider is shutting down, got: %+v", live.Checks)
+	}
+}
+
+func Test_NewHTTPServer_SharesHealthChecker(t *testing.T) {
+	checker := health.NewChecker(time.Second)
+	config := &types.FaaSConfig{HealthChecker: checker}
+
+	for i := 0; i < 2; i++ {
+		if _, err := NewHTTPServer(validHandlers(), config); err != nil {
+			t.Fatalf("server %d, want no error, got: %s", i+1, err)
+		}
+	}
+
+	if report := checker.Check(context.Background(), health.Readiness); len(report.Checks) != 1 {
+		t.Errorf("checks, want: %d, got: %+v", 1, report.Checks)
+	}
+}
+
+func Test_Server_Middleware(t *testing.T) {
+	header := func(value string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Middleware", value)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	s := NewServer(&types.FaaSConfig{
+		Middleware: []func(http.Handler) http.Handler{header("first"), header("second")},
+	})
+	s.Handlers(&types.FaaSHandlers{FunctionLister: ok, FunctionProxy: ok})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/system/functions", nil),
+		httptest.NewRequest(http.MethodPost, "/function/figlet", nil),
+	} {
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+
+		want := []string{"first", "second"}
+		if got := w.Header().Values("X-Middleware"); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s X-Middleware, want: %v, got: %v", req.URL.Path, want, got)
+		}
+	}
+}
+
+func Test_Server_MissingHandlers(t *testing.T) {
+	handlers := validHandlers()
+	handlers.FunctionProxy = nil
+	handlers.Logs = nil
+
+	s := NewServer(&types.FaaSConfig{})
+	s.Handlers(handlers)
+
+	err := s.Serve(context.Background())
+	want := "FunctionProxy, Logs must be set"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("error, want: %q, got: %v", want, err)
+	}
+}
+
+func Test_Server_H2C(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	handlers := validHandlers()
+	handlers.FunctionProxy = func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}
+
+	ready := make(chan struct{})
+	s := NewServer(&types.FaaSConfig{
+		TCPPort:   &port,
+		EnableH2C: true,
+		OnReady: func(addr net.Addr) {
+			close(ready)
+		},
+	})
+	s.Handlers(handlers)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Serve(ctx)
+	}()
+
+	select {
+	case <-ready:
+	case err := <-done:
+		t.Fatalf("want the server to start, got: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("want the server to start")
+	}
+
+	// Prior knowledge: HTTP/2 is spoken from the first byte, without an upgrade or TLS.
+	client := &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, addr)
+			},
+		},
+	}
+
+	res, err := client.Post(fmt.Sprintf("http://127.0.0.1:%d/function/figlet", port), "text/plain", strings.NewReader("hi"))
+	if err != nil {
+		t.Fatalf("want an h2c response, got: %s", err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("status code, want: %d, got: %d", http.StatusOK, res.StatusCode)
+	}
+	if string(body) != "HTTP/2.0" {
+		t.Errorf("protocol seen by the handler, want: %s, got: %s", "HTTP/2.0", body)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("want no error after cancelling the context, got: %s", err)
+	}
+}
+
+func Test_Server_UnixSocketPath(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	path := filepath.Join(t.TempDir(), "provider.sock")
+
+	ready := make(chan struct{})
+	s := NewServer(&types.FaaSConfig{
+		TCPPort:        &port,
+		BindAddress:    "127.0.0.1",
+		UnixSocketPath: path,
+		UnixSocketMode: 0600,
+		OnReady: func(addr net.Addr) {
+			close(ready)
+		},
+	})
+	s.Handlers(validHandlers())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Serve(ctx)
+	}()
+
+	select {
+	case <-ready:
+	case err := <-done:
+		t.Fatalf("want the server to start, got: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("want the server to start")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("want the socket to exist, got: %s", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("socket mode, want: %o, got: %o", 0600, mode)
+	}
+
+	unixClient := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			},
+		},
+	}
+
+	for name, get := range map[string]func() (*http.Response, error){
+		"tcp": func() (*http.Response, error) {
+			return http.Get(fmt.Sprintf("http://127.0.0.1:%d/system/functions", port))
+		},
+		"unix": func() (*http.Response, error) { return unixClient.Get("http://provider/system/functions") },
+	} {
+		res, err := get()
+		if err != nil {
+			t.Fatalf("want a response over %s, got: %s", name, err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("status code over %s, want: %d, got: %d", name, http.StatusOK, res.StatusCode)
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("want no error after cancelling the context, got: %s", err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("want the socket to be removed on shutdown, got: %v", err)
+	}
+}
+
+func Test_NewHTTPServer(t *testing.T) {
+	timeout := 3 * time.Second
+	srv, err := NewHTTPServer(validHandlers(), &types.FaaSConfig{ReadTimeout: timeout, WriteTimeout: timeout})
+	if err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+
+	if srv.Addr != ":8080" {
+		t.Errorf("Addr, want: %s, got: %s", ":8080", srv.Addr)
+	}
+	if srv.ReadTimeout != timeout || srv.WriteTimeout != timeout {
+		t.Errorf("timeouts, want: %s, got: %s and %s", timeout, srv.ReadTimeout, srv.WriteTimeout)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Serve(l)
+	}()
+
+	res, err := http.Get(fmt.Sprintf("http://%s/system/functions", l.Addr()))
+	if err != nil {
+		t.Fatalf("want the server to accept requests, got: %s", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("status code, want: %d, got: %d", http.StatusOK, res.StatusCode)
+	}
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Errorf("want no error from Shutdown, got: %s", err)
+	}
+	if err := <-done; err != http.ErrServerClosed {
+		t.Errorf("want http.ErrServerClosed, got: %v", err)
+	}
+}
+
+func Test_NewHTTPServer_Errors(t *testing.T) {
+	handlers := validHandlers()
+	handlers.FunctionProxy = nil
+
+	testCases := []struct {
+		name     string
+		handlers *types.FaaSHandlers
+		config   *types.FaaSConfig
+		wantErr  string
+	}{
+		{name: "missing handler", handlers: handlers, config: &types.FaaSConfig{}, wantErr: "FunctionProxy must be set"},
+		{name: "startup checks", handlers: validHandlers(), config: &types.FaaSConfig{
+			StartupChecks: []func(context.Context) error{func(context.Context) error { return nil }},
+		}, wantErr: "StartupChecks require Serve"},
+		{name: "unix socket", handlers: validHandlers(), config: &types.FaaSConfig{ListenAddress: "unix:///tmp/provider.sock"}, wantErr: "ListenAddress require Serve"},
+		{name: "events", handlers: validHandlers(), config: &types.FaaSConfig{Events: events.NewBus(events.Config{})}, wantErr: "Events require Serve"},
+		{name: "audit", handlers: validHandlers(), config: &types.FaaSConfig{Audit: audit.NewTrail(audit.Config{})}, wantErr: "Audit require Serve"},
+		{name: "checkpoint retention", handlers: validHandlers(), config: &types.FaaSConfig{
+			CheckpointStore:     &fakeCheckpointStore{},
+			CheckpointRetention: &types.CheckpointRetention{MaxPerFunction: 1},
+		}, wantErr: "CheckpointRetention require Serve"},
+		{name: "tracing", handlers: validHandlers(), config: &types.FaaSConfig{TracingEndpoint: "http://otel-collector:4318"}, wantErr: "TracingEndpoint require Serve"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewHTTPServer(tc.handlers, tc.config)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("error, want: %q, got: %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_Server_AsyncFunction(t *testing.T) {
+	handlers := validHandlers()
+	var gotName string
+	handlers.AsyncFunction = func(w http.ResponseWriter, r *http.Request) {
+		gotName = mux.Vars(r)["name"]
+		w.WriteHeader(http.StatusAccepted)
+	}
+
+	srv, err := NewHTTPServer(handlers, &types.FaaSConfig{})
+	if err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+
+	w := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/async-function/figlet/render", nil))
+	if w.Code != http.StatusAccepted {
+		t.Errorf("status code, want: %d, got: %d", http.StatusAccepted, w.Code)
+	}
+	if gotName != "figlet" {
+		t.Errorf("name, want: %s, got: %s", "figlet", gotName)
+	}
+}
+
+func Test_Server_Checkpoints(t *testing.T) {
+	handlers := validHandlers()
+	var gotID string
+	handlers.CheckpointStatus = func(w http.ResponseWriter, r *http.Request) {
+		gotID = mux.Vars(r)["id"]
+		types.WriteJSON(w, http.StatusOK, types.Checkpoint{ID: gotID})
+	}
+	handlers.DeleteCheckpoint = func(w http.ResponseWriter, r *http.Request) {
+		gotID = mux.Vars(r)["id"]
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	srv, err := NewHTTPServer(handlers, &types.FaaSConfig{})
+	if err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+
+	cases := []struct {
+		method     string
+		wantStatus int
+	}{
+		{http.MethodGet, http.StatusOK},
+		{http.MethodDelete, http.StatusNoContent},
+		{http.MethodPost, http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range cases {
+		gotID = ""
+		w := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(w, httptest.NewRequest(tc.method, "/system/checkpoint/figlet-1", nil))
+		if w.Code != tc.wantStatus {
+			t.Errorf("%s status code, want: %d, got: %d", tc.method, tc.wantStatus, w.Code)
+		}
+		if tc.wantStatus < 300 && gotID != "figlet-1" {
+			t.Errorf("%s id, want: %s, got: %s", tc.method, "figlet-1", gotID)
+		}
+	}
+}
+
+func Test_Server_Checkpoints_ReadOnlyAndAllowlist(t *testing.T) {
+	handlers := validHandlers()
+	handlers.CheckpointStatus = func(w http.ResponseWriter, r *http.Request) {
+		types.WriteJSON(w, http.StatusOK, types.Checkpoint{ID: mux.Vars(r)["id"]})
+	}
+	handlers.DeleteCheckpoint = func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	s := NewServer(&types.FaaSConfig{ReadOnly: true, AllowedNamespaces: []string{"openfaas-fn"}})
+	s.Handlers(handlers)
+
+	cases := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{"status", http.MethodGet, "/system/checkpoint/figlet-1?namespace=openfaas-fn", http.StatusOK},
+		{"status in another namespace", http.MethodGet, "/system/checkpoint/figlet-1?namespace=kube-system", http.StatusForbidden},
+		{"delete in read-only mode", http.MethodDelete, "/system/checkpoint/figlet-1?namespace=openfaas-fn", http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.Router().ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+			if w.Code != tc.wantStatus {
+				t.Errorf("status code, want: %d, got: %d", tc.wantStatus, w.Code)
+			}
+		})
+	}
+
+	s = NewServer(&types.FaaSConfig{AllowedNamespaces: []string{"openfaas-fn"}})
+	s.Handlers(handlers)
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/system/checkpoint/figlet-1?namespace=kube-system", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("delete in another namespace, status code, want: %d, got: %d", http.StatusForbidden, w.Code)
+	}
+}
+
+func Test_Server_WarmPool(t *testing.T) {
+	warmPool := func(w http.ResponseWriter, r *http.Request) {
+		name, namespace, _ := httputil.FunctionFromContext(r.Context())
+		status := types.WarmPoolStatus{Function: name, Namespace: namespace}
+
+		if r.Method == http.MethodPost {
+			policy, err := types.DecodeWarmPoolPolicy(r.Body)
+			if err != nil {
+				httputil.WriteError(w, r, http.StatusBadRequest, err.Error())
+				return
+			}
+			status.Policy, status.Starting = policy, policy.MinWarm
+		}
+		types.WriteJSON(w, http.StatusOK, status)
+	}
+
+	testCases := []struct {
+		name     string
+		config   types.FaaSConfig
+		method   string
+		path     string
+		body     string
+		wantCode int
+		want     types.WarmPoolStatus
+	}{
+		{
+			name:     "get",
+			method:   http.MethodGet,
+			path:     "/system/warm-pool/figlet?namespace=dev",
+			wantCode: http.StatusOK,
+			want:     types.WarmPoolStatus{Function: "figlet", Namespace: "dev"},
+		},
+		{
+			name:     "set policy",
+			method:   http.MethodPost,
+			path:     "/system/warm-pool/figlet.dev",
+			body:     `{"minWarm":2,"checkpointId":"figlet-1"}`,
+			wantCode: http.StatusOK,
+			want:     types.WarmPoolStatus{Function: "figlet", Namespace: "dev", Policy: types.WarmPoolPolicy{MinWarm: 2, CheckpointID: "figlet-1"}, Starting: 2},
+		},
+		{
+			name:     "invalid policy",
+			method:   http.MethodPost,
+			path:     "/system/warm-pool/figlet",
+			body:     `{"minWarm":-1}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "read-only",
+			config:   types.FaaSConfig{ReadOnly: true},
+			method:   http.MethodPost,
+			path:     "/system/warm-pool/figlet",
+			body:     `{"minWarm":1}`,
+			wantCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:     "method not allowed",
+			method:   http.MethodDelete,
+			path:     "/system/warm-pool/figlet",
+			wantCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handlers := validHandlers()
+			handlers.WarmPool = warmPool
+
+			s := NewServer(&tc.config)
+			s.Handlers(handlers)
+
+			w := httptest.NewRecorder()
+			s.Router().ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body)))
+
+			if w.Code != tc.wantCode {
+				t.Fatalf("status code, want: %d, got: %d (%s)", tc.wantCode, w.Code, w.Body.String())
+			}
+			if tc.wantCode != http.StatusOK {
+				return
+			}
+
+			got := types.WarmPoolStatus{}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("status, want: %+v, got: %+v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// shutdownPhaseTimeout bounds the pre-shutdown and post-shutdown hooks.
+const shutdownPhaseTimeout = 10 * time.Second
+
+// shutdown stops the server in a fixed order, so that hooks such as deregistering from
+// service discovery complete before the listener is closed:
+//
+//  1. the gate is closed, so that the health endpoint returns 503
+//  2. pre-shutdown hooks are run while requests are still served
+//  3. requests are still served until healthcheckInterval has passed since the gate was
+//     closed, so that load balancers see the failing health check and stop sending traffic
+//  4. the listener is closed and in-flight requests are given up to shutdownTimeout to
+//     drain, requests to functions tracked by drain are given up to drainTimeout before
+//     they are cancelled
+//  5. post-shutdown hooks are run
+//
+// Every server, i.e. the API and the metrics server, is drained at the same time. A hook
+// which fails is logged and does not stop the shutdown, the errors from draining the
+// servers are returned.
+func shutdown(logger *slog.Logger, servers []*http.Server, gate *startupGate, drain *proxyDrain, shutdownTimeout, drainTimeout, healthcheckInterval time.Duration, preHooks, postHooks []func(context.Context) error) error {
+	gate.stopping.Store(true)
+	stoppedAt := time.Now()
+
+	runShutdownHooks(logger, "pre-shutdown", preHooks)
+
+	if wait := healthcheckInterval - time.Since(stoppedAt); wait > 0 {
+		logger.Info("Waiting for the failing health check to be seen", "wait", wait)
+		time.Sleep(wait)
+	}
+
+	timeout := shutdownTimeout
+	if drain != nil {
+		timeout += drainTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	shutdownErr := make(chan error, len(servers))
+	for _, s := range servers {
+		go func(s *http.Server) {
+			shutdownErr <- s.Shutdown(ctx)
+		}(s)
+	}
+
+	if drain != nil && !drain.wait(drainTimeout) {
+		logger.Warn("Requests to functions did not complete in time, cancelling them", "timeout", drainTimeout)
+	}
+
+	var errs []error
+	for range servers {
+		if err := <-shutdownErr; err != nil {
+			errs = append(errs, err)
+		}
+	}
+	err := errors.Join(errs...)
+
+	runShutdownHooks(logger, "post-shutdown", postHooks)
+
+	return err
+}
+
+// runShutdownHooks calls each hook in order, sharing a single phase timeout.
+func runShutdownHooks(logger *slog.Logger, phase string, hooks []func(context.Context) error) {
+	if len(hooks) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownPhaseTimeout)
+	defer cancel()
+
+	for i, hook := range hooks {
+		if err := hook(ctx); err != nil {
+			logger.Error("Shutdown hook failed", "phase", phase, "hook", i, "error", err)
+		}
+	}
+}
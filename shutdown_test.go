@@ -0,0 +1,112 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_shutdown_Ordering(t *testing.T) {
+	gate := &startupGate{}
+	gate.ready.Store(true)
+
+	health := gate.decorate(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &http.Server{Handler: health}
+	go s.Serve(l)
+
+	url := "http://" + l.Addr().String() + "/healthz"
+
+	var got []string
+	pre := func(ctx context.Context) error {
+		w := httptest.NewRecorder()
+		health(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("health during pre-shutdown, want: %d, got: %d", http.StatusServiceUnavailable, w.Code)
+		}
+
+		// The listener is still open while pre-shutdown hooks run
+		res, err := http.Get(url)
+		if err != nil {
+			t.Errorf("want server to accept requests during pre-shutdown, got: %s", err)
+		} else {
+			res.Body.Close()
+		}
+
+		got = append(got, "pre")
+		return nil
+	}
+	failing := func(ctx context.Context) error {
+		got = append(got, "failing")
+		return errors.New("deregister failed")
+	}
+	post := func(ctx context.Context) error {
+		if _, err := http.Get(url); err == nil {
+			t.Errorf("want server to be closed during post-shutdown")
+		}
+
+		got = append(got, "post")
+		return nil
+	}
+
+	if err := shutdown(slog.Default(), []*http.Server{s}, gate, nil, 10*time.Second, 0, 0, []func(context.Context) error{pre, failing}, []func(context.Context) error{post}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"pre", "failing", "post"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("hooks, want: %v, got: %v", want, got)
+	}
+}
+
+func Test_shutdown_HealthcheckInterval(t *testing.T) {
+	gate := &startupGate{}
+	gate.ready.Store(true)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &http.Server{Handler: gate.decorate(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})}
+	go s.Serve(l)
+
+	interval := 300 * time.Millisecond
+	done := make(chan error, 1)
+	start := time.Now()
+	go func() {
+		done <- shutdown(slog.Default(), []*http.Server{s}, gate, nil, 10*time.Second, 0, interval, nil, nil)
+	}()
+
+	time.Sleep(interval / 3)
+	res, err := http.Get("http://" + l.Addr().String() + "/healthz")
+	if err != nil {
+		t.Fatalf("want the server to accept requests within the healthcheck interval, got: %s", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("health within the healthcheck interval, want: %d, got: %d", http.StatusServiceUnavailable, res.StatusCode)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < interval {
+		t.Errorf("want shutdown to wait for the healthcheck interval of %s, took: %s", interval, elapsed)
+	}
+}
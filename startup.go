@@ -0,0 +1,88 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/openfaas/faas-provider/httputil"
+)
+
+// startupCheckInterval is the delay between attempts of the startup checks.
+const startupCheckInterval = time.Second
+
+// startupGate reports the provider as not ready until the startup checks have passed,
+// and again once it has started to shut down.
+type startupGate struct {
+	ready    atomic.Bool
+	stopping atomic.Bool
+}
+
+// decorate returns 503 from next until the gate has been opened, and after it has
+// been closed for shutdown.
+func (g *startupGate) decorate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := g.check(r.Context()); err != nil {
+			httputil.WriteErrorCode(w, r, http.StatusServiceUnavailable, httputil.NotReady, err.Error())
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// check returns an error until the gate has been opened, and after it has been closed for
+// shutdown. It is registered as a probe of FaaSConfig.HealthChecker.
+func (g *startupGate) check(context.Context) error {
+	if g.stopping.Load() {
+		return errors.New("provider is shutting down")
+	}
+	if !g.ready.Load() {
+		return errors.New("provider is starting")
+	}
+	return nil
+}
+
+// run calls each check until all of them pass in the same attempt, or timeout elapses,
+// then opens the gate. A timeout of zero retries until ctx is cancelled.
+func (g *startupGate) run(ctx context.Context, logger *slog.Logger, checks []func(context.Context) error, timeout time.Duration, interval time.Duration) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := runChecks(ctx, checks)
+		if err == nil {
+			logger.Info("Startup checks passed")
+			g.ready.Store(true)
+			return
+		}
+
+		logger.Warn("Startup checks failed", "error", err)
+
+		select {
+		case <-ctx.Done():
+			logger.Warn("Startup checks did not pass in time, marking the provider as ready", "timeout", timeout)
+			g.ready.Store(true)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func runChecks(ctx context.Context, checks []func(context.Context) error) error {
+	for _, check := range checks {
+		if err := check(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_startupGate_NotReadyUntilChecksPass(t *testing.T) {
+	gate := &startupGate{}
+	health := gate.decorate(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	health.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status code before startup, want: %d, got: %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	attempts := 0
+	check := func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("backend not reachable")
+		}
+		return nil
+	}
+
+	gate.run(context.Background(), slog.Default(), []func(context.Context) error{check}, time.Second, time.Millisecond)
+
+	if attempts != 3 {
+		t.Errorf("attempts, want: %d, got: %d", 3, attempts)
+	}
+
+	w = httptest.NewRecorder()
+	health.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("status code after startup, want: %d, got: %d", http.StatusOK, w.Code)
+	}
+}
+
+func Test_startupGate_ReadyAfterTimeout(t *testing.T) {
+	gate := &startupGate{}
+
+	check := func(ctx context.Context) error {
+		return errors.New("backend not reachable")
+	}
+
+	gate.run(context.Background(), slog.Default(), []func(context.Context) error{check}, 20*time.Millisecond, time.Millisecond)
+
+	if !gate.ready.Load() {
+		t.Errorf("want the provider to be marked ready once the startup timeout elapsed")
+	}
+}
@@ -0,0 +1,64 @@
+package bootstrap
+
+import (
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// defaultStaticPath is where StaticDir is served when StaticPath is not set.
+const defaultStaticPath = "/ui/"
+
+// reservedPathPrefixes are used by the API, static files may not be served under them.
+var reservedPathPrefixes = append([]string{"/system/", "/danger/", "/healthz", "/readyz", "/metrics", "/debug/"}, invocationPrefixes...)
+
+// newStaticHandler serves the files in dir under prefix. Directories are only served when
+// they contain an index.html, so that their contents are not listed.
+func newStaticHandler(dir string, prefix string) http.Handler {
+	return http.StripPrefix(prefix, http.FileServer(noListingFileSystem{http.Dir(dir)}))
+}
+
+// noListingFileSystem hides directories which do not have an index.html.
+type noListingFileSystem struct {
+	fs http.FileSystem
+}
+
+func (n noListingFileSystem) Open(name string) (http.File, error) {
+	f, err := n.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if stat.IsDir() {
+		index, err := n.fs.Open(path.Join(name, "index.html"))
+		if err != nil {
+			f.Close()
+			return nil, os.ErrNotExist
+		}
+		index.Close()
+	}
+
+	return f, nil
+}
+
+// staticPathConflicts reports whether serving static files under prefix would shadow
+// or be shadowed by the API.
+func staticPathConflicts(prefix string) bool {
+	if prefix == "/" {
+		return true
+	}
+
+	for _, reserved := range reservedPathPrefixes {
+		if strings.HasPrefix(prefix, reserved) || strings.HasPrefix(reserved, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package bootstrap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_newStaticHandler(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html>status</html>"), 0644)
+	os.WriteFile(filepath.Join(dir, "app.js"), []byte("console.log(1)"), 0644)
+	os.Mkdir(filepath.Join(dir, "assets"), 0755)
+	os.WriteFile(filepath.Join(dir, "assets", "logo.svg"), []byte("<svg></svg>"), 0644)
+
+	handler := newStaticHandler(dir, "/ui/")
+
+	testCases := []struct {
+		path            string
+		wantCode        int
+		wantContentType string
+	}{
+		{path: "/ui/", wantCode: http.StatusOK, wantContentType: "text/html"},
+		{path: "/ui/app.js", wantCode: http.StatusOK, wantContentType: "javascript"},
+		{path: "/ui/assets/logo.svg", wantCode: http.StatusOK, wantContentType: "image/svg+xml"},
+		{path: "/ui/assets/", wantCode: http.StatusNotFound},
+		{path: "/ui/missing.css", wantCode: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			if w.Code != tc.wantCode {
+				t.Fatalf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+
+			if got := w.Header().Get("Content-Type"); !strings.Contains(got, tc.wantContentType) {
+				t.Errorf("Content-Type, want: %s, got: %s", tc.wantContentType, got)
+			}
+		})
+	}
+}
+
+func Test_staticPathConflicts(t *testing.T) {
+	testCases := []struct {
+		path string
+		want bool
+	}{
+		{path: "/ui/", want: false},
+		{path: "/dashboard/", want: false},
+		{path: "/", want: true},
+		{path: "/system/", want: true},
+		{path: "/function/ui/", want: true},
+		{path: "/sys", want: true},
+	}
+
+	for _, tc := range testCases {
+		if got := staticPathConflicts(tc.path); got != tc.want {
+			t.Errorf("%s conflicts, want: %t, got: %t", tc.path, tc.want, got)
+		}
+	}
+}
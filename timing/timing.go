@@ -0,0 +1,246 @@
+// Package timing breaks the latency of an invocation down into phases, such as resolving
+// the function and restoring it from a checkpoint, so that slow invocations can be
+// explained rather than only measured.
+//
+// Middleware adds a Recorder to the context of each request. The proxy marks the Resolve,
+// Connect and FirstByte phases, while a provider marks the phases only it can see with the
+// context of the request. A resolver.Resolver passed to the proxy with proxy.FromResolver
+// is given that context, so it can mark the restore of an instance from a checkpoint on
+// the request path:
+//
+//	done := timing.Start(ctx, timing.Restore)
+//	instance, err := restore(ctx, checkpoint)
+//	done()
+//
+// Each phase is observed in the provider_invocation_phase_duration_seconds histogram, and
+// when enabled, returned to the client as an X-Duration header, i.e. X-Duration-Restore.
+package timing
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Phase is a part of an invocation which is timed.
+type Phase string
+
+const (
+	// Resolve is the time taken to resolve the address of the function, including waiting
+	// for it to scale from zero.
+	Resolve Phase = "resolve"
+	// Restore is the time taken to restore an instance of the function from a checkpoint,
+	// it is marked by the provider.
+	Restore Phase = "restore"
+	// Connect is the time taken to obtain a connection to the function, which is close to
+	// zero when an idle connection is reused.
+	Connect Phase = "connect"
+	// FirstByte is the time from sending the request to the function until the first byte
+	// of its response.
+	FirstByte Phase = "first-byte"
+)
+
+// HeaderPrefix is the prefix of the response header of each phase, i.e. X-Duration-Resolve.
+// The value is the duration in seconds.
+const HeaderPrefix = "X-Duration-"
+
+// Header returns the name of the response header for phase.
+func Header(phase Phase) string {
+	return http.CanonicalHeaderKey(HeaderPrefix + string(phase))
+}
+
+var phaseDurationHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Subsystem: "provider",
+	Name:      "invocation_phase_duration_seconds",
+	Help:      "Seconds spent in each phase of an invocation.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"phase"})
+
+// Recorder accumulates the duration of each phase of a request, a phase which is marked
+// more than once, such as when a request is retried, adds up. It is safe for concurrent use.
+type Recorder struct {
+	mu        sync.Mutex
+	durations map[Phase]time.Duration
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{durations: map[Phase]time.Duration{}}
+}
+
+// Observe adds d to phase.
+func (r *Recorder) Observe(phase Phase, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.durations[phase] += d
+}
+
+// Start starts timing phase, the returned func stops it.
+func (r *Recorder) Start(phase Phase) func() {
+	start := time.Now()
+	return func() {
+		r.Observe(phase, time.Since(start))
+	}
+}
+
+// Durations returns a copy of the durations recorded so far.
+func (r *Recorder) Durations() map[Phase]time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	durations := make(map[Phase]time.Duration, len(r.durations))
+	for phase, d := range r.durations {
+		durations[phase] = d
+	}
+	return durations
+}
+
+type recorderKey struct{}
+
+// WithRecorder returns a copy of ctx carrying r.
+func WithRecorder(ctx context.Context, r *Recorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, r)
+}
+
+// FromContext returns the Recorder of ctx, or nil when there is none.
+func FromContext(ctx context.Context) *Recorder {
+	r, _ := ctx.Value(recorderKey{}).(*Recorder)
+	return r
+}
+
+// Start starts timing phase for the Recorder of ctx, the returned func stops it. It does
+// nothing when ctx has no Recorder.
+func Start(ctx context.Context, phase Phase) func() {
+	r := FromContext(ctx)
+	if r == nil {
+		return func() {}
+	}
+	return r.Start(phase)
+}
+
+// Observe adds d to phase for the Recorder of ctx. It does nothing when ctx has no Recorder.
+func Observe(ctx context.Context, phase Phase, d time.Duration) {
+	if r := FromContext(ctx); r != nil {
+		r.Observe(phase, d)
+	}
+}
+
+// WithClientTrace returns a copy of ctx which marks the Connect and FirstByte phases of
+// the requests sent with it, for the Recorder of ctx. It returns ctx when there is none.
+func WithClientTrace(ctx context.Context) context.Context {
+	r := FromContext(ctx)
+	if r == nil {
+		return ctx
+	}
+
+	var mu sync.Mutex
+	var getConn, wroteRequest time.Time
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(string) {
+			mu.Lock()
+			defer mu.Unlock()
+			getConn = time.Now()
+		},
+		GotConn: func(httptrace.GotConnInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			if !getConn.IsZero() {
+				r.Observe(Connect, time.Since(getConn))
+				getConn = time.Time{}
+			}
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			wroteRequest = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			mu.Lock()
+			defer mu.Unlock()
+			if !wroteRequest.IsZero() {
+				r.Observe(FirstByte, time.Since(wroteRequest))
+				wroteRequest = time.Time{}
+			}
+		},
+	})
+}
+
+// Middleware adds a Recorder to the context of each request. Every phase is observed in
+// the provider_invocation_phase_duration_seconds histogram once the request has been
+// served. With headers, the phases recorded by the time the response is written are set
+// as its X-Duration headers.
+func Middleware(next http.Handler, headers bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := NewRecorder()
+		if headers {
+			w = &responseWriter{ResponseWriter: w, recorder: recorder}
+		}
+		next.ServeHTTP(w, r.WithContext(WithRecorder(r.Context(), recorder)))
+
+		for phase, d := range recorder.Durations() {
+			phaseDurationHistogram.WithLabelValues(string(phase)).Observe(d.Seconds())
+		}
+	})
+}
+
+// Decorate is Middleware for a http.HandlerFunc.
+func Decorate(next http.HandlerFunc, headers bool) http.HandlerFunc {
+	return Middleware(next, headers).ServeHTTP
+}
+
+// responseWriter sets the X-Duration headers before the final response header is written.
+type responseWriter struct {
+	http.ResponseWriter
+	recorder    *Recorder
+	wroteHeader bool
+}
+
+func (w *responseWriter) setHeaders() {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	header := w.Header()
+	for phase, d := range w.recorder.Durations() {
+		header.Set(Header(phase), strconv.FormatFloat(d.Seconds(), 'f', 6, 64))
+	}
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	// 1xx responses, other than a switch of protocols, are followed by the final response.
+	if code >= http.StatusOK || code == http.StatusSwitchingProtocols {
+		w.setHeaders()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.setHeaders()
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.setHeaders()
+		f.Flush()
+	}
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
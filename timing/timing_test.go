@@ -0,0 +1,142 @@
+package timing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_Recorder_AddsUpPhases(t *testing.T) {
+	r := NewRecorder()
+	r.Observe(Resolve, 10*time.Millisecond)
+	r.Observe(Resolve, 5*time.Millisecond)
+	r.Observe(Connect, time.Millisecond)
+
+	durations := r.Durations()
+	if got := durations[Resolve]; got != 15*time.Millisecond {
+		t.Errorf("resolve, want: %s, got: %s", 15*time.Millisecond, got)
+	}
+	if got := durations[Connect]; got != time.Millisecond {
+		t.Errorf("connect, want: %s, got: %s", time.Millisecond, got)
+	}
+	if _, ok := durations[Restore]; ok {
+		t.Errorf("restore, want: not recorded, got: %s", durations[Restore])
+	}
+}
+
+func Test_Start_WithoutRecorder(t *testing.T) {
+	ctx := context.Background()
+
+	Start(ctx, Restore)()
+	Observe(ctx, Restore, time.Second)
+
+	if got := WithClientTrace(ctx); got != ctx {
+		t.Errorf("want the context to be returned as is without a Recorder")
+	}
+}
+
+func Test_Header(t *testing.T) {
+	testCases := []struct {
+		phase Phase
+		want  string
+	}{
+		{Resolve, "X-Duration-Resolve"},
+		{Restore, "X-Duration-Restore"},
+		{Connect, "X-Duration-Connect"},
+		{FirstByte, "X-Duration-First-Byte"},
+	}
+
+	for _, tc := range testCases {
+		if got := Header(tc.phase); got != tc.want {
+			t.Errorf("header, want: %s, got: %s", tc.want, got)
+		}
+	}
+}
+
+func Test_Middleware(t *testing.T) {
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Observe(r.Context(), Restore, 1500*time.Millisecond)
+
+		// 1xx responses are not the final response, the phases are not known yet.
+		w.WriteHeader(http.StatusEarlyHints)
+		if got := w.Header().Get(Header(Restore)); got != "" {
+			t.Errorf("%s on 1xx response, want: empty, got: %s", Header(Restore), got)
+		}
+
+		Observe(r.Context(), FirstByte, 250*time.Millisecond)
+		w.Write([]byte("OK"))
+
+		// Phases marked after the response was written can not be sent as headers.
+		Observe(r.Context(), Connect, time.Second)
+	}), true)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/function/figlet", nil))
+
+	testCases := []struct {
+		header string
+		want   string
+	}{
+		{Header(Restore), "1.500000"},
+		{Header(FirstByte), "0.250000"},
+		{Header(Connect), ""},
+	}
+	for _, tc := range testCases {
+		if got := w.Header().Get(tc.header); got != tc.want {
+			t.Errorf("%s, want: %q, got: %q", tc.header, tc.want, got)
+		}
+	}
+}
+
+func Test_Middleware_WithoutHeaders(t *testing.T) {
+	var recorder *Recorder
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder = FromContext(r.Context())
+		Observe(r.Context(), Restore, 1500*time.Millisecond)
+		w.Write([]byte("OK"))
+	}), false)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/function/figlet", nil))
+
+	if recorder == nil {
+		t.Fatalf("want a Recorder in the context of the request")
+	}
+	if got := recorder.Durations()[Restore]; got != 1500*time.Millisecond {
+		t.Errorf("%s, want: %s, got: %s", Restore, 1500*time.Millisecond, got)
+	}
+	if got := w.Header().Get(Header(Restore)); got != "" {
+		t.Errorf("%s, want: empty, got: %s", Header(Restore), got)
+	}
+}
+
+func Test_WithClientTrace(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("OK"))
+	}))
+	defer upstream.Close()
+
+	recorder := NewRecorder()
+	ctx := WithClientTrace(WithRecorder(context.Background(), recorder))
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL, nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	durations := recorder.Durations()
+	if _, ok := durations[Connect]; !ok {
+		t.Errorf("want the connect phase to be recorded")
+	}
+	if got := durations[FirstByte]; got < 20*time.Millisecond {
+		t.Errorf("first byte, want at least: %s, got: %s", 20*time.Millisecond, got)
+	}
+	if len(durations) != 2 {
+		t.Errorf("phases, want: %d, got: %d", 2, len(durations))
+	}
+}
@@ -0,0 +1,79 @@
+package bootstrap
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// certReloader serves the certificate in certFile and keyFile to TLS clients, and reads
+// them again on reload, so that a renewed certificate is used without a restart. New
+// connections get the new certificate, established ones keep the one they negotiated.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+
+	// watching is set while watch runs, so that a reloader shared by the HTTP and gRPC
+	// servers is only reloaded once for each SIGHUP.
+	watching atomic.Bool
+}
+
+// newCertReloader creates a reloader which serves cert, as loaded from certFile and keyFile.
+func newCertReloader(certFile, keyFile string, cert *tls.Certificate) *certReloader {
+	return &certReloader{certFile: certFile, keyFile: keyFile, cert: cert}
+}
+
+// reload reads the key pair again, the current certificate is kept when it can not be read.
+func (c *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("unable to load TLS certificate: %w", err)
+	}
+
+	c.mu.Lock()
+	c.cert = &cert
+	c.mu.Unlock()
+	return nil
+}
+
+// getCertificate implements tls.Config.GetCertificate.
+func (c *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// watch calls reload each time the process receives SIGHUP, until ctx is done. It returns
+// at once when the reloader is already being watched.
+func (c *certReloader) watch(ctx context.Context, logger *slog.Logger) {
+	if !c.watching.CompareAndSwap(false, true) {
+		return
+	}
+	defer c.watching.Store(false)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if err := c.reload(); err != nil {
+				logger.Error("Unable to reload the TLS certificate", "error", err)
+				continue
+			}
+			logger.Info("Reloaded the TLS certificate", "certFile", c.certFile)
+		}
+	}
+}
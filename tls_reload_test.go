@@ -0,0 +1,128 @@
+package bootstrap
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+// writeTestCertificate writes a self-signed certificate for commonName and its key to dir.
+func writeTestCertificate(t *testing.T, dir, commonName string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{commonName},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+
+	return certFile, keyFile
+}
+
+func commonName(t *testing.T, cert *tls.Certificate) string {
+	t.Helper()
+
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Subject.CommonName
+}
+
+func Test_certReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCertificate(t, dir, "old.example.com")
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newCertReloader(certFile, keyFile, &cert)
+
+	got, _ := c.getCertificate(nil)
+	if name := commonName(t, got); name != "old.example.com" {
+		t.Errorf("certificate before reload, want: %s, got: %s", "old.example.com", name)
+	}
+
+	writeTestCertificate(t, dir, "new.example.com")
+	if err := c.reload(); err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+
+	got, _ = c.getCertificate(nil)
+	if name := commonName(t, got); name != "new.example.com" {
+		t.Errorf("certificate after reload, want: %s, got: %s", "new.example.com", name)
+	}
+
+	os.WriteFile(keyFile, []byte("not a key"), 0600)
+	if err := c.reload(); err == nil {
+		t.Errorf("want error for an invalid key")
+	}
+
+	got, _ = c.getCertificate(nil)
+	if name := commonName(t, got); name != "new.example.com" {
+		t.Errorf("certificate after a failed reload, want: %s, got: %s", "new.example.com", name)
+	}
+}
+
+func Test_Server_serverTLSConfig_SharesCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCertificate(t, dir, "old.example.com")
+
+	s := NewServer(&types.FaaSConfig{TLSConfig: &types.TLSConfig{CertFile: certFile, KeyFile: keyFile}})
+
+	// The configs of the HTTP and gRPC servers
+	httpConfig, err := s.serverTLSConfig()
+	if err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+	grpcConfig, err := s.serverTLSConfig()
+	if err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+
+	writeTestCertificate(t, dir, "new.example.com")
+	if err := s.certs.reload(); err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+
+	for name, config := range map[string]*tls.Config{"HTTP": httpConfig, "gRPC": grpcConfig} {
+		got, _ := config.GetCertificate(nil)
+		if cn := commonName(t, got); cn != "new.example.com" {
+			t.Errorf("%s certificate after reload, want: %s, got: %s", name, "new.example.com", cn)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package bootstrap
+
+import (
+	"net/http"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/tracing"
+)
+
+// newTracingMiddleware records a server span for every request, named for the method and
+// the route which matched it, as a child of the traceparent sent by the caller when there
+// is one, keeping its tracestate. The span is carried by the request's context, so the proxy can propagate it to
+// the function and providers can add events to it with tracing.AddEvent.
+func newTracingMiddleware(tracer *tracing.Tracer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := httputil.RouteTemplate(r)
+			if len(route) == 0 {
+				route = r.URL.Path
+			}
+
+			var parent *tracing.SpanContext
+			if sc, ok := tracing.ParseTraceParent(r.Header.Get(tracing.TraceParentHeader)); ok {
+				sc.TraceState = r.Header.Get(tracing.TraceStateHeader)
+				parent = &sc
+			}
+
+			ctx, span := tracer.Start(r.Context(), r.Method+" "+route, tracing.SpanKindServer, parent)
+			defer span.End()
+
+			span.SetAttribute("http.request.method", r.Method)
+			span.SetAttribute("http.route", route)
+			if name := httputil.PathVar(r, "name"); len(name) > 0 {
+				span.SetAttribute("faas.function", name)
+			}
+
+			ww := httputil.NewHttpWriteInterceptor(w)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			status := ww.Status()
+			span.SetIntAttribute("http.response.status_code", int64(status))
+			if status >= http.StatusInternalServerError {
+				span.SetError(http.StatusText(status))
+			}
+		})
+	}
+}
+
+// decorateWithRestoreEvent records a tracing.EventCheckpointRestore on the span of requests
+// which restore a function from a checkpoint successfully.
+func decorateWithRestoreEvent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ww := httputil.NewHttpWriteInterceptor(w)
+		next(ww, r)
+
+		if status := ww.Status(); status >= 200 && status <= 299 {
+			tracing.AddEvent(r.Context(), tracing.EventCheckpointRestore, map[string]string{
+				"faas.function": httputil.PathVar(r, "name"),
+			})
+		}
+	}
+}
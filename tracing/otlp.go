@@ -0,0 +1,165 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The types below are the OTLP/HTTP JSON encoding of an ExportTraceServiceRequest, IDs are
+// hex encoded and 64-bit integers are strings.
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	TraceState        string          `json:"traceState,omitempty"`
+	Name              string          `json:"name"`
+	Kind              SpanKind        `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Events            []otlpEvent     `json:"events,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string          `json:"timeUnixNano"`
+	Name         string          `json:"name"`
+	Attributes   []otlpAttribute `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	// Code is 0 for unset and 2 for an error
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+// otlpValue is an AnyValue, with either StringValue or IntValue set.
+type otlpValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    string  `json:"intValue,omitempty"`
+}
+
+func toAttributes(attributes map[string]string) []otlpAttribute {
+	values := make(map[string]attributeValue, len(attributes))
+	for k, v := range attributes {
+		values[k] = attributeValue{str: v}
+	}
+	return toOTLPAttributes(values)
+}
+
+func toOTLPAttributes(attributes map[string]attributeValue) []otlpAttribute {
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make([]otlpAttribute, 0, len(keys))
+	for _, k := range keys {
+		v := attributes[k]
+		value := otlpValue{}
+		if v.isInt {
+			value.IntValue = strconv.FormatInt(v.integer, 10)
+		} else {
+			str := v.str
+			value.StringValue = &str
+		}
+		out = append(out, otlpAttribute{Key: k, Value: value})
+	}
+	return out
+}
+
+func toOTLP(span *Span) otlpSpan {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	s := otlpSpan{
+		TraceID:           hex.EncodeToString(span.Context.TraceID[:]),
+		SpanID:            hex.EncodeToString(span.Context.SpanID[:]),
+		Name:              span.Name,
+		Kind:              span.Kind,
+		StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+		TraceState:        span.Context.TraceState,
+		Attributes:        toOTLPAttributes(span.attributes),
+	}
+	if span.ParentID != [8]byte{} {
+		s.ParentSpanID = hex.EncodeToString(span.ParentID[:])
+	}
+	for _, e := range span.events {
+		s.Events = append(s.Events, otlpEvent{
+			TimeUnixNano: strconv.FormatInt(e.Time.UnixNano(), 10),
+			Name:         e.Name,
+			Attributes:   toAttributes(e.Attributes),
+		})
+	}
+	if len(span.err) > 0 {
+		s.Status = otlpStatus{Code: 2, Message: span.err}
+	}
+	return s
+}
+
+// send posts spans to the collector's "/v1/traces" endpoint.
+func (t *Tracer) send(spans []*Span) error {
+	scope := otlpScopeSpans{Scope: otlpScope{Name: "github.com/openfaas/faas-provider"}}
+	for _, span := range spans {
+		scope.Spans = append(scope.Spans, toOTLP(span))
+	}
+
+	body, err := json.Marshal(otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{Attributes: toAttributes(map[string]string{
+				"service.name": t.serviceName,
+			})},
+			ScopeSpans: []otlpScopeSpans{scope},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	res, err := t.client.Post(strings.TrimSuffix(t.endpoint, "/")+"/v1/traces", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+	return nil
+}
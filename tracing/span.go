@@ -0,0 +1,204 @@
+// Package tracing records spans for the requests served by the provider and exports them to
+// an OpenTelemetry collector over OTLP/HTTP, propagating the W3C Trace Context to functions
+// with the traceparent and tracestate headers.
+//
+// Providers add events to the span of a request with AddEvent, i.e. EventColdStart when an
+// instance had to be started to serve an invocation.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceParentHeader carries the W3C Trace Context of a request, it is set on requests
+// proxied to functions so that their spans join the trace.
+const TraceParentHeader = "traceparent"
+
+// TraceStateHeader carries the vendor specific part of the W3C Trace Context, it is passed
+// on to functions unchanged with the traceparent of the provider's span.
+const TraceStateHeader = "tracestate"
+
+const (
+	// EventColdStart is recorded when an instance of a function had to be started to serve
+	// the request.
+	EventColdStart = "faas.cold_start"
+
+	// EventCheckpointRestore is recorded when an instance of a function was restored from a
+	// checkpoint, rather than started from its image.
+	EventCheckpointRestore = "faas.checkpoint_restore"
+)
+
+// SpanKind describes the relationship of a span to its parent, as in OpenTelemetry.
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// SpanContext identifies a span within a trace.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+
+	// TraceState is the value of the tracestate header of the caller, if any
+	TraceState string
+}
+
+// ParseTraceParent parses the value of a traceparent header, it returns false when the
+// value is not a valid version 00 traceparent.
+func ParseTraceParent(value string) (SpanContext, bool) {
+	sc := SpanContext{}
+
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil || sc.TraceID == [16]byte{} {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil || sc.SpanID == [8]byte{} {
+		return sc, false
+	}
+
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, false
+	}
+	sc.Sampled = flags[0]&0x01 == 0x01
+
+	return sc, true
+}
+
+// TraceParent formats sc as the value of a traceparent header.
+func (sc SpanContext) TraceParent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-" + flags
+}
+
+// Event is a point in time within a span, such as a cold start.
+type Event struct {
+	Name       string
+	Time       time.Time
+	Attributes map[string]string
+}
+
+// Span is an operation within a trace, it is safe for concurrent use.
+type Span struct {
+	Name     string
+	Kind     SpanKind
+	Context  SpanContext
+	ParentID [8]byte
+
+	tracer *Tracer
+	start  time.Time
+
+	mu         sync.Mutex
+	end        time.Time
+	attributes map[string]attributeValue
+	events     []Event
+	err        string
+	ended      bool
+}
+
+// attributeValue is the value of an attribute of a span, a string or an integer.
+type attributeValue struct {
+	str     string
+	integer int64
+	isInt   bool
+}
+
+// SetAttribute sets a string attribute of the span.
+func (s *Span) SetAttribute(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attributes[key] = attributeValue{str: value}
+}
+
+// SetIntAttribute sets an integer attribute of the span, such as
+// "http.response.status_code".
+func (s *Span) SetIntAttribute(key string, value int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attributes[key] = attributeValue{integer: value, isInt: true}
+}
+
+// AddEvent records an event at the current time.
+func (s *Span) AddEvent(name string, attributes map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, Event{Name: name, Time: time.Now(), Attributes: attributes})
+}
+
+// SetError marks the span as failed with message.
+func (s *Span) SetError(message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.err = message
+}
+
+// End completes the span and queues it for export when it is sampled, further calls have
+// no effect.
+func (s *Span) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	if s.Context.Sampled && s.tracer != nil {
+		s.tracer.export(s)
+	}
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns a copy of ctx carrying span.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the span of ctx, or nil when the request is not traced.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// AddEvent records an event on the span of ctx, if there is one, so that providers can
+// record events such as EventColdStart without checking whether tracing is enabled.
+func AddEvent(ctx context.Context, name string, attributes map[string]string) {
+	if span := SpanFromContext(ctx); span != nil {
+		span.AddEvent(name, attributes)
+	}
+}
+
+func randomID(id []byte) {
+	for {
+		if _, err := rand.Read(id); err != nil {
+			panic(err)
+		}
+		for _, b := range id {
+			if b != 0 {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+)
+
+func Test_ParseTraceParent(t *testing.T) {
+	cases := []struct {
+		name        string
+		value       string
+		wantOK      bool
+		wantSampled bool
+	}{
+		{"sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"not sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		{"unknown version", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"zero trace ID", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"zero span ID", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, false},
+		{"short trace ID", "00-4bf92f3577b34da6-00f067aa0ba902b7-01", false, false},
+		{"not hex", "00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01", false, false},
+		{"empty", "", false, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sc, ok := ParseTraceParent(tc.value)
+			if ok != tc.wantOK {
+				t.Fatalf("ok, want: %v, got: %v", tc.wantOK, ok)
+			}
+			if !ok {
+				return
+			}
+			if sc.Sampled != tc.wantSampled {
+				t.Errorf("sampled, want: %v, got: %v", tc.wantSampled, sc.Sampled)
+			}
+			if got := sc.TraceParent(); got != tc.value {
+				t.Errorf("traceparent, want: %s, got: %s", tc.value, got)
+			}
+		})
+	}
+}
+
+func Test_AddEvent_WithoutSpan(t *testing.T) {
+	// Providers call AddEvent whether or not tracing is enabled
+	AddEvent(context.Background(), EventColdStart, nil)
+}
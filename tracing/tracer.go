@@ -0,0 +1,164 @@
+package tracing
+
+import (
+	"context"
+	"encoding/binary"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// maxQueuedSpans bounds the spans waiting to be exported, further spans are dropped
+	// while the collector is slow or unavailable.
+	maxQueuedSpans = 2048
+
+	// maxBatchSpans is the most spans sent to the collector in one request.
+	maxBatchSpans = 512
+
+	exportInterval = 5 * time.Second
+)
+
+// Tracer starts spans and exports the sampled ones to an OTLP/HTTP collector in batches.
+type Tracer struct {
+	endpoint    string
+	serviceName string
+	threshold   uint64
+	client      *http.Client
+
+	queue chan *Span
+	flush chan chan struct{}
+
+	once sync.Once
+	done chan struct{}
+}
+
+// NewTracer creates a tracer which exports spans to the collector at endpoint, such as
+// "http://otel-collector:4318", as serviceName. Traces started by the tracer are sampled
+// with the probability ratio, between 0 and 1, while requests which carry a traceparent
+// follow the sampling decision of their caller.
+func NewTracer(endpoint, serviceName string, ratio float64) *Tracer {
+	t := &Tracer{
+		endpoint:    endpoint,
+		serviceName: serviceName,
+		threshold:   ratioThreshold(ratio),
+		client:      &http.Client{Timeout: 10 * time.Second},
+		queue:       make(chan *Span, maxQueuedSpans),
+		flush:       make(chan chan struct{}),
+		done:        make(chan struct{}),
+	}
+
+	go t.run()
+
+	return t
+}
+
+// ratioThreshold converts a sampling ratio to the bound of the lower 63 bits of a trace ID
+// under which it is sampled, as OpenTelemetry's TraceIDRatioBased sampler does.
+func ratioThreshold(ratio float64) uint64 {
+	switch {
+	case ratio >= 1:
+		return 1 << 63
+	case ratio <= 0:
+		return 0
+	}
+	return uint64(ratio * (1 << 63))
+}
+
+// Start starts a span named name, a child of the span in ctx, or of parent when there is
+// none. A nil parent starts a new trace. The span must be ended with End.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind, parent *SpanContext) (context.Context, *Span) {
+	span := &Span{
+		Name:       name,
+		Kind:       kind,
+		tracer:     t,
+		start:      time.Now(),
+		attributes: map[string]attributeValue{},
+	}
+
+	if p := SpanFromContext(ctx); p != nil {
+		parent = &p.Context
+	}
+
+	if parent != nil {
+		span.Context.TraceID = parent.TraceID
+		span.Context.Sampled = parent.Sampled
+		span.Context.TraceState = parent.TraceState
+		span.ParentID = parent.SpanID
+	} else {
+		randomID(span.Context.TraceID[:])
+		span.Context.Sampled = binary.BigEndian.Uint64(span.Context.TraceID[8:])>>1 < t.threshold
+	}
+	randomID(span.Context.SpanID[:])
+
+	return ContextWithSpan(ctx, span), span
+}
+
+// Shutdown exports the spans which have ended and stops the tracer, waiting until ctx is
+// done at most.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	flushed := make(chan struct{})
+	select {
+	case t.flush <- flushed:
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	t.once.Do(func() { close(t.done) })
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// export queues span, it is dropped when the queue is full.
+func (t *Tracer) export(span *Span) {
+	select {
+	case t.queue <- span:
+	default:
+	}
+}
+
+func (t *Tracer) run() {
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, maxBatchSpans)
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := t.send(batch); err != nil {
+			slog.Warn("Unable to export spans", "spans", len(batch), "error", err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case span := <-t.queue:
+			batch = append(batch, span)
+			if len(batch) == maxBatchSpans {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case flushed := <-t.flush:
+			for len(t.queue) > 0 {
+				batch = append(batch, <-t.queue)
+				if len(batch) == maxBatchSpans {
+					send()
+				}
+			}
+			send()
+			close(flushed)
+			return
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type collector struct {
+	mu    sync.Mutex
+	spans []otlpSpan
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/traces" {
+		http.NotFound(w, r)
+		return
+	}
+
+	req := otlpRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			c.spans = append(c.spans, ss.Spans...)
+		}
+	}
+}
+
+func Test_Tracer_ExportsSampledSpans(t *testing.T) {
+	c := &collector{}
+	s := httptest.NewServer(c)
+	defer s.Close()
+
+	tracer := NewTracer(s.URL, "faas-provider", 0)
+
+	// A new trace is not sampled with a ratio of 0
+	_, dropped := tracer.Start(context.Background(), "GET /system/functions", SpanKindServer, nil)
+	dropped.End()
+
+	parent, _ := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	parent.TraceState = "vendor=value"
+	ctx, span := tracer.Start(context.Background(), "POST /function/{name}", SpanKindServer, &parent)
+	AddEvent(ctx, EventColdStart, map[string]string{"faas.function": "figlet"})
+	span.SetAttribute("http.route", "/function/{name}")
+	span.SetIntAttribute("http.response.status_code", 502)
+	span.SetError("Bad Gateway")
+	span.End()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tracer.Shutdown(ctx); err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.spans) != 1 {
+		t.Fatalf("spans, want: %d, got: %d", 1, len(c.spans))
+	}
+
+	got := c.spans[0]
+	if got.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace ID, want: %s, got: %s", "4bf92f3577b34da6a3ce929d0e0e4736", got.TraceID)
+	}
+	if got.ParentSpanID != "00f067aa0ba902b7" {
+		t.Errorf("parent span ID, want: %s, got: %s", "00f067aa0ba902b7", got.ParentSpanID)
+	}
+	if len(got.Events) != 1 || got.Events[0].Name != EventColdStart {
+		t.Errorf("events, want: %s, got: %v", EventColdStart, got.Events)
+	}
+	if got.Status.Code != 2 {
+		t.Errorf("status code, want: %d, got: %d", 2, got.Status.Code)
+	}
+	if got.TraceState != "vendor=value" {
+		t.Errorf("trace state, want: %s, got: %s", "vendor=value", got.TraceState)
+	}
+
+	attributes := map[string]otlpValue{}
+	for _, a := range got.Attributes {
+		attributes[a.Key] = a.Value
+	}
+	if v := attributes["http.response.status_code"]; v.IntValue != "502" || v.StringValue != nil {
+		t.Errorf("http.response.status_code, want: intValue 502, got: %+v", v)
+	}
+	if v := attributes["http.route"]; v.StringValue == nil || *v.StringValue != "/function/{name}" {
+		t.Errorf("http.route, want: stringValue %s, got: %+v", "/function/{name}", v)
+	}
+}
+
+func Test_ratioThreshold(t *testing.T) {
+	cases := []struct {
+		ratio float64
+		want  uint64
+	}{
+		{0, 0},
+		{0.5, 1 << 62},
+		{1, 1 << 63},
+		{2, 1 << 63},
+	}
+
+	for _, tc := range cases {
+		if got := ratioThreshold(tc.ratio); got != tc.want {
+			t.Errorf("ratio %g, want: %d, got: %d", tc.ratio, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package bootstrap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/openfaas/faas-provider/tracing"
+)
+
+func Test_newTracingMiddleware(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer collector.Close()
+
+	tracer := tracing.NewTracer(collector.URL, "faas-provider", 1)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		tracer.Shutdown(ctx)
+	}()
+
+	var got *tracing.Span
+	r := mux.NewRouter()
+	r.Use(newTracingMiddleware(tracer))
+	r.HandleFunc("/function/{name}", func(w http.ResponseWriter, r *http.Request) {
+		got = tracing.SpanFromContext(r.Context())
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/function/figlet", nil)
+	req.Header.Set(tracing.TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set(tracing.TraceStateHeader, "vendor=value")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if got == nil {
+		t.Fatalf("want a span in the request's context")
+	}
+	if got.Name != "POST /function/{name}" {
+		t.Errorf("name, want: %s, got: %s", "POST /function/{name}", got.Name)
+	}
+
+	parent, _ := tracing.ParseTraceParent(req.Header.Get(tracing.TraceParentHeader))
+	if got.Context.TraceID != parent.TraceID {
+		t.Errorf("want the span to join the caller's trace")
+	}
+	if got.ParentID != parent.SpanID {
+		t.Errorf("want the span to be a child of the caller's span")
+	}
+	if got.Context.TraceState != "vendor=value" {
+		t.Errorf("trace state, want: %s, got: %s", "vendor=value", got.Context.TraceState)
+	}
+}
@@ -0,0 +1,48 @@
+package types
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// FunctionTimeoutAnnotation is the annotation used by a function to declare how long its
+// invocations may run for, in the same format as FunctionTimeoutLabel. It takes precedence
+// over the label, so that the timeout can be set without changing the function's labels.
+const FunctionTimeoutAnnotation = "com.openfaas.timeout"
+
+// FunctionTimeoutFromAnnotations returns the timeout declared by the FunctionTimeoutAnnotation
+// of a function, see FunctionTimeoutFromLabels.
+func FunctionTimeoutFromAnnotations(annotations map[string]string) (time.Duration, bool, error) {
+	value, ok := annotations[FunctionTimeoutAnnotation]
+	if !ok {
+		return 0, false, nil
+	}
+
+	return parseFunctionTimeout(FunctionTimeoutAnnotation, value)
+}
+
+// ResponseHeaderAnnotationPrefix is the prefix of annotations which declare a header to be
+// set on every response proxied from the function, i.e.
+// "com.openfaas.response.header.cache-control": "max-age=60".
+const ResponseHeaderAnnotationPrefix = "com.openfaas.response.header."
+
+// ResponseHeadersFromAnnotations returns the response headers declared by the annotations
+// of a function, keyed by their canonical name. Annotations without a header name are ignored.
+func ResponseHeadersFromAnnotations(annotations map[string]string) http.Header {
+	headers := http.Header{}
+	for k, v := range annotations {
+		if !strings.HasPrefix(k, ResponseHeaderAnnotationPrefix) {
+			continue
+		}
+
+		name := strings.TrimSpace(strings.TrimPrefix(k, ResponseHeaderAnnotationPrefix))
+		if len(name) == 0 {
+			continue
+		}
+
+		headers.Set(name, v)
+	}
+
+	return headers
+}
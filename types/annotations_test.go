@@ -0,0 +1,66 @@
+package types
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestResponseHeadersFromAnnotations(t *testing.T) {
+	annotations := map[string]string{
+		"com.openfaas.response.header.cache-control":               "max-age=60",
+		"com.openfaas.response.header.Access-Control-Allow-Origin": "*",
+		"com.openfaas.response.header.":                            "ignored",
+		"com.openfaas.scale.min":                                   "1",
+		"topic":                                                    "payments",
+	}
+
+	want := http.Header{
+		"Cache-Control":               []string{"max-age=60"},
+		"Access-Control-Allow-Origin": []string{"*"},
+	}
+
+	got := ResponseHeadersFromAnnotations(annotations)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("headers, want: %v, got: %v", want, got)
+	}
+
+	if got := ResponseHeadersFromAnnotations(nil); len(got) != 0 {
+		t.Errorf("want no headers for nil annotations, got: %v", got)
+	}
+}
+
+func TestFunctionTimeoutFromAnnotations(t *testing.T) {
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		want        time.Duration
+		wantOK      bool
+		wantErr     bool
+	}{
+		{name: "no annotations"},
+		{name: "label key is ignored", annotations: map[string]string{FunctionTimeoutLabel: "30s"}},
+		{name: "duration", annotations: map[string]string{FunctionTimeoutAnnotation: "1m"}, want: time.Minute, wantOK: true},
+		{name: "seconds", annotations: map[string]string{FunctionTimeoutAnnotation: " 45 "}, want: 45 * time.Second, wantOK: true},
+		{name: "invalid", annotations: map[string]string{FunctionTimeoutAnnotation: "soon"}, wantErr: true},
+		{name: "zero", annotations: map[string]string{FunctionTimeoutAnnotation: "0"}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok, err := FunctionTimeoutFromAnnotations(tc.annotations)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error, want: %t, got: %v", tc.wantErr, err)
+			}
+
+			if ok != tc.wantOK {
+				t.Errorf("ok, want: %t, got: %t", tc.wantOK, ok)
+			}
+
+			if got != tc.want {
+				t.Errorf("timeout, want: %s, got: %s", tc.want, got)
+			}
+		})
+	}
+}
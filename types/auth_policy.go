@@ -0,0 +1,75 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+)
+
+// AuthPolicy is whether a route of the API requires credentials.
+type AuthPolicy string
+
+const (
+	// AuthRequired rejects requests without valid credentials with a 401.
+	AuthRequired AuthPolicy = "required"
+	// AuthOptional serves requests without credentials, but rejects requests with
+	// credentials which are not valid with a 401.
+	AuthOptional AuthPolicy = "optional"
+	// AuthNone serves every request without checking credentials.
+	AuthNone AuthPolicy = "none"
+)
+
+// The routes which are not authenticated unless set to AuthRequired or AuthOptional in
+// FaaSConfig.AuthPolicies. Every other route of the system API is always authenticated
+// when basic auth or an Authenticator is configured.
+const (
+	// InvokeRoute also sets the policy of "/invoke-batch" and "/async-function"
+	InvokeRoute      = "/invoke"
+	CheckpointsRoute = "/system/checkpoints"
+	KillRoute        = "/danger/kill"
+	MetricsRoute     = "/system/metrics"
+)
+
+var authPolicyRoutes = map[string]bool{
+	InvokeRoute:      true,
+	CheckpointsRoute: true,
+	KillRoute:        true,
+	MetricsRoute:     true,
+}
+
+// GetAuthPolicy is a helper to safely return the policy of route in AuthPolicies or
+// AuthNone when it is not set.
+func (c *FaaSConfig) GetAuthPolicy(route string) AuthPolicy {
+	if policy, ok := c.AuthPolicies[route]; ok && len(policy) > 0 {
+		return policy
+	}
+	return AuthNone
+}
+
+// validateAuthPolicies checks that AuthPolicies only names known routes and policies, and
+// that credentials are configured when a route requires them.
+func (c *FaaSConfig) validateAuthPolicies() error {
+	routes := make([]string, 0, len(c.AuthPolicies))
+	for route := range c.AuthPolicies {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	for _, route := range routes {
+		if !authPolicyRoutes[route] {
+			return fmt.Errorf("invalid AuthPolicies: unknown route %q, must be one of %s, %s, %s or %s",
+				route, InvokeRoute, CheckpointsRoute, KillRoute, MetricsRoute)
+		}
+
+		switch policy := c.AuthPolicies[route]; policy {
+		case AuthNone:
+		case AuthRequired, AuthOptional:
+			if c.Authenticator == nil && !c.EnableBasicAuth {
+				return fmt.Errorf("invalid AuthPolicies: %s is %s but neither EnableBasicAuth nor Authenticator is set", route, policy)
+			}
+		default:
+			return fmt.Errorf("invalid AuthPolicies: %s has unknown policy %q, must be required, optional or none", route, policy)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,24 @@
+package types
+
+// BatchResult is the outcome of one item of a batch sent to "/invoke-batch/{name}". The
+// results are written as lines of JSON in the order of the items in the batch.
+type BatchResult struct {
+	// Index of the item in the batch, starting at 0
+	Index int `json:"index"`
+
+	// Status is the status code returned by the function, or 0 when the item was not
+	// invoked, such as when the batch request was cancelled.
+	Status int `json:"status"`
+
+	// Latency of the invocation in seconds
+	Latency float64 `json:"latency"`
+
+	// ContentType of the body returned by the function
+	ContentType string `json:"contentType,omitempty"`
+
+	// Body returned by the function, encoded as base64 in JSON
+	Body []byte `json:"body,omitempty"`
+
+	// Error describes why the item was not invoked
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,56 @@
+package types
+
+// Capabilities reports which of the optional handlers a provider implements, as returned
+// by "/system/capabilities", so that clients can tell a feature is unsupported without
+// probing its route.
+type Capabilities struct {
+	Invoke            bool `json:"invoke"`
+	Async             bool `json:"async"`
+	WatchFunctions    bool `json:"watch_functions"`
+	PatchFunction     bool `json:"patch_function"`
+	FunctionInstances bool `json:"function_instances"`
+	FunctionSpec      bool `json:"function_spec"`
+	MutateNamespace   bool `json:"mutate_namespace"`
+	ProxyState        bool `json:"proxy_state"`
+	Checkpoints       bool `json:"checkpoints"`
+	CreateCheckpoint  bool `json:"create_checkpoint"`
+	RestoreCheckpoint bool `json:"restore_checkpoint"`
+	CheckpointStatus  bool `json:"checkpoint_status"`
+	DeleteCheckpoint  bool `json:"delete_checkpoint"`
+	WarmPool          bool `json:"warm_pool"`
+	Register          bool `json:"register"`
+	Metrics           bool `json:"metrics"`
+	KillInstances     bool `json:"kill_instances"`
+	CordonFunction    bool `json:"cordon_function"`
+	DrainFunction     bool `json:"drain_function"`
+	Health            bool `json:"health"`
+	Ready             bool `json:"ready"`
+}
+
+// CapabilitiesFromHandlers reports which optional handlers are set in h, it must be
+// called before the handlers are decorated.
+func CapabilitiesFromHandlers(h *FaaSHandlers) Capabilities {
+	return Capabilities{
+		Invoke:            h.InvokeFunction != nil,
+		Async:             h.AsyncFunction != nil,
+		WatchFunctions:    h.WatchFunctions != nil,
+		PatchFunction:     h.ReadDeployment != nil,
+		FunctionInstances: h.FunctionInstances != nil,
+		FunctionSpec:      h.FunctionSpec != nil,
+		MutateNamespace:   h.MutateNamespace != nil,
+		ProxyState:        h.ProxyState != nil,
+		Checkpoints:       h.ListCheckpoint != nil,
+		CreateCheckpoint:  h.CreateCheckpoint != nil,
+		RestoreCheckpoint: h.RestoreCheckpoint != nil,
+		CheckpointStatus:  h.CheckpointStatus != nil,
+		DeleteCheckpoint:  h.DeleteCheckpoint != nil,
+		WarmPool:          h.WarmPool != nil,
+		Register:          h.RegisterFunction != nil,
+		Metrics:           h.MetricFunction != nil,
+		KillInstances:     h.KillAllInstance != nil,
+		CordonFunction:    h.CordonFunction != nil,
+		DrainFunction:     h.DrainFunction != nil,
+		Health:            h.Health != nil,
+		Ready:             h.Ready != nil,
+	}
+}
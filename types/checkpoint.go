@@ -0,0 +1,101 @@
+package types
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Checkpoint describes a checkpoint of a function instance which can be
+// restored to avoid a cold start.
+type Checkpoint struct {
+	// Function is the name of the function the checkpoint was taken from
+	Function string `json:"function"`
+
+	// Namespace of the function, if supported by the faas-provider
+	Namespace string `json:"namespace,omitempty"`
+
+	// ID uniquely identifies the checkpoint within the provider
+	ID string `json:"id"`
+
+	// CreatedAt is the time the checkpoint was taken
+	CreatedAt time.Time `json:"createdAt"`
+
+	// SizeBytes is the size of the checkpoint image on disk
+	SizeBytes int64 `json:"sizeBytes"`
+
+	// Image is a reference to the checkpoint image, such as a path or an OCI image
+	// reference, if the faas-provider stores it outside of its own state
+	Image string `json:"image,omitempty"`
+
+	// Memory describes the memory of the instance when the checkpoint was taken, if
+	// reported by the faas-provider
+	Memory *CheckpointMemory `json:"memory,omitempty"`
+}
+
+// CheckpointMemory describes the memory captured by a checkpoint, the dirty pages are
+// those written since the previous checkpoint of an incremental chain.
+type CheckpointMemory struct {
+	// TotalBytes is the size of the memory of the instance
+	TotalBytes int64 `json:"totalBytes"`
+
+	// DirtyBytes is the size of the dirty pages
+	DirtyBytes int64 `json:"dirtyBytes"`
+
+	// DirtyPages is the number of dirty pages
+	DirtyPages int64 `json:"dirtyPages"`
+}
+
+// WriteCheckpoints writes checkpoints as a JSON array with a 200 status code,
+// an empty or nil list is written as [] so that clients can always decode
+// the body as a list.
+func WriteCheckpoints(w http.ResponseWriter, checkpoints []Checkpoint) error {
+	if checkpoints == nil {
+		checkpoints = []Checkpoint{}
+	}
+
+	body, err := json.Marshal(checkpoints)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(body)
+	return err
+}
+
+// CreateCheckpointRequest is posted to /system/function/{name}/checkpoint to take
+// a checkpoint of a running instance of the function. The response is the
+// created Checkpoint.
+type CreateCheckpointRequest struct {
+	// Namespace of the function, if supported by the faas-provider
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// RestoreCheckpointRequest is posted to /system/function/{name}/restore to start
+// an instance of the function from an existing checkpoint.
+type RestoreCheckpointRequest struct {
+	// Namespace of the function, if supported by the faas-provider
+	Namespace string `json:"namespace,omitempty"`
+
+	// CheckpointID is the ID of the checkpoint to restore from, as returned
+	// by the checkpoint list or create endpoints
+	CheckpointID string `json:"checkpointId"`
+}
+
+// RestoreCheckpointResponse is returned once an instance has been restored
+// from a checkpoint.
+type RestoreCheckpointResponse struct {
+	// Function is the name of the restored function
+	Function string `json:"function"`
+
+	// Namespace of the function, if supported by the faas-provider
+	Namespace string `json:"namespace,omitempty"`
+
+	// CheckpointID is the ID of the checkpoint which was restored
+	CheckpointID string `json:"checkpointId"`
+
+	// RestoredAt is the time the instance became available
+	RestoredAt time.Time `json:"restoredAt"`
+}
@@ -0,0 +1,71 @@
+package types
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// defaultCheckpointGCInterval is how often checkpoints are collected when
+// CheckpointRetention.Interval is not set.
+const defaultCheckpointGCInterval = 10 * time.Minute
+
+// CheckpointStore lists and deletes the checkpoints kept by a provider, so that old
+// checkpoints can be collected according to FaaSConfig.CheckpointRetention.
+type CheckpointStore interface {
+	// ListCheckpoints returns every checkpoint, with its CreatedAt and SizeBytes set.
+	ListCheckpoints(ctx context.Context) ([]Checkpoint, error)
+	// DeleteCheckpoint deletes the checkpoint and frees its image.
+	DeleteCheckpoint(ctx context.Context, checkpoint Checkpoint) error
+}
+
+// CheckpointRetention is the policy checkpoints are collected by, a checkpoint is deleted
+// when any of its limits is exceeded. A limit of 0 is not applied.
+type CheckpointRetention struct {
+	// MaxPerFunction is the number of checkpoints kept for each function, the oldest are
+	// deleted first.
+	MaxPerFunction int
+	// MaxTotalBytes caps the SizeBytes of every checkpoint added up, the oldest are
+	// deleted first.
+	MaxTotalBytes int64
+	// TTL is how long a checkpoint is kept for after its CreatedAt.
+	TTL time.Duration
+	// Interval is how often checkpoints are collected in the background, the default is
+	// 10 minutes. A collection can also be started with a POST to "/system/checkpoints/gc".
+	Interval time.Duration
+}
+
+// Validate checks that the limits of the policy are not negative.
+func (r *CheckpointRetention) Validate() error {
+	if r.MaxPerFunction < 0 {
+		return fmt.Errorf("invalid CheckpointRetention MaxPerFunction %d: must not be negative", r.MaxPerFunction)
+	}
+	if r.MaxTotalBytes < 0 {
+		return fmt.Errorf("invalid CheckpointRetention MaxTotalBytes %d: must not be negative", r.MaxTotalBytes)
+	}
+	if r.TTL < 0 {
+		return fmt.Errorf("invalid CheckpointRetention TTL %s: must not be negative", r.TTL)
+	}
+	if r.Interval < 0 {
+		return fmt.Errorf("invalid CheckpointRetention Interval %s: must not be negative", r.Interval)
+	}
+	return nil
+}
+
+// GetInterval returns the configured Interval or the default of 10 minutes.
+func (r *CheckpointRetention) GetInterval() time.Duration {
+	if r.Interval <= 0 {
+		return defaultCheckpointGCInterval
+	}
+	return r.Interval
+}
+
+// CheckpointGCResult is returned by a POST to "/system/checkpoints/gc".
+type CheckpointGCResult struct {
+	// Deleted are the checkpoints which were deleted.
+	Deleted []Checkpoint `json:"deleted"`
+	// FreedBytes is the SizeBytes of the deleted checkpoints added up.
+	FreedBytes int64 `json:"freedBytes"`
+	// Failed is the number of checkpoints which were due to be deleted, but could not be.
+	Failed int `json:"failed,omitempty"`
+}
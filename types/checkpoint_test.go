@@ -0,0 +1,60 @@
+package types
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_WriteCheckpoints(t *testing.T) {
+	createdAt := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name        string
+		checkpoints []Checkpoint
+		want        string
+	}{
+		{
+			name:        "nil list",
+			checkpoints: nil,
+			want:        `[]`,
+		},
+		{
+			name: "single checkpoint",
+			checkpoints: []Checkpoint{
+				{Function: "figlet", Namespace: "openfaas-fn", ID: "figlet-1", CreatedAt: createdAt, SizeBytes: 1024},
+			},
+			want: `[{"function":"figlet","namespace":"openfaas-fn","id":"figlet-1","createdAt":"2023-06-01T12:00:00Z","sizeBytes":1024}]`,
+		},
+		{
+			name: "checkpoint with image and memory",
+			checkpoints: []Checkpoint{
+				{Function: "figlet", ID: "figlet-2", CreatedAt: createdAt, SizeBytes: 2048, Image: "registry.example.com/checkpoints/figlet:2",
+					Memory: &CheckpointMemory{TotalBytes: 4096, DirtyBytes: 1024, DirtyPages: 1}},
+			},
+			want: `[{"function":"figlet","id":"figlet-2","createdAt":"2023-06-01T12:00:00Z","sizeBytes":2048,"image":"registry.example.com/checkpoints/figlet:2","memory":{"totalBytes":4096,"dirtyBytes":1024,"dirtyPages":1}}]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			if err := WriteCheckpoints(w, tc.checkpoints); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if w.Code != http.StatusOK {
+				t.Errorf("status code, want: %d, got: %d", http.StatusOK, w.Code)
+			}
+
+			if got := w.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type, want: application/json, got: %s", got)
+			}
+
+			if got := w.Body.String(); got != tc.want {
+				t.Errorf("body, want: %s, got: %s", tc.want, got)
+			}
+		})
+	}
+}
@@ -1,13 +1,66 @@
 package types
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"strings"
 	"time"
+
+	"github.com/openfaas/faas-provider/audit"
+	"github.com/openfaas/faas-provider/auth"
+	"github.com/openfaas/faas-provider/cache"
+	"github.com/openfaas/faas-provider/events"
+	"github.com/openfaas/faas-provider/health"
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/scaling"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultTCPPort            = 8080
+	defaultReadTimeout        = 10 * time.Second
+	defaultShutdownTimeout    = 10 * time.Second
+	defaultIdleTimeout        = 120 * time.Second
+	defaultMaxIdleConns       = 1024
+	defaultMaxFunctionMetrics = 1000
+	defaultTracingServiceName = "faas-provider"
+	defaultUnixSocketMode     = 0660
+	defaultIdempotencyTTL     = 24 * time.Hour
+	defaultFunctionCacheTTL   = 5 * time.Second
+	defaultBatchParallelism   = 10
 )
 
 const (
-	defaultReadTimeout  = 10 * time.Second
-	defaultMaxIdleConns = 1024
+	// LoadBalancingRoundRobin sends requests to each instance of a function in turn.
+	LoadBalancingRoundRobin = "round-robin"
+
+	// LoadBalancingLeastConnections sends requests to the instance of a function with the
+	// fewest requests in flight.
+	LoadBalancingLeastConnections = "least-connections"
+
+	// LoadBalancingConsistentHash sends the requests with the same value of the
+	// FaaSConfig.ProxyHashHeader to the same instance of a function, and the requests
+	// without it round-robin.
+	LoadBalancingConsistentHash = "consistent-hash"
+)
+
+// UnixSocketScheme is the scheme of a FaaSConfig.ListenAddress which is a Unix domain socket,
+// i.e. "unix:///var/run/provider.sock".
+const UnixSocketScheme = "unix://"
+
+const (
+	// AccessLogFormatJSON writes each request to the access log as a JSON object.
+	AccessLogFormatJSON = "json"
+
+	// AccessLogFormatCLF writes each request to the access log in the Common Log Format,
+	// `%h %l %u %t "%r" %>s %b`.
+	AccessLogFormatCLF = "clf"
 )
 
 // FaaSHandlers provide handlers for OpenFaaS
@@ -23,21 +76,74 @@ type FaaSHandlers struct {
 	// use the standard OpenFaaS proxy implementation or provide completely custom proxy logic.
 	FunctionProxy http.HandlerFunc
 
-	// FunctionLister lists deployed functions within a namespace
+	// ProxyState is bound to GET and DELETE "/system/proxy/state" to inspect and reset the
+	// internal state of the function proxy, use StateHandler of a proxy.Handler.
+	// If the handler is not set, then the route will not be configured
+	ProxyState http.HandlerFunc
+
+	// FunctionLister lists deployed functions within a namespace. When
+	// httputil.AllNamespacesRequested is true, it lists the functions in every namespace
+	// with the Namespace of each set, which are then filtered by AllowedNamespaces.
 	FunctionLister http.HandlerFunc
 
+	// WatchFunctions is bound to GET "/system/functions/watch" and streams a FunctionEvent
+	// as a Server-Sent Event, see httputil.NewEventStream, whenever a function is added,
+	// changed or removed. If the handler is not set, then the route streams a WatchEvent for
+	// each function event published on FaaSConfig.Events, or returns 501 when Events is not
+	// set. The stream is closed by the server after FaaSConfig.WriteTimeout. A "namespace"
+	// outside of FaaSConfig.AllowedNamespaces is rejected with a 403 before the handler is
+	// called, a handler set by the provider must leave out the events of other namespaces
+	// itself.
+	WatchFunctions http.HandlerFunc
+
 	// DeployFunction deploys a function which doesn't exist
+	//
+	// When the client sends "Prefer: respond-async", see httputil.PrefersAsync, a provider
+	// whose deploys can take minutes may return as soon as the deployment is accepted, with
+	// httputil.WriteAccepted and the httputil.FunctionStatusLocation of the function. The
+	// client then polls the FunctionStatus until it is ready. Providers which only deploy
+	// synchronously ignore the preference.
 	DeployFunction http.HandlerFunc
 
 	// UpdateFunction updates an existing function
 	UpdateFunction http.HandlerFunc
 
+	// ReadDeployment returns the current deployment of a function, or an error wrapping
+	// ErrFunctionNotFound. When it is set, PATCH "/system/functions" updates a function with
+	// a JSON merge patch, so that tools which change different fields, such as labels and
+	// annotations, do not overwrite each other: the patch is applied to the deployment, which
+	// is then passed to UpdateFunction with the same validation, admission, quotas and
+	// DeploymentInterceptors as a PUT. Otherwise the route will not be configured.
+	ReadDeployment func(ctx context.Context, name, namespace string) (FunctionDeployment, error)
+
+	// DeploymentInterceptors are called in order with the FunctionDeployment of each POST
+	// and PUT on "/system/functions", before it is validated, admitted with
+	// FaaSConfig.AdmitDeploy and passed to DeployFunction or UpdateFunction. Each may change
+	// the deployment or reject it, see DeploymentInterceptor.
+	DeploymentInterceptors []DeploymentInterceptor
+
 	DeleteFunction http.HandlerFunc
 
+	// FunctionStatus is bound to GET "/system/function/{name}" and returns a FunctionStatus.
+	// When httputil.EnvRequested is true, its Env should be set with RedactEnv.
 	FunctionStatus http.HandlerFunc
 
+	// ScaleFunction is bound to POST "/system/scale-function/{name}", read the request with
+	// DecodeScaleRequest. FaaSConfig.ScaleInSteps can be used to apply a large scale up
+	// gradually, in which case the handler may respond with a 202 before it completes.
 	ScaleFunction http.HandlerFunc
 
+	// FunctionInstances is bound to GET "/system/function/{name}/instances" and returns
+	// a list of FunctionInstance for each running instance of the function.
+	// If the handler is not set, then the route will not be configured
+	FunctionInstances http.HandlerFunc
+
+	// FunctionSpec is bound to GET "/system/function/{name}/spec" and returns the
+	// FunctionDeployment of the function as it was deployed, or a best-effort reconstruction
+	// when the provider does not keep the original.
+	// If the handler is not set, then the route will not be configured
+	FunctionSpec http.HandlerFunc
+
 	Secrets http.HandlerFunc
 
 	// Logs provides streaming json logs of functions
@@ -45,29 +151,171 @@ type FaaSHandlers struct {
 
 	// Health defines the default health endpoint bound to "/healthz
 	// If the handler is not set, then the "/healthz" path will not be configured
+	//
+	// It is used as a liveness check, and should only fail when the process can not recover
+	// without being restarted, not when a dependency such as the backend is unavailable.
 	Health http.HandlerFunc
 
+	// Ready is bound to "/readyz" and is used as a readiness check, it should fail while
+	// the provider can not serve traffic yet, i.e. until its function cache is warm.
+	// Both "/healthz" and "/readyz" return 503 until FaaSConfig.StartupChecks pass and
+	// once shutdown begins. If the handler is not set, "/readyz" returns 200.
+	Ready http.HandlerFunc
+
 	Info http.HandlerFunc
 
+	// ListCheckpoint is bound to "/system/checkpoints" and lists the available checkpoints,
+	// use WriteCheckpoints to return them in the standard shape.
 	ListCheckpoint http.HandlerFunc
 
+	// RegisterFunction is bound to "/system/register" and receives a RegisterRequest
+	// from a function instance registering itself with the provider.
 	RegisterFunction http.HandlerFunc
 
+	// CreateCheckpoint is bound to POST "/system/function/{name}/checkpoint" and takes a
+	// checkpoint of the function described by a CreateCheckpointRequest.
+	// If the handler is not set, then the route will not be configured
+	CreateCheckpoint http.HandlerFunc
+
+	// RestoreCheckpoint is bound to POST "/system/function/{name}/restore" and restores an
+	// instance of the function from the checkpoint in a RestoreCheckpointRequest.
+	// If the handler is not set, then the route will not be configured
+	RestoreCheckpoint http.HandlerFunc
+
+	// CheckpointStatus is bound to GET "/system/checkpoint/{id}" and returns the Checkpoint,
+	// see WriteJSON, or a 404 when it does not exist. The namespace may be given in the
+	// "namespace" query string parameter.
+	// If the handler is not set, then the route will not be configured
+	CheckpointStatus http.HandlerFunc
+
+	// DeleteCheckpoint is bound to DELETE "/system/checkpoint/{id}" and deletes the
+	// checkpoint, responding with a 204, or a 404 when it does not exist. The namespace
+	// may be given in the "namespace" query string parameter. It is rejected in read-only
+	// mode, and both checkpoint routes are limited to the AllowedNamespaces.
+	// If the handler is not set, then the route will not be configured
+	DeleteCheckpoint http.HandlerFunc
+
+	// WarmPool is bound to GET and POST "/system/warm-pool/{name}". A POST sets the
+	// WarmPoolPolicy of the function, see DecodeWarmPoolPolicy, and both respond with its
+	// WarmPoolStatus. Providers should call bootstrap.RecordWarmPoolHit when an invocation
+	// is served by a warm instance or has to start one.
+	// If the handler is not set, then the route will not be configured
+	WarmPool http.HandlerFunc
+
+	// InvokeFunction is bound to "/invoke/{name}" and invokes the function like the
+	// FunctionProxy. When the request carries httputil.InstanceHeader, it should be served by
+	// that instance alone, or answered with a 404 when the function has no such instance.
 	InvokeFunction http.HandlerFunc
 
+	// BatchInvoke is bound to POST "/invoke-batch/{name}" and invokes the function once
+	// for each item of a batch, responding with a BatchResult for each item as lines of
+	// JSON, in the order of the batch. If the handler is not set, then each item is sent
+	// to InvokeFunction, or to the FunctionProxy when it is not set, at most
+	// FaaSConfig.BatchParallelism at a time. The batch is either "application/x-ndjson",
+	// where each line is the JSON body of one item, or "multipart/form-data" or
+	// "multipart/mixed", where each part is one item with its own Content-Type.
+	BatchInvoke http.HandlerFunc
+
+	// AsyncFunction is bound to "/async-function/{name}" and queues invocations to be
+	// completed in the background, see queue.NewHandlerFunc, which publishes each request to
+	// a RequestQueuer. If the handler is not set, then the route will not be configured.
+	AsyncFunction http.HandlerFunc
+
+	// MetricFunction is bound to "/system/metrics". GET returns the provider's function
+	// metrics as a list of InstanceMetrics, DELETE resets the invocation counters of the
+	// function given in the "function" and "namespace" query string parameters, or of every
+	// function when they are omitted, and responds with a MetricResetResult. Both are scoped
+	// to one instance when the "instance" query string parameter is given, see
+	// httputil.InstanceFromQuery. Every reset is written to the audit log.
 	MetricFunction http.HandlerFunc
 
+	// KillAllInstance is bound to "/danger/kill" and kills function instances. Read the
+	// request with DecodeKillRequest and only kill the instances it selects, then respond
+	// with a KillResponse. The "namespace" query string parameter can also be read with
+	// httputil.NamespaceFromRequest.
+	//
+	// Without a namespace every instance managed by the provider is killed.
+	// Only POST is accepted, and FaaSConfig.KillConfirmationToken is required.
 	KillAllInstance http.HandlerFunc
+
+	// CordonFunction is bound to POST "/system/cordon/{name}" and stops routing invocations
+	// to the instances selected by a CordonRequest, see DecodeCordonRequest, or routes them
+	// again when it uncordons them. It responds with a DrainStatus. The namespace may be
+	// given in the "namespace" query string parameter.
+	// If the handler is not set, then the route will not be configured
+	CordonFunction http.HandlerFunc
+
+	// DrainFunction is bound to POST "/system/drain/{name}" and evicts the instances
+	// selected by a DrainRequest, see DecodeDrainRequest: they are cordoned, their in-flight
+	// requests are waited for, they are checkpointed when asked to, then terminated. It
+	// responds with a 202 and a DrainStatus once the drain has started, and publishes each
+	// later stage with bootstrap.PublishDrainProgress. The namespace may be given in the
+	// "namespace" query string parameter.
+	// If the handler is not set, then the route will not be configured
+	DrainFunction http.HandlerFunc
+}
+
+// Validate checks that the handlers bound to a route of the API whether or not they are set
+// are not nil, so that a missing handler is reported when the server starts rather than
+// by a panic on the first request. The error names every missing handler.
+func (h *FaaSHandlers) Validate() error {
+	if h == nil {
+		return fmt.Errorf("invalid FaaSHandlers: must not be nil")
+	}
+
+	required := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"FunctionProxy", h.FunctionProxy},
+		{"FunctionLister", h.FunctionLister},
+		{"DeployFunction", h.DeployFunction},
+		{"UpdateFunction", h.UpdateFunction},
+		{"DeleteFunction", h.DeleteFunction},
+		{"FunctionStatus", h.FunctionStatus},
+		{"ScaleFunction", h.ScaleFunction},
+		{"Secrets", h.Secrets},
+		{"Logs", h.Logs},
+		{"Info", h.Info},
+		{"ListNamespaces", h.ListNamespaces},
+	}
+
+	var missing []string
+	for _, r := range required {
+		if r.handler == nil {
+			missing = append(missing, r.name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("invalid FaaSHandlers: %s must be set", strings.Join(missing, ", "))
+	}
+
+	return nil
 }
 
 // FaaSConfig set config for HTTP handlers
 type FaaSConfig struct {
 	// TCPPort is the public port for the API.
 	TCPPort *int
+	// MetricsPort, when set to a port other than TCPPort, serves "/metrics" and "/healthz"
+	// from a separate listener, and "/metrics" is removed from the API. When nil, metrics
+	// are served by the API.
+	MetricsPort *int
+	// GRPCPort is the port the provider API is served on over gRPC by ServeGRPC, on
+	// BindAddress. It must differ from TCPPort and MetricsPort.
+	GRPCPort *int
 	// HTTP timeout for reading a request from clients.
 	ReadTimeout time.Duration
 	// HTTP timeout for writing a response from functions.
 	WriteTimeout time.Duration
+	// IdleTimeout is how long a keep-alive connection is kept open between requests, the
+	// default is 120 seconds.
+	IdleTimeout time.Duration
+	// ReadHeaderTimeout is how long clients are given to send the headers of a request, the
+	// default is ReadTimeout, or 10 seconds when it is not set. It does not limit how long
+	// a response such as a stream of logs may take.
+	ReadHeaderTimeout time.Duration
 	// EnableHealth enables/disables the default health endpoint bound to "/healthz".
 	//
 	// Deprecated: basic auth is enabled automatcally by setting the HealthHandler in the FaaSHandlers
@@ -76,12 +324,657 @@ type FaaSConfig struct {
 	// EnableBasicAuth enforces basic auth on the API. If set, reads secrets from file-system
 	// location specificed in `SecretMountPath`.
 	EnableBasicAuth bool
-	// SecretMountPath specifies where to read secrets from for embedded basic auth.
+	// SecretMountPath specifies where to read secrets from for embedded basic auth. When it
+	// is empty the credentials are read from the basic_auth_user and basic_auth_password
+	// environment variables instead.
 	SecretMountPath string
+	// BasicAuthReloadInterval, when set, is how often the basic auth credentials are read
+	// again while serving, so that a rotated secret is used without a restart.
+	BasicAuthReloadInterval time.Duration
+	// Authenticator, when set, authenticates requests to the system API in place of basic
+	// auth, i.e. to verify a bearer token issued by an OIDC provider. EnableBasicAuth is
+	// ignored when it is set.
+	Authenticator auth.Authenticator
+	// AuthPolicies sets whether credentials are checked for the routes which are open by
+	// default: InvokeRoute, CheckpointsRoute, KillRoute and MetricsRoute, i.e. to require
+	// them for "/danger/kill". Routes which are not set use AuthNone.
+	AuthPolicies map[string]AuthPolicy
+	// Network is the network the API listens on and the proxy dials functions over, one of
+	// "tcp" (the default, dual-stack), "tcp4" for IPv4 only or "tcp6" for IPv6 only.
+	Network string
+	// BindAddress is the address of the interface the API listens on, i.e. "127.0.0.1" to
+	// only accept local connections. The default is every interface.
+	BindAddress string
+	// ListenAddress, when set, is a Unix domain socket for the API to listen on instead of
+	// TCPPort, with the UnixSocketScheme, i.e. "unix:///var/run/provider.sock". A stale
+	// socket file left by a previous run is replaced, and the file is removed on shutdown.
+	ListenAddress string
+	// UnixSocketPath, when set, is a Unix domain socket the API is also served on, alongside
+	// TCPPort, i.e. "/var/run/provider.sock" for a gateway on the same host. It can not be
+	// combined with ListenAddress. A stale socket file left by a previous run is replaced,
+	// and the file is removed on shutdown.
+	UnixSocketPath string
+	// UnixSocketMode is the permissions of the socket file of ListenAddress or
+	// UnixSocketPath, the default is 0660 so that only the owner and group may connect.
+	UnixSocketMode os.FileMode
+	// TLSConfig, when set, serves the API over HTTPS instead of HTTP. The certificate is read
+	// again when the process receives SIGHUP, so that it can be renewed without a restart.
+	TLSConfig *TLSConfig
+	// EnableStreaming lifts the server's ReadTimeout and WriteTimeout and the timeout of the
+	// proxy for WebSocket connections and Server-Sent Events from functions, once the
+	// function has responded within its timeout, so that they can stay open. Without it,
+	// upgraded connections are still passed through, but closed by the timeouts.
+	EnableStreaming bool
+	// EnableH2C serves HTTP/2 without TLS (h2c), to clients which upgrade or which use
+	// prior knowledge, as well as HTTP/1.1. It is ignored when TLSConfig is set, as HTTP/2
+	// is then negotiated with ALPN.
+	EnableH2C bool
+	// HTTP2, when set, tunes the HTTP/2 connections of the API and of ProxyH2C, such as
+	// their flow-control windows. The defaults suit streamed request bodies.
+	HTTP2 *HTTP2Config
+	// CORS, when set, allows browsers on the given origins to call the API, and functions
+	// unless FunctionCORS is set. No CORS headers are sent when it is nil.
+	CORS *CORSConfig
+	// FunctionCORS, when set, is the CORS policy of "/function/", "/invoke/",
+	// "/invoke-batch/" and "/async-function/" in place of CORS, so that functions can be called from origins
+	// which are not allowed to manage them, or the other way around.
+	FunctionCORS *CORSConfig
+	// ProxyH2C sends requests to functions over HTTP/2 cleartext (h2c) with prior knowledge,
+	// so that concurrent requests to an instance share one connection. Functions must then
+	// serve h2c. WebSocket upgrades are still sent over HTTP/1.1.
+	ProxyH2C bool
 	// MaxIdleConns with a default value of 1024, can be used for tuning HTTP proxy performance.
 	MaxIdleConns int
 	// MaxIdleConnsPerHost with a default value of 1024, can be used for tuning HTTP proxy performance.
 	MaxIdleConnsPerHost int
+	// ProxyErrorHandler, when set, writes the response for requests which could not be proxied
+	// to a function because it could not be resolved or reached. The error can be inspected
+	// with errors.As and a *proxy.Error. When nil, the message is written with
+	// httputil.WriteErrorCode.
+	ProxyErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+	// ColdStartMaxWait bounds how long the proxy waits for the resolver to return the address
+	// of a function which may be scaling up from zero, including its wake up by
+	// FunctionScaler. When exceeded a 503 with a Retry-After header is returned. A value of 0
+	// waits for as long as the resolver and ScaleFromZeroTimeout allow.
+	ColdStartMaxWait time.Duration
+	// ProxyMaxTimeout is the longest timeout a function may declare with the
+	// com.openfaas.function.timeout label, when the resolver passed to the proxy can look up
+	// labels. Functions without the label are given ReadTimeout. When ProxyMaxTimeout is
+	// less than ReadTimeout, functions may only shorten their timeout. WriteTimeout should be
+	// at least as long, or the response is cut off before the function completes.
+	ProxyMaxTimeout time.Duration
+	// DefaultFunction, when set, receives the requests proxied to a function which the resolver
+	// reports as not found with proxy.ErrFunctionNotFound, instead of a 404 being returned.
+	// The name of the function in the original request is passed in the X-Original-Function header.
+	DefaultFunction string
+	// ProxyLoadBalancing is the strategy used by the proxy to pick an instance when the resolver
+	// returns every instance of a function, either LoadBalancingRoundRobin (the default),
+	// LoadBalancingLeastConnections or LoadBalancingConsistentHash. A resolver which
+	// implements resolver.Resolver picks the instance itself, see resolver.NewStrategy.
+	ProxyLoadBalancing string
+	// ProxyHashHeader is the request header whose value keeps the invocations of a function
+	// on the same instance with LoadBalancingConsistentHash, i.e. a session ID. It is set as
+	// the key of the "/function/" and "/invoke/" requests, see resolver.WithKey. Serve warns
+	// when it is set with another ProxyLoadBalancing, as only a resolver.Resolver which
+	// hashes by the key then uses it.
+	ProxyHashHeader string
+	// ProxyTimingHeaders returns the time spent in each phase of an invocation to the client
+	// in X-Duration headers, i.e. X-Duration-Resolve, see the timing package. The phases are
+	// observed in the provider_invocation_phase_duration_seconds histogram either way.
+	ProxyTimingHeaders bool
+	// ProxyRetry, when set, retries invocations which could not reach a function, such as
+	// when the connection is refused while an instance is being restored. Only idempotent
+	// methods are retried, see ProxyRetryPolicy.
+	ProxyRetry *ProxyRetryPolicy
+	// ProxyCircuitBreaker, when set, rejects invocations of a function with a 503 once
+	// requests to it keep failing, until it has had time to recover, see ProxyCircuitBreaker.
+	// The state of each circuit is exported as the provider_proxy_circuit_state metric, the
+	// circuits of functions without invocations for 10 minutes are removed.
+	ProxyCircuitBreaker *ProxyCircuitBreaker
+	// StartupChecks are run in a loop when the server starts, until they all pass or
+	// StartupTimeout elapses. Until then the Health handler returns 503, so that the
+	// provider is not sent traffic before its backend can be reached.
+	StartupChecks []func(ctx context.Context) error
+	// HealthChecker, when set, serves "/healthz" and "/readyz" with the JSON results of its
+	// liveness and readiness probes, unless FaaSHandlers.Health or FaaSHandlers.Ready are
+	// set. A "provider" probe is registered on it which fails until StartupChecks pass and
+	// once shutdown begins.
+	HealthChecker *health.Checker
+	// StartupTimeout bounds how long StartupChecks are retried for, a value of 0 retries
+	// until they pass.
+	StartupTimeout time.Duration
+	// DisableRecovery turns off the built-in middleware which recovers from a panic in a
+	// handler, logs it and returns a 500, for providers which install their own.
+	DisableRecovery bool
+	// Middleware is applied to every route of the API in the order given, before the built-in
+	// middleware such as the access log, i.e. to start a tracing span or to set a request ID.
+	Middleware []func(http.Handler) http.Handler
+	// NewRouter, when set, creates the routers of the API and of MetricsPort in place of
+	// httputil.NewMuxRouter, i.e. to serve the provider with chi or http.ServeMux.
+	NewRouter func() httputil.Router
+	// OnReady, when set, is called with the address of the listener once the API is
+	// accepting connections and StartupChecks have passed or StartupTimeout has elapsed,
+	// i.e. to notify systemd or a test harness that the provider can be sent traffic.
+	OnReady func(addr net.Addr)
+	// ScaleStep, when set, is the largest number of replicas a function is scaled up by at
+	// once by ScaleInSteps, which the ScaleFunction handler may call to apply a large scale up
+	// gradually. A value of 0 applies every change at once.
+	ScaleStep int
+	// ScaleStepInterval is how long ScaleInSteps waits between each step.
+	ScaleStepInterval time.Duration
+	// AdmitDeploy, when set, is called with the FunctionDeployment of each deploy and update
+	// request before the DeployFunction or UpdateFunction handler. Returning an error rejects
+	// the request with a 403 and the error message, i.e. when the requested resources exceed
+	// the remaining capacity.
+	AdmitDeploy func(ctx context.Context, deployment FunctionDeployment) error
+	// LifecycleHook, when set, is called after a function was successfully deployed, updated,
+	// deleted or scaled through the system API. It is called before the request completes,
+	// so long running work should be handed off to a goroutine.
+	LifecycleHook func(event LifecycleEvent)
+	// Events, when set, is published the events of the routes owned by the provider: deploys,
+	// updates, deletes, scaling, checkpoints, kills and cold starts. The context of each
+	// request carries it, so that handlers can publish further events with events.Publish.
+	// Serve runs it, delivering events to its sinks until the provider has shut down.
+	// NewHTTPServer returns an error when it is set.
+	Events *events.Bus
+	// Audit, when set, is logged a record of every request to the system API which may
+	// change something, and of every invocation when AuditInvocations is set, with the
+	// caller, route, function, status, duration and bytes transferred. Serve runs it,
+	// writing records to its sinks until the provider has shut down. NewHTTPServer returns
+	// an error when it is set.
+	Audit *audit.Trail
+	// AuditInvocations logs a record to Audit for every invocation of a function as well.
+	AuditInvocations bool
+	// ReadOnly starts the provider in read-only mode, where requests which would change
+	// functions, secrets or namespaces are rejected with a 503. Reads, invocations, metrics
+	// and health checks are still served. The mode can be changed at runtime with a PUT
+	// to "/system/read-only", or with ReadOnlyFile.
+	ReadOnly bool
+	// ReadOnlyFile, when set, enables read-only mode while the file exists. It is checked
+	// when the provider starts and each time the process receives SIGHUP, so the mode can
+	// be changed by creating or removing the file, then sending SIGHUP. The mode set by a
+	// SIGHUP replaces one set with "/system/read-only".
+	ReadOnlyFile string
+	// Maintenance starts the provider in maintenance mode, where every request apart from
+	// health checks and metrics is rejected with a 503, a JSON body containing
+	// MaintenanceMessage and a Retry-After header of MaintenanceRetryAfter. The mode can be
+	// changed at runtime with a PUT of a MaintenanceMode to "/system/maintenance", or with
+	// MaintenanceFile.
+	Maintenance bool
+	// MaintenanceFile, when set, enables maintenance mode while the file exists, with the
+	// content of the file as the message when it is not empty. It is read when the provider
+	// starts and each time the process receives SIGHUP, so maintenance can be started and
+	// ended by creating or removing the file, then sending SIGHUP. The state set by a SIGHUP
+	// replaces one set with "/system/maintenance".
+	MaintenanceFile string
+	// MaintenanceMessage is returned to callers during maintenance.
+	MaintenanceMessage string
+	// MaintenanceRetryAfter is how long callers are asked to wait before retrying
+	// during maintenance.
+	MaintenanceRetryAfter time.Duration
+	// RequireGateway rejects requests to the "/system/" API with a 403 unless they carry
+	// the GatewayHeaders set by the OpenFaaS gateway, so that the provider is only managed
+	// through the gateway.
+	RequireGateway bool
+	// GatewayHeaders must all be present for a request to be treated as coming from the
+	// gateway, the default is bootstrap.DefaultGatewayHeaders.
+	GatewayHeaders []string
+	// EnableHMACAuth rejects every request with a 401 unless it is signed with the key
+	// shared with the gateway, read from the "provider-hmac-key" file in SecretMountPath,
+	// see the auth/hmac package. The health checks, "/metrics" and, unless
+	// HMACVerifyInvocations is set, the invocation routes are served without a signature.
+	EnableHMACAuth bool
+	// HMACMaxSkew is how far the time a request was signed may be from the clock of the
+	// provider, the default is 5 minutes.
+	HMACMaxSkew time.Duration
+	// HMACVerifyInvocations requires a signature on "/function/", "/invoke/",
+	// "/invoke-batch/" and "/async-function/" as well. The body of each invocation is then
+	// buffered to be verified, up to MaxProxyBodyBytes, so responses can still stream but
+	// requests can not.
+	HMACVerifyInvocations bool
+	// HMACAllowMissingNonce accepts signed requests without the "X-Provider-Nonce" header,
+	// from gateways which predate it. Such requests can be replayed within HMACMaxSkew, so
+	// it should only be set until every gateway has been upgraded.
+	HMACAllowMissingNonce bool
+	// AllowedNamespaces limits the namespaces which namespace-scoped requests may address,
+	// an empty list allows any namespace. Kills, cordons, drains and checkpoint requests
+	// which do not give a namespace, and so would address every namespace, are scoped to
+	// the allowed namespace when only one is listed, and rejected with a 403 otherwise.
+	AllowedNamespaces []string
+	// DefaultNamespace is the namespace the provider uses for a function requested without
+	// one. The state kept per function, such as the FunctionCache entries and the invoke
+	// rate limits, is keyed by it, so that "figlet" and "figlet.openfaas-fn" share it. When
+	// empty, a function requested without a namespace is kept apart from one which names it.
+	DefaultNamespace string
+	// MaxLabelValueLength is the longest value of a label of a deployment, 63 characters
+	// when 0, as in Kubernetes. A negative value does not limit the length, see
+	// validation.Limits.
+	MaxLabelValueLength int
+	// MaxFunctionsPerNamespace caps the number of functions which may be deployed to each
+	// namespace, enforced by the DeployFunction handler with CheckFunctionQuota. A value of
+	// 0 means unlimited.
+	MaxFunctionsPerNamespace int
+	// Quotas, when set with NamespaceUsage, limits the functions, memory, CPU and
+	// checkpoints of each namespace. Deploy, update and register requests and the creation
+	// of checkpoints which would exceed the quota of their namespace are rejected with a 403
+	// and the *QuotaError as JSON, see WriteQuotaError. The checked requests of a namespace
+	// are served one at a time, so a quota is only exceeded by concurrent requests when
+	// they are served by different replicas of the provider.
+	Quotas QuotaProvider
+	// NamespaceUsage returns what a namespace has in use, it is called for each request
+	// which is checked against Quotas.
+	NamespaceUsage func(ctx context.Context, namespace string) (NamespaceUsage, error)
+	// KillConfirmationToken must be passed to "/danger/kill" in the X-Confirm-Kill header or
+	// the "confirm" query string parameter for the request to be accepted, i.e. the ID of
+	// the cluster. It is required when the KillAllInstance handler is set.
+	KillConfirmationToken string
+	// KillAuthenticator, when set, checks the credentials of every request to "/danger/kill"
+	// in place of Authenticator and AuthPolicies, i.e. with its own BasicAuthCredentials or
+	// a jwt.Verifier, so that the route can be protected when the rest of the API is not.
+	KillAuthenticator auth.Authenticator
+	// DeprecationSunset, when set, is sent in the Sunset header of responses to deploy and
+	// update requests which use deprecated fields of FunctionDeployment, as the date after
+	// which the fields may no longer be supported.
+	DeprecationSunset time.Time
+	// StaticDir, when set, is a directory of static files, such as a status UI, served under
+	// StaticPath. Directories are not listed unless they contain an index.html.
+	StaticDir string
+	// StaticPath is the path StaticDir is served under, it must start and end with "/" and
+	// not overlap the API. The default is "/ui/".
+	StaticPath string
+	// MaxRequestTimeout clamps the timeout clients of the "/system/" API may ask for with
+	// the X-Request-Timeout header, a value of 0 means no limit.
+	MaxRequestTimeout time.Duration
+	// EnableProfiling serves the net/http/pprof handlers under "/debug/pprof/", on MetricsPort
+	// when it is set. They require the same auth as the system API. When false the routes
+	// are not registered.
+	EnableProfiling bool
+	// EnableDebugEndpoints serves the net/http/pprof handlers under "/debug/pprof/", the
+	// expvar variables from "/debug/vars" and this config, with secrets redacted, from
+	// "/debug/config". They are served on DebugPort when it is set, otherwise on
+	// MetricsPort or the API like EnableProfiling, and require the same auth as the
+	// system API.
+	EnableDebugEndpoints bool
+	// DebugPort, when set, serves the debug endpoints from a separate listener on
+	// 127.0.0.1, so that they can only be reached from the same host, i.e. through
+	// kubectl port-forward. It requires EnableDebugEndpoints and must differ from
+	// TCPPort, MetricsPort and GRPCPort.
+	DebugPort *int
+	// MaxRequestBodyBytes caps the size of the body of requests which deploy, update, register
+	// or scale functions or manage secrets, larger requests are rejected with a 413. A value
+	// of 0 means unlimited.
+	MaxRequestBodyBytes int64
+	// MaxProxyBodyBytes caps the size of the body of requests to "/function/", "/invoke/" and
+	// "/async-function/" separately from MaxRequestBodyBytes, as invocations may carry large payloads. A value
+	// of 0 means unlimited.
+	MaxProxyBodyBytes int64
+	// BatchParallelism caps the number of items of a batch sent to "/invoke-batch/" which
+	// are invoked at once, the default is 10. The whole batch is limited by
+	// MaxProxyBodyBytes.
+	BatchParallelism int
+	// IdempotencyTTL is how long the responses to deploy, register and kill requests with an
+	// X-Idempotency-Key header are kept, to be replayed when the request is retried with the
+	// same key. The default is 24 hours.
+	IdempotencyTTL time.Duration
+	// FunctionCache, when set, caches the responses of GET "/system/functions" and
+	// "/system/function/{name}" for FunctionCacheTTL, so that repeated reads are not sent to
+	// the backing runtime. The entries of a function and of the lists of its namespace are
+	// removed when it is deployed, updated, deleted or scaled through the API. Responses
+	// carry an X-Cache header of HIT, MISS or BYPASS. Requests for the function's
+	// environment with "env=true" are not cached.
+	FunctionCache cache.Store
+	// FunctionCacheTTL is how long responses are kept in FunctionCache, the default is 5
+	// seconds. It bounds how stale a status may be after a change which was not made
+	// through the API, such as a function being scaled by the runtime.
+	FunctionCacheTTL time.Duration
+	// DecompressRequests accepts bodies sent with "Content-Encoding: gzip" to the "/system/"
+	// API and decompresses them for the handlers, other encodings are rejected with a 415.
+	// The decompressed size of every body is capped at MaxRequestBodyBytes, or 32MiB when it
+	// is not set.
+	DecompressRequests bool
+	// CompressResponses compresses the responses of the "/system/" API with gzip for
+	// clients which accept it. Responses from functions are passed through as they are.
+	CompressResponses bool
+	// MaxFunctionMetrics caps the number of functions given their own series in the
+	// invocation metrics served from "/metrics", invocations of further functions are
+	// counted under the function "other". The proxy caps the functions of its retry and
+	// circuit breaker metrics in the same way. The default is 1000.
+	MaxFunctionMetrics int
+	// MetricsTimeout bounds how long "/metrics" may take to gather metrics, a slower scrape
+	// is answered with a 503. A value of 0 means no timeout.
+	MetricsTimeout time.Duration
+	// InstanceMetrics, when set, gathers the metrics of function instances, such as a
+	// scrape.Scraper, to be served in the Prometheus formats from "/system/metrics". When
+	// FaaSHandlers.MetricFunction is also set, it keeps serving JSON and DELETE requests, and
+	// the exposition is only given to clients which accept "text/plain" or
+	// "application/openmetrics-text".
+	InstanceMetrics prometheus.Gatherer
+	// TracingEndpoint, when set, is the base URL of an OpenTelemetry collector which accepts
+	// OTLP/HTTP, i.e. "http://otel-collector:4318". A span is recorded for every request to
+	// the API and exported to it, and the traceparent header of requests proxied to
+	// functions is set so that their spans join the trace, see the tracing package. Spans
+	// are flushed by Serve as it returns, NewHTTPServer returns an error when it is set.
+	TracingEndpoint string
+	// TracingSampleRatio is the fraction of the traces started by the provider which are
+	// sampled, between 0 and 1. Requests which carry a traceparent header follow the
+	// sampling decision of their caller. When nil every trace is sampled, a ratio of 0
+	// samples none of them.
+	TracingSampleRatio *float64
+	// TracingServiceName is the service.name of the spans, the default is "faas-provider".
+	TracingServiceName string
+	// Logger receives the logs of the provider, such as the address it listens on, failed
+	// startup checks, panics and shutdown, as structured records. The default is
+	// slog.Default(), which writes human-readable lines through the log package. The
+	// context of each request carries Logger with the request's ID as the "request_id"
+	// attribute, handlers should log with logging.FromContext(r.Context()).
+	Logger *slog.Logger
+	// AccessLog writes a line for every request served by the API to stdout, or through
+	// Logger with the method, path, status and duration as attributes when Logger is set.
+	AccessLog bool
+	// AccessLogFormat is the format of the access log, either AccessLogFormatJSON (the
+	// default) or AccessLogFormatCLF.
+	AccessLogFormat string
+	// MaxSystemRequests caps the number of requests to the "/system/" API served at once,
+	// further requests are rejected with a 429. A value of 0 means unlimited.
+	MaxSystemRequests int
+	// MaxDataPlaneRequests caps the number of requests to "/function/", "/invoke/",
+	// "/invoke-batch/" and "/async-function/" served at once, separately from MaxSystemRequests, so that a burst of invocations can not
+	// block functions from being managed. A value of 0 means unlimited.
+	MaxDataPlaneRequests int
+	// MaxSystemConcurrency caps the number of requests which deploy, update, delete or scale
+	// functions served at once, further requests are rejected with a 429 and a Retry-After
+	// header. Invocations are not counted. A value of 0 means unlimited.
+	MaxSystemConcurrency int
+	// FunctionLabels, when set, is used to look up the limits each function declares with
+	// MaxInflightLabel and RequestsPerSecondLabel, which are then applied to "/function/"
+	// and "/invoke/", see the limiter package.
+	FunctionLabels LabelResolver
+	// FunctionScaler, when set, is used to wake functions which are scaled to zero before
+	// requests to "/function/" and "/invoke/" are proxied, see the scaling package. The proxy
+	// wakes them within ColdStartMaxWait.
+	FunctionScaler scaling.FunctionScaler
+	// ScaleFromZeroTimeout bounds how long a function is waited for to be woken by
+	// FunctionScaler, after which the proxy returns a 503 with a Retry-After header, as when
+	// ColdStartMaxWait is exceeded. Defaults to 30s.
+	ScaleFromZeroTimeout time.Duration
+	// CheckpointStore, when set with CheckpointRetention, is used to collect checkpoints
+	// which exceed the retention policy, in the background and on a POST to
+	// "/system/checkpoints/gc".
+	CheckpointStore CheckpointStore
+	// CheckpointRetention is the policy checkpoints are collected by, it requires
+	// CheckpointStore. Checkpoints are kept forever when it is nil. The background
+	// collection is run by Serve, NewHTTPServer returns an error when it is set.
+	CheckpointRetention *CheckpointRetention
+	// MaxLogStreams caps the number of requests to "/system/logs" with "follow=true" served at
+	// once, further requests are rejected with a 429. A value of 0 means unlimited.
+	MaxLogStreams int
+	// InvokeRateLimit caps the number of requests to each function through "/function/",
+	// "/invoke/" and "/async-function/" within InvokeRateLimitWindow, further requests are rejected with a 429.
+	// Each item of a batch to "/invoke-batch/" counts as a request, an item over the limit is
+	// answered with a 429 in its result.
+	// Responses carry X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers,
+	// so that clients can slow down before being limited. A value of 0 means unlimited.
+	// Functions are counted by name and namespace, with DefaultNamespace for those invoked
+	// without one.
+	InvokeRateLimit int
+	// InvokeRateLimitWindow is the window InvokeRateLimit applies to, the default is one second.
+	// The requests allowed are refilled evenly over the window, so X-RateLimit-Reset is when
+	// the whole limit is available again.
+	InvokeRateLimitWindow time.Duration
+	// PreShutdownHooks are called in order when the provider receives SIGINT or SIGTERM,
+	// after the Health handler starts to return 503 and while the API is still serving
+	// requests, i.e. to deregister the provider from service discovery.
+	//
+	// Shutdown happens in the following order: the provider is marked as not ready,
+	// PreShutdownHooks are run, requests are served until HealthcheckInterval has passed,
+	// in-flight requests are drained and the listener is closed, then PostShutdownHooks
+	// are run. Each set of hooks is given up to 10 seconds and
+	// draining is given up to ShutdownTimeout.
+	PreShutdownHooks []func(ctx context.Context) error
+	// ProxyDrainTimeout, when set, is how long requests in flight to functions through
+	// "/function/" and "/invoke/" are given to complete when the provider shuts down, before
+	// they are cancelled. The rest of the API is still given up to ShutdownTimeout to drain.
+	ProxyDrainTimeout time.Duration
+	// ShutdownTimeout is how long in-flight requests are given to complete when the provider
+	// receives SIGINT or SIGTERM, before the server is closed. The default is 10 seconds.
+	ShutdownTimeout time.Duration
+	// HealthcheckInterval is how often the load balancer or kubelet checks "/healthz". When
+	// set, the provider keeps accepting requests for this long after the health check
+	// starts to fail on SIGINT or SIGTERM, so that no new requests are routed to it by the
+	// time the listener is closed.
+	HealthcheckInterval time.Duration
+	// PostShutdownHooks are called in order once the API has stopped serving requests,
+	// i.e. to close connections to the backend.
+	PostShutdownHooks []func(ctx context.Context) error
+	// MaxConnections caps the number of concurrently accepted connections to the API,
+	// a value of 0 means unlimited.
+	MaxConnections int
+}
+
+// Validate checks the values of the config which would otherwise result in a server
+// which fails in confusing ways at runtime, such as negative timeouts or a port which
+// can not be bound. Every invalid value is reported, joined with errors.Join.
+func (c *FaaSConfig) Validate() error {
+	var errs []error
+
+	if c.TCPPort != nil && (*c.TCPPort < 1 || *c.TCPPort > 65535) {
+		errs = append(errs, fmt.Errorf("invalid TCPPort %d: must be between 1 and 65535", *c.TCPPort))
+	}
+
+	if c.MetricsPort != nil && (*c.MetricsPort < 1 || *c.MetricsPort > 65535) {
+		errs = append(errs, fmt.Errorf("invalid MetricsPort %d: must be between 1 and 65535", *c.MetricsPort))
+	}
+
+	if c.GRPCPort != nil {
+		if *c.GRPCPort < 1 || *c.GRPCPort > 65535 {
+			errs = append(errs, fmt.Errorf("invalid GRPCPort %d: must be between 1 and 65535", *c.GRPCPort))
+		}
+		if *c.GRPCPort == c.GetTCPPort() || (c.MetricsPort != nil && *c.GRPCPort == *c.MetricsPort) {
+			errs = append(errs, fmt.Errorf("invalid GRPCPort %d: must not be the same as TCPPort or MetricsPort", *c.GRPCPort))
+		}
+	}
+
+	if c.DebugPort != nil {
+		if *c.DebugPort < 1 || *c.DebugPort > 65535 {
+			errs = append(errs, fmt.Errorf("invalid DebugPort %d: must be between 1 and 65535", *c.DebugPort))
+		}
+		if *c.DebugPort == c.GetTCPPort() || (c.MetricsPort != nil && *c.DebugPort == *c.MetricsPort) || (c.GRPCPort != nil && *c.DebugPort == *c.GRPCPort) {
+			errs = append(errs, fmt.Errorf("invalid DebugPort %d: must not be the same as TCPPort, MetricsPort or GRPCPort", *c.DebugPort))
+		}
+		if !c.EnableDebugEndpoints {
+			errs = append(errs, fmt.Errorf("invalid DebugPort %d: EnableDebugEndpoints must be set", *c.DebugPort))
+		}
+	}
+
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"ReadTimeout", c.ReadTimeout},
+		{"WriteTimeout", c.WriteTimeout},
+		{"IdleTimeout", c.IdleTimeout},
+		{"ReadHeaderTimeout", c.ReadHeaderTimeout},
+		{"ColdStartMaxWait", c.ColdStartMaxWait},
+		{"ScaleFromZeroTimeout", c.ScaleFromZeroTimeout},
+		{"StartupTimeout", c.StartupTimeout},
+		{"MaintenanceRetryAfter", c.MaintenanceRetryAfter},
+		{"ProxyMaxTimeout", c.ProxyMaxTimeout},
+		{"ProxyDrainTimeout", c.ProxyDrainTimeout},
+		{"ShutdownTimeout", c.ShutdownTimeout},
+		{"HealthcheckInterval", c.HealthcheckInterval},
+		{"MetricsTimeout", c.MetricsTimeout},
+		{"MaxRequestTimeout", c.MaxRequestTimeout},
+		{"InvokeRateLimitWindow", c.InvokeRateLimitWindow},
+		{"ScaleStepInterval", c.ScaleStepInterval},
+		{"BasicAuthReloadInterval", c.BasicAuthReloadInterval},
+		{"IdempotencyTTL", c.IdempotencyTTL},
+		{"FunctionCacheTTL", c.FunctionCacheTTL},
+	}
+
+	for _, d := range durations {
+		if d.value < 0 {
+			errs = append(errs, fmt.Errorf("invalid %s %s: must not be negative", d.name, d.value))
+		}
+	}
+
+	switch c.Network {
+	case "", "tcp", "tcp4", "tcp6":
+	default:
+		errs = append(errs, fmt.Errorf("invalid Network %q: must be tcp, tcp4 or tcp6", c.Network))
+	}
+
+	if len(c.ListenAddress) > 0 {
+		if path, ok := c.GetUnixSocket(); !ok || len(path) == 0 {
+			errs = append(errs, fmt.Errorf("invalid ListenAddress %q: must be a path with the %s scheme", c.ListenAddress, UnixSocketScheme))
+		}
+
+		if len(c.UnixSocketPath) > 0 {
+			errs = append(errs, fmt.Errorf("invalid UnixSocketPath %q: can not be combined with ListenAddress", c.UnixSocketPath))
+		}
+	}
+
+	if c.UnixSocketMode&^os.ModePerm != 0 {
+		errs = append(errs, fmt.Errorf("invalid UnixSocketMode %s: must only set permission bits", c.UnixSocketMode))
+	}
+
+	if c.TLSConfig != nil {
+		if err := c.TLSConfig.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.HTTP2 != nil {
+		if err := c.HTTP2.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.CORS != nil {
+		if err := c.CORS.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.FunctionCORS != nil {
+		if err := c.FunctionCORS.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("invalid FunctionCORS: %w", err))
+		}
+	}
+
+	switch c.ProxyLoadBalancing {
+	case "", LoadBalancingRoundRobin, LoadBalancingLeastConnections:
+	case LoadBalancingConsistentHash:
+		if len(c.ProxyHashHeader) == 0 {
+			errs = append(errs, fmt.Errorf("invalid ProxyLoadBalancing %q: ProxyHashHeader must be set", c.ProxyLoadBalancing))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("invalid ProxyLoadBalancing %q: must be one of %q, %q or %q", c.ProxyLoadBalancing,
+			LoadBalancingRoundRobin, LoadBalancingLeastConnections, LoadBalancingConsistentHash))
+	}
+
+	if c.ProxyRetry != nil {
+		if err := c.ProxyRetry.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.ProxyCircuitBreaker != nil {
+		if err := c.ProxyCircuitBreaker.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.CheckpointRetention != nil {
+		if c.CheckpointStore == nil {
+			errs = append(errs, fmt.Errorf("invalid CheckpointRetention: CheckpointStore must be set"))
+		}
+		if err := c.CheckpointRetention.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := c.validateAuthPolicies(); err != nil {
+		errs = append(errs, err)
+	}
+
+	switch c.AccessLogFormat {
+	case "", AccessLogFormatJSON, AccessLogFormatCLF:
+	default:
+		errs = append(errs, fmt.Errorf("invalid AccessLogFormat %q: must be %q or %q", c.AccessLogFormat, AccessLogFormatJSON, AccessLogFormatCLF))
+	}
+
+	if len(c.TracingEndpoint) > 0 {
+		u, err := url.Parse(c.TracingEndpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+			errs = append(errs, fmt.Errorf("invalid TracingEndpoint %q: must be an http or https URL", c.TracingEndpoint))
+		}
+	}
+
+	if c.TracingSampleRatio != nil && (*c.TracingSampleRatio < 0 || *c.TracingSampleRatio > 1) {
+		errs = append(errs, fmt.Errorf("invalid TracingSampleRatio %g: must be between 0 and 1", *c.TracingSampleRatio))
+	}
+
+	if c.MaxIdleConns < 0 {
+		errs = append(errs, fmt.Errorf("invalid MaxIdleConns %d: must not be negative", c.MaxIdleConns))
+	}
+
+	if c.MaxIdleConnsPerHost < 0 {
+		errs = append(errs, fmt.Errorf("invalid MaxIdleConnsPerHost %d: must not be negative", c.MaxIdleConnsPerHost))
+	}
+
+	if c.MaxConnections < 0 {
+		errs = append(errs, fmt.Errorf("invalid MaxConnections %d: must not be negative", c.MaxConnections))
+	}
+
+	if c.MaxFunctionsPerNamespace < 0 {
+		errs = append(errs, fmt.Errorf("invalid MaxFunctionsPerNamespace %d: must not be negative", c.MaxFunctionsPerNamespace))
+	}
+
+	if c.BatchParallelism < 0 {
+		errs = append(errs, fmt.Errorf("invalid BatchParallelism %d: must not be negative", c.BatchParallelism))
+	}
+
+	if c.HMACMaxSkew < 0 {
+		errs = append(errs, fmt.Errorf("invalid HMACMaxSkew %s: must not be negative", c.HMACMaxSkew))
+	}
+
+	if c.Quotas != nil && c.NamespaceUsage == nil {
+		errs = append(errs, fmt.Errorf("invalid Quotas: NamespaceUsage must be set"))
+	}
+
+	if c.ScaleStep < 0 {
+		errs = append(errs, fmt.Errorf("invalid ScaleStep %d: must not be negative", c.ScaleStep))
+	}
+
+	if c.MaxRequestBodyBytes < 0 {
+		errs = append(errs, fmt.Errorf("invalid MaxRequestBodyBytes %d: must not be negative", c.MaxRequestBodyBytes))
+	}
+
+	if c.MaxProxyBodyBytes < 0 {
+		errs = append(errs, fmt.Errorf("invalid MaxProxyBodyBytes %d: must not be negative", c.MaxProxyBodyBytes))
+	}
+
+	if c.MaxSystemConcurrency < 0 {
+		errs = append(errs, fmt.Errorf("invalid MaxSystemConcurrency %d: must not be negative", c.MaxSystemConcurrency))
+	}
+
+	if c.MaxLogStreams < 0 {
+		errs = append(errs, fmt.Errorf("invalid MaxLogStreams %d: must not be negative", c.MaxLogStreams))
+	}
+
+	if c.InvokeRateLimit < 0 {
+		errs = append(errs, fmt.Errorf("invalid InvokeRateLimit %d: must not be negative", c.InvokeRateLimit))
+	}
+
+	if c.MaxSystemRequests < 0 {
+		errs = append(errs, fmt.Errorf("invalid MaxSystemRequests %d: must not be negative", c.MaxSystemRequests))
+	}
+
+	if c.MaxDataPlaneRequests < 0 {
+		errs = append(errs, fmt.Errorf("invalid MaxDataPlaneRequests %d: must not be negative", c.MaxDataPlaneRequests))
+	}
+
+	return errors.Join(errs...)
 }
 
 // GetReadTimeout is a helper to safely return the configured ReadTimeout or the default value of 10s
@@ -92,6 +985,148 @@ func (c *FaaSConfig) GetReadTimeout() time.Duration {
 	return c.ReadTimeout
 }
 
+// GetLogger is a helper to safely return the configured Logger or slog.Default()
+func (c *FaaSConfig) GetLogger() *slog.Logger {
+	if c.Logger == nil {
+		return slog.Default()
+	}
+	return c.Logger
+}
+
+// GetRouter is a helper to return a new router from NewRouter, or a httputil.MuxRouter
+func (c *FaaSConfig) GetRouter() httputil.Router {
+	if c.NewRouter == nil {
+		return httputil.NewMuxRouter()
+	}
+	return c.NewRouter()
+}
+
+// GetIdleTimeout is a helper to safely return the configured IdleTimeout or the default value of 120s
+func (c *FaaSConfig) GetIdleTimeout() time.Duration {
+	if c.IdleTimeout <= 0 {
+		return defaultIdleTimeout
+	}
+	return c.IdleTimeout
+}
+
+// GetReadHeaderTimeout is a helper to safely return the configured ReadHeaderTimeout or the
+// value of GetReadTimeout
+func (c *FaaSConfig) GetReadHeaderTimeout() time.Duration {
+	if c.ReadHeaderTimeout <= 0 {
+		return c.GetReadTimeout()
+	}
+	return c.ReadHeaderTimeout
+}
+
+// GetTCPPort is a helper to safely return the configured TCPPort or the default value of 8080
+func (c *FaaSConfig) GetTCPPort() int {
+	if c.TCPPort == nil {
+		return defaultTCPPort
+	}
+	return *c.TCPPort
+}
+
+// GetShutdownTimeout is a helper to safely return the configured ShutdownTimeout or the default value of 10s
+func (c *FaaSConfig) GetShutdownTimeout() time.Duration {
+	if c.ShutdownTimeout <= 0 {
+		return defaultShutdownTimeout
+	}
+	return c.ShutdownTimeout
+}
+
+// GetUnixSocket returns the path of the Unix domain socket in ListenAddress, the bool is
+// false when ListenAddress is not set or is not a Unix domain socket.
+func (c *FaaSConfig) GetUnixSocket() (string, bool) {
+	if !strings.HasPrefix(c.ListenAddress, UnixSocketScheme) {
+		return "", false
+	}
+	return strings.TrimPrefix(c.ListenAddress, UnixSocketScheme), true
+}
+
+// GetUnixSocketMode is a helper to safely return the configured UnixSocketMode or the
+// default value of 0660
+func (c *FaaSConfig) GetUnixSocketMode() os.FileMode {
+	if c.UnixSocketMode == 0 {
+		return defaultUnixSocketMode
+	}
+	return c.UnixSocketMode
+}
+
+// GetNetwork is a helper to safely return the configured Network or the default value of "tcp"
+func (c *FaaSConfig) GetNetwork() string {
+	if len(c.Network) == 0 {
+		return "tcp"
+	}
+	return c.Network
+}
+
+// GetTracingSampleRatio is a helper to safely return the configured TracingSampleRatio or
+// the default of 1
+func (c *FaaSConfig) GetTracingSampleRatio() float64 {
+	if c.TracingSampleRatio == nil {
+		return 1
+	}
+
+	return *c.TracingSampleRatio
+}
+
+// GetTracingServiceName is a helper to safely return the configured TracingServiceName or
+// the default
+func (c *FaaSConfig) GetTracingServiceName() string {
+	if len(c.TracingServiceName) == 0 {
+		return defaultTracingServiceName
+	}
+
+	return c.TracingServiceName
+}
+
+// GetMaxFunctionMetrics is a helper to safely return the configured MaxFunctionMetrics or the
+// default value of 1000
+func (c *FaaSConfig) GetMaxFunctionMetrics() int {
+	if c.MaxFunctionMetrics < 1 {
+		return defaultMaxFunctionMetrics
+	}
+
+	return c.MaxFunctionMetrics
+}
+
+// GetIdempotencyTTL returns IdempotencyTTL, or the default of 24 hours when it is not set.
+func (c *FaaSConfig) GetIdempotencyTTL() time.Duration {
+	if c.IdempotencyTTL <= 0 {
+		return defaultIdempotencyTTL
+	}
+
+	return c.IdempotencyTTL
+}
+
+// GetFunctionCacheTTL returns FunctionCacheTTL, or the default of 5 seconds when it is not
+// set.
+func (c *FaaSConfig) GetFunctionCacheTTL() time.Duration {
+	if c.FunctionCacheTTL <= 0 {
+		return defaultFunctionCacheTTL
+	}
+
+	return c.FunctionCacheTTL
+}
+
+// GetBatchParallelism returns BatchParallelism, or the default of 10 when it is not set.
+func (c *FaaSConfig) GetBatchParallelism() int {
+	if c.BatchParallelism <= 0 {
+		return defaultBatchParallelism
+	}
+
+	return c.BatchParallelism
+}
+
+// GetHTTP2 returns HTTP2, or an HTTP2Config with the defaults when it is not set.
+func (c *FaaSConfig) GetHTTP2() *HTTP2Config {
+	if c.HTTP2 == nil {
+		return &HTTP2Config{}
+	}
+
+	return c.HTTP2
+}
+
 // GetMaxIdleConns is a helper to safely return the configured MaxIdleConns or the default value of 1024
 func (c *FaaSConfig) GetMaxIdleConns() int {
 	if c.MaxIdleConns < 1 {
@@ -0,0 +1,204 @@
+package types
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type checkpointStore struct{}
+
+func (checkpointStore) ListCheckpoints(context.Context) ([]Checkpoint, error) { return nil, nil }
+
+func (checkpointStore) DeleteCheckpoint(context.Context, Checkpoint) error { return nil }
+
+func TestFaaSConfig_Validate(t *testing.T) {
+	port := func(p int) *int { return &p }
+	ratio := func(r float64) *float64 { return &r }
+
+	testCases := []struct {
+		name    string
+		config  FaaSConfig
+		wantErr string
+	}{
+		{name: "empty config", config: FaaSConfig{}},
+		{name: "valid config", config: FaaSConfig{TCPPort: port(8081), ReadTimeout: time.Second, WriteTimeout: time.Second}},
+		{name: "port zero", config: FaaSConfig{TCPPort: port(0)}, wantErr: "invalid TCPPort 0"},
+		{name: "port out of range", config: FaaSConfig{TCPPort: port(70000)}, wantErr: "invalid TCPPort 70000"},
+		{name: "negative read timeout", config: FaaSConfig{ReadTimeout: -time.Second}, wantErr: "invalid ReadTimeout -1s"},
+		{name: "negative write timeout", config: FaaSConfig{WriteTimeout: -time.Second}, wantErr: "invalid WriteTimeout -1s"},
+		{name: "negative idle timeout", config: FaaSConfig{IdleTimeout: -time.Second}, wantErr: "invalid IdleTimeout -1s"},
+		{name: "tls", config: FaaSConfig{TLSConfig: &TLSConfig{CertFile: "tls.crt", KeyFile: "tls.key", MinTLSVersion: TLSVersion13}}},
+		{name: "tls cipher suites", config: FaaSConfig{TLSConfig: &TLSConfig{CertFile: "tls.crt", KeyFile: "tls.key", CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}}}},
+		{name: "tls without key", config: FaaSConfig{TLSConfig: &TLSConfig{CertFile: "tls.crt"}}, wantErr: `CertFile "tls.crt" is set without a KeyFile`},
+		{name: "tls 1.1", config: FaaSConfig{TLSConfig: &TLSConfig{CertFile: "tls.crt", KeyFile: "tls.key", MinTLSVersion: "1.1"}}, wantErr: `invalid MinTLSVersion "1.1"`},
+		{name: "insecure cipher suite", config: FaaSConfig{TLSConfig: &TLSConfig{CertFile: "tls.crt", KeyFile: "tls.key", CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}}}, wantErr: "TLS_RSA_WITH_RC4_128_SHA is insecure"},
+		{name: "unknown cipher suite", config: FaaSConfig{TLSConfig: &TLSConfig{CertFile: "tls.crt", KeyFile: "tls.key", CipherSuites: []string{"TLS_FAST"}}}, wantErr: `unknown cipher suite "TLS_FAST"`},
+		{name: "tls optional client auth without CA", config: FaaSConfig{TLSConfig: &TLSConfig{CertFile: "tls.crt", KeyFile: "tls.key", ClientAuthOptional: true}}, wantErr: "ClientAuthOptional is set without a ClientCAFile"},
+		{name: "cors", config: FaaSConfig{CORS: &CORSConfig{AllowedOrigins: []string{"https://dashboard.example.com"}, AllowCredentials: true}}},
+		{name: "cors without origins", config: FaaSConfig{CORS: &CORSConfig{}}, wantErr: "AllowedOrigins must not be empty"},
+		{name: "cors wildcard with credentials", config: FaaSConfig{CORS: &CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}}, wantErr: "can not be used with AllowCredentials"},
+		{name: "function cors without origins", config: FaaSConfig{FunctionCORS: &CORSConfig{}}, wantErr: "invalid FunctionCORS: AllowedOrigins must not be empty"},
+		{name: "unix socket path with listen address", config: FaaSConfig{ListenAddress: "unix:///var/run/provider.sock", UnixSocketPath: "/var/run/gateway.sock"}, wantErr: "can not be combined with ListenAddress"},
+		{name: "unix socket mode", config: FaaSConfig{UnixSocketMode: os.ModeSetuid | 0660}, wantErr: "invalid UnixSocketMode"},
+		{name: "unix socket", config: FaaSConfig{ListenAddress: "unix:///var/run/provider.sock"}},
+		{name: "listen address without scheme", config: FaaSConfig{ListenAddress: "/var/run/provider.sock"}, wantErr: `invalid ListenAddress "/var/run/provider.sock"`},
+		{name: "listen address without path", config: FaaSConfig{ListenAddress: "unix://"}, wantErr: `invalid ListenAddress "unix://"`},
+		{name: "auth policy", config: FaaSConfig{EnableBasicAuth: true, AuthPolicies: map[string]AuthPolicy{KillRoute: AuthRequired, InvokeRoute: AuthOptional}}},
+		{name: "auth policy none without credentials", config: FaaSConfig{AuthPolicies: map[string]AuthPolicy{MetricsRoute: AuthNone}}},
+		{name: "tracing", config: FaaSConfig{TracingEndpoint: "http://otel-collector:4318", TracingSampleRatio: ratio(0.1)}},
+		{name: "tracing endpoint without scheme", config: FaaSConfig{TracingEndpoint: "otel-collector:4318"}, wantErr: `invalid TracingEndpoint "otel-collector:4318"`},
+		{name: "tracing sample ratio over 1", config: FaaSConfig{TracingSampleRatio: ratio(1.5)}, wantErr: "invalid TracingSampleRatio 1.5"},
+		{name: "auth policy without credentials", config: FaaSConfig{AuthPolicies: map[string]AuthPolicy{KillRoute: AuthRequired}}, wantErr: "neither EnableBasicAuth nor Authenticator is set"},
+		{name: "auth policy unknown route", config: FaaSConfig{EnableBasicAuth: true, AuthPolicies: map[string]AuthPolicy{"/system/info": AuthNone}}, wantErr: `unknown route "/system/info"`},
+		{name: "auth policy unknown policy", config: FaaSConfig{EnableBasicAuth: true, AuthPolicies: map[string]AuthPolicy{KillRoute: "always"}}, wantErr: `unknown policy "always"`},
+		{name: "grpc port", config: FaaSConfig{TCPPort: port(8080), GRPCPort: port(9090)}},
+		{name: "grpc port out of range", config: FaaSConfig{GRPCPort: port(0)}, wantErr: "invalid GRPCPort 0"},
+		{name: "grpc port same as tcp port", config: FaaSConfig{TCPPort: port(8080), GRPCPort: port(8080)}, wantErr: "must not be the same as TCPPort or MetricsPort"},
+		{name: "debug port", config: FaaSConfig{EnableDebugEndpoints: true, DebugPort: port(6060)}},
+		{name: "debug port same as metrics port", config: FaaSConfig{EnableDebugEndpoints: true, MetricsPort: port(8081), DebugPort: port(8081)}, wantErr: "must not be the same as TCPPort, MetricsPort or GRPCPort"},
+		{name: "debug port without debug endpoints", config: FaaSConfig{DebugPort: port(6060)}, wantErr: "EnableDebugEndpoints must be set"},
+		{name: "negative scale from zero timeout", config: FaaSConfig{ScaleFromZeroTimeout: -time.Second}, wantErr: "invalid ScaleFromZeroTimeout -1s"},
+		{name: "negative max connections", config: FaaSConfig{MaxConnections: -1}, wantErr: "invalid MaxConnections -1"},
+		{name: "checkpoint retention", config: FaaSConfig{CheckpointStore: checkpointStore{}, CheckpointRetention: &CheckpointRetention{MaxPerFunction: 3, TTL: time.Hour}}},
+		{name: "checkpoint retention without a store", config: FaaSConfig{CheckpointRetention: &CheckpointRetention{MaxPerFunction: 3}}, wantErr: "CheckpointStore must be set"},
+		{name: "negative checkpoint retention", config: FaaSConfig{CheckpointStore: checkpointStore{}, CheckpointRetention: &CheckpointRetention{MaxTotalBytes: -1}}, wantErr: "invalid CheckpointRetention MaxTotalBytes -1"},
+		{name: "quotas without usage", config: FaaSConfig{Quotas: &StaticQuotas{}}, wantErr: "NamespaceUsage must be set"},
+		{name: "negative proxy retry attempts", config: FaaSConfig{ProxyRetry: &ProxyRetryPolicy{MaxAttempts: -1}}, wantErr: "invalid ProxyRetry MaxAttempts -1"},
+		{name: "negative circuit breaker open duration", config: FaaSConfig{ProxyCircuitBreaker: &ProxyCircuitBreaker{OpenDuration: -time.Second}}, wantErr: "invalid ProxyCircuitBreaker OpenDuration -1s"},
+		{name: "negative batch parallelism", config: FaaSConfig{BatchParallelism: -1}, wantErr: "invalid BatchParallelism -1"},
+		{name: "negative hmac max skew", config: FaaSConfig{EnableHMACAuth: true, HMACMaxSkew: -time.Second}, wantErr: "invalid HMACMaxSkew -1s"},
+		{name: "consistent hash without a header", config: FaaSConfig{ProxyLoadBalancing: LoadBalancingConsistentHash}, wantErr: "ProxyHashHeader must be set"},
+		{name: "unknown load balancing", config: FaaSConfig{ProxyLoadBalancing: "random"}, wantErr: `invalid ProxyLoadBalancing "random"`},
+		{name: "http2 frame size too small", config: FaaSConfig{HTTP2: &HTTP2Config{MaxReadFrameSize: 1024}}, wantErr: "invalid HTTP2 MaxReadFrameSize 1024"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("want no error, got: %s", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("error, want: %q, got: %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestFaaSConfig_ServerTimeouts(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		config                FaaSConfig
+		wantIdleTimeout       time.Duration
+		wantReadHeaderTimeout time.Duration
+	}{
+		{name: "defaults", config: FaaSConfig{}, wantIdleTimeout: 120 * time.Second, wantReadHeaderTimeout: 10 * time.Second},
+		{name: "read timeout", config: FaaSConfig{ReadTimeout: 30 * time.Second}, wantIdleTimeout: 120 * time.Second, wantReadHeaderTimeout: 30 * time.Second},
+		{name: "overridden", config: FaaSConfig{ReadTimeout: 30 * time.Second, IdleTimeout: time.Minute, ReadHeaderTimeout: 5 * time.Second}, wantIdleTimeout: time.Minute, wantReadHeaderTimeout: 5 * time.Second},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.config.GetIdleTimeout(); got != tc.wantIdleTimeout {
+				t.Errorf("IdleTimeout, want: %s, got: %s", tc.wantIdleTimeout, got)
+			}
+			if got := tc.config.GetReadHeaderTimeout(); got != tc.wantReadHeaderTimeout {
+				t.Errorf("ReadHeaderTimeout, want: %s, got: %s", tc.wantReadHeaderTimeout, got)
+			}
+		})
+	}
+}
+
+func TestFaaSConfig_GetTracingSampleRatio(t *testing.T) {
+	zero, half := 0.0, 0.5
+
+	testCases := []struct {
+		name   string
+		config FaaSConfig
+		want   float64
+	}{
+		{name: "default", config: FaaSConfig{}, want: 1},
+		{name: "none", config: FaaSConfig{TracingSampleRatio: &zero}, want: 0},
+		{name: "half", config: FaaSConfig{TracingSampleRatio: &half}, want: 0.5},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.config.GetTracingSampleRatio(); got != tc.want {
+				t.Errorf("TracingSampleRatio, want: %g, got: %g", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestFaaSHandlers_Validate(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+
+	valid := func() *FaaSHandlers {
+		return &FaaSHandlers{
+			FunctionProxy:  ok,
+			FunctionLister: ok,
+			DeployFunction: ok,
+			UpdateFunction: ok,
+			DeleteFunction: ok,
+			FunctionStatus: ok,
+			ScaleFunction:  ok,
+			Secrets:        ok,
+			Logs:           ok,
+			Info:           ok,
+			ListNamespaces: ok,
+		}
+	}
+
+	testCases := []struct {
+		name    string
+		unset   func(h *FaaSHandlers)
+		wantErr string
+	}{
+		{name: "valid", unset: func(h *FaaSHandlers) {}},
+		{name: "FunctionProxy", unset: func(h *FaaSHandlers) { h.FunctionProxy = nil }, wantErr: "FunctionProxy must be set"},
+		{name: "FunctionLister", unset: func(h *FaaSHandlers) { h.FunctionLister = nil }, wantErr: "FunctionLister must be set"},
+		{name: "DeployFunction", unset: func(h *FaaSHandlers) { h.DeployFunction = nil }, wantErr: "DeployFunction must be set"},
+		{name: "UpdateFunction", unset: func(h *FaaSHandlers) { h.UpdateFunction = nil }, wantErr: "UpdateFunction must be set"},
+		{name: "DeleteFunction", unset: func(h *FaaSHandlers) { h.DeleteFunction = nil }, wantErr: "DeleteFunction must be set"},
+		{name: "FunctionStatus", unset: func(h *FaaSHandlers) { h.FunctionStatus = nil }, wantErr: "FunctionStatus must be set"},
+		{name: "ScaleFunction", unset: func(h *FaaSHandlers) { h.ScaleFunction = nil }, wantErr: "ScaleFunction must be set"},
+		{name: "Secrets", unset: func(h *FaaSHandlers) { h.Secrets = nil }, wantErr: "Secrets must be set"},
+		{name: "Logs", unset: func(h *FaaSHandlers) { h.Logs = nil }, wantErr: "Logs must be set"},
+		{name: "Info", unset: func(h *FaaSHandlers) { h.Info = nil }, wantErr: "Info must be set"},
+		{name: "ListNamespaces", unset: func(h *FaaSHandlers) { h.ListNamespaces = nil }, wantErr: "ListNamespaces must be set"},
+		{name: "optional handlers", unset: func(h *FaaSHandlers) { h.Health = nil; h.InvokeFunction = nil }},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := valid()
+			tc.unset(h)
+
+			err := h.Validate()
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("want no error, got: %s", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("error, want: %q, got: %v", tc.wantErr, err)
+			}
+		})
+	}
+
+	var nilHandlers *FaaSHandlers
+	if err := nilHandlers.Validate(); err == nil {
+		t.Errorf("want error for nil handlers")
+	}
+}
@@ -0,0 +1,59 @@
+package types
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// CORSConfig allows browsers on other origins, such as a dashboard, to call the API or
+// functions.
+type CORSConfig struct {
+	// AllowedOrigins are the origins which may call the API, i.e. "https://dashboard.example.com",
+	// or "*" for any origin.
+	AllowedOrigins []string
+	// AllowedMethods are the methods answered to a preflight request, the default is GET,
+	// HEAD, POST, PUT and DELETE.
+	AllowedMethods []string
+	// AllowedHeaders are the request headers answered to a preflight request, i.e.
+	// "Authorization" and "Content-Type". When empty, any header the browser asks for is
+	// allowed.
+	AllowedHeaders []string
+	// AllowCredentials allows requests to send cookies and the Authorization header, it
+	// can not be combined with the "*" origin.
+	AllowCredentials bool
+}
+
+// Validate checks that at least one origin is allowed and that the "*" origin is not
+// combined with AllowCredentials, which the CORS specification forbids.
+func (c *CORSConfig) Validate() error {
+	if err := c.validate(); err != nil {
+		return fmt.Errorf("invalid CORS: %w", err)
+	}
+	return nil
+}
+
+func (c *CORSConfig) validate() error {
+	if len(c.AllowedOrigins) == 0 {
+		return fmt.Errorf("AllowedOrigins must not be empty")
+	}
+
+	for _, origin := range c.AllowedOrigins {
+		if len(origin) == 0 {
+			return fmt.Errorf("AllowedOrigins must not contain an empty origin")
+		}
+		if origin == "*" && c.AllowCredentials {
+			return fmt.Errorf("the \"*\" origin can not be used with AllowCredentials")
+		}
+	}
+
+	return nil
+}
+
+// GetAllowedMethods is a helper to safely return the configured AllowedMethods or the
+// default methods of the API
+func (c *CORSConfig) GetAllowedMethods() []string {
+	if len(c.AllowedMethods) == 0 {
+		return []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodDelete}
+	}
+	return c.AllowedMethods
+}
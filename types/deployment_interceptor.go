@@ -0,0 +1,42 @@
+package types
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// DeploymentInterceptor is called with the FunctionDeployment of each deploy and update
+// request before the DeployFunction or UpdateFunction handler, see
+// FaaSHandlers.DeploymentInterceptors. It may change the deployment in place, i.e. to
+// inject environment variables or rewrite resource limits, and the handler then receives
+// the changed deployment. Returning an error rejects the request, with the status and
+// reason of a *DeploymentRejection, or a 403 and the error message for any other error.
+type DeploymentInterceptor func(ctx context.Context, deployment *FunctionDeployment) error
+
+// DeploymentRejection is returned by a DeploymentInterceptor to reject a deployment with a
+// reason which is given to the client.
+type DeploymentRejection struct {
+	// StatusCode of the response, 403 when not set
+	StatusCode int
+
+	// Reason the deployment was rejected, i.e. "image must be pulled from ghcr.io/acme"
+	Reason string
+}
+
+func (e *DeploymentRejection) Error() string {
+	return fmt.Sprintf("deployment rejected: %s", e.Reason)
+}
+
+// GetStatusCode returns the configured StatusCode or the default of 403.
+func (e *DeploymentRejection) GetStatusCode() int {
+	if e.StatusCode == 0 {
+		return http.StatusForbidden
+	}
+	return e.StatusCode
+}
+
+// RejectDeployment returns a *DeploymentRejection with a 403 and the formatted reason.
+func RejectDeployment(format string, args ...interface{}) error {
+	return &DeploymentRejection{Reason: fmt.Sprintf(format, args...)}
+}
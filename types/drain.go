@@ -0,0 +1,163 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// CordonRequest is posted to "/system/cordon/{name}" to stop routing invocations to
+// instances of a function, which keep running and serving their in-flight requests. An
+// empty request cordons every instance of the function.
+type CordonRequest struct {
+	// Instances limits the instances cordoned to those with the given IDs, as returned by
+	// the FunctionInstances handler
+	Instances []string `json:"instances,omitempty"`
+
+	// Uncordon routes invocations to the instances again
+	Uncordon bool `json:"uncordon,omitempty"`
+}
+
+// Validate checks that the request does not contain an empty instance ID.
+func (c CordonRequest) Validate() error {
+	return validateInstanceIDs(c.Instances)
+}
+
+// DrainRequest is posted to "/system/drain/{name}" to evict instances of a function: they
+// are cordoned, their in-flight requests are waited for, they are checkpointed when
+// Checkpoint is set, then they are terminated. An empty request drains every instance of
+// the function.
+type DrainRequest struct {
+	// Instances limits the instances drained to those with the given IDs, as returned by
+	// the FunctionInstances handler
+	Instances []string `json:"instances,omitempty"`
+
+	// Checkpoint takes a checkpoint of each instance once its in-flight requests have
+	// finished, before it is terminated
+	Checkpoint bool `json:"checkpoint,omitempty"`
+
+	// TimeoutSeconds is how long to wait for in-flight requests before the instances are
+	// terminated regardless, a value of 0 leaves it to the provider
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+}
+
+// Validate checks that the request does not contain an empty instance ID, nor a negative
+// timeout.
+func (d DrainRequest) Validate() error {
+	if d.TimeoutSeconds < 0 {
+		return fmt.Errorf("invalid timeoutSeconds %d: must not be negative", d.TimeoutSeconds)
+	}
+	return validateInstanceIDs(d.Instances)
+}
+
+// GetTimeout returns TimeoutSeconds as a duration, 0 when it is left to the provider.
+func (d DrainRequest) GetTimeout() time.Duration {
+	return time.Duration(d.TimeoutSeconds) * time.Second
+}
+
+func validateInstanceIDs(instances []string) error {
+	for _, id := range instances {
+		if len(id) == 0 {
+			return fmt.Errorf("instances must not contain an empty ID")
+		}
+	}
+	return nil
+}
+
+// DrainStage is how far the eviction of an instance has progressed.
+type DrainStage string
+
+const (
+	// DrainCordoned is reported once invocations are no longer routed to the instance
+	DrainCordoned DrainStage = "cordoned"
+
+	// DrainUncordoned is reported once invocations are routed to the instance again
+	DrainUncordoned DrainStage = "uncordoned"
+
+	// DrainDrained is reported once the in-flight requests of the instance have finished,
+	// or the timeout of the drain has passed
+	DrainDrained DrainStage = "drained"
+
+	// DrainCheckpointed is reported once a checkpoint of the instance was taken
+	DrainCheckpointed DrainStage = "checkpointed"
+
+	// DrainTerminated is reported once the instance was terminated, which completes its
+	// drain
+	DrainTerminated DrainStage = "terminated"
+
+	// DrainFailed is reported when the instance could not be drained, with the Error
+	DrainFailed DrainStage = "failed"
+)
+
+// InstanceDrainStatus is the progress of the drain or cordon of one instance.
+type InstanceDrainStatus struct {
+	// ID of the instance
+	ID string `json:"id"`
+
+	// Stage the instance has reached
+	Stage DrainStage `json:"stage"`
+
+	// InFlight is the number of requests the instance was serving, when known
+	InFlight int `json:"inFlight,omitempty"`
+
+	// CheckpointID is the ID of the checkpoint taken of the instance, once it was
+	// checkpointed
+	CheckpointID string `json:"checkpointId,omitempty"`
+
+	// Error describes why the instance could not be drained
+	Error string `json:"error,omitempty"`
+}
+
+// DrainStatus is returned by "/system/cordon/{name}" once the instances were cordoned,
+// and by "/system/drain/{name}" with a 202 once the drain has started, the stages which
+// follow are published with bootstrap.PublishDrainProgress.
+type DrainStatus struct {
+	// Function is the name of the function
+	Function string `json:"function"`
+
+	// Namespace of the function, if supported by the faas-provider
+	Namespace string `json:"namespace,omitempty"`
+
+	// Instances are the instances selected by the request
+	Instances []InstanceDrainStatus `json:"instances"`
+}
+
+// DecodeCordonRequest reads a CordonRequest from body, which may be empty, and validates it.
+func DecodeCordonRequest(body io.Reader) (CordonRequest, error) {
+	req := CordonRequest{}
+	if err := decodeOptional(body, &req); err != nil {
+		return req, fmt.Errorf("unable to decode cordon request: %w", err)
+	}
+
+	if err := req.Validate(); err != nil {
+		return req, err
+	}
+
+	return req, nil
+}
+
+// DecodeDrainRequest reads a DrainRequest from body, which may be empty, and validates it.
+func DecodeDrainRequest(body io.Reader) (DrainRequest, error) {
+	req := DrainRequest{}
+	if err := decodeOptional(body, &req); err != nil {
+		return req, fmt.Errorf("unable to decode drain request: %w", err)
+	}
+
+	if err := req.Validate(); err != nil {
+		return req, err
+	}
+
+	return req, nil
+}
+
+// decodeOptional decodes the JSON in body into v, leaving v as it is when body is empty.
+func decodeOptional(body io.Reader, v interface{}) error {
+	if body == nil {
+		return nil
+	}
+	if err := json.NewDecoder(body).Decode(v); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
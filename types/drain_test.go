@@ -0,0 +1,83 @@
+package types
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_DecodeDrainRequest(t *testing.T) {
+	testCases := []struct {
+		name    string
+		body    string
+		wantErr string
+		want    DrainRequest
+	}{
+		{
+			name: "every instance",
+			body: ``,
+			want: DrainRequest{},
+		},
+		{
+			name: "checkpointed instances",
+			body: `{"instances":["figlet-1","figlet-2"],"checkpoint":true,"timeoutSeconds":30}`,
+			want: DrainRequest{Instances: []string{"figlet-1", "figlet-2"}, Checkpoint: true, TimeoutSeconds: 30},
+		},
+		{
+			name:    "empty instance ID",
+			body:    `{"instances":[""]}`,
+			wantErr: "must not contain an empty ID",
+		},
+		{
+			name:    "negative timeout",
+			body:    `{"timeoutSeconds":-1}`,
+			wantErr: "invalid timeoutSeconds",
+		},
+		{
+			name:    "malformed json",
+			body:    `{"checkpoint":`,
+			wantErr: "unable to decode drain request",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := DecodeDrainRequest(strings.NewReader(tc.body))
+			if len(tc.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("want error containing %q, got: %v", tc.wantErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("want: %+v, got: %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_DecodeCordonRequest(t *testing.T) {
+	got, err := DecodeCordonRequest(strings.NewReader(`{"instances":["figlet-1"],"uncordon":true}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want := (CordonRequest{Instances: []string{"figlet-1"}, Uncordon: true}); !reflect.DeepEqual(got, want) {
+		t.Errorf("want: %+v, got: %+v", want, got)
+	}
+
+	if _, err := DecodeCordonRequest(strings.NewReader(`{"instances":["figlet-1",""]}`)); err == nil {
+		t.Errorf("want an error for an empty instance ID")
+	}
+}
+
+func Test_DrainRequest_GetTimeout(t *testing.T) {
+	if got := (DrainRequest{TimeoutSeconds: 30}).GetTimeout(); got != 30*time.Second {
+		t.Errorf("timeout, want: %s, got: %s", 30*time.Second, got)
+	}
+}
@@ -0,0 +1,36 @@
+package types
+
+// RedactedValue replaces the values of environment variables sourced from secrets.
+const RedactedValue = "***"
+
+// ResolvedEnv returns the environment a function is started with: its EnvVars, with
+// the fprocess variable set from EnvProcess when given.
+func ResolvedEnv(deployment FunctionDeployment) map[string]string {
+	env := make(map[string]string, len(deployment.EnvVars)+1)
+	for k, v := range deployment.EnvVars {
+		env[k] = v
+	}
+
+	if len(deployment.EnvProcess) > 0 {
+		env["fprocess"] = deployment.EnvProcess
+	}
+
+	return env
+}
+
+// RedactEnv returns a copy of env with the value of each variable in secretKeys
+// replaced by RedactedValue.
+func RedactEnv(env map[string]string, secretKeys []string) map[string]string {
+	redacted := make(map[string]string, len(env))
+	for k, v := range env {
+		redacted[k] = v
+	}
+
+	for _, k := range secretKeys {
+		if _, ok := redacted[k]; ok {
+			redacted[k] = RedactedValue
+		}
+	}
+
+	return redacted
+}
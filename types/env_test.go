@@ -0,0 +1,57 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolvedEnv(t *testing.T) {
+	deployment := FunctionDeployment{
+		EnvProcess: "python3 index.py",
+		EnvVars: map[string]string{
+			"fprocess":  "cat",
+			"write_ttl": "10s",
+		},
+	}
+
+	want := map[string]string{
+		"fprocess":  "python3 index.py",
+		"write_ttl": "10s",
+	}
+
+	got := ResolvedEnv(deployment)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("env, want: %v, got: %v", want, got)
+	}
+
+	if deployment.EnvVars["fprocess"] != "cat" {
+		t.Errorf("want EnvVars of the deployment to be unchanged")
+	}
+}
+
+func TestRedactEnv(t *testing.T) {
+	env := map[string]string{
+		"fprocess":     "python3 index.py",
+		"DB_PASSWORD":  "hunter2",
+		"API_TOKEN":    "abc123",
+		"LOG_LEVEL":    "debug",
+		"EMPTY_SECRET": "",
+	}
+
+	want := map[string]string{
+		"fprocess":     "python3 index.py",
+		"DB_PASSWORD":  RedactedValue,
+		"API_TOKEN":    RedactedValue,
+		"LOG_LEVEL":    "debug",
+		"EMPTY_SECRET": RedactedValue,
+	}
+
+	got := RedactEnv(env, []string{"DB_PASSWORD", "API_TOKEN", "EMPTY_SECRET", "MISSING"})
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("env, want: %v, got: %v", want, got)
+	}
+
+	if env["DB_PASSWORD"] != "hunter2" {
+		t.Errorf("want the original env to be unchanged")
+	}
+}
@@ -1,5 +1,7 @@
 package types
 
+import "fmt"
+
 // FunctionDeployment represents a request to create or update a Function.
 type FunctionDeployment struct {
 
@@ -14,6 +16,8 @@ type FunctionDeployment struct {
 
 	// EnvProcess overrides the fprocess environment variable and can be used
 	// with the watchdog
+	//
+	// Deprecated: set the "fprocess" key of EnvVars instead.
 	EnvProcess string `json:"envProcess,omitempty"`
 
 	// EnvVars can be provided to set environment variables for the function runtime.
@@ -44,12 +48,49 @@ type FunctionDeployment struct {
 	ReadOnlyRootFilesystem bool `json:"readOnlyRootFilesystem,omitempty"`
 
 	// SnapshotIds is the ID of the snapshots to be used for the function in Faasnap
-	SnapshotIds []string
+	SnapshotIds []string `json:"SnapshotIds,omitempty"`
 
 	// Language is the programming language of the function
 	Language string `json:"language,omitempty"`
 }
 
+// DeprecatedFields returns a message for each deprecated field which is set in the
+// deployment, so that clients can be warned to stop using it.
+func (f FunctionDeployment) DeprecatedFields() []string {
+	var messages []string
+	if len(f.EnvProcess) > 0 {
+		messages = append(messages, `envProcess is deprecated, set envVars["fprocess"] instead`)
+	}
+	return messages
+}
+
+// NormalizeLabels replaces the Labels and Annotations of the deployment with the result
+// of NormalizeLabels, see NormalizeLabels for the errors returned. The limits declared in
+// the labels are checked with FunctionLimitsFromLabels.
+func (f *FunctionDeployment) NormalizeLabels() error {
+	if f.Labels != nil {
+		labels, err := NormalizeLabels(*f.Labels)
+		if err != nil {
+			return fmt.Errorf("invalid labels: %w", err)
+		}
+		f.Labels = &labels
+
+		if _, err := FunctionLimitsFromLabels(labels); err != nil {
+			return fmt.Errorf("invalid labels: %w", err)
+		}
+	}
+
+	if f.Annotations != nil {
+		annotations, err := NormalizeLabels(*f.Annotations)
+		if err != nil {
+			return fmt.Errorf("invalid annotations: %w", err)
+		}
+		f.Annotations = &annotations
+	}
+
+	return nil
+}
+
 // FunctionResources Memory and CPU
 type FunctionResources struct {
 	Memory string `json:"memory,omitempty"`
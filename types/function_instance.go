@@ -0,0 +1,23 @@
+package types
+
+import "time"
+
+// FunctionInstance describes a single running instance of a function, as
+// returned by /system/function/{name}/instances
+type FunctionInstance struct {
+	// ID uniquely identifies the instance, i.e. a container or pod name
+	ID string `json:"id"`
+
+	// Address the instance can be reached on
+	Address string `json:"address,omitempty"`
+
+	// Node is the host the instance is running on
+	Node string `json:"node,omitempty"`
+
+	// State is the provider specific state of the instance, i.e. running,
+	// checkpointed or restoring
+	State string `json:"state"`
+
+	// StartedAt is the time the instance was started or restored
+	StartedAt time.Time `json:"startedAt"`
+}
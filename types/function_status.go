@@ -59,6 +59,14 @@ type FunctionStatus struct {
 	// invocations as reported by the faas-provider
 	AvailableReplicas uint64 `json:"availableReplicas,omitempty"`
 
+	// Phase is the stage the function's deployment has reached, as reported by the
+	// faas-provider
+	Phase FunctionPhase `json:"phase,omitempty"`
+
+	// FailureReason explains why the function is not running, i.e. an image which
+	// could not be pulled or a container which keeps crashing
+	FailureReason string `json:"failureReason,omitempty"`
+
 	// CreatedAt is the time read back from the faas backend's
 	// data store for when the function or its container was created.
 	CreatedAt time.Time `json:"createdAt,omitempty"`
@@ -67,8 +75,32 @@ type FunctionStatus struct {
 	// functions' replicas. Divide by AvailableReplicas for an
 	// average value per replica.
 	Usage *FunctionUsage `json:"usage,omitempty"`
+
+	// Env is the environment of the function as resolved by the faas-provider, with the
+	// values sourced from secrets redacted. It is only returned when requested with
+	// "?env=true", see httputil.EnvRequested, ResolvedEnv and RedactEnv.
+	Env map[string]string `json:"env,omitempty"`
 }
 
+// FunctionPhase is the stage a function's deployment has reached.
+type FunctionPhase string
+
+const (
+	// FunctionPhasePending is reported when the function has been accepted but has not
+	// been scheduled yet
+	FunctionPhasePending FunctionPhase = "Pending"
+
+	// FunctionPhasePulling is reported while the function's image is being pulled
+	FunctionPhasePulling FunctionPhase = "Pulling"
+
+	// FunctionPhaseRunning is reported when at least one replica is ready
+	FunctionPhaseRunning FunctionPhase = "Running"
+
+	// FunctionPhaseFailed is reported when the function can not start, the reason is
+	// given in FailureReason
+	FunctionPhaseFailed FunctionPhase = "Failed"
+)
+
 // FunctionUsage represents CPU and RAM used by all of the
 // functions' replicas.
 //
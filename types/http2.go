@@ -0,0 +1,132 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultHTTP2MaxConcurrentStreams is the number of requests a client may have in flight
+	// on one HTTP/2 connection to the API.
+	DefaultHTTP2MaxConcurrentStreams = 250
+	// DefaultHTTP2MaxReadFrameSize is the largest HTTP/2 frame read by the API and the proxy.
+	DefaultHTTP2MaxReadFrameSize = 1 << 20
+	// DefaultHTTP2StreamWindowSize is the flow-control window of each HTTP/2 request body,
+	// larger than the 1MB of the http2 package so that streamed uploads are not stalled
+	// while a handler or function reads them.
+	DefaultHTTP2StreamWindowSize = 4 << 20
+	// DefaultHTTP2ConnectionWindowSize is the flow-control window shared by the request
+	// bodies of one HTTP/2 connection.
+	DefaultHTTP2ConnectionWindowSize = 16 << 20
+	// DefaultHTTP2PingTimeout is how long a ping sent after HTTP2Config.ReadIdleTimeout may
+	// go unanswered before the connection is closed.
+	DefaultHTTP2PingTimeout = 15 * time.Second
+
+	// minHTTP2FrameSize and maxHTTP2FrameSize are the bounds of SETTINGS_MAX_FRAME_SIZE,
+	// see RFC 9113 section 6.5.2.
+	minHTTP2FrameSize = 1 << 14
+	maxHTTP2FrameSize = 1<<24 - 1
+	// minHTTP2WindowSize is the initial flow-control window of RFC 9113, smaller windows
+	// can not be advertised.
+	minHTTP2WindowSize = 65535
+)
+
+// HTTP2Config tunes HTTP/2 on the API, which is served over TLS or with
+// FaaSConfig.EnableH2C, and on the connections of the proxy to functions over h2c, see
+// FaaSConfig.ProxyH2C. Fields left at zero use the defaults.
+type HTTP2Config struct {
+	// MaxConcurrentStreams is the number of requests a client may have in flight on one
+	// connection to the API, DefaultHTTP2MaxConcurrentStreams by default.
+	MaxConcurrentStreams uint32
+	// MaxReadFrameSize is the largest frame read by the API and the proxy, between 16KB
+	// and 16MB, DefaultHTTP2MaxReadFrameSize by default.
+	MaxReadFrameSize uint32
+	// StreamWindowSize is how much of a request body a client may send to the API before
+	// it is read, DefaultHTTP2StreamWindowSize by default.
+	StreamWindowSize int32
+	// ConnectionWindowSize is how much of the request bodies of one connection a client may
+	// send before they are read, DefaultHTTP2ConnectionWindowSize by default. It should be
+	// a multiple of StreamWindowSize, so that concurrent uploads do not stall each other.
+	ConnectionWindowSize int32
+	// ReadIdleTimeout, when set, is how long a connection of the proxy to a function may
+	// receive no frames before it is checked with a ping, so that a connection to a
+	// function which went away is closed rather than reused.
+	ReadIdleTimeout time.Duration
+	// PingTimeout is how long the ping sent after ReadIdleTimeout may go unanswered before
+	// the connection is closed, DefaultHTTP2PingTimeout by default.
+	PingTimeout time.Duration
+}
+
+// Validate checks that the frame size and windows are within the bounds of the HTTP/2
+// specification and that the timeouts are not negative.
+func (c *HTTP2Config) Validate() error {
+	if c.MaxReadFrameSize != 0 && (c.MaxReadFrameSize < minHTTP2FrameSize || c.MaxReadFrameSize > maxHTTP2FrameSize) {
+		return fmt.Errorf("invalid HTTP2 MaxReadFrameSize %d: must be between %d and %d", c.MaxReadFrameSize, minHTTP2FrameSize, maxHTTP2FrameSize)
+	}
+
+	if c.StreamWindowSize != 0 && c.StreamWindowSize < minHTTP2WindowSize {
+		return fmt.Errorf("invalid HTTP2 StreamWindowSize %d: must be at least %d", c.StreamWindowSize, minHTTP2WindowSize)
+	}
+
+	if c.ConnectionWindowSize != 0 && c.ConnectionWindowSize < minHTTP2WindowSize {
+		return fmt.Errorf("invalid HTTP2 ConnectionWindowSize %d: must be at least %d", c.ConnectionWindowSize, minHTTP2WindowSize)
+	}
+
+	if c.ConnectionWindowSize != 0 && c.ConnectionWindowSize < c.GetStreamWindowSize() {
+		return fmt.Errorf("invalid HTTP2 ConnectionWindowSize %d: must not be smaller than StreamWindowSize %d", c.ConnectionWindowSize, c.GetStreamWindowSize())
+	}
+
+	if c.ReadIdleTimeout < 0 {
+		return fmt.Errorf("invalid HTTP2 ReadIdleTimeout %s: must not be negative", c.ReadIdleTimeout)
+	}
+
+	if c.PingTimeout < 0 {
+		return fmt.Errorf("invalid HTTP2 PingTimeout %s: must not be negative", c.PingTimeout)
+	}
+
+	return nil
+}
+
+// GetMaxConcurrentStreams returns MaxConcurrentStreams, or its default when it is not set.
+func (c *HTTP2Config) GetMaxConcurrentStreams() uint32 {
+	if c.MaxConcurrentStreams == 0 {
+		return DefaultHTTP2MaxConcurrentStreams
+	}
+	return c.MaxConcurrentStreams
+}
+
+// GetMaxReadFrameSize returns MaxReadFrameSize, or its default when it is not set.
+func (c *HTTP2Config) GetMaxReadFrameSize() uint32 {
+	if c.MaxReadFrameSize == 0 {
+		return DefaultHTTP2MaxReadFrameSize
+	}
+	return c.MaxReadFrameSize
+}
+
+// GetStreamWindowSize returns StreamWindowSize, or its default when it is not set.
+func (c *HTTP2Config) GetStreamWindowSize() int32 {
+	if c.StreamWindowSize == 0 {
+		return DefaultHTTP2StreamWindowSize
+	}
+	return c.StreamWindowSize
+}
+
+// GetConnectionWindowSize returns ConnectionWindowSize, or its default when it is not set.
+// The default is raised to StreamWindowSize when that is larger.
+func (c *HTTP2Config) GetConnectionWindowSize() int32 {
+	if c.ConnectionWindowSize == 0 {
+		if stream := c.GetStreamWindowSize(); stream > DefaultHTTP2ConnectionWindowSize {
+			return stream
+		}
+		return DefaultHTTP2ConnectionWindowSize
+	}
+	return c.ConnectionWindowSize
+}
+
+// GetPingTimeout returns PingTimeout, or its default when it is not set.
+func (c *HTTP2Config) GetPingTimeout() time.Duration {
+	if c.PingTimeout == 0 {
+		return DefaultHTTP2PingTimeout
+	}
+	return c.PingTimeout
+}
@@ -0,0 +1,54 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_HTTP2Config_Validate(t *testing.T) {
+	cases := []struct {
+		name    string
+		config  HTTP2Config
+		wantErr bool
+	}{
+		{"defaults", HTTP2Config{}, false},
+		{"tuned for streaming", HTTP2Config{MaxReadFrameSize: 1 << 20, StreamWindowSize: 8 << 20, ConnectionWindowSize: 64 << 20}, false},
+		{"frame size below minimum", HTTP2Config{MaxReadFrameSize: 1024}, true},
+		{"frame size above maximum", HTTP2Config{MaxReadFrameSize: 1 << 24}, true},
+		{"stream window below minimum", HTTP2Config{StreamWindowSize: 1024}, true},
+		{"connection window below minimum", HTTP2Config{ConnectionWindowSize: 1024}, true},
+		{"connection window smaller than stream window", HTTP2Config{StreamWindowSize: 8 << 20, ConnectionWindowSize: 4 << 20}, true},
+		{"connection window smaller than default stream window", HTTP2Config{ConnectionWindowSize: 1 << 20}, true},
+		{"negative read idle timeout", HTTP2Config{ReadIdleTimeout: -time.Second}, true},
+		{"negative ping timeout", HTTP2Config{PingTimeout: -time.Second}, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			if (err != nil) != tc.wantErr {
+				t.Errorf("error, want: %v, got: %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_HTTP2Config_GetConnectionWindowSize(t *testing.T) {
+	cases := []struct {
+		name   string
+		config HTTP2Config
+		want   int32
+	}{
+		{"default", HTTP2Config{}, DefaultHTTP2ConnectionWindowSize},
+		{"raised to a larger stream window", HTTP2Config{StreamWindowSize: 32 << 20}, 32 << 20},
+		{"set", HTTP2Config{ConnectionWindowSize: 8 << 20}, 8 << 20},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.config.GetConnectionWindowSize(); got != tc.want {
+				t.Errorf("connection window, want: %d, got: %d", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/openfaas/faas-provider/httputil"
+)
+
+// ErrorResponse is the body written by WriteJSONError, the same httputil.ErrorResponse the
+// provider writes for its own errors, so that clients parse a single shape.
+type ErrorResponse = httputil.ErrorResponse
+
+// WriteJSON writes v as a JSON body with the status code, see httputil.WriteJSON.
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) error {
+	return httputil.WriteJSON(w, status, v)
+}
+
+// WriteJSONError writes the formatted message as an ErrorResponse with the status code,
+// i.e. {"code": 404, "message": "function figlet not found"}, so that every provider reports
+// errors from its handlers in the same shape. Use httputil.WriteError when the request is at
+// hand, to carry its ID.
+func WriteJSONError(w http.ResponseWriter, status int, format string, args ...interface{}) error {
+	return WriteJSON(w, status, ErrorResponse{Code: status, Message: fmt.Sprintf(format, args...)})
+}
+
+// ReadJSON decodes the JSON body of r into v. It applies no limit itself: Serve wraps the
+// body of the handlers which read one, such as DeployFunction, ScaleFunction and Secrets,
+// with http.MaxBytesReader at FaaSConfig.MaxRequestBodyBytes, other callers should do the
+// same. A body over that limit returns an error which wraps an *http.MaxBytesError, for
+// which the handler should write a 413, see StatusForReadJSONError.
+func ReadJSON(r *http.Request, v interface{}) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		return fmt.Errorf("unable to decode request: empty body")
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		if err == io.EOF {
+			return fmt.Errorf("unable to decode request: empty body")
+		}
+		return fmt.Errorf("unable to decode request: %w", err)
+	}
+
+	return nil
+}
+
+// StatusForReadJSONError returns the status code for an error from ReadJSON, a 413 when
+// the body was too large, otherwise a 400.
+func StatusForReadJSONError(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
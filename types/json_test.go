@@ -0,0 +1,87 @@
+package types
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteJSONError(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := WriteJSONError(w, http.StatusNotFound, "function %s not found", "figlet"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status code, want: %d, got: %d", http.StatusNotFound, w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type, want: %s, got: %s", "application/json", got)
+	}
+
+	want := `{"code":404,"message":"function figlet not found"}`
+	if got := w.Body.String(); got != want {
+		t.Errorf("body, want: %s, got: %s", want, got)
+	}
+}
+
+func TestReadJSON(t *testing.T) {
+	testCases := []struct {
+		name       string
+		body       string
+		limit      int64
+		wantErr    bool
+		wantStatus int
+	}{
+		{name: "valid", body: `{"serviceName":"figlet","replicas":2}`},
+		{name: "empty body", body: "", wantErr: true, wantStatus: http.StatusBadRequest},
+		{name: "invalid json", body: `{"serviceName":`, wantErr: true, wantStatus: http.StatusBadRequest},
+		{name: "too large", body: `{"serviceName":"figlet","replicas":2}`, limit: 8, wantErr: true, wantStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/system/scale-function/figlet", strings.NewReader(tc.body))
+			if tc.limit > 0 {
+				r.Body = http.MaxBytesReader(w, r.Body, tc.limit)
+			}
+
+			req := ScaleServiceRequest{}
+			err := ReadJSON(r, &req)
+			if !tc.wantErr {
+				if err != nil {
+					t.Fatalf("want no error, got: %s", err)
+				}
+				if req.ServiceName != "figlet" || req.Replicas != 2 {
+					t.Errorf("request, want: figlet with 2 replicas, got: %+v", req)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("want error")
+			}
+			if got := StatusForReadJSONError(err); got != tc.wantStatus {
+				t.Errorf("status, want: %d, got: %d", tc.wantStatus, got)
+			}
+		})
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := WriteJSON(w, http.StatusOK, FunctionStatus{Name: "figlet"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	got := FunctionStatus{}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unable to decode body: %s", err)
+	}
+	if got.Name != "figlet" {
+		t.Errorf("name, want: %s, got: %s", "figlet", got.Name)
+	}
+}
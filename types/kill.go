@@ -0,0 +1,83 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// KillRequest selects the instances killed by "/danger/kill". An empty request kills every
+// instance managed by the provider.
+type KillRequest struct {
+	// Namespace limits the instances killed to those in the namespace, if supported by
+	// the faas-provider
+	Namespace string `json:"namespace,omitempty"`
+
+	// Function limits the instances killed to those of the function
+	Function string `json:"function,omitempty"`
+
+	// Instances limits the instances killed to those with the given IDs, as returned by
+	// the FunctionInstances handler, it requires Function
+	Instances []string `json:"instances,omitempty"`
+}
+
+// KillResponse is returned by "/danger/kill" once the instances have been killed.
+type KillResponse struct {
+	// Killed is the number of instances which were killed
+	Killed int `json:"killed"`
+
+	// Namespace the instances were killed in, empty when not limited to a namespace
+	Namespace string `json:"namespace,omitempty"`
+
+	// Function the instances were killed for, empty when not limited to a function
+	Function string `json:"function,omitempty"`
+}
+
+// Validate checks that instances are only given together with the function they belong to.
+func (k KillRequest) Validate() error {
+	if len(k.Instances) > 0 && len(k.Function) == 0 {
+		return fmt.Errorf("function is required when instances are given")
+	}
+
+	for _, id := range k.Instances {
+		if len(id) == 0 {
+			return fmt.Errorf("instances must not contain an empty ID")
+		}
+	}
+
+	return nil
+}
+
+// DecodeKillRequest reads the KillRequest sent to "/danger/kill" from the body, when there
+// is one, and the "namespace" query string parameter, then validates it. The function may
+// also be given in the "function" query string parameter, and a single instance in the
+// "instance" query string parameter.
+func DecodeKillRequest(r *http.Request) (KillRequest, error) {
+	req := KillRequest{}
+	if r.Body != nil {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			return req, fmt.Errorf("unable to decode kill request: %w", err)
+		}
+	}
+
+	// The namespace must be in the query string, where it is checked against
+	// FaaSConfig.AllowedNamespaces before the handler is called.
+	query := r.URL.Query()
+	if len(req.Namespace) > 0 && req.Namespace != query.Get("namespace") {
+		return req, fmt.Errorf("namespace %q must be given in the namespace query string parameter", req.Namespace)
+	}
+	req.Namespace = query.Get("namespace")
+	if len(req.Function) == 0 {
+		req.Function = query.Get("function")
+	}
+	if instance := query.Get("instance"); len(req.Instances) == 0 && len(instance) > 0 {
+		req.Instances = []string{instance}
+	}
+
+	if err := req.Validate(); err != nil {
+		return req, err
+	}
+
+	return req, nil
+}
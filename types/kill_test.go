@@ -0,0 +1,51 @@
+package types
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_DecodeKillRequest(t *testing.T) {
+	testCases := []struct {
+		name    string
+		target  string
+		body    string
+		want    KillRequest
+		wantErr string
+	}{
+		{name: "empty request", target: "/danger/kill", want: KillRequest{}},
+		{name: "query string", target: "/danger/kill?namespace=openfaas-fn&function=figlet", want: KillRequest{Namespace: "openfaas-fn", Function: "figlet"}},
+		{name: "body", target: "/danger/kill?namespace=openfaas-fn", body: `{"namespace":"openfaas-fn","function":"figlet","instances":["figlet-1"]}`,
+			want: KillRequest{Namespace: "openfaas-fn", Function: "figlet", Instances: []string{"figlet-1"}}},
+		{name: "instance in query string", target: "/danger/kill?namespace=openfaas-fn&function=figlet&instance=figlet-2",
+			want: KillRequest{Namespace: "openfaas-fn", Function: "figlet", Instances: []string{"figlet-2"}}},
+		{name: "instance without function", target: "/danger/kill?instance=figlet-2", wantErr: "function is required"},
+		{name: "namespace only in body", target: "/danger/kill", body: `{"namespace":"kube-system"}`, wantErr: "must be given in the namespace query string parameter"},
+		{name: "instances without function", target: "/danger/kill", body: `{"instances":["figlet-1"]}`, wantErr: "function is required"},
+		{name: "empty instance", target: "/danger/kill", body: `{"function":"figlet","instances":[""]}`, wantErr: "empty ID"},
+		{name: "invalid body", target: "/danger/kill", body: `{`, wantErr: "unable to decode kill request"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, tc.target, strings.NewReader(tc.body))
+
+			got, err := DecodeKillRequest(r)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("error, want: %q, got: %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want no error, got: %s", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("request, want: %+v, got: %+v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FunctionTimeoutLabel is the label used by a function to declare how long its invocations
+// may run for, as a Go duration such as "2m30s" or a number of seconds.
+const FunctionTimeoutLabel = "com.openfaas.function.timeout"
+
+// FunctionTimeoutFromLabels returns the timeout declared by the FunctionTimeoutLabel of a
+// function. The bool is false when the label is not set, and an error is returned when it
+// can not be parsed or is not positive.
+func FunctionTimeoutFromLabels(labels map[string]string) (time.Duration, bool, error) {
+	value, ok := labels[FunctionTimeoutLabel]
+	if !ok {
+		return 0, false, nil
+	}
+
+	return parseFunctionTimeout(FunctionTimeoutLabel, value)
+}
+
+// parseFunctionTimeout parses the value of the timeout label or annotation key.
+func parseFunctionTimeout(key, value string) (time.Duration, bool, error) {
+	value = strings.TrimSpace(value)
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			return 0, false, fmt.Errorf("invalid %s %q: must be a duration or a number of seconds", key, value)
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	if timeout <= 0 {
+		return 0, false, fmt.Errorf("invalid %s %q: must be greater than zero", key, value)
+	}
+
+	return timeout, true, nil
+}
+
+const (
+	// MaxInflightLabel is the label used by a function to cap the number of its invocations
+	// served at once, further invocations are rejected with a 429.
+	MaxInflightLabel = "com.openfaas.limits.max_inflight"
+
+	// RequestsPerSecondLabel is the label used by a function to cap the rate of its
+	// invocations, further invocations are rejected with a 429. It may be fractional, such
+	// as "0.5" for one request every two seconds.
+	RequestsPerSecondLabel = "com.openfaas.limits.requests_per_second"
+)
+
+// LabelResolver looks up the labels of a function by the name it is invoked with, it has
+// the same method as proxy.LabelResolver so that a provider's resolver can implement both.
+type LabelResolver interface {
+	ResolveLabels(functionName string) (map[string]string, error)
+}
+
+// FunctionLimits are the limits a function declares on its invocations with
+// MaxInflightLabel and RequestsPerSecondLabel, a value of 0 means unlimited.
+type FunctionLimits struct {
+	MaxInflight       int
+	RequestsPerSecond float64
+}
+
+// FunctionLimitsFromLabels returns the limits declared by the labels of a function, an
+// error is returned when a limit can not be parsed or is not positive.
+func FunctionLimitsFromLabels(labels map[string]string) (FunctionLimits, error) {
+	limits := FunctionLimits{}
+
+	if value, ok := labels[MaxInflightLabel]; ok {
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n <= 0 {
+			return FunctionLimits{}, fmt.Errorf("invalid %s %q: must be a whole number greater than zero", MaxInflightLabel, value)
+		}
+		limits.MaxInflight = n
+	}
+
+	if value, ok := labels[RequestsPerSecondLabel]; ok {
+		rps, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || rps <= 0 || math.IsInf(rps, 0) || math.IsNaN(rps) {
+			return FunctionLimits{}, fmt.Errorf("invalid %s %q: must be a number greater than zero", RequestsPerSecondLabel, value)
+		}
+		limits.RequestsPerSecond = rps
+	}
+
+	return limits, nil
+}
+
+// labelKeyExpression matches a label or annotation key, an optional DNS style prefix and a
+// "/" followed by a name, i.e. "com.openfaas.scale.min" or "example.com/team". The prefix
+// and name must start and end with a letter or digit and may contain "-", "_" and ".".
+var labelKeyExpression = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?/)?[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+
+// NormalizeLabels returns a copy of the labels or annotations of a function with the
+// whitespace trimmed from each key. An error is returned when a key is not valid, or when
+// two keys are the same after trimming, ignoring case, such as "Team" and "team ", as
+// providers would otherwise keep either one of them.
+func NormalizeLabels(labels map[string]string) (map[string]string, error) {
+	if labels == nil {
+		return nil, nil
+	}
+
+	normalized := make(map[string]string, len(labels))
+	seen := make(map[string]string, len(labels))
+
+	for key, value := range labels {
+		k := strings.TrimSpace(key)
+		if !labelKeyExpression.MatchString(k) {
+			return nil, fmt.Errorf("invalid key %q: must be an optional prefix and \"/\" followed by a name of letters, digits, \"-\", \"_\" or \".\"", key)
+		}
+
+		folded := strings.ToLower(k)
+		if other, ok := seen[folded]; ok {
+			return nil, fmt.Errorf("duplicate key %q: conflicts with %q", key, other)
+		}
+		seen[folded] = key
+
+		normalized[k] = value
+	}
+
+	return normalized, nil
+}
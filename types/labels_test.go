@@ -0,0 +1,111 @@
+package types
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFunctionTimeoutFromLabels(t *testing.T) {
+	testCases := []struct {
+		name    string
+		labels  map[string]string
+		want    time.Duration
+		wantOK  bool
+		wantErr bool
+	}{
+		{name: "no labels"},
+		{name: "other labels", labels: map[string]string{"com.openfaas.scale.min": "1"}},
+		{name: "duration", labels: map[string]string{FunctionTimeoutLabel: "2m30s"}, want: 150 * time.Second, wantOK: true},
+		{name: "seconds", labels: map[string]string{FunctionTimeoutLabel: "90"}, want: 90 * time.Second, wantOK: true},
+		{name: "invalid", labels: map[string]string{FunctionTimeoutLabel: "soon"}, wantErr: true},
+		{name: "zero", labels: map[string]string{FunctionTimeoutLabel: "0s"}, wantErr: true},
+		{name: "negative", labels: map[string]string{FunctionTimeoutLabel: "-10"}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok, err := FunctionTimeoutFromLabels(tc.labels)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error, want: %t, got: %v", tc.wantErr, err)
+			}
+
+			if ok != tc.wantOK {
+				t.Errorf("ok, want: %t, got: %t", tc.wantOK, ok)
+			}
+
+			if got != tc.want {
+				t.Errorf("timeout, want: %s, got: %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestFunctionLimitsFromLabels(t *testing.T) {
+	testCases := []struct {
+		name    string
+		labels  map[string]string
+		want    FunctionLimits
+		wantErr bool
+	}{
+		{name: "no labels"},
+		{name: "max inflight", labels: map[string]string{MaxInflightLabel: "10"}, want: FunctionLimits{MaxInflight: 10}},
+		{name: "requests per second", labels: map[string]string{RequestsPerSecondLabel: " 0.5 "}, want: FunctionLimits{RequestsPerSecond: 0.5}},
+		{name: "both", labels: map[string]string{MaxInflightLabel: "2", RequestsPerSecondLabel: "100"}, want: FunctionLimits{MaxInflight: 2, RequestsPerSecond: 100}},
+		{name: "fractional max inflight", labels: map[string]string{MaxInflightLabel: "1.5"}, wantErr: true},
+		{name: "zero max inflight", labels: map[string]string{MaxInflightLabel: "0"}, wantErr: true},
+		{name: "negative requests per second", labels: map[string]string{RequestsPerSecondLabel: "-1"}, wantErr: true},
+		{name: "infinite requests per second", labels: map[string]string{RequestsPerSecondLabel: "+Inf"}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := FunctionLimitsFromLabels(tc.labels)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error, want: %t, got: %v", tc.wantErr, err)
+			}
+
+			if got != tc.want {
+				t.Errorf("limits, want: %+v, got: %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNormalizeLabels(t *testing.T) {
+	testCases := []struct {
+		name    string
+		labels  map[string]string
+		want    map[string]string
+		wantErr string
+	}{
+		{name: "nil"},
+		{name: "valid", labels: map[string]string{"com.openfaas.scale.min": "1", "example.com/team": "payments"}, want: map[string]string{"com.openfaas.scale.min": "1", "example.com/team": "payments"}},
+		{name: "whitespace trimmed", labels: map[string]string{" team\t": "payments"}, want: map[string]string{"team": "payments"}},
+		{name: "duplicate after trimming", labels: map[string]string{"team": "a", "team ": "b"}, wantErr: "duplicate key"},
+		{name: "duplicate ignoring case", labels: map[string]string{"Team": "a", "team": "b"}, wantErr: "duplicate key"},
+		{name: "empty key", labels: map[string]string{" ": "a"}, wantErr: `invalid key " "`},
+		{name: "invalid character", labels: map[string]string{"team name": "a"}, wantErr: `invalid key "team name"`},
+		{name: "two prefixes", labels: map[string]string{"a/b/c": "a"}, wantErr: `invalid key "a/b/c"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NormalizeLabels(tc.labels)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("error, want: %q, got: %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want no error, got: %s", err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("labels, want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package types
+
+import "time"
+
+// LifecycleEventType is the kind of change made to a function
+type LifecycleEventType string
+
+const (
+	// FunctionCreated is emitted after a function was deployed
+	FunctionCreated LifecycleEventType = "created"
+
+	// FunctionUpdated is emitted after a function was updated
+	FunctionUpdated LifecycleEventType = "updated"
+
+	// FunctionDeleted is emitted after a function was deleted
+	FunctionDeleted LifecycleEventType = "deleted"
+
+	// FunctionScaled is emitted after the replicas of a function were changed
+	FunctionScaled LifecycleEventType = "scaled"
+)
+
+// LifecycleEvent describes a successful change to a function, it is passed to
+// FaaSConfig.LifecycleHook.
+type LifecycleEvent struct {
+	// Type is the kind of change which was made
+	Type LifecycleEventType `json:"type"`
+
+	// Name of the function
+	Name string `json:"name"`
+
+	// Namespace of the function, if supported by the faas-provider
+	Namespace string `json:"namespace,omitempty"`
+
+	// Replicas requested, only set for FunctionScaled
+	Replicas uint64 `json:"replicas,omitempty"`
+
+	// Timestamp is the time the change completed
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// FunctionEvent is streamed from "/system/functions/watch" when a function is added,
+// changed or removed, with Type set to FunctionCreated, FunctionUpdated or FunctionDeleted.
+type FunctionEvent struct {
+	// Type is the kind of change which was made
+	Type LifecycleEventType `json:"type"`
+
+	// Function is the status of the function after the change, or before it for
+	// FunctionDeleted
+	Function FunctionStatus `json:"function"`
+
+	// Timestamp is the time the change was observed
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// WatchEventType is the kind of change streamed by the watch of the function events
+// published on FaaSConfig.Events.
+type WatchEventType string
+
+const (
+	// WatchAdded is streamed after a function was deployed
+	WatchAdded WatchEventType = "added"
+
+	// WatchModified is streamed after a function was updated or scaled
+	WatchModified WatchEventType = "modified"
+
+	// WatchDeleted is streamed after a function was deleted
+	WatchDeleted WatchEventType = "deleted"
+
+	// WatchDrain is streamed as an instance of a function is cordoned, drained and
+	// terminated, with the Instance and its Stage
+	WatchDrain WatchEventType = "drain"
+)
+
+// WatchEvent is streamed from "/system/functions/watch" as a line of JSON when the
+// provider does not set FaaSHandlers.WatchFunctions and FaaSConfig.Events is set. A client
+// which reconnects passes the ResourceVersion of the last event it received in the
+// "resourceVersion" query string parameter to be sent the events it missed.
+type WatchEvent struct {
+	// Type is the kind of change which was made
+	Type WatchEventType `json:"type"`
+
+	// ResourceVersion orders the events of a provider, it is a number which increases with
+	// each event
+	ResourceVersion string `json:"resourceVersion"`
+
+	// Name of the function
+	Name string `json:"name"`
+
+	// Namespace of the function, if supported by the faas-provider
+	Namespace string `json:"namespace,omitempty"`
+
+	// Replicas requested, only set when the function was scaled
+	Replicas *uint64 `json:"replicas,omitempty"`
+
+	// Instance is the ID of the instance, only set for a WatchDrain
+	Instance string `json:"instance,omitempty"`
+
+	// Stage the instance has reached, only set for a WatchDrain
+	Stage DrainStage `json:"stage,omitempty"`
+
+	// Timestamp is the time the change completed
+	Timestamp time.Time `json:"timestamp"`
+}
@@ -0,0 +1,56 @@
+package types
+
+import "time"
+
+// MetricResetResult is returned by DELETE /system/metrics once the provider has
+// reset its per-function invocation counters.
+type MetricResetResult struct {
+	// Function is the function whose counters were reset, empty when the
+	// counters of every function were reset
+	Function string `json:"function,omitempty"`
+
+	// Namespace of the function, if supported by the faas-provider
+	Namespace string `json:"namespace,omitempty"`
+
+	// Instance is the instance whose counters were reset, empty when the counters of
+	// every instance were reset
+	Instance string `json:"instance,omitempty"`
+
+	// Reset is the number of counters which were reset
+	Reset int `json:"reset"`
+
+	// ResetAt is the time the counters were reset
+	ResetAt time.Time `json:"resetAt"`
+}
+
+// InstanceMetrics is returned in a list by GET /system/metrics, one for each function
+// instance managed by the provider.
+type InstanceMetrics struct {
+	// Function is the name of the function the instance belongs to
+	Function string `json:"function"`
+
+	// Namespace of the function, if supported by the faas-provider
+	Namespace string `json:"namespace,omitempty"`
+
+	// Instance is the ID of the instance, as returned by the FunctionInstances handler
+	Instance string `json:"instance"`
+
+	// Invocations is the number of requests served by the instance since it started
+	Invocations int64 `json:"invocations"`
+
+	// InFlight is the number of requests the instance is serving
+	InFlight int64 `json:"inFlight"`
+
+	// CPUSeconds is the CPU time used by the instance
+	CPUSeconds float64 `json:"cpuSeconds"`
+
+	// MemoryBytes is the memory used by the instance
+	MemoryBytes int64 `json:"memoryBytes"`
+
+	// RestoredFrom is the ID of the checkpoint the instance was restored from, empty
+	// when it was cold started
+	RestoredFrom string `json:"restoredFrom,omitempty"`
+
+	// StartedAt is the time the instance became available
+	StartedAt time.Time `json:"startedAt"`
+}
@@ -0,0 +1,143 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// MergePatchContentType is the media type of a JSON merge patch, see RFC 7386, which is
+// the body of PATCH "/system/functions".
+const MergePatchContentType = "application/merge-patch+json"
+
+// ErrFunctionNotFound should be wrapped by the errors of FaaSHandlers.ReadDeployment for a
+// function which does not exist, for PATCH "/system/functions" to respond with a 404.
+var ErrFunctionNotFound = errors.New("function not found")
+
+// MergePatch applies the JSON merge patch patch to the JSON document original, as defined
+// by RFC 7386: the members of an object in patch replace those of original, a member set
+// to null removes it, and any other value, including an array, replaces the original
+// value as a whole.
+func MergePatch(original, patch []byte) ([]byte, error) {
+	var target interface{}
+	if len(bytes.TrimSpace(original)) > 0 {
+		if err := decodeJSONNumbers(original, &target); err != nil {
+			return nil, fmt.Errorf("unable to decode document: %w", err)
+		}
+	}
+
+	var changes interface{}
+	if err := decodeJSONNumbers(patch, &changes); err != nil {
+		return nil, fmt.Errorf("unable to decode merge patch: %w", err)
+	}
+
+	return json.Marshal(mergeValue(target, changes))
+}
+
+// mergeValue is the MergePatch algorithm of RFC 7386, section 2.
+func mergeValue(target, patch interface{}) interface{} {
+	changes, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	merged, ok := target.(map[string]interface{})
+	if !ok {
+		merged = map[string]interface{}{}
+	}
+	for key, value := range changes {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		merged[key] = mergeValue(merged[key], value)
+	}
+	return merged
+}
+
+// decodeJSONNumbers decodes data into v keeping numbers as json.Number, so that they are
+// not rounded through a float64.
+func decodeJSONNumbers(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// FunctionPatch is a merge patch sent to PATCH "/system/functions", for the function named
+// by the "service" and "namespace" members of the patch.
+type FunctionPatch struct {
+	// Service is the name of the function to patch
+	Service string
+
+	// Namespace of the function, if supported by the faas-provider
+	Namespace string
+
+	// Patch is the merge patch, to be applied with FunctionDeployment.ApplyPatch
+	Patch []byte
+}
+
+// ParseFunctionPatch reads a FunctionPatch from body, which must be a JSON object with a
+// "service" member.
+func ParseFunctionPatch(body []byte) (FunctionPatch, error) {
+	target := struct {
+		Service   *string `json:"service"`
+		Namespace *string `json:"namespace"`
+	}{}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(body, &object); err != nil || object == nil {
+		return FunctionPatch{}, fmt.Errorf("unable to decode merge patch: must be a JSON object")
+	}
+	if err := json.Unmarshal(body, &target); err != nil {
+		return FunctionPatch{}, fmt.Errorf("unable to decode merge patch: %w", err)
+	}
+	if target.Service == nil || len(*target.Service) == 0 {
+		return FunctionPatch{}, fmt.Errorf("invalid merge patch: service must be set")
+	}
+
+	patch := FunctionPatch{Service: *target.Service, Patch: body}
+	if target.Namespace != nil {
+		patch.Namespace = *target.Namespace
+	}
+	return patch, nil
+}
+
+// DecodeFunctionPatch reads the FunctionPatch from the body of a PATCH
+// "/system/functions" request, see ParseFunctionPatch.
+func DecodeFunctionPatch(r *http.Request) (FunctionPatch, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return FunctionPatch{}, fmt.Errorf("unable to decode merge patch: empty body")
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return FunctionPatch{}, fmt.Errorf("unable to decode merge patch: %w", err)
+	}
+	return ParseFunctionPatch(body)
+}
+
+// ApplyPatch returns the deployment with the merge patch applied, for a provider to update
+// the function with. Members which are not part of a FunctionDeployment are rejected, so
+// that a misspelled field is not silently ignored.
+func (f FunctionDeployment) ApplyPatch(patch []byte) (FunctionDeployment, error) {
+	original, err := json.Marshal(f)
+	if err != nil {
+		return FunctionDeployment{}, err
+	}
+
+	merged, err := MergePatch(original, patch)
+	if err != nil {
+		return FunctionDeployment{}, err
+	}
+
+	patched := FunctionDeployment{}
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patched); err != nil {
+		return FunctionDeployment{}, fmt.Errorf("invalid merge patch: %w", err)
+	}
+	return patched, nil
+}
@@ -0,0 +1,114 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	// The examples of RFC 7386, appendix A.
+	testCases := []struct {
+		original string
+		patch    string
+		want     string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{`{"n":12345678901234567890}`, `{}`, `{"n":12345678901234567890}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.original+" "+tc.patch, func(t *testing.T) {
+			got, err := MergePatch([]byte(tc.original), []byte(tc.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("want: %s, got: %s", tc.want, got)
+			}
+		})
+	}
+
+	if _, err := MergePatch([]byte(`{}`), []byte(`{`)); err == nil {
+		t.Errorf("want an error for an invalid patch")
+	}
+}
+
+func TestFunctionDeployment_ApplyPatch(t *testing.T) {
+	labels := map[string]string{"team": "a", "tier": "gold"}
+	f := FunctionDeployment{
+		Service: "figlet",
+		Image:   "ghcr.io/openfaas/figlet:latest",
+		Labels:  &labels,
+		EnvVars: map[string]string{"mode": "fast"},
+	}
+
+	patched, err := f.ApplyPatch([]byte(`{"service":"figlet","labels":{"tier":null,"owner":"b"},"annotations":{"topic":"faas"},"envVars":null}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if patched.Image != f.Image {
+		t.Errorf("image, want: %s, got: %s", f.Image, patched.Image)
+	}
+	if want := map[string]string{"team": "a", "owner": "b"}; patched.Labels == nil || !reflect.DeepEqual(*patched.Labels, want) {
+		t.Errorf("labels, want: %v, got: %v", want, patched.Labels)
+	}
+	if want := map[string]string{"topic": "faas"}; patched.Annotations == nil || !reflect.DeepEqual(*patched.Annotations, want) {
+		t.Errorf("annotations, want: %v, got: %v", want, patched.Annotations)
+	}
+	if patched.EnvVars != nil {
+		t.Errorf("envVars, want: nil, got: %v", patched.EnvVars)
+	}
+	if len(labels) != 2 || labels["tier"] != "gold" {
+		t.Errorf("want the original labels to be unchanged, got: %v", labels)
+	}
+
+	if _, err := f.ApplyPatch([]byte(`{"imgae":"figlet:2"}`)); err == nil {
+		t.Errorf("want an error for an unknown field")
+	}
+}
+
+func TestParseFunctionPatch(t *testing.T) {
+	testCases := []struct {
+		name    string
+		body    string
+		want    FunctionPatch
+		wantErr bool
+	}{
+		{name: "service and namespace", body: `{"service":"figlet","namespace":"dev","image":"figlet:2"}`, want: FunctionPatch{Service: "figlet", Namespace: "dev"}},
+		{name: "service", body: `{"service":"figlet"}`, want: FunctionPatch{Service: "figlet"}},
+		{name: "no service", body: `{"image":"figlet:2"}`, wantErr: true},
+		{name: "not an object", body: `["figlet"]`, wantErr: true},
+		{name: "null", body: `null`, wantErr: true},
+		{name: "invalid service", body: `{"service":1}`, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseFunctionPatch([]byte(tc.body))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error, want: %v, got: %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if got.Service != tc.want.Service || got.Namespace != tc.want.Namespace || string(got.Patch) != tc.body {
+				t.Errorf("want: %+v, got: %+v", tc.want, got)
+			}
+		})
+	}
+}
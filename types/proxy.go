@@ -0,0 +1,57 @@
+package types
+
+import "time"
+
+// ProxyState is returned from "/system/proxy/state" to inspect the internal state of the
+// function proxy.
+type ProxyState struct {
+	// Timeouts are the timeouts read from the labels of functions which are cached
+	Timeouts []ProxyTimeoutState `json:"timeouts"`
+
+	// Instances are the function instances which requests are being balanced across
+	Instances []ProxyInstanceState `json:"instances"`
+
+	// Circuits are the circuit breakers of the functions which have failed, when
+	// FaaSConfig.ProxyCircuitBreaker is set
+	Circuits []ProxyCircuitState `json:"circuits,omitempty"`
+}
+
+// ProxyTimeoutState is the cached timeout of a function.
+type ProxyTimeoutState struct {
+	Function string `json:"function"`
+
+	// Timeout applied to invocations of the function, i.e. "1m30s"
+	Timeout string `json:"timeout"`
+
+	// ExpiresAt is when the function's labels will be looked up again
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// ProxyInstanceState is the load on, and the last failure of, an instance of a function.
+type ProxyInstanceState struct {
+	Address string `json:"address"`
+
+	// Active is the number of requests in flight to the instance
+	Active int `json:"active"`
+
+	// FailedAt is when the last request to the instance failed, if it did
+	FailedAt *time.Time `json:"failedAt,omitempty"`
+
+	// CoolingDown is true while the instance is skipped because it failed recently
+	CoolingDown bool `json:"coolingDown"`
+}
+
+// ProxyCircuitState is the circuit breaker of a function.
+type ProxyCircuitState struct {
+	Function string `json:"function"`
+
+	// State is "closed", "open" or "half-open" while an invocation is let through to find
+	// out whether the function recovered
+	State string `json:"state"`
+
+	// Failures is the number of invocations in a row which failed
+	Failures int `json:"failures"`
+
+	// OpenedAt is when the circuit opened, if it is open
+	OpenedAt *time.Time `json:"openedAt,omitempty"`
+}
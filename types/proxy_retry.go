@@ -0,0 +1,112 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	defaultRetryMaxAttempts    = 3
+	defaultRetryInitialBackoff = 100 * time.Millisecond
+	defaultRetryMaxBackoff     = 2 * time.Second
+
+	defaultBreakerFailureThreshold = 5
+	defaultBreakerOpenDuration     = 10 * time.Second
+)
+
+// ProxyRetryPolicy is how invocations which could not reach a function are retried by the
+// proxy, i.e. when the connection is refused while an instance is being restored. Only
+// requests with an idempotent method, and whose body was not sent, are retried. Responses
+// from the function are never retried. A limit of 0 uses the default.
+type ProxyRetryPolicy struct {
+	// MaxAttempts is the number of times a request is sent, including the first, the
+	// default is 3.
+	MaxAttempts int
+	// InitialBackoff is how long the proxy waits before the first retry, the wait is then
+	// doubled for each further retry. The default is 100ms.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between retries, the default is 2 seconds.
+	MaxBackoff time.Duration
+}
+
+// Validate checks that the limits of the policy are not negative.
+func (p *ProxyRetryPolicy) Validate() error {
+	if p.MaxAttempts < 0 {
+		return fmt.Errorf("invalid ProxyRetry MaxAttempts %d: must not be negative", p.MaxAttempts)
+	}
+	if p.InitialBackoff < 0 {
+		return fmt.Errorf("invalid ProxyRetry InitialBackoff %s: must not be negative", p.InitialBackoff)
+	}
+	if p.MaxBackoff < 0 {
+		return fmt.Errorf("invalid ProxyRetry MaxBackoff %s: must not be negative", p.MaxBackoff)
+	}
+	return nil
+}
+
+// GetMaxAttempts returns the configured MaxAttempts or the default of 3.
+func (p *ProxyRetryPolicy) GetMaxAttempts() int {
+	if p.MaxAttempts <= 0 {
+		return defaultRetryMaxAttempts
+	}
+	return p.MaxAttempts
+}
+
+// GetBackoff returns how long to wait before the given retry, counting from 1.
+func (p *ProxyRetryPolicy) GetBackoff(retry int) time.Duration {
+	backoff, maxBackoff := p.InitialBackoff, p.MaxBackoff
+	if backoff <= 0 {
+		backoff = defaultRetryInitialBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = defaultRetryMaxBackoff
+	}
+
+	for i := 1; i < retry && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
+
+// ProxyCircuitBreaker is the policy of the circuit breaker the proxy keeps for each
+// function. After FailureThreshold invocations in a row fail to reach the function, or are
+// answered with a 502, 503 or 504, the circuit opens and invocations are rejected with a
+// 503 for OpenDuration. A single invocation is then let through, which closes the circuit
+// when it succeeds or opens it again when it fails. A limit of 0 uses the default.
+type ProxyCircuitBreaker struct {
+	// FailureThreshold is the number of failures in a row which open the circuit, the
+	// default is 5.
+	FailureThreshold int
+	// OpenDuration is how long invocations are rejected for once the circuit opens, the
+	// default is 10 seconds.
+	OpenDuration time.Duration
+}
+
+// Validate checks that the limits of the policy are not negative.
+func (b *ProxyCircuitBreaker) Validate() error {
+	if b.FailureThreshold < 0 {
+		return fmt.Errorf("invalid ProxyCircuitBreaker FailureThreshold %d: must not be negative", b.FailureThreshold)
+	}
+	if b.OpenDuration < 0 {
+		return fmt.Errorf("invalid ProxyCircuitBreaker OpenDuration %s: must not be negative", b.OpenDuration)
+	}
+	return nil
+}
+
+// GetFailureThreshold returns the configured FailureThreshold or the default of 5.
+func (b *ProxyCircuitBreaker) GetFailureThreshold() int {
+	if b.FailureThreshold <= 0 {
+		return defaultBreakerFailureThreshold
+	}
+	return b.FailureThreshold
+}
+
+// GetOpenDuration returns the configured OpenDuration or the default of 10 seconds.
+func (b *ProxyCircuitBreaker) GetOpenDuration() time.Duration {
+	if b.OpenDuration <= 0 {
+		return defaultBreakerOpenDuration
+	}
+	return b.OpenDuration
+}
@@ -0,0 +1,29 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_ProxyRetryPolicy_GetBackoff(t *testing.T) {
+	testCases := []struct {
+		name   string
+		policy ProxyRetryPolicy
+		retry  int
+		want   time.Duration
+	}{
+		{name: "default first retry", retry: 1, want: 100 * time.Millisecond},
+		{name: "default third retry", retry: 3, want: 400 * time.Millisecond},
+		{name: "default is capped", retry: 10, want: 2 * time.Second},
+		{name: "configured", policy: ProxyRetryPolicy{InitialBackoff: time.Second, MaxBackoff: 3 * time.Second}, retry: 2, want: 2 * time.Second},
+		{name: "configured is capped", policy: ProxyRetryPolicy{InitialBackoff: time.Second, MaxBackoff: 3 * time.Second}, retry: 3, want: 3 * time.Second},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.policy.GetBackoff(tc.retry); got != tc.want {
+				t.Errorf("backoff, want: %s, got: %s", tc.want, got)
+			}
+		})
+	}
+}
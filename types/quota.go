@@ -0,0 +1,334 @@
+package types
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// QuotaError is returned when a request would take a namespace over one of its limits.
+type QuotaError struct {
+	// Namespace which is at its limit
+	Namespace string `json:"namespace"`
+
+	// Resource which is limited, one of "functions", "memory" in bytes, "cpu" in
+	// millicores or "checkpoints" in bytes
+	Resource string `json:"resource"`
+
+	// Limit is the maximum allowed in the namespace
+	Limit int `json:"limit"`
+
+	// Current is the amount in use in the namespace
+	Current int `json:"current"`
+
+	// Requested is the amount the request would have added, when it is known
+	Requested int `json:"requested,omitempty"`
+}
+
+func (e *QuotaError) Error() string {
+	if e.Requested > 0 {
+		return fmt.Sprintf("quota exceeded for %s in namespace %q: %d of %d in use, %d requested", e.Resource, e.Namespace, e.Current, e.Limit, e.Requested)
+	}
+	return fmt.Sprintf("quota exceeded for %s in namespace %q: %d of %d in use", e.Resource, e.Namespace, e.Current, e.Limit)
+}
+
+// CheckFunctionQuota returns a *QuotaError when deploying another function to namespace,
+// which currently has count functions, would exceed MaxFunctionsPerNamespace. It should be
+// called by the DeployFunction handler before creating the function.
+func (c *FaaSConfig) CheckFunctionQuota(namespace string, count int) error {
+	if c.MaxFunctionsPerNamespace <= 0 || count < c.MaxFunctionsPerNamespace {
+		return nil
+	}
+
+	return &QuotaError{
+		Namespace: namespace,
+		Resource:  "functions",
+		Limit:     c.MaxFunctionsPerNamespace,
+		Current:   count,
+	}
+}
+
+// WriteQuotaError writes err as a JSON body with a 403 status, so that every provider
+// reports exceeded quotas in the same shape.
+func WriteQuotaError(w http.ResponseWriter, err *QuotaError) error {
+	body, marshalErr := json.Marshal(struct {
+		Message string `json:"message"`
+		*QuotaError
+	}{err.Error(), err})
+	if marshalErr != nil {
+		return marshalErr
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	_, writeErr := w.Write(body)
+	return writeErr
+}
+
+// QuotaProvider returns the quota of each namespace, it is set on FaaSConfig.Quotas along
+// with FaaSConfig.NamespaceUsage. StaticQuotas, read with ReadQuotaFile, provides the
+// quotas from a file.
+type QuotaProvider interface {
+	// Quota returns the quota of namespace, or nil when the namespace is not limited.
+	Quota(ctx context.Context, namespace string) (*NamespaceQuota, error)
+}
+
+// NamespaceQuota is the limits of a namespace, which are enforced when a function is
+// deployed, updated or registered and when a checkpoint is created. A limit of 0, or an
+// empty quantity, is not applied.
+type NamespaceQuota struct {
+	// MaxFunctions is the number of functions in the namespace
+	MaxFunctions int `json:"maxFunctions,omitempty"`
+
+	// MaxMemory is the memory requested by the functions of the namespace added up, as a
+	// quantity such as "4Gi"
+	MaxMemory string `json:"maxMemory,omitempty"`
+
+	// MaxCPU is the CPU requested by the functions of the namespace added up, as a
+	// quantity such as "2" or "500m"
+	MaxCPU string `json:"maxCPU,omitempty"`
+
+	// MaxCheckpointBytes is the SizeBytes of the checkpoints of the namespace added up
+	MaxCheckpointBytes int64 `json:"maxCheckpointBytes,omitempty"`
+}
+
+// NamespaceUsage is what a namespace has in use, as reported by the provider.
+type NamespaceUsage struct {
+	// Functions holds the resources of each function in the namespace by its name. The
+	// requests of a function count towards the quota, or its limits when it has no
+	// requests.
+	Functions map[string]FunctionResources
+
+	// CheckpointBytes is the SizeBytes of the checkpoints of the namespace added up
+	CheckpointBytes int64
+}
+
+// Validate checks that the limits of the quota are not negative and that its quantities
+// can be parsed.
+func (q *NamespaceQuota) Validate() error {
+	if q.MaxFunctions < 0 {
+		return fmt.Errorf("invalid quota maxFunctions %d: must not be negative", q.MaxFunctions)
+	}
+	if _, err := parseMemory(q.MaxMemory); err != nil {
+		return fmt.Errorf("invalid quota maxMemory: %w", err)
+	}
+	if _, err := parseCPU(q.MaxCPU); err != nil {
+		return fmt.Errorf("invalid quota maxCPU: %w", err)
+	}
+	if q.MaxCheckpointBytes < 0 {
+		return fmt.Errorf("invalid quota maxCheckpointBytes %d: must not be negative", q.MaxCheckpointBytes)
+	}
+	return nil
+}
+
+// CheckDeployment returns a *QuotaError when deploying the function, or updating it when
+// it is already in usage, would take namespace over the quota. The resources of a function
+// which is updated are replaced by those of the deployment.
+func (q *NamespaceQuota) CheckDeployment(namespace string, usage NamespaceUsage, deployment FunctionDeployment) error {
+	if err := q.checkFunctions(namespace, usage, deployment.Service); err != nil {
+		return err
+	}
+
+	resources := deployment.Requests
+	if resources == nil {
+		resources = deployment.Limits
+	}
+	if resources == nil {
+		return nil
+	}
+
+	memory, cpu, err := usedResources(usage, deployment.Service)
+	if err != nil {
+		return err
+	}
+
+	requestedMemory, err := parseMemory(resources.Memory)
+	if err != nil {
+		return fmt.Errorf("invalid memory of %s: %w", deployment.Service, err)
+	}
+	maxMemory, _ := parseMemory(q.MaxMemory)
+	if maxMemory > 0 && memory+requestedMemory > maxMemory {
+		return &QuotaError{Namespace: namespace, Resource: "memory", Limit: int(maxMemory), Current: int(memory), Requested: int(requestedMemory)}
+	}
+
+	requestedCPU, err := parseCPU(resources.CPU)
+	if err != nil {
+		return fmt.Errorf("invalid cpu of %s: %w", deployment.Service, err)
+	}
+	maxCPU, _ := parseCPU(q.MaxCPU)
+	if maxCPU > 0 && cpu+requestedCPU > maxCPU {
+		return &QuotaError{Namespace: namespace, Resource: "cpu", Limit: int(maxCPU), Current: int(cpu), Requested: int(requestedCPU)}
+	}
+
+	return nil
+}
+
+// CheckRegister returns a *QuotaError when registering an instance of function would add
+// a function to namespace over the quota. Instances of a function which is in usage are
+// always accepted.
+func (q *NamespaceQuota) CheckRegister(namespace string, usage NamespaceUsage, function string) error {
+	return q.checkFunctions(namespace, usage, function)
+}
+
+// CheckCheckpoint returns a *QuotaError when the checkpoints of namespace have reached
+// MaxCheckpointBytes, as the size of a checkpoint is only known once it is created.
+func (q *NamespaceQuota) CheckCheckpoint(namespace string, usage NamespaceUsage) error {
+	if q.MaxCheckpointBytes <= 0 || usage.CheckpointBytes < q.MaxCheckpointBytes {
+		return nil
+	}
+
+	return &QuotaError{
+		Namespace: namespace,
+		Resource:  "checkpoints",
+		Limit:     int(q.MaxCheckpointBytes),
+		Current:   int(usage.CheckpointBytes),
+	}
+}
+
+func (q *NamespaceQuota) checkFunctions(namespace string, usage NamespaceUsage, function string) error {
+	if _, ok := usage.Functions[function]; ok || q.MaxFunctions <= 0 || len(usage.Functions) < q.MaxFunctions {
+		return nil
+	}
+
+	return &QuotaError{
+		Namespace: namespace,
+		Resource:  "functions",
+		Limit:     q.MaxFunctions,
+		Current:   len(usage.Functions),
+	}
+}
+
+// usedResources adds up the memory in bytes and the CPU in millicores of the functions in
+// usage, leaving out the function named except.
+func usedResources(usage NamespaceUsage, except string) (int64, int64, error) {
+	var memory, cpu int64
+	for name, resources := range usage.Functions {
+		if name == except {
+			continue
+		}
+
+		m, err := parseMemory(resources.Memory)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid memory of %s: %w", name, err)
+		}
+		c, err := parseCPU(resources.CPU)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid cpu of %s: %w", name, err)
+		}
+		memory, cpu = memory+m, cpu+c
+	}
+	return memory, cpu, nil
+}
+
+// quantityExpression matches a quantity such as "128Mi", "1G", "100m" or "0.5", the same
+// as those accepted for the limits and requests of a function.
+var quantityExpression = regexp.MustCompile(`^([0-9]+(?:\.[0-9]*)?|\.[0-9]+)([eE][-+]?[0-9]+|Ki|Mi|Gi|Ti|Pi|Ei|n|u|m|k|M|G|T|P|E)?$`)
+
+var quantitySuffixes = map[string]float64{
+	"": 1, "n": 1e-9, "u": 1e-6, "m": 1e-3,
+	"k": 1e3, "M": 1e6, "G": 1e9, "T": 1e12, "P": 1e15, "E": 1e18,
+	"Ki": 1 << 10, "Mi": 1 << 20, "Gi": 1 << 30, "Ti": 1 << 40, "Pi": 1 << 50, "Ei": 1 << 60,
+}
+
+// parseQuantity returns the value of a quantity, an empty quantity is 0.
+func parseQuantity(quantity string) (float64, error) {
+	if len(quantity) == 0 {
+		return 0, nil
+	}
+
+	match := quantityExpression.FindStringSubmatch(quantity)
+	if match == nil {
+		return 0, fmt.Errorf("%q must be a quantity such as \"128Mi\" or \"100m\"", quantity)
+	}
+
+	value, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q must be a quantity such as \"128Mi\" or \"100m\"", quantity)
+	}
+
+	multiplier, ok := quantitySuffixes[match[2]]
+	if !ok {
+		// The exponent of a quantity such as "1e3"
+		multiplier, _ = strconv.ParseFloat("1"+match[2], 64)
+	}
+	return value * multiplier, nil
+}
+
+// parseMemory returns a memory quantity in bytes, rounded up.
+func parseMemory(quantity string) (int64, error) {
+	value, err := parseQuantity(quantity)
+	return int64(math.Ceil(value)), err
+}
+
+// parseCPU returns a CPU quantity in millicores, rounded up.
+func parseCPU(quantity string) (int64, error) {
+	value, err := parseQuantity(quantity)
+	return int64(math.Ceil(value * 1000)), err
+}
+
+// StaticQuotas is a QuotaProvider with a fixed quota for each namespace, it is usually
+// read from a file with ReadQuotaFile.
+type StaticQuotas struct {
+	// Default is the quota of the namespaces which are not in Namespaces, they are not
+	// limited when it is nil.
+	Default *NamespaceQuota `json:"default,omitempty"`
+
+	// Namespaces holds the quota of each namespace by its name
+	Namespaces map[string]NamespaceQuota `json:"namespaces,omitempty"`
+}
+
+// Quota returns the quota of namespace, or Default when it has none of its own.
+func (s *StaticQuotas) Quota(ctx context.Context, namespace string) (*NamespaceQuota, error) {
+	if quota, ok := s.Namespaces[namespace]; ok {
+		return &quota, nil
+	}
+	return s.Default, nil
+}
+
+// Validate checks the quota of each namespace.
+func (s *StaticQuotas) Validate() error {
+	if s.Default != nil {
+		if err := s.Default.Validate(); err != nil {
+			return fmt.Errorf("default: %w", err)
+		}
+	}
+	for namespace, quota := range s.Namespaces {
+		if err := quota.Validate(); err != nil {
+			return fmt.Errorf("namespace %q: %w", namespace, err)
+		}
+	}
+	return nil
+}
+
+// ReadQuotaFile reads StaticQuotas from the JSON file at path, i.e.
+//
+//	{
+//	  "default": {"maxFunctions": 10},
+//	  "namespaces": {
+//	    "tenant-a": {"maxFunctions": 50, "maxMemory": "8Gi", "maxCPU": "4", "maxCheckpointBytes": 10737418240}
+//	  }
+//	}
+func ReadQuotaFile(path string) (*StaticQuotas, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read quota file: %w", err)
+	}
+	defer f.Close()
+
+	quotas := &StaticQuotas{}
+	decoder := json.NewDecoder(f)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(quotas); err != nil {
+		return nil, fmt.Errorf("unable to decode quota file %s: %w", path, err)
+	}
+
+	if err := quotas.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid quota file %s: %w", path, err)
+	}
+	return quotas, nil
+}
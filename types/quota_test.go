@@ -0,0 +1,192 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFaaSConfig_CheckFunctionQuota(t *testing.T) {
+	testCases := []struct {
+		name    string
+		max     int
+		count   int
+		wantErr bool
+	}{
+		{name: "no limit", max: 0, count: 1000},
+		{name: "under the limit", max: 5, count: 4},
+		{name: "at the limit", max: 5, count: 5, wantErr: true},
+		{name: "over the limit", max: 5, count: 7, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := FaaSConfig{MaxFunctionsPerNamespace: tc.max}
+
+			err := config.CheckFunctionQuota("tenant-a", tc.count)
+			if !tc.wantErr {
+				if err != nil {
+					t.Fatalf("want no error, got: %s", err)
+				}
+				return
+			}
+
+			var quotaErr *QuotaError
+			if !errors.As(err, &quotaErr) {
+				t.Fatalf("want a *QuotaError, got: %v", err)
+			}
+
+			if quotaErr.Namespace != "tenant-a" || quotaErr.Limit != tc.max || quotaErr.Current != tc.count {
+				t.Errorf("unexpected error: %+v", quotaErr)
+			}
+		})
+	}
+}
+
+func TestWriteQuotaError(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := &QuotaError{Namespace: "tenant-a", Resource: "functions", Limit: 5, Current: 5}
+
+	if writeErr := WriteQuotaError(w, err); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("status code, want: %d, got: %d", http.StatusForbidden, w.Code)
+	}
+
+	want := `{"message":"quota exceeded for functions in namespace \"tenant-a\": 5 of 5 in use","namespace":"tenant-a","resource":"functions","limit":5,"current":5}`
+	if got := w.Body.String(); got != want {
+		t.Errorf("body, want: %s, got: %s", want, got)
+	}
+}
+
+func TestNamespaceQuota_CheckDeployment(t *testing.T) {
+	quota := &NamespaceQuota{MaxFunctions: 2, MaxMemory: "1Gi", MaxCPU: "1"}
+	usage := NamespaceUsage{
+		Functions: map[string]FunctionResources{
+			"figlet":   {Memory: "512Mi", CPU: "500m"},
+			"nodeinfo": {},
+		},
+	}
+
+	testCases := []struct {
+		name         string
+		deployment   FunctionDeployment
+		wantResource string
+	}{
+		{name: "update without resources", deployment: FunctionDeployment{Service: "figlet"}},
+		{name: "update replaces the resources", deployment: FunctionDeployment{Service: "figlet", Limits: &FunctionResources{Memory: "1Gi", CPU: "1"}}},
+		{name: "new function", deployment: FunctionDeployment{Service: "env"}, wantResource: "functions"},
+		{name: "memory", deployment: FunctionDeployment{Service: "nodeinfo", Limits: &FunctionResources{Memory: "600Mi"}}, wantResource: "memory"},
+		{name: "requests before limits", deployment: FunctionDeployment{Service: "nodeinfo", Requests: &FunctionResources{Memory: "256Mi"}, Limits: &FunctionResources{Memory: "2Gi"}}},
+		{name: "cpu", deployment: FunctionDeployment{Service: "nodeinfo", Requests: &FunctionResources{CPU: "0.6"}}, wantResource: "cpu"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := quota.CheckDeployment("tenant-a", usage, tc.deployment)
+			if len(tc.wantResource) == 0 {
+				if err != nil {
+					t.Fatalf("want no error, got: %s", err)
+				}
+				return
+			}
+
+			var quotaErr *QuotaError
+			if !errors.As(err, &quotaErr) {
+				t.Fatalf("want a *QuotaError, got: %v", err)
+			}
+			if quotaErr.Resource != tc.wantResource {
+				t.Errorf("resource, want: %s, got: %s", tc.wantResource, quotaErr.Resource)
+			}
+		})
+	}
+}
+
+func TestNamespaceQuota_CheckCheckpoint(t *testing.T) {
+	quota := &NamespaceQuota{MaxCheckpointBytes: 100}
+
+	if err := quota.CheckCheckpoint("tenant-a", NamespaceUsage{CheckpointBytes: 99}); err != nil {
+		t.Errorf("want no error under the limit, got: %s", err)
+	}
+
+	var quotaErr *QuotaError
+	if err := quota.CheckCheckpoint("tenant-a", NamespaceUsage{CheckpointBytes: 100}); !errors.As(err, &quotaErr) {
+		t.Fatalf("want a *QuotaError at the limit, got: %v", err)
+	}
+	if quotaErr.Resource != "checkpoints" || quotaErr.Current != 100 || quotaErr.Limit != 100 {
+		t.Errorf("unexpected error: %+v", quotaErr)
+	}
+}
+
+func Test_parseQuantity(t *testing.T) {
+	testCases := []struct {
+		quantity   string
+		wantMemory int64
+		wantCPU    int64
+		wantErr    bool
+	}{
+		{quantity: "", wantMemory: 0, wantCPU: 0},
+		{quantity: "128Mi", wantMemory: 128 << 20, wantCPU: 128 << 20 * 1000},
+		{quantity: "1G", wantMemory: 1e9, wantCPU: 1e12},
+		{quantity: "100m", wantMemory: 1, wantCPU: 100},
+		{quantity: "0.5", wantMemory: 1, wantCPU: 500},
+		{quantity: "1e3", wantMemory: 1000, wantCPU: 1000000},
+		{quantity: "lots", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.quantity, func(t *testing.T) {
+			memory, err := parseMemory(tc.quantity)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error, want: %t, got: %v", tc.wantErr, err)
+			}
+			cpu, _ := parseCPU(tc.quantity)
+
+			if memory != tc.wantMemory {
+				t.Errorf("memory, want: %d, got: %d", tc.wantMemory, memory)
+			}
+			if cpu != tc.wantCPU {
+				t.Errorf("cpu, want: %d, got: %d", tc.wantCPU, cpu)
+			}
+		})
+	}
+}
+
+func TestReadQuotaFile(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "quotas.json")
+	os.WriteFile(valid, []byte(`{"default":{"maxFunctions":10},"namespaces":{"tenant-a":{"maxMemory":"8Gi"}}}`), 0600)
+
+	quotas, err := ReadQuotaFile(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	quota, _ := quotas.Quota(context.Background(), "tenant-a")
+	if quota == nil || quota.MaxMemory != "8Gi" {
+		t.Errorf("quota of tenant-a, want: %s, got: %+v", "8Gi", quota)
+	}
+	quota, _ = quotas.Quota(context.Background(), "tenant-b")
+	if quota == nil || quota.MaxFunctions != 10 {
+		t.Errorf("quota of tenant-b, want the default, got: %+v", quota)
+	}
+
+	for name, body := range map[string]string{
+		"unknown field": `{"namespaces":{"tenant-a":{"maxFunctionz":1}}}`,
+		"invalid":       `{"namespaces":{"tenant-a":{"maxMemory":"lots"}}}`,
+	} {
+		path := filepath.Join(dir, "invalid.json")
+		os.WriteFile(path, []byte(body), 0600)
+
+		if _, err := ReadQuotaFile(path); err == nil {
+			t.Errorf("%s: want an error", name)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -94,40 +95,191 @@ func ParseString(val string, fallback string) string {
 	return fallback
 }
 
-// Read fetches config from environmental variables.
+// Read fetches config from environmental variables, with the defaults of the gateway for
+// the variables which are not set. Durations are given in seconds or as a Go duration, i.e.
+// "30" or "1m30s", and lists are separated by commas. Every variable which can not be
+// parsed is reported, along with the errors of FaaSConfig.Validate, joined with
+// errors.Join.
+//
+// The variables read are:
+//
+//   - port, metrics_port, bind_address and network
+//   - read_timeout, write_timeout, idle_timeout, read_header_timeout, shutdown_timeout and
+//     healthcheck_interval
+//   - basic_auth, secret_mount_path, basic_auth_reload_interval, hmac_auth,
+//     hmac_max_skew and hmac_verify_invocations
+//   - tls_cert_file, tls_key_file, tls_client_ca_file and tls_min_version, TLSConfig is
+//     set when either of the first two is
+//   - enable_streaming, enable_h2c and proxy_h2c
+//   - max_idle_conns, max_idle_conns_per_host, max_connections, max_request_body_bytes,
+//     max_proxy_body_bytes, max_system_requests, max_data_plane_requests,
+//     max_system_concurrency, max_log_streams, max_request_timeout, invoke_rate_limit and
+//     invoke_rate_limit_window
+//   - batch_parallelism
+//   - proxy_max_timeout, cold_start_max_wait and default_function
+//   - allowed_namespaces, default_namespace, read_only, access_log and access_log_format
 func (ReadConfig) Read(hasEnv HasEnv) (*FaaSConfig, error) {
-	cfg := &FaaSConfig{
-		ReadTimeout:     ParseIntOrDurationValue(hasEnv.Getenv("read_timeout"), time.Second*10),
-		WriteTimeout:    ParseIntOrDurationValue(hasEnv.Getenv("write_timeout"), time.Second*10),
-		EnableBasicAuth: ParseBoolValue(hasEnv.Getenv("basic_auth"), false),
-		// default value from Gateway
-		SecretMountPath: ParseString(hasEnv.Getenv("secret_mount_path"), "/run/secrets/"),
-	}
-
-	port := ParseIntValue(hasEnv.Getenv("port"), 8080)
-	cfg.TCPPort = &port
-
-	cfg.MaxIdleConns = 1024
-	maxIdleConns := hasEnv.Getenv("max_idle_conns")
-	if len(maxIdleConns) > 0 {
-		val, err := strconv.Atoi(maxIdleConns)
-		if err != nil {
-			return nil, fmt.Errorf("invalid value for max_idle_conns: %s", maxIdleConns)
-		}
-		cfg.MaxIdleConns = val
+	env := &envReader{env: hasEnv}
 
+	port := env.int("port", defaultTCPPort)
+	cfg := &FaaSConfig{
+		TCPPort:                 &port,
+		MetricsPort:             env.optionalInt("metrics_port"),
+		BindAddress:             env.string("bind_address", ""),
+		Network:                 env.string("network", ""),
+		ReadTimeout:             env.duration("read_timeout", defaultReadTimeout),
+		WriteTimeout:            env.duration("write_timeout", defaultReadTimeout),
+		IdleTimeout:             env.duration("idle_timeout", 0),
+		ReadHeaderTimeout:       env.duration("read_header_timeout", 0),
+		ShutdownTimeout:         env.duration("shutdown_timeout", defaultShutdownTimeout),
+		HealthcheckInterval:     env.duration("healthcheck_interval", 0),
+		EnableBasicAuth:         env.bool("basic_auth", false),
+		SecretMountPath:         env.string("secret_mount_path", "/run/secrets/"), // default value from Gateway
+		BasicAuthReloadInterval: env.duration("basic_auth_reload_interval", 0),
+		EnableHMACAuth:          env.bool("hmac_auth", false),
+		HMACMaxSkew:             env.duration("hmac_max_skew", 0),
+		HMACVerifyInvocations:   env.bool("hmac_verify_invocations", false),
+		EnableStreaming:         env.bool("enable_streaming", false),
+		EnableH2C:               env.bool("enable_h2c", false),
+		ProxyH2C:                env.bool("proxy_h2c", false),
+		MaxIdleConns:            env.int("max_idle_conns", defaultMaxIdleConns),
+		MaxIdleConnsPerHost:     env.int("max_idle_conns_per_host", defaultMaxIdleConns),
+		MaxConnections:          env.int("max_connections", 0),
+		MaxRequestBodyBytes:     env.int64("max_request_body_bytes", 0),
+		MaxProxyBodyBytes:       env.int64("max_proxy_body_bytes", 0),
+		MaxSystemRequests:       env.int("max_system_requests", 0),
+		MaxDataPlaneRequests:    env.int("max_data_plane_requests", 0),
+		MaxSystemConcurrency:    env.int("max_system_concurrency", 0),
+		MaxLogStreams:           env.int("max_log_streams", 0),
+		MaxRequestTimeout:       env.duration("max_request_timeout", 0),
+		InvokeRateLimit:         env.int("invoke_rate_limit", 0),
+		InvokeRateLimitWindow:   env.duration("invoke_rate_limit_window", 0),
+		BatchParallelism:        env.int("batch_parallelism", 0),
+		ProxyMaxTimeout:         env.duration("proxy_max_timeout", 0),
+		ColdStartMaxWait:        env.duration("cold_start_max_wait", 0),
+		DefaultFunction:         env.string("default_function", ""),
+		AllowedNamespaces:       env.list("allowed_namespaces"),
+		DefaultNamespace:        env.string("default_namespace", ""),
+		ReadOnly:                env.bool("read_only", false),
+		AccessLog:               env.bool("access_log", false),
+		AccessLogFormat:         env.string("access_log_format", ""),
 	}
 
-	cfg.MaxIdleConnsPerHost = 1024
-	maxIdleConnsPerHost := hasEnv.Getenv("max_idle_conns_per_host")
-	if len(maxIdleConnsPerHost) > 0 {
-		val, err := strconv.Atoi(maxIdleConnsPerHost)
-		if err != nil {
-			return nil, fmt.Errorf("invalid value for max_idle_conns_per_host: %s", maxIdleConnsPerHost)
+	certFile, keyFile := env.string("tls_cert_file", ""), env.string("tls_key_file", "")
+	if len(certFile) > 0 || len(keyFile) > 0 {
+		cfg.TLSConfig = &TLSConfig{
+			CertFile:      certFile,
+			KeyFile:       keyFile,
+			ClientCAFile:  env.string("tls_client_ca_file", ""),
+			MinTLSVersion: env.string("tls_min_version", ""),
 		}
-		cfg.MaxIdleConnsPerHost = val
+	}
 
+	if err := cfg.Validate(); err != nil {
+		env.errs = append(env.errs, err)
+	}
+
+	if len(env.errs) > 0 {
+		return nil, errors.Join(env.errs...)
 	}
 
 	return cfg, nil
 }
+
+// envReader reads typed values from the environment, recording an error for each value
+// which can not be parsed, so that they are all reported at once.
+type envReader struct {
+	env  HasEnv
+	errs []error
+}
+
+func (r *envReader) invalid(key, val, want string) {
+	r.errs = append(r.errs, fmt.Errorf("invalid value for %s: %q, must be %s", key, val, want))
+}
+
+// string returns the value of key, or fallback when it is not set.
+func (r *envReader) string(key, fallback string) string {
+	return ParseString(strings.TrimSpace(r.env.Getenv(key)), fallback)
+}
+
+func (r *envReader) bool(key string, fallback bool) bool {
+	val := strings.TrimSpace(r.env.Getenv(key))
+	if len(val) == 0 {
+		return fallback
+	}
+
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		r.invalid(key, val, "true or false")
+		return fallback
+	}
+	return b
+}
+
+func (r *envReader) int(key string, fallback int) int {
+	val := strings.TrimSpace(r.env.Getenv(key))
+	if len(val) == 0 {
+		return fallback
+	}
+
+	i, err := strconv.Atoi(val)
+	if err != nil || i < 0 {
+		r.invalid(key, val, "a whole number which is not negative")
+		return fallback
+	}
+	return i
+}
+
+// optionalInt returns nil when key is not set.
+func (r *envReader) optionalInt(key string) *int {
+	if len(strings.TrimSpace(r.env.Getenv(key))) == 0 {
+		return nil
+	}
+
+	i := r.int(key, 0)
+	return &i
+}
+
+func (r *envReader) int64(key string, fallback int64) int64 {
+	val := strings.TrimSpace(r.env.Getenv(key))
+	if len(val) == 0 {
+		return fallback
+	}
+
+	i, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || i < 0 {
+		r.invalid(key, val, "a whole number which is not negative")
+		return fallback
+	}
+	return i
+}
+
+// duration reads a number of seconds or a Go duration, such as "1m30s".
+func (r *envReader) duration(key string, fallback time.Duration) time.Duration {
+	val := strings.TrimSpace(r.env.Getenv(key))
+	if len(val) == 0 {
+		return fallback
+	}
+
+	if seconds, err := strconv.Atoi(val); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	d, err := time.ParseDuration(val)
+	if err != nil || d < 0 {
+		r.invalid(key, val, `a number of seconds or a duration such as "1m30s"`)
+		return fallback
+	}
+	return d
+}
+
+// list reads the values separated by commas, empty values are dropped.
+func (r *envReader) list(key string) []string {
+	var values []string
+	for _, v := range strings.Split(r.env.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -2,6 +2,7 @@ package types
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 )
@@ -190,6 +191,136 @@ func TestRead_MaxIdleConns_Override(t *testing.T) {
 	}
 }
 
+func TestRead_MaxConnections(t *testing.T) {
+	defaults := NewEnvBucket()
+	readConfig := ReadConfig{}
+
+	config, err := readConfig.Read(defaults)
+	if err != nil {
+		t.Fatalf("unexpected error while reading config")
+	}
+
+	if config.MaxConnections != 0 {
+		t.Errorf("config.MaxConnections, want: %d, got: %d", 0, config.MaxConnections)
+	}
+
+	defaults.Setenv("max_connections", "512")
+	config, err = readConfig.Read(defaults)
+	if err != nil {
+		t.Fatalf("unexpected error while reading config")
+	}
+
+	if config.MaxConnections != 512 {
+		t.Errorf("config.MaxConnections, want: %d, got: %d", 512, config.MaxConnections)
+	}
+
+	defaults.Setenv("max_connections", "-1")
+	if _, err := readConfig.Read(defaults); err == nil {
+		t.Errorf("want error for negative max_connections")
+	}
+}
+
+func TestRead_ShutdownTimeout(t *testing.T) {
+	defaults := NewEnvBucket()
+	readConfig := ReadConfig{}
+
+	config, err := readConfig.Read(defaults)
+	if err != nil {
+		t.Fatalf("unexpected error while reading config")
+	}
+
+	if config.ShutdownTimeout != 10*time.Second {
+		t.Errorf("config.ShutdownTimeout, want: %s, got: %s", 10*time.Second, config.ShutdownTimeout)
+	}
+
+	defaults.Setenv("shutdown_timeout", "2m")
+	config, err = readConfig.Read(defaults)
+	if err != nil {
+		t.Fatalf("unexpected error while reading config")
+	}
+
+	if config.ShutdownTimeout != 2*time.Minute {
+		t.Errorf("config.ShutdownTimeout, want: %s, got: %s", 2*time.Minute, config.ShutdownTimeout)
+	}
+}
+
+func TestRead_ServerAndLimits(t *testing.T) {
+	defaults := NewEnvBucket()
+	defaults.Setenv("metrics_port", "8081")
+	defaults.Setenv("idle_timeout", "1m")
+	defaults.Setenv("tls_cert_file", "/etc/tls/tls.crt")
+	defaults.Setenv("tls_key_file", "/etc/tls/tls.key")
+	defaults.Setenv("tls_min_version", "1.3")
+	defaults.Setenv("enable_streaming", "true")
+	defaults.Setenv("max_request_body_bytes", "1048576")
+	defaults.Setenv("invoke_rate_limit", "100")
+	defaults.Setenv("invoke_rate_limit_window", "30")
+	defaults.Setenv("allowed_namespaces", "openfaas-fn, staging,")
+
+	readConfig := ReadConfig{}
+	config, err := readConfig.Read(defaults)
+	if err != nil {
+		t.Fatalf("unexpected error while reading config: %s", err)
+	}
+
+	if config.MetricsPort == nil || *config.MetricsPort != 8081 {
+		t.Errorf("config.MetricsPort, want: %d, got: %v", 8081, config.MetricsPort)
+	}
+	if config.IdleTimeout != time.Minute {
+		t.Errorf("config.IdleTimeout, want: %s, got: %s", time.Minute, config.IdleTimeout)
+	}
+	if config.TLSConfig == nil || config.TLSConfig.CertFile != "/etc/tls/tls.crt" || config.TLSConfig.MinTLSVersion != "1.3" {
+		t.Errorf("config.TLSConfig, want the cert file and version to be set, got: %+v", config.TLSConfig)
+	}
+	if !config.EnableStreaming {
+		t.Errorf("config.EnableStreaming, want: %t, got: %t", true, config.EnableStreaming)
+	}
+	if config.MaxRequestBodyBytes != 1048576 {
+		t.Errorf("config.MaxRequestBodyBytes, want: %d, got: %d", 1048576, config.MaxRequestBodyBytes)
+	}
+	if config.InvokeRateLimit != 100 || config.InvokeRateLimitWindow != 30*time.Second {
+		t.Errorf("config.InvokeRateLimit, want: %d per %s, got: %d per %s", 100, 30*time.Second, config.InvokeRateLimit, config.InvokeRateLimitWindow)
+	}
+	if want := []string{"openfaas-fn", "staging"}; fmt.Sprint(config.AllowedNamespaces) != fmt.Sprint(want) {
+		t.Errorf("config.AllowedNamespaces, want: %v, got: %v", want, config.AllowedNamespaces)
+	}
+}
+
+func TestRead_TLSConfigUnset(t *testing.T) {
+	defaults := NewEnvBucket()
+	defaults.Setenv("tls_min_version", "1.3")
+
+	readConfig := ReadConfig{}
+	config, err := readConfig.Read(defaults)
+	if err != nil {
+		t.Fatalf("unexpected error while reading config: %s", err)
+	}
+
+	if config.TLSConfig != nil {
+		t.Errorf("config.TLSConfig, want: nil without a cert or key, got: %+v", config.TLSConfig)
+	}
+}
+
+func TestRead_AggregatesErrors(t *testing.T) {
+	defaults := NewEnvBucket()
+	defaults.Setenv("basic_auth", "yes")
+	defaults.Setenv("port", "http")
+	defaults.Setenv("read_timeout", "soon")
+	defaults.Setenv("tls_cert_file", "/etc/tls/tls.crt")
+
+	readConfig := ReadConfig{}
+	_, err := readConfig.Read(defaults)
+	if err == nil {
+		t.Fatalf("want an error for the invalid values")
+	}
+
+	for _, want := range []string{"basic_auth", "port", "read_timeout", "KeyFile"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("want the error to report %s, got: %s", want, err)
+		}
+	}
+}
+
 func Test_ParseIntOrDuration(t *testing.T) {
 	tests := []struct {
 		val  string
@@ -0,0 +1,89 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// RegisterRequest is posted to /system/register by a function instance which
+// registers itself with the provider.
+type RegisterRequest struct {
+	// Name is the name of the function the instance belongs to
+	Name string `json:"name"`
+
+	// Namespace for the function, if supported by the faas-provider
+	Namespace string `json:"namespace,omitempty"`
+
+	// Address the instance can be reached on, either as host:port or
+	// as a http(s) URL
+	Address string `json:"address"`
+
+	// Ready is true when the instance is able to receive invocations
+	Ready bool `json:"ready"`
+}
+
+// RegisterResponse is returned by /system/register once an instance has been
+// accepted by the provider.
+type RegisterResponse struct {
+	// Name is the name of the function the instance was registered for
+	Name string `json:"name"`
+
+	// Namespace the function was registered in
+	Namespace string `json:"namespace,omitempty"`
+
+	// Address the provider will use to reach the instance
+	Address string `json:"address"`
+}
+
+// Validate checks that the request names a function and carries an address
+// which the provider can connect to.
+func (r RegisterRequest) Validate() error {
+	if len(r.Name) == 0 {
+		return fmt.Errorf("name is required")
+	}
+
+	if len(r.Address) == 0 {
+		return fmt.Errorf("address is required")
+	}
+
+	if strings.Contains(r.Address, "://") {
+		u, err := url.Parse(r.Address)
+		if err != nil {
+			return fmt.Errorf("invalid address %q: %w", r.Address, err)
+		}
+
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("invalid address %q: scheme must be http or https", r.Address)
+		}
+
+		if len(u.Host) == 0 {
+			return fmt.Errorf("invalid address %q: host is required", r.Address)
+		}
+
+		return nil
+	}
+
+	if _, _, err := net.SplitHostPort(r.Address); err != nil {
+		return fmt.Errorf("invalid address %q: %w", r.Address, err)
+	}
+
+	return nil
+}
+
+// DecodeRegisterRequest reads a RegisterRequest from body and validates it.
+func DecodeRegisterRequest(body io.Reader) (*RegisterRequest, error) {
+	req := &RegisterRequest{}
+	if err := json.NewDecoder(body).Decode(req); err != nil {
+		return nil, fmt.Errorf("unable to decode register request: %w", err)
+	}
+
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
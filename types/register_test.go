@@ -0,0 +1,71 @@
+package types
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_DecodeRegisterRequest(t *testing.T) {
+	testCases := []struct {
+		name    string
+		body    string
+		wantErr string
+		want    RegisterRequest
+	}{
+		{
+			name: "host and port",
+			body: `{"name":"figlet","namespace":"openfaas-fn","address":"10.0.0.2:8080","ready":true}`,
+			want: RegisterRequest{Name: "figlet", Namespace: "openfaas-fn", Address: "10.0.0.2:8080", Ready: true},
+		},
+		{
+			name: "url",
+			body: `{"name":"figlet","address":"http://10.0.0.2:8080"}`,
+			want: RegisterRequest{Name: "figlet", Address: "http://10.0.0.2:8080"},
+		},
+		{
+			name:    "missing name",
+			body:    `{"address":"10.0.0.2:8080"}`,
+			wantErr: "name is required",
+		},
+		{
+			name:    "missing address",
+			body:    `{"name":"figlet"}`,
+			wantErr: "address is required",
+		},
+		{
+			name:    "address without port",
+			body:    `{"name":"figlet","address":"10.0.0.2"}`,
+			wantErr: "invalid address",
+		},
+		{
+			name:    "unsupported scheme",
+			body:    `{"name":"figlet","address":"tcp://10.0.0.2:8080"}`,
+			wantErr: "scheme must be http or https",
+		},
+		{
+			name:    "malformed json",
+			body:    `{"name":`,
+			wantErr: "unable to decode register request",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := DecodeRegisterRequest(strings.NewReader(tc.body))
+			if len(tc.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("want error containing %q, got: %v", tc.wantErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if *got != tc.want {
+				t.Errorf("want: %+v, got: %+v", tc.want, *got)
+			}
+		})
+	}
+}
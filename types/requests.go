@@ -3,6 +3,18 @@
 
 package types
 
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-provider/httputil"
+)
+
 // ScaleServiceRequest scales the service to the requested replica count.
 type ScaleServiceRequest struct {
 	ServiceName string `json:"serviceName"`
@@ -10,6 +22,91 @@ type ScaleServiceRequest struct {
 	Namespace   string `json:"namespace,omitempty"`
 }
 
+// UnmarshalJSON accepts replicas either as a JSON number or as a string containing
+// a number, such as "3", which is sent by some older clients.
+func (s *ScaleServiceRequest) UnmarshalJSON(data []byte) error {
+	type scaleServiceRequest ScaleServiceRequest
+	req := struct {
+		*scaleServiceRequest
+		Replicas json.RawMessage `json:"replicas"`
+	}{
+		scaleServiceRequest: (*scaleServiceRequest)(s),
+	}
+
+	if err := json.Unmarshal(data, &req); err != nil {
+		return err
+	}
+
+	if len(req.Replicas) == 0 || string(req.Replicas) == "null" {
+		return nil
+	}
+
+	raw := string(req.Replicas)
+	if strings.HasPrefix(raw, `"`) {
+		if err := json.Unmarshal(req.Replicas, &raw); err != nil {
+			return err
+		}
+	}
+
+	replicas, err := strconv.ParseUint(strings.TrimSpace(raw), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid replicas %s: must be a non-negative integer", string(req.Replicas))
+	}
+
+	s.Replicas = replicas
+	return nil
+}
+
+// DecodeScaleRequest reads the ScaleServiceRequest sent to "/system/scale-function/{name}",
+// taking the name of the function from the path and the replicas from the body. An error
+// is returned when the body names a different function to the path.
+func DecodeScaleRequest(r *http.Request) (ScaleServiceRequest, error) {
+	req := ScaleServiceRequest{}
+	if r.Body != nil {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			return req, fmt.Errorf("unable to decode scale request: %w", err)
+		}
+	}
+
+	if name := httputil.PathVar(r, "name"); len(name) > 0 {
+		if len(req.ServiceName) > 0 && req.ServiceName != name {
+			return req, fmt.Errorf("serviceName %q does not match the function %q in the path", req.ServiceName, name)
+		}
+		req.ServiceName = name
+	}
+
+	if len(req.ServiceName) == 0 {
+		return req, fmt.Errorf("serviceName is required")
+	}
+
+	if len(req.Namespace) == 0 {
+		req.Namespace = r.URL.Query().Get("namespace")
+	}
+
+	return req, nil
+}
+
+// DecodeFunctionDeployment reads the FunctionDeployment sent to "/system/functions" to
+// deploy or update a function, with its labels and annotations normalized by
+// NormalizeLabels. An error is returned when the body can not be decoded or a label
+// or annotation is not valid.
+func DecodeFunctionDeployment(r *http.Request) (FunctionDeployment, error) {
+	req := FunctionDeployment{}
+	if r.Body == nil {
+		return req, fmt.Errorf("unable to decode deployment: empty body")
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return req, fmt.Errorf("unable to decode deployment: %w", err)
+	}
+
+	if err := req.NormalizeLabels(); err != nil {
+		return req, err
+	}
+
+	return req, nil
+}
+
 // DeleteFunctionRequest delete a deployed function
 type DeleteFunctionRequest struct {
 	FunctionName string `json:"functionName"`
@@ -21,6 +118,10 @@ type ProviderInfo struct {
 	Name          string       `json:"provider"`
 	Version       *VersionInfo `json:"version"`
 	Orchestration string       `json:"orchestration"`
+
+	// StartedAt is the time the provider started serving its API, it is filled in
+	// by the bootstrap package when left empty by the Info handler.
+	StartedAt *time.Time `json:"startedAt,omitempty"`
 }
 
 // VersionInfo provides the commit message, sha and release version number
@@ -37,3 +138,23 @@ type FunctionNamespace struct {
 	Annotations map[string]string `json:"annotations,omitempty"`
 	Labels      map[string]string `json:"labels,omitempty"`
 }
+
+// ReadOnlyMode is read from and written to /system/read-only to inspect or change
+// whether the provider rejects changes to functions, secrets and namespaces.
+type ReadOnlyMode struct {
+	Enabled bool `json:"enabled"`
+}
+
+// MaintenanceMode is read from and written to /system/maintenance to inspect or change
+// whether the provider rejects every request with a 503 for a maintenance window.
+type MaintenanceMode struct {
+	Enabled bool `json:"enabled"`
+
+	// Message is returned to callers, i.e. to explain the reason for and the end of
+	// the maintenance window.
+	Message string `json:"message,omitempty"`
+
+	// RetryAfter is the number of seconds callers are asked to wait before retrying,
+	// sent in the Retry-After header when greater than zero.
+	RetryAfter int `json:"retryAfter,omitempty"`
+}
@@ -0,0 +1,178 @@
+package types
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func Test_ScaleServiceRequest_UnmarshalReplicas(t *testing.T) {
+	testCases := []struct {
+		name    string
+		body    string
+		want    ScaleServiceRequest
+		wantErr string
+	}{
+		{
+			name: "number",
+			body: `{"serviceName":"figlet","replicas":3}`,
+			want: ScaleServiceRequest{ServiceName: "figlet", Replicas: 3},
+		},
+		{
+			name: "string",
+			body: `{"serviceName":"figlet","replicas":"3","namespace":"openfaas-fn"}`,
+			want: ScaleServiceRequest{ServiceName: "figlet", Replicas: 3, Namespace: "openfaas-fn"},
+		},
+		{
+			name: "large count",
+			body: `{"serviceName":"figlet","replicas":18446744073709551615}`,
+			want: ScaleServiceRequest{ServiceName: "figlet", Replicas: 18446744073709551615},
+		},
+		{
+			name: "missing replicas",
+			body: `{"serviceName":"figlet"}`,
+			want: ScaleServiceRequest{ServiceName: "figlet"},
+		},
+		{
+			name:    "negative",
+			body:    `{"serviceName":"figlet","replicas":-1}`,
+			wantErr: "invalid replicas -1",
+		},
+		{
+			name:    "not a number",
+			body:    `{"serviceName":"figlet","replicas":"three"}`,
+			wantErr: `invalid replicas "three"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ScaleServiceRequest{}
+			err := json.Unmarshal([]byte(tc.body), &got)
+
+			if len(tc.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("want error containing %q, got: %v", tc.wantErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("want: %+v, got: %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_ScaleServiceRequest_Marshal(t *testing.T) {
+	res, _ := json.Marshal(ScaleServiceRequest{ServiceName: "figlet", Replicas: 2})
+
+	want := `{"serviceName":"figlet","replicas":2}`
+	if string(res) != want {
+		t.Errorf("want: %s, got: %s", want, string(res))
+	}
+}
+
+func Test_DecodeScaleRequest(t *testing.T) {
+	testCases := []struct {
+		name     string
+		pathName string
+		target   string
+		body     string
+		want     ScaleServiceRequest
+		wantErr  string
+	}{
+		{
+			name:     "name from path",
+			pathName: "figlet",
+			target:   "/system/scale-function/figlet",
+			body:     `{"replicas":2}`,
+			want:     ScaleServiceRequest{ServiceName: "figlet", Replicas: 2},
+		},
+		{
+			name:     "matching names",
+			pathName: "figlet",
+			target:   "/system/scale-function/figlet",
+			body:     `{"serviceName":"figlet","replicas":2,"namespace":"dev"}`,
+			want:     ScaleServiceRequest{ServiceName: "figlet", Replicas: 2, Namespace: "dev"},
+		},
+		{
+			name:     "namespace from query",
+			pathName: "figlet",
+			target:   "/system/scale-function/figlet?namespace=staging",
+			body:     `{"replicas":0}`,
+			want:     ScaleServiceRequest{ServiceName: "figlet", Namespace: "staging"},
+		},
+		{
+			name:     "mismatched names",
+			pathName: "figlet",
+			target:   "/system/scale-function/figlet",
+			body:     `{"serviceName":"nodeinfo","replicas":2}`,
+			wantErr:  `serviceName "nodeinfo" does not match the function "figlet" in the path`,
+		},
+		{
+			name:    "no name",
+			target:  "/system/scale-function/",
+			body:    `{"replicas":2}`,
+			wantErr: "serviceName is required",
+		},
+		{
+			name:     "invalid body",
+			pathName: "figlet",
+			target:   "/system/scale-function/figlet",
+			body:     `{"replicas":`,
+			wantErr:  "unable to decode scale request",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, tc.target, strings.NewReader(tc.body))
+			if len(tc.pathName) > 0 {
+				r = mux.SetURLVars(r, map[string]string{"name": tc.pathName})
+			}
+
+			got, err := DecodeScaleRequest(r)
+			if len(tc.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("error, want: %q, got: %v", tc.wantErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("request, want: %+v, got: %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_DecodeFunctionDeployment(t *testing.T) {
+	body := `{"service":"figlet","image":"ghcr.io/openfaas/figlet","labels":{" team ":"payments"}}`
+	req, err := DecodeFunctionDeployment(httptest.NewRequest(http.MethodPost, "/system/functions", strings.NewReader(body)))
+	if err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+
+	if got := (*req.Labels)["team"]; got != "payments" {
+		t.Errorf("label team, want: %q, got: %q", "payments", got)
+	}
+
+	body = `{"service":"figlet","labels":{"team":"a","TEAM":"b"}}`
+	_, err = DecodeFunctionDeployment(httptest.NewRequest(http.MethodPost, "/system/functions", strings.NewReader(body)))
+	if err == nil || !strings.Contains(err.Error(), "invalid labels: duplicate key") {
+		t.Errorf("error, want: %q, got: %v", "invalid labels: duplicate key", err)
+	}
+}
@@ -0,0 +1,45 @@
+package types
+
+import (
+	"context"
+	"time"
+)
+
+// ScaleInSteps scales a function up from current to target replicas in increments of at
+// most ScaleStep, waiting ScaleStepInterval between each, so that a large scale up does
+// not overwhelm the backend. apply is called with the replicas of each step in turn,
+// ending with target, and can be used to report progress.
+//
+// Scaling down, or a change which is not larger than ScaleStep, is applied in one call.
+// When ScaleStep is 0 every change is applied in one call. An error from apply or the
+// cancellation of ctx stops the ramp, leaving the function at the last step applied.
+func (c *FaaSConfig) ScaleInSteps(ctx context.Context, current, target uint64, apply func(ctx context.Context, replicas uint64) error) error {
+	step := uint64(c.ScaleStep)
+	if step == 0 || target <= current || target-current <= step {
+		return apply(ctx, target)
+	}
+
+	replicas := current
+	for replicas < target {
+		replicas += step
+		if replicas > target {
+			replicas = target
+		}
+
+		if err := apply(ctx, replicas); err != nil {
+			return err
+		}
+
+		if replicas == target {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.ScaleStepInterval):
+		}
+	}
+
+	return nil
+}
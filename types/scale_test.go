@@ -0,0 +1,77 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFaaSConfig_ScaleInSteps(t *testing.T) {
+	testCases := []struct {
+		name    string
+		step    int
+		current uint64
+		target  uint64
+		want    []uint64
+	}{
+		{name: "no step", step: 0, current: 1, target: 50, want: []uint64{50}},
+		{name: "ramp up", step: 20, current: 1, target: 50, want: []uint64{21, 41, 50}},
+		{name: "exact steps", step: 10, current: 0, target: 20, want: []uint64{10, 20}},
+		{name: "within one step", step: 10, current: 1, target: 5, want: []uint64{5}},
+		{name: "scale down at once", step: 10, current: 50, target: 1, want: []uint64{1}},
+		{name: "no change", step: 10, current: 3, target: 3, want: []uint64{3}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &FaaSConfig{ScaleStep: tc.step, ScaleStepInterval: time.Millisecond}
+
+			var got []uint64
+			err := c.ScaleInSteps(context.Background(), tc.current, tc.target, func(ctx context.Context, replicas uint64) error {
+				got = append(got, replicas)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("want no error, got: %s", err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("steps, want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestFaaSConfig_ScaleInSteps_Stops(t *testing.T) {
+	c := &FaaSConfig{ScaleStep: 1, ScaleStepInterval: time.Millisecond}
+
+	calls := 0
+	failed := errors.New("backend unavailable")
+	err := c.ScaleInSteps(context.Background(), 0, 10, func(ctx context.Context, replicas uint64) error {
+		calls++
+		if replicas == 3 {
+			return failed
+		}
+		return nil
+	})
+
+	if !errors.Is(err, failed) {
+		t.Errorf("error, want: %s, got: %v", failed, err)
+	}
+	if calls != 3 {
+		t.Errorf("steps applied, want: %d, got: %d", 3, calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.ScaleStepInterval = time.Hour
+	err = c.ScaleInSteps(ctx, 0, 10, func(ctx context.Context, replicas uint64) error {
+		cancel()
+		return nil
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error, want: %s, got: %v", context.Canceled, err)
+	}
+}
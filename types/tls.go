@@ -0,0 +1,159 @@
+package types
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+const (
+	// TLSVersion12 is TLS 1.2, the default and lowest MinTLSVersion accepted.
+	TLSVersion12 = "1.2"
+
+	// TLSVersion13 is TLS 1.3.
+	TLSVersion13 = "1.3"
+)
+
+// TLSConfig enables HTTPS on the API with the certificate and key in CertFile and KeyFile.
+type TLSConfig struct {
+	// CertFile is the path of a PEM encoded certificate, including any intermediates.
+	CertFile string
+	// KeyFile is the path of the PEM encoded private key for CertFile.
+	KeyFile string
+	// MinTLSVersion is the lowest version of TLS clients may connect with, either
+	// TLSVersion12 (the default) or TLSVersion13.
+	MinTLSVersion string
+	// CipherSuites restricts the cipher suites offered for TLS 1.2 connections to the
+	// given names, as listed by tls.CipherSuites, i.e. "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256".
+	// The default is Go's secure defaults. The cipher suites of TLS 1.3 are not configurable.
+	CipherSuites []string
+	// ClientCAFile, when set, is the path of the PEM encoded certificates of the CAs which
+	// sign client certificates, and clients must present a certificate signed by one of
+	// them (mutual TLS).
+	ClientCAFile string
+	// ClientAuthOptional verifies a client certificate signed by ClientCAFile when one is
+	// presented, but also accepts clients without one, i.e. to migrate callers to mutual TLS.
+	ClientAuthOptional bool
+}
+
+// Validate checks that both CertFile and KeyFile are set and that MinTLSVersion and
+// CipherSuites are known values. Versions below TLS 1.2 and insecure cipher suites are
+// rejected.
+func (c *TLSConfig) Validate() error {
+	if len(c.CertFile) == 0 && len(c.KeyFile) == 0 {
+		return fmt.Errorf("invalid TLSConfig: CertFile and KeyFile must both be set")
+	}
+
+	if len(c.KeyFile) == 0 {
+		return fmt.Errorf("invalid TLSConfig: CertFile %q is set without a KeyFile", c.CertFile)
+	}
+
+	if len(c.CertFile) == 0 {
+		return fmt.Errorf("invalid TLSConfig: KeyFile %q is set without a CertFile", c.KeyFile)
+	}
+
+	if _, err := tlsVersion(c.MinTLSVersion); err != nil {
+		return err
+	}
+
+	if _, err := tlsCipherSuites(c.CipherSuites); err != nil {
+		return err
+	}
+
+	if c.ClientAuthOptional && len(c.ClientCAFile) == 0 {
+		return fmt.Errorf("invalid TLSConfig: ClientAuthOptional is set without a ClientCAFile")
+	}
+
+	return nil
+}
+
+// ServerConfig returns the tls.Config for the API's http.Server, with the certificate loaded
+// from CertFile and KeyFile and the CAs from ClientCAFile, so that a missing or mismatched
+// pair is reported before the port is bound.
+func (c *TLSConfig) ServerConfig() (*tls.Config, error) {
+	version, err := tlsVersion(c.MinTLSVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	suites, err := tlsCipherSuites(c.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load TLS certificate: %w", err)
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   version,
+		CipherSuites: suites,
+	}
+
+	if len(c.ClientCAFile) > 0 {
+		pem, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client CA: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("unable to load client CA: no certificates found in %s", c.ClientCAFile)
+		}
+
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		if c.ClientAuthOptional {
+			config.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+
+	return config, nil
+}
+
+func tlsVersion(version string) (uint16, error) {
+	switch version {
+	case "", TLSVersion12:
+		return tls.VersionTLS12, nil
+	case TLSVersion13:
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("invalid MinTLSVersion %q: must be %q or %q", version, TLSVersion12, TLSVersion13)
+	}
+}
+
+// tlsCipherSuites returns the IDs of the named cipher suites, or nil for Go's defaults
+// when names is empty.
+func tlsCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	secure := map[string]uint16{}
+	for _, s := range tls.CipherSuites() {
+		secure[s.Name] = s.ID
+	}
+
+	insecure := map[string]bool{}
+	for _, s := range tls.InsecureCipherSuites() {
+		insecure[s.Name] = true
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		if insecure[name] {
+			return nil, fmt.Errorf("invalid CipherSuites: %s is insecure", name)
+		}
+
+		id, ok := secure[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid CipherSuites: unknown cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
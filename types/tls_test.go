@@ -0,0 +1,129 @@
+package types
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestCertificate writes a self-signed certificate and its key to dir.
+func writeTestCertificate(t *testing.T, dir string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+
+	return certFile, keyFile
+}
+
+func TestTLSConfig_ServerConfig(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t, t.TempDir())
+
+	c := &TLSConfig{CertFile: certFile, KeyFile: keyFile}
+	got, err := c.ServerConfig()
+	if err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+
+	if got.MinVersion != tls.VersionTLS12 {
+		t.Errorf("MinVersion, want: %d, got: %d", tls.VersionTLS12, got.MinVersion)
+	}
+
+	if len(got.Certificates) != 1 {
+		t.Errorf("certificates, want: %d, got: %d", 1, len(got.Certificates))
+	}
+
+	if got.CipherSuites != nil {
+		t.Errorf("CipherSuites, want Go's defaults, got: %v", got.CipherSuites)
+	}
+
+	c.MinTLSVersion = TLSVersion13
+	if got, _ = c.ServerConfig(); got.MinVersion != tls.VersionTLS13 {
+		t.Errorf("MinVersion, want: %d, got: %d", tls.VersionTLS13, got.MinVersion)
+	}
+}
+
+func TestTLSConfig_ServerConfig_MissingCertificate(t *testing.T) {
+	dir := t.TempDir()
+	c := &TLSConfig{CertFile: filepath.Join(dir, "tls.crt"), KeyFile: filepath.Join(dir, "tls.key")}
+
+	_, err := c.ServerConfig()
+	if err == nil || !strings.Contains(err.Error(), "unable to load TLS certificate") {
+		t.Errorf("error, want: %q, got: %v", "unable to load TLS certificate", err)
+	}
+}
+
+func TestTLSConfig_ServerConfig_ClientCA(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCertificate(t, dir)
+
+	testCases := []struct {
+		name           string
+		config         TLSConfig
+		wantClientAuth tls.ClientAuthType
+		wantErr        string
+	}{
+		{name: "no client CA", config: TLSConfig{CertFile: certFile, KeyFile: keyFile}, wantClientAuth: tls.NoClientCert},
+		{name: "client CA", config: TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: certFile}, wantClientAuth: tls.RequireAndVerifyClientCert},
+		{name: "optional client CA", config: TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: certFile, ClientAuthOptional: true}, wantClientAuth: tls.VerifyClientCertIfGiven},
+		{name: "missing client CA", config: TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: filepath.Join(dir, "ca.crt")}, wantErr: "unable to load client CA"},
+		{name: "client CA without certificates", config: TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: keyFile}, wantErr: "no certificates found"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.config.ServerConfig()
+			if len(tc.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("error, want: %q, got: %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want no error, got: %s", err)
+			}
+
+			if got.ClientAuth != tc.wantClientAuth {
+				t.Errorf("ClientAuth, want: %s, got: %s", tc.wantClientAuth, got.ClientAuth)
+			}
+			if len(tc.config.ClientCAFile) > 0 && got.ClientCAs == nil {
+				t.Errorf("want ClientCAs to be set")
+			}
+		})
+	}
+}
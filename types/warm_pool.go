@@ -0,0 +1,78 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// WarmPoolPolicy is posted to "/system/warm-pool/{name}" to keep instances of a function
+// started ahead of its invocations, so that they are served without a cold start. The
+// instances are restored from CheckpointID when it is set, otherwise they are started
+// from the image of the function.
+type WarmPoolPolicy struct {
+	// Namespace of the function, if supported by the faas-provider
+	Namespace string `json:"namespace,omitempty"`
+
+	// MinWarm is the number of idle instances kept ready for invocations, a value of 0
+	// removes the pool
+	MinWarm int `json:"minWarm"`
+
+	// CheckpointID is the ID of the checkpoint the warm instances are restored from, as
+	// returned by the checkpoint list or create endpoints
+	CheckpointID string `json:"checkpointId,omitempty"`
+
+	// TTLSeconds is how long an idle warm instance is kept before it is replaced by a
+	// fresh one, a value of 0 keeps it until it is used
+	TTLSeconds int `json:"ttlSeconds,omitempty"`
+}
+
+// Validate checks that the counts of the policy are not negative.
+func (p WarmPoolPolicy) Validate() error {
+	if p.MinWarm < 0 {
+		return fmt.Errorf("invalid minWarm %d: must not be negative", p.MinWarm)
+	}
+	if p.TTLSeconds < 0 {
+		return fmt.Errorf("invalid ttlSeconds %d: must not be negative", p.TTLSeconds)
+	}
+	return nil
+}
+
+// GetTTL returns TTLSeconds as a duration, 0 when idle instances are kept until used.
+func (p WarmPoolPolicy) GetTTL() time.Duration {
+	return time.Duration(p.TTLSeconds) * time.Second
+}
+
+// WarmPoolStatus is returned by "/system/warm-pool/{name}" with the policy of the pool of
+// a function and the instances it holds.
+type WarmPoolStatus struct {
+	// Function is the name of the function
+	Function string `json:"function"`
+
+	// Namespace of the function, if supported by the faas-provider
+	Namespace string `json:"namespace,omitempty"`
+
+	// Policy is the policy of the pool, with a MinWarm of 0 when there is none
+	Policy WarmPoolPolicy `json:"policy"`
+
+	// Warm is the number of idle instances ready for invocations
+	Warm int `json:"warm"`
+
+	// Starting is the number of instances being started or restored to fill the pool
+	Starting int `json:"starting"`
+}
+
+// DecodeWarmPoolPolicy reads a WarmPoolPolicy from body and validates it.
+func DecodeWarmPoolPolicy(body io.Reader) (WarmPoolPolicy, error) {
+	policy := WarmPoolPolicy{}
+	if err := json.NewDecoder(body).Decode(&policy); err != nil {
+		return policy, fmt.Errorf("unable to decode warm pool policy: %w", err)
+	}
+
+	if err := policy.Validate(); err != nil {
+		return policy, err
+	}
+
+	return policy, nil
+}
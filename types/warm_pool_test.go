@@ -0,0 +1,68 @@
+package types
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_DecodeWarmPoolPolicy(t *testing.T) {
+	testCases := []struct {
+		name    string
+		body    string
+		wantErr string
+		want    WarmPoolPolicy
+	}{
+		{
+			name: "from checkpoint",
+			body: `{"namespace":"openfaas-fn","minWarm":2,"checkpointId":"figlet-1","ttlSeconds":300}`,
+			want: WarmPoolPolicy{Namespace: "openfaas-fn", MinWarm: 2, CheckpointID: "figlet-1", TTLSeconds: 300},
+		},
+		{
+			name: "remove pool",
+			body: `{"minWarm":0}`,
+			want: WarmPoolPolicy{},
+		},
+		{
+			name:    "negative minWarm",
+			body:    `{"minWarm":-1}`,
+			wantErr: "invalid minWarm",
+		},
+		{
+			name:    "negative ttl",
+			body:    `{"minWarm":1,"ttlSeconds":-5}`,
+			wantErr: "invalid ttlSeconds",
+		},
+		{
+			name:    "malformed json",
+			body:    `{"minWarm":`,
+			wantErr: "unable to decode warm pool policy",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := DecodeWarmPoolPolicy(strings.NewReader(tc.body))
+			if len(tc.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("want error containing %q, got: %v", tc.wantErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("want: %+v, got: %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_WarmPoolPolicy_GetTTL(t *testing.T) {
+	if got := (WarmPoolPolicy{TTLSeconds: 90}).GetTTL(); got != 90*time.Second {
+		t.Errorf("TTL, want: %s, got: %s", 90*time.Second, got)
+	}
+}
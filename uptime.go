@@ -0,0 +1,81 @@
+package bootstrap
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	startedAt   time.Time
+	startedAtMu sync.RWMutex
+
+	startTimeGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "faas",
+		Subsystem: "provider",
+		Name:      "start_time_seconds",
+		Help:      "Start time of the provider since unix epoch in seconds.",
+	})
+)
+
+// StartedAt returns the time the provider started serving its API, or the zero
+// time when Serve has not been called.
+func StartedAt() time.Time {
+	startedAtMu.RLock()
+	defer startedAtMu.RUnlock()
+	return startedAt
+}
+
+// markStarted records t as the start time of the provider.
+func markStarted(t time.Time) {
+	startedAtMu.Lock()
+	startedAt = t
+	startedAtMu.Unlock()
+
+	startTimeGauge.Set(float64(t.UnixNano()) / 1e9)
+}
+
+// decorateWithStartedAt adds the "startedAt" field to the ProviderInfo written by
+// the Info handler, when the handler did not set it.
+func decorateWithStartedAt(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bw := httputil.NewBufferedResponseWriter()
+		next.ServeHTTP(bw, r)
+
+		started := StartedAt()
+		if bw.Status() != http.StatusOK || started.IsZero() {
+			bw.Flush(w)
+			return
+		}
+
+		info := map[string]json.RawMessage{}
+		if err := json.Unmarshal(bw.Body(), &info); err != nil {
+			bw.Flush(w)
+			return
+		}
+
+		if _, ok := info["startedAt"]; ok {
+			bw.Flush(w)
+			return
+		}
+
+		info["startedAt"], _ = json.Marshal(started)
+		body, err := json.Marshal(info)
+		if err != nil {
+			bw.Flush(w)
+			return
+		}
+
+		for k, v := range bw.Header() {
+			w.Header()[k] = v
+		}
+		w.Header().Del("Content-Length")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}
+}
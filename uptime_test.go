@@ -0,0 +1,57 @@
+package bootstrap
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_decorateWithStartedAt(t *testing.T) {
+	started := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	markStarted(started)
+	defer markStarted(time.Time{})
+
+	set := started.Add(time.Hour)
+
+	testCases := []struct {
+		name string
+		info types.ProviderInfo
+		want time.Time
+	}{
+		{name: "filled in when empty", info: types.ProviderInfo{Name: "faasd"}, want: started},
+		{name: "kept when set by the provider", info: types.ProviderInfo{Name: "faasd", StartedAt: &set}, want: set},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := decorateWithStartedAt(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(tc.info)
+			})
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/system/info", nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status code, want: %d, got: %d", http.StatusOK, w.Code)
+			}
+
+			got := types.ProviderInfo{}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+
+			if got.StartedAt == nil || !got.StartedAt.Equal(tc.want) {
+				t.Errorf("startedAt, want: %s, got: %v", tc.want, got.StartedAt)
+			}
+
+			if got.Name != tc.info.Name {
+				t.Errorf("provider, want: %s, got: %s", tc.info.Name, got.Name)
+			}
+		})
+	}
+}
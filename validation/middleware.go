@@ -0,0 +1,47 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// Decorate rejects deploy and update requests whose FunctionDeployment fails
+// ValidateFunctionDeployment with limits, with a 400 written by WriteErrors. The request
+// body is restored before next is called. Requests which can not be decoded are passed to
+// next, which reports the error, apart from those over the limit of http.MaxBytesReader,
+// which are rejected with a 413.
+func Decorate(next http.HandlerFunc, limits Limits) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, ok := httputil.BufferBody(w, r)
+		if !ok {
+			return
+		}
+
+		req := types.FunctionDeployment{}
+		if err := json.Unmarshal(body, &req); err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var errs Errors
+		if errors.As(ValidateFunctionDeployment(req, limits), &errs) {
+			WriteErrors(w, r, errs)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// WriteErrors writes errs with a 400 status as an httputil.ErrorResponse, or a Problem when
+// the client accepts it, with the invalid fields in "errors", so that clients can show which
+// fields of the deployment to correct:
+//
+//	{"code": 400, "message": "invalid function deployment: ...", "errorCode": "InvalidRequest", "errors": [{"field": "limits.memory", "value": "lots", "message": "..."}]}
+func WriteErrors(w http.ResponseWriter, r *http.Request, errs Errors) {
+	httputil.WriteErrorDetails(w, r, http.StatusBadRequest, httputil.InvalidRequest, errs.Error(), errs)
+}
@@ -0,0 +1,178 @@
+// Package validation checks the FunctionDeployment of deploy and update requests before
+// they reach the provider's handlers, so that every provider rejects invalid names,
+// resource quantities, environment variables and oversized labels in the same way.
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/openfaas/faas-provider/types"
+)
+
+const (
+	// MaxNameLength is the longest name of a function or namespace.
+	MaxNameLength = 253
+	// MaxLabels is the most labels a function may have.
+	MaxLabels = 64
+	// DefaultMaxLabelValueLength is the longest value of a label when
+	// Limits.MaxLabelValueLength is not set, as in Kubernetes.
+	DefaultMaxLabelValueLength = 63
+	// MaxAnnotationsSize is the most bytes the keys and values of the annotations of a
+	// function may add up to.
+	MaxAnnotationsSize = 256 * 1024
+)
+
+// nameExpression matches the names the router accepts for a function, see
+// bootstrap.NameExpression.
+var nameExpression = regexp.MustCompile(`^[-a-zA-Z_0-9.]+$`)
+
+// envNameExpression matches the names of environment variables.
+var envNameExpression = regexp.MustCompile(`^[-._a-zA-Z][-._a-zA-Z0-9]*$`)
+
+// quantityExpression matches a resource quantity such as "128Mi", "0.5" or "100m".
+var quantityExpression = regexp.MustCompile(`^([0-9]+(\.[0-9]*)?|\.[0-9]+)([eE][-+]?[0-9]+|Ki|Mi|Gi|Ti|Pi|Ei|n|u|m|k|M|G|T|P|E)?$`)
+
+// Limits are the limits of a FunctionDeployment which depend on the provider.
+type Limits struct {
+	// MaxLabelValueLength is the longest value of a label, DefaultMaxLabelValueLength when
+	// 0, a negative value does not limit the length.
+	MaxLabelValueLength int
+}
+
+func (l Limits) maxLabelValueLength() int {
+	if l.MaxLabelValueLength == 0 {
+		return DefaultMaxLabelValueLength
+	}
+	return l.MaxLabelValueLength
+}
+
+// FieldError is a field of a FunctionDeployment which is not valid.
+type FieldError struct {
+	// Field is the JSON path of the field, i.e. "limits.memory" or "envVars.PATH".
+	Field string `json:"field"`
+	// Value is the invalid value, it is omitted for values which are too large or may be
+	// secret, such as those of environment variables.
+	Value string `json:"value,omitempty"`
+	// Message describes what is wrong with the value.
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// Errors are the fields of a FunctionDeployment which are not valid.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return "invalid function deployment: " + strings.Join(messages, ", ")
+}
+
+// ValidateFunctionDeployment checks the name, namespace, environment variables, resources,
+// labels, annotations and declared timeout of a deployment against limits, it returns
+// Errors listing every invalid field, or nil. The syntax of label and annotation keys is
+// checked by types.NormalizeLabels.
+func ValidateFunctionDeployment(d types.FunctionDeployment, limits Limits) error {
+	var errs Errors
+
+	// A missing service is left to the provider, which reports it as before.
+	if len(d.Service) > 0 {
+		errs = append(errs, validateName("service", d.Service)...)
+	}
+	if len(d.Namespace) > 0 {
+		errs = append(errs, validateName("namespace", d.Namespace)...)
+	}
+
+	for _, name := range sortedKeys(d.EnvVars) {
+		field := "envVars." + name
+		if !envNameExpression.MatchString(name) {
+			errs = append(errs, FieldError{Field: field, Message: `name must start with a letter, "-", "_" or "." followed by letters, digits, "-", "_" or "."`})
+		}
+		if strings.ContainsRune(d.EnvVars[name], 0) {
+			errs = append(errs, FieldError{Field: field, Message: "value must not contain a NUL byte"})
+		}
+	}
+
+	errs = append(errs, validateResources("limits", d.Limits)...)
+	errs = append(errs, validateResources("requests", d.Requests)...)
+
+	if d.Labels != nil {
+		labels := *d.Labels
+		if len(labels) > MaxLabels {
+			errs = append(errs, FieldError{Field: "labels", Message: fmt.Sprintf("must not have more than %d labels, got %d", MaxLabels, len(labels))})
+		}
+		if max := limits.maxLabelValueLength(); max > 0 {
+			for _, key := range sortedKeys(labels) {
+				if len(labels[key]) > max {
+					errs = append(errs, FieldError{Field: "labels." + key, Message: fmt.Sprintf("value must not be longer than %d characters", max)})
+				}
+			}
+		}
+	}
+
+	if d.Labels != nil {
+		if _, _, err := types.FunctionTimeoutFromLabels(*d.Labels); err != nil {
+			errs = append(errs, FieldError{Field: "labels." + types.FunctionTimeoutLabel, Value: (*d.Labels)[types.FunctionTimeoutLabel], Message: "must be a duration or a number of seconds greater than zero"})
+		}
+	}
+	if d.Annotations != nil {
+		if _, _, err := types.FunctionTimeoutFromAnnotations(*d.Annotations); err != nil {
+			errs = append(errs, FieldError{Field: "annotations." + types.FunctionTimeoutAnnotation, Value: (*d.Annotations)[types.FunctionTimeoutAnnotation], Message: "must be a duration or a number of seconds greater than zero"})
+		}
+
+		size := 0
+		for key, value := range *d.Annotations {
+			size += len(key) + len(value)
+		}
+		if size > MaxAnnotationsSize {
+			errs = append(errs, FieldError{Field: "annotations", Message: fmt.Sprintf("must not be larger than %d bytes in total, got %d", MaxAnnotationsSize, size)})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateName(field, name string) Errors {
+	if len(name) > MaxNameLength {
+		return Errors{{Field: field, Message: fmt.Sprintf("must not be longer than %d characters", MaxNameLength)}}
+	}
+	if !nameExpression.MatchString(name) {
+		return Errors{{Field: field, Value: name, Message: `must only contain letters, digits, "-", "_" or "."`}}
+	}
+	return nil
+}
+
+func validateResources(field string, resources *types.FunctionResources) Errors {
+	if resources == nil {
+		return nil
+	}
+
+	var errs Errors
+	if len(resources.Memory) > 0 && !quantityExpression.MatchString(resources.Memory) {
+		errs = append(errs, FieldError{Field: field + ".memory", Value: resources.Memory, Message: `must be a quantity such as "128Mi" or "1G"`})
+	}
+	if len(resources.CPU) > 0 && !quantityExpression.MatchString(resources.CPU) {
+		errs = append(errs, FieldError{Field: field + ".cpu", Value: resources.CPU, Message: `must be a quantity such as "100m" or "0.5"`})
+	}
+	return errs
+}
+
+// sortedKeys returns the keys of m in order, so that errors are reported in a stable order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
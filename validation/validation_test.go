@@ -0,0 +1,142 @@
+package validation
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-provider/httputil"
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_ValidateFunctionDeployment(t *testing.T) {
+	labels := func(n int, value string) *map[string]string {
+		m := map[string]string{}
+		for i := 0; i < n; i++ {
+			m[strings.Repeat("a", i+1)] = value
+		}
+		return &m
+	}
+
+	cases := []struct {
+		name       string
+		deployment types.FunctionDeployment
+		limits     Limits
+		wantFields []string
+	}{
+		{"valid", types.FunctionDeployment{
+			Service:   "figlet",
+			Namespace: "openfaas-fn",
+			EnvVars:   map[string]string{"write_debug": "true", "fprocess": "figlet"},
+			Limits:    &types.FunctionResources{Memory: "128Mi", CPU: "100m"},
+			Requests:  &types.FunctionResources{Memory: "1e6", CPU: "0.5"},
+			Labels:    labels(MaxLabels, "1"),
+		}, Limits{}, nil},
+		{"no service is left to the provider", types.FunctionDeployment{}, Limits{}, nil},
+		{"invalid name", types.FunctionDeployment{Service: "fig let", Namespace: "openfaas/fn"}, Limits{}, []string{"service", "namespace"}},
+		{"long name", types.FunctionDeployment{Service: strings.Repeat("a", MaxNameLength+1)}, Limits{}, []string{"service"}},
+		{"invalid env vars", types.FunctionDeployment{Service: "figlet", EnvVars: map[string]string{"1st": "a", "A=B": "c", "ok": "a\x00b"}}, Limits{}, []string{"envVars.1st", "envVars.A=B", "envVars.ok"}},
+		{"invalid quantities", types.FunctionDeployment{
+			Service:  "figlet",
+			Limits:   &types.FunctionResources{Memory: "lots", CPU: "-1"},
+			Requests: &types.FunctionResources{Memory: "128MB"},
+		}, Limits{}, []string{"limits.memory", "limits.cpu", "requests.memory"}},
+		{"too many labels", types.FunctionDeployment{Service: "figlet", Labels: labels(MaxLabels+1, "1")}, Limits{}, []string{"labels"}},
+		{"long label value", types.FunctionDeployment{Service: "figlet", Labels: &map[string]string{"team": strings.Repeat("a", DefaultMaxLabelValueLength+1)}}, Limits{}, []string{"labels.team"}},
+		{"label value within a larger limit", types.FunctionDeployment{Service: "figlet", Labels: &map[string]string{"team": strings.Repeat("a", DefaultMaxLabelValueLength+1)}}, Limits{MaxLabelValueLength: 128}, nil},
+		{"label value over a smaller limit", types.FunctionDeployment{Service: "figlet", Labels: &map[string]string{"team": "openfaas"}}, Limits{MaxLabelValueLength: 4}, []string{"labels.team"}},
+		{"label value without a limit", types.FunctionDeployment{Service: "figlet", Labels: &map[string]string{"team": strings.Repeat("a", 1024)}}, Limits{MaxLabelValueLength: -1}, nil},
+		{"invalid timeouts", types.FunctionDeployment{
+			Service:     "figlet",
+			Labels:      &map[string]string{types.FunctionTimeoutLabel: "soon"},
+			Annotations: &map[string]string{types.FunctionTimeoutAnnotation: "0s"},
+		}, Limits{}, []string{"labels." + types.FunctionTimeoutLabel, "annotations." + types.FunctionTimeoutAnnotation}},
+		{"large annotations", types.FunctionDeployment{Service: "figlet", Annotations: &map[string]string{"topic": strings.Repeat("a", MaxAnnotationsSize)}}, Limits{}, []string{"annotations"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateFunctionDeployment(tc.deployment, tc.limits)
+
+			var got []string
+			if err != nil {
+				for _, fieldErr := range err.(Errors) {
+					got = append(got, fieldErr.Field)
+				}
+			}
+			if !reflect.DeepEqual(tc.wantFields, got) {
+				t.Errorf("invalid fields, want: %v, got: %v", tc.wantFields, got)
+			}
+		})
+	}
+}
+
+func Test_Decorate(t *testing.T) {
+	cases := []struct {
+		name            string
+		body            string
+		accept          string
+		wantCode        int
+		wantContentType string
+		wantFields      []string
+	}{
+		{"valid", `{"service":"figlet","limits":{"memory":"128Mi"}}`, "", http.StatusAccepted, "", nil},
+		{"invalid json is passed on", `{`, "", http.StatusAccepted, "", nil},
+		{"invalid", `{"service":"fig let","limits":{"memory":"lots"}}`, "", http.StatusBadRequest, "application/json", []string{"service", "limits.memory"}},
+		{"invalid problem", `{"service":"fig let"}`, httputil.ProblemContentType, http.StatusBadRequest, httputil.ProblemContentType, []string{"service"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := Decorate(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != tc.body {
+					t.Errorf("body, want: %q, got: %q", tc.body, string(body))
+				}
+				w.WriteHeader(http.StatusAccepted)
+			}, Limits{})
+
+			r := httptest.NewRequest(http.MethodPost, "/system/functions", strings.NewReader(tc.body))
+			if len(tc.accept) > 0 {
+				r.Header.Set("Accept", tc.accept)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tc.wantCode {
+				t.Fatalf("status code, want: %d, got: %d", tc.wantCode, w.Code)
+			}
+			if tc.wantCode != http.StatusBadRequest {
+				return
+			}
+
+			if got := w.Header().Get("Content-Type"); got != tc.wantContentType {
+				t.Errorf("Content-Type, want: %s, got: %s", tc.wantContentType, got)
+			}
+
+			res := struct {
+				Message string `json:"message"`
+				Detail  string `json:"detail"`
+				Errors  Errors `json:"errors"`
+			}{}
+			if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+				t.Fatalf("want a JSON body, got: %s", err)
+			}
+
+			var got []string
+			for _, fieldErr := range res.Errors {
+				got = append(got, fieldErr.Field)
+			}
+			if !reflect.DeepEqual(tc.wantFields, got) {
+				t.Errorf("invalid fields, want: %v, got: %v", tc.wantFields, got)
+			}
+			if !strings.HasPrefix(res.Message+res.Detail, "invalid function deployment: ") {
+				t.Errorf("message, want a summary of the errors, got: %q", res.Message+res.Detail)
+			}
+		})
+	}
+}